@@ -26,26 +26,47 @@ import (
 	"istio.io/istio/istioctl/pkg/admin"
 	"istio.io/istio/istioctl/pkg/analyze"
 	"istio.io/istio/istioctl/pkg/authz"
+	"istio.io/istio/istioctl/pkg/blastradius"
 	"istio.io/istio/istioctl/pkg/checkinject"
 	"istio.io/istio/istioctl/pkg/cli"
 	"istio.io/istio/istioctl/pkg/completion"
 	"istio.io/istio/istioctl/pkg/config"
+	"istio.io/istio/istioctl/pkg/configsize"
+	"istio.io/istio/istioctl/pkg/connectlatency"
 	"istio.io/istio/istioctl/pkg/dashboard"
 	"istio.io/istio/istioctl/pkg/describe"
+	"istio.io/istio/istioctl/pkg/envdump"
+	"istio.io/istio/istioctl/pkg/envoydeprecation"
+	"istio.io/istio/istioctl/pkg/gatewayapi"
+	"istio.io/istio/istioctl/pkg/headless"
+	"istio.io/istio/istioctl/pkg/healthscore"
+	"istio.io/istio/istioctl/pkg/identity"
 	"istio.io/istio/istioctl/pkg/injector"
 	"istio.io/istio/istioctl/pkg/internaldebug"
 	"istio.io/istio/istioctl/pkg/kubeinject"
+	"istio.io/istio/istioctl/pkg/labelsync"
 	"istio.io/istio/istioctl/pkg/metrics"
 	"istio.io/istio/istioctl/pkg/multicluster"
+	"istio.io/istio/istioctl/pkg/orphans"
+	"istio.io/istio/istioctl/pkg/peerauth"
 	"istio.io/istio/istioctl/pkg/precheck"
+	"istio.io/istio/istioctl/pkg/protocolcheck"
+	"istio.io/istio/istioctl/pkg/proxyanomaly"
 	"istio.io/istio/istioctl/pkg/proxyconfig"
 	"istio.io/istio/istioctl/pkg/proxystatus"
 	"istio.io/istio/istioctl/pkg/root"
+	"istio.io/istio/istioctl/pkg/routetest"
+	"istio.io/istio/istioctl/pkg/scaffold"
+	"istio.io/istio/istioctl/pkg/simulate"
+	"istio.io/istio/istioctl/pkg/slowstart"
 	"istio.io/istio/istioctl/pkg/tag"
+	"istio.io/istio/istioctl/pkg/throttletest"
+	"istio.io/istio/istioctl/pkg/unusedconfig"
 	"istio.io/istio/istioctl/pkg/util"
 	"istio.io/istio/istioctl/pkg/validate"
 	"istio.io/istio/istioctl/pkg/version"
 	"istio.io/istio/istioctl/pkg/waypoint"
+	"istio.io/istio/istioctl/pkg/webhookorder"
 	"istio.io/istio/istioctl/pkg/workload"
 	"istio.io/istio/istioctl/pkg/ztunnelconfig"
 	"istio.io/istio/operator/cmd/mesh"
@@ -105,7 +126,13 @@ func GetRootCmd(args []string) *cobra.Command {
 		Short:             "Istio control interface.",
 		SilenceUsage:      true,
 		DisableAutoGenTag: true,
-		PersistentPreRunE: ConfigureLogging,
+		PersistentPreRunE: func(c *cobra.Command, args []string) error {
+			// The flags not set on the command line default to the settings of the current mesh, if any.
+			if err := config.ApplyMeshDefaults(c); err != nil {
+				return err
+			}
+			return ConfigureLogging(c, args)
+		},
 		Long: `Istio configuration command line utility for service operators to
 debug and diagnose their Istio mesh.
 `,
@@ -186,6 +213,7 @@ debug and diagnose their Istio mesh.
 	rootCmd.AddCommand(proxyconfig.ProxyConfig(ctx))
 	rootCmd.AddCommand(admin.Cmd(ctx))
 	experimentalCmd.AddCommand(injector.Cmd(ctx))
+	experimentalCmd.AddCommand(injector.RevisionCmd(ctx))
 
 	rootCmd.AddCommand(mesh.UninstallCmd(ctx))
 
@@ -198,7 +226,30 @@ debug and diagnose their Istio mesh.
 	experimentalCmd.AddCommand(internaldebug.DebugCommand(ctx))
 	experimentalCmd.AddCommand(precheck.Cmd(ctx))
 	experimentalCmd.AddCommand(proxyconfig.StatsConfigCmd(ctx))
+	experimentalCmd.AddCommand(proxyconfig.FailoverCmd(ctx))
+	experimentalCmd.AddCommand(orphans.Cmd(ctx))
+	experimentalCmd.AddCommand(healthscore.Cmd(ctx))
+	experimentalCmd.AddCommand(protocolcheck.Cmd(ctx))
+	experimentalCmd.AddCommand(configsize.Cmd(ctx))
+	experimentalCmd.AddCommand(headless.Cmd(ctx))
+	experimentalCmd.AddCommand(gatewayapi.Cmd(ctx))
+	experimentalCmd.AddCommand(peerauth.Cmd(ctx))
 	experimentalCmd.AddCommand(checkinject.Cmd(ctx))
+	experimentalCmd.AddCommand(kubeinject.RestartNeededCommand(ctx))
+	experimentalCmd.AddCommand(envdump.Cmd(ctx))
+	experimentalCmd.AddCommand(labelsync.Cmd(ctx))
+	experimentalCmd.AddCommand(connectlatency.Cmd(ctx))
+	experimentalCmd.AddCommand(blastradius.Cmd(ctx))
+	experimentalCmd.AddCommand(identity.Cmd(ctx))
+	experimentalCmd.AddCommand(unusedconfig.Cmd(ctx))
+	experimentalCmd.AddCommand(throttletest.Cmd(ctx))
+	experimentalCmd.AddCommand(scaffold.Cmd(ctx))
+	experimentalCmd.AddCommand(webhookorder.Cmd(ctx))
+	experimentalCmd.AddCommand(simulate.Cmd(ctx))
+	experimentalCmd.AddCommand(proxyanomaly.Cmd(ctx))
+	experimentalCmd.AddCommand(routetest.Cmd(ctx))
+	experimentalCmd.AddCommand(envoydeprecation.Cmd(ctx))
+	experimentalCmd.AddCommand(slowstart.Cmd(ctx))
 	rootCmd.AddCommand(waypoint.Cmd(ctx))
 	rootCmd.AddCommand(ztunnelconfig.ZtunnelConfig(ctx))
 
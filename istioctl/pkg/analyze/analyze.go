@@ -16,12 +16,15 @@ package analyze
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"runtime"
 	"sort"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/mattn/go-isatty"
@@ -29,6 +32,7 @@ import (
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/yaml"
 
 	"istio.io/istio/istioctl/pkg/cli"
 	"istio.io/istio/istioctl/pkg/util"
@@ -36,6 +40,7 @@ import (
 	"istio.io/istio/pkg/cluster"
 	"istio.io/istio/pkg/config/analysis"
 	"istio.io/istio/pkg/config/analysis/analyzers"
+	"istio.io/istio/pkg/config/analysis/analyzers/deployment"
 	"istio.io/istio/pkg/config/analysis/diag"
 	"istio.io/istio/pkg/config/analysis/local"
 	"istio.io/istio/pkg/config/analysis/msg"
@@ -81,6 +86,15 @@ var (
 	ignoreUnknown     bool
 	revisionSpecified string
 	remoteContexts    []string
+	baselineFile      string
+	pluginPaths       []string
+	suppressionsPath  string
+	severitiesPath    string
+	watch             bool
+	changesOnly       bool
+	baseFiles         []string
+	archivePath       string
+	startupImages     []string
 
 	fileExtensions = []string{".json", ".yaml", ".yml"}
 )
@@ -112,6 +126,40 @@ func Analyze(ctx cli.Context) *cobra.Command {
   # and suppress MisplacedAnnotation on deployment foobar in namespace default.
   istioctl analyze -S "IST0103=Pod *.testing" -S "IST0107=Deployment foobar.default"
 
+  # Analyze the current live cluster, suppressing the findings accepted in a checked-in file
+  istioctl analyze --suppressions-file .istio-suppressions.yaml
+
+  # Analyze the current live cluster, with the severity of message codes set by the organization, such as IST0118: error
+  istioctl analyze --severities-file .istio-severities.yaml
+
+  # Analyze the current live cluster and report only the findings which are new or resolved since a previous run
+  istioctl analyze -o json > baseline.json
+  istioctl analyze --baseline baseline.json
+
+  # Analyze the current live cluster and report only the findings introduced or resolved by applying yaml files
+  istioctl analyze --changes-only my-app-config/
+
+  # Analyze two versions of yaml files, such as the base and the head of a pull request, and report only the findings
+  # introduced or resolved by the change
+  istioctl analyze --use-kube=false --base base/my-app-config/ my-app-config/
+
+  # Analyze the resources archived by istioctl bug-report, without access to the cluster
+  istioctl analyze --archive bug-report.tar.gz -A
+
+  # Analyze yaml files and produce a SARIF report for GitHub code scanning. Only the results of files have a
+  # physical location, which GitHub code scanning requires, the resources of a live cluster being reported by name.
+  istioctl analyze --use-kube=false -o sarif my-app-config/ > istio.sarif
+
+  # List the analyzers, with the code, severity and template of the messages they report, for documentation generators
+  istioctl analyze --list-analyzers -o json
+
+  # Analyze the current live cluster, then keep watching it and print the findings which are new or resolved as
+  # resources change, until interrupted
+  istioctl analyze --watch
+
+  # Analyze the current live cluster with the analyzer of an organization plugin, in addition to the built-in analyzers
+  istioctl analyze --plugin ./acme-mesh-lint
+
   # List available analyzers
   istioctl analyze -L`,
 		RunE: func(cmd *cobra.Command, args []string) error {
@@ -123,11 +171,56 @@ func Analyze(ctx cli.Context) *cobra.Command {
 				}
 			}
 
+			plugins, err := loadPlugins(pluginPaths)
+			if err != nil {
+				return err
+			}
+			allAnalyzers := analyzers.All()
+			for _, a := range allAnalyzers {
+				if s, ok := a.(*deployment.ProxyStartupOrderAnalyzer); ok {
+					s.StartupImages = startupImages
+				}
+			}
+			for _, p := range plugins {
+				allAnalyzers = append(allAnalyzers, p)
+			}
+
 			if listAnalyzers {
-				fmt.Print(AnalyzersAsString(analyzers.All()))
+				if msgOutputFormat == formatting.JSONFormat || msgOutputFormat == formatting.YAMLFormat {
+					output, err := AnalyzersAsCatalog(allAnalyzers, msgOutputFormat)
+					if err != nil {
+						return err
+					}
+					_, _ = fmt.Fprint(cmd.OutOrStdout(), output)
+					return nil
+				}
+				fmt.Print(AnalyzersAsString(allAnalyzers))
 				return nil
 			}
 
+			if archivePath != "" {
+				if cmd.Flags().Changed("use-kube") && useKube {
+					return util.CommandParseError{Err: fmt.Errorf("--archive cannot be combined with --use-kube, the archive replaces the live cluster")}
+				}
+				useKube = false
+			}
+
+			if watch && (!useKube || msgOutputFormat != formatting.LogFormat) {
+				return util.CommandParseError{
+					Err: fmt.Errorf("--watch requires a live cluster (--use-kube) and the %s output format", formatting.LogFormat),
+				}
+			}
+
+			if len(baseFiles) > 0 {
+				changesOnly = true
+			}
+			if changesOnly && (baselineFile != "" || watch) {
+				return util.CommandParseError{Err: fmt.Errorf("--changes-only cannot be combined with --baseline or --watch")}
+			}
+			if changesOnly && !useKube && archivePath == "" && len(baseFiles) == 0 {
+				return util.CommandParseError{Err: fmt.Errorf("--changes-only without a live cluster requires the files of the base with --base")}
+			}
+
 			if recursive {
 				fmt.Println("The recursive flag has been removed and is hardcoded to true without explicitly specifying it.")
 				return nil
@@ -137,6 +230,12 @@ func Analyze(ctx cli.Context) *cobra.Command {
 			if err != nil {
 				return err
 			}
+			var dump *clusterDump
+			if archivePath != "" {
+				if dump, err = readClusterDump(archivePath, ctx.IstioNamespace(), revisionSpecified, cmd.ErrOrStderr()); err != nil {
+					return err
+				}
+			}
 			cancel := make(chan struct{})
 
 			// We use the "namespace" arg that's provided as part of root istioctl as a flag for specifying what namespace to use
@@ -168,10 +267,6 @@ func Analyze(ctx cli.Context) *cobra.Command {
 				selectedNamespace = metav1.NamespaceDefault
 			}
 
-			sa := local.NewIstiodAnalyzer(analyzers.AllCombined(),
-				resource.Namespace(selectedNamespace),
-				resource.Namespace(ctx.IstioNamespace()), nil)
-
 			// Check for suppressions and add them to our SourceAnalyzer
 			suppressions := make([]local.AnalysisSuppression, 0, len(suppress))
 			for _, s := range suppress {
@@ -179,59 +274,115 @@ func Analyze(ctx cli.Context) *cobra.Command {
 				if len(parts) != 2 {
 					return fmt.Errorf("%s is not a valid suppression value. See istioctl analyze --help", s)
 				}
+				suppressions = append(suppressions, local.AnalysisSuppression{
+					Code:         parts[0],
+					ResourceName: parts[1],
+				})
+			}
+			if suppressionsPath != "" {
+				fromFile, expired, err := readSuppressionsFile(suppressionsPath, time.Now())
+				if err != nil {
+					return err
+				}
+				for _, e := range expired {
+					fmt.Fprintf(cmd.ErrOrStderr(), "Warning: Suppression %s of %s expired on %s and no longer applies.\n", e, suppressionsPath, e.Expires)
+				}
+				suppressions = append(suppressions, fromFile...)
+			}
+			var severities []local.AnalysisSeverity
+			if severitiesPath != "" {
+				if severities, err = readSeveritiesFile(severitiesPath); err != nil {
+					return err
+				}
+			}
+			codes := make([]string, 0, len(suppressions)+len(severities))
+			for _, s := range suppressions {
+				codes = append(codes, s.Code)
+			}
+			for _, s := range severities {
+				codes = append(codes, s.Code)
+			}
+			for _, code := range codes {
 				// Check to see if the supplied code is valid. If not, emit a
 				// warning but continue.
 				codeIsValid := false
 				for _, at := range msg.All() {
-					if at.Code() == parts[0] {
+					if at.Code() == code {
 						codeIsValid = true
 						break
 					}
 				}
 
 				if !codeIsValid {
-					fmt.Fprintf(cmd.ErrOrStderr(), "Warning: Supplied message code '%s' is an unknown message code and will not have any effect.\n", parts[0])
+					fmt.Fprintf(cmd.ErrOrStderr(), "Warning: Supplied message code '%s' is an unknown message code and will not have any effect.\n", code)
 				}
-				suppressions = append(suppressions, local.AnalysisSuppression{
-					Code:         parts[0],
-					ResourceName: parts[1],
-				})
 			}
-			sa.SetSuppressions(suppressions)
 
-			// If we're using kube, use that as a base source.
-			if useKube {
-				clients, err := getClients(ctx)
-				if err != nil {
-					return err
+			// newAnalyzer returns an analyzer of the live cluster, if used, with the files applied.
+			newAnalyzer := func(readers []local.ReaderSource) (*local.IstiodAnalyzer, int, error) {
+				sa := local.NewIstiodAnalyzer(analysis.Combine("all", allAnalyzers...),
+					resource.Namespace(selectedNamespace),
+					resource.Namespace(ctx.IstioNamespace()), nil)
+				sa.SetSuppressions(suppressions)
+				sa.SetSeverities(severities)
+
+				// If we're using kube, use that as a base source.
+				if useKube {
+					clients, err := getClients(ctx)
+					if err != nil {
+						return nil, 0, err
+					}
+					for _, c := range clients {
+						k := kube.EnableCrdWatcher(c.client)
+						sa.AddRunningKubeSourceWithRevision(k, revisionSpecified, c.remote)
+					}
 				}
-				for _, c := range clients {
-					k := kube.EnableCrdWatcher(c.client)
-					sa.AddRunningKubeSourceWithRevision(k, revisionSpecified, c.remote)
+
+				// The resources of an archive replace those of the live cluster, and its mesh config the default one.
+				parseErrors := 0
+				if dump != nil {
+					if err := sa.AddClusterDumpKubeSource(dump.readers()); err != nil {
+						fmt.Fprintf(cmd.ErrOrStderr(), "Error(s) adding the archive: %v", err)
+						parseErrors++
+					}
+					if dump.istioConfigMap != nil {
+						if err := sa.AddKubeMeshConfigMap(dump.istioConfigMap); err != nil {
+							fmt.Fprintf(cmd.ErrOrStderr(), "Warning: ignoring the mesh config of the archive: %v\n", err)
+						}
+					}
 				}
-			}
 
-			// If we explicitly specify mesh config, use it.
-			// This takes precedence over default mesh config or mesh config from a running Kube instance.
-			if meshCfgFile != "" {
-				_ = sa.AddFileKubeMeshConfig(meshCfgFile)
-			}
+				// If we explicitly specify mesh config, use it.
+				// This takes precedence over default mesh config or mesh config from a running Kube instance.
+				if meshCfgFile != "" {
+					_ = sa.AddFileKubeMeshConfig(meshCfgFile)
+				}
 
-			// If we're not using kube (files only), add defaults for some resources we expect to be provided by Istio
-			if !useKube {
-				err := sa.AddDefaultResources()
-				if err != nil {
-					return err
+				// If we're not using kube (files only), add defaults for some resources we expect to be provided by Istio
+				if !useKube && dump == nil {
+					err := sa.AddDefaultResources()
+					if err != nil {
+						return nil, 0, err
+					}
 				}
-			}
 
-			// If files are provided, treat them (collectively) as a source.
-			parseErrors := 0
-			if len(readers) > 0 {
-				if err = sa.AddReaderKubeSource(readers); err != nil {
-					fmt.Fprintf(cmd.ErrOrStderr(), "Error(s) adding files: %v", err)
-					parseErrors++
+				// If files are provided, treat them (collectively) as a source.
+				if len(readers) > 0 {
+					if err := sa.AddReaderKubeSource(readers); err != nil {
+						fmt.Fprintf(cmd.ErrOrStderr(), "Error(s) adding files: %v", err)
+						parseErrors++
+					}
 				}
+				return sa, parseErrors, nil
+			}
+			sa, parseErrors, err := newAnalyzer(readers)
+			if err != nil {
+				return err
+			}
+
+			var changes *watchedChanges
+			if watch {
+				changes = watchChanges(sa)
 			}
 
 			// Do the analysis
@@ -239,6 +390,9 @@ func Analyze(ctx cli.Context) *cobra.Command {
 			if err != nil {
 				return err
 			}
+			if err := pluginsError(plugins); err != nil {
+				return err
+			}
 
 			// Maybe output details about which analyzers ran
 			if verbose {
@@ -259,6 +413,47 @@ func Analyze(ctx cli.Context) *cobra.Command {
 				fmt.Fprintln(cmd.ErrOrStderr())
 			}
 
+			analyzed := result.Messages
+
+			// Only the findings which are introduced by the change from the base are reported, and considered for the
+			// exit code
+			if changesOnly {
+				baseReaders, err := gatherFiles(cmd, baseFiles)
+				if err != nil {
+					return err
+				}
+				base, _, err := newAnalyzer(baseReaders)
+				if err != nil {
+					return err
+				}
+				baseResult, err := base.Analyze(cancel)
+				if err != nil {
+					return fmt.Errorf("failed to analyze the base: %v", err)
+				}
+				baseFindings := make([]baselineFinding, 0, len(baseResult.Messages))
+				for _, m := range baseResult.Messages {
+					baseFindings = append(baseFindings, findingOf(m))
+				}
+				var resolved []baselineFinding
+				result.Messages, resolved = compareBaseline(result.Messages, baseFindings)
+				for _, f := range resolved {
+					fmt.Fprintf(cmd.ErrOrStderr(), "Resolved by the change: %s\n", f)
+				}
+			}
+
+			// Only the findings which are new since the baseline are reported, and considered for the exit code
+			if baselineFile != "" {
+				baseline, err := readBaseline(baselineFile)
+				if err != nil {
+					return err
+				}
+				var resolved []baselineFinding
+				result.Messages, resolved = compareBaseline(result.Messages, baseline)
+				for _, f := range resolved {
+					fmt.Fprintf(cmd.ErrOrStderr(), "Resolved since the baseline: %s\n", f)
+				}
+			}
+
 			// Get messages for output
 			outputMessages := result.Messages.SetDocRef("istioctl-analyze").FilterOutLowerThan(outputThreshold.Level)
 
@@ -271,7 +466,13 @@ func Analyze(ctx cli.Context) *cobra.Command {
 
 			// An extra message on success
 			if len(outputMessages) == 0 {
-				if parseErrors == 0 {
+				if parseErrors == 0 && changesOnly {
+					fmt.Fprintf(cmd.ErrOrStderr(), "\u2714 No new validation issues introduced by the change when analyzing %s.\n",
+						analyzeTargetAsString())
+				} else if parseErrors == 0 && baselineFile != "" {
+					fmt.Fprintf(cmd.ErrOrStderr(), "\u2714 No new validation issues found when analyzing %s compared to the baseline %s.\n",
+						analyzeTargetAsString(), baselineFile)
+				} else if parseErrors == 0 {
 					if len(readers) > 0 {
 						var files []string
 						for _, r := range readers {
@@ -299,6 +500,18 @@ func Analyze(ctx cli.Context) *cobra.Command {
 				}
 			}
 
+			if watch {
+				fmt.Fprintln(cmd.ErrOrStderr(), "Watching for changes, press Ctrl+C to stop.")
+				signals := make(chan os.Signal, 1)
+				signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+				go func() {
+					<-signals
+					close(cancel)
+				}()
+				watchAnalysis(sa, plugins, analyzed, changes, cmd.OutOrStdout(), cancel)
+				return nil
+			}
+
 			// Return code is based on the unfiltered validation message list/parse errors
 			// We're intentionally keeping failure threshold and output threshold decoupled for now
 			var returnError error
@@ -313,7 +526,8 @@ func Analyze(ctx cli.Context) *cobra.Command {
 	}
 
 	analysisCmd.PersistentFlags().BoolVarP(&listAnalyzers, "list-analyzers", "L", false,
-		"List the analyzers available to run. Suppresses normal execution.")
+		"List the analyzers available to run. Suppresses normal execution. With --output json or yaml, "+
+			"the analyzers are listed with the messages they report.")
 	analysisCmd.PersistentFlags().BoolVarP(&useKube, "use-kube", "k", true,
 		"Use live Kubernetes cluster for analysis. Set --use-kube=false to analyze files only.")
 	analysisCmd.PersistentFlags().BoolVar(&colorize, "color", formatting.IstioctlColorDefault(analysisCmd.OutOrStdout()),
@@ -334,6 +548,12 @@ func Analyze(ctx cli.Context) *cobra.Command {
 		"Suppress reporting a message code on a specific resource. Values are supplied in the form "+
 			`<code>=<resource> (e.g. '--suppress "IST0102=DestinationRule primary-dr.default"'). Can be repeated. `+
 			`You can include the wildcard character '*' to support a partial match (e.g. '--suppress "IST0102=DestinationRule *.default" ).`)
+	analysisCmd.PersistentFlags().StringVar(&suppressionsPath, "suppressions-file", "",
+		"A YAML file of accepted findings to suppress, as a list of 'suppressions' with a 'code' and a 'resource' as with "+
+			"--suppress, and optionally a 'reason' and an 'expires' date (YYYY-MM-DD) from which the suppression no longer applies.")
+	analysisCmd.PersistentFlags().StringVar(&severitiesPath, "severities-file", "",
+		"A YAML file of 'severities' mapping message codes to the level their messages are reported at: error, warning, "+
+			"info or ignore (e.g. 'IST0118: error' to fail on IST0118 with the default --failure-threshold).")
 	analysisCmd.PersistentFlags().DurationVar(&analysisTimeout, "timeout", 30*time.Second,
 		"The duration to wait before failing")
 	analysisCmd.PersistentFlags().BoolVarP(&recursive, "recursive", "R", false,
@@ -342,6 +562,26 @@ func Analyze(ctx cli.Context) *cobra.Command {
 		"Don't complain about un-parseable input documents, for cases where analyze should run only on k8s compliant inputs.")
 	analysisCmd.PersistentFlags().StringVarP(&revisionSpecified, "revision", "r", "default",
 		"analyze a specific revision deployed.")
+	analysisCmd.PersistentFlags().StringVar(&baselineFile, "baseline", "",
+		"The output of a previous analysis with --output json or yaml. Only findings which are new since then are reported "+
+			"and cause a failure exit code; findings which are resolved since then are listed.")
+	analysisCmd.PersistentFlags().BoolVar(&changesOnly, "changes-only", false,
+		"Analyze the configuration before the change as well, the live cluster without the files or with the files of --base, "+
+			"and report only the findings introduced by the change, listing those it resolves.")
+	analysisCmd.PersistentFlags().StringArrayVar(&baseFiles, "base", []string{},
+		"A file or directory of the configuration before the change, implying --changes-only. Can be repeated.")
+	analysisCmd.PersistentFlags().StringArrayVar(&pluginPaths, "plugin", []string{},
+		"An executable implementing an additional analyzer, run with the 'metadata' argument to describe the analyzer, "+
+			"then with the 'analyze' argument to report messages about the resources it is given as JSON on stdin. Can be repeated.")
+	analysisCmd.PersistentFlags().BoolVar(&watch, "watch", false,
+		"Keep watching the live cluster after the analysis, and print the findings which are new or resolved as resources "+
+			"change, until interrupted.")
+	analysisCmd.PersistentFlags().StringVar(&archivePath, "archive", "",
+		"Analyze the resources of a bug report or cluster dump, either its tarball or the directory it was extracted to, instead "+
+			"of the live cluster. Files are analyzed as applied to the archived resources.")
+	analysisCmd.PersistentFlags().StringSliceVar(&startupImages, "startup-images", nil,
+		"Image name fragments of containers known to make network calls as soon as they start, such as database migration tools, "+
+			"in addition to the built-in ones, for the analysis of the proxy startup ordering. Can be repeated.")
 	analysisCmd.PersistentFlags().StringArrayVar(&remoteContexts, "remote-contexts", []string{},
 		`Kubernetes configuration contexts for remote clusters to be used in multi-cluster analysis. Not to be confused with '--context'. `+
 			"If unspecified, contexts are read from the remote secrets in the cluster.")
@@ -355,7 +595,7 @@ func gatherFiles(cmd *cobra.Command, args []string) ([]local.ReaderSource, error
 
 		// Handle "-" as stdin as a special case.
 		if f == "-" {
-			if isatty.IsTerminal(os.Stdin.Fd()) && !isJSONorYAMLOutputFormat() {
+			if isatty.IsTerminal(os.Stdin.Fd()) && !isStructuredOutputFormat() {
 				fmt.Fprint(cmd.OutOrStdout(), "Reading from stdin:\n")
 			}
 			r = os.Stdin
@@ -481,6 +721,26 @@ func AnalyzersAsString(analyzers []analysis.Analyzer) string {
 	return b.String()
 }
 
+// AnalyzersAsCatalog returns the catalog of the analyzers and of the messages they report, in the JSON or YAML format.
+func AnalyzersAsCatalog(analyzers []analysis.Analyzer, format string) (string, error) {
+	catalog := analysis.Catalog(analyzers...)
+	var out []byte
+	var err error
+	switch format {
+	case formatting.JSONFormat:
+		out, err = json.MarshalIndent(catalog, "", "  ")
+		out = append(out, '\n')
+	case formatting.YAMLFormat:
+		out, err = yaml.Marshal(catalog)
+	default:
+		return "", fmt.Errorf("invalid format, expected %s or %s but got %q", formatting.JSONFormat, formatting.YAMLFormat, format)
+	}
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
 func analyzeTargetAsString() string {
 	if allNamespaces {
 		return "all namespaces"
@@ -489,8 +749,9 @@ func analyzeTargetAsString() string {
 }
 
 // TODO: Refactor output writer so that it is smart enough to know when to output what.
-func isJSONorYAMLOutputFormat() bool {
-	return msgOutputFormat == formatting.JSONFormat || msgOutputFormat == formatting.YAMLFormat
+func isStructuredOutputFormat() bool {
+	return msgOutputFormat == formatting.JSONFormat || msgOutputFormat == formatting.YAMLFormat ||
+		msgOutputFormat == formatting.SARIFFormat
 }
 
 type Client struct {
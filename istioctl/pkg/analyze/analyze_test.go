@@ -15,14 +15,32 @@
 package analyze
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"istio.io/istio/istioctl/pkg/cli"
 	"istio.io/istio/istioctl/pkg/util/testutil"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/analysis"
 	"istio.io/istio/pkg/config/analysis/diag"
+	"istio.io/istio/pkg/config/analysis/local"
+	"istio.io/istio/pkg/config/resource"
+	"istio.io/istio/pkg/config/schema/gvk"
+	"istio.io/istio/pkg/kube"
+	"istio.io/istio/pkg/test"
+	"istio.io/istio/tools/bug-report/pkg/archive"
 )
 
 func TestErrorOnIssuesFound(t *testing.T) {
@@ -77,3 +95,331 @@ func TestSkipPodsInFiles(t *testing.T) {
 	analyze := Analyze(cli.NewFakeContext(nil))
 	testutil.VerifyOutput(t, analyze, c)
 }
+
+func TestChangesOnly(t *testing.T) {
+	cases := []testutil.TestCase{
+		{
+			// The findings of the base are resolved, and those of the new VirtualService fail the analysis.
+			Args: strings.Split("-A --use-kube=false --base testdata/analyze-changes/base testdata/analyze-changes/head", " "),
+			ExpectedRegexp: regexp.MustCompile(`(?s)Resolved by the change: Error \[IST0101\] \(VirtualService default/reviews\) .*` +
+				`Error \[IST0101\] \(VirtualService default/ratings testdata/analyze-changes/head/virtualservices.yaml:23\)`),
+			WantException: true,
+		},
+		{
+			Args:           strings.Split("-A --use-kube=false --base testdata/analyze-changes/base testdata/analyze-changes/fixed", " "),
+			ExpectedRegexp: regexp.MustCompile(`No new validation issues introduced by the change`),
+		},
+		{
+			Args:           strings.Split("-A --use-kube=false --changes-only testdata/analyze-changes/head", " "),
+			ExpectedRegexp: regexp.MustCompile(`--changes-only without a live cluster requires the files of the base with --base`),
+			WantException:  true,
+		},
+	}
+	for _, c := range cases {
+		t.Run(strings.Join(c.Args, " "), func(t *testing.T) {
+			testutil.VerifyOutput(t, Analyze(cli.NewFakeContext(nil)), c)
+		})
+	}
+}
+
+func TestAnalyzeArchive(t *testing.T) {
+	g := NewWithT(t)
+
+	var warnings bytes.Buffer
+	dump, err := readClusterDump("testdata/analyze-archive", "istio-system", "default", &warnings)
+	g.Expect(err).To(BeNil())
+	g.Expect(warnings.String()).To(BeEmpty())
+	// The proxy logs are skipped.
+	g.Expect(dump.files).To(HaveLen(2))
+	g.Expect(dump.istioConfigMap).To(HaveKeyWithValue("mesh", "rootNamespace: istio-system"))
+
+	archivePath := filepath.Join(t.TempDir(), "bug-report.tar.gz")
+	g.Expect(archive.Create("testdata/analyze-archive", archivePath)).To(Succeed())
+	cases := []testutil.TestCase{
+		{
+			// The pods of the archive are analyzed, unlike those of files.
+			Args: strings.Split("-A --archive "+archivePath, " "),
+			ExpectedRegexp: regexp.MustCompile(`(?s)Error \[IST0101\] \(VirtualService default/reviews bug-report/cluster/crs:\d+\) ` +
+				`Referenced gateway not found: "bookinfo-gateway".*` +
+				`Warning \[IST0103\] \(Pod default/reviews-v1-5d9cf6b4c9-x7k2p bug-report/cluster/k8s-resources:\d+\)`),
+			WantException: true,
+		},
+		{
+			Args:           strings.Split("-A --use-kube=true --archive "+archivePath, " "),
+			ExpectedRegexp: regexp.MustCompile(`--archive cannot be combined with --use-kube`),
+			WantException:  true,
+		},
+	}
+	for _, c := range cases {
+		t.Run(strings.Join(c.Args, " "), func(t *testing.T) {
+			testutil.VerifyOutput(t, Analyze(cli.NewFakeContext(nil)), c)
+		})
+	}
+}
+
+func TestCompareBaseline(t *testing.T) {
+	g := NewWithT(t)
+
+	kept := diag.NewMessage(diag.NewMessageType(diag.Warning, "A1", "Template: %q"), nil, "kept")
+	fixed := diag.NewMessage(diag.NewMessageType(diag.Error, "B1", "Template: %q"), nil, "fixed")
+	added := diag.NewMessage(diag.NewMessageType(diag.Error, "B1", "Template: %q"), nil, "added")
+
+	js, err := json.Marshal([]*diag.Message{&kept, &fixed})
+	g.Expect(err).To(BeNil())
+	path := filepath.Join(t.TempDir(), "baseline.json")
+	g.Expect(os.WriteFile(path, js, 0o644)).To(Succeed())
+	baseline, err := readBaseline(path)
+	g.Expect(err).To(BeNil())
+
+	newMsgs, resolved := compareBaseline(diag.Messages{kept, added}, baseline)
+	g.Expect(newMsgs).To(Equal(diag.Messages{added}))
+	g.Expect(resolved).To(HaveLen(1))
+	g.Expect(resolved[0].String()).To(Equal(`Error [B1] Template: "fixed"`))
+	g.Expect(errorIfMessagesExceedThreshold(newMsgs)).To(BeIdenticalTo(AnalyzerFoundIssuesError{}))
+
+	newMsgs, resolved = compareBaseline(diag.Messages{kept}, baseline)
+	g.Expect(newMsgs).To(BeEmpty())
+	g.Expect(resolved).To(HaveLen(1))
+	g.Expect(errorIfMessagesExceedThreshold(newMsgs)).To(BeNil())
+}
+
+func TestPluginAnalyzer(t *testing.T) {
+	g := NewWithT(t)
+
+	// The plugin reports the gateways exposing all hosts, which it finds with a naive match of its input.
+	plugin := filepath.Join(t.TempDir(), "acme-lint")
+	g.Expect(os.WriteFile(plugin, []byte(`#!/bin/sh
+case "$1" in
+metadata)
+  echo '{"name": "acme.WildcardHosts", "description": "Rejects wildcard hosts", "inputs": [{"apiVersion": "networking.istio.io/v1", "kind": "Gateway"}]}'
+  ;;
+analyze)
+  if grep -q '"\*"' -; then
+    echo '{"messages": [{"code": "ACME0001", "level": "Warning", "message": "wildcard hosts are not allowed",
+      "resource": {"apiVersion": "networking.istio.io/v1", "kind": "Gateway", "namespace": "istio-system", "name": "public-gateway"}},
+      {"code": "ACME0002", "level": "Warning", "message": "gateway is retired",
+      "resource": {"apiVersion": "networking.istio.io/v1", "kind": "Gateway", "namespace": "istio-system", "name": "retired-gateway"}}]}'
+  else
+    echo '{"messages": []}'
+  fi
+  ;;
+*)
+  echo "unknown operation $1" >&2
+  exit 1
+esac
+`), 0o755)).To(Succeed())
+
+	plugins, err := loadPlugins([]string{plugin})
+	g.Expect(err).To(BeNil())
+	g.Expect(plugins[0].Metadata().Name).To(Equal("acme.WildcardHosts"))
+
+	analyze := Analyze(cli.NewFakeContext(nil))
+	var out strings.Builder
+	analyze.SetOut(&out)
+	analyze.SetErr(&out)
+	analyze.SetArgs(strings.Split("-A --use-kube=false -o json --plugin "+plugin+" testdata/analyze-file/public-gateway.yaml", " "))
+	g.Expect(analyze.Execute()).To(Succeed())
+	g.Expect(out.String()).To(ContainSubstring(`"code": "ACME0001"`))
+	g.Expect(out.String()).To(ContainSubstring(`"origin": "Gateway istio-system/public-gateway"`))
+	// Resources which are not analyzed are reported by the kind and name given by the plugin.
+	g.Expect(out.String()).To(ContainSubstring(`"origin": "Gateway istio-system/retired-gateway"`))
+
+	// The messages about them can be suppressed as any other.
+	analyze = Analyze(cli.NewFakeContext(nil))
+	out.Reset()
+	analyze.SetOut(&out)
+	analyze.SetErr(&out)
+	analyze.SetArgs(strings.Split("-A --use-kube=false -o json --suppress ACME0002=Gateway*retired-gateway --plugin "+plugin+
+		" testdata/analyze-file/public-gateway.yaml", " "))
+	g.Expect(analyze.Execute()).To(Succeed())
+	g.Expect(out.String()).To(ContainSubstring(`"code": "ACME0001"`))
+	g.Expect(out.String()).NotTo(ContainSubstring(`"code": "ACME0002"`))
+
+	// Plugins which cannot describe themselves fail the analysis.
+	broken := filepath.Join(t.TempDir(), "broken")
+	g.Expect(os.WriteFile(broken, []byte("#!/bin/sh\necho '{\"name\": \"broken\", \"inputs\": [{\"apiVersion\": \"acme.io/v1\", \"kind\": \"Widget\"}]}'\n"),
+		0o755)).To(Succeed())
+	_, err = loadPlugins([]string{broken})
+	g.Expect(err).To(MatchError(ContainSubstring("unknown kind acme.io/v1 Widget")))
+}
+
+func TestReadSuppressionsFile(t *testing.T) {
+	g := NewWithT(t)
+
+	path := filepath.Join(t.TempDir(), "suppressions.yaml")
+	g.Expect(os.WriteFile(path, []byte(`suppressions:
+- code: IST0102
+  resource: "Namespace legacy-*"
+  reason: Legacy namespaces are migrated in Q3
+  expires: 2024-10-01
+- code: IST0118
+  resource: "Service reviews.default"
+- code: IST0101
+  resource: "VirtualService *"
+  expires: 2024-06-01
+`), 0o644)).To(Succeed())
+
+	now := time.Date(2024, 6, 1, 9, 0, 0, 0, time.UTC)
+	suppressions, expired, err := readSuppressionsFile(path, now)
+	g.Expect(err).To(BeNil())
+	g.Expect(suppressions).To(Equal([]local.AnalysisSuppression{
+		{Code: "IST0102", ResourceName: "Namespace legacy-*"},
+		{Code: "IST0118", ResourceName: "Service reviews.default"},
+	}))
+	g.Expect(expired).To(HaveLen(1))
+	g.Expect(expired[0].String()).To(Equal("IST0101=VirtualService *"))
+
+	g.Expect(os.WriteFile(path, []byte("suppressions:\n- code: IST0102\n"), 0o644)).To(Succeed())
+	_, _, err = readSuppressionsFile(path, now)
+	g.Expect(err).To(MatchError(ContainSubstring("requires a code and a resource")))
+
+	g.Expect(os.WriteFile(path, []byte("suppressions:\n- code: IST0102\n  resource: '*'\n  expires: next week\n"), 0o644)).To(Succeed())
+	_, _, err = readSuppressionsFile(path, now)
+	g.Expect(err).To(MatchError(ContainSubstring("invalid expiry date")))
+}
+
+func TestReadSeveritiesFile(t *testing.T) {
+	g := NewWithT(t)
+
+	path := filepath.Join(t.TempDir(), "severities.yaml")
+	g.Expect(os.WriteFile(path, []byte(`severities:
+  IST0118: error
+  IST0102: ignore
+  IST0103: warn
+  IST0107: Info
+`), 0o644)).To(Succeed())
+
+	severities, err := readSeveritiesFile(path)
+	g.Expect(err).To(BeNil())
+	g.Expect(severities).To(Equal([]local.AnalysisSeverity{
+		{Code: "IST0102", Ignore: true},
+		{Code: "IST0103", Level: diag.Warning},
+		{Code: "IST0107", Level: diag.Info},
+		{Code: "IST0118", Level: diag.Error},
+	}))
+
+	g.Expect(os.WriteFile(path, []byte("severities:\n  IST0118: fatal\n"), 0o644)).To(Succeed())
+	_, err = readSeveritiesFile(path)
+	g.Expect(err).To(MatchError(ContainSubstring(`severity "fatal" of IST0118`)))
+}
+
+// labeledNamespaceAnalyzer reports the namespaces labeled broken=true.
+type labeledNamespaceAnalyzer struct{}
+
+func (a *labeledNamespaceAnalyzer) Metadata() analysis.Metadata {
+	return analysis.Metadata{Name: "labeledNamespaceAnalyzer", Inputs: []config.GroupVersionKind{gvk.Namespace}}
+}
+
+func (a *labeledNamespaceAnalyzer) Analyze(c analysis.Context) {
+	c.ForEach(gvk.Namespace, func(r *resource.Instance) bool {
+		if r.Metadata.Labels["broken"] == "true" {
+			c.Report(gvk.Namespace, diag.NewMessage(diag.NewMessageType(diag.Warning, "B1", "Broken: %s"), r, r.Metadata.FullName.Name))
+		}
+		return true
+	})
+}
+
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestWatchAnalysis(t *testing.T) {
+	g := NewWithT(t)
+	stop := test.NewStop(t)
+
+	client := kube.NewFakeClient()
+	namespaces := client.Kube().CoreV1().Namespaces()
+	_, err := namespaces.Create(context.TODO(), &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{Name: "legacy", Labels: map[string]string{"broken": "true"}},
+	}, metav1.CreateOptions{})
+	g.Expect(err).To(BeNil())
+
+	sa := local.NewIstiodAnalyzer(analysis.Combine("test", &labeledNamespaceAnalyzer{}), "", "istio-system", nil)
+	sa.AddRunningKubeSource(client)
+	changes := watchChanges(sa)
+	result, err := sa.Analyze(stop)
+	g.Expect(err).To(BeNil())
+	g.Expect(result.Messages).To(HaveLen(1))
+
+	out := &syncBuffer{}
+	go watchAnalysis(sa, nil, result.Messages, changes, out, stop)
+
+	_, err = namespaces.Create(context.TODO(), &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{Name: "payments", Labels: map[string]string{"broken": "true"}},
+	}, metav1.CreateOptions{})
+	g.Expect(err).To(BeNil())
+	g.Expect(namespaces.Delete(context.TODO(), "legacy", metav1.DeleteOptions{})).To(Succeed())
+
+	g.Eventually(out.String, 10*time.Second).Should(And(
+		ContainSubstring(` New: Warning [B1] (Namespace payments) Broken: payments`),
+		ContainSubstring(` Resolved: Warning [B1] (Namespace legacy) Broken: legacy`),
+	))
+}
+
+func TestWatchAnalysisPluginFailure(t *testing.T) {
+	g := NewWithT(t)
+	stop := test.NewStop(t)
+
+	// The plugin fails on the namespaces named broken, and reports the ones named payments.
+	plugin := filepath.Join(t.TempDir(), "acme-namespaces")
+	g.Expect(os.WriteFile(plugin, []byte(`#!/bin/sh
+case "$1" in
+metadata)
+  echo '{"name": "acme.Namespaces", "inputs": [{"apiVersion": "v1", "kind": "Namespace"}]}'
+  ;;
+analyze)
+  in=$(cat)
+  case "$in" in
+  *'"broken"'*)
+    echo "cannot analyze namespace broken" >&2
+    exit 1
+    ;;
+  *'"payments"'*)
+    echo '{"messages": [{"code": "ACME0003", "level": "Warning", "message": "payments namespace",
+      "resource": {"apiVersion": "v1", "kind": "Namespace", "name": "payments"}}]}'
+    ;;
+  *)
+    echo '{"messages": []}'
+    ;;
+  esac
+  ;;
+esac
+`), 0o755)).To(Succeed())
+	plugins, err := loadPlugins([]string{plugin})
+	g.Expect(err).To(BeNil())
+
+	client := kube.NewFakeClient()
+	namespaces := client.Kube().CoreV1().Namespaces()
+	sa := local.NewIstiodAnalyzer(analysis.Combine("test", plugins[0]), "", "istio-system", nil)
+	sa.AddRunningKubeSource(client)
+	changes := watchChanges(sa)
+	result, err := sa.Analyze(stop)
+	g.Expect(err).To(BeNil())
+	g.Expect(pluginsError(plugins)).To(BeNil())
+
+	out := &syncBuffer{}
+	go watchAnalysis(sa, plugins, result.Messages, changes, out, stop)
+
+	_, err = namespaces.Create(context.TODO(), &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "broken"}}, metav1.CreateOptions{})
+	g.Expect(err).To(BeNil())
+	g.Eventually(out.String, 10*time.Second).Should(ContainSubstring("Analysis failed: analyzer plugin acme.Namespaces failed"))
+
+	// The failure is cleared once the plugin succeeds again.
+	g.Expect(namespaces.Delete(context.TODO(), "broken", metav1.DeleteOptions{})).To(Succeed())
+	_, err = namespaces.Create(context.TODO(), &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "payments"}}, metav1.CreateOptions{})
+	g.Expect(err).To(BeNil())
+	g.Eventually(out.String, 10*time.Second).Should(ContainSubstring(" New: Warning [ACME0003] (Namespace payments)"))
+}
@@ -0,0 +1,194 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package analyze
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	kubeyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/yaml"
+
+	"istio.io/istio/pkg/config/analysis/local"
+	"istio.io/istio/pkg/slices"
+)
+
+// bugReportResourceFiles are the files of a bug report holding cluster resources, which have no extension.
+var bugReportResourceFiles = []string{"k8s-resources", "crs", "secrets"}
+
+// clusterDump is the snapshot of the resources of a cluster, read from a bug report or cluster dump archive.
+type clusterDump struct {
+	files []archivedFile
+	// istioConfigMap is the data of the istio ConfigMap of the revision analyzed, nil if not archived.
+	istioConfigMap map[string]string
+}
+
+// archivedFile holds the resources of a file of the archive, as yaml documents.
+type archivedFile struct {
+	name string
+	yaml []byte
+}
+
+// readers returns a reader of each file of the dump. They can be called for more than one analysis.
+func (d *clusterDump) readers() []local.ReaderSource {
+	readers := make([]local.ReaderSource, 0, len(d.files))
+	for _, f := range d.files {
+		readers = append(readers, local.ReaderSource{Name: f.name, Reader: bytes.NewReader(f.yaml)})
+	}
+	return readers
+}
+
+// readClusterDump reads the resources of a bug report or cluster dump, either a tarball, optionally gzipped, or the
+// directory it was extracted to. Files of the archive which are not resources, such as logs, are skipped, as well as
+// the files which cannot be parsed, after a warning.
+func readClusterDump(path, istioNamespace, revision string, warnings io.Writer) (*clusterDump, error) {
+	configMapName := "istio"
+	if revision != "" && revision != "default" {
+		configMapName = "istio-" + revision
+	}
+	dump := &clusterDump{}
+	add := func(name string, r io.Reader) {
+		if !isResourceFile(name) {
+			return
+		}
+		objects, err := readResources(r)
+		if err != nil {
+			fmt.Fprintf(warnings, "Skipping %s of the archive, which could not be parsed: %v\n", name, err)
+			return
+		}
+		var b bytes.Buffer
+		for _, obj := range objects {
+			if obj.GetKind() == "ConfigMap" && obj.GetNamespace() == istioNamespace && obj.GetName() == configMapName {
+				dump.istioConfigMap, _, _ = unstructured.NestedStringMap(obj.Object, "data")
+			}
+			y, err := yaml.Marshal(obj.Object)
+			if err != nil {
+				fmt.Fprintf(warnings, "Skipping %s %s/%s of %s: %v\n", obj.GetKind(), obj.GetNamespace(), obj.GetName(), name, err)
+				continue
+			}
+			b.WriteString("---\n")
+			b.Write(y)
+		}
+		if b.Len() > 0 {
+			dump.files = append(dump.files, archivedFile{name: name, yaml: b.Bytes()})
+		}
+	}
+
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if fi.IsDir() {
+		err := filepath.Walk(path, func(p string, info os.FileInfo, err error) error {
+			if err != nil || !info.Mode().IsRegular() {
+				return err
+			}
+			f, err := os.Open(p)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			add(p, f)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		return dump, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	br := bufio.NewReader(f)
+	var r io.Reader = br
+	if magic, err := br.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the archive %s: %v", path, err)
+		}
+		defer gz.Close()
+		r = gz
+	}
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the archive %s: %v", path, err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		add(header.Name, tr)
+	}
+	return dump, nil
+}
+
+// isResourceFile returns whether the file of an archive may hold resources: the resource files of a bug report, and
+// the yaml and json files of cluster dumps.
+func isResourceFile(name string) bool {
+	return slices.Contains(bugReportResourceFiles, filepath.Base(name)) || isValidFile(name)
+}
+
+// readResources returns the resources of the yaml or json documents, with the items of lists as resources of their own.
+// The items of the lists dumped by kubectl cluster-info dump have no kind, which is then the one of the list.
+func readResources(r io.Reader) ([]*unstructured.Unstructured, error) {
+	var objects []*unstructured.Unstructured
+	decoder := kubeyaml.NewYAMLOrJSONDecoder(r, 512*1024)
+	for {
+		obj := &unstructured.Unstructured{}
+		err := decoder.Decode(&obj.Object)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(obj.Object) == 0 {
+			continue
+		}
+		if !obj.IsList() {
+			objects = append(objects, obj)
+			continue
+		}
+		list, err := obj.ToList()
+		if err != nil {
+			return nil, err
+		}
+		itemKind := strings.TrimSuffix(obj.GetKind(), "List")
+		for i := range list.Items {
+			item := &list.Items[i]
+			if item.GetKind() == "" && itemKind != "" {
+				item.SetKind(itemKind)
+				item.SetAPIVersion(obj.GetAPIVersion())
+			}
+			objects = append(objects, item)
+		}
+	}
+	return objects, nil
+}
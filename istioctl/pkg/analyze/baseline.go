@@ -0,0 +1,100 @@
+// Copyright Istio Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package analyze
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"sigs.k8s.io/yaml"
+
+	"istio.io/istio/pkg/config/analysis/diag"
+	"istio.io/istio/pkg/util/sets"
+)
+
+// baselineFinding is a message of a previous analysis, as printed with --output json or yaml. Findings are compared by
+// code, origin and message; the reference is left out as the line numbers of files change with unrelated edits.
+type baselineFinding struct {
+	Code    string `json:"code"`
+	Level   string `json:"level,omitempty"`
+	Origin  string `json:"origin,omitempty"`
+	Message string `json:"message"`
+}
+
+func (f baselineFinding) key() baselineFinding {
+	f.Level = ""
+	return f
+}
+
+func (f baselineFinding) String() string {
+	origin := ""
+	if f.Origin != "" {
+		origin = " (" + f.Origin + ")"
+	}
+	return fmt.Sprintf("%s [%s]%s %s", f.Level, f.Code, origin, f.Message)
+}
+
+func findingOf(m diag.Message) baselineFinding {
+	f := baselineFinding{
+		Code:    m.Type.Code(),
+		Level:   m.Type.Level().String(),
+		Message: fmt.Sprintf(m.Type.Template(), m.Parameters...),
+	}
+	if m.Resource != nil {
+		f.Origin = m.Resource.Origin.FriendlyName()
+	}
+	return f
+}
+
+// readBaseline reads the findings of a previous analysis from its JSON or YAML output.
+func readBaseline(path string) ([]baselineFinding, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the baseline: %v", err)
+	}
+	var findings []baselineFinding
+	// YAML is a superset of JSON, so both output formats can be read the same way.
+	if err := yaml.Unmarshal(b, &findings); err != nil {
+		return nil, fmt.Errorf("failed to parse the baseline %s, expected the output of analyze -o json or -o yaml: %v", path, err)
+	}
+	return findings, nil
+}
+
+// compareBaseline returns the messages which are not findings of the baseline, and the findings of the baseline which
+// are no longer reported.
+func compareBaseline(messages diag.Messages, baseline []baselineFinding) (diag.Messages, []baselineFinding) {
+	known := sets.New[baselineFinding]()
+	for _, f := range baseline {
+		known.Insert(f.key())
+	}
+	current := sets.New[baselineFinding]()
+	added := diag.Messages{}
+	for _, m := range messages {
+		f := findingOf(m).key()
+		current.Insert(f)
+		if !known.Contains(f) {
+			added = append(added, m)
+		}
+	}
+	var resolved []baselineFinding
+	for _, f := range baseline {
+		if !current.Contains(f.key()) {
+			resolved = append(resolved, f)
+		}
+	}
+	sort.SliceStable(resolved, func(i, j int) bool { return resolved[i].String() < resolved[j].String() })
+	return added, resolved
+}
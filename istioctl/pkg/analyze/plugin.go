@@ -0,0 +1,264 @@
+// Copyright Istio Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package analyze
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/analysis"
+	"istio.io/istio/pkg/config/analysis/diag"
+	"istio.io/istio/pkg/config/analysis/legacy/source/kube"
+	"istio.io/istio/pkg/config/resource"
+	"istio.io/istio/pkg/config/schema/collections"
+)
+
+// pluginTimeout bounds each invocation of an analyzer plugin.
+const pluginTimeout = time.Minute
+
+// Analyzer plugins are executables run with a single argument, the operation:
+//
+//	metadata  prints the pluginMetadata of the analyzer as JSON.
+//	analyze   reads a pluginRequest as JSON on stdin, and prints a pluginResponse as JSON.
+//
+// A plugin failing, by exiting with a non-zero status, fails the analysis with its stderr.
+
+type pluginMetadata struct {
+	Name        string              `json:"name"`
+	Description string              `json:"description,omitempty"`
+	Inputs      []pluginResourceRef `json:"inputs"`
+}
+
+// pluginResourceRef identifies a kind of resource, and a resource with a name.
+type pluginResourceRef struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Namespace  string `json:"namespace,omitempty"`
+	Name       string `json:"name,omitempty"`
+}
+
+type pluginRequest struct {
+	Resources []pluginResource `json:"resources"`
+}
+
+type pluginResource struct {
+	APIVersion string             `json:"apiVersion"`
+	Kind       string             `json:"kind"`
+	Metadata   pluginResourceMeta `json:"metadata"`
+	Spec       json.RawMessage    `json:"spec,omitempty"`
+}
+
+type pluginResourceMeta struct {
+	Name        string            `json:"name"`
+	Namespace   string            `json:"namespace,omitempty"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+type pluginResponse struct {
+	Messages []pluginMessage `json:"messages"`
+}
+
+type pluginMessage struct {
+	Code     string             `json:"code"`
+	Level    string             `json:"level"`
+	Message  string             `json:"message"`
+	Resource *pluginResourceRef `json:"resource,omitempty"`
+}
+
+// pluginAnalyzer runs an analyzer plugin as part of the analysis. As analyzers cannot fail, the error of the plugin is
+// kept, to be checked once the analysis completes.
+type pluginAnalyzer struct {
+	path     string
+	metadata analysis.Metadata
+	err      error
+}
+
+var _ analysis.Analyzer = &pluginAnalyzer{}
+
+// loadPlugins returns the analyzers of the plugins at the given paths.
+func loadPlugins(paths []string) ([]*pluginAnalyzer, error) {
+	plugins := make([]*pluginAnalyzer, 0, len(paths))
+	for _, path := range paths {
+		p, err := loadPlugin(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load analyzer plugin %s: %v", path, err)
+		}
+		plugins = append(plugins, p)
+	}
+	return plugins, nil
+}
+
+func loadPlugin(path string) (*pluginAnalyzer, error) {
+	out, err := runPlugin(path, "metadata", nil)
+	if err != nil {
+		return nil, err
+	}
+	var md pluginMetadata
+	if err := json.Unmarshal(out, &md); err != nil {
+		return nil, fmt.Errorf("invalid metadata: %v", err)
+	}
+	if md.Name == "" {
+		return nil, fmt.Errorf("invalid metadata: no name")
+	}
+	if len(md.Inputs) == 0 {
+		return nil, fmt.Errorf("invalid metadata: no inputs")
+	}
+	p := &pluginAnalyzer{path: path, metadata: analysis.Metadata{Name: md.Name, Description: md.Description}}
+	for _, in := range md.Inputs {
+		gvk, err := pluginKind(in)
+		if err != nil {
+			return nil, fmt.Errorf("invalid metadata: %v", err)
+		}
+		p.metadata.Inputs = append(p.metadata.Inputs, gvk)
+	}
+	return p, nil
+}
+
+// pluginKind returns the kind of the resources the reference is about, which must be known to the analysis.
+func pluginKind(ref pluginResourceRef) (config.GroupVersionKind, error) {
+	gvk := config.FromKubernetesGVK(schema.FromAPIVersionAndKind(ref.APIVersion, ref.Kind))
+	s, ok := collections.All.FindByGroupVersionAliasesKind(gvk)
+	if !ok {
+		return config.GroupVersionKind{}, fmt.Errorf("unknown kind %s %s", ref.APIVersion, ref.Kind)
+	}
+	return s.GroupVersionKind(), nil
+}
+
+// pluginsError returns the error of the first plugin which failed in the last analysis.
+func pluginsError(plugins []*pluginAnalyzer) error {
+	for _, p := range plugins {
+		if p.err != nil {
+			return p.err
+		}
+	}
+	return nil
+}
+
+func (p *pluginAnalyzer) Metadata() analysis.Metadata {
+	return p.metadata
+}
+
+func (p *pluginAnalyzer) Analyze(ctx analysis.Context) {
+	// The analyzer runs again for each change in watch mode, where a failure is only kept until the next run.
+	p.err = nil
+	req := pluginRequest{Resources: []pluginResource{}}
+	for _, in := range p.metadata.Inputs {
+		ctx.ForEach(in, func(r *resource.Instance) bool {
+			res, err := pluginResourceOf(in, r)
+			if err != nil {
+				p.err = err
+				return false
+			}
+			req.Resources = append(req.Resources, res)
+			return true
+		})
+	}
+	if p.err != nil {
+		return
+	}
+	out, err := runPlugin(p.path, "analyze", req)
+	if err != nil {
+		p.err = fmt.Errorf("analyzer plugin %s failed: %v", p.metadata.Name, err)
+		return
+	}
+	var resp pluginResponse
+	if err := json.Unmarshal(out, &resp); err != nil {
+		p.err = fmt.Errorf("analyzer plugin %s returned an invalid response: %v", p.metadata.Name, err)
+		return
+	}
+	levels := diag.GetUppercaseStringToLevelMap()
+	for _, m := range resp.Messages {
+		level, ok := levels[strings.ToUpper(m.Level)]
+		if !ok || m.Code == "" {
+			p.err = fmt.Errorf("analyzer plugin %s returned an invalid message: code %q, level %q", p.metadata.Name, m.Code, m.Level)
+			return
+		}
+		// Messages are reported against the resource they are about, so that they are filtered and suppressed as
+		// the messages of the built-in analyzers are.
+		gvk := p.metadata.Inputs[0]
+		var instance *resource.Instance
+		if m.Resource != nil {
+			if gvk, err = pluginKind(*m.Resource); err != nil {
+				p.err = fmt.Errorf("analyzer plugin %s returned an invalid message: %v", p.metadata.Name, err)
+				return
+			}
+			name := resource.NewFullName(resource.Namespace(m.Resource.Namespace), resource.LocalName(m.Resource.Name))
+			instance = ctx.Find(gvk, name)
+			if instance == nil {
+				// The resource is not among the ones analyzed, such as one the plugin looked up itself: it is reported
+				// by the kind and name given, still allowing to suppress the message.
+				instance = &resource.Instance{
+					Metadata: resource.Metadata{FullName: name},
+					Origin:   &kube.Origin{Type: gvk, FullName: name},
+				}
+			}
+		}
+		ctx.Report(gvk, diag.NewMessage(diag.NewMessageType(level, m.Code, "%s"), instance, m.Message))
+	}
+}
+
+func pluginResourceOf(gvk config.GroupVersionKind, r *resource.Instance) (pluginResource, error) {
+	res := pluginResource{
+		APIVersion: gvk.GroupVersion(),
+		Kind:       gvk.Kind,
+		Metadata: pluginResourceMeta{
+			Name:        r.Metadata.FullName.Name.String(),
+			Namespace:   r.Metadata.FullName.Namespace.String(),
+			Labels:      r.Metadata.Labels,
+			Annotations: r.Metadata.Annotations,
+		},
+	}
+	if r.Message != nil {
+		spec, err := config.ToJSON(r.Message)
+		if err != nil {
+			return pluginResource{}, fmt.Errorf("failed to encode %s %s: %v", gvk.Kind, r.Metadata.FullName, err)
+		}
+		res.Spec = spec
+	}
+	return res, nil
+}
+
+// runPlugin runs the operation of the plugin, with the input as JSON on stdin, and returns its stdout.
+func runPlugin(path, operation string, input any) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), pluginTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, path, operation)
+	if input != nil {
+		in, err := json.Marshal(input)
+		if err != nil {
+			return nil, err
+		}
+		cmd.Stdin = bytes.NewReader(in)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%v: %s", err, msg)
+		}
+		return nil, err
+	}
+	return stdout.Bytes(), nil
+}
@@ -0,0 +1,67 @@
+// Copyright Istio Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package analyze
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+
+	"istio.io/istio/pkg/config/analysis/diag"
+	"istio.io/istio/pkg/config/analysis/local"
+)
+
+// severitiesFile maps message codes to the severity their messages are reported at, one of error, warning (or warn),
+// info or ignore, such as:
+//
+//	severities:
+//	  IST0118: error
+//	  IST0102: ignore
+type severitiesFile struct {
+	Severities map[string]string `json:"severities"`
+}
+
+// readSeveritiesFile returns the severity overrides of the file, ordered by code.
+func readSeveritiesFile(path string) ([]local.AnalysisSeverity, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the severities file: %v", err)
+	}
+	var f severitiesFile
+	if err := yaml.UnmarshalStrict(b, &f); err != nil {
+		return nil, fmt.Errorf("failed to parse the severities file %s: %v", path, err)
+	}
+	levels := diag.GetUppercaseStringToLevelMap()
+	levels["WARN"] = diag.Warning
+	var severities []local.AnalysisSeverity
+	for code, severity := range f.Severities {
+		s := local.AnalysisSeverity{Code: code}
+		if strings.EqualFold(severity, "ignore") {
+			s.Ignore = true
+		} else if level, ok := levels[strings.ToUpper(severity)]; ok {
+			s.Level = level
+		} else {
+			return nil, fmt.Errorf("severity %q of %s in %s is not one of error, warning, info or ignore", severity, code, path)
+		}
+		severities = append(severities, s)
+	}
+	sort.Slice(severities, func(i, j int) bool {
+		return severities[i].Code < severities[j].Code
+	})
+	return severities, nil
+}
@@ -0,0 +1,82 @@
+// Copyright Istio Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package analyze
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"sigs.k8s.io/yaml"
+
+	"istio.io/istio/pkg/config/analysis/local"
+)
+
+// suppressionsFile is a list of accepted findings, typically checked in next to the configuration, such as:
+//
+//	suppressions:
+//	- code: IST0102
+//	  resource: "Namespace legacy-*"
+//	  reason: Legacy namespaces are migrated in Q3
+//	  expires: 2024-10-01
+type suppressionsFile struct {
+	Suppressions []suppressionEntry `json:"suppressions"`
+}
+
+type suppressionEntry struct {
+	// Code is the code of the suppressed messages.
+	Code string `json:"code"`
+	// Resource matches the resources of the suppressed messages, as with --suppress.
+	Resource string `json:"resource"`
+	// Reason documents why the findings are accepted.
+	Reason string `json:"reason,omitempty"`
+	// Expires is the date, as YYYY-MM-DD, from which the suppression no longer applies.
+	Expires string `json:"expires,omitempty"`
+}
+
+func (e suppressionEntry) String() string {
+	return fmt.Sprintf("%s=%s", e.Code, e.Resource)
+}
+
+// readSuppressionsFile returns the suppressions of the file which still apply, and the entries which expired.
+func readSuppressionsFile(path string, now time.Time) ([]local.AnalysisSuppression, []suppressionEntry, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read the suppressions file: %v", err)
+	}
+	var f suppressionsFile
+	if err := yaml.UnmarshalStrict(b, &f); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse the suppressions file %s: %v", path, err)
+	}
+	var suppressions []local.AnalysisSuppression
+	var expired []suppressionEntry
+	for i, e := range f.Suppressions {
+		if e.Code == "" || e.Resource == "" {
+			return nil, nil, fmt.Errorf("suppression %d of %s requires a code and a resource", i+1, path)
+		}
+		if e.Expires != "" {
+			expires, err := time.ParseInLocation(time.DateOnly, e.Expires, now.Location())
+			if err != nil {
+				return nil, nil, fmt.Errorf("suppression %s of %s has an invalid expiry date: %v", e, path, err)
+			}
+			if !now.Before(expires) {
+				expired = append(expired, e)
+				continue
+			}
+		}
+		suppressions = append(suppressions, local.AnalysisSuppression{Code: e.Code, ResourceName: e.Resource})
+	}
+	return suppressions, expired, nil
+}
@@ -0,0 +1,115 @@
+// Copyright Istio Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package analyze
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/analysis/diag"
+	"istio.io/istio/pkg/config/analysis/local"
+	"istio.io/istio/pkg/util/concurrent"
+	"istio.io/istio/pkg/util/sets"
+)
+
+const (
+	// watchDebounceMin is how long the resources must be left unchanged before they are analyzed again.
+	watchDebounceMin = time.Second
+	// watchDebounceMax is how long changes to the resources can delay their analysis at most.
+	watchDebounceMax = 10 * time.Second
+)
+
+// watchedChanges are the kinds of the resources changed since the last analysis.
+type watchedChanges struct {
+	ch chan config.GroupVersionKind
+
+	mu sync.Mutex
+	// overflow are the kinds which did not fit in ch. As ch is full then, they are analyzed with the kinds still in ch.
+	overflow sets.Set[config.GroupVersionKind]
+}
+
+// take returns the kinds changed which overflowed, in addition to kinds.
+func (c *watchedChanges) take(kinds sets.Set[config.GroupVersionKind]) sets.Set[config.GroupVersionKind] {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	kinds = kinds.Union(c.overflow)
+	c.overflow = sets.New[config.GroupVersionKind]()
+	return kinds
+}
+
+// watchChanges registers for the changes to the resources of the analyzer, which must be done before its sources run.
+func watchChanges(sa *local.IstiodAnalyzer) *watchedChanges {
+	changes := &watchedChanges{ch: make(chan config.GroupVersionKind, 10), overflow: sets.New[config.GroupVersionKind]()}
+	for _, s := range sa.Schemas().All() {
+		sa.RegisterEventHandler(s.GroupVersionKind(), func(oldcfg config.Config, newcfg config.Config, ev model.Event) {
+			kind := oldcfg.GroupVersionKind
+			if (kind == config.GroupVersionKind{}) {
+				kind = newcfg.GroupVersionKind
+			}
+			// Only the analyzers of the kinds changed are run again, so no kind changed may be lost, but the informers
+			// must not be blocked either.
+			select {
+			case changes.ch <- kind:
+			default:
+				changes.mu.Lock()
+				changes.overflow.Insert(kind)
+				changes.mu.Unlock()
+			}
+		})
+	}
+	return changes
+}
+
+// watchAnalysis analyzes the resources again whenever they change, and prints the findings which are new or resolved
+// since the previous analysis, until stop is closed. Only the analyzers of the kinds changed are run again. An analysis
+// in which a plugin fails is reported as failed, as the findings of the plugin are missing from it.
+func watchAnalysis(sa *local.IstiodAnalyzer, plugins []*pluginAnalyzer, previous diag.Messages, changes *watchedChanges,
+	w io.Writer, stop <-chan struct{},
+) {
+	previous = previous.FilterOutLowerThan(outputThreshold.Level)
+	db := concurrent.Debouncer[config.GroupVersionKind]{}
+	db.Run(changes.ch, stop, watchDebounceMin, watchDebounceMax, func(kinds sets.Set[config.GroupVersionKind]) {
+		result, err := sa.ReAnalyzeIncremental(changes.take(kinds), stop)
+		if err == nil {
+			err = pluginsError(plugins)
+		}
+		if err != nil {
+			fmt.Fprintf(w, "%s Analysis failed: %v\n", time.Now().Format(time.RFC3339), err)
+			return
+		}
+		current := result.Messages.FilterOutLowerThan(outputThreshold.Level)
+		previousFindings := make([]baselineFinding, 0, len(previous))
+		for _, m := range previous {
+			previousFindings = append(previousFindings, findingOf(m))
+		}
+		added, resolved := compareBaseline(current, previousFindings)
+		printWatchUpdate(w, time.Now(), added, resolved)
+		previous = current
+	})
+}
+
+func printWatchUpdate(w io.Writer, now time.Time, added diag.Messages, resolved []baselineFinding) {
+	timestamp := now.Format(time.RFC3339)
+	for _, m := range added {
+		fmt.Fprintf(w, "%s New: %s\n", timestamp, findingOf(m))
+	}
+	for _, f := range resolved {
+		fmt.Fprintf(w, "%s Resolved: %s\n", timestamp, f)
+	}
+}
@@ -0,0 +1,230 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package blastradius
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	klabels "k8s.io/apimachinery/pkg/labels"
+
+	networking "istio.io/api/networking/v1alpha3"
+	typev1beta1 "istio.io/api/type/v1beta1"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/schema/gvk"
+	"istio.io/istio/pkg/util/sets"
+)
+
+// Risk is the overall risk of a config change.
+type Risk string
+
+const (
+	RiskLow    Risk = "LOW"
+	RiskMedium Risk = "MEDIUM"
+	RiskHigh   Risk = "HIGH"
+)
+
+// Proxy is a proxy of the mesh which may receive a push.
+type Proxy struct {
+	Name      string
+	Namespace string
+	Labels    map[string]string
+	// Gateway is whether the proxy is a gateway rather than a sidecar.
+	Gateway bool
+}
+
+// Flow is live traffic to a host affected by a change.
+type Flow struct {
+	Source      string  `json:"source"`
+	Destination string  `json:"destination"`
+	RPS         float64 `json:"rps"`
+}
+
+// Impact is the impact of a single config object of the change.
+type Impact struct {
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	// Scope describes the proxies the object applies to.
+	Scope    string   `json:"scope"`
+	Proxies  int      `json:"proxies"`
+	Hosts    []string `json:"hosts,omitempty"`
+	Routes   []string `json:"routes,omitempty"`
+	Clusters []string `json:"clusters,omitempty"`
+	Flows    []Flow   `json:"flows,omitempty"`
+
+	affected sets.String
+}
+
+// Report is the blast radius of a config change.
+type Report struct {
+	Changes         []Impact `json:"changes"`
+	TotalProxies    int      `json:"totalProxies"`
+	AffectedProxies int      `json:"affectedProxies"`
+	// AffectedRPS is the rate of the live requests to the hosts of the change, when telemetry is available.
+	AffectedRPS *float64 `json:"affectedRPS,omitempty"`
+	Risk        Risk     `json:"risk"`
+	Reasons     []string `json:"reasons"`
+}
+
+// Compute returns the proxies, routes and clusters affected by each config object of the change.
+func Compute(changes []config.Config, proxies []Proxy, rootNamespace string) Report {
+	r := Report{TotalProxies: len(proxies)}
+	affected := sets.New[string]()
+	for _, c := range changes {
+		impact := computeImpact(c, proxies, rootNamespace)
+		affected.Merge(impact.affected)
+		r.Changes = append(r.Changes, impact)
+	}
+	r.AffectedProxies = affected.Len()
+	return r
+}
+
+func computeImpact(c config.Config, proxies []Proxy, rootNamespace string) Impact {
+	impact := Impact{Kind: c.GroupVersionKind.Kind, Namespace: c.Namespace, Name: c.Name}
+	// By default, proxies in the namespace of the object, or all proxies for objects of the root namespace.
+	visible := func(p Proxy) bool { return c.Namespace == rootNamespace || p.Namespace == c.Namespace }
+	impact.Scope = "namespace " + c.Namespace
+	if c.Namespace == rootNamespace {
+		impact.Scope = "all namespaces (root namespace)"
+	}
+	var selector map[string]string
+	gatewaysOnly, sidecarsOnly := false, false
+
+	switch spec := c.Spec.(type) {
+	case *networking.VirtualService:
+		visible, impact.Scope = exportedTo(c.Namespace, spec.ExportTo)
+		impact.Hosts = spec.Hosts
+		for _, h := range spec.Hosts {
+			impact.Routes = append(impact.Routes, h+":*")
+		}
+		// Without gateways, virtual services only apply to sidecars, through the reserved "mesh" gateway.
+		gateways := sets.New(spec.Gateways...)
+		if gateways.IsEmpty() {
+			gateways.Insert("mesh")
+		}
+		switch {
+		case !gateways.Contains("mesh"):
+			gatewaysOnly = true
+			impact.Scope += ", gateways " + strings.Join(sets.SortedList(gateways), ",")
+		case gateways.Len() == 1:
+			sidecarsOnly = true
+		}
+	case *networking.DestinationRule:
+		visible, impact.Scope = exportedTo(c.Namespace, spec.ExportTo)
+		impact.Hosts = []string{spec.Host}
+		impact.Clusters = []string{fmt.Sprintf("outbound|*||%s", spec.Host)}
+		for _, s := range spec.Subsets {
+			impact.Clusters = append(impact.Clusters, fmt.Sprintf("outbound|*|%s|%s", s.Name, spec.Host))
+		}
+		selector = spec.GetWorkloadSelector().GetMatchLabels()
+	case *networking.ServiceEntry:
+		visible, impact.Scope = exportedTo(c.Namespace, spec.ExportTo)
+		impact.Hosts = spec.Hosts
+		for _, h := range spec.Hosts {
+			for _, p := range spec.Ports {
+				impact.Clusters = append(impact.Clusters, fmt.Sprintf("outbound|%d||%s", p.Number, h))
+			}
+		}
+	case *networking.Gateway:
+		// Gateways select gateway proxies of any namespace.
+		visible = func(Proxy) bool { return true }
+		impact.Scope = "gateways selected by " + klabels.Set(spec.Selector).String()
+		selector = spec.Selector
+		gatewaysOnly = true
+		for _, s := range spec.Servers {
+			impact.Hosts = append(impact.Hosts, s.Hosts...)
+		}
+	default:
+		switch s := c.Spec.(type) {
+		case interface {
+			GetSelector() *typev1beta1.WorkloadSelector
+		}:
+			selector = s.GetSelector().GetMatchLabels()
+		case interface {
+			GetWorkloadSelector() *networking.WorkloadSelector
+		}:
+			selector = s.GetWorkloadSelector().GetLabels()
+		}
+	}
+	if len(selector) > 0 && c.GroupVersionKind != gvk.Gateway {
+		impact.Scope += ", workloads selected by " + klabels.Set(selector).String()
+	}
+
+	impact.affected = sets.New[string]()
+	for _, p := range proxies {
+		if !visible(p) || (gatewaysOnly && !p.Gateway) || (sidecarsOnly && p.Gateway) {
+			continue
+		}
+		if len(selector) > 0 && !klabels.SelectorFromSet(selector).Matches(klabels.Set(p.Labels)) {
+			continue
+		}
+		impact.affected.Insert(p.Namespace + "/" + p.Name)
+	}
+	impact.Proxies = impact.affected.Len()
+	return impact
+}
+
+// exportedTo returns whether proxies see an object of the namespace with the exportTo, and describes them.
+func exportedTo(namespace string, exportTo []string) (func(Proxy) bool, string) {
+	if len(exportTo) == 0 || sets.New(exportTo...).Contains("*") {
+		return func(Proxy) bool { return true }, "all namespaces"
+	}
+	namespaces := sets.New[string]()
+	for _, e := range exportTo {
+		switch e {
+		case ".":
+			namespaces.Insert(namespace)
+		case "~":
+		default:
+			namespaces.Insert(e)
+		}
+	}
+	if namespaces.IsEmpty() {
+		return func(Proxy) bool { return false }, "no namespace"
+	}
+	return func(p Proxy) bool { return namespaces.Contains(p.Namespace) }, "namespaces " + strings.Join(sets.SortedList(namespaces), ",")
+}
+
+// AssessRisk sets the risk of the change from the share of proxies and the live traffic it affects.
+func AssessRisk(r *Report) {
+	r.Risk = RiskLow
+	r.Reasons = []string{}
+	raise := func(risk Risk, reason string) {
+		if risk == RiskHigh || r.Risk == RiskLow {
+			r.Risk = risk
+		}
+		r.Reasons = append(r.Reasons, reason)
+	}
+	if r.TotalProxies > 0 {
+		share := float64(r.AffectedProxies) / float64(r.TotalProxies)
+		switch {
+		case share > 0.5:
+			raise(RiskHigh, fmt.Sprintf("%d of %d proxies receive a push", r.AffectedProxies, r.TotalProxies))
+		case share > 0.1:
+			raise(RiskMedium, fmt.Sprintf("%d of %d proxies receive a push", r.AffectedProxies, r.TotalProxies))
+		}
+	}
+	if r.AffectedRPS != nil && *r.AffectedRPS > 0 {
+		raise(RiskMedium, fmt.Sprintf("%.2f requests per second to the hosts of the change", *r.AffectedRPS))
+	}
+	for _, c := range r.Changes {
+		if c.Kind == gvk.EnvoyFilter.Kind {
+			raise(RiskMedium, fmt.Sprintf("EnvoyFilter %s/%s patches the generated configuration directly", c.Namespace, c.Name))
+		}
+	}
+	sort.Strings(r.Reasons)
+}
@@ -0,0 +1,151 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package blastradius
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"istio.io/istio/pkg/ptr"
+	"istio.io/istio/pkg/test/util/assert"
+)
+
+const change = `apiVersion: networking.istio.io/v1
+kind: VirtualService
+metadata:
+  name: reviews
+spec:
+  hosts:
+  - reviews
+  exportTo:
+  - "."
+  http:
+  - route:
+    - destination:
+        host: reviews
+        subset: v2
+---
+apiVersion: networking.istio.io/v1
+kind: DestinationRule
+metadata:
+  name: reviews
+  namespace: istio-system
+spec:
+  host: reviews.bookinfo.svc.cluster.local
+  subsets:
+  - name: v2
+    labels:
+      version: v2
+---
+apiVersion: networking.istio.io/v1
+kind: Gateway
+metadata:
+  name: public
+  namespace: bookinfo
+spec:
+  selector:
+    istio: ingressgateway
+  servers:
+  - port:
+      number: 80
+      name: http
+      protocol: HTTP
+    hosts:
+    - bookinfo.example.com
+---
+apiVersion: security.istio.io/v1
+kind: AuthorizationPolicy
+metadata:
+  name: ratings
+  namespace: bookinfo
+spec:
+  selector:
+    matchLabels:
+      app: ratings
+`
+
+func TestCompute(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "change.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte(change), 0o644))
+	changes, err := readChanges([]string{path}, "bookinfo")
+	assert.NoError(t, err)
+
+	proxies := []Proxy{
+		{Name: "productpage", Namespace: "bookinfo", Labels: map[string]string{"app": "productpage"}},
+		{Name: "ratings", Namespace: "bookinfo", Labels: map[string]string{"app": "ratings"}},
+		{Name: "sleep", Namespace: "other", Labels: map[string]string{"app": "sleep"}},
+		{Name: "ingress", Namespace: "istio-system", Labels: map[string]string{"istio": "ingressgateway"}, Gateway: true},
+	}
+	r := Compute(changes, proxies, "istio-system")
+
+	get := func(kind string) Impact {
+		for _, c := range r.Changes {
+			if c.Kind == kind {
+				return c
+			}
+		}
+		t.Fatalf("no change of kind %s", kind)
+		return Impact{}
+	}
+	vs := get("VirtualService")
+	assert.Equal(t, vs.Namespace, "bookinfo")
+	assert.Equal(t, vs.Scope, "namespaces bookinfo")
+	assert.Equal(t, vs.Proxies, 2)
+	assert.Equal(t, vs.Routes, []string{"reviews:*"})
+
+	dr := get("DestinationRule")
+	assert.Equal(t, dr.Scope, "all namespaces")
+	assert.Equal(t, dr.Proxies, 4)
+	assert.Equal(t, dr.Clusters, []string{
+		"outbound|*||reviews.bookinfo.svc.cluster.local",
+		"outbound|*|v2|reviews.bookinfo.svc.cluster.local",
+	})
+
+	gw := get("Gateway")
+	assert.Equal(t, gw.Scope, "gateways selected by istio=ingressgateway")
+	assert.Equal(t, gw.Proxies, 1)
+
+	ap := get("AuthorizationPolicy")
+	assert.Equal(t, ap.Scope, "namespace bookinfo, workloads selected by app=ratings")
+	assert.Equal(t, ap.Proxies, 1)
+
+	assert.Equal(t, r.TotalProxies, 4)
+	assert.Equal(t, r.AffectedProxies, 4)
+	AssessRisk(&r)
+	assert.Equal(t, r.Risk, RiskHigh)
+	assert.Equal(t, r.Reasons, []string{"4 of 4 proxies receive a push"})
+}
+
+func TestAssessRisk(t *testing.T) {
+	r := Report{TotalProxies: 100, AffectedProxies: 5}
+	AssessRisk(&r)
+	assert.Equal(t, r.Risk, RiskLow)
+
+	r = Report{TotalProxies: 100, AffectedProxies: 5, AffectedRPS: ptr.Of(12.5)}
+	AssessRisk(&r)
+	assert.Equal(t, r.Risk, RiskMedium)
+	assert.Equal(t, r.Reasons, []string{"12.50 requests per second to the hosts of the change"})
+
+	r = Report{TotalProxies: 100, AffectedProxies: 5, Changes: []Impact{{Kind: "EnvoyFilter", Namespace: "foo", Name: "lua"}}}
+	AssessRisk(&r)
+	assert.Equal(t, r.Risk, RiskMedium)
+}
+
+func TestHostPattern(t *testing.T) {
+	assert.Equal(t, HostPattern("reviews", "bookinfo"), `reviews\.bookinfo\.svc\.cluster\.local`)
+	assert.Equal(t, HostPattern("*.example.com", "bookinfo"), `.*\.example\.com`)
+	assert.Equal(t, HostPattern("*", "bookinfo"), ".*")
+}
@@ -0,0 +1,299 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package blastradius
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/prometheus/client_golang/api"
+	promv1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	kubeyaml "k8s.io/apimachinery/pkg/util/yaml"
+
+	"istio.io/istio/istioctl/pkg/cli"
+	"istio.io/istio/istioctl/pkg/dashboard"
+	"istio.io/istio/pilot/pkg/config/kube/crd"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/constants"
+	"istio.io/istio/pkg/kube"
+	"istio.io/istio/pkg/kube/inject"
+	"istio.io/istio/pkg/slices"
+)
+
+func Cmd(ctx cli.Context) *cobra.Command {
+	var (
+		filenames    []string
+		telemetry    bool
+		duration     time.Duration
+		outputFormat string
+	)
+	cmd := &cobra.Command{
+		Use:   "blast-radius -f <file>",
+		Short: "Estimates the impact of an Istio config change before applying it",
+		Long: `Estimates the impact of applying Istio config objects, for change management records:
+
+  * the proxies which receive a push, from the namespace, exportTo, gateways and workload selector of each object
+  * the routes (virtual hosts) and clusters of the proxies which change
+  * the live traffic to the hosts of the change, from Prometheus metrics, when available
+
+and summarizes them as a LOW, MEDIUM or HIGH risk. The scope of the objects as given is used; an object replacing one
+with a wider scope also pushes to the proxies of the previous scope. Sidecar resources restricting the hosts proxies
+see are not considered, so the number of proxies is an upper bound.
+
+Telemetry requires a Prometheus pod labeled app.kubernetes.io/name=prometheus in the Istio namespace, and is skipped
+otherwise.`,
+		Example: `  # Estimate the impact of a change
+  istioctl experimental blast-radius -f change.yaml
+
+  # Record the estimate as JSON, without querying Prometheus
+  istioctl experimental blast-radius -f change.yaml --telemetry=false -o json`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(filenames) == 0 {
+				return errors.New("no config file specified (see --filename or -f)")
+			}
+			if outputFormat != "table" && outputFormat != "json" {
+				return fmt.Errorf("unknown output format %q, must be table or json", outputFormat)
+			}
+			changes, err := readChanges(filenames, ctx.NamespaceOrDefault(ctx.Namespace()))
+			if err != nil {
+				return err
+			}
+			kubeClient, err := ctx.CLIClient()
+			if err != nil {
+				return err
+			}
+			proxies, err := listProxies(kubeClient)
+			if err != nil {
+				return err
+			}
+			report := Compute(changes, proxies, ctx.IstioNamespace())
+			var telemetryErr error
+			if telemetry {
+				telemetryErr = addFlows(kubeClient, ctx.IstioNamespace(), &report, duration)
+			}
+			AssessRisk(&report)
+			if telemetry && telemetryErr != nil {
+				report.Reasons = append(report.Reasons, fmt.Sprintf("live traffic unknown: %v", telemetryErr))
+			}
+			return printReport(cmd.OutOrStdout(), report, outputFormat)
+		},
+	}
+	cmd.Flags().StringSliceVarP(&filenames, "filename", "f", nil, "Istio config files of the change")
+	cmd.Flags().BoolVar(&telemetry, "telemetry", true, "Query Prometheus for the live traffic to the hosts of the change")
+	cmd.Flags().DurationVar(&duration, "duration", 5*time.Minute, "Window of the live traffic rate")
+	cmd.Flags().StringVarP(&outputFormat, "output", "o", "table", "Output format: one of table|json")
+	return cmd
+}
+
+func readChanges(filenames []string, defaultNamespace string) ([]config.Config, error) {
+	var changes []config.Config
+	for _, f := range filenames {
+		b, err := os.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+		decoder := kubeyaml.NewYAMLOrJSONDecoder(bytes.NewReader(b), 512*1024)
+		for {
+			obj := &unstructured.Unstructured{}
+			err := decoder.Decode(&obj.Object)
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse %s: %v", f, err)
+			}
+			if len(obj.Object) == 0 {
+				continue
+			}
+			// The namespace is set before validation, which resolves exportTo "." against it.
+			if obj.GetNamespace() == "" {
+				obj.SetNamespace(defaultNamespace)
+			}
+			js, err := obj.MarshalJSON()
+			if err != nil {
+				return nil, err
+			}
+			configs, others, err := crd.ParseInputs(string(js))
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse %s %s in %s: %v", obj.GetKind(), obj.GetName(), f, err)
+			}
+			if len(others) > 0 {
+				return nil, fmt.Errorf("%s contains %s %s, which is not an Istio config object", f, obj.GetKind(), obj.GetName())
+			}
+			changes = append(changes, configs...)
+		}
+	}
+	return changes, nil
+}
+
+func listProxies(kubeClient kube.CLIClient) ([]Proxy, error) {
+	pods, err := kubeClient.Kube().CoreV1().Pods(metav1.NamespaceAll).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pods: %v", err)
+	}
+	var proxies []Proxy
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		proxy := inject.FindSidecar(pod)
+		if proxy == nil {
+			continue
+		}
+		proxies = append(proxies, Proxy{
+			Name:      pod.Name,
+			Namespace: pod.Namespace,
+			Labels:    pod.Labels,
+			Gateway:   slices.Contains(proxy.Args, "router"),
+		})
+	}
+	return proxies, nil
+}
+
+// addFlows adds the live traffic to the hosts of each change to the report.
+func addFlows(kubeClient kube.CLIClient, istioNamespace string, r *Report, duration time.Duration) error {
+	promAPI, closer, err := prometheusAPI(kubeClient, istioNamespace)
+	if err != nil {
+		return err
+	}
+	defer closer()
+	total := 0.0
+	for i := range r.Changes {
+		c := &r.Changes[i]
+		if len(c.Hosts) == 0 {
+			continue
+		}
+		patterns := make([]string, 0, len(c.Hosts))
+		for _, h := range c.Hosts {
+			patterns = append(patterns, HostPattern(h, c.Namespace))
+		}
+		query := fmt.Sprintf(`sum(rate(istio_requests_total{reporter="source",destination_service=~"%s"}[%s])) `+
+			`by (source_workload, source_workload_namespace, destination_service)`,
+			strings.Join(patterns, "|"), model.Duration(duration))
+		val, _, err := promAPI.Query(context.Background(), query, time.Now())
+		if err != nil {
+			return fmt.Errorf("query() failure for '%s': %v", query, err)
+		}
+		vector, ok := val.(model.Vector)
+		if !ok {
+			return errors.New("bad metric value type returned for query")
+		}
+		for _, s := range vector {
+			c.Flows = append(c.Flows, Flow{
+				Source:      string(s.Metric["source_workload"]) + "." + string(s.Metric["source_workload_namespace"]),
+				Destination: string(s.Metric["destination_service"]),
+				RPS:         float64(s.Value),
+			})
+			total += float64(s.Value)
+		}
+		sort.Slice(c.Flows, func(i, j int) bool { return c.Flows[i].RPS > c.Flows[j].RPS })
+	}
+	r.AffectedRPS = &total
+	return nil
+}
+
+// HostPattern returns the regular expression matching the destination_service label of the requests to a host of a
+// config object of the namespace. Short names are relative to the namespace.
+func HostPattern(host, namespace string) string {
+	if host == "*" {
+		return ".*"
+	}
+	if !strings.Contains(host, ".") {
+		host = fmt.Sprintf("%s.%s.svc.%s", host, namespace, constants.DefaultClusterLocalDomain)
+	}
+	if rest, ok := strings.CutPrefix(host, "*"); ok {
+		return ".*" + regexp.QuoteMeta(rest)
+	}
+	return regexp.QuoteMeta(host)
+}
+
+func prometheusAPI(kubeClient kube.CLIClient, istioNamespace string) (promv1.API, func(), error) {
+	pl, err := kubeClient.PodsForSelector(context.TODO(), istioNamespace, "app.kubernetes.io/name=prometheus")
+	if err != nil {
+		return nil, nil, fmt.Errorf("not able to locate Prometheus pod: %v", err)
+	}
+	if len(pl.Items) < 1 {
+		return nil, nil, errors.New("no Prometheus pods found")
+	}
+	fw, err := kubeClient.NewPortForwarder(pl.Items[0].Name, istioNamespace, "", 0, 9090)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not build port forwarder for prometheus: %v", err)
+	}
+	if err = fw.Start(); err != nil {
+		return nil, nil, fmt.Errorf("failure running port forward process: %v", err)
+	}
+	dashboard.ClosePortForwarderOnInterrupt(fw)
+	promClient, err := api.NewClient(api.Config{Address: fmt.Sprintf("http://%s", fw.Address())})
+	if err != nil {
+		fw.Close()
+		return nil, nil, fmt.Errorf("could not build prometheus client: %v", err)
+	}
+	return promv1.NewAPI(promClient), fw.Close, nil
+}
+
+func printReport(w io.Writer, r Report, outputFormat string) error {
+	if outputFormat == "json" {
+		out, err := json.MarshalIndent(r, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(out))
+		return err
+	}
+	for _, c := range r.Changes {
+		_, _ = fmt.Fprintf(w, "%s %s/%s\n", c.Kind, c.Namespace, c.Name)
+		_, _ = fmt.Fprintf(w, "  Scope:    %s\n", c.Scope)
+		_, _ = fmt.Fprintf(w, "  Proxies:  %d\n", c.Proxies)
+		if len(c.Routes) > 0 {
+			_, _ = fmt.Fprintf(w, "  Routes:   %s\n", strings.Join(c.Routes, ", "))
+		}
+		if len(c.Clusters) > 0 {
+			_, _ = fmt.Fprintf(w, "  Clusters: %s\n", strings.Join(c.Clusters, ", "))
+		}
+		if len(c.Flows) > 0 {
+			_, _ = fmt.Fprintln(w, "  Live traffic:")
+			tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+			for _, f := range c.Flows {
+				_, _ = fmt.Fprintf(tw, "    %s\t-> %s\t%.2f rps\n", f.Source, f.Destination, f.RPS)
+			}
+			if err := tw.Flush(); err != nil {
+				return err
+			}
+		}
+		_, _ = fmt.Fprintln(w)
+	}
+	_, _ = fmt.Fprintf(w, "Proxies receiving a push: %d of %d\n", r.AffectedProxies, r.TotalProxies)
+	if r.AffectedRPS != nil {
+		_, _ = fmt.Fprintf(w, "Live traffic affected:    %.2f rps\n", *r.AffectedRPS)
+	}
+	_, _ = fmt.Fprintf(w, "Risk:                     %s\n", r.Risk)
+	for _, reason := range r.Reasons {
+		_, _ = fmt.Fprintf(w, "  - %s\n", reason)
+	}
+	return nil
+}
@@ -18,6 +18,7 @@ import (
 	"fmt"
 	"io"
 	"sort"
+	"strings"
 	"text/tabwriter"
 
 	"github.com/spf13/cobra"
@@ -46,10 +47,20 @@ func Cmd() *cobra.Command {
 		Use:   "config SUBCOMMAND",
 		Short: "Configure istioctl defaults",
 		Args:  cobra.NoArgs,
+		Annotations: map[string]string{
+			ignoreMeshAnnotation: "",
+		},
 		Example: `  # list configuration parameters
-  istioctl experimental config list`,
+  istioctl experimental config list
+
+  # define the settings of the prod mesh, and use them as the defaults of istioctl flags
+  istioctl config set-mesh prod context=prod-cluster istioNamespace=istio-prod revision=1-24
+  istioctl config use-mesh prod`,
 	}
 	configCmd.AddCommand(listCommand())
+	configCmd.AddCommand(setMeshCommand())
+	configCmd.AddCommand(useMeshCommand())
+	configCmd.AddCommand(getMeshesCommand())
 	return configCmd
 }
 
@@ -79,17 +90,38 @@ func runList(writer io.Writer) error {
 	fmt.Fprintf(w, "FLAG\tVALUE\tFROM\n")
 	for _, flag := range keys {
 		v := settableFlags[flag]
-		fmt.Fprintf(w, "%s\t%s\t%v\n", flag, viper.GetString(flag), configSource(flag, v))
+		value := viper.GetString(flag)
+		if mv, ok := currentMeshSetting(flag); ok && !v.IsSet() {
+			value = fmt.Sprint(mv)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%v\n", flag, value, configSource(flag, v))
 	}
 	return w.Flush()
 }
 
+// currentMeshSetting returns the setting of the flag of the current mesh, if any.
+func currentMeshSetting(flag string) (any, bool) {
+	if CurrentMesh() == "" {
+		return nil, false
+	}
+	settings, err := meshSettings(CurrentMesh())
+	if err != nil {
+		return nil, false
+	}
+	v, ok := settings[strings.ToLower(flag)]
+	return v, ok
+}
+
 func configSource(flag string, v env.VariableInfo) string {
 	// Environment variables have high precedence in Viper
 	if v.IsSet() {
 		return "$" + v.GetName()
 	}
 
+	if _, ok := currentMeshSetting(flag); ok {
+		return fmt.Sprintf("mesh %s of %s", CurrentMesh(), root.IstioConfig)
+	}
+
 	if viper.InConfig(flag) {
 		return root.IstioConfig
 	}
@@ -16,13 +16,18 @@ package config
 
 import (
 	"fmt"
+	"path/filepath"
+	"regexp"
 	"strings"
 	"testing"
 
+	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 
+	"istio.io/istio/istioctl/pkg/root"
 	"istio.io/istio/istioctl/pkg/util/testutil"
 	"istio.io/istio/pkg/config/constants"
+	"istio.io/istio/pkg/test/util/assert"
 )
 
 func TestConfigList(t *testing.T) {
@@ -55,6 +60,51 @@ xds-port                15012            default
 	}
 }
 
+func TestMeshes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	defaultConfig := root.IstioConfig
+	root.IstioConfig = path
+	t.Cleanup(func() {
+		root.IstioConfig = defaultConfig
+		viper.Reset()
+		viper.SetDefault("istioNamespace", constants.IstioSystemNamespace)
+		viper.SetDefault("xds-port", 15012)
+	})
+
+	for _, c := range []testutil.TestCase{
+		{Args: strings.Split("set-mesh prod istioNamespace=istio-prod revision=1-24 set=hub=registry.example.com set=tag=1.24.0", " ")},
+		{Args: strings.Split("set-mesh staging revision=canary", " ")},
+		{Args: strings.Split("use-mesh dev", " "), ExpectedRegexp: regexp.MustCompile(`mesh "dev" is not defined`), WantException: true},
+		{Args: strings.Split("use-mesh prod", " ")},
+	} {
+		testutil.VerifyOutput(t, Cmd(), c)
+	}
+	viper.SetConfigFile(path)
+	if err := viper.ReadInConfig(); err != nil {
+		t.Fatal(err)
+	}
+	testutil.VerifyOutput(t, Cmd(), testutil.TestCase{
+		Args: []string{"get-meshes"},
+		ExpectedOutput: `CURRENT     NAME        SETTINGS
+*           prod        istionamespace=istio-prod,revision=1-24,set=[hub=registry.example.com tag=1.24.0]
+            staging     revision=canary
+`,
+	})
+
+	var istioNamespace, revision string
+	var set []string
+	cmd := &cobra.Command{Use: "install", Run: func(*cobra.Command, []string) {}}
+	cmd.Flags().StringVarP(&istioNamespace, "istioNamespace", "i", constants.IstioSystemNamespace, "")
+	cmd.Flags().StringVarP(&revision, "revision", "r", "", "")
+	cmd.Flags().StringArrayVarP(&set, "set", "s", nil, "")
+	assert.NoError(t, cmd.ParseFlags([]string{"--revision", "1-23"}))
+	assert.NoError(t, ApplyMeshDefaults(cmd))
+	assert.Equal(t, istioNamespace, "istio-prod")
+	// Flags set on the command line take precedence over the settings of the mesh.
+	assert.Equal(t, revision, "1-23")
+	assert.Equal(t, set, []string{"hub=registry.example.com", "tag=1.24.0"})
+}
+
 func init() {
 	viper.SetDefault("istioNamespace", constants.IstioSystemNamespace)
 	viper.SetDefault("xds-port", 15012)
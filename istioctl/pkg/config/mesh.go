@@ -0,0 +1,251 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"sigs.k8s.io/yaml"
+
+	"istio.io/istio/istioctl/pkg/root"
+)
+
+const (
+	// currentMeshKey is the key of the config file naming the mesh whose settings are used, overridden by
+	// $ISTIOCTL_CURRENT_MESH.
+	currentMeshKey = "current-mesh"
+	// meshesKey is the key of the config file holding the settings of each mesh, by name.
+	meshesKey = "meshes"
+
+	// ignoreMeshAnnotation marks the commands whose flags do not default to the settings of the current mesh, such as
+	// the commands changing the meshes, which have to work whatever the current mesh is.
+	ignoreMeshAnnotation = "istioctl.istio.io/ignore-mesh"
+)
+
+// CurrentMesh returns the name of the mesh whose settings are used, empty if none.
+func CurrentMesh() string {
+	return viper.GetString(currentMeshKey)
+}
+
+// meshSettings returns the settings of the mesh: flag values by flag name, lower cased by viper.
+func meshSettings(name string) (map[string]any, error) {
+	if !viper.IsSet(meshesKey + "." + name) {
+		return nil, fmt.Errorf("mesh %q is not defined in %s", name, root.IstioConfig)
+	}
+	return viper.GetStringMap(meshesKey + "." + name), nil
+}
+
+// ApplyMeshDefaults sets the flags of the command which are not set on the command line, nor by their environment
+// variable, to the settings of the current mesh. Settings of flags the command does not have are ignored.
+func ApplyMeshDefaults(cmd *cobra.Command) error {
+	name := CurrentMesh()
+	if name == "" {
+		return nil
+	}
+	for c := cmd; c != nil; c = c.Parent() {
+		if _, ok := c.Annotations[ignoreMeshAnnotation]; ok {
+			return nil
+		}
+	}
+	settings, err := meshSettings(name)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	cmd.Flags().VisitAll(func(f *pflag.Flag) {
+		v, ok := settings[strings.ToLower(f.Name)]
+		if !ok || f.Changed {
+			return
+		}
+		if _, ok := os.LookupEnv("ISTIOCTL_" + strings.ToUpper(strings.ReplaceAll(f.Name, "-", "_"))); ok {
+			return
+		}
+		values := []any{v}
+		if list, ok := v.([]any); ok {
+			values = list
+		}
+		for _, value := range values {
+			if err := f.Value.Set(fmt.Sprint(value)); err != nil {
+				errs = append(errs, fmt.Errorf("invalid setting %s of mesh %q: %v", f.Name, name, err))
+			}
+		}
+	})
+	return errors.Join(errs...)
+}
+
+func useMeshCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "use-mesh <name>",
+		Short: "Use the settings of a mesh as the defaults of istioctl flags",
+		Long: `Sets the current mesh of the istioctl config file. The settings of the current mesh are the defaults of the flags
+of the same name of every command, unless set on the command line or by their environment variable. Setting
+$ISTIOCTL_CURRENT_MESH overrides the current mesh.`,
+		Example: `  # Use the settings of the prod mesh
+  istioctl config use-mesh prod
+
+  # Stop using the settings of any mesh
+  istioctl config use-mesh ""`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(c *cobra.Command, args []string) error {
+			return updateConfigFile(func(cfg map[string]any) error {
+				if args[0] == "" {
+					delete(cfg, currentMeshKey)
+					return nil
+				}
+				meshes, _ := cfg[meshesKey].(map[string]any)
+				if _, ok := meshes[args[0]]; !ok {
+					return fmt.Errorf("mesh %q is not defined in %s, see istioctl config set-mesh", args[0], root.IstioConfig)
+				}
+				cfg[currentMeshKey] = args[0]
+				return nil
+			})
+		},
+	}
+}
+
+func setMeshCommand() *cobra.Command {
+	var unset []string
+	cmd := &cobra.Command{
+		Use:   "set-mesh <name> [<flag>=<value>...]",
+		Short: "Define the settings of a mesh",
+		Long: `Defines or updates the settings of a mesh in the istioctl config file: the defaults of the istioctl flags of the same
+name, such as istioNamespace, revision, context, manifests or output. A setting given more than once, like set, is a
+list of values.`,
+		Example: `  # Define the prod mesh, in the prod-cluster context, with its own Istio namespace and revision
+  istioctl config set-mesh prod context=prod-cluster istioNamespace=istio-prod revision=1-24
+
+  # Install the prod mesh from local charts with images of a private registry
+  istioctl config set-mesh prod manifests=/opt/istio/manifests set=hub=registry.example.com/istio
+
+  # Remove the revision setting of the prod mesh
+  istioctl config set-mesh prod --unset revision`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(c *cobra.Command, args []string) error {
+			settings := map[string]any{}
+			for _, arg := range args[1:] {
+				k, v, ok := strings.Cut(arg, "=")
+				if !ok || k == "" {
+					return fmt.Errorf("invalid setting %q, must be <flag>=<value>", arg)
+				}
+				switch existing := settings[k].(type) {
+				case nil:
+					settings[k] = v
+				case string:
+					settings[k] = []any{existing, v}
+				case []any:
+					settings[k] = append(existing, v)
+				}
+			}
+			return updateConfigFile(func(cfg map[string]any) error {
+				meshes, _ := cfg[meshesKey].(map[string]any)
+				if meshes == nil {
+					meshes = map[string]any{}
+				}
+				mesh, _ := meshes[args[0]].(map[string]any)
+				if mesh == nil {
+					mesh = map[string]any{}
+				}
+				for k, v := range settings {
+					mesh[k] = v
+				}
+				for _, k := range unset {
+					delete(mesh, k)
+				}
+				meshes[args[0]] = mesh
+				cfg[meshesKey] = meshes
+				return nil
+			})
+		},
+	}
+	cmd.Flags().StringSliceVar(&unset, "unset", nil, "Settings to remove from the mesh")
+	return cmd
+}
+
+func getMeshesCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "get-meshes",
+		Short: "List the meshes of the istioctl config file and their settings",
+		Args:  cobra.NoArgs,
+		RunE: func(c *cobra.Command, _ []string) error {
+			return runGetMeshes(c.OutOrStdout())
+		},
+	}
+}
+
+func runGetMeshes(writer io.Writer) error {
+	meshes := viper.GetStringMap(meshesKey)
+	names := make([]string, 0, len(meshes))
+	for name := range meshes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	w := new(tabwriter.Writer).Init(writer, 0, 8, 5, ' ', 0)
+	fmt.Fprintf(w, "CURRENT\tNAME\tSETTINGS\n")
+	for _, name := range names {
+		current := ""
+		if strings.EqualFold(name, CurrentMesh()) {
+			current = "*"
+		}
+		settings := viper.GetStringMap(meshesKey + "." + name)
+		keys := make([]string, 0, len(settings))
+		for k := range settings {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		pairs := make([]string, 0, len(keys))
+		for _, k := range keys {
+			pairs = append(pairs, fmt.Sprintf("%s=%v", k, settings[k]))
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", current, name, strings.Join(pairs, ","))
+	}
+	return w.Flush()
+}
+
+// updateConfigFile applies the update to the istioctl config file, created if missing.
+func updateConfigFile(update func(cfg map[string]any) error) error {
+	path := os.ExpandEnv(root.IstioConfig)
+	cfg := map[string]any{}
+	b, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := yaml.Unmarshal(b, &cfg); err != nil {
+		return fmt.Errorf("failed to parse %s: %v", path, err)
+	}
+	if cfg == nil {
+		cfg = map[string]any{}
+	}
+	if err := update(cfg); err != nil {
+		return err
+	}
+	out, err := yaml.Marshal(cfg)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, out, 0o600)
+}
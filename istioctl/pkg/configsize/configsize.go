@@ -0,0 +1,319 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configsize
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	route "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+
+	"istio.io/api/annotation"
+	"istio.io/istio/istioctl/pkg/cli"
+	"istio.io/istio/istioctl/pkg/completion"
+	"istio.io/istio/istioctl/pkg/precheck"
+	"istio.io/istio/istioctl/pkg/util/configdump"
+	"istio.io/istio/istioctl/pkg/util/formatting"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pkg/config/analysis/diag"
+	"istio.io/istio/pkg/config/analysis/msg"
+	"istio.io/istio/pkg/kube"
+)
+
+// budgetsConfigMapKey is the key of the budgets in the ConfigMap passed with --budgets-configmap.
+const budgetsConfigMapKey = "budgets"
+
+// Budgets declares limits on the size of the mesh configuration. Zero values are not enforced.
+type Budgets struct {
+	// MaxClustersPerProxy is the maximum number of clusters of a proxy.
+	MaxClustersPerProxy int `json:"maxClustersPerProxy,omitempty"`
+	// MaxListenersPerProxy is the maximum number of listeners of a proxy.
+	MaxListenersPerProxy int `json:"maxListenersPerProxy,omitempty"`
+	// MaxRoutesPerGateway is the maximum number of routes of a gateway, across all its route configurations.
+	MaxRoutesPerGateway int `json:"maxRoutesPerGateway,omitempty"`
+	// MaxEnvoyFiltersPerNamespace is the maximum number of EnvoyFilters in a namespace.
+	MaxEnvoyFiltersPerNamespace int `json:"maxEnvoyFiltersPerNamespace,omitempty"`
+	// Enforce makes the command fail when a budget is exceeded, rather than only warn.
+	Enforce bool `json:"enforce,omitempty"`
+}
+
+// ParseBudgets parses budgets from YAML, rejecting unknown fields.
+func ParseBudgets(b []byte) (*Budgets, error) {
+	budgets := &Budgets{}
+	if err := yaml.UnmarshalStrict(b, budgets); err != nil {
+		return nil, fmt.Errorf("invalid budgets: %v", err)
+	}
+	return budgets, nil
+}
+
+func Cmd(ctx cli.Context) *cobra.Command {
+	var budgetsFile, budgetsConfigMap string
+	var msgOutputFormat string
+	cmd := &cobra.Command{
+		Use:   "config-size [<pod-name>[.<namespace>]...]",
+		Short: "Check the size of the mesh configuration against budgets",
+		Long: `Checks the size of the mesh configuration against budgets declared for the mesh, such as the maximum number of
+clusters of a proxy, of routes of a gateway, or of EnvoyFilters in a namespace. The budgets are read from a file or
+from a ConfigMap in the Istio namespace, under the "budgets" key, so that a single policy can be shared by the teams
+of the mesh:
+
+  maxClustersPerProxy: 2000
+  maxListenersPerProxy: 200
+  maxRoutesPerGateway: 5000
+  maxEnvoyFiltersPerNamespace: 5
+  enforce: true
+
+Exceeded budgets are reported as warnings. When enforce is set, the command also fails, so that it can be used as a
+guardrail in CI pipelines. Proxies are checked from their config dump: the given pods, or all the proxies in the
+namespace, or in all namespaces when no namespace is set.`,
+		Example: `  # Check all the proxies and EnvoyFilters of the mesh against the budgets in a file
+  istioctl experimental config-size --budgets budgets.yaml
+
+  # Check the proxies of the foo namespace against the budgets of the mesh-budgets ConfigMap in istio-system
+  istioctl experimental config-size -n foo --budgets-configmap mesh-budgets
+
+  # Check a single gateway
+  istioctl experimental config-size istio-ingressgateway-5d8c9b8b9b-x2v4q.istio-system --budgets budgets.yaml`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if (budgetsFile == "") == (budgetsConfigMap == "") {
+				return errors.New("exactly one of --budgets or --budgets-configmap must be set")
+			}
+			kubeClient, err := ctx.CLIClient()
+			if err != nil {
+				return err
+			}
+			budgets, err := loadBudgets(kubeClient, ctx.IstioNamespace(), budgetsFile, budgetsConfigMap)
+			if err != nil {
+				return err
+			}
+
+			pods, err := proxyPods(ctx, kubeClient, args)
+			if err != nil {
+				return err
+			}
+			msgs := diag.Messages{}
+			for _, pod := range pods {
+				cd, err := configDump(kubeClient, pod.Name, pod.Namespace)
+				if err != nil {
+					return fmt.Errorf("failed to retrieve the configuration of %s.%s: %v", pod.Name, pod.Namespace, err)
+				}
+				m, err := CheckProxy(pod, cd, budgets)
+				if err != nil {
+					return fmt.Errorf("failed to check the configuration of %s.%s: %v", pod.Name, pod.Namespace, err)
+				}
+				msgs = append(msgs, m...)
+			}
+
+			if budgets.MaxEnvoyFiltersPerNamespace > 0 {
+				filters, err := kubeClient.Istio().NetworkingV1alpha3().EnvoyFilters(ctx.Namespace()).List(context.Background(), metav1.ListOptions{})
+				if err != nil {
+					return err
+				}
+				namespaces := make([]string, 0, len(filters.Items))
+				for _, f := range filters.Items {
+					namespaces = append(namespaces, f.Namespace)
+				}
+				msgs = append(msgs, CheckEnvoyFilters(namespaces, budgets)...)
+			}
+
+			if len(msgs) == 0 {
+				_, _ = fmt.Fprintln(cmd.ErrOrStderr(), color.New(color.FgGreen).Sprint("✔")+" No configuration size budget exceeded.")
+				return nil
+			}
+			output, err := formatting.Print(msgs.SortedDedupedCopy(), msgOutputFormat, true)
+			if err != nil {
+				return err
+			}
+			_, _ = fmt.Fprintln(cmd.OutOrStdout(), output)
+			if budgets.Enforce {
+				return fmt.Errorf("%d configuration size budgets exceeded", len(msgs))
+			}
+			return nil
+		},
+		ValidArgsFunction: completion.ValidPodsNameArgs(ctx),
+	}
+	cmd.Flags().StringVar(&budgetsFile, "budgets", "", "File declaring the configuration size budgets")
+	cmd.Flags().StringVar(&budgetsConfigMap, "budgets-configmap", "",
+		fmt.Sprintf("ConfigMap in the Istio namespace declaring the configuration size budgets under the %q key", budgetsConfigMapKey))
+	cmd.Flags().StringVarP(&msgOutputFormat, "output", "o", formatting.LogFormat,
+		fmt.Sprintf("Output format: one of %v", formatting.MsgOutputFormatKeys))
+	return cmd
+}
+
+func loadBudgets(kubeClient kube.CLIClient, istioNamespace, file, configMap string) (*Budgets, error) {
+	if file != "" {
+		b, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		return ParseBudgets(b)
+	}
+	cm, err := kubeClient.Kube().CoreV1().ConfigMaps(istioNamespace).Get(context.Background(), configMap, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve the budgets ConfigMap %s.%s: %v", configMap, istioNamespace, err)
+	}
+	data, ok := cm.Data[budgetsConfigMapKey]
+	if !ok {
+		return nil, fmt.Errorf("the ConfigMap %s.%s has no %q key", configMap, istioNamespace, budgetsConfigMapKey)
+	}
+	return ParseBudgets([]byte(data))
+}
+
+// proxyPods returns the pods given as arguments, or the running pods with a proxy in the namespace, or in all
+// namespaces when none is set.
+func proxyPods(ctx cli.Context, kubeClient kube.CLIClient, args []string) ([]*corev1.Pod, error) {
+	var res []*corev1.Pod
+	if len(args) > 0 {
+		for _, arg := range args {
+			name, namespace, err := ctx.InferPodInfoFromTypedResource(arg, ctx.NamespaceOrDefault(ctx.Namespace()))
+			if err != nil {
+				return nil, err
+			}
+			pod, err := kubeClient.Kube().CoreV1().Pods(namespace).Get(context.Background(), name, metav1.GetOptions{})
+			if err != nil {
+				return nil, err
+			}
+			res = append(res, pod)
+		}
+		return res, nil
+	}
+	pods, err := kubeClient.Kube().CoreV1().Pods(ctx.Namespace()).List(context.Background(), metav1.ListOptions{
+		FieldSelector: "status.phase=Running",
+	})
+	if err != nil {
+		return nil, err
+	}
+	for i := range pods.Items {
+		if _, ok := pods.Items[i].Annotations[annotation.SidecarStatus.Name]; ok {
+			res = append(res, &pods.Items[i])
+		}
+	}
+	return res, nil
+}
+
+func configDump(kubeClient kube.CLIClient, podName, podNamespace string) (*configdump.Wrapper, error) {
+	b, err := kubeClient.EnvoyDo(context.TODO(), podName, podNamespace, "GET", "config_dump")
+	if err != nil {
+		return nil, err
+	}
+	cd := &configdump.Wrapper{}
+	if err := cd.UnmarshalJSON(b); err != nil {
+		return nil, err
+	}
+	return cd, nil
+}
+
+// CheckProxy checks the configuration of the proxy of a pod against the budgets.
+func CheckProxy(pod *corev1.Pod, cd *configdump.Wrapper, budgets *Budgets) (diag.Messages, error) {
+	msgs := diag.Messages{}
+	res := precheck.ObjectToInstance(pod)
+	name := pod.Name + "." + pod.Namespace
+	if budgets.MaxClustersPerProxy > 0 {
+		dump, err := cd.GetDynamicClusterDump(false)
+		if err != nil {
+			return nil, err
+		}
+		if n := len(dump.GetDynamicActiveClusters()); n > budgets.MaxClustersPerProxy {
+			msgs.Add(msg.NewConfigSizeBudgetExceeded(res, "Proxy", name, n, "clusters", budgets.MaxClustersPerProxy))
+		}
+	}
+	if budgets.MaxListenersPerProxy > 0 {
+		dump, err := cd.GetDynamicListenerDump(false)
+		if err != nil {
+			return nil, err
+		}
+		if n := len(dump.GetDynamicListeners()); n > budgets.MaxListenersPerProxy {
+			msgs.Add(msg.NewConfigSizeBudgetExceeded(res, "Proxy", name, n, "listeners", budgets.MaxListenersPerProxy))
+		}
+	}
+	if budgets.MaxRoutesPerGateway > 0 {
+		gateway, err := isGateway(cd)
+		if err != nil {
+			return nil, err
+		}
+		if gateway {
+			n, err := CountRoutes(cd)
+			if err != nil {
+				return nil, err
+			}
+			if n > budgets.MaxRoutesPerGateway {
+				msgs.Add(msg.NewConfigSizeBudgetExceeded(res, "Gateway", name, n, "routes", budgets.MaxRoutesPerGateway))
+			}
+		}
+	}
+	return msgs, nil
+}
+
+// isGateway returns true if the proxy is a gateway, as identified by the node type of its bootstrap.
+func isGateway(cd *configdump.Wrapper) (bool, error) {
+	bootstrap, err := cd.GetBootstrapConfigDump()
+	if err != nil {
+		return false, err
+	}
+	return strings.HasPrefix(bootstrap.GetBootstrap().GetNode().GetId(), string(model.Router)+"~"), nil
+}
+
+// CountRoutes returns the number of routes of the proxy, across all its route configurations.
+func CountRoutes(cd *configdump.Wrapper) (int, error) {
+	dump, err := cd.GetDynamicRouteDump(false)
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, rc := range dump.GetDynamicRouteConfigs() {
+		routeConfig := &route.RouteConfiguration{}
+		if err := rc.GetRouteConfig().UnmarshalTo(routeConfig); err != nil {
+			return 0, err
+		}
+		for _, vh := range routeConfig.GetVirtualHosts() {
+			n += len(vh.GetRoutes())
+		}
+	}
+	return n, nil
+}
+
+// CheckEnvoyFilters checks the number of EnvoyFilters of each namespace against the budgets, given the
+// namespace of each EnvoyFilter.
+func CheckEnvoyFilters(namespaces []string, budgets *Budgets) diag.Messages {
+	msgs := diag.Messages{}
+	if budgets.MaxEnvoyFiltersPerNamespace <= 0 {
+		return msgs
+	}
+	counts := map[string]int{}
+	for _, ns := range namespaces {
+		counts[ns]++
+	}
+	names := make([]string, 0, len(counts))
+	for ns := range counts {
+		names = append(names, ns)
+	}
+	sort.Strings(names)
+	for _, ns := range names {
+		if counts[ns] > budgets.MaxEnvoyFiltersPerNamespace {
+			res := precheck.ObjectToInstance(&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: ns}})
+			msgs.Add(msg.NewConfigSizeBudgetExceeded(res, "Namespace", ns, counts[ns], "EnvoyFilters", budgets.MaxEnvoyFiltersPerNamespace))
+		}
+	}
+	return msgs
+}
@@ -0,0 +1,113 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configsize
+
+import (
+	"fmt"
+	"testing"
+
+	admin "github.com/envoyproxy/go-control-plane/envoy/admin/v3"
+	bootstrap "github.com/envoyproxy/go-control-plane/envoy/config/bootstrap/v3"
+	cluster "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	listener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	route "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	"google.golang.org/protobuf/types/known/anypb"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"istio.io/istio/istioctl/pkg/util/configdump"
+	"istio.io/istio/pilot/pkg/util/protoconv"
+	"istio.io/istio/pkg/config/analysis/diag"
+	"istio.io/istio/pkg/config/analysis/msg"
+	"istio.io/istio/pkg/test/util/assert"
+)
+
+func dump(nodeType string, clusters, listeners, routes int) *configdump.Wrapper {
+	cd := &admin.ClustersConfigDump{}
+	for i := 0; i < clusters; i++ {
+		cd.DynamicActiveClusters = append(cd.DynamicActiveClusters, &admin.ClustersConfigDump_DynamicCluster{
+			Cluster: protoconv.MessageToAny(&cluster.Cluster{Name: fmt.Sprintf("cluster-%d", i)}),
+		})
+	}
+	ld := &admin.ListenersConfigDump{}
+	for i := 0; i < listeners; i++ {
+		ld.DynamicListeners = append(ld.DynamicListeners, &admin.ListenersConfigDump_DynamicListener{
+			Name:        fmt.Sprintf("listener-%d", i),
+			ActiveState: &admin.ListenersConfigDump_DynamicListenerState{Listener: protoconv.MessageToAny(&listener.Listener{})},
+		})
+	}
+	vh := &route.VirtualHost{Name: "vh"}
+	for i := 0; i < routes; i++ {
+		vh.Routes = append(vh.Routes, &route.Route{Name: fmt.Sprintf("route-%d", i)})
+	}
+	rd := &admin.RoutesConfigDump{DynamicRouteConfigs: []*admin.RoutesConfigDump_DynamicRouteConfig{
+		{RouteConfig: protoconv.MessageToAny(&route.RouteConfiguration{Name: "http.8080", VirtualHosts: []*route.VirtualHost{vh}})},
+	}}
+	bd := &admin.BootstrapConfigDump{Bootstrap: &bootstrap.Bootstrap{Node: &core.Node{Id: nodeType + "~10.0.0.1~pod.foo~foo.svc.cluster.local"}}}
+	return &configdump.Wrapper{ConfigDump: &admin.ConfigDump{Configs: []*anypb.Any{
+		protoconv.MessageToAny(bd),
+		protoconv.MessageToAny(cd),
+		protoconv.MessageToAny(ld),
+		protoconv.MessageToAny(rd),
+	}}}
+}
+
+func messageParameters(msgs diag.Messages) [][]any {
+	res := make([][]any, 0, len(msgs))
+	for _, m := range msgs {
+		res = append(res, m.Parameters)
+	}
+	return res
+}
+
+func TestParseBudgets(t *testing.T) {
+	b, err := ParseBudgets([]byte("maxClustersPerProxy: 10\nenforce: true\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, b, &Budgets{MaxClustersPerProxy: 10, Enforce: true})
+
+	_, err = ParseBudgets([]byte("maxClusters: 10\n"))
+	assert.Error(t, err)
+}
+
+func TestCheckProxy(t *testing.T) {
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "foo"}}
+	budgets := &Budgets{MaxClustersPerProxy: 2, MaxListenersPerProxy: 2, MaxRoutesPerGateway: 2}
+
+	msgs, err := CheckProxy(pod, dump("sidecar", 3, 2, 5), budgets)
+	assert.NoError(t, err)
+	// Route budgets only apply to gateways.
+	assert.Equal(t, messageParameters(msgs), [][]any{{"Proxy", "pod.foo", 3, "clusters", 2}})
+	assert.Equal(t, msgs[0].Type == msg.ConfigSizeBudgetExceeded, true)
+
+	msgs, err = CheckProxy(pod, dump("router", 1, 3, 5), budgets)
+	assert.NoError(t, err)
+	assert.Equal(t, messageParameters(msgs), [][]any{
+		{"Proxy", "pod.foo", 3, "listeners", 2},
+		{"Gateway", "pod.foo", 5, "routes", 2},
+	})
+
+	msgs, err = CheckProxy(pod, dump("router", 100, 100, 100), &Budgets{})
+	assert.NoError(t, err)
+	assert.Equal(t, len(msgs), 0)
+}
+
+func TestCheckEnvoyFilters(t *testing.T) {
+	namespaces := []string{"foo", "bar", "foo", "istio-system", "foo"}
+	msgs := CheckEnvoyFilters(namespaces, &Budgets{MaxEnvoyFiltersPerNamespace: 2})
+	assert.Equal(t, messageParameters(msgs), [][]any{{"Namespace", "foo", 3, "EnvoyFilters", 2}})
+
+	assert.Equal(t, len(CheckEnvoyFilters(namespaces, &Budgets{})), 0)
+}
@@ -0,0 +1,284 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connectlatency
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	klabels "k8s.io/apimachinery/pkg/labels"
+
+	"istio.io/istio/istioctl/pkg/cli"
+	"istio.io/istio/istioctl/pkg/completion"
+	"istio.io/istio/pkg/config/constants"
+	"istio.io/istio/pkg/kube"
+	"istio.io/istio/pkg/kube/inject"
+)
+
+// Quantiles are the quantiles of the latency histograms reported, as computed by Envoy.
+var Quantiles = []float64{50, 90, 99}
+
+// Latency holds the quantiles of a latency histogram in milliseconds, in the order of Quantiles. It is empty when
+// the histogram has no samples.
+type Latency []float64
+
+func (l Latency) String() string {
+	if len(l) == 0 {
+		return "-"
+	}
+	values := make([]string, 0, len(l))
+	for _, v := range l {
+		values = append(values, strconv.FormatFloat(v, 'f', -1, 64))
+	}
+	return strings.Join(values, "/")
+}
+
+// PathLatency is the connection latency of the path from the source to the destination through a service port.
+type PathLatency struct {
+	Service     string `json:"service"`
+	Port        int32  `json:"port"`
+	Connections int64  `json:"connections"`
+	// MTLS is whether the source proxy completed TLS handshakes with the service.
+	MTLS bool `json:"mtls"`
+	// Connect is the time the source proxy takes to establish connections, including the TLS handshake.
+	Connect Latency `json:"connect"`
+	// LocalConnect is the time the destination proxy takes to connect to the application over loopback, which is
+	// the cost of establishing a connection without the mesh.
+	LocalConnect Latency `json:"localConnect"`
+	// Request is the time from the source proxy sending a request to it receiving the response.
+	Request Latency `json:"request"`
+}
+
+func Cmd(ctx cli.Context) *cobra.Command {
+	var (
+		servicePort  int32
+		outputFormat string
+	)
+	cmd := &cobra.Command{
+		Use:   "connect-latency <source-pod>[.<namespace>] <destination-pod>[.<namespace>]",
+		Short: "Measures the connection latency between two workloads, with and without the mesh",
+		Long: `Measures the latency of the connections from a source workload to a destination workload through each
+service port selecting the destination, from the timing statistics of the proxies:
+
+  CONNECT        the time the source proxy takes to establish a connection, including the mTLS handshake
+  LOCAL CONNECT  the time the destination proxy takes to connect to the application over loopback, the cost of a
+                 connection without the mesh
+  REQUEST        the time from the source proxy sending a request to it receiving the response, for HTTP ports
+
+Each latency is given as its P50/P90/P99 quantiles in milliseconds, over the lifetime of the proxies. The difference
+between CONNECT and LOCAL CONNECT is the overhead of the mesh on connection establishment, mostly the mTLS handshake
+when MTLS is true. Generate traffic between the workloads before running the command.`,
+		Example: `  # Measure the connection latency from a sleep pod to an httpbin pod
+  istioctl experimental connect-latency sleep-5d8c9b8b9b-x2v4q httpbin-7f8c9b8b9b-k9s8d.foo
+
+  # Only measure connections through service port 8000, as JSON
+  istioctl experimental connect-latency sleep-5d8c9b8b9b-x2v4q httpbin-7f8c9b8b9b-k9s8d.foo --port 8000 -o json`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 2 {
+				cmd.Println(cmd.UsageString())
+				return fmt.Errorf("connect-latency requires a source and a destination pod")
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if outputFormat != "table" && outputFormat != "json" {
+				return fmt.Errorf("unknown output format %q, must be table or json", outputFormat)
+			}
+			kubeClient, err := ctx.CLIClient()
+			if err != nil {
+				return err
+			}
+			srcName, srcNamespace, err := ctx.InferPodInfoFromTypedResource(args[0], ctx.NamespaceOrDefault(ctx.Namespace()))
+			if err != nil {
+				return err
+			}
+			dstName, dstNamespace, err := ctx.InferPodInfoFromTypedResource(args[1], ctx.NamespaceOrDefault(ctx.Namespace()))
+			if err != nil {
+				return err
+			}
+			paths, err := measure(kubeClient, srcName, srcNamespace, dstName, dstNamespace, servicePort)
+			if err != nil {
+				return err
+			}
+			return printPaths(cmd.OutOrStdout(), paths, outputFormat)
+		},
+		ValidArgsFunction: completion.ValidPodsNameArgs(ctx),
+	}
+	cmd.Flags().Int32Var(&servicePort, "port", 0, "Only measure the service port with this number")
+	cmd.Flags().StringVarP(&outputFormat, "output", "o", "table", "Output format: one of table|json")
+	return cmd
+}
+
+func measure(kubeClient kube.CLIClient, srcName, srcNamespace, dstName, dstNamespace string, servicePort int32) ([]PathLatency, error) {
+	dstPod, err := kubeClient.Kube().CoreV1().Pods(dstNamespace).Get(context.TODO(), dstName, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve destination pod %s.%s: %v", dstName, dstNamespace, err)
+	}
+	services, err := kubeClient.Kube().CoreV1().Services(dstNamespace).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list services in %s: %v", dstNamespace, err)
+	}
+	dstHasSidecar := inject.FindSidecar(dstPod) != nil
+
+	var paths []PathLatency
+	for _, svc := range services.Items {
+		if len(svc.Spec.Selector) == 0 || !klabels.SelectorFromSet(svc.Spec.Selector).Matches(klabels.Set(dstPod.Labels)) {
+			continue
+		}
+		host := fmt.Sprintf("%s.%s.svc.%s", svc.Name, svc.Namespace, constants.DefaultClusterLocalDomain)
+		for _, port := range svc.Spec.Ports {
+			if servicePort != 0 && port.Port != servicePort {
+				continue
+			}
+			outbound, err := clusterStats(kubeClient, srcName, srcNamespace, fmt.Sprintf("outbound|%d||%s", port.Port, host))
+			if err != nil {
+				return nil, fmt.Errorf("failed to retrieve stats from the source proxy: %v", err)
+			}
+			path := PathLatency{
+				Service:     host,
+				Port:        port.Port,
+				Connections: outbound.counters["upstream_cx_total"],
+				MTLS:        outbound.counters["ssl.handshake"] > 0,
+				Connect:     outbound.histograms["upstream_cx_connect_ms"],
+				Request:     outbound.histograms["upstream_rq_time"],
+			}
+			if dstHasSidecar {
+				inbound, err := clusterStats(kubeClient, dstName, dstNamespace, fmt.Sprintf("inbound|%d||", resolveTargetPort(port, dstPod)))
+				if err != nil {
+					return nil, fmt.Errorf("failed to retrieve stats from the destination proxy: %v", err)
+				}
+				path.LocalConnect = inbound.histograms["upstream_cx_connect_ms"]
+			}
+			paths = append(paths, path)
+		}
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no service port selecting pod %s.%s found", dstName, dstNamespace)
+	}
+	return paths, nil
+}
+
+// stats are the counters and latency histograms of an Envoy cluster, keyed by the name of the statistic without
+// the cluster prefix.
+type stats struct {
+	counters   map[string]int64
+	histograms map[string]Latency
+}
+
+func clusterStats(kubeClient kube.CLIClient, podName, podNamespace, clusterName string) (stats, error) {
+	filter := fmt.Sprintf(`^cluster\.%s\.(ssl\.handshake|upstream_cx_total|upstream_cx_connect_ms|upstream_rq_time)$`,
+		regexp.QuoteMeta(clusterName))
+	b, err := kubeClient.EnvoyDo(context.TODO(), podName, podNamespace, "GET", "stats?format=json&filter="+url.QueryEscape(filter))
+	if err != nil {
+		return stats{}, err
+	}
+	return parseStats(b, "cluster."+clusterName+".")
+}
+
+// envoyStats is the JSON format of the stats of the Envoy admin API.
+type envoyStats struct {
+	Stats []struct {
+		Name       string `json:"name"`
+		Value      *int64 `json:"value"`
+		Histograms *struct {
+			SupportedQuantiles []float64 `json:"supported_quantiles"`
+			ComputedQuantiles  []struct {
+				Name   string `json:"name"`
+				Values []struct {
+					Cumulative *float64 `json:"cumulative"`
+				} `json:"values"`
+			} `json:"computed_quantiles"`
+		} `json:"histograms"`
+	} `json:"stats"`
+}
+
+// parseStats parses the stats of the Envoy admin API in JSON format, keeping those with the prefix.
+func parseStats(b []byte, prefix string) (stats, error) {
+	var in envoyStats
+	if err := json.Unmarshal(b, &in); err != nil {
+		return stats{}, fmt.Errorf("failed to parse Envoy stats: %v", err)
+	}
+	res := stats{counters: map[string]int64{}, histograms: map[string]Latency{}}
+	for _, s := range in.Stats {
+		if name, ok := strings.CutPrefix(s.Name, prefix); ok && s.Value != nil {
+			res.counters[name] = *s.Value
+		}
+		if s.Histograms == nil {
+			continue
+		}
+		for _, h := range s.Histograms.ComputedQuantiles {
+			name, ok := strings.CutPrefix(h.Name, prefix)
+			if !ok {
+				continue
+			}
+			var l Latency
+			for _, q := range Quantiles {
+				for i, supported := range s.Histograms.SupportedQuantiles {
+					if supported == q && i < len(h.Values) && h.Values[i].Cumulative != nil {
+						l = append(l, *h.Values[i].Cumulative)
+					}
+				}
+			}
+			// Histograms without samples have no values.
+			if len(l) == len(Quantiles) {
+				res.histograms[name] = l
+			}
+		}
+	}
+	return res, nil
+}
+
+func resolveTargetPort(port corev1.ServicePort, pod *corev1.Pod) int {
+	if port.TargetPort.IntValue() != 0 {
+		return port.TargetPort.IntValue()
+	}
+	if name := port.TargetPort.String(); name != "" && name != "0" {
+		for _, c := range pod.Spec.Containers {
+			for _, p := range c.Ports {
+				if p.Name == name {
+					return int(p.ContainerPort)
+				}
+			}
+		}
+	}
+	return int(port.Port)
+}
+
+func printPaths(w io.Writer, paths []PathLatency, outputFormat string) error {
+	if outputFormat == "json" {
+		out, err := json.MarshalIndent(paths, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(out))
+		return err
+	}
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "SERVICE\tPORT\tCONNECTIONS\tMTLS\tCONNECT (ms)\tLOCAL CONNECT (ms)\tREQUEST (ms)")
+	for _, p := range paths {
+		_, _ = fmt.Fprintf(tw, "%s\t%d\t%d\t%t\t%s\t%s\t%s\n", p.Service, p.Port, p.Connections, p.MTLS, p.Connect, p.LocalConnect, p.Request)
+	}
+	return tw.Flush()
+}
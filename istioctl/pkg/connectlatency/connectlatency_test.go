@@ -0,0 +1,95 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connectlatency
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"istio.io/istio/istioctl/pkg/cli"
+	"istio.io/istio/pkg/kube"
+	"istio.io/istio/pkg/test/util/assert"
+)
+
+const sourceStats = `{"stats":[
+  {"name":"cluster.outbound|8000||httpbin.foo.svc.cluster.local.ssl.handshake","value":12},
+  {"name":"cluster.outbound|8000||httpbin.foo.svc.cluster.local.upstream_cx_total","value":12},
+  {"name":"cluster.outbound|9000||other.foo.svc.cluster.local.upstream_cx_total","value":3},
+  {"histograms":{
+    "supported_quantiles":[0,25,50,75,90,95,99,99.5,99.9,100],
+    "computed_quantiles":[
+      {"name":"cluster.outbound|8000||httpbin.foo.svc.cluster.local.upstream_cx_connect_ms",
+       "values":[{"interval":null,"cumulative":1},{"interval":null,"cumulative":1.5},{"interval":null,"cumulative":2.05},
+         {"interval":null,"cumulative":2.5},{"interval":null,"cumulative":3.1},{"interval":null,"cumulative":4},
+         {"interval":null,"cumulative":6.2},{"interval":null,"cumulative":7},{"interval":null,"cumulative":7},{"interval":null,"cumulative":7}]},
+      {"name":"cluster.outbound|8000||httpbin.foo.svc.cluster.local.upstream_rq_time",
+       "values":[{"interval":null,"cumulative":null},{"interval":null,"cumulative":null},{"interval":null,"cumulative":null},
+         {"interval":null,"cumulative":null},{"interval":null,"cumulative":null},{"interval":null,"cumulative":null},
+         {"interval":null,"cumulative":null},{"interval":null,"cumulative":null},{"interval":null,"cumulative":null},{"interval":null,"cumulative":null}]}
+    ]}}
+]}`
+
+const destinationStats = `{"stats":[
+  {"histograms":{
+    "supported_quantiles":[0,25,50,75,90,95,99,99.5,99.9,100],
+    "computed_quantiles":[
+      {"name":"cluster.inbound|80||.upstream_cx_connect_ms",
+       "values":[{"cumulative":0},{"cumulative":0},{"cumulative":0.05},{"cumulative":0.1},{"cumulative":0.1},
+         {"cumulative":0.2},{"cumulative":0.3},{"cumulative":0.3},{"cumulative":0.3},{"cumulative":0.3}]}
+    ]}}
+]}`
+
+func TestMeasure(t *testing.T) {
+	dst := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "httpbin-1", Namespace: "foo", Labels: map[string]string{"app": "httpbin"}},
+		Spec: corev1.PodSpec{Containers: []corev1.Container{
+			{Name: "httpbin", Ports: []corev1.ContainerPort{{Name: "http", ContainerPort: 80}}},
+			{Name: "istio-proxy"},
+		}},
+	}
+	svc := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "httpbin", Namespace: "foo"},
+		Spec: corev1.ServiceSpec{
+			Selector: map[string]string{"app": "httpbin"},
+			Ports:    []corev1.ServicePort{{Port: 8000, TargetPort: intstr.FromString("http")}},
+		},
+	}
+	client := cli.MockClient{
+		CLIClient: kube.NewFakeClient(dst, svc),
+		Results: map[string][]byte{
+			"sleep-1":   []byte(sourceStats),
+			"httpbin-1": []byte(destinationStats),
+		},
+	}
+
+	paths, err := measure(client, "sleep-1", "default", "httpbin-1", "foo", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, paths, []PathLatency{{
+		Service:      "httpbin.foo.svc.cluster.local",
+		Port:         8000,
+		Connections:  12,
+		MTLS:         true,
+		Connect:      Latency{2.05, 3.1, 6.2},
+		LocalConnect: Latency{0.05, 0.1, 0.3},
+	}})
+	assert.Equal(t, paths[0].Connect.String(), "2.05/3.1/6.2")
+	assert.Equal(t, paths[0].Request.String(), "-")
+
+	_, err = measure(client, "sleep-1", "default", "httpbin-1", "foo", 9000)
+	assert.Error(t, err)
+}
@@ -97,6 +97,9 @@ var (
 	ignoreUnmeshed = false
 
 	describeNamespace string
+
+	// Only describe the exposure of workloads through this gateway, in the form <name>[.<namespace>].
+	describeGateway string
 )
 
 func podDescribeCmd(ctx cli.Context) *cobra.Command {
@@ -186,6 +189,9 @@ the configuration objects that affect that pod.`,
 
 	cmd.PersistentFlags().BoolVar(&ignoreUnmeshed, "ignoreUnmeshed", false,
 		"Suppress warnings for unmeshed pods")
+	cmd.PersistentFlags().StringVar(&describeGateway, "gateway", "",
+		"Only describe the exposure through this gateway, deployed either by Gateway API automated deployment or as a "+
+			"classic gateway such as istio-ingressgateway, in the form <name>[.<namespace>]")
 	cmd.Long += "\n\n" + istioctlutil.ExperimentalMsg
 	return cmd
 }
@@ -988,24 +994,21 @@ func printIngressInfo(
 	configClient istioclient.Interface,
 	client kube.CLIClient,
 ) error {
-	pods, err := kubeClient.CoreV1().Pods("").List(context.TODO(), metav1.ListOptions{
-		LabelSelector: "istio=ingressgateway",
-		FieldSelector: "status.phase=Running",
-	})
+	pods, err := ingressGatewayPods(kubeClient)
 	if err != nil {
 		return multierror.Prefix(err, "Could not find ingress gateway pods")
 	}
-	if len(pods.Items) == 0 {
+	if len(pods) == 0 {
 		fmt.Fprintf(writer, "Skipping Gateway information (no ingress gateway pods)\n")
 		return nil
 	}
 	// key: namespace
 	ingressPods := map[string][]*corev1.Pod{}
 	ingressNss := sets.New[string]()
-	for i, pod := range pods.Items {
+	for i, pod := range pods {
 		ns := pod.GetNamespace()
 		ingressNss.Insert(ns)
-		ingressPods[ns] = append(ingressPods[ns], pods.Items[i].DeepCopy())
+		ingressPods[ns] = append(ingressPods[ns], pods[i].DeepCopy())
 	}
 
 	foundIngresses := []*ingressInfo{}
@@ -1041,8 +1044,10 @@ func printIngressInfo(
 	recordDestinationRules := map[string]*clientnetworking.DestinationRule{}
 	// recordGateways, key: ns/gwName
 	recordGateways := map[string]bool{}
+	// recordRoutes, key: ns/routeName
+	recordRoutes := map[string]bool{}
 
-	for _, pod := range pods.Items {
+	for _, pod := range pods {
 		byConfigDump, err := client.EnvoyDo(context.TODO(), pod.Name, pod.Namespace, "GET", "config_dump")
 		if err != nil {
 			return fmt.Errorf("failed to execute command on ingress gateway sidecar: %v", err)
@@ -1079,6 +1084,21 @@ func printIngressInfo(
 
 				// found virtual service
 				vsName, vsNamespace, err := getIstioVirtualServiceNameForSvc(&cd, svc, port.Port)
+				// Routes of Gateway API gateways come from virtual services generated from the HTTPRoutes.
+				if routeName, ok := gatewayAPIRouteName(vsName); err == nil && ok {
+					routeID := newResourceID(vsNamespace, routeName)
+					if !recordRoutes[routeID] {
+						recordRoutes[routeID] = true
+						fmt.Fprintf(writer, "--------------------\n")
+						for _, ingress := range foundIngresses {
+							if slices.FindFunc(ingress.pods, func(p *corev1.Pod) bool { return p.Name == pod.Name && p.Namespace == pod.Namespace }) != nil {
+								printIngressService(writer, printLevel0, ingress)
+							}
+						}
+						fmt.Fprintf(writer, "HTTPRoute: %s.%s\n", routeName, vsNamespace)
+					}
+					continue
+				}
 				var vs *clientnetworking.VirtualService
 				if err == nil && vsName != "" && vsNamespace != "" {
 					exist := false
@@ -1151,6 +1171,44 @@ func printIngressInfo(
 	return nil
 }
 
+// ingressGatewayPods returns the running pods of the gateway of --gateway, or else of all the ingress gateways: the
+// classic gateways labeled istio=ingressgateway, and the gateways deployed by Gateway API automated deployment.
+func ingressGatewayPods(kubeClient kubernetes.Interface) ([]corev1.Pod, error) {
+	if describeGateway != "" {
+		name, ns := handlers.InferPodInfo(describeGateway, describeNamespace)
+		return handlers.GatewayPods(context.TODO(), kubeClient, name, ns)
+	}
+	var pods []corev1.Pod
+	for _, selector := range []string{
+		"istio=ingressgateway",
+		label.GatewayManaged.Name + "=" + constants.ManagedGatewayControllerLabel,
+	} {
+		pl, err := kubeClient.CoreV1().Pods("").List(context.TODO(), metav1.ListOptions{
+			LabelSelector: selector,
+			FieldSelector: "status.phase=Running",
+		})
+		if err != nil {
+			return nil, err
+		}
+		pods = append(pods, pl.Items...)
+	}
+	return pods, nil
+}
+
+// gatewayAPIRouteName returns the name of the route a virtual service was generated from for Gateway API gateways,
+// named <route>-<index>-istio-autogenerated-k8s-gateway.
+func gatewayAPIRouteName(vsName string) (string, bool) {
+	prefix, ok := strings.CutSuffix(vsName, "-"+constants.KubernetesGatewayName)
+	if !ok {
+		return "", false
+	}
+	idx := strings.LastIndex(prefix, "-")
+	if idx < 0 {
+		return "", false
+	}
+	return prefix[:idx], true
+}
+
 func printIngressService(writer io.Writer, initPrintNum int,
 	ingress *ingressInfo,
 ) {
@@ -1304,6 +1362,9 @@ the configuration objects that affect that service.`,
 
 	cmd.PersistentFlags().BoolVar(&ignoreUnmeshed, "ignoreUnmeshed", false,
 		"Suppress warnings for unmeshed pods")
+	cmd.PersistentFlags().StringVar(&describeGateway, "gateway", "",
+		"Only describe the exposure through this gateway, deployed either by Gateway API automated deployment or as a "+
+			"classic gateway such as istio-ingressgateway, in the form <name>[.<namespace>]")
 	cmd.Long += "\n\n" + istioctlutil.ExperimentalMsg
 	return cmd
 }
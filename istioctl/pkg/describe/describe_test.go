@@ -35,6 +35,7 @@ import (
 	"istio.io/istio/istioctl/pkg/cli"
 	"istio.io/istio/istioctl/pkg/util/configdump"
 	"istio.io/istio/pilot/test/util"
+	"istio.io/istio/pkg/kube"
 	"istio.io/istio/pkg/test/util/assert"
 )
 
@@ -1203,3 +1204,60 @@ func TestGetIstioVirtualServicePathForSvcFromRoute(t *testing.T) {
 		})
 	}
 }
+
+func TestGatewayAPIRouteName(t *testing.T) {
+	cases := []struct {
+		vsName    string
+		wantRoute string
+		wantOk    bool
+	}{
+		{vsName: "http-route-0-istio-autogenerated-k8s-gateway", wantRoute: "http-route", wantOk: true},
+		{vsName: "bookinfo-12-istio-autogenerated-k8s-gateway", wantRoute: "bookinfo", wantOk: true},
+		{vsName: "bookinfo", wantOk: false},
+		{vsName: "", wantOk: false},
+	}
+	for _, c := range cases {
+		t.Run(c.vsName, func(t *testing.T) {
+			route, ok := gatewayAPIRouteName(c.vsName)
+			assert.Equal(t, ok, c.wantOk)
+			assert.Equal(t, route, c.wantRoute)
+		})
+	}
+}
+
+func TestIngressGatewayPods(t *testing.T) {
+	gatewayPod := func(name, namespace string, labels map[string]string) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, Labels: labels},
+			Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+		}
+	}
+	client := kube.NewFakeClient(
+		gatewayPod("istio-ingressgateway-8d9697654-qdzgh", "istio-system", map[string]string{"app": "istio-ingressgateway", "istio": "ingressgateway"}),
+		gatewayPod("external-istio-7f8c9b8b9b-k9s8d", "gateways", map[string]string{
+			"gateway.networking.k8s.io/gateway-name": "external",
+			"gateway.istio.io/managed":               "istio.io-gateway-controller",
+		}),
+		gatewayPod("waypoint-5d8c9b8b9b-x2v4q", "default", map[string]string{
+			"gateway.networking.k8s.io/gateway-name": "waypoint",
+			"gateway.istio.io/managed":               "istio.io-mesh-controller",
+		}),
+	)
+	names := func(pods []corev1.Pod) []string {
+		var res []string
+		for _, p := range pods {
+			res = append(res, p.Name)
+		}
+		return res
+	}
+
+	pods, err := ingressGatewayPods(client.Kube())
+	assert.NoError(t, err)
+	assert.Equal(t, names(pods), []string{"istio-ingressgateway-8d9697654-qdzgh", "external-istio-7f8c9b8b9b-k9s8d"})
+
+	describeGateway = "external.gateways"
+	defer func() { describeGateway = "" }()
+	pods, err = ingressGatewayPods(client.Kube())
+	assert.NoError(t, err)
+	assert.Equal(t, names(pods), []string{"external-istio-7f8c9b8b9b-k9s8d"})
+}
@@ -0,0 +1,309 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package envdump
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+
+	"istio.io/api/annotation"
+	"istio.io/api/label"
+	"istio.io/istio/istioctl/pkg/cli"
+	"istio.io/istio/istioctl/pkg/util"
+	"istio.io/istio/pkg/config/constants"
+	"istio.io/istio/pkg/kube"
+	"istio.io/istio/pkg/kube/inject"
+	"istio.io/istio/pkg/slices"
+	"istio.io/istio/pkg/version"
+)
+
+const redacted = "<redacted>"
+
+// Fingerprint is the environment of a mesh, with the details relevant to reproduce an issue and nothing identifying
+// the cluster, such as names, addresses or credentials.
+type Fingerprint struct {
+	IstioctlVersion   string         `json:"istioctlVersion"`
+	KubernetesVersion string         `json:"kubernetesVersion"`
+	CloudProvider     string         `json:"cloudProvider"`
+	CNI               []string       `json:"cni"`
+	ControlPlanes     []ControlPlane `json:"controlPlanes"`
+	Proxies           ProxyCounts    `json:"proxies"`
+}
+
+// ControlPlane is an istiod revision of the mesh.
+type ControlPlane struct {
+	Revision string `json:"revision"`
+	Version  string `json:"version"`
+	// MeshConfigHash is the sha256 of the mesh config of the revision, to compare it across environments without
+	// disclosing it.
+	MeshConfigHash string            `json:"meshConfigHash,omitempty"`
+	FeatureFlags   map[string]string `json:"featureFlags,omitempty"`
+}
+
+// ProxyCounts counts the proxies of the mesh by kind.
+type ProxyCounts struct {
+	// Sidecars counts the injected pods by revision.
+	Sidecars  map[string]int `json:"sidecars,omitempty"`
+	Gateways  int            `json:"gateways"`
+	Waypoints int            `json:"waypoints"`
+	Ztunnels  int            `json:"ztunnels"`
+}
+
+// knownCNIs maps the DaemonSets of common CNI plugins to the plugin name.
+var knownCNIs = map[string]string{
+	"aws-node":        "aws-vpc-cni",
+	"azure-cns":       "azure-cni",
+	"calico-node":     "calico",
+	"cilium":          "cilium",
+	"antrea-agent":    "antrea",
+	"kube-flannel-ds": "flannel",
+	"weave-net":       "weave",
+	"kindnet":         "kindnet",
+	"istio-cni-node":  "istio-cni",
+}
+
+func Cmd(ctx cli.Context) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "env",
+		Short: "Commands to describe the environment of the mesh",
+	}
+	cmd.AddCommand(dumpCmd(ctx))
+	return cmd
+}
+
+func dumpCmd(ctx cli.Context) *cobra.Command {
+	var outputFile string
+	cmd := &cobra.Command{
+		Use:   "dump",
+		Short: "Dumps a redacted fingerprint of the environment of the mesh, for bug reports",
+		Long: `Dumps a fingerprint of the environment of the mesh as YAML: the Istio versions and revisions, the Kubernetes
+version, cloud provider and CNI plugins, a hash of the mesh config and the feature flags of each revision, and the
+number of proxies by kind.
+
+The fingerprint contains no cluster, namespace or workload names, addresses or credentials, and can be pasted into
+bug reports as is.`,
+		Example: `  # Print the fingerprint of the environment
+  istioctl experimental env dump
+
+  # Save the fingerprint to a file
+  istioctl experimental env dump --output-file env.yaml`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			kubeClient, err := ctx.CLIClient()
+			if err != nil {
+				return err
+			}
+			fp, err := Collect(kubeClient)
+			if err != nil {
+				return err
+			}
+			out, err := yaml.Marshal(fp)
+			if err != nil {
+				return err
+			}
+			if outputFile != "" {
+				return os.WriteFile(outputFile, out, 0o644)
+			}
+			_, err = cmd.OutOrStdout().Write(out)
+			return err
+		},
+	}
+	cmd.Flags().StringVar(&outputFile, "output-file", "", "Write the fingerprint to this file instead of stdout")
+	return cmd
+}
+
+// ReadFingerprint reads a fingerprint written by "istioctl x env dump".
+func ReadFingerprint(path string) (*Fingerprint, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	fp := &Fingerprint{}
+	if err := yaml.UnmarshalStrict(b, fp); err != nil {
+		return nil, fmt.Errorf("failed to parse the environment fingerprint %s: %v", path, err)
+	}
+	return fp, nil
+}
+
+// Collect gathers the fingerprint of the environment of the cluster.
+func Collect(kubeClient kube.CLIClient) (*Fingerprint, error) {
+	fp := &Fingerprint{IstioctlVersion: version.Info.Version, CNI: []string{}}
+	kv, err := kubeClient.GetKubernetesVersion()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the Kubernetes version: %v", err)
+	}
+	fp.KubernetesVersion = kv.GitVersion
+
+	nodes, err := kubeClient.Kube().CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{Limit: 1})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %v", err)
+	}
+	fp.CloudProvider = "unknown"
+	if len(nodes.Items) > 0 {
+		fp.CloudProvider = cloudProvider(nodes.Items[0].Spec.ProviderID)
+	}
+
+	daemonSets, err := kubeClient.Kube().AppsV1().DaemonSets(metav1.NamespaceAll).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list daemonsets: %v", err)
+	}
+	for _, ds := range daemonSets.Items {
+		if cni, f := knownCNIs[ds.Name]; f {
+			fp.CNI = append(fp.CNI, cni)
+		}
+	}
+	fp.CNI = slices.FilterDuplicatesPresorted(slices.Sort(fp.CNI))
+
+	istiods, err := kubeClient.Kube().AppsV1().Deployments(metav1.NamespaceAll).List(context.TODO(), metav1.ListOptions{
+		LabelSelector: "app=istiod",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list istiod deployments: %v", err)
+	}
+	for i := range istiods.Items {
+		cp, err := controlPlane(kubeClient, &istiods.Items[i])
+		if err != nil {
+			return nil, err
+		}
+		fp.ControlPlanes = append(fp.ControlPlanes, cp)
+	}
+	sort.Slice(fp.ControlPlanes, func(i, j int) bool { return fp.ControlPlanes[i].Revision < fp.ControlPlanes[j].Revision })
+
+	pods, err := kubeClient.Kube().CoreV1().Pods(metav1.NamespaceAll).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pods: %v", err)
+	}
+	fp.Proxies = countProxies(pods.Items)
+	return fp, nil
+}
+
+func controlPlane(kubeClient kube.CLIClient, istiod *appsv1.Deployment) (ControlPlane, error) {
+	cp := ControlPlane{Revision: istiod.Labels[label.IoIstioRev.Name]}
+	if cp.Revision == "" {
+		cp.Revision = util.DefaultRevisionName
+	}
+	for _, c := range istiod.Spec.Template.Spec.Containers {
+		if c.Name != "discovery" {
+			continue
+		}
+		cp.Version = imageTag(c.Image)
+		cp.FeatureFlags = featureFlags(c.Env)
+	}
+
+	cmName := "istio"
+	if cp.Revision != util.DefaultRevisionName {
+		cmName += "-" + cp.Revision
+	}
+	cm, err := kubeClient.Kube().CoreV1().ConfigMaps(istiod.Namespace).Get(context.TODO(), cmName, metav1.GetOptions{})
+	if kerrors.IsNotFound(err) {
+		// The mesh config is optional, istiod runs with the defaults without it.
+		return cp, nil
+	}
+	if err != nil {
+		return ControlPlane{}, fmt.Errorf("failed to retrieve the mesh config of revision %s: %v", cp.Revision, err)
+	}
+	if mc, f := cm.Data[util.ConfigMapKey]; f {
+		sum := sha256.Sum256([]byte(mc))
+		cp.MeshConfigHash = "sha256:" + hex.EncodeToString(sum[:])
+	}
+	return cp, nil
+}
+
+// imageTag returns the tag of an image, such as 1.24.0 for docker.io/istio/pilot:1.24.0.
+func imageTag(image string) string {
+	name := image[strings.LastIndex(image, "/")+1:]
+	if _, tag, found := strings.Cut(name, ":"); found {
+		return tag
+	}
+	return ""
+}
+
+// featureFlags returns the environment variables of istiod set to a literal value, which is redacted when it may
+// identify the cluster or hold a credential.
+func featureFlags(env []corev1.EnvVar) map[string]string {
+	res := map[string]string{}
+	for _, e := range env {
+		if e.ValueFrom != nil {
+			continue
+		}
+		res[e.Name] = e.Value
+		if sensitiveEnv(e.Name) {
+			res[e.Name] = redacted
+		}
+	}
+	return res
+}
+
+func sensitiveEnv(name string) bool {
+	switch name {
+	case "CLUSTER_ID", "ISTIOD_CUSTOM_HOST", "EXTERNAL_ISTIOD", "ISTIO_META_CLUSTER_ID":
+		return true
+	}
+	for _, s := range []string{"SECRET", "TOKEN", "PASSWORD", "KEY", "ADDRESS", "HOST", "URL"} {
+		if strings.Contains(name, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// cloudProvider returns the provider of a node from its provider ID, such as aws:///us-east-1a/i-0123.
+func cloudProvider(providerID string) string {
+	provider, _, found := strings.Cut(providerID, "://")
+	if !found || provider == "" {
+		return "unknown"
+	}
+	return provider
+}
+
+func countProxies(pods []corev1.Pod) ProxyCounts {
+	res := ProxyCounts{Sidecars: map[string]int{}}
+	for i := range pods {
+		pod := &pods[i]
+		switch {
+		case pod.Labels["app"] == "ztunnel":
+			res.Ztunnels++
+		case pod.Labels[label.GatewayManaged.Name] == constants.ManagedGatewayMeshControllerLabel:
+			res.Waypoints++
+		case isGateway(pod):
+			res.Gateways++
+		case pod.Annotations[annotation.SidecarStatus.Name] != "":
+			rev := pod.Labels[label.IoIstioRev.Name]
+			if rev == "" {
+				rev = util.DefaultRevisionName
+			}
+			res.Sidecars[rev]++
+		}
+	}
+	return res
+}
+
+// isGateway returns whether the pod runs a proxy in router mode, as done by gateways.
+func isGateway(pod *corev1.Pod) bool {
+	proxy := inject.FindSidecar(pod)
+	return proxy != nil && slices.Contains(proxy.Args, "router")
+}
@@ -0,0 +1,105 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package envdump
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+
+	"istio.io/istio/pkg/kube"
+	"istio.io/istio/pkg/test/util/assert"
+	"istio.io/istio/pkg/version"
+)
+
+func TestCollect(t *testing.T) {
+	istiod := func(revision, image string, env ...corev1.EnvVar) *appsv1.Deployment {
+		labels := map[string]string{"app": "istiod"}
+		name := "istiod"
+		if revision != "" {
+			labels["istio.io/rev"] = revision
+			name += "-" + revision
+		}
+		d := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "istio-system", Labels: labels}}
+		d.Spec.Template.Spec.Containers = []corev1.Container{{Name: "discovery", Image: image, Env: env}}
+		return d
+	}
+	pod := func(name string, labels, annotations map[string]string, args ...string) *corev1.Pod {
+		p := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Labels: labels, Annotations: annotations}}
+		p.Spec.Containers = []corev1.Container{{Name: "istio-proxy", Args: args}}
+		return p
+	}
+	injected := map[string]string{"sidecar.istio.io/status": "{}"}
+	client := kube.NewFakeClientWithVersion("30",
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}, Spec: corev1.NodeSpec{ProviderID: "gce://project/us-central1-a/node-1"}},
+		&appsv1.DaemonSet{ObjectMeta: metav1.ObjectMeta{Name: "calico-node", Namespace: "kube-system"}},
+		&appsv1.DaemonSet{ObjectMeta: metav1.ObjectMeta{Name: "istio-cni-node", Namespace: "istio-system"}},
+		istiod("", "docker.io/istio/pilot:1.24.0",
+			corev1.EnvVar{Name: "PILOT_ENABLE_AMBIENT", Value: "true"},
+			corev1.EnvVar{Name: "CLUSTER_ID", Value: "prod-us-central1"},
+			corev1.EnvVar{Name: "POD_NAME", ValueFrom: &corev1.EnvVarSource{FieldRef: &corev1.ObjectFieldSelector{FieldPath: "metadata.name"}}}),
+		istiod("canary", "localhost:5000/istio/pilot:1.25.0"),
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "istio", Namespace: "istio-system"}, Data: map[string]string{"mesh": "{}"}},
+		pod("productpage", nil, injected),
+		pod("reviews", map[string]string{"istio.io/rev": "canary"}, injected),
+		pod("ingress", nil, nil, "proxy", "router"),
+		pod("waypoint", map[string]string{"gateway.istio.io/managed": "istio.io-mesh-controller"}, nil, "proxy", "router"),
+		pod("ztunnel", map[string]string{"app": "ztunnel"}, nil),
+	)
+
+	fp, err := Collect(client)
+	assert.NoError(t, err)
+	assert.Equal(t, fp, &Fingerprint{
+		IstioctlVersion:   version.Info.Version,
+		KubernetesVersion: "v1.30.0",
+		CloudProvider:     "gce",
+		CNI:               []string{"calico", "istio-cni"},
+		ControlPlanes: []ControlPlane{
+			{Revision: "canary", Version: "1.25.0", FeatureFlags: map[string]string{}},
+			{
+				Revision:       "default",
+				Version:        "1.24.0",
+				MeshConfigHash: "sha256:44136fa355b3678a1146ad16f7e8649e94fb4fc21fe77e8310c060f61caaff8a",
+				FeatureFlags:   map[string]string{"PILOT_ENABLE_AMBIENT": "true", "CLUSTER_ID": redacted},
+			},
+		},
+		Proxies: ProxyCounts{
+			Sidecars:  map[string]int{"default": 1, "canary": 1},
+			Gateways:  1,
+			Waypoints: 1,
+			Ztunnels:  1,
+		},
+	})
+
+	// The dump can be read back by other commands.
+	out, err := yaml.Marshal(fp)
+	assert.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "env.yaml")
+	assert.NoError(t, os.WriteFile(path, out, 0o644))
+	read, err := ReadFingerprint(path)
+	assert.NoError(t, err)
+	assert.Equal(t, read, fp)
+}
+
+func TestCloudProvider(t *testing.T) {
+	assert.Equal(t, cloudProvider("aws:///us-east-1a/i-0123"), "aws")
+	assert.Equal(t, cloudProvider("kind://docker/kind/kind-control-plane"), "kind")
+	assert.Equal(t, cloudProvider(""), "unknown")
+}
@@ -0,0 +1,196 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package envoydeprecation
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"istio.io/istio/istioctl/pkg/cli"
+	"istio.io/istio/istioctl/pkg/util/configdump"
+	"istio.io/istio/pkg/config/xds"
+	"istio.io/istio/pkg/kube"
+	"istio.io/istio/pkg/kube/inject"
+)
+
+const deprecatedFeatureUseStat = "runtime.deprecated_feature_use"
+
+func Cmd(ctx cli.Context) *cobra.Command {
+	var outputFormat string
+	cmd := &cobra.Command{
+		Use:   "envoy-deprecations",
+		Short: "Lists the deprecated Envoy fields and runtime features in use by the proxies of the mesh",
+		Long: `Scans the config dumps of the proxies of the mesh for the deprecated Envoy fields and enum values they use, and
+their runtime for overrides of the features guarding Envoy behavior changes. Deprecated fields are removed, and runtime
+guards dropped, by later Envoy releases, so these are to be remediated before upgrading the proxies.
+
+The usages are aggregated by source: the EnvoyFilters istiod recorded as patching the resource, or else the EnvoyFilters
+whose patches set the deprecated field, or else the config generated by istiod. Proxies reporting the use of deprecated
+features through the runtime.deprecated_feature_use statistic are listed as well; their logs tell which features.`,
+		Example: `  # List the deprecated Envoy fields and runtime features in use by the proxies of the mesh
+  istioctl experimental envoy-deprecations
+
+  # List those in use by the proxies of the default namespace, as JSON
+  istioctl x envoy-deprecations -n default -o json`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if outputFormat != "text" && outputFormat != "json" {
+				return fmt.Errorf("unknown output format %q, must be text or json", outputFormat)
+			}
+			kubeClient, err := ctx.CLIClient()
+			if err != nil {
+				return err
+			}
+			patched, err := patchedFields(kubeClient)
+			if err != nil {
+				return err
+			}
+			pods, err := kubeClient.Kube().CoreV1().Pods(ctx.Namespace()).List(context.Background(), metav1.ListOptions{
+				FieldSelector: "status.phase=Running",
+			})
+			if err != nil {
+				return err
+			}
+			usages := map[string][]Usage{}
+			for i := range pods.Items {
+				pod := &pods.Items[i]
+				if inject.FindSidecar(pod) == nil {
+					continue
+				}
+				name := pod.Name + "." + pod.Namespace
+				us, err := proxyUsages(kubeClient, pod.Name, pod.Namespace)
+				if err != nil {
+					// A proxy which cannot be scanned should not hide the findings of the others.
+					_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Warning: failed to scan %s: %v\n", name, err)
+					continue
+				}
+				usages[name] = Attribute(us, patched)
+			}
+			return printFindings(cmd.OutOrStdout(), Aggregate(usages), len(usages), outputFormat)
+		},
+	}
+	cmd.Flags().StringVarP(&outputFormat, "output", "o", "text", "Output format: one of text|json")
+	return cmd
+}
+
+// patchedFields returns the EnvoyFilters of the cluster setting each deprecated field in their patches.
+func patchedFields(kubeClient kube.CLIClient) (map[string][]string, error) {
+	efs, err := kubeClient.Istio().NetworkingV1alpha3().EnvoyFilters(metav1.NamespaceAll).List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list EnvoyFilters: %v", err)
+	}
+	patched := map[string][]string{}
+	for _, ef := range efs.Items {
+		source := fmt.Sprintf("EnvoyFilter %s/%s", ef.Namespace, ef.Name)
+		for _, cp := range ef.Spec.ConfigPatches {
+			if cp.GetPatch().GetValue() == nil {
+				continue
+			}
+			obj, err := xds.BuildXDSObjectFromStruct(cp.ApplyTo, cp.Patch.Value, false)
+			if err != nil || obj == nil {
+				continue
+			}
+			for _, u := range Scan(obj) {
+				if fs := patched[u.Field]; len(fs) == 0 || fs[len(fs)-1] != source {
+					patched[u.Field] = append(fs, source)
+				}
+			}
+		}
+	}
+	return patched, nil
+}
+
+// proxyUsages returns the deprecated fields and runtime features in use by the proxy of the pod.
+func proxyUsages(kubeClient kube.CLIClient, podName, namespace string) ([]Usage, error) {
+	b, err := kubeClient.EnvoyDo(context.TODO(), podName, namespace, "GET", "config_dump")
+	if err != nil {
+		return nil, err
+	}
+	cd := &configdump.Wrapper{}
+	if err := cd.UnmarshalJSON(b); err != nil {
+		return nil, err
+	}
+	usages := Scan(cd.ConfigDump)
+	b, err = kubeClient.EnvoyDo(context.TODO(), podName, namespace, "GET", "runtime")
+	if err != nil {
+		return nil, err
+	}
+	overrides, err := RuntimeOverrides(b)
+	if err != nil {
+		return nil, err
+	}
+	usages = append(usages, overrides...)
+	b, err = kubeClient.EnvoyDo(context.TODO(), podName, namespace, "GET", "stats?filter=^"+deprecatedFeatureUseStat+"$")
+	if err != nil {
+		return nil, err
+	}
+	if n := parseStat(b, deprecatedFeatureUseStat); n > 0 {
+		usages = append(usages, Usage{Source: "deprecated features, see the proxy logs", Field: deprecatedFeatureUseStat})
+	}
+	return usages, nil
+}
+
+// parseStat returns the value of the statistic in the response of the stats admin endpoint, 0 if absent.
+func parseStat(b []byte, name string) int {
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	for scanner.Scan() {
+		k, v, ok := strings.Cut(scanner.Text(), ": ")
+		if !ok || k != name {
+			continue
+		}
+		n, _ := strconv.Atoi(strings.TrimSpace(v))
+		return n
+	}
+	return 0
+}
+
+func printFindings(w io.Writer, findings []Finding, scanned int, outputFormat string) error {
+	if outputFormat == "json" {
+		out, err := json.MarshalIndent(findings, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(out))
+		return err
+	}
+	if len(findings) == 0 {
+		_, err := fmt.Fprintf(w, "No deprecated Envoy field or runtime feature in use, out of %d proxies.\n", scanned)
+		return err
+	}
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "SOURCE\tFIELD\tDEPRECATED IN\tPROXIES")
+	for _, f := range findings {
+		deprecatedIn := f.DeprecatedIn
+		if deprecatedIn == "" {
+			deprecatedIn = "-"
+		}
+		proxies := strings.Join(f.Proxies, ",")
+		if len(f.Proxies) > 3 {
+			proxies = fmt.Sprintf("%s and %d more", strings.Join(f.Proxies[:3], ","), len(f.Proxies)-3)
+		}
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", f.Source, f.Field, deprecatedIn, proxies)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,254 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package envoydeprecation
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/envoyproxy/go-control-plane/envoy/annotations"
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/known/anypb"
+
+	"istio.io/istio/pilot/pkg/networking/util"
+	"istio.io/istio/pkg/util/sets"
+	"istio.io/istio/pkg/util/strcase"
+)
+
+const (
+	// GeneratedSource is the source of the deprecated fields generated by istiod rather than patched by an EnvoyFilter.
+	GeneratedSource = "istiod generated config"
+
+	// adminPackage is the package of the config dump types, whose own fields are not config of the proxy.
+	adminPackage = "envoy.admin.v3"
+)
+
+// runtimeFeaturePrefixes are the prefixes of the runtime keys guarding Envoy behavior changes. Overrides of these keys
+// keep the old behavior until the guard is removed by a later Envoy release.
+var runtimeFeaturePrefixes = []string{"envoy.reloadable_features.", "envoy.restart_features.", "envoy.deprecated_features."}
+
+// Usage is a deprecated Envoy field, enum value or runtime feature override in use by a proxy.
+type Usage struct {
+	// Source is the Istio config the usage originates from, such as "EnvoyFilter istio-system/lua".
+	Source string `json:"source"`
+	// Field is the full name of the field or enum value, or the runtime key and its value.
+	Field string `json:"field"`
+	// DeprecatedIn is the Envoy minor version deprecating the field, empty when unknown.
+	DeprecatedIn string `json:"deprecatedIn,omitempty"`
+}
+
+// Scan returns the deprecated fields and enum values set in the message and in the messages it contains, including the
+// typed configs of Any fields. Usages are attributed to the EnvoyFilters recorded as patching the closest resource by
+// istiod, or left without source.
+func Scan(m proto.Message) []Usage {
+	s := &scanner{seen: sets.New[Usage]()}
+	s.walk(m.ProtoReflect(), "")
+	return s.usages
+}
+
+type scanner struct {
+	seen   sets.Set[Usage]
+	usages []Usage
+}
+
+func (s *scanner) add(u Usage) {
+	if !s.seen.InsertContains(u) {
+		s.usages = append(s.usages, u)
+	}
+}
+
+func (s *scanner) walk(m protoreflect.Message, source string) {
+	if a, ok := m.Interface().(*anypb.Any); ok {
+		// Types unknown to istioctl, such as those of newer Envoy releases, are skipped.
+		if inner, err := a.UnmarshalNew(); err == nil {
+			s.walk(inner.ProtoReflect(), source)
+		}
+		return
+	}
+	if src := envoyFilterSource(m); src != "" {
+		source = src
+	}
+	admin := m.Descriptor().ParentFile().Package() == adminPackage
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		if !admin {
+			s.check(fd, v, source)
+		}
+		switch {
+		case fd.IsList() && fd.Message() != nil:
+			l := v.List()
+			for i := 0; i < l.Len(); i++ {
+				s.walk(l.Get(i).Message(), source)
+			}
+		case fd.IsMap() && fd.MapValue().Message() != nil:
+			v.Map().Range(func(_ protoreflect.MapKey, mv protoreflect.Value) bool {
+				s.walk(mv.Message(), source)
+				return true
+			})
+		case !fd.IsList() && !fd.IsMap() && fd.Message() != nil:
+			s.walk(v.Message(), source)
+		}
+		return true
+	})
+}
+
+// check records the field if it is deprecated, and its enum values which are.
+func (s *scanner) check(fd protoreflect.FieldDescriptor, v protoreflect.Value, source string) {
+	if opts, ok := fd.Options().(*descriptorpb.FieldOptions); ok && opts.GetDeprecated() {
+		version, _ := proto.GetExtension(opts, annotations.E_DeprecatedAtMinorVersion).(string)
+		s.add(Usage{Source: source, Field: string(fd.FullName()), DeprecatedIn: version})
+	}
+	if fd.Enum() == nil || fd.IsMap() {
+		return
+	}
+	numbers := []protoreflect.EnumNumber{}
+	if fd.IsList() {
+		for i := 0; i < v.List().Len(); i++ {
+			numbers = append(numbers, v.List().Get(i).Enum())
+		}
+	} else {
+		numbers = append(numbers, v.Enum())
+	}
+	for _, n := range numbers {
+		ev := fd.Enum().Values().ByNumber(n)
+		if ev == nil {
+			continue
+		}
+		if opts, ok := ev.Options().(*descriptorpb.EnumValueOptions); ok && opts.GetDeprecated() {
+			version, _ := proto.GetExtension(opts, annotations.E_DeprecatedAtMinorVersionEnum).(string)
+			s.add(Usage{Source: source, Field: string(ev.FullName()), DeprecatedIn: version})
+		}
+	}
+}
+
+// envoyFilterSource returns the EnvoyFilters istiod recorded as patching the resource in its metadata, if any.
+func envoyFilterSource(m protoreflect.Message) string {
+	fd := m.Descriptor().Fields().ByName("metadata")
+	if fd == nil || fd.Message() == nil || fd.Message().FullName() != "envoy.config.core.v3.Metadata" || !m.Has(fd) {
+		return ""
+	}
+	md, ok := m.Get(fd).Message().Interface().(*core.Metadata)
+	if !ok {
+		return ""
+	}
+	values := md.GetFilterMetadata()[util.IstioMetadataKey].GetFields()[util.EnvoyFiltersMetadataKey].GetListValue().GetValues()
+	names := make([]string, 0, len(values))
+	for _, v := range values {
+		names = append(names, renderConfig(v.GetStringValue()))
+	}
+	return strings.Join(names, ", ")
+}
+
+// renderConfig renders the path of an Istio config recorded by istiod, such as
+// /apis/networking.istio.io/v1alpha3/namespaces/istio-system/envoy-filter/lua, as "EnvoyFilter istio-system/lua".
+func renderConfig(path string) string {
+	pieces := strings.Split(path, "/")
+	if len(pieces) != 8 || pieces[4] != "namespaces" {
+		return path
+	}
+	return fmt.Sprintf("%s %s/%s", strcase.CamelCase(pieces[6]), pieces[5], pieces[7])
+}
+
+// Attribute sets the source of the usages without one: the EnvoyFilters whose patches set the same field, as given by
+// patched, or istiod otherwise.
+func Attribute(usages []Usage, patched map[string][]string) []Usage {
+	res := make([]Usage, 0, len(usages))
+	for _, u := range usages {
+		if u.Source == "" {
+			u.Source = GeneratedSource
+			if filters := patched[u.Field]; len(filters) > 0 {
+				u.Source = strings.Join(filters, ", ")
+			}
+		}
+		res = append(res, u)
+	}
+	return res
+}
+
+// runtimeDump is the subset of the response of the runtime admin endpoint read by the command.
+type runtimeDump struct {
+	Layers  []string `json:"layers"`
+	Entries map[string]struct {
+		LayerValues []string `json:"layer_values"`
+	} `json:"entries"`
+}
+
+// RuntimeOverrides returns the runtime features guarding Envoy behavior changes which are overridden, from the response
+// of the runtime admin endpoint. Their source is the runtime layer overriding them.
+func RuntimeOverrides(b []byte) ([]Usage, error) {
+	dump := runtimeDump{}
+	if err := json.Unmarshal(b, &dump); err != nil {
+		return nil, fmt.Errorf("failed to parse the runtime: %v", err)
+	}
+	var res []Usage
+	for key, entry := range dump.Entries {
+		if !hasFeaturePrefix(key) {
+			continue
+		}
+		for i, v := range entry.LayerValues {
+			if v == "" || i >= len(dump.Layers) {
+				continue
+			}
+			res = append(res, Usage{Source: fmt.Sprintf("runtime layer %q", dump.Layers[i]), Field: key + "=" + v})
+		}
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Field < res[j].Field
+	})
+	return res, nil
+}
+
+func hasFeaturePrefix(key string) bool {
+	for _, p := range runtimeFeaturePrefixes {
+		if strings.HasPrefix(key, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// Finding is a usage, and the proxies using it.
+type Finding struct {
+	Usage
+	Proxies []string `json:"proxies"`
+}
+
+// Aggregate aggregates the usages of the proxies, by proxy name, by source and field.
+func Aggregate(usages map[string][]Usage) []Finding {
+	proxies := map[Usage]sets.String{}
+	for proxy, us := range usages {
+		for _, u := range us {
+			if proxies[u] == nil {
+				proxies[u] = sets.New[string]()
+			}
+			proxies[u].Insert(proxy)
+		}
+	}
+	res := make([]Finding, 0, len(proxies))
+	for u, p := range proxies {
+		res = append(res, Finding{Usage: u, Proxies: sets.SortedList(p)})
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Source != res[j].Source {
+			return res[i].Source < res[j].Source
+		}
+		return res[i].Field < res[j].Field
+	})
+	return res
+}
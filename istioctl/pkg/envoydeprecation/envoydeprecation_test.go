@@ -0,0 +1,104 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package envoydeprecation
+
+import (
+	"testing"
+
+	admin "github.com/envoyproxy/go-control-plane/envoy/admin/v3"
+	cluster "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	listener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	hcm "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	"istio.io/istio/pilot/pkg/networking/util"
+	"istio.io/istio/pilot/pkg/util/protoconv"
+	"istio.io/istio/pkg/test/util/assert"
+)
+
+func TestScan(t *testing.T) {
+	patchedCluster := &cluster.Cluster{
+		Name:                          "outbound|9080||reviews.default.svc.cluster.local",
+		MaxRequestsPerConnection:      wrapperspb.UInt32(1),
+		Metadata:                      util.AddEnvoyFilterToMetadata(nil, "istio-system", "max-requests"),
+		PerConnectionBufferLimitBytes: wrapperspb.UInt32(1024),
+	}
+	manager := &hcm.HttpConnectionManager{StatPrefix: "inbound", FlushAccessLogOnNewRequest: true}
+	l := &listener.Listener{
+		Name: "virtualInbound",
+		FilterChains: []*listener.FilterChain{{
+			Filters: []*listener.Filter{{
+				Name:       "envoy.filters.network.http_connection_manager",
+				ConfigType: &listener.Filter_TypedConfig{TypedConfig: protoconv.MessageToAny(manager)},
+			}},
+		}},
+	}
+	dump := &admin.ConfigDump{Configs: []*anypb.Any{
+		protoconv.MessageToAny(&admin.ClustersConfigDump{DynamicActiveClusters: []*admin.ClustersConfigDump_DynamicCluster{{
+			Cluster: protoconv.MessageToAny(patchedCluster),
+		}}}),
+		protoconv.MessageToAny(&admin.ListenersConfigDump{DynamicListeners: []*admin.ListenersConfigDump_DynamicListener{{
+			ActiveState: &admin.ListenersConfigDump_DynamicListenerState{Listener: protoconv.MessageToAny(l)},
+		}}}),
+	}}
+
+	usages := Scan(dump)
+	assert.Equal(t, usages, []Usage{
+		{Source: "EnvoyFilter istio-system/max-requests", Field: "envoy.config.cluster.v3.Cluster.max_requests_per_connection", DeprecatedIn: "3.0"},
+		{
+			Field:        "envoy.extensions.filters.network.http_connection_manager.v3.HttpConnectionManager.flush_access_log_on_new_request",
+			DeprecatedIn: "3.0",
+		},
+	})
+
+	attributed := Attribute(usages, map[string][]string{
+		"envoy.extensions.filters.network.http_connection_manager.v3.HttpConnectionManager.flush_access_log_on_new_request": {
+			"EnvoyFilter default/access-log",
+		},
+	})
+	assert.Equal(t, attributed[1].Source, "EnvoyFilter default/access-log")
+	assert.Equal(t, Attribute([]Usage{{Field: "envoy.config.cluster.v3.Cluster.track_timeout_budgets"}}, nil)[0].Source, GeneratedSource)
+}
+
+func TestRuntimeOverrides(t *testing.T) {
+	runtime := `{
+  "layers": ["global config", "admin"],
+  "entries": {
+    "envoy.reloadable_features.http_reject_path_with_fragment": {"layer_values": ["false", ""], "final_value": "false"},
+    "envoy.restart_features.use_fast_protobuf_hash": {"layer_values": ["", "true"], "final_value": "true"},
+    "overload.global_downstream_max_connections": {"layer_values": ["2147483647", ""], "final_value": "2147483647"}
+  }
+}`
+	overrides, err := RuntimeOverrides([]byte(runtime))
+	assert.NoError(t, err)
+	assert.Equal(t, overrides, []Usage{
+		{Source: `runtime layer "global config"`, Field: "envoy.reloadable_features.http_reject_path_with_fragment=false"},
+		{Source: `runtime layer "admin"`, Field: "envoy.restart_features.use_fast_protobuf_hash=true"},
+	})
+}
+
+func TestAggregate(t *testing.T) {
+	u := Usage{Source: GeneratedSource, Field: "envoy.config.cluster.v3.Cluster.track_timeout_budgets"}
+	findings := Aggregate(map[string][]Usage{
+		"productpage-v1-1.default": {u},
+		"reviews-v1-1.default":     {u, {Source: "EnvoyFilter default/lua", Field: "envoy.config.route.v3.RouteAction.cors"}},
+	})
+	assert.Equal(t, findings, []Finding{
+		{Usage: Usage{Source: "EnvoyFilter default/lua", Field: "envoy.config.route.v3.RouteAction.cors"}, Proxies: []string{"reviews-v1-1.default"}},
+		{Usage: u, Proxies: []string{"productpage-v1-1.default", "reviews-v1-1.default"}},
+	})
+	assert.Equal(t, parseStat([]byte("runtime.deprecated_feature_use: 2\n"), deprecatedFeatureUseStat), 2)
+}
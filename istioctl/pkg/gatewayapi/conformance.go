@@ -0,0 +1,491 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gatewayapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	gateway "sigs.k8s.io/gateway-api/apis/v1"
+	"sigs.k8s.io/gateway-api/pkg/features"
+
+	"istio.io/api/label"
+	"istio.io/istio/pkg/kube"
+)
+
+const (
+	// echoImage is the echo server used by the upstream conformance suite, which reflects requests as JSON.
+	echoImage = "gcr.io/k8s-staging-gateway-api/echo-basic:v20240412-v1.0.0-394-g40c666fd"
+
+	gatewayName = "conformance"
+	gatewayPort = 80
+)
+
+// manifests returns the backends, Gateway and HTTPRoutes exercised by the conformance tests. Each route uses its
+// own hostname so that all of them can be attached to the Gateway at once.
+func manifests(gatewayClass string) string {
+	backends := make([]string, 0, 2)
+	for _, name := range []string{"infra-backend-v1", "infra-backend-v2"} {
+		backends = append(backends, fmt.Sprintf(`apiVersion: v1
+kind: Service
+metadata:
+  name: %[1]s
+spec:
+  selector:
+    app: %[1]s
+  ports:
+  - protocol: TCP
+    port: 8080
+    targetPort: 3000
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: %[1]s
+  labels:
+    app: %[1]s
+spec:
+  replicas: 1
+  selector:
+    matchLabels:
+      app: %[1]s
+  template:
+    metadata:
+      labels:
+        app: %[1]s
+    spec:
+      containers:
+      - name: %[1]s
+        image: %[2]s
+        env:
+        - name: POD_NAME
+          valueFrom:
+            fieldRef:
+              fieldPath: metadata.name
+        - name: NAMESPACE
+          valueFrom:
+            fieldRef:
+              fieldPath: metadata.namespace
+        resources:
+          requests:
+            cpu: 10m
+`, name, echoImage))
+	}
+	return strings.Join(backends, "---\n") + fmt.Sprintf(`---
+apiVersion: gateway.networking.k8s.io/v1
+kind: Gateway
+metadata:
+  name: %[1]s
+spec:
+  gatewayClassName: %[2]s
+  listeners:
+  - name: http
+    port: %[3]d
+    protocol: HTTP
+    allowedRoutes:
+      namespaces:
+        from: Same
+---
+apiVersion: gateway.networking.k8s.io/v1
+kind: HTTPRoute
+metadata:
+  name: matching
+spec:
+  parentRefs:
+  - name: %[1]s
+  hostnames:
+  - matching.example.com
+  rules:
+  - matches:
+    - path:
+        type: PathPrefix
+        value: /v2
+    - headers:
+      - name: version
+        value: two
+    backendRefs:
+    - name: infra-backend-v2
+      port: 8080
+  - matches:
+    - queryParams:
+      - name: version
+        value: two
+    backendRefs:
+    - name: infra-backend-v2
+      port: 8080
+  - backendRefs:
+    - name: infra-backend-v1
+      port: 8080
+---
+apiVersion: gateway.networking.k8s.io/v1
+kind: HTTPRoute
+metadata:
+  name: header-modifier
+spec:
+  parentRefs:
+  - name: %[1]s
+  hostnames:
+  - modifier.example.com
+  rules:
+  - filters:
+    - type: RequestHeaderModifier
+      requestHeaderModifier:
+        add:
+        - name: X-Conformance-Request
+          value: added
+    - type: ResponseHeaderModifier
+      responseHeaderModifier:
+        set:
+        - name: X-Conformance-Response
+          value: set
+    backendRefs:
+    - name: infra-backend-v1
+      port: 8080
+---
+apiVersion: gateway.networking.k8s.io/v1
+kind: HTTPRoute
+metadata:
+  name: invalid-backend
+spec:
+  parentRefs:
+  - name: %[1]s
+  hostnames:
+  - invalid.example.com
+  rules:
+  - backendRefs:
+    - name: does-not-exist
+      port: 8080
+`, gatewayName, gatewayClass, gatewayPort)
+}
+
+// Result is the outcome of a conformance test.
+type Result struct {
+	Feature features.FeatureName `json:"feature"`
+	Test    string               `json:"test"`
+	Passed  bool                 `json:"passed"`
+	Message string               `json:"message,omitempty"`
+}
+
+// FeatureResults returns whether each feature passed, which requires all its tests to pass, in order of appearance.
+func FeatureResults(results []Result) ([]features.FeatureName, map[features.FeatureName]bool) {
+	var order []features.FeatureName
+	passed := map[features.FeatureName]bool{}
+	for _, r := range results {
+		if _, ok := passed[r.Feature]; !ok {
+			order = append(order, r.Feature)
+			passed[r.Feature] = true
+		}
+		passed[r.Feature] = passed[r.Feature] && r.Passed
+	}
+	return order, passed
+}
+
+// echoResponse is the request reflected by the echo server.
+type echoResponse struct {
+	Path    string              `json:"path"`
+	Headers map[string][]string `json:"headers"`
+	Pod     string              `json:"pod"`
+
+	// status and responseHeaders are taken from the HTTP response rather than its body.
+	status          int
+	responseHeaders http.Header
+}
+
+// request is a request sent through the Gateway, with its expected outcome.
+type request struct {
+	host    string
+	path    string
+	headers map[string]string
+
+	// status is the expected status code, 200 if unset.
+	status int
+	// backend is the prefix of the pod expected to serve the request, if any.
+	backend string
+	// requestHeaders are the headers the backend is expected to receive.
+	requestHeaders map[string]string
+	// responseHeaders are the headers the client is expected to receive.
+	responseHeaders map[string]string
+}
+
+// check returns an error describing how the response differs from the expectation.
+func (r request) check(resp *echoResponse) error {
+	status := r.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	if resp.status != status {
+		return fmt.Errorf("expected status %d, got %d", status, resp.status)
+	}
+	if r.backend != "" && !strings.HasPrefix(resp.Pod, r.backend) {
+		return fmt.Errorf("expected the request to be served by %s, got %q", r.backend, resp.Pod)
+	}
+	for k, v := range r.requestHeaders {
+		if got := http.Header(resp.Headers).Get(k); got != v {
+			return fmt.Errorf("expected the backend to receive header %s=%q, got %q", k, v, got)
+		}
+	}
+	for k, v := range r.responseHeaders {
+		if got := resp.responseHeaders.Get(k); got != v {
+			return fmt.Errorf("expected response header %s=%q, got %q", k, v, got)
+		}
+	}
+	return nil
+}
+
+// session holds the state shared by the conformance tests.
+type session struct {
+	client    kube.CLIClient
+	namespace string
+	timeout   time.Duration
+	http      *http.Client
+	// forwarder forwards a local address to the Gateway, once its pod is running.
+	forwarder kube.PortForwarder
+}
+
+type conformanceTest struct {
+	feature features.FeatureName
+	name    string
+	run     func(s *session) error
+}
+
+var conformanceTests = []conformanceTest{
+	{
+		feature: features.SupportGateway,
+		name:    "GatewayProgrammed",
+		run: func(s *session) error {
+			return s.poll(func() error {
+				gw, err := s.client.GatewayAPI().GatewayV1().Gateways(s.namespace).Get(context.Background(), gatewayName, metav1.GetOptions{})
+				if err != nil {
+					return err
+				}
+				for _, c := range []gateway.GatewayConditionType{gateway.GatewayConditionAccepted, gateway.GatewayConditionProgrammed} {
+					if !meta.IsStatusConditionTrue(gw.Status.Conditions, string(c)) {
+						return fmt.Errorf("gateway condition %s is not True", c)
+					}
+				}
+				return nil
+			})
+		},
+	},
+	{
+		feature: features.SupportHTTPRoute,
+		name:    "HTTPRouteAccepted",
+		run: func(s *session) error {
+			for _, name := range []string{"matching", "header-modifier"} {
+				if err := s.routeConditions(name, gateway.RouteConditionResolvedRefs, metav1.ConditionTrue, ""); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		feature: features.SupportHTTPRoute,
+		name:    "HTTPRouteInvalidBackendRefNotFound",
+		run: func(s *session) error {
+			if err := s.routeConditions("invalid-backend", gateway.RouteConditionResolvedRefs, metav1.ConditionFalse,
+				string(gateway.RouteReasonBackendNotFound)); err != nil {
+				return err
+			}
+			return s.send(request{host: "invalid.example.com", path: "/", status: http.StatusInternalServerError})
+		},
+	},
+	{
+		feature: features.SupportHTTPRoute,
+		name:    "HTTPRouteMatching",
+		run: func(s *session) error {
+			return s.send(
+				request{host: "matching.example.com", path: "/", backend: "infra-backend-v1"},
+				request{host: "matching.example.com", path: "/v2/foo", backend: "infra-backend-v2"},
+				request{host: "matching.example.com", path: "/", headers: map[string]string{"version": "two"}, backend: "infra-backend-v2"},
+			)
+		},
+	},
+	{
+		feature: features.SupportHTTPRouteQueryParamMatching,
+		name:    "HTTPRouteQueryParamMatching",
+		run: func(s *session) error {
+			return s.send(request{host: "matching.example.com", path: "/?version=two", backend: "infra-backend-v2"})
+		},
+	},
+	{
+		feature: features.SupportHTTPRoute,
+		name:    "HTTPRouteRequestHeaderModifier",
+		run: func(s *session) error {
+			return s.send(request{
+				host:           "modifier.example.com",
+				path:           "/",
+				backend:        "infra-backend-v1",
+				requestHeaders: map[string]string{"X-Conformance-Request": "added"},
+			})
+		},
+	},
+	{
+		feature: features.SupportHTTPRouteResponseHeaderModification,
+		name:    "HTTPRouteResponseHeaderModifier",
+		run: func(s *session) error {
+			return s.send(request{
+				host:            "modifier.example.com",
+				path:            "/",
+				responseHeaders: map[string]string{"X-Conformance-Response": "set"},
+			})
+		},
+	},
+}
+
+// poll calls fn until it succeeds or the timeout expires, returning the last error.
+func (s *session) poll(fn func() error) error {
+	deadline := time.Now().Add(s.timeout)
+	for {
+		err := fn()
+		if err == nil {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return err
+		}
+		time.Sleep(time.Second)
+	}
+}
+
+// routeConditions waits for the HTTPRoute to be accepted by the Gateway, with the condition in the expected state.
+func (s *session) routeConditions(name string, condition gateway.RouteConditionType, status metav1.ConditionStatus, reason string) error {
+	return s.poll(func() error {
+		route, err := s.client.GatewayAPI().GatewayV1().HTTPRoutes(s.namespace).Get(context.Background(), name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		for _, parent := range route.Status.Parents {
+			if string(parent.ParentRef.Name) != gatewayName {
+				continue
+			}
+			if !meta.IsStatusConditionTrue(parent.Conditions, string(gateway.RouteConditionAccepted)) {
+				return fmt.Errorf("HTTPRoute %s is not accepted", name)
+			}
+			c := meta.FindStatusCondition(parent.Conditions, string(condition))
+			if c == nil || c.Status != status || reason != "" && c.Reason != reason {
+				return fmt.Errorf("HTTPRoute %s: expected condition %s to be %s %s, got %v", name, condition, status, reason, c)
+			}
+			return nil
+		}
+		return fmt.Errorf("HTTPRoute %s has no status for Gateway %s", name, gatewayName)
+	})
+}
+
+// send sends the requests through the Gateway, retrying each until it succeeds or the timeout expires, as
+// configuration takes time to reach the Gateway.
+func (s *session) send(requests ...request) error {
+	for _, r := range requests {
+		if err := s.poll(func() error {
+			resp, err := s.do(r)
+			if err != nil {
+				return err
+			}
+			return r.check(resp)
+		}); err != nil {
+			return fmt.Errorf("%s%s: %v", r.host, r.path, err)
+		}
+	}
+	return nil
+}
+
+// connect forwards a local port to a running pod of the Gateway.
+func (s *session) connect() error {
+	if s.forwarder != nil {
+		return nil
+	}
+	pods, err := s.client.PodsForSelector(context.Background(), s.namespace, label.IoK8sNetworkingGatewayGatewayName.Name+"="+gatewayName)
+	if err != nil {
+		return err
+	}
+	for _, pod := range pods.Items {
+		if pod.Status.Phase != corev1.PodRunning {
+			continue
+		}
+		fw, err := s.client.NewPortForwarder(pod.Name, pod.Namespace, "", 0, gatewayPort)
+		if err != nil {
+			return err
+		}
+		if err := fw.Start(); err != nil {
+			return err
+		}
+		s.forwarder = fw
+		return nil
+	}
+	return fmt.Errorf("no running pod found for Gateway %s", gatewayName)
+}
+
+func (s *session) close() {
+	if s.forwarder != nil {
+		s.forwarder.Close()
+	}
+}
+
+func (s *session) do(r request) (*echoResponse, error) {
+	if err := s.connect(); err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(http.MethodGet, "http://"+s.forwarder.Address()+r.path, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Host = r.host
+	for k, v := range r.headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := s.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	res := &echoResponse{}
+	if resp.StatusCode == http.StatusOK {
+		if err := json.Unmarshal(body, res); err != nil {
+			return nil, fmt.Errorf("invalid response from the echo server: %v", err)
+		}
+	}
+	res.status = resp.StatusCode
+	res.responseHeaders = resp.Header
+	return res, nil
+}
+
+// runTests runs the conformance tests, skipping those of features which are not selected.
+func runTests(s *session, selected func(features.FeatureName) bool) []Result {
+	var results []Result
+	for _, t := range conformanceTests {
+		if !selected(t.feature) {
+			continue
+		}
+		r := Result{Feature: t.feature, Test: t.name, Passed: true}
+		if err := t.run(s); err != nil {
+			r.Passed = false
+			r.Message = err.Error()
+		}
+		results = append(results, r)
+	}
+	return results
+}
@@ -0,0 +1,64 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gatewayapi
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"sigs.k8s.io/gateway-api/pkg/features"
+	"sigs.k8s.io/yaml"
+
+	"istio.io/istio/pkg/test/util/assert"
+)
+
+func TestManifests(t *testing.T) {
+	docs := strings.Split(manifests("istio"), "---\n")
+	assert.Equal(t, len(docs), 8)
+	for _, doc := range docs {
+		obj := map[string]any{}
+		assert.NoError(t, yaml.Unmarshal([]byte(doc), &obj))
+		assert.Equal(t, obj["kind"] != nil, true)
+	}
+}
+
+func TestRequestCheck(t *testing.T) {
+	resp := &echoResponse{
+		Pod:             "infra-backend-v2-6b8b9c4d5f-abcde",
+		Headers:         map[string][]string{"X-Conformance-Request": {"added"}},
+		status:          http.StatusOK,
+		responseHeaders: http.Header{"X-Conformance-Response": {"set"}},
+	}
+	assert.NoError(t, request{
+		backend:         "infra-backend-v2",
+		requestHeaders:  map[string]string{"X-Conformance-Request": "added"},
+		responseHeaders: map[string]string{"X-Conformance-Response": "set"},
+	}.check(resp))
+	assert.Error(t, request{backend: "infra-backend-v1"}.check(resp))
+	assert.Error(t, request{requestHeaders: map[string]string{"X-Missing": "value"}}.check(resp))
+	assert.Error(t, request{status: http.StatusInternalServerError}.check(resp))
+}
+
+func TestFeatureResults(t *testing.T) {
+	order, passed := FeatureResults([]Result{
+		{Feature: features.SupportGateway, Passed: true},
+		{Feature: features.SupportHTTPRoute, Passed: true},
+		{Feature: features.SupportHTTPRoute, Passed: false},
+		{Feature: features.SupportHTTPRoute, Passed: true},
+	})
+	assert.Equal(t, order, []features.FeatureName{features.SupportGateway, features.SupportHTTPRoute})
+	assert.Equal(t, passed, map[features.FeatureName]bool{features.SupportGateway: true, features.SupportHTTPRoute: false})
+}
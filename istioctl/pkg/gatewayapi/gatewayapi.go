@@ -0,0 +1,172 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gatewayapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/gateway-api/pkg/features"
+
+	"istio.io/istio/istioctl/pkg/cli"
+	"istio.io/istio/pkg/kube"
+	"istio.io/istio/pkg/util/sets"
+)
+
+func Cmd(ctx cli.Context) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "gateway-api",
+		Short: "Commands to check the Gateway API implementation of Istio",
+	}
+	cmd.AddCommand(conformanceCmd(ctx))
+	return cmd
+}
+
+func conformanceCmd(ctx cli.Context) *cobra.Command {
+	var (
+		gatewayClass string
+		namespace    string
+		selected     []string
+		timeout      time.Duration
+		cleanup      bool
+		outputFormat string
+	)
+	cmd := &cobra.Command{
+		Use:   "conformance",
+		Short: "Runs a subset of the Gateway API conformance tests against the cluster",
+		Long: `Runs a curated subset of the upstream Gateway API conformance tests against the Istio installation of the
+cluster, and reports whether each feature passed.
+
+The tests deploy echo backends, a Gateway and HTTPRoutes in a dedicated namespace, check their status conditions,
+and send requests through the Gateway using a port forward to its pod. The namespace is deleted afterwards, unless
+--cleanup=false is set to investigate failures.`,
+		Example: `  # Run all the tests against the istio GatewayClass
+  istioctl experimental gateway-api conformance
+
+  # Only test HTTPRoute response header modification, keeping the resources afterwards
+  istioctl experimental gateway-api conformance --features HTTPRouteResponseHeaderModification --cleanup=false`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if outputFormat != "table" && outputFormat != "json" {
+				return fmt.Errorf("unknown output format %q, must be table or json", outputFormat)
+			}
+			kubeClient, err := ctx.CLIClient()
+			if err != nil {
+				return err
+			}
+			return runConformance(cmd.OutOrStdout(), kubeClient, gatewayClass, namespace, sets.New(selected...), timeout, cleanup, outputFormat)
+		},
+	}
+	cmd.Flags().StringVar(&gatewayClass, "gateway-class", "istio", "The GatewayClass of the Gateway under test")
+	cmd.Flags().StringVar(&namespace, "test-namespace", "istioctl-gateway-conformance",
+		"The namespace in which the test resources are created; it must not exist")
+	cmd.Flags().StringSliceVar(&selected, "features", nil, "The features to test, all if unset")
+	cmd.Flags().DurationVar(&timeout, "timeout", 3*time.Minute, "The maximum time to wait for each test to pass")
+	cmd.Flags().BoolVar(&cleanup, "cleanup", true, "Delete the test namespace once the tests complete")
+	cmd.Flags().StringVarP(&outputFormat, "output", "o", "table", "Output format: one of table|json")
+	return cmd
+}
+
+func runConformance(w io.Writer, kubeClient kube.CLIClient, gatewayClass, namespace string, selected sets.String,
+	timeout time.Duration, cleanup bool, outputFormat string,
+) error {
+	if _, err := kubeClient.GatewayAPI().GatewayV1().GatewayClasses().Get(context.TODO(), gatewayClass, metav1.GetOptions{}); err != nil {
+		return fmt.Errorf("failed to retrieve GatewayClass %s: %v", gatewayClass, err)
+	}
+	ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace}}
+	if _, err := kubeClient.Kube().CoreV1().Namespaces().Create(context.TODO(), ns, metav1.CreateOptions{}); err != nil {
+		if kerrors.IsAlreadyExists(err) {
+			return fmt.Errorf("namespace %s already exists; delete it or use --test-namespace", namespace)
+		}
+		return err
+	}
+	if cleanup {
+		defer func() {
+			if err := kubeClient.Kube().CoreV1().Namespaces().Delete(context.TODO(), namespace, metav1.DeleteOptions{}); err != nil {
+				fmt.Fprintf(w, "failed to delete namespace %s: %v\n", namespace, err)
+			}
+		}()
+	}
+	if err := kubeClient.ApplyYAMLContents(namespace, manifests(gatewayClass)); err != nil {
+		return fmt.Errorf("failed to apply the test resources: %v", err)
+	}
+
+	s := &session{
+		client:    kubeClient,
+		namespace: namespace,
+		timeout:   timeout,
+		http:      &http.Client{Timeout: 5 * time.Second},
+	}
+	defer s.close()
+	results := runTests(s, func(f features.FeatureName) bool {
+		return selected.IsEmpty() || selected.Contains(string(f))
+	})
+	if len(results) == 0 {
+		return fmt.Errorf("no test covers the selected features")
+	}
+
+	if err := printResults(w, results, outputFormat); err != nil {
+		return err
+	}
+	for _, r := range results {
+		if !r.Passed {
+			return fmt.Errorf("some conformance tests failed")
+		}
+	}
+	return nil
+}
+
+func printResults(w io.Writer, results []Result, outputFormat string) error {
+	if outputFormat == "json" {
+		out, err := json.MarshalIndent(results, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(out))
+		return err
+	}
+	tw := tabwriter.NewWriter(w, 0, 8, 1, ' ', 0)
+	fmt.Fprintln(tw, "FEATURE\tTEST\tRESULT\tMESSAGE")
+	for _, r := range results {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", r.Feature, r.Test, resultString(r.Passed), r.Message)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	order, passed := FeatureResults(results)
+	fmt.Fprintln(w)
+	tw = tabwriter.NewWriter(w, 0, 8, 1, ' ', 0)
+	fmt.Fprintln(tw, "FEATURE\tRESULT")
+	for _, f := range order {
+		fmt.Fprintf(tw, "%s\t%s\n", f, resultString(passed[f]))
+	}
+	return tw.Flush()
+}
+
+func resultString(passed bool) string {
+	if passed {
+		return "PASS"
+	}
+	return "FAIL"
+}
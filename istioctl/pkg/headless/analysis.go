@@ -0,0 +1,214 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package headless
+
+import (
+	"fmt"
+	"strings"
+
+	cluster "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	endpoint "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
+
+	"istio.io/istio/istioctl/pkg/protocolcheck"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/networking/util"
+	"istio.io/istio/pkg/config/protocol"
+	dnsProto "istio.io/istio/pkg/dns/proto"
+	"istio.io/istio/pkg/util/sets"
+)
+
+// Status is the outcome of a check for a pod of the headless service.
+type Status string
+
+const (
+	StatusOK      Status = "OK"
+	StatusMissing Status = "MISSING"
+	StatusWrong   Status = "WRONG"
+	// StatusNotApplicable is used when a check does not apply, such as DNS when the proxy does not capture DNS.
+	StatusNotApplicable Status = "-"
+)
+
+// Member is a pod backing the headless service, as listed in its EndpointSlices.
+type Member struct {
+	Pod      string
+	IP       string
+	Hostname string
+	// Sidecar is true if the pod has a sidecar, and thus accepts istio mTLS.
+	Sidecar bool
+}
+
+// ClientView is the configuration of the client proxy for a port of the headless service.
+type ClientView struct {
+	// Cluster is the outbound cluster of the service port, or nil if the proxy has none.
+	Cluster *cluster.Cluster
+	// LoadAssignment is the EDS configuration of the cluster, or nil if the proxy has none.
+	LoadAssignment *endpoint.ClusterLoadAssignment
+	// Listeners are the names of the listeners of the proxy.
+	Listeners sets.String
+	// NameTable is the DNS name table of the proxy, or nil if the proxy does not capture DNS.
+	NameTable *dnsProto.NameTable
+}
+
+// MemberResult holds the outcome of the checks for a member of the headless service.
+type MemberResult struct {
+	Member
+	// DNSName is the per pod DNS name of the member, empty if the pod has no hostname.
+	DNSName  string
+	Endpoint Status
+	DNS      Status
+	MTLS     Status
+	Findings []string
+}
+
+// CheckMembers checks that the client proxy can address each member of the headless service directly on a port:
+// its per pod DNS name resolves to its IP through the DNS proxy, its IP is programmed in the proxy, and connections
+// to it use istio mTLS when the pod has a sidecar.
+//
+// Headless services use ORIGINAL_DST clusters, without endpoints: TCP ports get an outbound listener per pod IP
+// instead, while HTTP ports are routed to any pod IP through the wildcard listener of the port.
+func CheckMembers(host string, port ServicePort, members []Member, view ClientView) []MemberResult {
+	endpoints := map[string]*endpoint.LbEndpoint{}
+	for _, locality := range view.LoadAssignment.GetEndpoints() {
+		for _, ep := range locality.GetLbEndpoints() {
+			if addr := ep.GetEndpoint().GetAddress().GetSocketAddress(); addr != nil {
+				endpoints[addr.GetAddress()] = ep
+			}
+		}
+	}
+	var client protocolcheck.ClientSettings
+	var clientErr error
+	if view.Cluster != nil {
+		client, clientErr = protocolcheck.ClientSettingsForCluster(view.Cluster, true)
+	}
+
+	res := make([]MemberResult, 0, len(members))
+	for _, m := range members {
+		r := MemberResult{Member: m, Endpoint: StatusOK, DNS: StatusNotApplicable, MTLS: StatusNotApplicable}
+		if m.Hostname != "" {
+			r.DNSName = m.Hostname + "." + host
+		}
+
+		ep, programmed := endpoints[m.IP]
+		switch {
+		case view.Cluster.GetType() != cluster.Cluster_ORIGINAL_DST:
+			if !programmed {
+				r.Endpoint = StatusMissing
+				r.Findings = append(r.Findings, fmt.Sprintf("%s is not an endpoint of the service in the client proxy; "+
+					"the proxy may not have received the latest configuration", m.IP))
+			}
+		case port.Protocol.IsTCP() || port.Protocol.IsUnsupported():
+			programmed = view.Listeners.Contains(fmt.Sprintf("%s_%d", m.IP, port.Port))
+			if !programmed {
+				r.Endpoint = StatusMissing
+				r.Findings = append(r.Findings, fmt.Sprintf("the client proxy has no outbound listener for %s:%d; "+
+					"the proxy may not have received the latest configuration", m.IP, port.Port))
+			}
+		default:
+			programmed = true
+			r.Endpoint = StatusNotApplicable
+		}
+
+		switch {
+		case view.NameTable == nil:
+		case r.DNSName == "":
+			r.DNS = StatusMissing
+			r.Findings = append(r.Findings, "the pod has no hostname in the EndpointSlice; set spec.hostname and spec.subdomain, "+
+				"or the StatefulSet serviceName, to get a per pod DNS name")
+		default:
+			r.DNS = dnsStatus(view.NameTable.GetTable()[r.DNSName], m.IP)
+			switch r.DNS {
+			case StatusMissing:
+				r.Findings = append(r.Findings, fmt.Sprintf("%s is not in the name table of the DNS proxy", r.DNSName))
+			case StatusWrong:
+				r.Findings = append(r.Findings, fmt.Sprintf("the DNS proxy resolves %s to %v rather than %s",
+					r.DNSName, view.NameTable.GetTable()[r.DNSName].GetIps(), m.IP))
+			}
+		}
+
+		if view.Cluster != nil && programmed && clientErr == nil {
+			r.MTLS = mtlsStatus(client, ep, m.Sidecar)
+			if r.MTLS == StatusWrong {
+				if m.Sidecar {
+					r.Findings = append(r.Findings, fmt.Sprintf("connections to %s do not use istio mTLS (%s) although the pod has a sidecar", m.IP, client))
+				} else {
+					r.Findings = append(r.Findings, fmt.Sprintf("connections to %s use istio mTLS but the pod has no sidecar to terminate it", m.IP))
+				}
+			}
+		}
+		res = append(res, r)
+	}
+	return res
+}
+
+func dnsStatus(info *dnsProto.NameTable_NameInfo, ip string) Status {
+	if info == nil {
+		return StatusMissing
+	}
+	if len(info.GetIps()) == 1 && info.GetIps()[0] == ip {
+		return StatusOK
+	}
+	return StatusWrong
+}
+
+// mtlsStatus returns whether connections to the endpoint use istio mTLS if and only if the pod has a sidecar.
+// With auto mTLS, istio mTLS is only used for endpoints labeled with the istio TLS mode.
+// Auto mTLS is not applied to ORIGINAL_DST clusters, which use istio mTLS for all pods or none.
+func mtlsStatus(client protocolcheck.ClientSettings, ep *endpoint.LbEndpoint, sidecar bool) Status {
+	mtls := client.Mode == protocolcheck.ClientIstioMTLS
+	if mtls && client.AutoMTLS && ep != nil {
+		mtls = endpointTLSMode(ep) == model.IstioMutualTLSModeLabel
+	}
+	if mtls == sidecar {
+		return StatusOK
+	}
+	return StatusWrong
+}
+
+func endpointTLSMode(ep *endpoint.LbEndpoint) string {
+	md := ep.GetMetadata().GetFilterMetadata()[util.EnvoyTransportSocketMetadataKey]
+	return md.GetFields()[model.TLSModeLabelShortname].GetStringValue()
+}
+
+// ServiceFindings returns the issues of the headless service itself which affect direct pod addressing.
+func ServiceFindings(ports []ServicePort) []string {
+	var res []string
+	for _, p := range ports {
+		if p.Protocol.IsUnsupported() {
+			res = append(res, fmt.Sprintf("port %d does not declare its protocol; name it with a protocol prefix or set appProtocol, "+
+				"as protocol detection adds latency and fails for server first protocols such as MySQL", p.Port))
+		}
+	}
+	return res
+}
+
+// ServicePort is a port of the headless service, with its declared protocol, or protocol.Unsupported.
+type ServicePort struct {
+	Port     int32
+	Protocol protocol.Instance
+}
+
+// clusterName returns the outbound cluster name prefix of a port of the service, omitting the domain suffix.
+func clusterName(port int32, name, namespace string) string {
+	return fmt.Sprintf("outbound|%d||%s.%s.svc.", port, name, namespace)
+}
+
+// hostFromCluster returns the service hostname from an outbound cluster name.
+func hostFromCluster(name string) string {
+	parts := strings.SplitN(name, "|", 4)
+	if len(parts) != 4 {
+		return ""
+	}
+	return parts[3]
+}
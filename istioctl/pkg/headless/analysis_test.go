@@ -0,0 +1,100 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package headless
+
+import (
+	"testing"
+
+	cluster "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	tlsv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
+
+	"istio.io/istio/pilot/pkg/networking/util"
+	"istio.io/istio/pilot/pkg/util/protoconv"
+	"istio.io/istio/pkg/config/protocol"
+	dnsProto "istio.io/istio/pkg/dns/proto"
+	"istio.io/istio/pkg/test/util/assert"
+	"istio.io/istio/pkg/util/sets"
+	"istio.io/istio/pkg/wellknown"
+)
+
+const host = "kafka.foo.svc.cluster.local"
+
+func originalDstCluster(mtls bool) *cluster.Cluster {
+	c := &cluster.Cluster{
+		Name:                 "outbound|9092||" + host,
+		ClusterDiscoveryType: &cluster.Cluster_Type{Type: cluster.Cluster_ORIGINAL_DST},
+	}
+	if mtls {
+		ctx := &tlsv3.UpstreamTlsContext{CommonTlsContext: &tlsv3.CommonTlsContext{
+			AlpnProtocols:                  util.ALPNInMeshWithMxc,
+			TlsCertificateSdsSecretConfigs: []*tlsv3.SdsSecretConfig{{Name: "default"}},
+		}}
+		c.TransportSocket = &core.TransportSocket{
+			Name:       wellknown.TransportSocketTLS,
+			ConfigType: &core.TransportSocket_TypedConfig{TypedConfig: protoconv.MessageToAny(ctx)},
+		}
+	}
+	return c
+}
+
+func TestCheckMembers(t *testing.T) {
+	members := []Member{
+		{Pod: "kafka-0", IP: "10.0.0.1", Hostname: "kafka-0", Sidecar: true},
+		{Pod: "kafka-1", IP: "10.0.0.2", Hostname: "kafka-1", Sidecar: true},
+		{Pod: "kafka-2", IP: "10.0.0.3", Sidecar: false},
+	}
+	view := ClientView{
+		Cluster:   originalDstCluster(true),
+		Listeners: sets.New("10.0.0.1_9092", "10.0.0.3_9092"),
+		NameTable: &dnsProto.NameTable{Table: map[string]*dnsProto.NameTable_NameInfo{
+			"kafka-0." + host: {Ips: []string{"10.0.0.1"}},
+			"kafka-1." + host: {Ips: []string{"10.0.0.9"}},
+		}},
+	}
+
+	got := CheckMembers(host, ServicePort{Port: 9092, Protocol: protocol.TCP}, members, view)
+	assert.Equal(t, len(got), 3)
+
+	assert.Equal(t, got[0].DNSName, "kafka-0."+host)
+	assert.Equal(t, []Status{got[0].Endpoint, got[0].DNS, got[0].MTLS}, []Status{StatusOK, StatusOK, StatusOK})
+	assert.Equal(t, len(got[0].Findings), 0)
+
+	// No per pod listener, and a stale DNS entry.
+	assert.Equal(t, []Status{got[1].Endpoint, got[1].DNS, got[1].MTLS}, []Status{StatusMissing, StatusWrong, StatusNotApplicable})
+	assert.Equal(t, len(got[1].Findings), 2)
+
+	// No hostname, and istio mTLS sent to a pod without sidecar.
+	assert.Equal(t, []Status{got[2].Endpoint, got[2].DNS, got[2].MTLS}, []Status{StatusOK, StatusMissing, StatusWrong})
+	assert.Equal(t, len(got[2].Findings), 2)
+
+	// HTTP ports are routed through the wildcard listener, and DNS is not checked without DNS capture.
+	view = ClientView{Cluster: originalDstCluster(false)}
+	got = CheckMembers(host, ServicePort{Port: 8080, Protocol: protocol.HTTP}, members[:1], view)
+	assert.Equal(t, []Status{got[0].Endpoint, got[0].DNS, got[0].MTLS}, []Status{StatusNotApplicable, StatusNotApplicable, StatusWrong})
+}
+
+func TestServiceFindings(t *testing.T) {
+	findings := ServiceFindings([]ServicePort{
+		{Port: 9092, Protocol: protocol.TCP},
+		{Port: 3306, Protocol: protocol.Unsupported},
+	})
+	assert.Equal(t, len(findings), 1)
+}
+
+func TestHostFromCluster(t *testing.T) {
+	assert.Equal(t, hostFromCluster("outbound|9092||"+host), host)
+	assert.Equal(t, hostFromCluster("PassthroughCluster"), "")
+}
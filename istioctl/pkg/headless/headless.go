@@ -0,0 +1,307 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package headless
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	cluster "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	endpoint "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
+	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"istio.io/api/annotation"
+	"istio.io/istio/istioctl/pkg/cli"
+	"istio.io/istio/istioctl/pkg/clioptions"
+	"istio.io/istio/istioctl/pkg/completion"
+	"istio.io/istio/istioctl/pkg/multixds"
+	"istio.io/istio/istioctl/pkg/util/configdump"
+	v3 "istio.io/istio/pilot/pkg/xds/v3"
+	configkube "istio.io/istio/pkg/config/kube"
+	dnsProto "istio.io/istio/pkg/dns/proto"
+	"istio.io/istio/pkg/kube"
+	"istio.io/istio/pkg/util/protomarshal"
+	"istio.io/istio/pkg/util/sets"
+)
+
+func Cmd(ctx cli.Context) *cobra.Command {
+	var centralOpts clioptions.CentralControlPlaneOptions
+	var from string
+	cmd := &cobra.Command{
+		Use:   "headless-check <service>[.<namespace>] --from <pod-name>[.<namespace>]",
+		Short: "Diagnoses direct pod addressing of a headless service, as used by StatefulSets",
+		Long: `Diagnoses how a client proxy addresses the pods of a headless service directly, as StatefulSet workloads
+such as Kafka or databases do. For each pod backing the service, and each service port, it checks that:
+
+  * the per pod DNS name (<hostname>.<service>.<namespace>.svc.<domain>) resolves to the pod IP through the DNS
+    proxy of the client, when DNS capture is enabled,
+  * the pod IP is programmed as an endpoint of the service cluster of the client,
+  * connections to the pod IP use istio mTLS if, and only if, the pod has a sidecar.
+
+Ports of the service which do not declare their protocol are reported as well.`,
+		Example: `  # Check how a consumer pod reaches the pods of the kafka headless service
+  istioctl experimental headless-check kafka-headless.kafka --from consumer-5d8c9b8b9b-x2v4q.apps`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				cmd.Println(cmd.UsageString())
+				return fmt.Errorf("headless-check requires a service")
+			}
+			if from == "" {
+				return fmt.Errorf("--from is required")
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			kubeClient, err := ctx.CLIClient()
+			if err != nil {
+				return err
+			}
+			svcName, svcNamespace, ok := strings.Cut(args[0], ".")
+			if !ok {
+				svcNamespace = ctx.NamespaceOrDefault(ctx.Namespace())
+			}
+			podName, podNamespace, err := ctx.InferPodInfoFromTypedResource(from, ctx.NamespaceOrDefault(ctx.Namespace()))
+			if err != nil {
+				return err
+			}
+			return run(cmd.OutOrStdout(), kubeClient, centralOpts, ctx.IstioNamespace(), svcName, svcNamespace, podName, podNamespace)
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			return completion.ValidServiceArgs(cmd, ctx, args, toComplete)
+		},
+	}
+	centralOpts.AttachControlPlaneFlags(cmd)
+	cmd.Flags().StringVar(&from, "from", "", "The client pod, whose proxy configuration is checked")
+	return cmd
+}
+
+func run(w io.Writer, kubeClient kube.CLIClient, centralOpts clioptions.CentralControlPlaneOptions, istioNamespace,
+	svcName, svcNamespace, podName, podNamespace string,
+) error {
+	svc, err := kubeClient.Kube().CoreV1().Services(svcNamespace).Get(context.TODO(), svcName, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to retrieve service %s.%s: %v", svcName, svcNamespace, err)
+	}
+	if svc.Spec.ClusterIP != corev1.ClusterIPNone {
+		return fmt.Errorf("service %s.%s is not headless", svcName, svcNamespace)
+	}
+	members, err := serviceMembers(kubeClient, svc)
+	if err != nil {
+		return err
+	}
+
+	cd, err := configDump(kubeClient, podName, podNamespace)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve the configuration of %s.%s: %v", podName, podNamespace, err)
+	}
+	clusters, err := clustersByName(cd)
+	if err != nil {
+		return err
+	}
+	loadAssignments, err := loadAssignmentsByCluster(cd)
+	if err != nil {
+		return err
+	}
+	listeners, err := listenerNames(cd)
+	if err != nil {
+		return err
+	}
+	nameTable, err := proxyNameTable(kubeClient, centralOpts, istioNamespace, podName, podNamespace)
+	if err != nil {
+		return err
+	}
+
+	ports := make([]ServicePort, 0, len(svc.Spec.Ports))
+	for _, p := range svc.Spec.Ports {
+		ports = append(ports, ServicePort{Port: p.Port, Protocol: configkube.ConvertProtocol(p.Port, p.Name, p.Protocol, p.AppProtocol)})
+	}
+
+	_, _ = fmt.Fprintf(w, "Headless service %s.%s seen from %s.%s: %d pods\n", svcName, svcNamespace, podName, podNamespace, len(members))
+	if nameTable == nil {
+		_, _ = fmt.Fprintf(w, "DNS capture is not enabled in the client proxy: per pod DNS names are resolved by the cluster DNS\n")
+	}
+	for _, f := range ServiceFindings(ports) {
+		_, _ = fmt.Fprintf(w, "  - %s\n", f)
+	}
+	for _, p := range ports {
+		_, _ = fmt.Fprintln(w)
+		view := ClientView{NameTable: nameTable, Listeners: listeners}
+		view.Cluster = findCluster(clusters, clusterName(p.Port, svcName, svcNamespace))
+		if view.Cluster == nil {
+			_, _ = fmt.Fprintf(w, "Port %d: no cluster found in the client proxy; the service may not be visible to it\n", p.Port)
+			continue
+		}
+		view.LoadAssignment = loadAssignments[view.Cluster.Name]
+		_, _ = fmt.Fprintf(w, "Port %d (%s)\n", p.Port, view.Cluster.Name)
+		printResults(w, CheckMembers(hostFromCluster(view.Cluster.Name), p, members, view))
+	}
+	return nil
+}
+
+func printResults(w io.Writer, results []MemberResult) {
+	tw := new(tabwriter.Writer).Init(w, 0, 8, 3, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "POD\tIP\tDNS NAME\tENDPOINT\tDNS\tMTLS")
+	for _, r := range results {
+		dnsName := r.DNSName
+		if dnsName == "" {
+			dnsName = "-"
+		}
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", r.Pod, r.IP, dnsName, r.Endpoint, r.DNS, r.MTLS)
+	}
+	_ = tw.Flush()
+	for _, r := range results {
+		for _, f := range r.Findings {
+			_, _ = fmt.Fprintf(w, "  - %s: %s\n", r.Pod, f)
+		}
+	}
+}
+
+// serviceMembers returns the ready pods of the service listed in its EndpointSlices, sorted by pod name.
+func serviceMembers(kubeClient kube.CLIClient, svc *corev1.Service) ([]Member, error) {
+	slices, err := kubeClient.Kube().DiscoveryV1().EndpointSlices(svc.Namespace).List(context.TODO(), metav1.ListOptions{
+		LabelSelector: discoveryv1.LabelServiceName + "=" + svc.Name,
+	})
+	if err != nil {
+		return nil, err
+	}
+	var members []Member
+	for _, slice := range slices.Items {
+		for _, ep := range slice.Endpoints {
+			if ep.Conditions.Ready != nil && !*ep.Conditions.Ready || len(ep.Addresses) == 0 {
+				continue
+			}
+			m := Member{IP: ep.Addresses[0]}
+			if ep.Hostname != nil {
+				m.Hostname = *ep.Hostname
+			}
+			if ep.TargetRef != nil && ep.TargetRef.Kind == "Pod" {
+				m.Pod = ep.TargetRef.Name
+				pod, err := kubeClient.Kube().CoreV1().Pods(svc.Namespace).Get(context.TODO(), ep.TargetRef.Name, metav1.GetOptions{})
+				if err != nil {
+					return nil, err
+				}
+				_, m.Sidecar = pod.Annotations[annotation.SidecarStatus.Name]
+			}
+			members = append(members, m)
+		}
+	}
+	sort.Slice(members, func(i, j int) bool {
+		return members[i].Pod < members[j].Pod
+	})
+	return members, nil
+}
+
+func configDump(kubeClient kube.CLIClient, podName, podNamespace string) (*configdump.Wrapper, error) {
+	b, err := kubeClient.EnvoyDo(context.TODO(), podName, podNamespace, "GET", "config_dump?include_eds")
+	if err != nil {
+		return nil, err
+	}
+	cd := &configdump.Wrapper{}
+	if err := cd.UnmarshalJSON(b); err != nil {
+		return nil, err
+	}
+	return cd, nil
+}
+
+func clustersByName(cd *configdump.Wrapper) (map[string]*cluster.Cluster, error) {
+	dump, err := cd.GetDynamicClusterDump(false)
+	if err != nil {
+		return nil, err
+	}
+	res := map[string]*cluster.Cluster{}
+	for _, dac := range dump.GetDynamicActiveClusters() {
+		c := &cluster.Cluster{}
+		if err := dac.GetCluster().UnmarshalTo(c); err != nil {
+			return nil, err
+		}
+		res[c.Name] = c
+	}
+	return res, nil
+}
+
+func loadAssignmentsByCluster(cd *configdump.Wrapper) (map[string]*endpoint.ClusterLoadAssignment, error) {
+	dump, err := cd.GetEndpointsConfigDump()
+	if err != nil {
+		return nil, err
+	}
+	res := map[string]*endpoint.ClusterLoadAssignment{}
+	for _, dec := range dump.GetDynamicEndpointConfigs() {
+		cla := &endpoint.ClusterLoadAssignment{}
+		if err := dec.GetEndpointConfig().UnmarshalTo(cla); err != nil {
+			return nil, err
+		}
+		res[cla.ClusterName] = cla
+	}
+	return res, nil
+}
+
+func listenerNames(cd *configdump.Wrapper) (sets.String, error) {
+	dump, err := cd.GetDynamicListenerDump(false)
+	if err != nil {
+		return nil, err
+	}
+	res := sets.New[string]()
+	for _, l := range dump.GetDynamicListeners() {
+		res.Insert(l.GetName())
+	}
+	return res, nil
+}
+
+// findCluster returns the cluster whose name starts with the prefix, which omits the domain suffix.
+func findCluster(clusters map[string]*cluster.Cluster, prefix string) *cluster.Cluster {
+	for name, c := range clusters {
+		if strings.HasPrefix(name, prefix) {
+			return c
+		}
+	}
+	return nil
+}
+
+// proxyNameTable returns the DNS name table istiod sends to the proxy, or nil if the proxy does not capture DNS.
+func proxyNameTable(kubeClient kube.CLIClient, centralOpts clioptions.CentralControlPlaneOptions, istioNamespace,
+	podName, podNamespace string,
+) (*dnsProto.NameTable, error) {
+	xdsRequest := discovery.DiscoveryRequest{
+		ResourceNames: []string{fmt.Sprintf("ndsz?proxyID=%s.%s", podName, podNamespace)},
+		Node: &core.Node{
+			Id: "debug~0.0.0.0~istioctl~cluster.local",
+		},
+		TypeUrl: v3.DebugType,
+	}
+	responses, err := multixds.FirstRequestAndProcessXds(&xdsRequest, centralOpts, istioNamespace, "", "", kubeClient, multixds.DefaultOptions)
+	if err != nil {
+		return nil, err
+	}
+	for _, dr := range responses {
+		for _, r := range dr.GetResources() {
+			nt := &dnsProto.NameTable{}
+			// Proxies without DNS capture get a plain text error rather than a name table.
+			if err := protomarshal.UnmarshalAllowUnknown(r.GetValue(), nt); err != nil {
+				return nil, nil
+			}
+			return nt, nil
+		}
+	}
+	return nil, nil
+}
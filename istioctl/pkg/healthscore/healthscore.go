@@ -0,0 +1,482 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package healthscore
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	xdsstatus "github.com/envoyproxy/go-control-plane/envoy/service/status/v3"
+	"github.com/prometheus/client_golang/api"
+	promv1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"istio.io/istio/istioctl/pkg/cli"
+	"istio.io/istio/istioctl/pkg/clioptions"
+	"istio.io/istio/istioctl/pkg/dashboard"
+	"istio.io/istio/istioctl/pkg/multixds"
+	pilotxds "istio.io/istio/pilot/pkg/xds"
+	"istio.io/istio/pkg/kube"
+	"istio.io/istio/pkg/log"
+)
+
+const (
+	shortOutput = "short"
+	tableOutput = "table"
+	jsonOutput  = "json"
+
+	factorControlPlane     = "control plane"
+	factorProxySync        = "proxy sync"
+	factorCertExpiry       = "cert expiry"
+	factorConfigRejections = "config rejections"
+	factorDataPlaneErrors  = "data plane errors"
+)
+
+// Factor is a single contributor to the mesh health score.
+type Factor struct {
+	Name string `json:"name"`
+	// Score is between 0 and 100.
+	Score  float64 `json:"score"`
+	Weight float64 `json:"weight"`
+	Detail string  `json:"detail"`
+	// Skipped is set when the data for the factor could not be collected. Skipped factors do not contribute to the score.
+	Skipped bool `json:"skipped,omitempty"`
+}
+
+// Report is the result of a single health score computation.
+type Report struct {
+	Time    time.Time `json:"time"`
+	Score   float64   `json:"score"`
+	Factors []Factor  `json:"factors"`
+}
+
+type options struct {
+	duration    time.Duration
+	slo         float64
+	certMargin  time.Duration
+	historyFile string
+	output      string
+}
+
+func Cmd(ctx cli.Context) *cobra.Command {
+	var opts clioptions.ControlPlaneOptions
+	var centralOpts clioptions.CentralControlPlaneOptions
+	o := options{}
+	cmd := &cobra.Command{
+		Use:   "health-score",
+		Short: "Computes a composite health score for the mesh",
+		Long: `Computes a composite health score between 0 and 100 for the mesh, with a breakdown per contributing factor:
+
+  control plane       ratio of ready istiod replicas
+  proxy sync          ratio of proxies which have acknowledged the latest configuration pushed by istiod
+  cert expiry         time remaining before the CA root certificate expires, relative to --cert-margin
+  config rejections   configuration rejected (NACKed) by proxies over --duration
+  data plane errors   error budget remaining for the server reported 5xx ratio over --duration, relative to --slo
+
+Factors relying on metrics require a Prometheus pod labeled app.kubernetes.io/name=prometheus in the Istio namespace;
+they are skipped if it is not found. When --history is set, each run is appended to the file and the score is compared
+against the previous run.`,
+		Example: `  # Print the mesh health score with a breakdown per factor
+  istioctl experimental health-score
+
+  # Print only the one line summary, tracking the trend between runs
+  istioctl experimental health-score -o short --history ~/.istio-health.jsonl
+
+  # Use a 99.5% success rate objective computed over the last hour
+  istioctl experimental health-score --slo 99.5 --duration 1h`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if o.output != shortOutput && o.output != tableOutput && o.output != jsonOutput {
+				return fmt.Errorf("unknown output format %q, expected one of %s, %s or %s", o.output, shortOutput, tableOutput, jsonOutput)
+			}
+			if o.slo <= 0 || o.slo >= 100 {
+				return fmt.Errorf("--slo must be between 0 and 100 exclusive, got %v", o.slo)
+			}
+			kubeClient, err := ctx.CLIClientWithRevision(opts.Revision)
+			if err != nil {
+				return err
+			}
+			factors := []Factor{
+				controlPlaneFactor(kubeClient, ctx.IstioNamespace(), opts.Revision),
+				proxySyncFactor(kubeClient, ctx.IstioNamespace(), centralOpts),
+			}
+			factors = append(factors, prometheusFactors(kubeClient, ctx.IstioNamespace(), o)...)
+			report := NewReport(time.Now(), factors)
+
+			var previous *Report
+			if o.historyFile != "" {
+				if previous, err = lastReport(o.historyFile); err != nil {
+					return err
+				}
+				if err := appendReport(o.historyFile, report); err != nil {
+					return err
+				}
+			}
+			return printReport(cmd.OutOrStdout(), o.output, report, previous)
+		},
+	}
+	opts.AttachControlPlaneFlags(cmd)
+	centralOpts.AttachControlPlaneFlags(cmd)
+	cmd.Flags().DurationVarP(&o.duration, "duration", "d", 5*time.Minute, "Duration over which rates are computed")
+	cmd.Flags().Float64Var(&o.slo, "slo", 99.9, "Success rate objective for data plane requests, in percent")
+	cmd.Flags().DurationVar(&o.certMargin, "cert-margin", 30*24*time.Hour, "Time before expiry below which the root certificate lowers the score")
+	cmd.Flags().StringVar(&o.historyFile, "history", "", "File to record scores in, used to report the trend since the previous run")
+	cmd.Flags().StringVarP(&o.output, "output", "o", tableOutput, "Output format: one of short|table|json")
+	return cmd
+}
+
+// NewReport computes the weighted score of the factors which were not skipped.
+func NewReport(t time.Time, factors []Factor) Report {
+	var total, weights float64
+	for _, f := range factors {
+		if f.Skipped {
+			continue
+		}
+		total += f.Score * f.Weight
+		weights += f.Weight
+	}
+	score := 0.0
+	if weights > 0 {
+		score = total / weights
+	}
+	return Report{Time: t, Score: math.Round(score), Factors: factors}
+}
+
+func skipped(name string, weight float64, format string, args ...any) Factor {
+	return Factor{Name: name, Weight: weight, Skipped: true, Detail: fmt.Sprintf(format, args...)}
+}
+
+func controlPlaneFactor(kubeClient kube.CLIClient, istioNamespace, revision string) Factor {
+	const weight = 3
+	selector := "app=istiod"
+	if revision != "" {
+		selector += ",istio.io/rev=" + revision
+	}
+	deployments, err := kubeClient.Kube().AppsV1().Deployments(istioNamespace).List(context.TODO(), metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return skipped(factorControlPlane, weight, "failed to list istiod deployments: %v", err)
+	}
+	var desired, ready int32
+	for _, d := range deployments.Items {
+		if d.Spec.Replicas != nil {
+			desired += *d.Spec.Replicas
+		} else {
+			desired++
+		}
+		ready += d.Status.ReadyReplicas
+	}
+	return ControlPlaneScore(ready, desired, weight)
+}
+
+// ControlPlaneScore scores the control plane by the ratio of ready istiod replicas.
+func ControlPlaneScore(ready, desired int32, weight float64) Factor {
+	f := Factor{Name: factorControlPlane, Weight: weight, Detail: fmt.Sprintf("%d/%d istiod replicas ready", ready, desired)}
+	if desired > 0 {
+		f.Score = clamp(100 * float64(ready) / float64(desired))
+	}
+	return f
+}
+
+func proxySyncFactor(kubeClient kube.CLIClient, istioNamespace string, centralOpts clioptions.CentralControlPlaneOptions) Factor {
+	const weight = 2
+	xdsRequest := discovery.DiscoveryRequest{TypeUrl: pilotxds.TypeDebugSyncronization}
+	responses, err := multixds.AllRequestAndProcessXds(&xdsRequest, centralOpts, istioNamespace, "", "", kubeClient, multixds.DefaultOptions)
+	if err != nil {
+		return skipped(factorProxySync, weight, "failed to retrieve sync status from istiod: %v", err)
+	}
+	f, err := ProxySyncScore(responses, weight)
+	if err != nil {
+		return skipped(factorProxySync, weight, "%v", err)
+	}
+	return f
+}
+
+// ProxySyncScore scores the data plane by the ratio of proxies which have acknowledged all configuration sent to them.
+func ProxySyncScore(responses map[string]*discovery.DiscoveryResponse, weight float64) (Factor, error) {
+	var total, synced int
+	for _, dr := range responses {
+		for _, resource := range dr.Resources {
+			clientConfig := &xdsstatus.ClientConfig{}
+			if err := resource.UnmarshalTo(clientConfig); err != nil {
+				return Factor{}, fmt.Errorf("could not unmarshal ClientConfig: %w", err)
+			}
+			total++
+			if isSynced(clientConfig) {
+				synced++
+			}
+		}
+	}
+	if total == 0 {
+		return skipped(factorProxySync, weight, "no proxies connected"), nil
+	}
+	return Factor{
+		Name:   factorProxySync,
+		Weight: weight,
+		Score:  clamp(100 * float64(synced) / float64(total)),
+		Detail: fmt.Sprintf("%d/%d proxies synced", synced, total),
+	}, nil
+}
+
+func isSynced(clientConfig *xdsstatus.ClientConfig) bool {
+	for _, c := range clientConfig.GetGenericXdsConfigs() {
+		switch c.GetConfigStatus() {
+		case xdsstatus.ConfigStatus_STALE, xdsstatus.ConfigStatus_ERROR:
+			return false
+		}
+	}
+	return true
+}
+
+// prometheusFactors returns the factors computed from metrics, which are all skipped if Prometheus can not be reached.
+func prometheusFactors(kubeClient kube.CLIClient, istioNamespace string, o options) []Factor {
+	const certWeight, rejectWeight, errorWeight = 1, 1, 3
+	promAPI, closer, err := prometheusAPI(kubeClient, istioNamespace)
+	if err != nil {
+		return []Factor{
+			skipped(factorCertExpiry, certWeight, "%v", err),
+			skipped(factorConfigRejections, rejectWeight, "%v", err),
+			skipped(factorDataPlaneErrors, errorWeight, "%v", err),
+		}
+	}
+	defer closer()
+
+	var factors []Factor
+	expiry, ok, err := vectorValue(promAPI, "min(citadel_server_root_cert_expiry_timestamp)")
+	switch {
+	case err != nil:
+		factors = append(factors, skipped(factorCertExpiry, certWeight, "%v", err))
+	case !ok:
+		factors = append(factors, skipped(factorCertExpiry, certWeight, "no root certificate expiry reported by istiod"))
+	default:
+		remaining := time.Until(time.Unix(int64(expiry), 0))
+		factors = append(factors, CertExpiryScore(remaining, o.certMargin, certWeight))
+	}
+
+	rejects, _, err := vectorValue(promAPI, fmt.Sprintf("sum(increase(pilot_total_xds_rejects[%s]))", model.Duration(o.duration)))
+	if err != nil {
+		factors = append(factors, skipped(factorConfigRejections, rejectWeight, "%v", err))
+	} else {
+		factors = append(factors, ConfigRejectionScore(rejects, o.duration, rejectWeight))
+	}
+
+	total, _, err := vectorValue(promAPI, fmt.Sprintf(`sum(rate(istio_requests_total{reporter="destination"}[%s]))`, model.Duration(o.duration)))
+	if err != nil {
+		factors = append(factors, skipped(factorDataPlaneErrors, errorWeight, "%v", err))
+		return factors
+	}
+	errorRate, _, err := vectorValue(promAPI,
+		fmt.Sprintf(`sum(rate(istio_requests_total{reporter="destination",response_code=~"5.."}[%s]))`, model.Duration(o.duration)))
+	if err != nil {
+		factors = append(factors, skipped(factorDataPlaneErrors, errorWeight, "%v", err))
+		return factors
+	}
+	factors = append(factors, DataPlaneErrorScore(total, errorRate, o.slo, errorWeight))
+	return factors
+}
+
+// CertExpiryScore scores the remaining lifetime of the root certificate; anything beyond margin scores 100.
+func CertExpiryScore(remaining, margin time.Duration, weight float64) Factor {
+	return Factor{
+		Name:   factorCertExpiry,
+		Weight: weight,
+		Score:  clamp(100 * float64(remaining) / float64(margin)),
+		Detail: fmt.Sprintf("root certificate expires in %s", remaining.Truncate(time.Hour)),
+	}
+}
+
+// ConfigRejectionScore scores rejected configuration; each rejection costs 10 points.
+func ConfigRejectionScore(rejects float64, duration time.Duration, weight float64) Factor {
+	rejects = math.Round(rejects)
+	return Factor{
+		Name:   factorConfigRejections,
+		Weight: weight,
+		Score:  clamp(100 - 10*rejects),
+		Detail: fmt.Sprintf("%v rejections in the last %s", rejects, model.Duration(duration)),
+	}
+}
+
+// DataPlaneErrorScore scores the fraction of the error budget left by the 5xx ratio, given the success rate objective in percent.
+func DataPlaneErrorScore(totalRate, errorRate, slo, weight float64) Factor {
+	f := Factor{Name: factorDataPlaneErrors, Weight: weight}
+	if totalRate == 0 {
+		f.Skipped = true
+		f.Detail = "no traffic"
+		return f
+	}
+	successRate := 100 * (1 - errorRate/totalRate)
+	budget := 100 - slo
+	burned := (100 - successRate) / budget
+	f.Score = clamp(100 * (1 - burned))
+	f.Detail = fmt.Sprintf("%.3f%% success rate against a %v%% objective", successRate, slo)
+	return f
+}
+
+func clamp(v float64) float64 {
+	return math.Round(math.Max(0, math.Min(100, v)))
+}
+
+func prometheusAPI(kubeClient kube.CLIClient, istioNamespace string) (promv1.API, func(), error) {
+	pl, err := kubeClient.PodsForSelector(context.TODO(), istioNamespace, "app.kubernetes.io/name=prometheus")
+	if err != nil {
+		return nil, nil, fmt.Errorf("not able to locate Prometheus pod: %v", err)
+	}
+	if len(pl.Items) < 1 {
+		return nil, nil, errors.New("no Prometheus pods found")
+	}
+	fw, err := kubeClient.NewPortForwarder(pl.Items[0].Name, istioNamespace, "", 0, 9090)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not build port forwarder for prometheus: %v", err)
+	}
+	if err = fw.Start(); err != nil {
+		return nil, nil, fmt.Errorf("failure running port forward process: %v", err)
+	}
+	dashboard.ClosePortForwarderOnInterrupt(fw)
+	promClient, err := api.NewClient(api.Config{Address: fmt.Sprintf("http://%s", fw.Address())})
+	if err != nil {
+		fw.Close()
+		return nil, nil, fmt.Errorf("could not build prometheus client: %v", err)
+	}
+	return promv1.NewAPI(promClient), fw.Close, nil
+}
+
+// vectorValue returns the first value of an instant vector query, and whether the query returned any value.
+func vectorValue(promAPI promv1.API, query string) (float64, bool, error) {
+	val, _, err := promAPI.Query(context.Background(), query, time.Now())
+	if err != nil {
+		return 0, false, fmt.Errorf("query() failure for '%s': %v", query, err)
+	}
+	log.Debugf("executing query: %s  result:%s", query, val)
+	v, ok := val.(model.Vector)
+	if !ok {
+		return 0, false, errors.New("bad metric value type returned for query")
+	}
+	if v.Len() < 1 {
+		return 0, false, nil
+	}
+	return float64(v[0].Value), true, nil
+}
+
+func lastReport(filename string) (*Report, error) {
+	f, err := os.Open(filename)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var last *Report
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		r := &Report{}
+		if err := json.Unmarshal(scanner.Bytes(), r); err != nil {
+			return nil, fmt.Errorf("failed to parse history file %s: %v", filename, err)
+		}
+		last = r
+	}
+	return last, scanner.Err()
+}
+
+func appendReport(filename string, r Report) error {
+	b, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(filename, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(b, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func printReport(w io.Writer, output string, r Report, previous *Report) error {
+	if output == jsonOutput {
+		b, err := json.MarshalIndent(r, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, _ = fmt.Fprintln(w, string(b))
+		return nil
+	}
+
+	summary := fmt.Sprintf("Mesh health score: %v/100", r.Score)
+	if previous != nil {
+		summary += fmt.Sprintf(" (%s since %s)", trend(r.Score, previous.Score), previous.Time.Format(time.RFC3339))
+	}
+	_, _ = fmt.Fprintln(w, summary)
+	if output == shortOutput {
+		return nil
+	}
+
+	_, _ = fmt.Fprintln(w)
+	tw := new(tabwriter.Writer).Init(w, 0, 8, 2, ' ', 0)
+	header := "FACTOR\tSCORE\tWEIGHT\tDETAIL"
+	if previous != nil {
+		header = "FACTOR\tSCORE\tTREND\tWEIGHT\tDETAIL"
+	}
+	_, _ = fmt.Fprintln(tw, header)
+	for _, f := range r.Factors {
+		score := fmt.Sprint(f.Score)
+		if f.Skipped {
+			score = "-"
+		}
+		if previous == nil {
+			_, _ = fmt.Fprintf(tw, "%s\t%s\t%v\t%s\n", f.Name, score, f.Weight, f.Detail)
+			continue
+		}
+		t := "-"
+		if p := findFactor(previous.Factors, f.Name); p != nil && !p.Skipped && !f.Skipped {
+			t = trend(f.Score, p.Score)
+		}
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%v\t%s\n", f.Name, score, t, f.Weight, f.Detail)
+	}
+	return tw.Flush()
+}
+
+func findFactor(factors []Factor, name string) *Factor {
+	for i := range factors {
+		if factors[i].Name == name {
+			return &factors[i]
+		}
+	}
+	return nil
+}
+
+func trend(current, previous float64) string {
+	d := current - previous
+	if d == 0 {
+		return "="
+	}
+	return fmt.Sprintf("%+v", d)
+}
@@ -0,0 +1,130 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package healthscore
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	xdsstatus "github.com/envoyproxy/go-control-plane/envoy/service/status/v3"
+	"google.golang.org/protobuf/types/known/anypb"
+
+	"istio.io/istio/pkg/test/util/assert"
+)
+
+func TestNewReport(t *testing.T) {
+	r := NewReport(time.Time{}, []Factor{
+		{Name: "a", Score: 100, Weight: 3},
+		{Name: "b", Score: 50, Weight: 1},
+		{Name: "c", Score: 0, Weight: 5, Skipped: true},
+	})
+	assert.Equal(t, r.Score, 88.0)
+
+	r = NewReport(time.Time{}, []Factor{{Name: "a", Weight: 1, Skipped: true}})
+	assert.Equal(t, r.Score, 0.0)
+}
+
+func TestScores(t *testing.T) {
+	cases := []struct {
+		name  string
+		got   Factor
+		score float64
+	}{
+		{"all istiod ready", ControlPlaneScore(2, 2, 1), 100},
+		{"half istiod ready", ControlPlaneScore(1, 2, 1), 50},
+		{"no istiod", ControlPlaneScore(0, 0, 1), 0},
+		{"cert beyond margin", CertExpiryScore(90*24*time.Hour, 30*24*time.Hour, 1), 100},
+		{"cert within margin", CertExpiryScore(15*24*time.Hour, 30*24*time.Hour, 1), 50},
+		{"cert expired", CertExpiryScore(-time.Hour, 30*24*time.Hour, 1), 0},
+		{"no rejections", ConfigRejectionScore(0, time.Minute, 1), 100},
+		{"some rejections", ConfigRejectionScore(3, time.Minute, 1), 70},
+		{"many rejections", ConfigRejectionScore(42, time.Minute, 1), 0},
+		{"no errors", DataPlaneErrorScore(100, 0, 99.9, 1), 100},
+		{"half budget burned", DataPlaneErrorScore(1000, 0.5, 99.9, 1), 50},
+		{"budget exhausted", DataPlaneErrorScore(100, 5, 99.9, 1), 0},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.got.Score, tt.score)
+		})
+	}
+	assert.Equal(t, DataPlaneErrorScore(0, 0, 99.9, 1).Skipped, true)
+}
+
+func TestProxySyncScore(t *testing.T) {
+	config := func(statuses ...xdsstatus.ConfigStatus) *anypb.Any {
+		cc := &xdsstatus.ClientConfig{}
+		for _, s := range statuses {
+			cc.GenericXdsConfigs = append(cc.GenericXdsConfigs, &xdsstatus.ClientConfig_GenericXdsConfig{ConfigStatus: s})
+		}
+		a, err := anypb.New(cc)
+		assert.NoError(t, err)
+		return a
+	}
+	responses := map[string]*discovery.DiscoveryResponse{
+		"istiod-1": {Resources: []*anypb.Any{
+			config(xdsstatus.ConfigStatus_SYNCED, xdsstatus.ConfigStatus_NOT_SENT),
+			config(xdsstatus.ConfigStatus_SYNCED, xdsstatus.ConfigStatus_STALE),
+		}},
+		"istiod-2": {Resources: []*anypb.Any{
+			config(xdsstatus.ConfigStatus_SYNCED),
+			config(xdsstatus.ConfigStatus_ERROR),
+		}},
+	}
+	f, err := ProxySyncScore(responses, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, f.Score, 50.0)
+	assert.Equal(t, f.Detail, "2/4 proxies synced")
+
+	f, err = ProxySyncScore(nil, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, f.Skipped, true)
+}
+
+func TestHistory(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "history.jsonl")
+	previous, err := lastReport(file)
+	assert.NoError(t, err)
+	assert.Equal(t, previous == nil, true)
+
+	first := NewReport(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), []Factor{
+		{Name: factorControlPlane, Score: 100, Weight: 1},
+		{Name: factorProxySync, Score: 80, Weight: 1},
+	})
+	second := NewReport(time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), []Factor{
+		{Name: factorControlPlane, Score: 100, Weight: 1},
+		{Name: factorProxySync, Score: 60, Weight: 1},
+	})
+	assert.NoError(t, appendReport(file, first))
+	assert.NoError(t, appendReport(file, second))
+	previous, err = lastReport(file)
+	assert.NoError(t, err)
+	assert.Equal(t, previous.Score, second.Score)
+
+	var out bytes.Buffer
+	assert.NoError(t, printReport(&out, shortOutput, second, &first))
+	assert.Equal(t, out.String(), "Mesh health score: 80/100 (-10 since 2024-01-01T00:00:00Z)\n")
+
+	out.Reset()
+	assert.NoError(t, printReport(&out, tableOutput, second, &first))
+	lines := strings.Split(out.String(), "\n")
+	assert.Equal(t, strings.Fields(lines[2]), []string{"FACTOR", "SCORE", "TREND", "WEIGHT", "DETAIL"})
+	assert.Equal(t, strings.Fields(lines[3]), []string{"control", "plane", "100", "=", "1"})
+	assert.Equal(t, strings.Fields(lines[4]), []string{"proxy", "sync", "60", "-20", "1"})
+}
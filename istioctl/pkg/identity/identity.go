@@ -0,0 +1,324 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package identity
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	auth "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
+	"github.com/spf13/cobra"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	meshconfig "istio.io/api/mesh/v1alpha1"
+	clientsecurity "istio.io/client-go/pkg/apis/security/v1"
+	"istio.io/istio/istioctl/pkg/cli"
+	"istio.io/istio/istioctl/pkg/completion"
+	"istio.io/istio/istioctl/pkg/util"
+	"istio.io/istio/istioctl/pkg/util/configdump"
+	"istio.io/istio/pilot/pkg/security/trustdomain"
+	"istio.io/istio/pkg/config/mesh"
+	"istio.io/istio/pkg/kube"
+	"istio.io/istio/pkg/slices"
+	"istio.io/istio/pkg/spiffe"
+	pkiutil "istio.io/istio/security/pkg/pki/util"
+)
+
+// Identity is the workload identity of a pod and the authorization policies referencing it.
+type Identity struct {
+	Pod                string   `json:"pod"`
+	Namespace          string   `json:"namespace"`
+	ServiceAccount     string   `json:"serviceAccount"`
+	TrustDomain        string   `json:"trustDomain"`
+	TrustDomainAliases []string `json:"trustDomainAliases,omitempty"`
+	SpiffeID           string   `json:"spiffeID"`
+	// Certificate is the workload certificate served by the proxy, when it could be retrieved.
+	Certificate *Certificate `json:"certificate,omitempty"`
+	// CertificateError is why the workload certificate could not be retrieved.
+	CertificateError string            `json:"certificateError,omitempty"`
+	Policies         []PolicyReference `json:"policies"`
+}
+
+// Certificate is the workload certificate of a proxy.
+type Certificate struct {
+	URISANs  []string  `json:"uriSANs,omitempty"`
+	DNSSANs  []string  `json:"dnsSANs,omitempty"`
+	Issuer   string    `json:"issuer"`
+	NotAfter time.Time `json:"notAfter"`
+}
+
+// PolicyReference is a source field of an authorization policy rule matching the identity.
+type PolicyReference struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	Action    string `json:"action"`
+	Rule      int    `json:"rule"`
+	// Field is the source field of the rule, such as principals or notNamespaces. A not field excludes the identity
+	// from the rule.
+	Field string `json:"field"`
+	Value string `json:"value"`
+}
+
+func Cmd(ctx cli.Context) *cobra.Command {
+	var outputFormat string
+	cmd := &cobra.Command{
+		Use:   "identity <pod-name>[.<namespace>]",
+		Short: "Prints the SPIFFE identity of a workload and the authorization policies referencing it",
+		Long: `Prints the SPIFFE identity of a workload: the service account and namespace it is derived from, the trust
+domain of the mesh, and the SANs of the certificate the proxy serves. The authorization policies of the mesh whose
+rules reference the identity, through their source principals or namespaces, are listed with the matching field.
+Principals of policies are matched as the proxies enforce them, including the trust domain aliases of the mesh.`,
+		Example: `  # Print the identity of a pod
+  istioctl experimental identity productpage-v1-7f8c9b8b9b-x2v4q.default
+
+  # Print the identity of the pod of a deployment as JSON
+  istioctl experimental identity deployment/productpage-v1 -o json`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				cmd.Println(cmd.UsageString())
+				return fmt.Errorf("identity requires a pod name")
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if outputFormat != "table" && outputFormat != "json" {
+				return fmt.Errorf("unknown output format %q, must be table or json", outputFormat)
+			}
+			kubeClient, err := ctx.CLIClient()
+			if err != nil {
+				return err
+			}
+			podName, podNamespace, err := ctx.InferPodInfoFromTypedResource(args[0], ctx.NamespaceOrDefault(ctx.Namespace()))
+			if err != nil {
+				return err
+			}
+			id, err := inspect(kubeClient, podName, podNamespace, ctx.IstioNamespace())
+			if err != nil {
+				return err
+			}
+			return printIdentity(cmd.OutOrStdout(), id, outputFormat)
+		},
+		ValidArgsFunction: completion.ValidPodsNameArgs(ctx),
+	}
+	cmd.Flags().StringVarP(&outputFormat, "output", "o", "table", "Output format: one of table|json")
+	return cmd
+}
+
+func inspect(kubeClient kube.CLIClient, podName, podNamespace, istioNamespace string) (Identity, error) {
+	pod, err := kubeClient.Kube().CoreV1().Pods(podNamespace).Get(context.TODO(), podName, metav1.GetOptions{})
+	if err != nil {
+		return Identity{}, fmt.Errorf("failed to retrieve pod %s.%s: %v", podName, podNamespace, err)
+	}
+	meshConfig, err := getMeshConfig(kubeClient, istioNamespace)
+	if err != nil {
+		return Identity{}, err
+	}
+	serviceAccount := pod.Spec.ServiceAccountName
+	if serviceAccount == "" {
+		serviceAccount = "default"
+	}
+	id := Identity{
+		Pod:                pod.Name,
+		Namespace:          pod.Namespace,
+		ServiceAccount:     serviceAccount,
+		TrustDomain:        meshConfig.GetTrustDomain(),
+		TrustDomainAliases: meshConfig.GetTrustDomainAliases(),
+		SpiffeID:           spiffe.MustGenSpiffeURI(meshConfig, pod.Namespace, serviceAccount),
+	}
+	if id.Certificate, err = workloadCertificate(kubeClient, pod.Name, pod.Namespace); err != nil {
+		id.CertificateError = err.Error()
+	}
+	policies, err := kubeClient.Istio().SecurityV1().AuthorizationPolicies(metav1.NamespaceAll).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return Identity{}, fmt.Errorf("failed to list AuthorizationPolicies: %v", err)
+	}
+	id.Policies = PolicyReferences(id, policies.Items)
+	return id, nil
+}
+
+// getMeshConfig returns the mesh config of the Istio namespace, or the defaults if there is none.
+func getMeshConfig(kubeClient kube.CLIClient, istioNamespace string) (*meshconfig.MeshConfig, error) {
+	cm, err := kubeClient.Kube().CoreV1().ConfigMaps(istioNamespace).Get(context.TODO(), util.DefaultMeshConfigMapName, metav1.GetOptions{})
+	if kerrors.IsNotFound(err) {
+		return mesh.DefaultMeshConfig(), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve the mesh config: %v", err)
+	}
+	meshConfig, err := mesh.ApplyMeshConfigDefaults(cm.Data[util.ConfigMapKey])
+	if err != nil {
+		return nil, fmt.Errorf("error parsing mesh config: %v", err)
+	}
+	return meshConfig, nil
+}
+
+// workloadCertificate returns the workload certificate of the "default" secret of the proxy of the pod.
+func workloadCertificate(kubeClient kube.CLIClient, podName, podNamespace string) (*Certificate, error) {
+	debug, err := kubeClient.EnvoyDo(context.TODO(), podName, podNamespace, "GET", "config_dump")
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve the config dump of the proxy: %v", err)
+	}
+	cd := &configdump.Wrapper{}
+	if err := cd.UnmarshalJSON(debug); err != nil {
+		return nil, fmt.Errorf("failed to parse the config dump of the proxy: %v", err)
+	}
+	secrets, err := cd.GetSecretConfigDump()
+	if err != nil {
+		return nil, fmt.Errorf("the proxy has no secrets: %v", err)
+	}
+	for _, s := range secrets.DynamicActiveSecrets {
+		if s.Name != "default" {
+			continue
+		}
+		secret := &auth.Secret{}
+		if err := s.GetSecret().UnmarshalTo(secret); err != nil {
+			return nil, fmt.Errorf("failed to parse the workload certificate: %v", err)
+		}
+		certs, _, err := pkiutil.ParsePemEncodedCertificateChain(secret.GetTlsCertificate().GetCertificateChain().GetInlineBytes())
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse the workload certificate: %v", err)
+		}
+		return certificateOf(certs[0]), nil
+	}
+	return nil, fmt.Errorf("the proxy has no active workload certificate")
+}
+
+func certificateOf(cert *x509.Certificate) *Certificate {
+	c := &Certificate{
+		DNSSANs:  cert.DNSNames,
+		Issuer:   cert.Issuer.String(),
+		NotAfter: cert.NotAfter,
+	}
+	for _, u := range cert.URIs {
+		c.URISANs = append(c.URISANs, u.String())
+	}
+	return c
+}
+
+// PolicyReferences returns the source fields of the authorization policy rules matching the identity.
+func PolicyReferences(id Identity, policies []*clientsecurity.AuthorizationPolicy) []PolicyReference {
+	// Principals are enforced without the scheme, and with the trust domain aliases of the mesh.
+	principal := strings.TrimPrefix(id.SpiffeID, spiffe.URIPrefix)
+	bundle := trustdomain.NewBundle(id.TrustDomain, id.TrustDomainAliases)
+	refs := []PolicyReference{}
+	for _, p := range policies {
+		for i, rule := range p.Spec.Rules {
+			for _, from := range rule.From {
+				add := func(field string, values []string, value string, expand bool) {
+					for _, v := range values {
+						patterns := []string{v}
+						if expand {
+							patterns = bundle.ReplaceTrustDomainAliases(patterns)
+						}
+						if matchesAny(value, patterns) {
+							refs = append(refs, PolicyReference{
+								Name:      p.Name,
+								Namespace: p.Namespace,
+								Action:    p.Spec.Action.String(),
+								Rule:      i,
+								Field:     field,
+								Value:     v,
+							})
+						}
+					}
+				}
+				src := from.GetSource()
+				add("principals", src.GetPrincipals(), principal, true)
+				add("notPrincipals", src.GetNotPrincipals(), principal, true)
+				add("namespaces", src.GetNamespaces(), id.Namespace, false)
+				add("notNamespaces", src.GetNotNamespaces(), id.Namespace, false)
+			}
+		}
+	}
+	sort.SliceStable(refs, func(i, j int) bool {
+		if refs[i].Namespace != refs[j].Namespace {
+			return refs[i].Namespace < refs[j].Namespace
+		}
+		return refs[i].Name < refs[j].Name
+	})
+	return refs
+}
+
+// matchesAny returns whether the value matches one of the patterns, which are exact, "*", prefix ("foo*") or
+// suffix ("*foo") matches as in authorization policies.
+func matchesAny(value string, patterns []string) bool {
+	for _, p := range patterns {
+		switch {
+		case p == "*" || p == value:
+			return true
+		case strings.HasSuffix(p, "*") && strings.HasPrefix(value, strings.TrimSuffix(p, "*")):
+			return true
+		case strings.HasPrefix(p, "*") && strings.HasSuffix(value, strings.TrimPrefix(p, "*")):
+			return true
+		}
+	}
+	return false
+}
+
+func printIdentity(w io.Writer, id Identity, outputFormat string) error {
+	if outputFormat == "json" {
+		out, err := json.MarshalIndent(id, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(out))
+		return err
+	}
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	_, _ = fmt.Fprintf(tw, "Pod:\t%s.%s\n", id.Pod, id.Namespace)
+	_, _ = fmt.Fprintf(tw, "Service Account:\t%s\n", id.ServiceAccount)
+	trustDomain := id.TrustDomain
+	if len(id.TrustDomainAliases) > 0 {
+		trustDomain += fmt.Sprintf(" (aliases: %s)", strings.Join(id.TrustDomainAliases, ", "))
+	}
+	_, _ = fmt.Fprintf(tw, "Trust Domain:\t%s\n", trustDomain)
+	_, _ = fmt.Fprintf(tw, "SPIFFE ID:\t%s\n", id.SpiffeID)
+	if c := id.Certificate; c != nil {
+		_, _ = fmt.Fprintf(tw, "Certificate URI SANs:\t%s\n", strings.Join(c.URISANs, ", "))
+		if len(c.DNSSANs) > 0 {
+			_, _ = fmt.Fprintf(tw, "Certificate DNS SANs:\t%s\n", strings.Join(c.DNSSANs, ", "))
+		}
+		_, _ = fmt.Fprintf(tw, "Certificate Issuer:\t%s\n", c.Issuer)
+		_, _ = fmt.Fprintf(tw, "Certificate Expires:\t%s\n", c.NotAfter.UTC().Format(time.RFC3339))
+		if !slices.Contains(c.URISANs, id.SpiffeID) {
+			_, _ = fmt.Fprintf(tw, "Warning:\tthe certificate does not carry the SPIFFE ID, peers see a different identity\n")
+		}
+	} else {
+		_, _ = fmt.Fprintf(tw, "Certificate:\tunavailable, %s\n", id.CertificateError)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	_, _ = fmt.Fprintln(w)
+	if len(id.Policies) == 0 {
+		_, _ = fmt.Fprintln(w, "No AuthorizationPolicies reference the identity.")
+		return nil
+	}
+	_, _ = fmt.Fprintln(w, "AuthorizationPolicies referencing the identity:")
+	tw = tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "NAMESPACE\tNAME\tACTION\tRULE\tFIELD\tVALUE")
+	for _, r := range id.Policies {
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%s\t%s\n", r.Namespace, r.Name, r.Action, r.Rule, r.Field, r.Value)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,83 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package identity
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"istio.io/api/security/v1beta1"
+	clientsecurity "istio.io/client-go/pkg/apis/security/v1"
+	"istio.io/istio/istioctl/pkg/cli"
+	"istio.io/istio/pkg/config/schema/gvk"
+	"istio.io/istio/pkg/kube"
+	"istio.io/istio/pkg/test/util/assert"
+)
+
+func policy(name, namespace string, action v1beta1.AuthorizationPolicy_Action, source *v1beta1.Source) *clientsecurity.AuthorizationPolicy {
+	return &clientsecurity.AuthorizationPolicy{
+		TypeMeta:   metav1.TypeMeta{APIVersion: gvk.AuthorizationPolicy.GroupVersion(), Kind: gvk.AuthorizationPolicy.Kind},
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Spec: v1beta1.AuthorizationPolicy{
+			Action: action,
+			Rules:  []*v1beta1.Rule{{From: []*v1beta1.Rule_From{{Source: source}}}},
+		},
+	}
+}
+
+func TestPolicyReferences(t *testing.T) {
+	id := Identity{
+		Namespace:          "foo",
+		ServiceAccount:     "sleep",
+		TrustDomain:        "td1",
+		TrustDomainAliases: []string{"old-td"},
+		SpiffeID:           "spiffe://td1/ns/foo/sa/sleep",
+	}
+	policies := []*clientsecurity.AuthorizationPolicy{
+		policy("exact", "bar", v1beta1.AuthorizationPolicy_ALLOW, &v1beta1.Source{Principals: []string{"td1/ns/foo/sa/sleep"}}),
+		policy("alias", "bar", v1beta1.AuthorizationPolicy_ALLOW, &v1beta1.Source{Principals: []string{"old-td/ns/foo/sa/sleep"}}),
+		policy("suffix", "baz", v1beta1.AuthorizationPolicy_DENY, &v1beta1.Source{NotPrincipals: []string{"*/ns/foo/sa/sleep"}}),
+		policy("namespace", "baz", v1beta1.AuthorizationPolicy_ALLOW, &v1beta1.Source{Namespaces: []string{"foo"}}),
+		policy("other", "baz", v1beta1.AuthorizationPolicy_ALLOW, &v1beta1.Source{Principals: []string{"td1/ns/foo/sa/httpbin"}}),
+	}
+
+	assert.Equal(t, PolicyReferences(id, policies), []PolicyReference{
+		{Name: "alias", Namespace: "bar", Action: "ALLOW", Field: "principals", Value: "old-td/ns/foo/sa/sleep"},
+		{Name: "exact", Namespace: "bar", Action: "ALLOW", Field: "principals", Value: "td1/ns/foo/sa/sleep"},
+		{Name: "namespace", Namespace: "baz", Action: "ALLOW", Field: "namespaces", Value: "foo"},
+		{Name: "suffix", Namespace: "baz", Action: "DENY", Field: "notPrincipals", Value: "*/ns/foo/sa/sleep"},
+	})
+}
+
+func TestInspect(t *testing.T) {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "sleep-1", Namespace: "foo"},
+		Spec:       corev1.PodSpec{ServiceAccountName: "sleep"},
+	}
+	client := cli.MockClient{
+		CLIClient: kube.NewFakeClient(pod,
+			policy("allow-sleep", "foo", v1beta1.AuthorizationPolicy_ALLOW, &v1beta1.Source{Principals: []string{"cluster.local/ns/foo/sa/sleep"}})),
+	}
+
+	id, err := inspect(client, "sleep-1", "foo", "istio-system")
+	assert.NoError(t, err)
+	assert.Equal(t, id.SpiffeID, "spiffe://cluster.local/ns/foo/sa/sleep")
+	assert.Equal(t, id.Certificate == nil, true)
+	assert.Equal(t, id.CertificateError != "", true)
+	assert.Equal(t, len(id.Policies), 1)
+	assert.Equal(t, id.Policies[0].Name, "allow-sleep")
+}
@@ -0,0 +1,261 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package injector
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	xdsstatus "github.com/envoyproxy/go-control-plane/envoy/service/status/v3"
+	"github.com/spf13/cobra"
+	admitv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"istio.io/api/label"
+	"istio.io/istio/istioctl/pkg/cli"
+	"istio.io/istio/istioctl/pkg/clioptions"
+	"istio.io/istio/istioctl/pkg/multixds"
+	"istio.io/istio/pilot/pkg/model"
+	pilotxds "istio.io/istio/pilot/pkg/xds"
+	"istio.io/istio/pkg/config/resource"
+	"istio.io/istio/pkg/kube"
+)
+
+const (
+	prometheusOutput = "prom"
+	jsonOutput       = "json"
+
+	unknownRevision = "<unknown>"
+)
+
+// RevisionAdoption holds the data plane adoption of a control plane revision.
+type RevisionAdoption struct {
+	Revision string `json:"revision"`
+	// Namespaces is the number of namespaces whose injector is the revision, directly or through a tag.
+	Namespaces int `json:"namespaces"`
+	// PodsInjected is the number of pods injected by the revision.
+	PodsInjected int `json:"podsInjected"`
+	// ProxiesConnected is the number of proxies connected to an istiod of the revision.
+	ProxiesConnected int `json:"proxiesConnected"`
+	// ProxyVersions is the number of connected proxies per proxy version.
+	ProxyVersions map[string]int `json:"proxyVersions,omitempty"`
+}
+
+// RevisionCmd returns the revision command, which reports on control plane revisions.
+func RevisionCmd(cliContext cli.Context) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "revision",
+		Short:   "Report data plane adoption of control plane revisions",
+		Long:    `Report data plane adoption of control plane revisions`,
+		Example: `  istioctl experimental revision metrics`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 0 {
+				return fmt.Errorf("unknown subcommand %q", args[0])
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.HelpFunc()(cmd, args)
+			return nil
+		},
+	}
+
+	cmd.AddCommand(revisionMetricsCommand(cliContext))
+	return cmd
+}
+
+func revisionMetricsCommand(ctx cli.Context) *cobra.Command {
+	var opts clioptions.ControlPlaneOptions
+	var centralOpts clioptions.CentralControlPlaneOptions
+	var outputFormat string
+	cmd := &cobra.Command{
+		Use:   "metrics",
+		Short: "Print per revision data plane adoption statistics",
+		Long: `Print per revision data plane adoption statistics: the namespaces using the revision for injection, the pods
+injected by it, and the proxies connected to it with their versions.
+
+The output is in Prometheus exposition format by default, so it can be scraped from a file or pushed to a Pushgateway
+and charted to follow the progress of an upgrade.`,
+		Example: `  # Print revision adoption metrics in Prometheus exposition format
+  istioctl experimental revision metrics
+
+  # Print revision adoption metrics as JSON
+  istioctl experimental revision metrics -o json`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if outputFormat != prometheusOutput && outputFormat != jsonOutput {
+				return fmt.Errorf("unknown output format %q, expected one of %s or %s", outputFormat, prometheusOutput, jsonOutput)
+			}
+			client, err := ctx.CLIClientWithRevision(opts.Revision)
+			if err != nil {
+				return fmt.Errorf("failed to create k8s client: %v", err)
+			}
+			nsList, err := getNamespaces(context.Background(), client, ctx.IstioNamespace())
+			if err != nil {
+				return err
+			}
+			hooksList, err := client.Kube().AdmissionregistrationV1().MutatingWebhookConfigurations().List(context.Background(), metav1.ListOptions{
+				LabelSelector: "app=sidecar-injector",
+			})
+			if err != nil {
+				return err
+			}
+			pods, err := getPods(context.Background(), client)
+			if err != nil {
+				return err
+			}
+			xdsRequest := discovery.DiscoveryRequest{TypeUrl: pilotxds.TypeDebugSyncronization}
+			responses, err := multixds.AllRequestAndProcessXds(&xdsRequest, centralOpts, ctx.IstioNamespace(), "", "", client, multixds.DefaultOptions)
+			if err != nil {
+				return err
+			}
+			istiodRevisions, err := getIstiodRevisions(context.Background(), client, ctx.IstioNamespace())
+			if err != nil {
+				return err
+			}
+			adoption, err := revisionAdoption(nsList, hooksList.Items, pods, responses, istiodRevisions)
+			if err != nil {
+				return err
+			}
+			if outputFormat == jsonOutput {
+				b, err := json.MarshalIndent(adoption, "", "  ")
+				if err != nil {
+					return err
+				}
+				_, _ = fmt.Fprintln(cmd.OutOrStdout(), string(b))
+				return nil
+			}
+			printRevisionMetrics(cmd.OutOrStdout(), adoption)
+			return nil
+		},
+	}
+	opts.AttachControlPlaneFlags(cmd)
+	centralOpts.AttachControlPlaneFlags(cmd)
+	cmd.Flags().StringVarP(&outputFormat, "output", "o", prometheusOutput, "Output format: one of prom|json")
+	return cmd
+}
+
+// getIstiodRevisions returns the revision of each istiod pod, keyed by pod name.
+func getIstiodRevisions(ctx context.Context, client kube.CLIClient, istioNamespace string) (map[string]string, error) {
+	pods, err := client.Kube().CoreV1().Pods(istioNamespace).List(ctx, metav1.ListOptions{LabelSelector: "app=istiod"})
+	if err != nil {
+		return nil, err
+	}
+	res := map[string]string{}
+	for _, pod := range pods.Items {
+		rev := pod.Labels[label.IoIstioRev.Name]
+		if rev == "" {
+			rev = "default"
+		}
+		res[pod.Name] = rev
+	}
+	return res, nil
+}
+
+func revisionAdoption(namespaces []corev1.Namespace, hooks []admitv1.MutatingWebhookConfiguration, pods map[resource.Namespace][]corev1.Pod,
+	responses map[string]*discovery.DiscoveryResponse, istiodRevisions map[string]string,
+) ([]*RevisionAdoption, error) {
+	byRevision := map[string]*RevisionAdoption{}
+	get := func(rev string) *RevisionAdoption {
+		if byRevision[rev] == nil {
+			byRevision[rev] = &RevisionAdoption{Revision: rev, ProxyVersions: map[string]int{}}
+		}
+		return byRevision[rev]
+	}
+
+	for i := range namespaces {
+		rev := getInjectedRevision(&namespaces[i], hooks)
+		// Namespaces which are not injected, or whose revision has no injector, are not adopting any revision.
+		if rev == "" || strings.HasPrefix(rev, "MISSING/") {
+			continue
+		}
+		get(rev).Namespaces++
+	}
+
+	for _, nsPods := range pods {
+		for i := range nsPods {
+			if rev := extractRevisionFromPod(&nsPods[i]); rev != "" {
+				get(rev).PodsInjected++
+			}
+		}
+	}
+
+	for _, dr := range responses {
+		rev, ok := istiodRevisions[multixds.CpInfo(dr).ID]
+		if !ok {
+			rev = unknownRevision
+		}
+		for _, resource := range dr.Resources {
+			clientConfig := xdsstatus.ClientConfig{}
+			if err := resource.UnmarshalTo(&clientConfig); err != nil {
+				return nil, fmt.Errorf("could not unmarshal ClientConfig: %w", err)
+			}
+			meta, err := model.ParseMetadata(clientConfig.GetNode().GetMetadata())
+			if err != nil {
+				return nil, fmt.Errorf("could not parse node metadata: %w", err)
+			}
+			a := get(rev)
+			a.ProxiesConnected++
+			version := meta.IstioVersion
+			if version == "" {
+				version = unknownRevision
+			}
+			a.ProxyVersions[version]++
+		}
+	}
+
+	res := make([]*RevisionAdoption, 0, len(byRevision))
+	for _, a := range byRevision {
+		res = append(res, a)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Revision < res[j].Revision
+	})
+	return res, nil
+}
+
+func printRevisionMetrics(w io.Writer, adoption []*RevisionAdoption) {
+	gauges := []struct {
+		name, help string
+		value      func(*RevisionAdoption) int
+	}{
+		{"istio_revision_namespaces", "Number of namespaces using the revision for injection.", func(a *RevisionAdoption) int { return a.Namespaces }},
+		{"istio_revision_pods_injected", "Number of pods injected by the revision.", func(a *RevisionAdoption) int { return a.PodsInjected }},
+		{"istio_revision_proxies_connected", "Number of proxies connected to the revision.", func(a *RevisionAdoption) int { return a.ProxiesConnected }},
+	}
+	for _, g := range gauges {
+		_, _ = fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n", g.name, g.help, g.name)
+		for _, a := range adoption {
+			_, _ = fmt.Fprintf(w, "%s{revision=%q} %d\n", g.name, a.Revision, g.value(a))
+		}
+	}
+	const versions = "istio_revision_proxy_versions"
+	_, _ = fmt.Fprintf(w, "# HELP %s Number of proxies connected to the revision per proxy version.\n# TYPE %s gauge\n", versions, versions)
+	for _, a := range adoption {
+		keys := make([]string, 0, len(a.ProxyVersions))
+		for v := range a.ProxyVersions {
+			keys = append(keys, v)
+		}
+		sort.Strings(keys)
+		for _, v := range keys {
+			_, _ = fmt.Fprintf(w, "%s{revision=%q,version=%q} %d\n", versions, a.Revision, v, a.ProxyVersions[v])
+		}
+	}
+}
@@ -0,0 +1,122 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package injector
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	xdsstatus "github.com/envoyproxy/go-control-plane/envoy/service/status/v3"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/structpb"
+	admitv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"istio.io/api/annotation"
+	"istio.io/api/label"
+	"istio.io/istio/pilot/pkg/util/protoconv"
+	pilotxds "istio.io/istio/pilot/pkg/xds"
+	"istio.io/istio/pkg/config/resource"
+	"istio.io/istio/pkg/test/util/assert"
+)
+
+func revisionedNamespace(name, rev string) corev1.Namespace {
+	return corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{label.IoIstioRev.Name: rev}}}
+}
+
+func injectedPod(name, rev string) corev1.Pod {
+	return corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+		Name:        name,
+		Annotations: map[string]string{annotation.SidecarStatus.Name: `{"revision":"` + rev + `"}`},
+	}}
+}
+
+func syncResponse(t *testing.T, istiod string, proxyVersions ...string) *discovery.DiscoveryResponse {
+	identifier, err := json.Marshal(&pilotxds.IstioControlPlaneInstance{Component: "istiod", ID: istiod})
+	assert.NoError(t, err)
+	resources := make([]*anypb.Any, 0, len(proxyVersions))
+	for _, v := range proxyVersions {
+		resources = append(resources, protoconv.MessageToAny(&xdsstatus.ClientConfig{
+			Node: &core.Node{Metadata: &structpb.Struct{Fields: map[string]*structpb.Value{
+				"ISTIO_VERSION": structpb.NewStringValue(v),
+			}}},
+		}))
+	}
+	return &discovery.DiscoveryResponse{
+		Resources:    resources,
+		ControlPlane: &core.ControlPlane{Identifier: string(identifier)},
+	}
+}
+
+func Test_revisionAdoption(t *testing.T) {
+	hooks := []admitv1.MutatingWebhookConfiguration{{
+		ObjectMeta: metav1.ObjectMeta{Name: "istio-sidecar-injector-canary", Labels: map[string]string{label.IoIstioRev.Name: "canary"}},
+		Webhooks: []admitv1.MutatingWebhook{{
+			NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{label.IoIstioRev.Name: "canary"}},
+		}},
+	}}
+	namespaces := []corev1.Namespace{
+		revisionedNamespace("foo", "canary"),
+		revisionedNamespace("bar", "canary"),
+		// No injector for this revision, so the namespace is not counted.
+		revisionedNamespace("baz", "missing"),
+		{ObjectMeta: metav1.ObjectMeta{Name: "default"}},
+	}
+	pods := map[resource.Namespace][]corev1.Pod{
+		"foo": {injectedPod("a", "canary"), injectedPod("b", "canary")},
+		"bar": {injectedPod("c", "stable"), {ObjectMeta: metav1.ObjectMeta{Name: "not-injected"}}},
+	}
+	responses := map[string]*discovery.DiscoveryResponse{
+		"istiod-canary-1": syncResponse(t, "istiod-canary-1", "1.24.0", "1.24.0"),
+		"istiod-stable-1": syncResponse(t, "istiod-stable-1", "1.23.2"),
+		"istiod-gone":     syncResponse(t, "istiod-gone", ""),
+	}
+	istiodRevisions := map[string]string{
+		"istiod-canary-1": "canary",
+		"istiod-stable-1": "stable",
+	}
+
+	got, err := revisionAdoption(namespaces, hooks, pods, responses, istiodRevisions)
+	assert.NoError(t, err)
+	assert.Equal(t, got, []*RevisionAdoption{
+		{Revision: unknownRevision, ProxiesConnected: 1, ProxyVersions: map[string]int{unknownRevision: 1}},
+		{Revision: "canary", Namespaces: 2, PodsInjected: 2, ProxiesConnected: 2, ProxyVersions: map[string]int{"1.24.0": 2}},
+		{Revision: "stable", PodsInjected: 1, ProxiesConnected: 1, ProxyVersions: map[string]int{"1.23.2": 1}},
+	})
+}
+
+func Test_printRevisionMetrics(t *testing.T) {
+	var out bytes.Buffer
+	printRevisionMetrics(&out, []*RevisionAdoption{
+		{Revision: "canary", Namespaces: 2, PodsInjected: 3, ProxiesConnected: 3, ProxyVersions: map[string]int{"1.24.0": 2, "1.23.2": 1}},
+	})
+	for _, line := range []string{
+		"# TYPE istio_revision_namespaces gauge",
+		`istio_revision_namespaces{revision="canary"} 2`,
+		`istio_revision_pods_injected{revision="canary"} 3`,
+		`istio_revision_proxies_connected{revision="canary"} 3`,
+		`istio_revision_proxy_versions{revision="canary",version="1.23.2"} 1`,
+		`istio_revision_proxy_versions{revision="canary",version="1.24.0"} 2`,
+	} {
+		if !strings.Contains(out.String(), line+"\n") {
+			t.Errorf("expected output to contain %q, got:\n%s", line, out.String())
+		}
+	}
+}
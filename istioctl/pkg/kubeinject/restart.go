@@ -0,0 +1,287 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubeinject
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"istio.io/api/annotation"
+	"istio.io/api/label"
+	"istio.io/istio/istioctl/pkg/cli"
+	"istio.io/istio/pkg/config/mesh"
+	"istio.io/istio/pkg/kube"
+	"istio.io/istio/pkg/kube/inject"
+	"istio.io/istio/pkg/maps"
+	"istio.io/istio/pkg/slices"
+	"istio.io/istio/pkg/util/sets"
+)
+
+// PodRestart describes an injected pod whose sidecar differs from what the injector currently produces.
+type PodRestart struct {
+	Name     string   `json:"name"`
+	Revision string   `json:"revision"`
+	Changes  []string `json:"changes"`
+}
+
+// NamespaceRestarts holds the injected pods of a namespace which need a restart.
+type NamespaceRestarts struct {
+	Namespace     string       `json:"namespace"`
+	InjectedPods  int          `json:"injectedPods"`
+	RestartNeeded []PodRestart `json:"restartNeeded"`
+}
+
+func RestartNeededCommand(ctx cli.Context) *cobra.Command {
+	var outputFormat string
+	cmd := &cobra.Command{
+		Use:   "sidecar-restart-needed",
+		Short: "Lists the pods which need a restart to pick up the current sidecar injection configuration",
+		Long: `Lists the injected pods whose sidecar (image, args and environment) differs from what the injector of their
+revision currently produces, grouped by namespace. These pods need a restart to pick up changes of the injection
+template, values or mesh config, for instance after an upgrade.
+
+The expected sidecar of each pod is obtained by sending the pod template of its owner to the injection webhook.
+Pods without a ReplicaSet, StatefulSet, DaemonSet or Job owner are skipped.`,
+		Example: `  # List the pods of all namespaces which need a restart
+  istioctl experimental sidecar-restart-needed
+
+  # List the pods of the foo namespace which need a restart, as JSON
+  istioctl experimental sidecar-restart-needed -n foo -o json`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if outputFormat != "table" && outputFormat != "json" {
+				return fmt.Errorf("unknown output format %q, must be table or json", outputFormat)
+			}
+			client, err := ctx.CLIClient()
+			if err != nil {
+				return err
+			}
+			injectors := map[string]inject.Injector{}
+			injectorFor := func(revision string) (inject.Injector, error) {
+				if i, f := injectors[revision]; f {
+					return i, nil
+				}
+				i, err := setUpExternalInjector(ctx, revision, "")
+				if err != nil {
+					return nil, err
+				}
+				injectors[revision] = i
+				return i, nil
+			}
+			res, err := restartsNeeded(client, ctx.Namespace(), injectorFor, cmd.ErrOrStderr())
+			if err != nil {
+				return err
+			}
+			return printRestartsNeeded(cmd.OutOrStdout(), res, outputFormat)
+		},
+	}
+	cmd.Flags().StringVarP(&outputFormat, "output", "o", "table", "Output format: one of table|json")
+	return cmd
+}
+
+func restartsNeeded(client kube.CLIClient, namespace string, injectorFor func(revision string) (inject.Injector, error),
+	stderr io.Writer,
+) ([]NamespaceRestarts, error) {
+	pods, err := client.Kube().CoreV1().Pods(namespace).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	byNamespace := map[string]*NamespaceRestarts{}
+	// Pods of the same owner and revision share the same expected sidecar, rendered once.
+	expected := map[types.UID]*corev1.Pod{}
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if _, injected := pod.Annotations[annotation.SidecarStatus.Name]; !injected {
+			continue
+		}
+		ns := byNamespace[pod.Namespace]
+		if ns == nil {
+			ns = &NamespaceRestarts{Namespace: pod.Namespace, RestartNeeded: []PodRestart{}}
+			byNamespace[pod.Namespace] = ns
+		}
+		ns.InjectedPods++
+
+		owner := metav1.GetControllerOf(pod)
+		if owner == nil {
+			fmt.Fprintf(stderr, "skipping pod %s/%s: it has no owner\n", pod.Namespace, pod.Name)
+			continue
+		}
+		revision := pod.Labels[label.IoIstioRev.Name]
+		if revision == "" {
+			revision = "default"
+		}
+		want, f := expected[owner.UID]
+		if !f {
+			template, err := ownerTemplate(client, pod.Namespace, owner)
+			if err != nil {
+				fmt.Fprintf(stderr, "skipping pod %s/%s: %v\n", pod.Namespace, pod.Name, err)
+				continue
+			}
+			injector, err := injectorFor(revision)
+			if err != nil {
+				return nil, fmt.Errorf("failed to find the injector of revision %s: %v", revision, err)
+			}
+			want, err = injectTemplate(injector, revision, pod, template)
+			if err != nil {
+				return nil, fmt.Errorf("failed to inject the template of pod %s/%s: %v", pod.Namespace, pod.Name, err)
+			}
+			expected[owner.UID] = want
+		}
+		if changes := sidecarChanges(pod, want); len(changes) > 0 {
+			ns.RestartNeeded = append(ns.RestartNeeded, PodRestart{Name: pod.Name, Revision: revision, Changes: changes})
+		}
+	}
+
+	res := make([]NamespaceRestarts, 0, len(byNamespace))
+	for _, ns := range byNamespace {
+		sort.Slice(ns.RestartNeeded, func(i, j int) bool { return ns.RestartNeeded[i].Name < ns.RestartNeeded[j].Name })
+		res = append(res, *ns)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Namespace < res[j].Namespace })
+	return res, nil
+}
+
+// ownerTemplate returns the pod template of the controller of a pod.
+func ownerTemplate(client kube.CLIClient, namespace string, owner *metav1.OwnerReference) (*corev1.PodTemplateSpec, error) {
+	switch owner.Kind {
+	case "ReplicaSet":
+		rs, err := client.Kube().AppsV1().ReplicaSets(namespace).Get(context.TODO(), owner.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		return &rs.Spec.Template, nil
+	case "StatefulSet":
+		sts, err := client.Kube().AppsV1().StatefulSets(namespace).Get(context.TODO(), owner.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		return &sts.Spec.Template, nil
+	case "DaemonSet":
+		ds, err := client.Kube().AppsV1().DaemonSets(namespace).Get(context.TODO(), owner.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		return &ds.Spec.Template, nil
+	case "Job":
+		job, err := client.Kube().BatchV1().Jobs(namespace).Get(context.TODO(), owner.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		return &job.Spec.Template, nil
+	}
+	return nil, fmt.Errorf("unsupported owner kind %s", owner.Kind)
+}
+
+// injectTemplate returns the pod the injector would produce for a new pod of the template.
+func injectTemplate(injector inject.Injector, revision string, pod *corev1.Pod, template *corev1.PodTemplateSpec) (*corev1.Pod, error) {
+	in := &corev1.Pod{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"},
+		ObjectMeta: *template.ObjectMeta.DeepCopy(),
+		Spec:       *template.Spec.DeepCopy(),
+	}
+	in.Namespace = pod.Namespace
+	in.GenerateName = pod.GenerateName
+	in.OwnerReferences = pod.OwnerReferences
+	out, err := inject.IntoObject(injector, nil, inject.ValuesConfig{}, revision, mesh.DefaultMeshConfig(), in, func(string) {})
+	if err != nil {
+		return nil, err
+	}
+	return out.(*corev1.Pod), nil
+}
+
+// sidecarChanges returns the differences between the sidecar of a pod and the expected one.
+func sidecarChanges(pod, expected *corev1.Pod) []string {
+	got := inject.FindSidecar(pod)
+	want := inject.FindSidecar(expected)
+	switch {
+	case got == nil && want == nil:
+		return nil
+	case want == nil:
+		return []string{"the pod would no longer be injected"}
+	case got == nil:
+		return []string{"the pod has no sidecar container"}
+	}
+
+	var changes []string
+	if got.Image != want.Image {
+		changes = append(changes, fmt.Sprintf("image %s -> %s", got.Image, want.Image))
+	}
+	if !slices.Equal(got.Args, want.Args) {
+		changes = append(changes, fmt.Sprintf("args %s -> %s", strings.Join(got.Args, " "), strings.Join(want.Args, " ")))
+	}
+	gotEnv := envByName(got.Env)
+	wantEnv := envByName(want.Env)
+	names := sets.SortedList(sets.New(maps.Keys(gotEnv)...).InsertAll(maps.Keys(wantEnv)...))
+	for _, name := range names {
+		g, inGot := gotEnv[name]
+		w, inWant := wantEnv[name]
+		switch {
+		case !inGot && inWant:
+			changes = append(changes, "env "+name+" added")
+		case inGot && !inWant:
+			changes = append(changes, "env "+name+" removed")
+		case !equality.Semantic.DeepEqual(g, w):
+			changes = append(changes, "env "+name+" changed")
+		}
+	}
+	return changes
+}
+
+func envByName(env []corev1.EnvVar) map[string]corev1.EnvVar {
+	res := make(map[string]corev1.EnvVar, len(env))
+	for _, e := range env {
+		res[e.Name] = e
+	}
+	return res
+}
+
+func printRestartsNeeded(w io.Writer, res []NamespaceRestarts, outputFormat string) error {
+	if outputFormat == "json" {
+		out, err := json.MarshalIndent(res, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(out))
+		return err
+	}
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAMESPACE\tPOD\tREVISION\tCHANGES")
+	for _, ns := range res {
+		for _, p := range ns.RestartNeeded {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", ns.Namespace, p.Name, p.Revision, strings.Join(p.Changes, ", "))
+		}
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	fmt.Fprintln(w)
+	tw = tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAMESPACE\tINJECTED PODS\tRESTART NEEDED")
+	for _, ns := range res {
+		fmt.Fprintf(tw, "%s\t%d\t%d\n", ns.Namespace, ns.InjectedPods, len(ns.RestartNeeded))
+	}
+	return tw.Flush()
+}
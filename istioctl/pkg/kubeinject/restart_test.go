@@ -0,0 +1,103 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubeinject
+
+import (
+	"io"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"istio.io/istio/pkg/kube"
+	"istio.io/istio/pkg/kube/inject"
+	"istio.io/istio/pkg/ptr"
+	"istio.io/istio/pkg/test/util/assert"
+)
+
+// patchInjector adds a sidecar with the given image through a JSON patch, like the injection webhook.
+type patchInjector struct {
+	image string
+}
+
+func (p patchInjector) Inject(*corev1.Pod, string) ([]byte, error) {
+	return []byte(`[{"op":"add","path":"/spec/containers/-","value":{"name":"istio-proxy","image":"` + p.image +
+		`","args":["proxy","sidecar"],"env":[{"name":"ISTIO_META_CLUSTER_ID","value":"Kubernetes"}]}}]`), nil
+}
+
+func sidecarPod(image string, env ...corev1.EnvVar) *corev1.Pod {
+	return &corev1.Pod{Spec: corev1.PodSpec{Containers: []corev1.Container{
+		{Name: "app", Image: "app"},
+		{Name: "istio-proxy", Image: image, Args: []string{"proxy", "sidecar"}, Env: env},
+	}}}
+}
+
+func TestSidecarChanges(t *testing.T) {
+	clusterID := corev1.EnvVar{Name: "ISTIO_META_CLUSTER_ID", Value: "Kubernetes"}
+	want := sidecarPod("proxyv2:1.25", clusterID)
+
+	assert.Equal(t, sidecarChanges(sidecarPod("proxyv2:1.25", clusterID), want), nil)
+	assert.Equal(t, sidecarChanges(sidecarPod("proxyv2:1.24", clusterID), want), []string{"image proxyv2:1.24 -> proxyv2:1.25"})
+	assert.Equal(t, sidecarChanges(sidecarPod("proxyv2:1.25", corev1.EnvVar{Name: "ISTIO_META_CLUSTER_ID", Value: "east"},
+		corev1.EnvVar{Name: "PROXY_CONFIG", Value: "{}"}), want), []string{"env ISTIO_META_CLUSTER_ID changed", "env PROXY_CONFIG removed"})
+	assert.Equal(t, sidecarChanges(&corev1.Pod{}, want), []string{"the pod has no sidecar container"})
+	assert.Equal(t, sidecarChanges(want, &corev1.Pod{}), []string{"the pod would no longer be injected"})
+}
+
+func TestRestartsNeeded(t *testing.T) {
+	rs := &appsv1.ReplicaSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "reviews-5c9d", Namespace: "bookinfo", UID: "rs-uid"},
+		Spec: appsv1.ReplicaSetSpec{Template: corev1.PodTemplateSpec{
+			Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: "app"}}},
+		}},
+	}
+	pod := func(name, image string, owned bool) *corev1.Pod {
+		p := sidecarPod(image, corev1.EnvVar{Name: "ISTIO_META_CLUSTER_ID", Value: "Kubernetes"})
+		p.ObjectMeta = metav1.ObjectMeta{
+			Name:        name,
+			Namespace:   "bookinfo",
+			Annotations: map[string]string{"sidecar.istio.io/status": "{}"},
+		}
+		if owned {
+			p.OwnerReferences = []metav1.OwnerReference{{
+				APIVersion: "apps/v1", Kind: "ReplicaSet", Name: rs.Name, UID: rs.UID, Controller: ptr.Of(true),
+			}}
+		}
+		return p
+	}
+	uninjected := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "bookinfo"}}
+	client := kube.NewFakeClient(rs, uninjected,
+		pod("reviews-5c9d-a", "proxyv2:1.24", true),
+		pod("reviews-5c9d-b", "proxyv2:1.25", true),
+		pod("orphan", "proxyv2:1.24", false),
+	)
+
+	revisions := map[string]int{}
+	res, err := restartsNeeded(client, "", func(revision string) (inject.Injector, error) {
+		revisions[revision]++
+		return patchInjector{image: "proxyv2:1.25"}, nil
+	}, io.Discard)
+	assert.NoError(t, err)
+	assert.Equal(t, res, []NamespaceRestarts{{
+		Namespace:    "bookinfo",
+		InjectedPods: 3,
+		RestartNeeded: []PodRestart{{
+			Name: "reviews-5c9d-a", Revision: "default", Changes: []string{"image proxyv2:1.24 -> proxyv2:1.25"},
+		}},
+	}})
+	// The template of the ReplicaSet is only injected once.
+	assert.Equal(t, revisions, map[string]int{"default": 1})
+}
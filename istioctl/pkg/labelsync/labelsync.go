@@ -0,0 +1,265 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package labelsync
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/yaml"
+
+	"istio.io/api/label"
+	"istio.io/istio/istioctl/pkg/cli"
+	"istio.io/istio/istioctl/pkg/orphans"
+	"istio.io/istio/istioctl/pkg/tag"
+	"istio.io/istio/istioctl/pkg/util"
+	"istio.io/istio/pkg/util/sets"
+)
+
+// Mapping is the desired state of the revision labels of the namespaces.
+type Mapping struct {
+	// Namespaces maps namespaces to the revision or tag they should be labeled with.
+	Namespaces map[string]string `json:"namespaces,omitempty"`
+	// Deprecated lists revisions and tags namespaces should no longer be labeled with.
+	Deprecated []string `json:"deprecated,omitempty"`
+}
+
+// Status is the outcome of the audit of the revision label of a namespace.
+type Status string
+
+const (
+	// StatusMissing is reported for labels pointing at neither an installed revision nor a tag.
+	StatusMissing Status = "MISSING"
+	// StatusDeprecated is reported for labels pointing at a deprecated revision or tag.
+	StatusDeprecated Status = "DEPRECATED"
+	// StatusDrift is reported for labels which differ from the mapping.
+	StatusDrift Status = "DRIFT"
+)
+
+// Finding is a namespace whose revision label needs attention.
+type Finding struct {
+	Namespace string `json:"namespace"`
+	Label     string `json:"label"`
+	Status    Status `json:"status"`
+	// Desired is the label from the mapping, if any, which the namespace is fixed to.
+	Desired string `json:"desired,omitempty"`
+}
+
+func Cmd(ctx cli.Context) *cobra.Command {
+	var (
+		mappingFile      string
+		fix              bool
+		skipConfirmation bool
+		watch            bool
+		interval         time.Duration
+	)
+	cmd := &cobra.Command{
+		Use:   "label-sync",
+		Short: "Audits and fixes the istio.io/rev labels of namespaces against the revisions and tags of the mesh",
+		Long: `Audits the istio.io/rev labels of the namespaces of the cluster, and reports namespaces:
+
+  MISSING     labeled with neither an installed revision nor a revision tag
+  DEPRECATED  labeled with a revision or tag listed as deprecated in the mapping file
+  DRIFT       labeled differently from the mapping file
+
+The mapping file sets the desired revision or tag of namespaces, and the deprecated revisions and tags:
+
+  namespaces:
+    checkout: prod
+    payments: prod
+  deprecated:
+  - 1-22
+
+With --fix, the label of the namespaces which have a desired value in the mapping file is updated. Other findings are
+only reported. With --watch, the audit runs again at each interval until interrupted.`,
+		Example: `  # Report namespaces labeled with a revision that is not installed
+  istioctl experimental label-sync
+
+  # Move namespaces to the revisions of the mapping file
+  istioctl experimental label-sync --mapping mapping.yaml --fix
+
+  # Keep the namespaces consistent with the mapping file during a migration
+  istioctl experimental label-sync --mapping mapping.yaml --fix --watch -y`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if watch && fix && !skipConfirmation {
+				return fmt.Errorf("--watch with --fix requires --skip-confirmation")
+			}
+			mapping := &Mapping{}
+			if mappingFile != "" {
+				var err error
+				if mapping, err = readMapping(mappingFile); err != nil {
+					return err
+				}
+			}
+			kubeClient, err := ctx.CLIClient()
+			if err != nil {
+				return err
+			}
+			client := kubeClient.Kube()
+			w := cmd.OutOrStdout()
+			for {
+				if err := syncOnce(client, mapping, fix, skipConfirmation, w); err != nil {
+					return err
+				}
+				if !watch {
+					return nil
+				}
+				time.Sleep(interval)
+				_, _ = fmt.Fprintln(w)
+			}
+		},
+	}
+	cmd.Flags().StringVar(&mappingFile, "mapping", "", "File with the desired revision labels of namespaces and the deprecated revisions")
+	cmd.Flags().BoolVar(&fix, "fix", false, "Update the labels of the namespaces with a desired value in the mapping file")
+	cmd.Flags().BoolVarP(&skipConfirmation, "skip-confirmation", "y", false,
+		"The skipConfirmation determines whether the user is prompted for confirmation before updating namespaces.")
+	cmd.Flags().BoolVar(&watch, "watch", false, "Audit the namespaces again at each interval until interrupted")
+	cmd.Flags().DurationVar(&interval, "interval", time.Minute, "Interval between audits with --watch")
+	return cmd
+}
+
+func syncOnce(client kubernetes.Interface, mapping *Mapping, fix, skipConfirmation bool, w io.Writer) error {
+	findings, err := Audit(context.TODO(), client, mapping)
+	if err != nil {
+		return err
+	}
+	if len(findings) == 0 {
+		_, _ = fmt.Fprintln(w, "The revision labels of all namespaces are consistent.")
+		return nil
+	}
+	if err := printFindings(w, findings); err != nil {
+		return err
+	}
+	if !fix {
+		return nil
+	}
+	fixable := 0
+	for _, f := range findings {
+		if f.Desired != "" {
+			fixable++
+		}
+	}
+	if fixable == 0 {
+		return nil
+	}
+	if !skipConfirmation && !util.Confirm(fmt.Sprintf("\nUpdate the revision label of %d namespaces? [y/N]", fixable), w) {
+		_, _ = fmt.Fprintln(w, "Aborting operation.")
+		return nil
+	}
+	if err := Fix(context.TODO(), client, findings); err != nil {
+		return err
+	}
+	_, _ = fmt.Fprintf(w, "Updated the revision label of %d namespaces.\n", fixable)
+	return nil
+}
+
+func readMapping(path string) (*Mapping, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	m := &Mapping{}
+	if err := yaml.UnmarshalStrict(b, m); err != nil {
+		return nil, fmt.Errorf("failed to parse the mapping file %s: %v", path, err)
+	}
+	return m, nil
+}
+
+// Audit returns the namespaces whose istio.io/rev label points at a revision or tag which does not exist, is
+// deprecated, or differs from the mapping.
+func Audit(ctx context.Context, client kubernetes.Interface, mapping *Mapping) ([]Finding, error) {
+	valid, err := orphans.InstalledRevisions(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+	webhooks, err := tag.GetRevisionWebhooks(ctx, client)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list revision webhooks: %v", err)
+	}
+	for _, wh := range webhooks {
+		if t := tag.GetWebhookTagName(wh); t != "" {
+			valid.Insert(t)
+		}
+	}
+	deprecated := sets.New(mapping.Deprecated...)
+
+	namespaces, err := client.CoreV1().Namespaces().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	var findings []Finding
+	for _, ns := range namespaces.Items {
+		current, labeled := ns.Labels[label.IoIstioRev.Name]
+		desired, mapped := mapping.Namespaces[ns.Name]
+		if !labeled && !mapped {
+			continue
+		}
+		f := Finding{Namespace: ns.Name, Label: current}
+		switch {
+		case mapped && current != desired:
+			f.Status = StatusDrift
+			f.Desired = desired
+		case deprecated.Contains(current):
+			f.Status = StatusDeprecated
+		case !valid.Contains(current):
+			f.Status = StatusMissing
+		default:
+			continue
+		}
+		findings = append(findings, f)
+	}
+	sort.Slice(findings, func(i, j int) bool { return findings[i].Namespace < findings[j].Namespace })
+	return findings, nil
+}
+
+// Fix updates the istio.io/rev label of the namespaces of the findings with a desired value.
+func Fix(ctx context.Context, client kubernetes.Interface, findings []Finding) error {
+	for _, f := range findings {
+		if f.Desired == "" {
+			continue
+		}
+		patch := fmt.Sprintf(`{"metadata":{"labels":{%q:%q}}}`, label.IoIstioRev.Name, f.Desired)
+		if _, err := client.CoreV1().Namespaces().Patch(ctx, f.Namespace, types.MergePatchType, []byte(patch), metav1.PatchOptions{}); err != nil {
+			return fmt.Errorf("failed to update the revision label of namespace %s: %v", f.Namespace, err)
+		}
+	}
+	return nil
+}
+
+func printFindings(w io.Writer, findings []Finding) error {
+	tw := new(tabwriter.Writer).Init(w, 0, 8, 1, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "NAMESPACE\tLABEL\tSTATUS\tDESIRED")
+	for _, f := range findings {
+		current, desired := f.Label, f.Desired
+		if current == "" {
+			current = "-"
+		}
+		if desired == "" {
+			desired = "-"
+		}
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", f.Namespace, current, f.Status, desired)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,77 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package labelsync
+
+import (
+	"context"
+	"testing"
+
+	admitv1 "k8s.io/api/admissionregistration/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"istio.io/istio/pkg/test/util/assert"
+)
+
+func TestAuditAndFix(t *testing.T) {
+	namespace := func(name, revision string) *corev1.Namespace {
+		ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name}}
+		if revision != "" {
+			ns.Labels = map[string]string{"istio.io/rev": revision}
+		}
+		return ns
+	}
+	istiod := func(revision string) *appsv1.Deployment {
+		return &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{
+			Name: "istiod-" + revision, Namespace: "istio-system", Labels: map[string]string{"app": "istiod", "istio.io/rev": revision},
+		}}
+	}
+	client := fake.NewClientset(
+		istiod("1-23"),
+		istiod("1-24"),
+		&admitv1.MutatingWebhookConfiguration{ObjectMeta: metav1.ObjectMeta{
+			Name: "istio-revision-tag-prod", Labels: map[string]string{"istio.io/rev": "1-24", "istio.io/tag": "prod"},
+		}},
+		namespace("checkout", "prod"),
+		namespace("payments", "1-23"),
+		namespace("legacy", "1-21"),
+		namespace("search", "1-23"),
+		namespace("batch", ""),
+		namespace("unlabeled", ""),
+	)
+	mapping := &Mapping{
+		Namespaces: map[string]string{"payments": "prod", "batch": "prod", "checkout": "prod"},
+		Deprecated: []string{"1-23"},
+	}
+
+	findings, err := Audit(context.TODO(), client, mapping)
+	assert.NoError(t, err)
+	assert.Equal(t, findings, []Finding{
+		{Namespace: "batch", Label: "", Status: StatusDrift, Desired: "prod"},
+		{Namespace: "legacy", Label: "1-21", Status: StatusMissing},
+		{Namespace: "payments", Label: "1-23", Status: StatusDrift, Desired: "prod"},
+		{Namespace: "search", Label: "1-23", Status: StatusDeprecated},
+	})
+
+	assert.NoError(t, Fix(context.TODO(), client, findings))
+	findings, err = Audit(context.TODO(), client, mapping)
+	assert.NoError(t, err)
+	assert.Equal(t, findings, []Finding{
+		{Namespace: "legacy", Label: "1-21", Status: StatusMissing},
+		{Namespace: "search", Label: "1-23", Status: StatusDeprecated},
+	})
+}
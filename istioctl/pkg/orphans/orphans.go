@@ -0,0 +1,429 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orphans
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/spf13/cobra"
+	admitv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"istio.io/api/annotation"
+	"istio.io/api/label"
+	clientnetworking "istio.io/client-go/pkg/apis/networking/v1"
+	"istio.io/istio/istioctl/pkg/cli"
+	"istio.io/istio/istioctl/pkg/tag"
+	"istio.io/istio/istioctl/pkg/util"
+	"istio.io/istio/pilot/pkg/serviceregistry/kube/controller"
+	"istio.io/istio/pkg/kube"
+	"istio.io/istio/pkg/util/sets"
+)
+
+const (
+	mutatingWebhookKind   = "MutatingWebhookConfiguration"
+	validatingWebhookKind = "ValidatingWebhookConfiguration"
+	configMapKind         = "ConfigMap"
+	workloadEntryKind     = "WorkloadEntry"
+	deploymentKind        = "Deployment"
+	serviceKind           = "Service"
+
+	injectorConfigMapPrefix = "istio-sidecar-injector"
+	meshConfigMapName       = "istio"
+)
+
+// Orphan is an Istio artifact which no longer belongs to an installed control plane.
+type Orphan struct {
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+	Reason    string `json:"reason"`
+}
+
+func Cmd(ctx cli.Context) *cobra.Command {
+	var deleteOrphans, skipConfirmation bool
+	cmd := &cobra.Command{
+		Use:   "cleanup-orphans",
+		Short: "Detect and remove Istio artifacts left behind by uninstalled control plane revisions",
+		Long: `Detect Istio artifacts which no longer belong to an installed control plane revision:
+
+  * revision and tag MutatingWebhookConfigurations pointing at a revision with no istiod Deployment
+  * revision ValidatingWebhookConfigurations for a revision with no istiod Deployment
+  * injector and mesh ConfigMaps for a revision with no istiod Deployment
+  * istio-ca-root-cert ConfigMaps left in terminating namespaces
+  * WorkloadEntries auto-registered by istiod for a WorkloadGroup which no longer exists
+  * Deployments and Services of automated gateway deployments for a Gateway which no longer exists
+
+Installed revisions are determined from the istiod Deployments in the cluster. Webhooks pointing at a URL rather than
+a Service, as used with an external control plane, are never reported.
+
+Files installed on nodes by the Istio CNI plugin cannot be inspected through the Kubernetes API and are not checked.`,
+		Example: `  # List orphaned Istio artifacts
+  istioctl experimental cleanup-orphans
+
+  # Remove orphaned Istio artifacts after confirmation
+  istioctl experimental cleanup-orphans --delete`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			kubeClient, err := ctx.CLIClient()
+			if err != nil {
+				return err
+			}
+			orphans, err := FindOrphans(context.Background(), kubeClient)
+			if err != nil {
+				return err
+			}
+			w := cmd.OutOrStdout()
+			if len(orphans) == 0 {
+				_, _ = fmt.Fprintln(w, "No orphaned Istio artifacts found.")
+				return nil
+			}
+			if err := printOrphans(w, orphans); err != nil {
+				return err
+			}
+			if !deleteOrphans {
+				return nil
+			}
+			if !skipConfirmation && !util.Confirm(fmt.Sprintf("\nDelete the %d resources listed above? [y/N]", len(orphans)), w) {
+				_, _ = fmt.Fprintln(w, "Aborting operation.")
+				return nil
+			}
+			if err := DeleteOrphans(context.Background(), kubeClient, orphans); err != nil {
+				return err
+			}
+			_, _ = fmt.Fprintf(w, "Deleted %d orphaned Istio artifacts.\n", len(orphans))
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&deleteOrphans, "delete", false, "Delete the orphaned artifacts after confirmation")
+	cmd.Flags().BoolVarP(&skipConfirmation, "skip-confirmation", "y", false,
+		"The skipConfirmation determines whether the user is prompted for confirmation before deleting.")
+	return cmd
+}
+
+func printOrphans(w io.Writer, orphans []Orphan) error {
+	tw := new(tabwriter.Writer).Init(w, 0, 8, 1, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "KIND\tNAMESPACE\tNAME\tREASON")
+	for _, o := range orphans {
+		ns := o.Namespace
+		if ns == "" {
+			ns = "-"
+		}
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", o.Kind, ns, o.Name, o.Reason)
+	}
+	return tw.Flush()
+}
+
+// InstalledRevisions returns the revisions with an istiod Deployment in the cluster.
+func InstalledRevisions(ctx context.Context, client kubernetes.Interface) (sets.String, error) {
+	deployments, err := client.AppsV1().Deployments(metav1.NamespaceAll).List(ctx, metav1.ListOptions{
+		LabelSelector: "app=istiod",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list istiod deployments: %v", err)
+	}
+	revisions := sets.New[string]()
+	for _, d := range deployments.Items {
+		revisions.Insert(revisionOf(d.Labels))
+	}
+	return revisions, nil
+}
+
+// FindOrphans returns the Istio artifacts in the cluster which no longer belong to an installed revision.
+func FindOrphans(ctx context.Context, kubeClient kube.Client) ([]Orphan, error) {
+	client := kubeClient.Kube()
+	revisions, err := InstalledRevisions(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+	var orphans []Orphan
+	// Without any istiod, every revisioned resource would be reported. That is an uninstall, not a cleanup.
+	if revisions.Len() > 0 {
+		revisioned, err := findRevisionOrphans(ctx, client, revisions)
+		if err != nil {
+			return nil, err
+		}
+		orphans = append(orphans, revisioned...)
+	}
+	rootCerts, err := findRootCertOrphans(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+	orphans = append(orphans, rootCerts...)
+	runtime, err := findRuntimeOrphans(ctx, kubeClient)
+	if err != nil {
+		return nil, err
+	}
+	orphans = append(orphans, runtime...)
+	sort.SliceStable(orphans, func(i, j int) bool {
+		if orphans[i].Kind != orphans[j].Kind {
+			return orphans[i].Kind < orphans[j].Kind
+		}
+		if orphans[i].Namespace != orphans[j].Namespace {
+			return orphans[i].Namespace < orphans[j].Namespace
+		}
+		return orphans[i].Name < orphans[j].Name
+	})
+	return orphans, nil
+}
+
+func findRevisionOrphans(ctx context.Context, client kubernetes.Interface, revisions sets.String) ([]Orphan, error) {
+	var orphans []Orphan
+	selector := metav1.ListOptions{LabelSelector: label.IoIstioRev.Name}
+
+	mwhs, err := client.AdmissionregistrationV1().MutatingWebhookConfigurations().List(ctx, selector)
+	if err != nil {
+		return nil, err
+	}
+	for _, wh := range mwhs.Items {
+		rev := revisionOf(wh.Labels)
+		if revisions.Contains(rev) || usesURL(mutatingClientConfigs(wh)) {
+			continue
+		}
+		reason := fmt.Sprintf("revision %q is not installed", rev)
+		if t := tag.GetWebhookTagName(wh); t != "" {
+			reason = fmt.Sprintf("tag %q points at revision %q which is not installed", t, rev)
+		}
+		orphans = append(orphans, Orphan{Kind: mutatingWebhookKind, Name: wh.Name, Reason: reason})
+	}
+
+	vwhs, err := client.AdmissionregistrationV1().ValidatingWebhookConfigurations().List(ctx, selector)
+	if err != nil {
+		return nil, err
+	}
+	for _, wh := range vwhs.Items {
+		rev := revisionOf(wh.Labels)
+		if revisions.Contains(rev) || usesURL(validatingClientConfigs(wh)) {
+			continue
+		}
+		orphans = append(orphans, Orphan{
+			Kind:   validatingWebhookKind,
+			Name:   wh.Name,
+			Reason: fmt.Sprintf("revision %q is not installed", rev),
+		})
+	}
+
+	cms, err := client.CoreV1().ConfigMaps(metav1.NamespaceAll).List(ctx, selector)
+	if err != nil {
+		return nil, err
+	}
+	for _, cm := range cms.Items {
+		rev := revisionOf(cm.Labels)
+		if !isRevisionConfigMap(cm.Name, rev) || revisions.Contains(rev) {
+			continue
+		}
+		orphans = append(orphans, Orphan{
+			Kind:      configMapKind,
+			Namespace: cm.Namespace,
+			Name:      cm.Name,
+			Reason:    fmt.Sprintf("revision %q is not installed", rev),
+		})
+	}
+	return orphans, nil
+}
+
+// findRootCertOrphans returns the root certificate ConfigMaps in namespaces which are being deleted.
+func findRootCertOrphans(ctx context.Context, client kubernetes.Interface) ([]Orphan, error) {
+	namespaces, err := client.CoreV1().Namespaces().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	var orphans []Orphan
+	for _, ns := range namespaces.Items {
+		if ns.Status.Phase != corev1.NamespaceTerminating {
+			continue
+		}
+		_, err := client.CoreV1().ConfigMaps(ns.Name).Get(ctx, controller.CACertNamespaceConfigMap, metav1.GetOptions{})
+		if kerrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		orphans = append(orphans, Orphan{
+			Kind:      configMapKind,
+			Namespace: ns.Name,
+			Name:      controller.CACertNamespaceConfigMap,
+			Reason:    "namespace is terminating",
+		})
+	}
+	return orphans, nil
+}
+
+// findRuntimeOrphans returns the resources created at runtime by istiod whose owner no longer exists: auto-registered
+// WorkloadEntries without their WorkloadGroup, and automated gateway deployments without their Gateway. They are
+// normally garbage collected with their owner, unless the owner reference was removed or the owner was deleted while
+// istiod was not running.
+func findRuntimeOrphans(ctx context.Context, kubeClient kube.Client) ([]Orphan, error) {
+	var orphans []Orphan
+	entries, err := kubeClient.Istio().NetworkingV1().WorkloadEntries(metav1.NamespaceAll).List(ctx, metav1.ListOptions{})
+	// Without the WorkloadEntry CRD, there is nothing to check.
+	if kerrors.IsNotFound(err) {
+		entries = &clientnetworking.WorkloadEntryList{}
+	} else if err != nil {
+		return nil, err
+	}
+	for _, we := range entries.Items {
+		group := we.Annotations[annotation.IoIstioAutoRegistrationGroup.Name]
+		if group == "" {
+			continue
+		}
+		_, err := kubeClient.Istio().NetworkingV1().WorkloadGroups(we.Namespace).Get(ctx, group, metav1.GetOptions{})
+		if err == nil {
+			continue
+		}
+		if !kerrors.IsNotFound(err) {
+			return nil, err
+		}
+		orphans = append(orphans, Orphan{
+			Kind:      workloadEntryKind,
+			Namespace: we.Namespace,
+			Name:      we.Name,
+			Reason:    fmt.Sprintf("WorkloadGroup %q no longer exists", group),
+		})
+	}
+
+	managed := metav1.ListOptions{LabelSelector: label.GatewayManaged.Name}
+	// gatewayExists returns whether the Gateway of the resource exists, or true if the resource does not name its Gateway
+	// as it cannot be known to be orphaned.
+	gatewayExists := func(namespace string, labels map[string]string) (bool, error) {
+		name := labels[label.IoK8sNetworkingGatewayGatewayName.Name]
+		if name == "" {
+			return true, nil
+		}
+		_, err := kubeClient.GatewayAPI().GatewayV1().Gateways(namespace).Get(ctx, name, metav1.GetOptions{})
+		if kerrors.IsNotFound(err) {
+			return false, nil
+		}
+		return err == nil, err
+	}
+	deployments, err := kubeClient.Kube().AppsV1().Deployments(metav1.NamespaceAll).List(ctx, managed)
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range deployments.Items {
+		exists, err := gatewayExists(d.Namespace, d.Labels)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			orphans = append(orphans, Orphan{
+				Kind:      deploymentKind,
+				Namespace: d.Namespace,
+				Name:      d.Name,
+				Reason:    fmt.Sprintf("Gateway %q no longer exists", d.Labels[label.IoK8sNetworkingGatewayGatewayName.Name]),
+			})
+		}
+	}
+	services, err := kubeClient.Kube().CoreV1().Services(metav1.NamespaceAll).List(ctx, managed)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range services.Items {
+		exists, err := gatewayExists(s.Namespace, s.Labels)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			orphans = append(orphans, Orphan{
+				Kind:      serviceKind,
+				Namespace: s.Namespace,
+				Name:      s.Name,
+				Reason:    fmt.Sprintf("Gateway %q no longer exists", s.Labels[label.IoK8sNetworkingGatewayGatewayName.Name]),
+			})
+		}
+	}
+	return orphans, nil
+}
+
+// DeleteOrphans deletes the given orphaned artifacts.
+func DeleteOrphans(ctx context.Context, kubeClient kube.Client, orphans []Orphan) error {
+	client := kubeClient.Kube()
+	var result error
+	for _, o := range orphans {
+		var err error
+		switch o.Kind {
+		case mutatingWebhookKind:
+			err = client.AdmissionregistrationV1().MutatingWebhookConfigurations().Delete(ctx, o.Name, metav1.DeleteOptions{})
+		case validatingWebhookKind:
+			err = client.AdmissionregistrationV1().ValidatingWebhookConfigurations().Delete(ctx, o.Name, metav1.DeleteOptions{})
+		case configMapKind:
+			err = client.CoreV1().ConfigMaps(o.Namespace).Delete(ctx, o.Name, metav1.DeleteOptions{})
+		case workloadEntryKind:
+			err = kubeClient.Istio().NetworkingV1().WorkloadEntries(o.Namespace).Delete(ctx, o.Name, metav1.DeleteOptions{})
+		case deploymentKind:
+			err = client.AppsV1().Deployments(o.Namespace).Delete(ctx, o.Name, metav1.DeleteOptions{})
+		case serviceKind:
+			err = client.CoreV1().Services(o.Namespace).Delete(ctx, o.Name, metav1.DeleteOptions{})
+		default:
+			err = fmt.Errorf("unknown kind %q", o.Kind)
+		}
+		if err != nil && !kerrors.IsNotFound(err) {
+			result = multierror.Append(result, fmt.Errorf("failed to delete %s %s: %v", o.Kind, o.Name, err))
+		}
+	}
+	return result
+}
+
+func revisionOf(labels map[string]string) string {
+	if rev := labels[label.IoIstioRev.Name]; rev != "" {
+		return rev
+	}
+	return tag.DefaultRevisionName
+}
+
+// isRevisionConfigMap returns true for the injector and mesh config ConfigMaps created by the installer for the revision.
+// Other ConfigMaps labeled with a revision, such as the one of istio-cni, are not owned by the control plane.
+func isRevisionConfigMap(name, rev string) bool {
+	for _, base := range []string{injectorConfigMapPrefix, meshConfigMapName} {
+		if name == base || name == base+"-"+rev {
+			return true
+		}
+	}
+	return false
+}
+
+func mutatingClientConfigs(wh admitv1.MutatingWebhookConfiguration) []admitv1.WebhookClientConfig {
+	res := make([]admitv1.WebhookClientConfig, 0, len(wh.Webhooks))
+	for _, w := range wh.Webhooks {
+		res = append(res, w.ClientConfig)
+	}
+	return res
+}
+
+func validatingClientConfigs(wh admitv1.ValidatingWebhookConfiguration) []admitv1.WebhookClientConfig {
+	res := make([]admitv1.WebhookClientConfig, 0, len(wh.Webhooks))
+	for _, w := range wh.Webhooks {
+		res = append(res, w.ClientConfig)
+	}
+	return res
+}
+
+func usesURL(configs []admitv1.WebhookClientConfig) bool {
+	for _, c := range configs {
+		if c.URL != nil {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,175 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orphans
+
+import (
+	"context"
+	"testing"
+
+	admitv1 "k8s.io/api/admissionregistration/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	gateway "sigs.k8s.io/gateway-api/apis/v1"
+
+	"istio.io/api/annotation"
+	"istio.io/api/label"
+	clientnetworking "istio.io/client-go/pkg/apis/networking/v1"
+	"istio.io/istio/pkg/config/schema/gvk"
+	"istio.io/istio/pkg/kube"
+	"istio.io/istio/pkg/ptr"
+	"istio.io/istio/pkg/test/util/assert"
+)
+
+func istiod(rev string) *appsv1.Deployment {
+	labels := map[string]string{"app": "istiod"}
+	name := "istiod"
+	if rev != "" {
+		labels[label.IoIstioRev.Name] = rev
+		name += "-" + rev
+	}
+	return &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "istio-system", Labels: labels}}
+}
+
+func mutatingWebhook(name string, labels map[string]string, url *string) *admitv1.MutatingWebhookConfiguration {
+	return &admitv1.MutatingWebhookConfiguration{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels},
+		Webhooks:   []admitv1.MutatingWebhook{{Name: "sidecar-injector.istio.io", ClientConfig: admitv1.WebhookClientConfig{URL: url}}},
+	}
+}
+
+func configMap(ns, name, rev string) *corev1.ConfigMap {
+	return &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: ns, Labels: map[string]string{label.IoIstioRev.Name: rev}}}
+}
+
+func workloadEntry(name, group string) *clientnetworking.WorkloadEntry {
+	we := &clientnetworking.WorkloadEntry{
+		TypeMeta:   metav1.TypeMeta{APIVersion: gvk.WorkloadEntry.GroupVersion(), Kind: gvk.WorkloadEntry.Kind},
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+	}
+	if group != "" {
+		we.Annotations = map[string]string{annotation.IoIstioAutoRegistrationGroup.Name: group}
+	}
+	return we
+}
+
+func gatewayLabels(gw string) map[string]string {
+	return map[string]string{label.GatewayManaged.Name: "istio.io-gateway-controller", label.IoK8sNetworkingGatewayGatewayName.Name: gw}
+}
+
+func gatewayDeployment(name, gw string) *appsv1.Deployment {
+	return &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Labels: gatewayLabels(gw)}}
+}
+
+func TestFindOrphans(t *testing.T) {
+	cases := []struct {
+		name    string
+		objects []runtime.Object
+		want    []Orphan
+	}{
+		{
+			name: "no orphans",
+			objects: []runtime.Object{
+				istiod(""),
+				mutatingWebhook("istio-sidecar-injector", map[string]string{label.IoIstioRev.Name: "default"}, nil),
+				configMap("istio-system", "istio", "default"),
+			},
+		},
+		{
+			name: "no control plane",
+			objects: []runtime.Object{
+				mutatingWebhook("istio-sidecar-injector-canary", map[string]string{label.IoIstioRev.Name: "canary"}, nil),
+			},
+		},
+		{
+			name: "stale revision",
+			objects: []runtime.Object{
+				istiod("stable"),
+				mutatingWebhook("istio-sidecar-injector-canary", map[string]string{label.IoIstioRev.Name: "canary"}, nil),
+				mutatingWebhook("istio-revision-tag-prod", map[string]string{label.IoIstioRev.Name: "canary", label.IoIstioTag.Name: "prod"}, nil),
+				mutatingWebhook("istio-sidecar-injector-remote", map[string]string{label.IoIstioRev.Name: "remote"}, ptr.Of("https://istiod.example.com")),
+				&admitv1.ValidatingWebhookConfiguration{
+					ObjectMeta: metav1.ObjectMeta{Name: "istio-validator-canary-istio-system", Labels: map[string]string{label.IoIstioRev.Name: "canary"}},
+				},
+				configMap("istio-system", "istio-stable", "stable"),
+				configMap("istio-system", "istio-canary", "canary"),
+				configMap("istio-system", "istio-sidecar-injector-canary", "canary"),
+				configMap("istio-system", "unrelated", "canary"),
+				configMap("istio-system", "istio-cni-config", "default"),
+			},
+			want: []Orphan{
+				{Kind: configMapKind, Namespace: "istio-system", Name: "istio-canary", Reason: `revision "canary" is not installed`},
+				{Kind: configMapKind, Namespace: "istio-system", Name: "istio-sidecar-injector-canary", Reason: `revision "canary" is not installed`},
+				{Kind: mutatingWebhookKind, Name: "istio-revision-tag-prod", Reason: `tag "prod" points at revision "canary" which is not installed`},
+				{Kind: mutatingWebhookKind, Name: "istio-sidecar-injector-canary", Reason: `revision "canary" is not installed`},
+				{Kind: validatingWebhookKind, Name: "istio-validator-canary-istio-system", Reason: `revision "canary" is not installed`},
+			},
+		},
+		{
+			name: "terminating namespace",
+			objects: []runtime.Object{
+				&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "gone"}, Status: corev1.NamespaceStatus{Phase: corev1.NamespaceTerminating}},
+				&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "active"}, Status: corev1.NamespaceStatus{Phase: corev1.NamespaceActive}},
+				&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "istio-ca-root-cert", Namespace: "gone"}},
+				&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "istio-ca-root-cert", Namespace: "active"}},
+			},
+			want: []Orphan{
+				{Kind: configMapKind, Namespace: "gone", Name: "istio-ca-root-cert", Reason: "namespace is terminating"},
+			},
+		},
+		{
+			name: "runtime resources without owner",
+			objects: []runtime.Object{
+				istiod(""),
+				&clientnetworking.WorkloadGroup{
+					TypeMeta:   metav1.TypeMeta{APIVersion: gvk.WorkloadGroup.GroupVersion(), Kind: gvk.WorkloadGroup.Kind},
+					ObjectMeta: metav1.ObjectMeta{Name: "vm", Namespace: "default"},
+				},
+				workloadEntry("vm-10.0.0.1", "vm"),
+				workloadEntry("legacy-10.0.0.2", "legacy"),
+				workloadEntry("static", ""),
+				&gateway.Gateway{
+					TypeMeta:   metav1.TypeMeta{APIVersion: gvk.KubernetesGateway_v1.GroupVersion(), Kind: gvk.KubernetesGateway_v1.Kind},
+					ObjectMeta: metav1.ObjectMeta{Name: "ingress", Namespace: "default"},
+				},
+				gatewayDeployment("ingress-istio", "ingress"),
+				gatewayDeployment("old-istio", "old"),
+				&corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "old-istio", Namespace: "default", Labels: gatewayLabels("old")}},
+				&corev1.Service{ObjectMeta: metav1.ObjectMeta{
+					Name: "unnamed-istio", Namespace: "default", Labels: map[string]string{label.GatewayManaged.Name: "istio.io-gateway-controller"},
+				}},
+			},
+			want: []Orphan{
+				{Kind: deploymentKind, Namespace: "default", Name: "old-istio", Reason: `Gateway "old" no longer exists`},
+				{Kind: serviceKind, Namespace: "default", Name: "old-istio", Reason: `Gateway "old" no longer exists`},
+				{Kind: workloadEntryKind, Namespace: "default", Name: "legacy-10.0.0.2", Reason: `WorkloadGroup "legacy" no longer exists`},
+			},
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			client := kube.NewFakeClient(tt.objects...)
+			got, err := FindOrphans(context.Background(), client)
+			assert.NoError(t, err)
+			assert.Equal(t, got, tt.want)
+
+			assert.NoError(t, DeleteOrphans(context.Background(), client, got))
+			got, err = FindOrphans(context.Background(), client)
+			assert.NoError(t, err)
+			assert.Equal(t, len(got), 0)
+		})
+	}
+}
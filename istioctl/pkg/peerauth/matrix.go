@@ -0,0 +1,259 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package peerauth
+
+import (
+	"sort"
+
+	networking "istio.io/api/networking/v1alpha3"
+	"istio.io/api/security/v1beta1"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/security/authn"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/host"
+)
+
+// Effective is the outcome of a connection between a client and a server.
+type Effective string
+
+const (
+	// EffectiveMTLS is used when the connection uses istio mTLS.
+	EffectiveMTLS Effective = "MTLS"
+	// EffectiveTLS is used when the client originates TLS which is not istio mTLS, passed through to the server.
+	EffectiveTLS Effective = "TLS"
+	// EffectivePlaintext is used when the connection is plaintext.
+	EffectivePlaintext Effective = "PLAINTEXT"
+	// EffectiveBroken is used when the TLS settings of the client are rejected by the server.
+	EffectiveBroken Effective = "BROKEN"
+)
+
+// Namespace is a namespace of the mesh.
+type Namespace struct {
+	Name string
+	// Mesh is true if the workloads of the namespace have a proxy, through sidecar injection or ambient mode.
+	Mesh bool
+}
+
+// Inputs holds the configuration from which the matrix is computed.
+type Inputs struct {
+	RootNamespace string
+	DomainSuffix  string
+	AutoMTLS      bool
+	Namespaces    []Namespace
+	// PeerAuthentications and DestinationRules hold the configurations of all namespaces.
+	PeerAuthentications []*config.Config
+	DestinationRules    []*config.Config
+}
+
+// Server is a set of workloads sharing the same effective PeerAuthentication: a namespace, or the workloads selected
+// by a workload level PeerAuthentication.
+type Server struct {
+	Name      string
+	Namespace string
+	Mesh      bool
+	Policy    authn.MergedPeerAuthentication
+	// PlaintextAllowed is true if some workloads or ports of the server accept plaintext.
+	PlaintextAllowed bool
+}
+
+// Cell is the effective mTLS mode of the connections from a client namespace to a server.
+type Cell struct {
+	Client     string `json:"client"`
+	Server     string `json:"server"`
+	ServerMode string `json:"serverMode"`
+	ClientTLS  string `json:"clientTLS"`
+	// AutoMTLS is true if the client TLS mode is set by auto mTLS rather than a DestinationRule.
+	AutoMTLS         bool      `json:"autoMTLS"`
+	DestinationRule  string    `json:"destinationRule,omitempty"`
+	Effective        Effective `json:"effective"`
+	PlaintextAllowed bool      `json:"plaintextAllowed"`
+}
+
+// Matrix holds a cell for each client namespace and server.
+type Matrix struct {
+	Clients []string
+	Servers []string
+	Cells   []Cell
+}
+
+// Compute returns the matrix of effective mTLS modes between the namespaces. With workloads set, the workloads
+// selected by a PeerAuthentication are reported as their own servers; otherwise their modes are only reflected in
+// whether the namespace accepts plaintext.
+//
+// The TLS mode of the client is taken from the DestinationRules covering the whole server namespace, such as
+// *.<namespace>.svc.cluster.local; DestinationRules for individual services are not considered.
+func Compute(in Inputs, workloads bool) Matrix {
+	namespaces := append([]Namespace{}, in.Namespaces...)
+	sort.Slice(namespaces, func(i, j int) bool { return namespaces[i].Name < namespaces[j].Name })
+
+	var servers []Server
+	for _, ns := range namespaces {
+		servers = append(servers, serversForNamespace(in, ns, workloads)...)
+	}
+
+	m := Matrix{}
+	for _, s := range servers {
+		m.Servers = append(m.Servers, s.Name)
+	}
+	for _, client := range namespaces {
+		m.Clients = append(m.Clients, client.Name)
+		for _, s := range servers {
+			m.Cells = append(m.Cells, cell(in, client, s))
+		}
+	}
+	return m
+}
+
+func serversForNamespace(in Inputs, ns Namespace, workloads bool) []Server {
+	var namespaceCfgs, workloadCfgs []*config.Config
+	for _, cfg := range in.PeerAuthentications {
+		if cfg.Namespace != ns.Name && cfg.Namespace != in.RootNamespace {
+			continue
+		}
+		if len(cfg.Spec.(*v1beta1.PeerAuthentication).GetSelector().GetMatchLabels()) == 0 {
+			namespaceCfgs = append(namespaceCfgs, cfg)
+		} else if cfg.Namespace == ns.Name && cfg.Namespace != in.RootNamespace {
+			workloadCfgs = append(workloadCfgs, cfg)
+		}
+	}
+
+	policy := authn.ComposePeerAuthentication(in.RootNamespace, namespaceCfgs)
+	res := []Server{{Name: ns.Name, Namespace: ns.Name, Mesh: ns.Mesh, Policy: policy, PlaintextAllowed: plaintextAllowed(policy)}}
+	sort.Slice(workloadCfgs, func(i, j int) bool { return workloadCfgs[i].Name < workloadCfgs[j].Name })
+	for _, cfg := range workloadCfgs {
+		wp := authn.ComposePeerAuthentication(in.RootNamespace, append(append([]*config.Config{}, namespaceCfgs...), cfg))
+		if workloads {
+			res = append(res, Server{Name: ns.Name + "/" + cfg.Name, Namespace: ns.Name, Mesh: ns.Mesh, Policy: wp, PlaintextAllowed: plaintextAllowed(wp)})
+		} else {
+			res[0].PlaintextAllowed = res[0].PlaintextAllowed || plaintextAllowed(wp)
+		}
+	}
+	for i := range res {
+		res[i].PlaintextAllowed = res[i].PlaintextAllowed || !ns.Mesh
+	}
+	return res
+}
+
+func plaintextAllowed(p authn.MergedPeerAuthentication) bool {
+	if p.Mode != model.MTLSStrict {
+		return true
+	}
+	for _, mode := range p.PerPort {
+		if mode != model.MTLSStrict {
+			return true
+		}
+	}
+	return false
+}
+
+func cell(in Inputs, client Namespace, s Server) Cell {
+	c := Cell{
+		Client:           client.Name,
+		Server:           s.Name,
+		ServerMode:       s.Policy.Mode.String(),
+		PlaintextAllowed: s.PlaintextAllowed,
+	}
+	if !s.Mesh {
+		// PeerAuthentications only apply to workloads with a proxy.
+		c.ServerMode = "-"
+	}
+
+	mode := networking.ClientTLSSettings_DISABLE
+	switch {
+	case !client.Mesh:
+		// Without a proxy, the client sends plaintext unless the application originates TLS.
+	default:
+		dr, tls := destinationRule(in, client.Name, s.Namespace)
+		if dr != nil {
+			c.DestinationRule = dr.Namespace + "/" + dr.Name
+		}
+		if tls != nil {
+			mode = tls.GetMode()
+		} else if in.AutoMTLS {
+			c.AutoMTLS = true
+			if s.Mesh {
+				mode = networking.ClientTLSSettings_ISTIO_MUTUAL
+			}
+		}
+	}
+	c.ClientTLS = mode.String()
+	c.Effective = effective(mode, s)
+	return c
+}
+
+func effective(mode networking.ClientTLSSettings_TLSmode, s Server) Effective {
+	serverMode := s.Policy.Mode
+	if !s.Mesh {
+		serverMode = model.MTLSDisable
+	}
+	switch mode {
+	case networking.ClientTLSSettings_ISTIO_MUTUAL:
+		if !s.Mesh || serverMode == model.MTLSDisable {
+			return EffectiveBroken
+		}
+		return EffectiveMTLS
+	case networking.ClientTLSSettings_SIMPLE, networking.ClientTLSSettings_MUTUAL:
+		if serverMode == model.MTLSStrict {
+			return EffectiveBroken
+		}
+		return EffectiveTLS
+	default:
+		if serverMode == model.MTLSStrict {
+			return EffectiveBroken
+		}
+		return EffectivePlaintext
+	}
+}
+
+// destinationRule returns the DestinationRule applied by the proxies of the client namespace to the services of the
+// server namespace, with the TLS settings it sets, if any. As in istiod, the DestinationRules of the client
+// namespace take precedence over those of the server namespace, then of the root namespace.
+func destinationRule(in Inputs, client, server string) (*config.Config, *networking.ClientTLSSettings) {
+	target := host.Name("*." + server + ".svc." + in.DomainSuffix)
+	for _, ns := range []string{client, server, in.RootNamespace} {
+		var match *config.Config
+		var matchHost host.Name
+		for _, cfg := range in.DestinationRules {
+			dr := cfg.Spec.(*networking.DestinationRule)
+			if cfg.Namespace != ns || dr.GetWorkloadSelector() != nil || !exportedTo(cfg.Namespace, dr.GetExportTo(), client) {
+				continue
+			}
+			h := host.Name(dr.GetHost())
+			if !target.SubsetOf(h) {
+				continue
+			}
+			// The most specific host wins.
+			if match == nil || len(h) > len(matchHost) {
+				match, matchHost = cfg, h
+			}
+		}
+		if match != nil {
+			return match, match.Spec.(*networking.DestinationRule).GetTrafficPolicy().GetTls()
+		}
+	}
+	return nil, nil
+}
+
+func exportedTo(namespace string, exportTo []string, client string) bool {
+	if len(exportTo) == 0 {
+		return true
+	}
+	for _, e := range exportTo {
+		if e == "*" || e == client || e == "." && namespace == client {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,111 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package peerauth
+
+import (
+	"testing"
+
+	networking "istio.io/api/networking/v1alpha3"
+	"istio.io/api/security/v1beta1"
+	typev1beta1 "istio.io/api/type/v1beta1"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/test/util/assert"
+)
+
+func newPeerAuthentication(name, namespace string, mode v1beta1.PeerAuthentication_MutualTLS_Mode, selector map[string]string) *config.Config {
+	spec := &v1beta1.PeerAuthentication{Mtls: &v1beta1.PeerAuthentication_MutualTLS{Mode: mode}}
+	if selector != nil {
+		spec.Selector = &typev1beta1.WorkloadSelector{MatchLabels: selector}
+	}
+	return &config.Config{Meta: config.Meta{Name: name, Namespace: namespace}, Spec: spec}
+}
+
+func newDestinationRule(name, namespace, host string, mode networking.ClientTLSSettings_TLSmode, exportTo ...string) *config.Config {
+	return &config.Config{Meta: config.Meta{Name: name, Namespace: namespace}, Spec: &networking.DestinationRule{
+		Host:          host,
+		TrafficPolicy: &networking.TrafficPolicy{Tls: &networking.ClientTLSSettings{Mode: mode}},
+		ExportTo:      exportTo,
+	}}
+}
+
+func TestCompute(t *testing.T) {
+	in := Inputs{
+		RootNamespace: "istio-system",
+		DomainSuffix:  "cluster.local",
+		AutoMTLS:      true,
+		Namespaces: []Namespace{
+			{Name: "istio-system", Mesh: false},
+			{Name: "legacy", Mesh: false},
+			{Name: "bar", Mesh: true},
+			{Name: "foo", Mesh: true},
+		},
+		PeerAuthentications: []*config.Config{
+			newPeerAuthentication("default", "istio-system", v1beta1.PeerAuthentication_MutualTLS_STRICT, nil),
+			newPeerAuthentication("metrics", "foo", v1beta1.PeerAuthentication_MutualTLS_PERMISSIVE, map[string]string{"app": "metrics"}),
+		},
+		DestinationRules: []*config.Config{
+			// Only applies to the clients of bar.
+			newDestinationRule("plaintext", "bar", "*.foo.svc.cluster.local", networking.ClientTLSSettings_DISABLE, "."),
+			newDestinationRule("istio-mutual", "bar", "*.local", networking.ClientTLSSettings_ISTIO_MUTUAL, "."),
+		},
+	}
+
+	m := Compute(in, false)
+	assert.Equal(t, m.Clients, []string{"bar", "foo", "istio-system", "legacy"})
+	assert.Equal(t, m.Servers, []string{"bar", "foo", "istio-system", "legacy"})
+	get := func(m Matrix, client, server string) Cell {
+		for _, c := range m.Cells {
+			if c.Client == client && c.Server == server {
+				return c
+			}
+		}
+		t.Fatalf("no cell for %s -> %s", client, server)
+		return Cell{}
+	}
+
+	c := get(m, "foo", "bar")
+	assert.Equal(t, c, Cell{
+		Client: "foo", Server: "bar", ServerMode: "STRICT", ClientTLS: "ISTIO_MUTUAL", AutoMTLS: true,
+		Effective: EffectiveMTLS, PlaintextAllowed: false,
+	})
+	// A workload level PeerAuthentication allows plaintext.
+	assert.Equal(t, get(m, "bar", "foo").PlaintextAllowed, true)
+	assert.Equal(t, get(m, "bar", "foo").Effective, EffectiveBroken)
+	assert.Equal(t, get(m, "bar", "foo").DestinationRule, "bar/plaintext")
+	assert.Equal(t, get(m, "foo", "foo").Effective, EffectiveMTLS)
+	// Clients without a proxy send plaintext, rejected by STRICT servers.
+	assert.Equal(t, get(m, "legacy", "bar").Effective, EffectiveBroken)
+	// Auto mTLS sends plaintext to servers without a proxy.
+	assert.Equal(t, get(m, "foo", "legacy"), Cell{
+		Client: "foo", Server: "legacy", ServerMode: "-", ClientTLS: "DISABLE", AutoMTLS: true,
+		Effective: EffectivePlaintext, PlaintextAllowed: true,
+	})
+	// The explicit ISTIO_MUTUAL of the *.local DestinationRule breaks connections to servers without a proxy.
+	assert.Equal(t, get(m, "bar", "legacy").Effective, EffectiveBroken)
+
+	m = Compute(in, true)
+	assert.Equal(t, m.Servers, []string{"bar", "foo", "foo/metrics", "istio-system", "legacy"})
+	assert.Equal(t, get(m, "bar", "foo").PlaintextAllowed, false)
+	assert.Equal(t, get(m, "bar", "foo/metrics").Effective, EffectivePlaintext)
+	assert.Equal(t, get(m, "bar", "foo/metrics").ServerMode, "PERMISSIVE")
+}
+
+func TestExportedTo(t *testing.T) {
+	assert.Equal(t, exportedTo("foo", nil, "bar"), true)
+	assert.Equal(t, exportedTo("foo", []string{"."}, "bar"), false)
+	assert.Equal(t, exportedTo("foo", []string{"."}, "foo"), true)
+	assert.Equal(t, exportedTo("foo", []string{"bar"}, "bar"), true)
+	assert.Equal(t, exportedTo("foo", []string{"~"}, "bar"), false)
+}
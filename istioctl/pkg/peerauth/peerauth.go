@@ -0,0 +1,189 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package peerauth
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"istio.io/api/label"
+	"istio.io/istio/istioctl/pkg/cli"
+	"istio.io/istio/istioctl/pkg/util"
+	"istio.io/istio/istioctl/pkg/util/ambient"
+	"istio.io/istio/pilot/pkg/config/kube/crdclient"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/constants"
+	"istio.io/istio/pkg/config/mesh"
+	"istio.io/istio/pkg/kube"
+	"istio.io/istio/pkg/kube/inject"
+)
+
+func Cmd(ctx cli.Context) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "peer-auth",
+		Short: "Commands to review the mTLS settings of the mesh",
+	}
+	cmd.AddCommand(matrixCmd(ctx))
+	return cmd
+}
+
+func matrixCmd(ctx cli.Context) *cobra.Command {
+	var (
+		workloads    bool
+		outputFormat string
+	)
+	cmd := &cobra.Command{
+		Use:   "matrix",
+		Short: "Prints the effective mTLS mode between each pair of namespaces",
+		Long: `Prints a matrix of the effective mTLS mode of the connections from each client namespace (rows) to each
+server namespace (columns), computed from the PeerAuthentications and DestinationRules of the cluster.
+
+Each cell is one of:
+
+  MTLS       the connection uses istio mTLS
+  TLS        the client originates TLS which is passed through to the server
+  PLAINTEXT  the connection is plaintext
+  BROKEN     the server rejects the TLS settings of the client
+
+and is suffixed with "*" when the server accepts plaintext from clients outside the mesh, because it is not
+STRICT, or some of its workloads or ports are not.
+
+With --workloads, workloads selected by a PeerAuthentication are reported as their own servers. DestinationRules
+for individual services are not considered, only those covering whole namespaces.`,
+		Example: `  # Print the matrix of the mesh
+  istioctl experimental peer-auth matrix
+
+  # Export the matrix, including workload level PeerAuthentications, for review
+  istioctl experimental peer-auth matrix --workloads -o csv > mtls.csv`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if outputFormat != "table" && outputFormat != "csv" && outputFormat != "json" {
+				return fmt.Errorf("unknown output format %q, must be table, csv or json", outputFormat)
+			}
+			kubeClient, err := ctx.CLIClient()
+			if err != nil {
+				return err
+			}
+			in, err := fetchInputs(kubeClient, ctx.IstioNamespace())
+			if err != nil {
+				return err
+			}
+			return printMatrix(cmd.OutOrStdout(), Compute(in, workloads), outputFormat)
+		},
+	}
+	cmd.Flags().BoolVar(&workloads, "workloads", false, "Report workloads selected by a PeerAuthentication as their own servers")
+	cmd.Flags().StringVarP(&outputFormat, "output", "o", "table", "Output format: one of table|csv|json")
+	return cmd
+}
+
+func fetchInputs(kubeClient kube.CLIClient, istioNamespace string) (Inputs, error) {
+	cm, err := kubeClient.Kube().CoreV1().ConfigMaps(istioNamespace).Get(context.TODO(), util.DefaultMeshConfigMapName, metav1.GetOptions{})
+	if err != nil {
+		return Inputs{}, fmt.Errorf("failed to retrieve the mesh config: %v", err)
+	}
+	meshCfg, err := mesh.ApplyMeshConfigDefaults(cm.Data[util.ConfigMapKey])
+	if err != nil {
+		return Inputs{}, fmt.Errorf("failed to parse the mesh config: %v", err)
+	}
+	in := Inputs{
+		RootNamespace: meshCfg.GetRootNamespace(),
+		DomainSuffix:  constants.DefaultClusterLocalDomain,
+		AutoMTLS:      meshCfg.GetEnableAutoMtls().GetValue(),
+	}
+
+	namespaces, err := kubeClient.Kube().CoreV1().Namespaces().List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return Inputs{}, err
+	}
+	for _, ns := range namespaces.Items {
+		if inject.IgnoredNamespaces.Contains(ns.Name) {
+			continue
+		}
+		in.Namespaces = append(in.Namespaces, Namespace{Name: ns.Name, Mesh: inMesh(&ns)})
+	}
+
+	pas, err := kubeClient.Istio().SecurityV1().PeerAuthentications(metav1.NamespaceAll).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return Inputs{}, fmt.Errorf("failed to list PeerAuthentications: %v", err)
+	}
+	for _, pa := range pas.Items {
+		cfg := crdclient.TranslateObject(pa, config.GroupVersionKind(pa.GroupVersionKind()), "")
+		in.PeerAuthentications = append(in.PeerAuthentications, &cfg)
+	}
+	drs, err := kubeClient.Istio().NetworkingV1().DestinationRules(metav1.NamespaceAll).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return Inputs{}, fmt.Errorf("failed to list DestinationRules: %v", err)
+	}
+	for _, dr := range drs.Items {
+		cfg := crdclient.TranslateObject(dr, config.GroupVersionKind(dr.GroupVersionKind()), "")
+		in.DestinationRules = append(in.DestinationRules, &cfg)
+	}
+	return in, nil
+}
+
+// inMesh returns whether the workloads of the namespace have a proxy, through sidecar injection or ambient mode.
+func inMesh(ns *corev1.Namespace) bool {
+	if ns.Labels["istio-injection"] == "enabled" || ns.Labels[label.IoIstioRev.Name] != "" {
+		return true
+	}
+	return ambient.InAmbient(ns)
+}
+
+func printMatrix(w io.Writer, m Matrix, outputFormat string) error {
+	switch outputFormat {
+	case "json":
+		out, err := json.MarshalIndent(m.Cells, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(out))
+		return err
+	case "csv":
+		cw := csv.NewWriter(w)
+		_ = cw.Write([]string{"client", "server", "serverMode", "clientTLS", "autoMTLS", "destinationRule", "effective", "plaintextAllowed"})
+		for _, c := range m.Cells {
+			_ = cw.Write([]string{
+				c.Client, c.Server, c.ServerMode, c.ClientTLS, strconv.FormatBool(c.AutoMTLS),
+				c.DestinationRule, string(c.Effective), strconv.FormatBool(c.PlaintextAllowed),
+			})
+		}
+		cw.Flush()
+		return cw.Error()
+	}
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(tw, "CLIENT \\ SERVER\t%s\n", strings.Join(m.Servers, "\t"))
+	for i, client := range m.Clients {
+		row := make([]string, 0, len(m.Servers))
+		for _, c := range m.Cells[i*len(m.Servers) : (i+1)*len(m.Servers)] {
+			s := string(c.Effective)
+			if c.PlaintextAllowed {
+				s += "*"
+			}
+			row = append(row, s)
+		}
+		fmt.Fprintf(tw, "%s\t%s\n", client, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,141 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package precheck
+
+import (
+	"context"
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"istio.io/istio/operator/pkg/component"
+	"istio.io/istio/pkg/config/analysis/diag"
+	"istio.io/istio/pkg/config/analysis/msg"
+	"istio.io/istio/pkg/kube"
+	"istio.io/istio/pkg/ptr"
+)
+
+// componentLabel is set by the charts on the resources of each Istio component, such as their autoscalers and budgets.
+const componentLabel = "operator.istio.io/component"
+
+// Checks the HorizontalPodAutoscalers and PodDisruptionBudgets of the Istio components, istiod and the gateways, against
+// their live Deployments and pods, as their existence tells nothing of whether they work: an autoscaler without metrics
+// does not scale, and a budget selecting no ready pod protects nothing, or blocks every eviction.
+func checkAutoscaling(cli kube.CLIClient) (diag.Messages, error) {
+	ctx := context.Background()
+	msgs := diag.Messages{}
+	hpas, err := cli.Kube().AutoscalingV2().HorizontalPodAutoscalers(metav1.NamespaceAll).List(ctx, metav1.ListOptions{LabelSelector: componentLabel})
+	if err != nil {
+		return nil, err
+	}
+	for i := range hpas.Items {
+		hpa := &hpas.Items[i]
+		target := hpa.Spec.ScaleTargetRef
+		if target.Kind != "Deployment" {
+			continue
+		}
+		d, err := cli.Kube().AppsV1().Deployments(hpa.Namespace).Get(ctx, target.Name, metav1.GetOptions{})
+		if kerrors.IsNotFound(err) {
+			msgs.Add(msg.NewAutoscalerIneffective(kindToInstance(hpaKind, hpa), target.Name, "the Deployment does not exist"))
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, problem := range autoscalerProblems(hpa, d) {
+			msgs.Add(msg.NewAutoscalerIneffective(kindToInstance(hpaKind, hpa), d.Name, problem))
+		}
+	}
+
+	pdbs, err := cli.Kube().PolicyV1().PodDisruptionBudgets(metav1.NamespaceAll).List(ctx, metav1.ListOptions{LabelSelector: componentLabel})
+	if err != nil {
+		return nil, err
+	}
+	for i := range pdbs.Items {
+		pdb := &pdbs.Items[i]
+		selector, err := metav1.LabelSelectorAsSelector(pdb.Spec.Selector)
+		if err != nil || selector.Empty() {
+			continue
+		}
+		pods, err := cli.Kube().CoreV1().Pods(pdb.Namespace).List(ctx, metav1.ListOptions{LabelSelector: selector.String()})
+		if err != nil {
+			return nil, err
+		}
+		selected, ready := 0, 0
+		for j := range pods.Items {
+			if pods.Items[j].DeletionTimestamp != nil {
+				continue
+			}
+			selected++
+			if podNotReadyReason(&pods.Items[j]) == "" {
+				ready++
+			}
+		}
+		var problem string
+		required, blocked := drainBlocked(pdb.Spec.MinAvailable, pdb.Spec.MaxUnavailable, selected)
+		switch {
+		case selected == 0:
+			problem = "selects no pod, so it protects none"
+		case ready < required:
+			problem = fmt.Sprintf("requires %d available pods, but only %d of the %d pods it selects are ready, so no pod can be evicted",
+				required, ready, selected)
+		case !blocked:
+		// The budgets of istiod are checked with its replicas, and the default budget of a single replica install is
+		// only worth a notice.
+		case pdb.Labels[componentLabel] == string(component.PilotComponentName):
+		case selected == 1 && pdb.Spec.MinAvailable != nil && *pdb.Spec.MinAvailable == intstr.FromInt32(1):
+		default:
+			problem = fmt.Sprintf("requires %d available pods of the %d it selects, so no pod can be evicted and node drains will block",
+				required, selected)
+		}
+		if problem != "" {
+			msgs.Add(msg.NewPodDisruptionBudgetIneffective(pdbToInstance(pdb), pdb.Name, problem))
+		}
+	}
+	return msgs, nil
+}
+
+// autoscalerProblems returns why the autoscaler cannot scale the Deployment as configured.
+func autoscalerProblems(hpa *autoscalingv2.HorizontalPodAutoscaler, d *appsv1.Deployment) []string {
+	var problems []string
+	for _, c := range hpa.Status.Conditions {
+		if c.Type == autoscalingv2.ScalingActive && c.Status == corev1.ConditionFalse {
+			problems = append(problems, fmt.Sprintf("its metrics are unavailable (%s: %s)", c.Reason, c.Message))
+		}
+	}
+	// Utilization is relative to the requests of the containers, so it cannot be computed if any of them has none.
+	for _, m := range hpa.Spec.Metrics {
+		if m.Type != autoscalingv2.ResourceMetricSourceType || m.Resource == nil || m.Resource.Target.Type != autoscalingv2.UtilizationMetricType {
+			continue
+		}
+		for _, c := range d.Spec.Template.Spec.Containers {
+			if _, ok := c.Resources.Requests[m.Resource.Name]; !ok {
+				problems = append(problems, fmt.Sprintf("the utilization of %s is relative to the requests of the containers, which container %s does not set",
+					m.Resource.Name, c.Name))
+			}
+		}
+	}
+	minReplicas := ptr.OrDefault(hpa.Spec.MinReplicas, 1)
+	if replicas := ptr.OrDefault(d.Spec.Replicas, 1); replicas < minReplicas || replicas > hpa.Spec.MaxReplicas {
+		problems = append(problems, fmt.Sprintf("the Deployment runs %d replicas, out of the bounds of the autoscaler (%d to %d)",
+			replicas, minReplicas, hpa.Spec.MaxReplicas))
+	}
+	return problems
+}
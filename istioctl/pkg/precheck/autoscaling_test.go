@@ -0,0 +1,163 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package precheck
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"istio.io/istio/pkg/config/analysis/msg"
+	"istio.io/istio/pkg/kube"
+	"istio.io/istio/pkg/ptr"
+)
+
+func TestCheckAutoscaling(t *testing.T) {
+	labels := map[string]string{"app": "istio-ingressgateway", "istio": "ingressgateway"}
+	componentLabels := map[string]string{componentLabel: "IngressGateways"}
+	deployment := func(requests corev1.ResourceList) *appsv1.Deployment {
+		return &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "istio-ingressgateway", Namespace: "istio-system", Labels: labels},
+			Spec: appsv1.DeploymentSpec{
+				Replicas: ptr.Of(int32(2)),
+				Selector: &metav1.LabelSelector{MatchLabels: labels},
+				Template: corev1.PodTemplateSpec{
+					ObjectMeta: metav1.ObjectMeta{Labels: labels},
+					Spec: corev1.PodSpec{Containers: []corev1.Container{{
+						Name:      "istio-proxy",
+						Resources: corev1.ResourceRequirements{Requests: requests},
+					}}},
+				},
+			},
+		}
+	}
+	withRequests := deployment(corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("100m")})
+	hpa := func(target string, active bool) *autoscalingv2.HorizontalPodAutoscaler {
+		status := corev1.ConditionTrue
+		if !active {
+			status = corev1.ConditionFalse
+		}
+		return &autoscalingv2.HorizontalPodAutoscaler{
+			ObjectMeta: metav1.ObjectMeta{Name: "istio-ingressgateway", Namespace: "istio-system", Labels: componentLabels},
+			Spec: autoscalingv2.HorizontalPodAutoscalerSpec{
+				ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{Kind: "Deployment", Name: target},
+				MinReplicas:    ptr.Of(int32(1)),
+				MaxReplicas:    5,
+				Metrics: []autoscalingv2.MetricSpec{{
+					Type: autoscalingv2.ResourceMetricSourceType,
+					Resource: &autoscalingv2.ResourceMetricSource{
+						Name:   corev1.ResourceCPU,
+						Target: autoscalingv2.MetricTarget{Type: autoscalingv2.UtilizationMetricType, AverageUtilization: ptr.Of(int32(80))},
+					},
+				}},
+			},
+			Status: autoscalingv2.HorizontalPodAutoscalerStatus{Conditions: []autoscalingv2.HorizontalPodAutoscalerCondition{{
+				Type:    autoscalingv2.ScalingActive,
+				Status:  status,
+				Reason:  "FailedGetResourceMetric",
+				Message: "unable to get metrics for resource cpu",
+			}}},
+		}
+	}
+	pod := func(name string, ready bool) *corev1.Pod {
+		status := corev1.ConditionTrue
+		if !ready {
+			status = corev1.ConditionFalse
+		}
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "istio-system", Labels: labels},
+			Status: corev1.PodStatus{
+				Phase:      corev1.PodRunning,
+				Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: status}},
+			},
+		}
+	}
+	pdb := func(minAvailable, maxUnavailable *intstr.IntOrString) *policyv1.PodDisruptionBudget {
+		return &policyv1.PodDisruptionBudget{
+			ObjectMeta: metav1.ObjectMeta{Name: "istio-ingressgateway", Namespace: "istio-system", Labels: componentLabels},
+			Spec: policyv1.PodDisruptionBudgetSpec{
+				MinAvailable:   minAvailable,
+				MaxUnavailable: maxUnavailable,
+				Selector:       &metav1.LabelSelector{MatchLabels: labels},
+			},
+		}
+	}
+	one, two, zero := ptr.Of(intstr.FromInt32(1)), ptr.Of(intstr.FromInt32(2)), ptr.Of(intstr.FromInt32(0))
+
+	cases := []struct {
+		name    string
+		objects []runtime.Object
+		want    [][]any
+	}{
+		{
+			name:    "healthy",
+			objects: []runtime.Object{withRequests, hpa("istio-ingressgateway", true), pdb(one, nil), pod("gw-a", true), pod("gw-b", true)},
+		},
+		{
+			name:    "default single replica budget",
+			objects: []runtime.Object{withRequests, pdb(one, nil), pod("gw-a", true)},
+		},
+		{
+			name:    "autoscaler without target",
+			objects: []runtime.Object{hpa("istio-egressgateway", true)},
+			want:    [][]any{{"istio-egressgateway", "the Deployment does not exist"}},
+		},
+		{
+			name:    "autoscaler without metrics",
+			objects: []runtime.Object{deployment(nil), hpa("istio-ingressgateway", false)},
+			want: [][]any{
+				{"istio-ingressgateway", "its metrics are unavailable (FailedGetResourceMetric: unable to get metrics for resource cpu)"},
+				{"istio-ingressgateway", "the utilization of cpu is relative to the requests of the containers, which container istio-proxy does not set"},
+			},
+		},
+		{
+			name:    "budget selecting no pod",
+			objects: []runtime.Object{withRequests, pdb(one, nil)},
+			want:    [][]any{{"istio-ingressgateway", "selects no pod, so it protects none"}},
+		},
+		{
+			name:    "budget requiring pods which are not ready",
+			objects: []runtime.Object{withRequests, pdb(two, nil), pod("gw-a", true), pod("gw-b", false)},
+			want: [][]any{{"istio-ingressgateway", "requires 2 available pods, but only 1 of the 2 pods it selects are ready, " +
+				"so no pod can be evicted"}},
+		},
+		{
+			name:    "budget allowing no disruption",
+			objects: []runtime.Object{withRequests, pdb(nil, zero), pod("gw-a", true), pod("gw-b", true)},
+			want: [][]any{{"istio-ingressgateway", "requires 2 available pods of the 2 it selects, so no pod can be evicted and " +
+				"node drains will block"}},
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			msgs, err := checkAutoscaling(kube.NewFakeClient(tt.objects...))
+			assert.NoError(t, err)
+			var got [][]any
+			for _, m := range msgs {
+				assert.Contains(t, []string{msg.AutoscalerIneffective.Code(), msg.PodDisruptionBudgetIneffective.Code()}, m.Type.Code())
+				got = append(got, m.Parameters)
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
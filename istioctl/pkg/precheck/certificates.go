@@ -0,0 +1,203 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package precheck
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"istio.io/istio/istioctl/pkg/util"
+	"istio.io/istio/pilot/pkg/serviceregistry/kube/controller"
+	"istio.io/istio/pkg/config/analysis/diag"
+	"istio.io/istio/pkg/config/analysis/msg"
+	"istio.io/istio/pkg/config/constants"
+	"istio.io/istio/pkg/config/mesh"
+	"istio.io/istio/pkg/kube"
+	"istio.io/istio/pkg/spiffe"
+	"istio.io/istio/pkg/util/sets"
+	"istio.io/istio/security/pkg/pki/ca"
+	pkiutil "istio.io/istio/security/pkg/pki/util"
+)
+
+// caCertificate is a certificate of the Istio CA, with the role it plays in the CA hierarchy.
+type caCertificate struct {
+	role string
+	cert *x509.Certificate
+}
+
+// Checks the certificates of the Istio CA: the plugged in "cacerts" secret if present, and the self-signed
+// "istio-ca-secret" otherwise. Each certificate is checked for expiry and for its trust domain, and the root
+// certificates distributed in the istio-ca-root-cert ConfigMaps are checked to contain the CA root.
+// Clusters without either secret are skipped, as Istio is not installed yet or uses an external CA.
+func checkCACertificates(cli kube.CLIClient, istioNamespace string, expiryWindow time.Duration, now time.Time) (diag.Messages, error) {
+	msgs := diag.Messages{}
+	secret, err := caSecret(cli, istioNamespace)
+	if err != nil || secret == nil {
+		return msgs, err
+	}
+	certs, roots, err := parseCASecret(secret)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse CA secret %s/%s: %v", secret.Namespace, secret.Name, err)
+	}
+	trustDomains, meshTrustDomain, err := meshTrustDomains(cli, istioNamespace)
+	if err != nil {
+		return nil, err
+	}
+
+	res := ObjectToInstance(secret)
+	for _, c := range certs {
+		subject := c.cert.Subject.String()
+		notAfter := c.cert.NotAfter.UTC().Format(time.RFC3339)
+		remaining := c.cert.NotAfter.Sub(now)
+		switch {
+		case remaining <= 0:
+			msgs.Add(msg.NewCACertificateExpired(res, c.role, subject, secret.Name, notAfter))
+		case remaining < expiryWindow:
+			msgs.Add(msg.NewCACertificateExpiring(res, c.role, subject, secret.Name, notAfter, remaining.Truncate(time.Minute).String()))
+		default:
+			msgs.Add(msg.NewCACertificateLifetime(res, c.role, subject, secret.Name, notAfter, remaining.Truncate(time.Minute).String()))
+		}
+		if td := certTrustDomain(c.cert); td != "" && len(trustDomains) > 0 && !trustDomains.Contains(td) {
+			msgs.Add(msg.NewCACertificateTrustDomainMismatch(res, c.role, subject, secret.Name, td, meshTrustDomain))
+		}
+	}
+
+	cms, err := cli.Kube().CoreV1().ConfigMaps(metav1.NamespaceAll).List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for i := range cms.Items {
+		cm := &cms.Items[i]
+		if cm.Name != controller.CACertNamespaceConfigMap {
+			continue
+		}
+		if !containsRoot(cm.Data[constants.CACertNamespaceConfigMapDataName], roots) {
+			msgs.Add(msg.NewRootCertBundleMismatch(ObjectToInstance(cm), cm.Namespace, secret.Name))
+		}
+	}
+	return msgs, nil
+}
+
+// caSecret returns the secret holding the CA certificates used by istiod, or nil if there is none.
+func caSecret(cli kube.CLIClient, istioNamespace string) (*corev1.Secret, error) {
+	for _, name := range []string{ca.CACertsSecret, ca.CASecret} {
+		s, err := cli.Kube().CoreV1().Secrets(istioNamespace).Get(context.Background(), name, metav1.GetOptions{})
+		if kerrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return s, nil
+	}
+	return nil, nil
+}
+
+// parseCASecret returns the certificates of the CA secret, and its root certificates. Both the Istio key names
+// and the kubernetes.io/tls ones are supported. A CA without a root certificate is self-signed, and is its own root.
+func parseCASecret(secret *corev1.Secret) ([]caCertificate, []*x509.Certificate, error) {
+	signingFile, rootFile := ca.CACertFile, ca.RootCertFile
+	if _, ok := secret.Data[signingFile]; !ok {
+		signingFile, rootFile = ca.TLSSecretCACertFile, ca.TLSSecretRootCertFile
+	}
+	signing, err := pkiutil.ParsePemEncodedCertificate(secret.Data[signingFile])
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %v", signingFile, err)
+	}
+	certs := []caCertificate{{role: "signing", cert: signing}}
+
+	roots := []*x509.Certificate{signing}
+	if len(bytes.TrimSpace(secret.Data[rootFile])) > 0 {
+		roots, _, err = pkiutil.ParsePemEncodedCertificateChain(secret.Data[rootFile])
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %v", rootFile, err)
+		}
+		for _, r := range roots {
+			if !r.Equal(signing) {
+				certs = append(certs, caCertificate{role: "root", cert: r})
+			}
+		}
+	}
+
+	if chain := secret.Data[ca.CertChainFile]; len(bytes.TrimSpace(chain)) > 0 {
+		intermediates, _, err := pkiutil.ParsePemEncodedCertificateChain(chain)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %v", ca.CertChainFile, err)
+		}
+	chain:
+		for _, c := range intermediates {
+			for _, known := range certs {
+				if c.Equal(known.cert) {
+					continue chain
+				}
+			}
+			certs = append(certs, caCertificate{role: "intermediate", cert: c})
+		}
+	}
+	return certs, roots, nil
+}
+
+// meshTrustDomains returns the trust domain of the mesh and its aliases. Nothing is returned if the mesh
+// config is not found, in which case trust domains are not checked.
+func meshTrustDomains(cli kube.CLIClient, istioNamespace string) (sets.String, string, error) {
+	cm, err := cli.Kube().CoreV1().ConfigMaps(istioNamespace).Get(context.Background(), util.DefaultMeshConfigMapName, metav1.GetOptions{})
+	if kerrors.IsNotFound(err) {
+		return nil, "", nil
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	cfg, err := mesh.ApplyMeshConfigDefaults(cm.Data[util.ConfigMapKey])
+	if err != nil {
+		return nil, "", fmt.Errorf("error parsing mesh config: %v", err)
+	}
+	return sets.New(cfg.TrustDomain).InsertAll(cfg.TrustDomainAliases...), cfg.TrustDomain, nil
+}
+
+// certTrustDomain returns the trust domain of the SPIFFE URI SAN of the certificate, if any.
+func certTrustDomain(cert *x509.Certificate) string {
+	for _, u := range cert.URIs {
+		if u.Scheme == spiffe.Scheme {
+			return u.Host
+		}
+	}
+	return ""
+}
+
+// containsRoot returns true if the PEM bundle contains one of the roots.
+func containsRoot(bundle string, roots []*x509.Certificate) bool {
+	if len(bytes.TrimSpace([]byte(bundle))) == 0 {
+		return false
+	}
+	certs, _, err := pkiutil.ParsePemEncodedCertificateChain([]byte(bundle))
+	if err != nil {
+		return false
+	}
+	for _, c := range certs {
+		for _, r := range roots {
+			if c.Equal(r) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,118 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package precheck
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"istio.io/istio/pkg/config/analysis/diag"
+	"istio.io/istio/pkg/config/analysis/msg"
+	"istio.io/istio/pkg/kube"
+	"istio.io/istio/security/pkg/pki/ca"
+	pkiutil "istio.io/istio/security/pkg/pki/util"
+)
+
+func genCACert(t *testing.T, opts pkiutil.CertOptions) ([]byte, []byte) {
+	opts.IsCA = true
+	opts.RSAKeySize = 2048
+	cert, key, err := pkiutil.GenCertKeyFromOptions(opts)
+	assert.NoError(t, err)
+	return cert, key
+}
+
+func TestCheckCACertificates(t *testing.T) {
+	now := time.Now()
+	rootPem, rootKeyPem := genCACert(t, pkiutil.CertOptions{Org: "root", NotBefore: now, TTL: 365 * 24 * time.Hour, IsSelfSigned: true})
+	root, err := pkiutil.ParsePemEncodedCertificate(rootPem)
+	assert.NoError(t, err)
+	rootKey, err := pkiutil.ParsePemEncodedKey(rootKeyPem)
+	assert.NoError(t, err)
+	intermediatePem, _ := genCACert(t, pkiutil.CertOptions{
+		Host:       "spiffe://other.domain/ns/istio-system/sa/citadel",
+		Org:        "intermediate",
+		NotBefore:  now,
+		TTL:        10 * 24 * time.Hour,
+		SignerCert: root,
+		SignerPriv: rootKey,
+	})
+	otherRootPem, _ := genCACert(t, pkiutil.CertOptions{Org: "other", NotBefore: now, TTL: time.Hour, IsSelfSigned: true})
+
+	rootCertConfigMap := func(namespace string, root []byte) *corev1.ConfigMap {
+		return &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "istio-ca-root-cert", Namespace: namespace},
+			Data:       map[string]string{"root-cert.pem": string(root)},
+		}
+	}
+	cli := kube.NewFakeClient(
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: ca.CACertsSecret, Namespace: "istio-system"},
+			Data: map[string][]byte{
+				ca.CACertFile:    intermediatePem,
+				ca.RootCertFile:  rootPem,
+				ca.CertChainFile: append(append([]byte{}, intermediatePem...), rootPem...),
+			},
+		},
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "istio", Namespace: "istio-system"},
+			Data:       map[string]string{"mesh": "trustDomain: cluster.local"},
+		},
+		rootCertConfigMap("foo", rootPem),
+		rootCertConfigMap("bar", otherRootPem),
+	)
+
+	msgs, err := checkCACertificates(cli, "istio-system", 30*24*time.Hour, now)
+	assert.NoError(t, err)
+	types := map[*diag.MessageType]int{}
+	for _, m := range msgs {
+		types[m.Type]++
+	}
+	assert.Equal(t, map[*diag.MessageType]int{
+		msg.CACertificateExpiring:            1,
+		msg.CACertificateTrustDomainMismatch: 1,
+		msg.CACertificateLifetime:            1,
+		msg.RootCertBundleMismatch:           1,
+	}, types)
+	for _, m := range msgs {
+		switch m.Type {
+		case msg.CACertificateTrustDomainMismatch:
+			assert.Equal(t, []any{"signing", "O=intermediate", ca.CACertsSecret, "other.domain", "cluster.local"}, m.Parameters)
+		case msg.CACertificateLifetime:
+			assert.Equal(t, "root", m.Parameters[0])
+		case msg.RootCertBundleMismatch:
+			assert.Equal(t, []any{"bar", ca.CACertsSecret}, m.Parameters)
+		}
+	}
+
+	// Once the signing certificate has expired, it is reported as such.
+	msgs, err = checkCACertificates(cli, "istio-system", 30*24*time.Hour, now.Add(11*24*time.Hour))
+	assert.NoError(t, err)
+	found := false
+	for _, m := range msgs {
+		if m.Type == msg.CACertificateExpired {
+			found = true
+		}
+	}
+	assert.True(t, found)
+
+	// Clusters without a CA secret are skipped.
+	msgs, err = checkCACertificates(kube.NewFakeClient(), "istio-system", 30*24*time.Hour, now)
+	assert.NoError(t, err)
+	assert.Len(t, msgs, 0)
+}
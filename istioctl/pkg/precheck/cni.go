@@ -0,0 +1,238 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package precheck
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+
+	"istio.io/istio/pkg/config/analysis/diag"
+	"istio.io/istio/pkg/config/analysis/msg"
+	"istio.io/istio/pkg/kube"
+	"istio.io/istio/pkg/kube/controllers"
+	"istio.io/istio/pkg/ptr"
+)
+
+const (
+	cniNodeSelector = "k8s-app=istio-cni-node"
+	// cniNodePoolLabel is set on the pods of the DaemonSets rendered for the nodePools of the istio-cni chart.
+	cniNodePoolLabel = "istio.io/cni-node-pool"
+	// cniConfigMap holds the configuration of the istio-cni node agent, in the namespace of its DaemonSet.
+	cniConfigMap     = "istio-cni-config"
+	ztunnelSelector  = "app.kubernetes.io/name=ztunnel"
+	waypointSelector = "gateway.istio.io/managed=istio.io-mesh-controller"
+)
+
+// Checks that, when the CNI node agent is installed per node pool, each Linux node is selected by exactly one of its
+// DaemonSets, and runs a ready pod of it. A node selected by none of them gets no CNI configuration, and a node selected
+// by several gets its CNI configuration written by concurrent agents, possibly with different directories.
+func checkCNINodePools(cli kube.CLIClient) (diag.Messages, error) {
+	ctx := context.Background()
+	daemonSets, err := cli.Kube().AppsV1().DaemonSets(metav1.NamespaceAll).List(ctx, metav1.ListOptions{LabelSelector: cniNodeSelector})
+	if err != nil {
+		return nil, err
+	}
+	pools := false
+	for _, ds := range daemonSets.Items {
+		if _, ok := ds.Spec.Template.Labels[cniNodePoolLabel]; ok {
+			pools = true
+		}
+	}
+	if !pools {
+		return nil, nil
+	}
+
+	nodes, err := cli.Kube().CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	pods, err := cli.Kube().CoreV1().Pods(metav1.NamespaceAll).List(ctx, metav1.ListOptions{LabelSelector: cniNodeSelector})
+	if err != nil {
+		return nil, err
+	}
+	// The DaemonSets, by namespace and name, with a ready pod on each node.
+	ready := map[string]map[string]bool{}
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		owner := metav1.GetControllerOf(pod)
+		if owner == nil || owner.Kind != "DaemonSet" || !podReady(pod) {
+			continue
+		}
+		if ready[pod.Spec.NodeName] == nil {
+			ready[pod.Spec.NodeName] = map[string]bool{}
+		}
+		ready[pod.Spec.NodeName][pod.Namespace+"/"+owner.Name] = true
+	}
+
+	msgs := diag.Messages{}
+	for i := range nodes.Items {
+		node := &nodes.Items[i]
+		if node.Labels[corev1.LabelOSStable] == "windows" {
+			continue
+		}
+		var matching []string
+		for j := range daemonSets.Items {
+			if ds := &daemonSets.Items[j]; schedulesOn(&ds.Spec.Template.Spec, node) {
+				matching = append(matching, ds.Namespace+"/"+ds.Name)
+			}
+		}
+		sort.Strings(matching)
+		var reason string
+		switch {
+		case len(matching) == 0:
+			reason = "is not selected by any Istio CNI DaemonSet"
+		case len(matching) > 1:
+			reason = fmt.Sprintf("is selected by several Istio CNI DaemonSets (%s)", strings.Join(matching, ", "))
+		case !ready[node.Name][matching[0]]:
+			reason = fmt.Sprintf("has no ready pod of the Istio CNI DaemonSet %s", matching[0])
+		default:
+			continue
+		}
+		msgs.Add(msg.NewCNINodeNotCovered(ObjectToInstance(node), node.Name, reason))
+	}
+	return msgs, nil
+}
+
+// schedulesOn returns whether the nodeSelector and the required node affinity of the pod spec match the node.
+func schedulesOn(spec *corev1.PodSpec, node *corev1.Node) bool {
+	if !labels.SelectorFromSet(spec.NodeSelector).Matches(labels.Set(node.Labels)) {
+		return false
+	}
+	if spec.Affinity == nil || spec.Affinity.NodeAffinity == nil ||
+		spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution == nil {
+		return true
+	}
+	// The terms are ORed, and the requirements of each term ANDed. A term without requirements matches no node.
+	for _, term := range spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms {
+		if len(term.MatchExpressions)+len(term.MatchFields) > 0 &&
+			matchesRequirements(term.MatchExpressions, node.Labels) &&
+			matchesRequirements(term.MatchFields, map[string]string{"metadata.name": node.Name}) {
+			return true
+		}
+	}
+	return false
+}
+
+var nodeSelectorOperators = map[corev1.NodeSelectorOperator]selection.Operator{
+	corev1.NodeSelectorOpIn:           selection.In,
+	corev1.NodeSelectorOpNotIn:        selection.NotIn,
+	corev1.NodeSelectorOpExists:       selection.Exists,
+	corev1.NodeSelectorOpDoesNotExist: selection.DoesNotExist,
+	corev1.NodeSelectorOpGt:           selection.GreaterThan,
+	corev1.NodeSelectorOpLt:           selection.LessThan,
+}
+
+func matchesRequirements(requirements []corev1.NodeSelectorRequirement, set map[string]string) bool {
+	for _, r := range requirements {
+		req, err := labels.NewRequirement(r.Key, nodeSelectorOperators[r.Operator], r.Values)
+		if err != nil || !req.Matches(labels.Set(set)) {
+			return false
+		}
+	}
+	return true
+}
+
+func podReady(pod *corev1.Pod) bool {
+	for _, c := range pod.Status.Conditions {
+		if c.Type == corev1.PodReady {
+			return c.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// Checks the components of the ambient mesh, when ztunnel is installed: the ztunnel and istio-cni DaemonSets must be
+// rolled out and ready on every node, the istio-cni node agent must run with ambient enabled to redirect the traffic of
+// ambient pods to ztunnel, and the waypoint Deployments must be ready.
+func checkAmbient(cli kube.CLIClient) (diag.Messages, error) {
+	ctx := context.Background()
+	ztunnels, err := cli.Kube().AppsV1().DaemonSets(metav1.NamespaceAll).List(ctx, metav1.ListOptions{LabelSelector: ztunnelSelector})
+	if err != nil {
+		return nil, err
+	}
+	if len(ztunnels.Items) == 0 {
+		return nil, nil
+	}
+	cnis, err := cli.Kube().AppsV1().DaemonSets(metav1.NamespaceAll).List(ctx, metav1.ListOptions{LabelSelector: cniNodeSelector})
+	if err != nil {
+		return nil, err
+	}
+
+	msgs := diag.Messages{}
+	unhealthy := func(obj controllers.Object, kind, problem string) {
+		msgs.Add(msg.NewAmbientComponentUnhealthy(ObjectToInstance(obj), kind, obj.GetNamespace()+"/"+obj.GetName(), problem))
+	}
+	for i := range ztunnels.Items {
+		if problem := rolloutProblem(&ztunnels.Items[i]); problem != "" {
+			unhealthy(&ztunnels.Items[i], "DaemonSet", problem)
+		}
+	}
+	if len(cnis.Items) == 0 {
+		unhealthy(&ztunnels.Items[0], "DaemonSet", "the istio-cni node agent, which redirects the traffic of ambient pods to ztunnel, is not installed")
+	}
+	for i := range cnis.Items {
+		ds := &cnis.Items[i]
+		if problem := rolloutProblem(ds); problem != "" {
+			unhealthy(ds, "DaemonSet", problem)
+		}
+		cm, err := cli.Kube().CoreV1().ConfigMaps(ds.Namespace).Get(ctx, cniConfigMap, metav1.GetOptions{})
+		if kerrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if cm.Data["AMBIENT_ENABLED"] != "true" {
+			unhealthy(cm, "ConfigMap", "AMBIENT_ENABLED is not \"true\", so the istio-cni node agent does not redirect the traffic of "+
+				"ambient pods to ztunnel")
+		}
+	}
+
+	waypoints, err := cli.Kube().AppsV1().Deployments(metav1.NamespaceAll).List(ctx, metav1.ListOptions{LabelSelector: waypointSelector})
+	if err != nil {
+		return nil, err
+	}
+	for i := range waypoints.Items {
+		d := &waypoints.Items[i]
+		if replicas := ptr.OrDefault(d.Spec.Replicas, 1); d.Status.ReadyReplicas < replicas {
+			unhealthy(d, "waypoint Deployment", fmt.Sprintf("%d of its %d replicas are ready", d.Status.ReadyReplicas, replicas))
+		}
+	}
+	return msgs, nil
+}
+
+// rolloutProblem returns why the DaemonSet is not rolled out and ready on every node it is scheduled on, or an empty
+// string if it is.
+func rolloutProblem(ds *appsv1.DaemonSet) string {
+	status := ds.Status
+	switch {
+	case status.ObservedGeneration < ds.Generation:
+		return "its latest spec is not observed by the DaemonSet controller yet"
+	case status.UpdatedNumberScheduled < status.DesiredNumberScheduled:
+		return fmt.Sprintf("%d of its %d pods run its latest spec", status.UpdatedNumberScheduled, status.DesiredNumberScheduled)
+	case status.NumberReady < status.DesiredNumberScheduled:
+		return fmt.Sprintf("%d of its %d pods are ready", status.NumberReady, status.DesiredNumberScheduled)
+	}
+	return ""
+}
@@ -0,0 +1,224 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package precheck
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"istio.io/istio/pkg/config/analysis/msg"
+	"istio.io/istio/pkg/kube"
+)
+
+func TestCheckCNINodePools(t *testing.T) {
+	const poolLabel = "cloud.google.com/gke-nodepool"
+	node := func(name string, labels ...string) *corev1.Node {
+		l := map[string]string{}
+		for i := 0; i < len(labels); i += 2 {
+			l[labels[i]] = labels[i+1]
+		}
+		return &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: l}}
+	}
+	daemonSet := func(name, pool string, affinity *corev1.Affinity) *appsv1.DaemonSet {
+		podLabels := map[string]string{"k8s-app": "istio-cni-node"}
+		var selector map[string]string
+		if pool != "" {
+			podLabels[cniNodePoolLabel] = pool
+			selector = map[string]string{poolLabel: pool}
+		}
+		return &appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "istio-system", Labels: map[string]string{"k8s-app": "istio-cni-node"}},
+			Spec: appsv1.DaemonSetSpec{Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: podLabels},
+				Spec:       corev1.PodSpec{NodeSelector: selector, Affinity: affinity},
+			}},
+		}
+	}
+	excludePools := &corev1.Affinity{NodeAffinity: &corev1.NodeAffinity{
+		RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{NodeSelectorTerms: []corev1.NodeSelectorTerm{{
+			MatchExpressions: []corev1.NodeSelectorRequirement{{
+				Key: poolLabel, Operator: corev1.NodeSelectorOpNotIn, Values: []string{"legacy"},
+			}},
+		}}},
+	}}
+	pod := func(daemonSet, node string, ready bool) *corev1.Pod {
+		status := corev1.ConditionFalse
+		if ready {
+			status = corev1.ConditionTrue
+		}
+		controller := true
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: daemonSet + "-" + node, Namespace: "istio-system", Labels: map[string]string{"k8s-app": "istio-cni-node"},
+				OwnerReferences: []metav1.OwnerReference{{Kind: "DaemonSet", Name: daemonSet, Controller: &controller}},
+			},
+			Spec:   corev1.PodSpec{NodeName: node},
+			Status: corev1.PodStatus{Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: status}}},
+		}
+	}
+
+	cases := []struct {
+		name    string
+		objects []runtime.Object
+		want    [][]any
+	}{
+		{
+			name: "no node pools",
+			objects: []runtime.Object{
+				daemonSet("istio-cni-node", "", nil),
+				node("node-1"),
+			},
+		},
+		{
+			name: "all nodes covered",
+			objects: []runtime.Object{
+				daemonSet("istio-cni-node", "", excludePools),
+				daemonSet("istio-cni-node-legacy", "legacy", nil),
+				node("node-1", poolLabel, "default"),
+				node("node-2", poolLabel, "legacy"),
+				node("windows", corev1.LabelOSStable, "windows"),
+				pod("istio-cni-node", "node-1", true),
+				pod("istio-cni-node-legacy", "node-2", true),
+			},
+		},
+		{
+			name: "uncovered, doubly covered and not ready nodes",
+			objects: []runtime.Object{
+				daemonSet("istio-cni-node", "", nil),
+				daemonSet("istio-cni-node-legacy", "legacy", nil),
+				daemonSet("istio-cni-node-custom", "custom", excludePools),
+				node("node-1", poolLabel, "default"),
+				node("node-2", poolLabel, "legacy"),
+				node("node-3", poolLabel, "custom"),
+				pod("istio-cni-node", "node-1", false),
+			},
+			want: [][]any{
+				{"node-1", "has no ready pod of the Istio CNI DaemonSet istio-system/istio-cni-node"},
+				{"node-2", "is selected by several Istio CNI DaemonSets (istio-system/istio-cni-node, istio-system/istio-cni-node-legacy)"},
+				{"node-3", "is selected by several Istio CNI DaemonSets (istio-system/istio-cni-node, istio-system/istio-cni-node-custom)"},
+			},
+		},
+		{
+			name: "node of no pool",
+			objects: []runtime.Object{
+				daemonSet("istio-cni-node-legacy", "legacy", nil),
+				node("node-1", poolLabel, "default"),
+			},
+			want: [][]any{
+				{"node-1", "is not selected by any Istio CNI DaemonSet"},
+			},
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			msgs, err := checkCNINodePools(kube.NewFakeClient(tt.objects...))
+			assert.NoError(t, err)
+			var got [][]any
+			for _, m := range msgs.SortedDedupedCopy() {
+				assert.Equal(t, msg.CNINodeNotCovered, m.Type)
+				got = append(got, m.Parameters)
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestCheckAmbient(t *testing.T) {
+	daemonSet := func(name string, labels map[string]string, desired, updated, ready int32) *appsv1.DaemonSet {
+		return &appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "istio-system", Labels: labels},
+			Status: appsv1.DaemonSetStatus{
+				DesiredNumberScheduled: desired,
+				UpdatedNumberScheduled: updated,
+				NumberReady:            ready,
+			},
+		}
+	}
+	ztunnel := func(updated, ready int32) *appsv1.DaemonSet {
+		return daemonSet("ztunnel", map[string]string{"app.kubernetes.io/name": "ztunnel"}, 3, updated, ready)
+	}
+	cni := func(updated, ready int32) *appsv1.DaemonSet {
+		return daemonSet("istio-cni-node", map[string]string{"k8s-app": "istio-cni-node"}, 3, updated, ready)
+	}
+	cniConfig := func(ambient string) *corev1.ConfigMap {
+		return &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "istio-cni-config", Namespace: "istio-system"},
+			Data:       map[string]string{"AMBIENT_ENABLED": ambient},
+		}
+	}
+	waypoint := func(ready int32) *appsv1.Deployment {
+		return &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "waypoint",
+				Namespace: "bookinfo",
+				Labels:    map[string]string{"gateway.istio.io/managed": "istio.io-mesh-controller"},
+			},
+			Status: appsv1.DeploymentStatus{ReadyReplicas: ready},
+		}
+	}
+
+	cases := []struct {
+		name    string
+		objects []runtime.Object
+		want    [][]any
+	}{
+		{
+			name:    "sidecar install",
+			objects: []runtime.Object{cni(1, 3), cniConfig("false")},
+		},
+		{
+			name:    "healthy",
+			objects: []runtime.Object{ztunnel(3, 3), cni(3, 3), cniConfig("true"), waypoint(1)},
+		},
+		{
+			name:    "rolling out",
+			objects: []runtime.Object{ztunnel(1, 3), cni(3, 2), cniConfig("true"), waypoint(0)},
+			want: [][]any{
+				{"DaemonSet", "istio-system/ztunnel", "1 of its 3 pods run its latest spec"},
+				{"DaemonSet", "istio-system/istio-cni-node", "2 of its 3 pods are ready"},
+				{"waypoint Deployment", "bookinfo/waypoint", "0 of its 1 replicas are ready"},
+			},
+		},
+		{
+			name:    "ambient disabled in istio-cni",
+			objects: []runtime.Object{ztunnel(3, 3), cni(3, 3), cniConfig("false")},
+			want: [][]any{{"ConfigMap", "istio-system/istio-cni-config", "AMBIENT_ENABLED is not \"true\", so the istio-cni node agent " +
+				"does not redirect the traffic of ambient pods to ztunnel"}},
+		},
+		{
+			name:    "istio-cni missing",
+			objects: []runtime.Object{ztunnel(3, 3)},
+			want: [][]any{{"DaemonSet", "istio-system/ztunnel", "the istio-cni node agent, which redirects the traffic of ambient pods " +
+				"to ztunnel, is not installed"}},
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			msgs, err := checkAmbient(kube.NewFakeClient(tt.objects...))
+			assert.NoError(t, err)
+			var got [][]any
+			for _, m := range msgs {
+				assert.Equal(t, msg.AmbientComponentUnhealthy, m.Type)
+				got = append(got, m.Parameters)
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,192 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package precheck
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"strings"
+
+	crd "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+
+	"istio.io/istio/manifests"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pkg/config/analysis/diag"
+	legacykube "istio.io/istio/pkg/config/analysis/legacy/source/kube"
+	"istio.io/istio/pkg/config/analysis/msg"
+	"istio.io/istio/pkg/config/resource"
+	"istio.io/istio/pkg/config/schema/gvk"
+	"istio.io/istio/pkg/kube"
+)
+
+const (
+	// crdsPath is the location of the Istio CRDs in the manifests.
+	crdsPath = "charts/base/files/crd-all.gen.yaml"
+	// chartPath is the location of the base chart in the manifests, whose appVersion is the Istio version of the manifests.
+	chartPath = "charts/base/Chart.yaml"
+)
+
+// Checks that the Istio CRDs in the cluster match the ones of the manifests, the bundled ones unless manifestsPath is
+// set, for a control plane of the version of the manifests: CRDs of a previous version are expected before an upgrade,
+// which updates them, so only the CRDs left behind after installing or upgrading the control plane are reported. To
+// check the CRDs used by a control plane of another version, manifestsPath should be the manifests of that version.
+// CRDs which are not installed are skipped, as they are created on install.
+func checkIstioCRDs(cli kube.CLIClient, istioNamespace, manifestsPath string) (diag.Messages, error) {
+	expected, manifestsVersion, err := manifestCRDs(manifestsPath)
+	if err != nil {
+		return nil, err
+	}
+	running, err := runsVersion(cli, istioNamespace, manifestsVersion)
+	if err != nil || !running {
+		return nil, err
+	}
+	msgs := diag.Messages{}
+	res, err := cli.Ext().ApiextensionsV1().CustomResourceDefinitions().List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range res.Items {
+		want, ok := expected[r.Name]
+		if !ok {
+			continue
+		}
+		problems := compareCRD(&r, want)
+		if len(problems) == 0 {
+			continue
+		}
+		origin := legacykube.Origin{
+			Type: gvk.CustomResourceDefinition,
+			FullName: resource.FullName{
+				Name: resource.LocalName(r.Name),
+			},
+			ResourceVersion: resource.Version(r.ResourceVersion),
+		}
+		msgs.Add(msg.NewOutdatedIstioCRD(&resource.Instance{Origin: &origin}, r.Name, strings.Join(problems, "; ")))
+	}
+	return msgs, nil
+}
+
+// runsVersion returns whether an istiod Deployment of the namespace has the major and minor version of the manifests.
+func runsVersion(cli kube.CLIClient, istioNamespace, manifestsVersion string) (bool, error) {
+	deployments, err := cli.Kube().AppsV1().Deployments(istioNamespace).List(context.Background(),
+		metav1.ListOptions{LabelSelector: "app=istiod"})
+	if err != nil {
+		return false, err
+	}
+	want := model.ParseIstioVersion(manifestsVersion)
+	for _, d := range deployments.Items {
+		v, ok := d.Labels["app.kubernetes.io/version"]
+		if !ok {
+			continue
+		}
+		if got := model.ParseIstioVersion(v); got.Major == want.Major && got.Minor == want.Minor {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// manifestCRDs returns the Istio CRDs of the manifests, keyed by name, and the Istio version of the manifests.
+func manifestCRDs(manifestsPath string) (map[string]*crd.CustomResourceDefinition, string, error) {
+	fsys := manifests.BuiltinOrDir(manifestsPath)
+	b, err := fs.ReadFile(fsys, chartPath)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read the base chart: %v", err)
+	}
+	chart := struct {
+		AppVersion string `json:"appVersion"`
+	}{}
+	if err := yaml.Unmarshal(b, &chart); err != nil {
+		return nil, "", fmt.Errorf("failed to parse the base chart: %v", err)
+	}
+	b, err = fs.ReadFile(fsys, crdsPath)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read CRDs: %v", err)
+	}
+	res := map[string]*crd.CustomResourceDefinition{}
+	for _, doc := range strings.Split(string(b), "\n---\n") {
+		if strings.TrimSpace(doc) == "" {
+			continue
+		}
+		c := &crd.CustomResourceDefinition{}
+		if err := yaml.Unmarshal([]byte(doc), c); err != nil {
+			return nil, "", fmt.Errorf("failed to parse CRDs: %v", err)
+		}
+		if c.Name != "" {
+			res[c.Name] = c
+		}
+	}
+	return res, chart.AppVersion, nil
+}
+
+// compareCRD returns the differences between an installed CRD and the one of the manifests
+// which may break the control plane: missing served versions, a different storage version, and schema changes.
+func compareCRD(installed, expected *crd.CustomResourceDefinition) []string {
+	var problems []string
+	installedVersions := map[string]crd.CustomResourceDefinitionVersion{}
+	for _, v := range installed.Spec.Versions {
+		installedVersions[v.Name] = v
+	}
+
+	var missing, schemaChanged []string
+	for _, v := range expected.Spec.Versions {
+		if !v.Served {
+			continue
+		}
+		iv, ok := installedVersions[v.Name]
+		if !ok || !iv.Served {
+			missing = append(missing, v.Name)
+			continue
+		}
+		if schemaHash(iv.Schema) != schemaHash(v.Schema) {
+			schemaChanged = append(schemaChanged, v.Name)
+		}
+	}
+	if len(missing) > 0 {
+		problems = append(problems, "versions not served: "+strings.Join(missing, ","))
+	}
+	if is, es := storageVersion(installed), storageVersion(expected); is != es {
+		problems = append(problems, fmt.Sprintf("storage version is %s, expected %s", is, es))
+	}
+	if len(schemaChanged) > 0 {
+		problems = append(problems, "schema differs for versions: "+strings.Join(schemaChanged, ","))
+	}
+	return problems
+}
+
+func storageVersion(r *crd.CustomResourceDefinition) string {
+	for _, v := range r.Spec.Versions {
+		if v.Storage {
+			return v.Name
+		}
+	}
+	return ""
+}
+
+func schemaHash(v *crd.CustomResourceValidation) string {
+	if v == nil {
+		return ""
+	}
+	b, err := json.Marshal(v.OpenAPIV3Schema)
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf("%x", sha256.Sum256(b))
+}
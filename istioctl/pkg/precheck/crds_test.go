@@ -0,0 +1,138 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package precheck
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	crd "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	extfake "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset/fake"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"istio.io/istio/pkg/config/analysis/msg"
+	"istio.io/istio/pkg/kube"
+	"istio.io/istio/pkg/test/env"
+)
+
+func TestCheckIstioCRDs(t *testing.T) {
+	expected, manifestsVersion, err := manifestCRDs("")
+	assert.NoError(t, err)
+	vs, ok := expected["virtualservices.networking.istio.io"]
+	assert.True(t, ok)
+
+	current := vs.DeepCopy()
+	stale := vs.DeepCopy()
+	stale.Name = "destinationrules.networking.istio.io"
+	var versions []crd.CustomResourceDefinitionVersion
+	for _, v := range stale.Spec.Versions {
+		if v.Name == "v1" {
+			continue
+		}
+		v.Storage = v.Name == "v1alpha3"
+		v.Schema = &crd.CustomResourceValidation{OpenAPIV3Schema: &crd.JSONSchemaProps{Type: "object"}}
+		versions = append(versions, v)
+	}
+	stale.Spec.Versions = versions
+	istiod := func(version string) *appsv1.Deployment {
+		return &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{
+			Name: "istiod", Namespace: "istio-system",
+			Labels: map[string]string{"app": "istiod", "app.kubernetes.io/version": version},
+		}}
+	}
+	staleMessage := []any{
+		"destinationrules.networking.istio.io",
+		"versions not served: v1; storage version is v1alpha3, expected v1beta1; schema differs for versions: v1alpha3,v1beta1",
+	}
+
+	cases := []struct {
+		name          string
+		controlPlane  []runtime.Object
+		manifestsPath string
+		want          []any
+	}{
+		{
+			name: "no control plane",
+		},
+		{
+			name:         "control plane of another version",
+			controlPlane: []runtime.Object{istiod("0.9.3")},
+		},
+		{
+			name:         "control plane of the bundled manifests version",
+			controlPlane: []runtime.Object{istiod(manifestsVersion)},
+			want:         staleMessage,
+		},
+		{
+			name:          "control plane of the selected manifests version",
+			controlPlane:  []runtime.Object{istiod(manifestsVersion)},
+			manifestsPath: filepath.Join(env.IstioSrc, "manifests"),
+			want:          staleMessage,
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			cli := kube.NewFakeClient(tt.controlPlane...)
+			for _, c := range []*crd.CustomResourceDefinition{current, stale} {
+				assert.NoError(t, cli.Ext().(*extfake.Clientset).Tracker().Add(c))
+			}
+			msgs, err := checkIstioCRDs(cli, "istio-system", tt.manifestsPath)
+			assert.NoError(t, err)
+			if tt.want == nil {
+				assert.Len(t, msgs, 0)
+				return
+			}
+			assert.Len(t, msgs, 1)
+			assert.Equal(t, msg.OutdatedIstioCRD, msgs[0].Type)
+			assert.Equal(t, tt.want, msgs[0].Parameters)
+		})
+	}
+
+	_, _, err = manifestCRDs(t.TempDir())
+	assert.Error(t, err)
+}
+
+func TestCompareCRD(t *testing.T) {
+	schema := func(t string) *crd.CustomResourceValidation {
+		return &crd.CustomResourceValidation{OpenAPIV3Schema: &crd.JSONSchemaProps{Type: t}}
+	}
+	version := func(name string, served, storage bool, s *crd.CustomResourceValidation) crd.CustomResourceDefinitionVersion {
+		return crd.CustomResourceDefinitionVersion{Name: name, Served: served, Storage: storage, Schema: s}
+	}
+	expected := &crd.CustomResourceDefinition{Spec: crd.CustomResourceDefinitionSpec{Versions: []crd.CustomResourceDefinitionVersion{
+		version("v1alpha1", false, false, schema("object")),
+		version("v1beta1", true, false, schema("object")),
+		version("v1", true, true, schema("object")),
+	}}}
+	assert.Empty(t, compareCRD(expected, expected))
+
+	// Versions which are not served by the manifests are not compared.
+	installed := expected.DeepCopy()
+	installed.Spec.Versions = installed.Spec.Versions[1:]
+	assert.Empty(t, compareCRD(installed, expected))
+
+	installed = &crd.CustomResourceDefinition{Spec: crd.CustomResourceDefinitionSpec{Versions: []crd.CustomResourceDefinitionVersion{
+		version("v1beta1", true, true, schema("string")),
+		version("v1", false, false, schema("object")),
+	}}}
+	assert.Equal(t, []string{
+		"versions not served: v1",
+		"storage version is v1beta1, expected v1",
+		"schema differs for versions: v1beta1",
+	}, compareCRD(installed, expected))
+}
@@ -0,0 +1,119 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package precheck
+
+import (
+	"context"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"istio.io/api/annotation"
+	"istio.io/api/label"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pkg/config/analysis/diag"
+	"istio.io/istio/pkg/config/analysis/msg"
+	"istio.io/istio/pkg/kube"
+)
+
+// proxyContainer is the name of the container of the proxy in the injected pods.
+const proxyContainer = "istio-proxy"
+
+// Checks that the proxies of the injected pods are at most maxSkew minor versions behind the istiod of their revision.
+// Injected pods keep the proxy they were created with until they are restarted, so after an upgrade of the control
+// plane they may run a proxy older than istiod supports.
+func checkProxyVersionSkew(cli kube.CLIClient, istioNamespace string, maxSkew int) (diag.Messages, error) {
+	ctx := context.Background()
+	deployments, err := cli.Kube().AppsV1().Deployments(istioNamespace).List(ctx, metav1.ListOptions{LabelSelector: "app=istiod"})
+	if err != nil {
+		return nil, err
+	}
+	istiodVersions := map[string]string{}
+	for _, d := range deployments.Items {
+		v, ok := d.Labels["app.kubernetes.io/version"]
+		if !ok {
+			v = imageTag(d.Spec.Template.Spec.Containers, "discovery")
+		}
+		if imageVersion(v) != nil {
+			istiodVersions[revisionOf(d.Labels)] = v
+		}
+	}
+	if len(istiodVersions) == 0 {
+		return nil, nil
+	}
+
+	pods, err := cli.Kube().CoreV1().Pods(metav1.NamespaceAll).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	msgs := diag.Messages{}
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if _, ok := pod.Annotations[annotation.SidecarStatus.Name]; !ok {
+			continue
+		}
+		revision := revisionOf(pod.Labels)
+		istiodVersion, ok := istiodVersions[revision]
+		if !ok {
+			continue
+		}
+		// Native sidecars run the proxy as an init container.
+		proxyVersion := imageTag(pod.Spec.Containers, proxyContainer)
+		if proxyVersion == "" {
+			proxyVersion = imageTag(pod.Spec.InitContainers, proxyContainer)
+		}
+		proxy, istiod := imageVersion(proxyVersion), imageVersion(istiodVersion)
+		if proxy == nil || proxy.Major != istiod.Major {
+			continue
+		}
+		if skew := istiod.Minor - proxy.Minor; skew > maxSkew {
+			msgs.Add(msg.NewProxyVersionSkew(ObjectToInstance(pod), pod.Namespace+"/"+pod.Name, proxyVersion, skew, istiodVersion, revision))
+		}
+	}
+	return msgs, nil
+}
+
+// revisionOf returns the revision of the istio.io/rev label, which the default revision does not set.
+func revisionOf(labels map[string]string) string {
+	if rev := labels[label.IoIstioRev.Name]; rev != "" {
+		return rev
+	}
+	return "default"
+}
+
+// imageTag returns the tag of the image of the named container, without its digest.
+func imageTag(containers []corev1.Container, name string) string {
+	for _, c := range containers {
+		if c.Name != name {
+			continue
+		}
+		image, _, _ := strings.Cut(c.Image, "@")
+		if i := strings.LastIndex(image, ":"); i >= 0 && !strings.Contains(image[i:], "/") {
+			return image[i+1:]
+		}
+	}
+	return ""
+}
+
+// imageVersion returns the Istio version of an image tag, such as 1.24.1-distroless, or nil if it has none, as for
+// the tags of development builds.
+func imageVersion(tag string) *model.IstioVersion {
+	v := model.ParseIstioVersion(tag)
+	if v == model.MaxIstioVersion {
+		return nil
+	}
+	return v
+}
@@ -0,0 +1,127 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package precheck
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"istio.io/api/annotation"
+	"istio.io/api/label"
+	"istio.io/istio/pkg/config/analysis/msg"
+	"istio.io/istio/pkg/kube"
+)
+
+func TestCheckProxyVersionSkew(t *testing.T) {
+	istiod := func(name, revision, image string, labels map[string]string) *appsv1.Deployment {
+		labels["app"] = "istiod"
+		if revision != "" {
+			labels[label.IoIstioRev.Name] = revision
+		}
+		return &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "istio-system", Labels: labels},
+			Spec: appsv1.DeploymentSpec{Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{
+				Containers: []corev1.Container{{Name: "discovery", Image: image}},
+			}}},
+		}
+	}
+	pod := func(name, revision, image string, native bool) *corev1.Pod {
+		p := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        name,
+				Namespace:   "default",
+				Labels:      map[string]string{},
+				Annotations: map[string]string{annotation.SidecarStatus.Name: "{}"},
+			},
+		}
+		if revision != "" {
+			p.Labels[label.IoIstioRev.Name] = revision
+		}
+		containers := []corev1.Container{{Name: proxyContainer, Image: image}}
+		if native {
+			p.Spec.InitContainers = containers
+		} else {
+			p.Spec.Containers = containers
+		}
+		return p
+	}
+	uninjected := pod("uninjected", "", "docker.io/istio/proxyv2:1.18.0", false)
+	uninjected.Annotations = nil
+
+	cases := []struct {
+		name    string
+		maxSkew int
+		objects []runtime.Object
+		want    [][]any
+	}{
+		{
+			name:    "no istiod",
+			maxSkew: 2,
+			objects: []runtime.Object{pod("app", "", "docker.io/istio/proxyv2:1.18.0", false)},
+		},
+		{
+			name:    "within skew",
+			maxSkew: 2,
+			objects: []runtime.Object{
+				istiod("istiod", "", "docker.io/istio/pilot:1.24.1", map[string]string{"app.kubernetes.io/version": "1.24.1"}),
+				pod("app", "", "docker.io/istio/proxyv2:1.22.3-distroless", false),
+				pod("dev", "", "gcr.io/istio-testing/proxyv2:latest", false),
+				uninjected,
+			},
+		},
+		{
+			name:    "beyond skew",
+			maxSkew: 1,
+			objects: []runtime.Object{
+				istiod("istiod", "", "docker.io/istio/pilot:1.24.1", map[string]string{}),
+				pod("app", "", "docker.io/istio/proxyv2:1.22.3@sha256:0123", false),
+				pod("native", "default", "registry:5000/istio/proxyv2:1.21.0", true),
+			},
+			want: [][]any{
+				{"default/app", "1.22.3", 2, "1.24.1", "default"},
+				{"default/native", "1.21.0", 3, "1.24.1", "default"},
+			},
+		},
+		{
+			name:    "per revision",
+			maxSkew: 1,
+			objects: []runtime.Object{
+				istiod("istiod", "", "docker.io/istio/pilot:1.22.0", map[string]string{}),
+				istiod("istiod-canary", "canary", "docker.io/istio/pilot:1.24.0", map[string]string{}),
+				pod("stable", "", "docker.io/istio/proxyv2:1.22.0", false),
+				pod("canary", "canary", "docker.io/istio/proxyv2:1.22.0", false),
+				pod("unknown", "other", "docker.io/istio/proxyv2:1.18.0", false),
+			},
+			want: [][]any{{"default/canary", "1.22.0", 2, "1.24.0", "canary"}},
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			msgs, err := checkProxyVersionSkew(kube.NewFakeClient(tt.objects...), "istio-system", tt.maxSkew)
+			assert.NoError(t, err)
+			var got [][]any
+			for _, m := range msgs {
+				assert.Equal(t, msg.ProxyVersionSkew, m.Type)
+				got = append(got, m.Parameters)
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,87 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package precheck
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	goversion "github.com/hashicorp/go-version"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	networking "istio.io/api/networking/v1alpha3"
+	"istio.io/istio/pkg/config/analysis/diag"
+	"istio.io/istio/pkg/config/analysis/msg"
+	"istio.io/istio/pkg/config/xds"
+	"istio.io/istio/pkg/kube"
+)
+
+// Checks the EnvoyFilters of the cluster against the proxies of this release. Filters referencing filter names or
+// typed configs the bundled Envoy no longer knows, or matching proxy versions which exclude this release, keep being
+// accepted after an upgrade but silently stop patching the proxies.
+func checkEnvoyFilters(cli kube.CLIClient, targetVersion string) (diag.Messages, error) {
+	msgs := diag.Messages{}
+	efs, err := cli.Istio().NetworkingV1alpha3().EnvoyFilters(metav1.NamespaceAll).List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list EnvoyFilters: %v", err)
+	}
+	// Development builds have no release version to match proxy versions against.
+	matchVersion := targetVersion
+	if _, err := goversion.NewVersion(targetVersion); err != nil {
+		matchVersion = ""
+	}
+	for _, ef := range efs.Items {
+		res := ObjectToInstance(ef)
+		for i, cp := range ef.Spec.ConfigPatches {
+			for _, reason := range incompatiblePatch(cp, matchVersion) {
+				msgs.Add(msg.NewEnvoyFilterIncompatible(res, i, targetVersion, reason))
+			}
+		}
+	}
+	return msgs, nil
+}
+
+// incompatiblePatch returns the reasons the config patch does not apply to proxies of the target version. Proxy
+// version matches are not checked without a target version.
+func incompatiblePatch(cp *networking.EnvoyFilter_EnvoyConfigObjectPatch, targetVersion string) []string {
+	var reasons []string
+	if cp == nil || cp.Patch == nil {
+		return nil
+	}
+	filter := cp.GetMatch().GetListener().GetFilterChain().GetFilter()
+	for _, name := range []string{filter.GetName(), filter.GetSubFilter().GetName(), cp.Patch.GetValue().GetFields()["name"].GetStringValue()} {
+		if canonical, ok := xds.ReverseDeprecatedFilterNames[name]; ok {
+			reasons = append(reasons, fmt.Sprintf("filter name %q is deprecated, use %q instead", name, canonical))
+		}
+	}
+	if cp.Patch.Value != nil {
+		if _, err := xds.BuildXDSObjectFromStruct(cp.ApplyTo, cp.Patch.Value, false); err != nil &&
+			strings.Contains(err.Error(), "could not resolve Any message type") {
+			reason := fmt.Sprintf("the patch references a type unknown to the bundled Envoy (%v)", err)
+			if strings.Contains(err.Error(), ".v2.") {
+				reason = fmt.Sprintf("the patch references a type of the removed v2 xDS API, use the v3 API instead (%v)", err)
+			}
+			reasons = append(reasons, reason)
+		}
+	}
+	if pv := cp.GetMatch().GetProxy().GetProxyVersion(); pv != "" && targetVersion != "" {
+		if re, err := regexp.Compile(pv); err == nil && !re.MatchString(targetVersion) {
+			reasons = append(reasons, fmt.Sprintf("it only applies to proxy versions matching %q, which excludes the upgraded proxies", pv))
+		}
+	}
+	return reasons
+}
@@ -0,0 +1,118 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package precheck
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/types/known/structpb"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	networking "istio.io/api/networking/v1alpha3"
+	clientnetworking "istio.io/client-go/pkg/apis/networking/v1alpha3"
+	"istio.io/istio/pkg/config/analysis/msg"
+	"istio.io/istio/pkg/config/schema/gvk"
+	"istio.io/istio/pkg/kube"
+)
+
+func TestCheckEnvoyFilters(t *testing.T) {
+	value := func(v map[string]any) *structpb.Struct {
+		s, err := structpb.NewStruct(v)
+		assert.NoError(t, err)
+		return s
+	}
+	envoyFilter := func(name string, patches ...*networking.EnvoyFilter_EnvoyConfigObjectPatch) *clientnetworking.EnvoyFilter {
+		return &clientnetworking.EnvoyFilter{
+			TypeMeta:   metav1.TypeMeta{APIVersion: gvk.EnvoyFilter.GroupVersion(), Kind: gvk.EnvoyFilter.Kind},
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "istio-system"},
+			Spec:       networking.EnvoyFilter{ConfigPatches: patches},
+		}
+	}
+	listenerMatch := func(filter string) *networking.EnvoyFilter_EnvoyConfigObjectMatch {
+		return &networking.EnvoyFilter_EnvoyConfigObjectMatch{
+			ObjectTypes: &networking.EnvoyFilter_EnvoyConfigObjectMatch_Listener{
+				Listener: &networking.EnvoyFilter_ListenerMatch{
+					FilterChain: &networking.EnvoyFilter_ListenerMatch_FilterChainMatch{
+						Filter: &networking.EnvoyFilter_ListenerMatch_FilterMatch{Name: filter},
+					},
+				},
+			},
+		}
+	}
+	lua := value(map[string]any{
+		"name": "envoy.filters.http.lua",
+		"typed_config": map[string]any{
+			"@type":      "type.googleapis.com/envoy.extensions.filters.http.lua.v3.Lua",
+			"inlineCode": "function envoy_on_request(h) end",
+		},
+	})
+	cli := kube.NewFakeClient(
+		envoyFilter("compatible", &networking.EnvoyFilter_EnvoyConfigObjectPatch{
+			ApplyTo: networking.EnvoyFilter_HTTP_FILTER,
+			Match:   listenerMatch("envoy.filters.network.http_connection_manager"),
+			Patch:   &networking.EnvoyFilter_Patch{Operation: networking.EnvoyFilter_Patch_INSERT_BEFORE, Value: lua},
+		}),
+		envoyFilter("deprecated-name", &networking.EnvoyFilter_EnvoyConfigObjectPatch{
+			ApplyTo: networking.EnvoyFilter_HTTP_FILTER,
+			Match:   listenerMatch("envoy.http_connection_manager"),
+			Patch:   &networking.EnvoyFilter_Patch{Operation: networking.EnvoyFilter_Patch_INSERT_BEFORE, Value: lua},
+		}),
+		envoyFilter("v2-type", &networking.EnvoyFilter_EnvoyConfigObjectPatch{
+			ApplyTo: networking.EnvoyFilter_HTTP_FILTER,
+			Match:   listenerMatch("envoy.filters.network.http_connection_manager"),
+			Patch: &networking.EnvoyFilter_Patch{Operation: networking.EnvoyFilter_Patch_INSERT_BEFORE, Value: value(map[string]any{
+				"name": "envoy.filters.http.ext_authz",
+				"typed_config": map[string]any{
+					"@type": "type.googleapis.com/envoy.config.filter.http.ext_authz.v2.ExtAuthz",
+				},
+			})},
+		}),
+		envoyFilter("pinned-version",
+			&networking.EnvoyFilter_EnvoyConfigObjectPatch{
+				ApplyTo: networking.EnvoyFilter_HTTP_FILTER,
+				Match: &networking.EnvoyFilter_EnvoyConfigObjectMatch{
+					Proxy: &networking.EnvoyFilter_ProxyMatch{ProxyVersion: `^1\.23.*`},
+				},
+				Patch: &networking.EnvoyFilter_Patch{Operation: networking.EnvoyFilter_Patch_INSERT_FIRST, Value: lua},
+			},
+			&networking.EnvoyFilter_EnvoyConfigObjectPatch{
+				ApplyTo: networking.EnvoyFilter_HTTP_FILTER,
+				Match: &networking.EnvoyFilter_EnvoyConfigObjectMatch{
+					Proxy: &networking.EnvoyFilter_ProxyMatch{ProxyVersion: `^1\.2[3-4].*`},
+				},
+				Patch: &networking.EnvoyFilter_Patch{Operation: networking.EnvoyFilter_Patch_INSERT_FIRST, Value: lua},
+			}),
+	)
+
+	msgs, err := checkEnvoyFilters(cli, "1.24.0")
+	assert.NoError(t, err)
+	found := map[string][]any{}
+	for _, m := range msgs {
+		assert.Equal(t, msg.EnvoyFilterIncompatible, m.Type)
+		found[m.Resource.Origin.FriendlyName()] = m.Parameters
+	}
+	assert.Len(t, found, 3)
+	assert.Equal(t, []any{0, "1.24.0", `filter name "envoy.http_connection_manager" is deprecated, ` +
+		`use "envoy.filters.network.http_connection_manager" instead`}, found["EnvoyFilter istio-system/deprecated-name"])
+	assert.Contains(t, found["EnvoyFilter istio-system/v2-type"][2], "removed v2 xDS API")
+	assert.Equal(t, []any{0, "1.24.0", `it only applies to proxy versions matching "^1\\.23.*", which excludes the upgraded proxies`},
+		found["EnvoyFilter istio-system/pinned-version"])
+
+	// Proxy versions are not matched against development builds.
+	msgs, err = checkEnvoyFilters(cli, "unknown")
+	assert.NoError(t, err)
+	assert.Len(t, msgs, 2)
+}
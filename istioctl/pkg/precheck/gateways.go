@@ -0,0 +1,56 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package precheck
+
+import (
+	"context"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"istio.io/istio/pkg/config/analysis/diag"
+	"istio.io/istio/pkg/config/analysis/msg"
+	"istio.io/istio/pkg/kube"
+)
+
+// Checks that the LoadBalancer Services of the gateways have an external address. A load balancer which cannot be
+// provisioned, for lack of quota or of a cloud controller, leaves the Service pending forever without any error.
+func checkGatewayAddresses(cli kube.CLIClient, now time.Time) (diag.Messages, error) {
+	services, err := cli.Kube().CoreV1().Services(metav1.NamespaceAll).List(context.Background(),
+		metav1.ListOptions{LabelSelector: istioServicesSelector})
+	if err != nil {
+		return nil, err
+	}
+	msgs := diag.Messages{}
+	for i := range services.Items {
+		svc := &services.Items[i]
+		if svc.Spec.Type != corev1.ServiceTypeLoadBalancer {
+			continue
+		}
+		assigned := false
+		for _, ingress := range svc.Status.LoadBalancer.Ingress {
+			if ingress.IP != "" || ingress.Hostname != "" {
+				assigned = true
+			}
+		}
+		if assigned {
+			continue
+		}
+		age := now.Sub(svc.CreationTimestamp.Time).Round(time.Second)
+		msgs.Add(msg.NewGatewayAddressPending(ObjectToInstance(svc), svc.Namespace+"/"+svc.Name, age.String()))
+	}
+	return msgs, nil
+}
@@ -0,0 +1,54 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package precheck
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"istio.io/istio/pkg/config/analysis/msg"
+	"istio.io/istio/pkg/kube"
+)
+
+func TestCheckGatewayAddresses(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	service := func(name string, serviceType corev1.ServiceType, ingress ...corev1.LoadBalancerIngress) *corev1.Service {
+		return &corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              name,
+				Namespace:         "istio-system",
+				Labels:            map[string]string{"istio": "ingressgateway"},
+				CreationTimestamp: metav1.NewTime(now.Add(-90 * time.Minute)),
+			},
+			Spec:   corev1.ServiceSpec{Type: serviceType},
+			Status: corev1.ServiceStatus{LoadBalancer: corev1.LoadBalancerStatus{Ingress: ingress}},
+		}
+	}
+	cli := kube.NewFakeClient(
+		service("istio-ingressgateway", corev1.ServiceTypeLoadBalancer, corev1.LoadBalancerIngress{IP: "203.0.113.10"}),
+		service("istio-internal-gateway", corev1.ServiceTypeLoadBalancer, corev1.LoadBalancerIngress{Hostname: "internal.example.com"}),
+		service("istio-eastwestgateway", corev1.ServiceTypeLoadBalancer),
+		service("istio-egressgateway", corev1.ServiceTypeClusterIP),
+	)
+	msgs, err := checkGatewayAddresses(cli, now)
+	assert.NoError(t, err)
+	assert.Len(t, msgs, 1)
+	assert.Equal(t, msg.GatewayAddressPending, msgs[0].Type)
+	assert.Equal(t, []any{"istio-system/istio-eastwestgateway", "1h30m0s"}, msgs[0].Parameters)
+}
@@ -0,0 +1,121 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package precheck
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"istio.io/istio/pkg/config/analysis/diag"
+	"istio.io/istio/pkg/config/analysis/msg"
+	"istio.io/istio/pkg/kube"
+	"istio.io/istio/pkg/ptr"
+	"istio.io/istio/pkg/util/sets"
+)
+
+// helmRelease is a Helm release of Istio, with the resources of the Istio components it manages.
+type helmRelease struct {
+	namespace string
+	name      string
+	resources []istioResource
+}
+
+// Checks the Helm releases installing Istio, found from the annotations Helm sets on the resources of the Istio
+// components: their last revision must be deployed, as a failed or pending one blocks the next helm upgrade, and the
+// Deployments and DaemonSets they manage must be ready.
+func checkHelmReleases(cli kube.CLIClient) (diag.Messages, error) {
+	resources, err := istioResources(cli)
+	if err != nil {
+		return nil, err
+	}
+	var releases []*helmRelease
+	byName := map[string]*helmRelease{}
+	for _, r := range resources {
+		name, ok := r.obj.GetAnnotations()[helmReleaseName]
+		if !ok {
+			continue
+		}
+		namespace := r.obj.GetAnnotations()[helmReleaseNamespace]
+		key := namespace + "/" + name
+		if byName[key] == nil {
+			byName[key] = &helmRelease{namespace: namespace, name: name}
+			releases = append(releases, byName[key])
+		}
+		byName[key].resources = append(byName[key].resources, r)
+	}
+
+	msgs := diag.Messages{}
+	for _, release := range releases {
+		res := ObjectToInstance(release.resources[0].obj)
+		fullName := release.namespace + "/" + release.name
+		components := sets.New[string]()
+		for _, r := range release.resources {
+			components.Insert(r.obj.GetLabels()[componentLabel])
+		}
+		msgs.Add(msg.NewHelmReleaseDetected(res, fullName, strings.Join(sets.SortedList(components), ", ")))
+
+		problems, err := helmReleaseProblems(cli, release)
+		if err != nil {
+			return nil, err
+		}
+		for _, problem := range problems {
+			msgs.Add(msg.NewHelmReleaseUnhealthy(res, fullName, problem))
+		}
+	}
+	return msgs, nil
+}
+
+// helmReleaseProblems returns why the Helm release cannot be upgraded, or why the components it manages do not work.
+func helmReleaseProblems(cli kube.CLIClient, release *helmRelease) ([]string, error) {
+	var problems []string
+	// Helm stores each revision of a release in a Secret labeled with its number and status.
+	secrets, err := cli.Kube().CoreV1().Secrets(release.namespace).List(context.Background(),
+		metav1.ListOptions{LabelSelector: "owner=helm,name=" + release.name})
+	if err != nil {
+		return nil, err
+	}
+	var last *corev1.Secret
+	lastVersion := 0
+	for i := range secrets.Items {
+		if v, err := strconv.Atoi(secrets.Items[i].Labels["version"]); err == nil && v > lastVersion {
+			last, lastVersion = &secrets.Items[i], v
+		}
+	}
+	if last != nil && last.Labels["status"] != "deployed" {
+		problems = append(problems, fmt.Sprintf("its last revision %d is %s, so helm upgrade may fail until it is rolled back",
+			lastVersion, last.Labels["status"]))
+	}
+
+	for _, r := range release.resources {
+		switch obj := r.obj.(type) {
+		case *appsv1.Deployment:
+			if replicas := ptr.OrDefault(obj.Spec.Replicas, 1); obj.Status.ReadyReplicas < replicas {
+				problems = append(problems, fmt.Sprintf("the Deployment %s/%s has %d of its %d replicas ready",
+					obj.Namespace, obj.Name, obj.Status.ReadyReplicas, replicas))
+			}
+		case *appsv1.DaemonSet:
+			if problem := rolloutProblem(obj); problem != "" {
+				problems = append(problems, fmt.Sprintf("the DaemonSet %s/%s is not rolled out, as %s", obj.Namespace, obj.Name, problem))
+			}
+		}
+	}
+	return problems, nil
+}
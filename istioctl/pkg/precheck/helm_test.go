@@ -0,0 +1,109 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package precheck
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"istio.io/istio/pkg/config/analysis/msg"
+	"istio.io/istio/pkg/kube"
+	"istio.io/istio/pkg/ptr"
+)
+
+func TestCheckHelmReleases(t *testing.T) {
+	meta := func(name, component, release string) metav1.ObjectMeta {
+		m := metav1.ObjectMeta{Name: name, Namespace: "istio-system", Labels: map[string]string{componentLabel: component}}
+		if release != "" {
+			m.Annotations = map[string]string{helmReleaseName: release, helmReleaseNamespace: "istio-system"}
+		}
+		return m
+	}
+	istiod := func(ready int32) *appsv1.Deployment {
+		return &appsv1.Deployment{
+			ObjectMeta: meta("istiod", "Pilot", "istiod"),
+			Spec:       appsv1.DeploymentSpec{Replicas: ptr.Of(int32(2))},
+			Status:     appsv1.DeploymentStatus{ReadyReplicas: ready},
+		}
+	}
+	cni := &appsv1.DaemonSet{
+		ObjectMeta: meta("istio-cni-node", "Cni", "istio-cni"),
+		Status:     appsv1.DaemonSetStatus{DesiredNumberScheduled: 3, UpdatedNumberScheduled: 3, NumberReady: 2},
+	}
+	revision := func(version, status string) *corev1.Secret {
+		return &corev1.Secret{ObjectMeta: metav1.ObjectMeta{
+			Name:      "sh.helm.release.v1.istiod.v" + version,
+			Namespace: "istio-system",
+			Labels:    map[string]string{"owner": "helm", "name": "istiod", "version": version, "status": status},
+		}}
+	}
+	configMap := &corev1.ConfigMap{ObjectMeta: meta("istio", "Pilot", "istiod")}
+
+	cases := []struct {
+		name      string
+		objects   []runtime.Object
+		releases  [][]any
+		unhealthy [][]any
+	}{
+		{
+			name:    "not installed by Helm",
+			objects: []runtime.Object{&appsv1.Deployment{ObjectMeta: meta("istiod", "Pilot", "")}},
+		},
+		{
+			name:     "healthy",
+			objects:  []runtime.Object{istiod(2), configMap, revision("1", "superseded"), revision("2", "deployed")},
+			releases: [][]any{{"istio-system/istiod", "Pilot"}},
+		},
+		{
+			name:     "release without revisions",
+			objects:  []runtime.Object{istiod(2)},
+			releases: [][]any{{"istio-system/istiod", "Pilot"}},
+		},
+		{
+			name:     "unhealthy",
+			objects:  []runtime.Object{istiod(1), configMap, cni, revision("9", "deployed"), revision("10", "pending-upgrade")},
+			releases: [][]any{{"istio-system/istio-cni", "Cni"}, {"istio-system/istiod", "Pilot"}},
+			unhealthy: [][]any{
+				{"istio-system/istio-cni", "the DaemonSet istio-system/istio-cni-node is not rolled out, as 2 of its 3 pods are ready"},
+				{"istio-system/istiod", "its last revision 10 is pending-upgrade, so helm upgrade may fail until it is rolled back"},
+				{"istio-system/istiod", "the Deployment istio-system/istiod has 1 of its 2 replicas ready"},
+			},
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			msgs, err := checkHelmReleases(kube.NewFakeClient(tt.objects...))
+			assert.NoError(t, err)
+			var releases, unhealthy [][]any
+			for _, m := range msgs.SortedDedupedCopy() {
+				switch m.Type {
+				case msg.HelmReleaseDetected:
+					releases = append(releases, m.Parameters)
+				case msg.HelmReleaseUnhealthy:
+					unhealthy = append(unhealthy, m.Parameters)
+				default:
+					t.Errorf("unexpected message %v", m)
+				}
+			}
+			assert.Equal(t, tt.releases, releases)
+			assert.Equal(t, tt.unhealthy, unhealthy)
+		})
+	}
+}
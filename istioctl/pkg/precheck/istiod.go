@@ -0,0 +1,420 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package precheck
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	klabels "k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"istio.io/istio/pilot/pkg/leaderelection/k8sleaderelection/k8sresourcelock"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/analysis/diag"
+	legacykube "istio.io/istio/pkg/config/analysis/legacy/source/kube"
+	"istio.io/istio/pkg/config/analysis/msg"
+	"istio.io/istio/pkg/config/resource"
+	"istio.io/istio/pkg/kube"
+	"istio.io/istio/pkg/ptr"
+	"istio.io/istio/pkg/util/sets"
+)
+
+// istiodReadinessPort serves the readiness endpoint of istiod, which is ready once its xDS server serves proxies and
+// its sidecar injector and config validation webhooks are ready.
+const istiodReadinessPort = 8080
+
+// Checks the health of the istiod replicas beyond the Available condition of their Deployment: every replica must be
+// ready, and report it now on its readiness endpoint rather than at its last readiness probe, the replicas must not all
+// run in the same node or zone when they could be spread, the leader election locks must be held by running istiod
+// pods which renew them, and PodDisruptionBudgets must allow evicting a replica, or node drains block.
+func checkIstiodHealth(cli kube.CLIClient, istioNamespace string, now time.Time) (diag.Messages, error) {
+	ctx := context.Background()
+	msgs := diag.Messages{}
+	deployments, err := cli.Kube().AppsV1().Deployments(istioNamespace).List(ctx, metav1.ListOptions{LabelSelector: "app=istiod"})
+	if err != nil {
+		return nil, err
+	}
+	if len(deployments.Items) == 0 {
+		return msgs, nil
+	}
+	pods, err := cli.Kube().CoreV1().Pods(istioNamespace).List(ctx, metav1.ListOptions{LabelSelector: "app=istiod"})
+	if err != nil {
+		return nil, err
+	}
+	pdbs, err := cli.Kube().PolicyV1().PodDisruptionBudgets(istioNamespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	hpas, err := cli.Kube().AutoscalingV2().HorizontalPodAutoscalers(istioNamespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	// Nodes are cluster scoped, and may not be readable by users which can read the Istio namespace.
+	nodes, err := cli.Kube().CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		msgs.Add(msg.NewIstiodSpreadUnverified(ObjectToInstance(&deployments.Items[0]), err.Error()))
+		nodes = nil
+	}
+
+	for i := range deployments.Items {
+		d := &deployments.Items[i]
+		selector, err := metav1.LabelSelectorAsSelector(d.Spec.Selector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid selector of deployment %s: %v", d.Name, err)
+		}
+		replicas := int(ptr.OrDefault(d.Spec.Replicas, 1))
+		var notReady, readyNodes []string
+		for j := range pods.Items {
+			p := &pods.Items[j]
+			if p.DeletionTimestamp != nil || !selector.Matches(klabels.Set(p.Labels)) {
+				continue
+			}
+			reason := podNotReadyReason(p)
+			if reason == "" {
+				reason = probeIstiodReadiness(ctx, cli, p)
+			}
+			if reason != "" {
+				notReady = append(notReady, fmt.Sprintf("%s (%s)", p.Name, reason))
+			} else if p.Spec.NodeName != "" {
+				readyNodes = append(readyNodes, p.Spec.NodeName)
+			}
+		}
+		if len(notReady) > 0 {
+			msgs.Add(msg.NewIstiodReplicasNotReady(ObjectToInstance(d), len(notReady), replicas, strings.Join(notReady, ", ")))
+		}
+		if nodes != nil {
+			if m := checkSpread(d, readyNodes, nodes.Items); m != nil {
+				msgs.Add(*m)
+			}
+		}
+
+		// With autoscaling, the deployment may scale down to the minimum replicas of the autoscaler.
+		for _, hpa := range hpas.Items {
+			if hpa.Spec.ScaleTargetRef.Kind == "Deployment" && hpa.Spec.ScaleTargetRef.Name == d.Name {
+				replicas = int(ptr.OrDefault(hpa.Spec.MinReplicas, 1))
+			}
+		}
+		for j := range pdbs.Items {
+			pdb := &pdbs.Items[j]
+			pdbSelector, err := metav1.LabelSelectorAsSelector(pdb.Spec.Selector)
+			if err != nil || pdbSelector.Empty() || !pdbSelector.Matches(klabels.Set(d.Spec.Template.Labels)) {
+				continue
+			}
+			required, blocked := drainBlocked(pdb.Spec.MinAvailable, pdb.Spec.MaxUnavailable, replicas)
+			switch {
+			case !blocked:
+			case replicas == 1 && defaultBudget(pdb):
+				// The default install runs a single replica with the default budget, which is only worth a notice.
+				msgs.Add(msg.NewIstiodSingleReplicaDrainBlocked(pdbToInstance(pdb), d.Name))
+			default:
+				msgs.Add(msg.NewPodDisruptionBudgetBlocksDrain(pdbToInstance(pdb), strconv.Itoa(required), d.Name, replicas))
+			}
+		}
+	}
+
+	running := sets.New[string]()
+	for _, p := range pods.Items {
+		if p.Status.Phase == corev1.PodRunning && p.DeletionTimestamp == nil {
+			running.Insert(p.Name)
+		}
+	}
+	leaderMsgs, err := checkLeaderElection(cli, istioNamespace, running, now)
+	if err != nil {
+		return nil, err
+	}
+	return append(msgs, leaderMsgs...), nil
+}
+
+// probeIstiodReadiness returns why the readiness endpoint of the istiod pod, reached through a port forward, does not
+// report it ready.
+func probeIstiodReadiness(ctx context.Context, cli kube.CLIClient, p *corev1.Pod) string {
+	if _, err := cli.EnvoyDoWithPort(ctx, p.Name, p.Namespace, "GET", "ready", istiodReadinessPort); err != nil {
+		return fmt.Sprintf("its readiness endpoint fails: %v", err)
+	}
+	return ""
+}
+
+// istioServicesSelector selects the Services of istiod and of the gateways, whether installed by the operator or with
+// the Helm charts, which all have the "istio" label.
+const istioServicesSelector = "istio"
+
+// Checks that the Services of istiod and of the gateways have ready endpoints. A Service whose selector matches no
+// ready pod, such as one selecting a revision which is not installed, exists without serving anything.
+func checkServiceEndpoints(cli kube.CLIClient) (diag.Messages, error) {
+	ctx := context.Background()
+	msgs := diag.Messages{}
+	services, err := cli.Kube().CoreV1().Services(metav1.NamespaceAll).List(ctx, metav1.ListOptions{LabelSelector: istioServicesSelector})
+	if err != nil {
+		return nil, err
+	}
+	for i := range services.Items {
+		svc := &services.Items[i]
+		// Services without a selector have their endpoints managed by something else, such as the remote istiod ones.
+		if len(svc.Spec.Selector) == 0 || svc.Spec.Type == corev1.ServiceTypeExternalName {
+			continue
+		}
+		slices, err := cli.Kube().DiscoveryV1().EndpointSlices(svc.Namespace).List(ctx,
+			metav1.ListOptions{LabelSelector: discoveryv1.LabelServiceName + "=" + svc.Name})
+		if err != nil {
+			return nil, err
+		}
+		if readyEndpoints(slices.Items) > 0 {
+			continue
+		}
+		selector := klabels.SelectorFromSet(svc.Spec.Selector)
+		pods, err := cli.Kube().CoreV1().Pods(svc.Namespace).List(ctx, metav1.ListOptions{LabelSelector: selector.String()})
+		if err != nil {
+			return nil, err
+		}
+		reason := fmt.Sprintf("its selector %s matches no pod", selector)
+		if len(pods.Items) > 0 {
+			reason = fmt.Sprintf("none of the %d pods its selector %s matches is ready", len(pods.Items), selector)
+		}
+		msgs.Add(msg.NewServiceNoReadyEndpoints(ObjectToInstance(svc), svc.Namespace+"/"+svc.Name, reason))
+	}
+	return msgs, nil
+}
+
+// readyEndpoints returns the number of ready endpoints of the EndpointSlices. Endpoints without a ready condition are
+// ready, as per the EndpointSlice API.
+func readyEndpoints(slices []discoveryv1.EndpointSlice) int {
+	ready := 0
+	for _, slice := range slices {
+		for _, ep := range slice.Endpoints {
+			if ptr.OrDefault(ep.Conditions.Ready, true) {
+				ready++
+			}
+		}
+	}
+	return ready
+}
+
+// spreadTopologies are the topologies the replicas of istiod are checked to be spread across, from the narrowest.
+var spreadTopologies = []struct {
+	name string
+	key  string
+}{
+	{"node", corev1.LabelHostname},
+	{"zone", corev1.LabelTopologyZone},
+}
+
+// checkSpread returns a message if the ready replicas of the deployment, running on the given nodes, all run in the same
+// node or zone, while the nodes they may be scheduled on span several. Only the narrowest topology is reported.
+func checkSpread(d *appsv1.Deployment, readyNodes []string, nodes []corev1.Node) *diag.Message {
+	if len(readyNodes) < 2 {
+		return nil
+	}
+	byName := map[string]*corev1.Node{}
+	for i := range nodes {
+		byName[nodes[i].Name] = &nodes[i]
+	}
+	for _, topology := range spreadTopologies {
+		domainOf := func(node *corev1.Node) string {
+			if topology.key == corev1.LabelHostname {
+				return node.Name
+			}
+			return node.Labels[topology.key]
+		}
+		used := sets.New[string]()
+		for _, name := range readyNodes {
+			if node, ok := byName[name]; ok && domainOf(node) != "" {
+				used.Insert(domainOf(node))
+			}
+		}
+		available := sets.New[string]()
+		for i := range nodes {
+			node := &nodes[i]
+			if !node.Spec.Unschedulable && domainOf(node) != "" && schedulesOn(&d.Spec.Template.Spec, node) {
+				available.Insert(domainOf(node))
+			}
+		}
+		if used.Len() != 1 || available.Len() < 2 {
+			continue
+		}
+		reason := fmt.Sprintf("as its pods declare no anti-affinity or topology spread constraint on %s "+
+			"(set with values.pilot.affinity or values.pilot.topologySpreadConstraints)", topology.key)
+		if spreadRequested(&d.Spec.Template.Spec, topology.key) {
+			reason = fmt.Sprintf("although its pods declare anti-affinity or a topology spread constraint on %s", topology.key)
+		}
+		m := msg.NewIstiodReplicasNotSpread(ObjectToInstance(d), len(readyNodes), d.Name, topology.name, sets.SortedList(used)[0],
+			available.Len(), reason)
+		return &m
+	}
+	return nil
+}
+
+// spreadRequested returns whether the pod anti-affinity or the topology spread constraints of the pod spec spread its
+// pods across the topology.
+func spreadRequested(spec *corev1.PodSpec, key string) bool {
+	for _, c := range spec.TopologySpreadConstraints {
+		if c.TopologyKey == key {
+			return true
+		}
+	}
+	if spec.Affinity == nil || spec.Affinity.PodAntiAffinity == nil {
+		return false
+	}
+	for _, term := range spec.Affinity.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution {
+		if term.TopologyKey == key {
+			return true
+		}
+	}
+	for _, term := range spec.Affinity.PodAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution {
+		if term.PodAffinityTerm.TopologyKey == key {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	hpaKind = config.GroupVersionKind{Group: "autoscaling", Version: "v2", Kind: "HorizontalPodAutoscaler"}
+	pdbKind = config.GroupVersionKind{Group: "policy", Version: "v1", Kind: "PodDisruptionBudget"}
+)
+
+// pdbToInstance is ObjectToInstance for PodDisruptionBudgets, whose kind is not part of the Istio schemas.
+func pdbToInstance(pdb *policyv1.PodDisruptionBudget) *resource.Instance {
+	return kindToInstance(pdbKind, pdb)
+}
+
+// kindToInstance is ObjectToInstance for the kinds which are not part of the Istio schemas.
+func kindToInstance(kind config.GroupVersionKind, obj metav1.Object) *resource.Instance {
+	return &resource.Instance{
+		Origin: &legacykube.Origin{
+			Type: kind,
+			FullName: resource.FullName{
+				Namespace: resource.Namespace(obj.GetNamespace()),
+				Name:      resource.LocalName(obj.GetName()),
+			},
+			ResourceVersion: resource.Version(obj.GetResourceVersion()),
+		},
+	}
+}
+
+// defaultBudget returns whether the PodDisruptionBudget is the default one of the istiod chart, requiring one available
+// replica.
+func defaultBudget(pdb *policyv1.PodDisruptionBudget) bool {
+	return pdb.Labels["app"] == "istiod" && pdb.Labels["operator.istio.io/component"] == "Pilot" &&
+		pdb.Spec.MaxUnavailable == nil && pdb.Spec.MinAvailable != nil && *pdb.Spec.MinAvailable == intstr.FromInt32(1)
+}
+
+// podNotReadyReason returns why the pod is not ready, or an empty string if it is.
+func podNotReadyReason(p *corev1.Pod) string {
+	if p.Status.Phase != corev1.PodRunning {
+		return string(p.Status.Phase)
+	}
+	for _, c := range p.Status.ContainerStatuses {
+		if c.State.Waiting != nil {
+			return c.State.Waiting.Reason
+		}
+	}
+	for _, c := range p.Status.Conditions {
+		if c.Type == corev1.PodReady && c.Status != corev1.ConditionTrue {
+			return "readiness probe failing"
+		}
+	}
+	return ""
+}
+
+// drainBlocked returns the number of replicas the budget requires available, and whether this leaves no replica to
+// evict.
+func drainBlocked(minAvailable, maxUnavailable *intstr.IntOrString, replicas int) (int, bool) {
+	switch {
+	case maxUnavailable != nil:
+		allowed, err := intstr.GetScaledValueFromIntOrPercent(maxUnavailable, replicas, true)
+		if err != nil {
+			return 0, false
+		}
+		return replicas - allowed, allowed <= 0
+	case minAvailable != nil:
+		required, err := intstr.GetScaledValueFromIntOrPercent(minAvailable, replicas, true)
+		if err != nil {
+			return 0, false
+		}
+		return required, required >= replicas
+	}
+	return 0, false
+}
+
+// leaseExpiryFactor is how many lease durations a lock may go without renewal before its holder is considered stuck,
+// allowing for clock skew between the cluster and the client.
+const leaseExpiryFactor = 2
+
+// checkLeaderElection checks that the leader election locks of istiod, kept in ConfigMaps and Leases of the Istio
+// namespace, are held by running istiod pods which renew them.
+func checkLeaderElection(cli kube.CLIClient, istioNamespace string, running sets.String, now time.Time) (diag.Messages, error) {
+	ctx := context.Background()
+	msgs := diag.Messages{}
+	check := func(res *resource.Instance, record k8sresourcelock.LeaderElectionRecord) {
+		// Released locks have no holder, and locks of remote istiods are held by pods of another cluster.
+		if record.HolderIdentity == "" || strings.HasPrefix(record.HolderKey, "^") {
+			return
+		}
+		switch {
+		case !running.Contains(record.HolderIdentity):
+			msgs.Add(msg.NewLeaderElectionHolderUnhealthy(res, record.HolderIdentity, "is not a running istiod pod"))
+		case record.LeaseDurationSeconds > 0 && !record.RenewTime.IsZero() &&
+			now.Sub(record.RenewTime.Time) > leaseExpiryFactor*time.Duration(record.LeaseDurationSeconds)*time.Second:
+			msgs.Add(msg.NewLeaderElectionHolderUnhealthy(res, record.HolderIdentity,
+				fmt.Sprintf("has not renewed it for %v", now.Sub(record.RenewTime.Time).Round(time.Second))))
+		}
+	}
+
+	configMaps, err := cli.Kube().CoreV1().ConfigMaps(istioNamespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for i := range configMaps.Items {
+		cm := &configMaps.Items[i]
+		annotation, ok := cm.Annotations[k8sresourcelock.LeaderElectionRecordAnnotationKey]
+		if !ok || !strings.HasPrefix(cm.Name, "istio-") {
+			continue
+		}
+		var record k8sresourcelock.LeaderElectionRecord
+		if err := json.Unmarshal([]byte(annotation), &record); err != nil {
+			continue
+		}
+		check(ObjectToInstance(cm), record)
+	}
+	leases, err := cli.Kube().CoordinationV1().Leases(istioNamespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for i := range leases.Items {
+		lease := &leases.Items[i]
+		if !strings.HasPrefix(lease.Name, "istio-") {
+			continue
+		}
+		record := k8sresourcelock.LeaderElectionRecord{
+			HolderIdentity:       ptr.OrEmpty(lease.Spec.HolderIdentity),
+			LeaseDurationSeconds: int(ptr.OrEmpty(lease.Spec.LeaseDurationSeconds)),
+		}
+		if lease.Spec.RenewTime != nil {
+			record.RenewTime = metav1.Time{Time: lease.Spec.RenewTime.Time}
+		}
+		check(ObjectToInstance(lease), record)
+	}
+	return msgs, nil
+}
@@ -0,0 +1,325 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package precheck
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"istio.io/istio/istioctl/pkg/cli"
+	"istio.io/istio/pkg/config/analysis/diag"
+	"istio.io/istio/pkg/config/analysis/msg"
+	"istio.io/istio/pkg/kube"
+	"istio.io/istio/pkg/ptr"
+	"istio.io/istio/pkg/slices"
+)
+
+func TestCheckIstiodHealth(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	labels := map[string]string{"app": "istiod"}
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "istiod", Namespace: "istio-system", Labels: labels},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: ptr.Of(int32(2)),
+			Selector: &metav1.LabelSelector{MatchLabels: labels},
+			Template: corev1.PodTemplateSpec{ObjectMeta: metav1.ObjectMeta{Labels: labels}},
+		},
+	}
+	pod := func(name string, ready bool) *corev1.Pod {
+		status := corev1.ConditionTrue
+		if !ready {
+			status = corev1.ConditionFalse
+		}
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "istio-system", Labels: labels},
+			Status: corev1.PodStatus{
+				Phase:      corev1.PodRunning,
+				Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: status}},
+			},
+		}
+	}
+	pdb := func(minAvailable intstr.IntOrString) *policyv1.PodDisruptionBudget {
+		return &policyv1.PodDisruptionBudget{
+			ObjectMeta: metav1.ObjectMeta{Name: "istiod", Namespace: "istio-system"},
+			Spec: policyv1.PodDisruptionBudgetSpec{
+				MinAvailable: &minAvailable,
+				Selector:     &metav1.LabelSelector{MatchLabels: labels},
+			},
+		}
+	}
+	// The istiod Deployment and PodDisruptionBudget of the default install, scaled between 1 and 5 replicas.
+	singleReplica := deployment.DeepCopy()
+	singleReplica.Spec.Replicas = ptr.Of(int32(1))
+	defaultPDB := pdb(intstr.FromInt32(1))
+	defaultPDB.Labels = map[string]string{"app": "istiod", "operator.istio.io/component": "Pilot", "istio": "pilot"}
+	hpa := &autoscalingv2.HorizontalPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Name: "istiod", Namespace: "istio-system"},
+		Spec: autoscalingv2.HorizontalPodAutoscalerSpec{
+			ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{Kind: "Deployment", Name: "istiod"},
+			MinReplicas:    ptr.Of(int32(1)),
+			MaxReplicas:    5,
+		},
+	}
+	leaderConfigMap := func(holder string, renewed time.Time) *corev1.ConfigMap {
+		return &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{
+			Name:      "istio-leader",
+			Namespace: "istio-system",
+			Annotations: map[string]string{"control-plane.alpha.kubernetes.io/leader": `{"holderIdentity":"` + holder +
+				`","holderKey":"default","leaseDurationSeconds":30,"renewTime":"` + renewed.Format(time.RFC3339) + `"}`},
+		}}
+	}
+	onNode := func(p *corev1.Pod, node string) *corev1.Pod {
+		p.Spec.NodeName = node
+		return p
+	}
+	node := func(name, zone string) *corev1.Node {
+		return &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{corev1.LabelTopologyZone: zone}}}
+	}
+	nodes := []runtime.Object{node("node-a", "zone-1"), node("node-b", "zone-1"), node("node-c", "zone-2")}
+	zoneSpread := deployment.DeepCopy()
+	zoneSpread.Spec.Template.Spec.TopologySpreadConstraints = []corev1.TopologySpreadConstraint{{
+		MaxSkew:           1,
+		TopologyKey:       corev1.LabelTopologyZone,
+		WhenUnsatisfiable: corev1.ScheduleAnyway,
+		LabelSelector:     &metav1.LabelSelector{MatchLabels: labels},
+	}}
+	lease := &coordinationv1.Lease{
+		ObjectMeta: metav1.ObjectMeta{Name: "istio-gateway-deployment-default", Namespace: "istio-system"},
+		Spec: coordinationv1.LeaseSpec{
+			HolderIdentity:       ptr.Of("istiod-b"),
+			LeaseDurationSeconds: ptr.Of(int32(30)),
+			RenewTime:            &metav1.MicroTime{Time: now.Add(-10 * time.Minute)},
+		},
+	}
+
+	cases := []struct {
+		name    string
+		objects []runtime.Object
+		want    map[*diag.MessageType][]any
+		// unready are the pods whose readiness endpoint fails.
+		unready []string
+		// noWarnings is set for cases which must not fail the precheck.
+		noWarnings bool
+	}{
+		{
+			name: "healthy",
+			objects: []runtime.Object{
+				deployment, pod("istiod-a", true), pod("istiod-b", true),
+				pdb(intstr.FromInt32(1)), leaderConfigMap("istiod-a", now.Add(-5*time.Second)),
+			},
+			want:       map[*diag.MessageType][]any{},
+			noWarnings: true,
+		},
+		{
+			name: "replicas spread across nodes and zones",
+			objects: append([]runtime.Object{
+				zoneSpread, onNode(pod("istiod-a", true), "node-a"), onNode(pod("istiod-b", true), "node-c"),
+			}, nodes...),
+			want:       map[*diag.MessageType][]any{},
+			noWarnings: true,
+		},
+		{
+			name: "replicas on the same node",
+			objects: append([]runtime.Object{
+				deployment, onNode(pod("istiod-a", true), "node-a"), onNode(pod("istiod-b", true), "node-a"),
+			}, nodes...),
+			want: map[*diag.MessageType][]any{
+				msg.IstiodReplicasNotSpread: {2, "istiod", "node", "node-a", 3, "as its pods declare no anti-affinity or topology " +
+					"spread constraint on kubernetes.io/hostname (set with values.pilot.affinity or values.pilot.topologySpreadConstraints)"},
+			},
+		},
+		{
+			name: "replicas in the same zone despite the spread constraint",
+			objects: append([]runtime.Object{
+				zoneSpread, onNode(pod("istiod-a", true), "node-a"), onNode(pod("istiod-b", true), "node-b"),
+			}, nodes...),
+			want: map[*diag.MessageType][]any{
+				msg.IstiodReplicasNotSpread: {2, "istiod", "zone", "zone-1", 2,
+					"although its pods declare anti-affinity or a topology spread constraint on topology.kubernetes.io/zone"},
+			},
+		},
+		{
+			name: "default install",
+			objects: []runtime.Object{
+				singleReplica, pod("istiod-a", true), defaultPDB, hpa, leaderConfigMap("istiod-a", now.Add(-5*time.Second)),
+			},
+			want: map[*diag.MessageType][]any{
+				msg.IstiodSingleReplicaDrainBlocked: {"istiod"},
+			},
+			noWarnings: true,
+		},
+		{
+			name: "budget requiring every replica",
+			objects: []runtime.Object{
+				deployment, pod("istiod-a", true), pod("istiod-b", true), pdb(intstr.FromInt32(2)),
+			},
+			want: map[*diag.MessageType][]any{
+				msg.PodDisruptionBudgetBlocksDrain: {"2", "istiod", 2},
+			},
+		},
+		{
+			name: "replica not ready and dead leader",
+			objects: []runtime.Object{
+				deployment, pod("istiod-a", true), pod("istiod-b", false), leaderConfigMap("istiod-c", now.Add(-5*time.Second)),
+			},
+			want: map[*diag.MessageType][]any{
+				msg.IstiodReplicasNotReady:        {1, 2, "istiod-b (readiness probe failing)"},
+				msg.LeaderElectionHolderUnhealthy: {"istiod-c", "is not a running istiod pod"},
+			},
+		},
+		{
+			name: "readiness endpoint failing",
+			objects: []runtime.Object{
+				deployment, pod("istiod-a", true), pod("istiod-b", true), leaderConfigMap("istiod-a", now.Add(-5*time.Second)),
+			},
+			unready: []string{"istiod-b"},
+			want: map[*diag.MessageType][]any{
+				msg.IstiodReplicasNotReady: {1, 2, `istiod-b (its readiness endpoint fails: unable to retrieve Pod: pods "istiod-b" not found)`},
+			},
+		},
+		{
+			name: "stale lease",
+			objects: []runtime.Object{
+				deployment, pod("istiod-a", true), pod("istiod-b", true), lease,
+			},
+			want: map[*diag.MessageType][]any{
+				msg.LeaderElectionHolderUnhealthy: {"istiod-b", "has not renewed it for 10m0s"},
+			},
+		},
+		{
+			name: "budget blocks drains at the autoscaler minimum",
+			objects: []runtime.Object{
+				deployment, pod("istiod-a", true), pod("istiod-b", true), pdb(intstr.FromString("50%")), hpa,
+			},
+			want: map[*diag.MessageType][]any{
+				msg.PodDisruptionBudgetBlocksDrain: {"1", "istiod", 1},
+			},
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			// The readiness endpoints of the pods answer, unless they are unready.
+			client := cli.MockClient{CLIClient: kube.NewFakeClient(tt.objects...), Results: map[string][]byte{}}
+			for _, o := range tt.objects {
+				if p, ok := o.(*corev1.Pod); ok && !slices.Contains(tt.unready, p.Name) {
+					client.Results[p.Name] = nil
+				}
+			}
+			msgs, err := checkIstiodHealth(client, "istio-system", now)
+			assert.NoError(t, err)
+			got := map[*diag.MessageType][]any{}
+			for _, m := range msgs {
+				got[m.Type] = m.Parameters
+				if tt.noWarnings {
+					assert.False(t, m.Type.Level().IsWorseThanOrEqualTo(diag.Warning), "unexpected message %v", m)
+				}
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestCheckIstiodHealthNodesForbidden(t *testing.T) {
+	labels := map[string]string{"app": "istiod"}
+	client := kube.NewFakeClient(&appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "istiod", Namespace: "istio-system", Labels: labels},
+		Spec:       appsv1.DeploymentSpec{Selector: &metav1.LabelSelector{MatchLabels: labels}},
+	})
+	client.Kube().(*kubefake.Clientset).PrependReactor("list", "nodes", func(k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, kerrors.NewForbidden(corev1.Resource("nodes"), "", errors.New("no RBAC"))
+	})
+	msgs, err := checkIstiodHealth(client, "istio-system", time.Now())
+	assert.NoError(t, err)
+	assert.Len(t, msgs, 1)
+	assert.Equal(t, msg.IstiodSpreadUnverified, msgs[0].Type)
+	assert.Equal(t, diag.Info, msgs[0].Type.Level())
+}
+
+func TestCheckServiceEndpoints(t *testing.T) {
+	service := func(name string, selector map[string]string) *corev1.Service {
+		return &corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "istio-system", Labels: map[string]string{"istio": "pilot"}},
+			Spec:       corev1.ServiceSpec{Selector: selector},
+		}
+	}
+	slice := func(service string, ready bool) *discoveryv1.EndpointSlice {
+		return &discoveryv1.EndpointSlice{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      service + "-abcde",
+				Namespace: "istio-system",
+				Labels:    map[string]string{discoveryv1.LabelServiceName: service},
+			},
+			Endpoints: []discoveryv1.Endpoint{{Addresses: []string{"10.0.0.1"}, Conditions: discoveryv1.EndpointConditions{Ready: ptr.Of(ready)}}},
+		}
+	}
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+		Name:      "istiod-canary-a",
+		Namespace: "istio-system",
+		Labels:    map[string]string{"istio.io/rev": "canary"},
+	}}
+
+	cases := []struct {
+		name    string
+		objects []runtime.Object
+		want    []any
+	}{
+		{
+			name:    "ready endpoints",
+			objects: []runtime.Object{service("istiod", map[string]string{"istio.io/rev": "default"}), slice("istiod", true)},
+		},
+		{
+			name:    "endpoints managed elsewhere",
+			objects: []runtime.Object{service("istiod-remote", nil)},
+		},
+		{
+			name:    "selector matching no pod",
+			objects: []runtime.Object{service("istiod", map[string]string{"istio.io/rev": "stable"}), pod},
+			want:    []any{"istio-system/istiod", "its selector istio.io/rev=stable matches no pod"},
+		},
+		{
+			name:    "pods not ready",
+			objects: []runtime.Object{service("istiod-canary", map[string]string{"istio.io/rev": "canary"}), slice("istiod-canary", false), pod},
+			want:    []any{"istio-system/istiod-canary", "none of the 1 pods its selector istio.io/rev=canary matches is ready"},
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			msgs, err := checkServiceEndpoints(kube.NewFakeClient(tt.objects...))
+			assert.NoError(t, err)
+			if tt.want == nil {
+				assert.Empty(t, msgs)
+				return
+			}
+			assert.Len(t, msgs, 1)
+			assert.Equal(t, msg.ServiceNoReadyEndpoints, msgs[0].Type)
+			assert.Equal(t, tt.want, msgs[0].Parameters)
+		})
+	}
+}
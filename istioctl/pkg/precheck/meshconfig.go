@@ -0,0 +1,189 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package precheck
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/go-multierror"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	meshconfig "istio.io/api/mesh/v1alpha1"
+	"istio.io/istio/istioctl/pkg/util"
+	"istio.io/istio/pkg/config/analysis/diag"
+	"istio.io/istio/pkg/config/analysis/msg"
+	"istio.io/istio/pkg/config/mesh"
+	"istio.io/istio/pkg/config/validation/agent"
+	"istio.io/istio/pkg/kube"
+	"istio.io/istio/pkg/util/protomarshal"
+)
+
+// Checks the mesh configs of the control plane, in the "istio" and "istio-<revision>" ConfigMaps, as istiod is lenient
+// when loading them: it ignores unknown fields, only logs invalid extension providers and validation warnings, and
+// keeps its previous mesh config, or the default one, when the mesh config is invalid. Deprecated fields are reported
+// as well, as they may be ignored or removed in a later release, and so are the extension providers using a Kubernetes
+// Service which does not exist or lacks their port, which istiod accepts.
+func checkMeshConfig(cli kube.CLIClient, istioNamespace string) (diag.Messages, error) {
+	cms, err := cli.Kube().CoreV1().ConfigMaps(istioNamespace).List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	msgs := diag.Messages{}
+	for i := range cms.Items {
+		cm := &cms.Items[i]
+		yml, ok := cm.Data[util.ConfigMapKey]
+		if !ok || (cm.Name != util.DefaultMeshConfigMapName && !strings.HasPrefix(cm.Name, util.DefaultMeshConfigMapName+"-")) {
+			continue
+		}
+		res := ObjectToInstance(cm)
+		invalid := func(problem, consequence string) {
+			msgs.Add(msg.NewMeshConfigInvalid(res, cm.Name, problem, consequence))
+		}
+
+		if err := protomarshal.ApplyYAMLStrict(yml, &meshconfig.MeshConfig{}); err != nil {
+			invalid(err.Error(), "istiod ignores unknown fields")
+		}
+		set := &meshconfig.MeshConfig{}
+		if err := protomarshal.ApplyYAML(yml, set); err == nil {
+			for _, field := range deprecatedFields(set.ProtoReflect(), "") {
+				msgs.Add(msg.NewMeshConfigDeprecatedField(res, cm.Name, field))
+			}
+		}
+
+		cfg, err := mesh.ApplyMeshConfigDefaults(yml)
+		if err != nil {
+			for _, problem := range errorList(err) {
+				invalid(problem, "istiod rejects it, and keeps its previous mesh config or the default one")
+			}
+			continue
+		}
+		warnings, _ := agent.ValidateMeshConfig(cfg)
+		for _, problem := range errorList(warnings) {
+			invalid(problem, "istiod only logs a warning")
+		}
+		for _, problem := range errorList(agent.ValidateExtensionProviders(cfg)) {
+			invalid(problem, "istiod only logs a warning, and the resources using the extension provider are not applied")
+		}
+		for _, p := range cfg.ExtensionProviders {
+			problem, err := extensionProviderProblem(cli, p)
+			if err != nil {
+				return nil, err
+			}
+			if problem != "" {
+				msgs.Add(msg.NewExtensionProviderUnresolved(res, p.Name, cm.Name, providerService(p).GetService(), problem))
+			}
+		}
+	}
+	return msgs, nil
+}
+
+// serviceProvider is implemented by the extension providers sending to a service, such as tracing collectors,
+// external authorizers and access log services.
+type serviceProvider interface {
+	GetService() string
+	GetPort() uint32
+}
+
+// providerService returns the configuration of the extension provider, if it sends to a service.
+func providerService(p *meshconfig.MeshConfig_ExtensionProvider) serviceProvider {
+	m := p.ProtoReflect()
+	fd := m.WhichOneof(m.Descriptor().Oneofs().ByName("provider"))
+	if fd == nil || fd.Message() == nil {
+		return nil
+	}
+	sp, _ := m.Get(fd).Message().Interface().(serviceProvider)
+	return sp
+}
+
+// extensionProviderProblem returns why the Kubernetes Service of the extension provider cannot be reached, if it does
+// not exist or lacks the port of the provider. The services which are not the hostname of a Kubernetes Service, in the
+// form of <name>.<namespace>.svc.<domain>, may be ServiceEntries and are not checked.
+func extensionProviderProblem(cli kube.CLIClient, p *meshconfig.MeshConfig_ExtensionProvider) (string, error) {
+	sp := providerService(p)
+	if sp == nil {
+		return "", nil
+	}
+	hostname := sp.GetService()
+	if _, h, ok := strings.Cut(hostname, "/"); ok {
+		hostname = h
+	}
+	parts := strings.Split(hostname, ".")
+	if len(parts) < 3 || parts[2] != "svc" {
+		return "", nil
+	}
+	svc, err := cli.Kube().CoreV1().Services(parts[1]).Get(context.Background(), parts[0], metav1.GetOptions{})
+	if kerrors.IsNotFound(err) {
+		return "does not exist", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	for _, port := range svc.Spec.Ports {
+		if uint32(port.Port) == sp.GetPort() {
+			return "", nil
+		}
+	}
+	return fmt.Sprintf("has no port %d", sp.GetPort()), nil
+}
+
+// deprecatedFields returns the paths of the deprecated fields set in the message, and in the messages it contains.
+func deprecatedFields(m protoreflect.Message, prefix string) []string {
+	var res []string
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		path := prefix + fd.JSONName()
+		if opts, ok := fd.Options().(*descriptorpb.FieldOptions); ok && opts.GetDeprecated() {
+			res = append(res, path)
+		}
+		switch {
+		case fd.IsList() && fd.Message() != nil:
+			for i := 0; i < v.List().Len(); i++ {
+				res = append(res, deprecatedFields(v.List().Get(i).Message(), fmt.Sprintf("%s[%d].", path, i))...)
+			}
+		case fd.IsMap() && fd.MapValue().Message() != nil:
+			v.Map().Range(func(k protoreflect.MapKey, v protoreflect.Value) bool {
+				res = append(res, deprecatedFields(v.Message(), fmt.Sprintf("%s[%s].", path, k.String()))...)
+				return true
+			})
+		case !fd.IsList() && !fd.IsMap() && fd.Message() != nil:
+			res = append(res, deprecatedFields(v.Message(), path+".")...)
+		}
+		return true
+	})
+	sort.Strings(res)
+	return res
+}
+
+// errorList returns the messages of the errors wrapped by a multierror, or of the error.
+func errorList(err error) []string {
+	if err == nil {
+		return nil
+	}
+	var merr *multierror.Error
+	if errors.As(err, &merr) {
+		var res []string
+		for _, e := range merr.Errors {
+			res = append(res, errorList(e)...)
+		}
+		return res
+	}
+	return []string{err.Error()}
+}
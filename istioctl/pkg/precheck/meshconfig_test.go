@@ -0,0 +1,139 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package precheck
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"istio.io/istio/pkg/config/analysis/diag"
+	"istio.io/istio/pkg/config/analysis/msg"
+	"istio.io/istio/pkg/kube"
+)
+
+func TestCheckMeshConfig(t *testing.T) {
+	configMap := func(name, mesh string) *corev1.ConfigMap {
+		return &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "istio-system"},
+			Data:       map[string]string{"mesh": mesh},
+		}
+	}
+	// The mesh config of the default profile.
+	defaultMesh := `defaultConfig:
+  discoveryAddress: istiod.istio-system.svc:15012
+defaultProviders:
+  metrics:
+  - prometheus
+enablePrometheusMerge: true
+rootNamespace: istio-system
+trustDomain: cluster.local
+`
+
+	zipkin := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "zipkin", Namespace: "istio-system"},
+		Spec:       corev1.ServiceSpec{Ports: []corev1.ServicePort{{Name: "http-query", Port: 9411}}},
+	}
+
+	cases := []struct {
+		name    string
+		objects []runtime.Object
+		want    []*diag.MessageType
+		params  [][]any
+	}{
+		{
+			name: "no mesh config",
+		},
+		{
+			name: "default mesh config",
+			objects: []runtime.Object{
+				configMap("istio", defaultMesh),
+				configMap("istio-canary", defaultMesh),
+				&corev1.ConfigMap{
+					ObjectMeta: metav1.ObjectMeta{Name: "istio-sidecar-injector", Namespace: "istio-system"},
+					Data:       map[string]string{"config": "policy: enabled"},
+				},
+			},
+		},
+		{
+			name: "unknown field",
+			objects: []runtime.Object{
+				configMap("istio", defaultMesh+"enableTracng: true\n"),
+			},
+			want: []*diag.MessageType{msg.MeshConfigInvalid},
+		},
+		{
+			name: "deprecated fields",
+			objects: []runtime.Object{
+				configMap("istio-canary", defaultMesh+"certificates:\n- secretName: dns.example\n  dnsNames: [example.com]\n"+
+					"extensionProviders:\n- name: zipkin\n  zipkin:\n    service: zipkin.istio-system.svc.cluster.local\n    port: 9411\n"),
+				configMap("istio", "defaultConfig:\n  zipkinAddress: zipkin:9411\n"),
+				zipkin,
+			},
+			want:   []*diag.MessageType{msg.MeshConfigDeprecatedField, msg.MeshConfigDeprecatedField},
+			params: [][]any{{"istio-canary", "certificates"}, {"istio", "defaultConfig.zipkinAddress"}},
+		},
+		{
+			name: "invalid trust domain",
+			objects: []runtime.Object{
+				configMap("istio", "trustDomain: cluster_local\n"),
+			},
+			want: []*diag.MessageType{msg.MeshConfigInvalid},
+		},
+		{
+			name: "invalid extension provider",
+			objects: []runtime.Object{
+				configMap("istio", "extensionProviders:\n- name: authz\n  envoyExtAuthzHttp:\n    service: authz\n    port: 0\n"),
+			},
+			want: []*diag.MessageType{msg.MeshConfigInvalid},
+		},
+		{
+			name: "unresolved extension providers",
+			objects: []runtime.Object{
+				configMap("istio", defaultMesh+"extensionProviders:\n"+
+					"- name: zipkin\n  zipkin:\n    service: istio-system/zipkin.istio-system.svc.cluster.local\n    port: 9411\n"+
+					"- name: otel\n  opentelemetry:\n    service: otel-collector.observability.svc.cluster.local\n    port: 4317\n"+
+					"- name: authz\n  envoyExtAuthzGrpc:\n    service: zipkin.istio-system.svc.cluster.local\n    port: 9000\n"+
+					"- name: external\n  envoyExtAuthzHttp:\n    service: authz.example.com\n    port: 8000\n"+
+					"- name: prometheus\n  prometheus: {}\n"),
+				zipkin,
+			},
+			want: []*diag.MessageType{msg.ExtensionProviderUnresolved, msg.ExtensionProviderUnresolved},
+			params: [][]any{
+				{"authz", "istio", "zipkin.istio-system.svc.cluster.local", "has no port 9000"},
+				{"otel", "istio", "otel-collector.observability.svc.cluster.local", "does not exist"},
+			},
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			msgs, err := checkMeshConfig(kube.NewFakeClient(tt.objects...), "istio-system")
+			assert.NoError(t, err)
+			var got []*diag.MessageType
+			var params [][]any
+			for _, m := range msgs.SortedDedupedCopy() {
+				got = append(got, m.Type)
+				if m.Type == msg.MeshConfigDeprecatedField || m.Type == msg.ExtensionProviderUnresolved {
+					params = append(params, m.Parameters)
+				}
+			}
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.params, params)
+		})
+	}
+}
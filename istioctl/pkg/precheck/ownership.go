@@ -0,0 +1,138 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package precheck
+
+import (
+	"context"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"istio.io/istio/operator/pkg/manifest"
+	"istio.io/istio/pkg/config/analysis/diag"
+	"istio.io/istio/pkg/config/analysis/msg"
+	"istio.io/istio/pkg/kube"
+	"istio.io/istio/pkg/kube/controllers"
+)
+
+const (
+	// helmReleaseName and helmReleaseNamespace are set by Helm on the resources of its releases.
+	helmReleaseName      = "meta.helm.sh/release-name"
+	helmReleaseNamespace = "meta.helm.sh/release-namespace"
+	// managedByLabel names the tool managing a resource. The charts set it to Helm even when they are rendered by
+	// another tool, so only its other values are taken as a manager.
+	managedByLabel = "app.kubernetes.io/managed-by"
+	// argoTrackingID is set by Argo CD on the resources of its applications, as <application>:<group>/<kind>:<resource>.
+	argoTrackingID = "argocd.argoproj.io/tracking-id"
+)
+
+// istioResource is a resource of an Istio component, with its kind, which listed objects do not have.
+type istioResource struct {
+	kind string
+	obj  controllers.Object
+}
+
+// istioResources returns the resources of the Istio components which may be changed by their installer, as the
+// charts label them with the component.
+func istioResources(cli kube.CLIClient) ([]istioResource, error) {
+	ctx := context.Background()
+	opts := metav1.ListOptions{LabelSelector: componentLabel}
+	var res []istioResource
+	deployments, err := cli.Kube().AppsV1().Deployments(metav1.NamespaceAll).List(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	for i := range deployments.Items {
+		res = append(res, istioResource{"Deployment", &deployments.Items[i]})
+	}
+	daemonSets, err := cli.Kube().AppsV1().DaemonSets(metav1.NamespaceAll).List(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	for i := range daemonSets.Items {
+		res = append(res, istioResource{"DaemonSet", &daemonSets.Items[i]})
+	}
+	services, err := cli.Kube().CoreV1().Services(metav1.NamespaceAll).List(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	for i := range services.Items {
+		res = append(res, istioResource{"Service", &services.Items[i]})
+	}
+	configMaps, err := cli.Kube().CoreV1().ConfigMaps(metav1.NamespaceAll).List(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	for i := range configMaps.Items {
+		res = append(res, istioResource{"ConfigMap", &configMaps.Items[i]})
+	}
+	mwcs, err := cli.Kube().AdmissionregistrationV1().MutatingWebhookConfigurations().List(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	for i := range mwcs.Items {
+		res = append(res, istioResource{"MutatingWebhookConfiguration", &mwcs.Items[i]})
+	}
+	vwcs, err := cli.Kube().AdmissionregistrationV1().ValidatingWebhookConfigurations().List(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	for i := range vwcs.Items {
+		res = append(res, istioResource{"ValidatingWebhookConfiguration", &vwcs.Items[i]})
+	}
+	return res, nil
+}
+
+// resourceManagers returns the tools managing the resource, according to the labels and annotations they set on it.
+func resourceManagers(obj metav1.Object) []string {
+	var managers []string
+	if release, ok := obj.GetAnnotations()[helmReleaseName]; ok {
+		managers = append(managers, "the Helm release "+obj.GetAnnotations()[helmReleaseNamespace]+"/"+release)
+	}
+	if owner, ok := obj.GetLabels()[manifest.OwningResourceName]; ok {
+		managers = append(managers, "istioctl install (IstioOperator "+owner+")")
+	}
+	if id, ok := obj.GetAnnotations()[argoTrackingID]; ok {
+		app, _, _ := strings.Cut(id, ":")
+		managers = append(managers, "the Argo CD application "+app)
+	}
+	if managedBy := obj.GetLabels()[managedByLabel]; managedBy != "" && managedBy != "Helm" {
+		managers = append(managers, managedBy)
+	}
+	return managers
+}
+
+// Checks that each resource of the Istio components is managed by a single tool, among Helm, istioctl install, Argo CD
+// and the tools named by the app.kubernetes.io/managed-by label. Tools managing the same resource revert the changes
+// of each other, so that an install or upgrade with one of them may be undone by another.
+func checkResourceManagers(cli kube.CLIClient) (diag.Messages, error) {
+	resources, err := istioResources(cli)
+	if err != nil {
+		return nil, err
+	}
+	msgs := diag.Messages{}
+	for _, r := range resources {
+		managers := resourceManagers(r.obj)
+		if len(managers) < 2 {
+			continue
+		}
+		name := r.obj.GetName()
+		if r.obj.GetNamespace() != "" {
+			name = r.obj.GetNamespace() + "/" + name
+		}
+		msgs.Add(msg.NewConflictingResourceManagers(ObjectToInstance(r.obj), r.kind, name, strings.Join(managers, ", ")))
+	}
+	return msgs, nil
+}
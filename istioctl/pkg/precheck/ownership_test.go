@@ -0,0 +1,63 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package precheck
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"istio.io/istio/operator/pkg/manifest"
+	"istio.io/istio/pkg/config/analysis/msg"
+	"istio.io/istio/pkg/kube"
+)
+
+func TestCheckResourceManagers(t *testing.T) {
+	meta := func(name, namespace string, labels, annotations map[string]string) metav1.ObjectMeta {
+		labels[componentLabel] = "Pilot"
+		return metav1.ObjectMeta{Name: name, Namespace: namespace, Labels: labels, Annotations: annotations}
+	}
+	helm := map[string]string{helmReleaseName: "istiod", helmReleaseNamespace: "istio-system"}
+	cli := kube.NewFakeClient(
+		// A chart rendered by Argo CD sets the managed-by label to Helm without being a Helm release.
+		&appsv1.Deployment{ObjectMeta: meta("istiod", "istio-system",
+			map[string]string{managedByLabel: "Helm"},
+			map[string]string{argoTrackingID: "istio:apps/Deployment:istio-system/istiod"})},
+		&corev1.Service{ObjectMeta: meta("istiod", "istio-system",
+			map[string]string{managedByLabel: "Helm", manifest.OwningResourceName: "installed-state"}, helm)},
+		&corev1.ConfigMap{ObjectMeta: meta("istio", "istio-system", map[string]string{managedByLabel: "Helm"}, helm)},
+		&admissionregistrationv1.MutatingWebhookConfiguration{ObjectMeta: meta("istio-sidecar-injector", "",
+			map[string]string{managedByLabel: "kustomize"},
+			map[string]string{argoTrackingID: "istio:admissionregistration.k8s.io/MutatingWebhookConfiguration:istio-sidecar-injector"})},
+		// Resources without the component label are not checked.
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default",
+			Labels: map[string]string{manifest.OwningResourceName: "installed-state"}, Annotations: helm}},
+	)
+	msgs, err := checkResourceManagers(cli)
+	assert.NoError(t, err)
+	var got [][]any
+	for _, m := range msgs.SortedDedupedCopy() {
+		assert.Equal(t, msg.ConflictingResourceManagers, m.Type)
+		got = append(got, m.Parameters)
+	}
+	assert.Equal(t, [][]any{
+		{"MutatingWebhookConfiguration", "istio-sidecar-injector", "the Argo CD application istio, kustomize"},
+		{"Service", "istio-system/istiod", "the Helm release istio-system/istiod, istioctl install (IstioOperator installed-state)"},
+	}, got)
+}
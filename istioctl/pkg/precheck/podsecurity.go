@@ -0,0 +1,113 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package precheck
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"istio.io/api/label"
+	"istio.io/istio/pkg/config/analysis/diag"
+	"istio.io/istio/pkg/config/analysis/msg"
+	"istio.io/istio/pkg/kube"
+)
+
+const podSecurityEnforceLabel = "pod-security.kubernetes.io/enforce"
+
+// podSecurityLevels orders the levels of the Pod Security Standards, from the least to the most restrictive.
+var podSecurityLevels = map[string]int{"privileged": 0, "baseline": 1, "restricted": 2}
+
+type podSecurityRequirement struct {
+	component string
+	level     string
+}
+
+// Checks that the namespaces hosting Istio components do not enforce a Pod Security level their pods violate, which
+// otherwise only surfaces as pods failing admission. istiod and the gateways need the baseline level: they set no
+// seccomp profile by default, and gateways use the net.ipv4.ip_unprivileged_port_start sysctl. The CNI and ztunnel
+// node agents need the privileged level, and so do injected pods without CNI, as their istio-init container requires
+// the NET_ADMIN and NET_RAW capabilities. Namespaces without the enforce label follow the cluster default, which is
+// not visible through the API, and are skipped.
+func checkPodSecurity(cli kube.CLIClient, istioNamespace string) (diag.Messages, error) {
+	ctx := context.Background()
+	namespaces, err := cli.Kube().CoreV1().Namespaces().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	required := map[string][]podSecurityRequirement{}
+	add := func(namespace, component, level string) {
+		required[namespace] = append(required[namespace], podSecurityRequirement{component: component, level: level})
+	}
+	add(istioNamespace, "istiod", "baseline")
+
+	for _, selector := range []string{"istio in (ingressgateway,egressgateway)", "gateway.istio.io/managed"} {
+		gateways, err := cli.Kube().AppsV1().Deployments(metav1.NamespaceAll).List(ctx, metav1.ListOptions{LabelSelector: selector})
+		if err != nil {
+			return nil, err
+		}
+		for _, d := range gateways.Items {
+			add(d.Namespace, "gateway "+d.Name, "baseline")
+		}
+	}
+
+	cniInstalled := false
+	for _, agent := range []struct{ selector, component string }{
+		{"k8s-app=istio-cni-node", "the Istio CNI node agent"},
+		{"app=ztunnel", "ztunnel"},
+	} {
+		agents, err := cli.Kube().AppsV1().DaemonSets(metav1.NamespaceAll).List(ctx, metav1.ListOptions{LabelSelector: agent.selector})
+		if err != nil {
+			return nil, err
+		}
+		for _, ds := range agents.Items {
+			add(ds.Namespace, agent.component, "privileged")
+			cniInstalled = true
+		}
+	}
+	if !cniInstalled {
+		for i := range namespaces.Items {
+			if ns := &namespaces.Items[i]; injectionEnabled(ns) {
+				add(ns.Name, "the istio-init container of injected pods", "privileged")
+			}
+		}
+	}
+
+	msgs := diag.Messages{}
+	for i := range namespaces.Items {
+		ns := &namespaces.Items[i]
+		enforced := ns.Labels[podSecurityEnforceLabel]
+		enforcedLevel, ok := podSecurityLevels[enforced]
+		if !ok {
+			continue
+		}
+		for _, r := range required[ns.Name] {
+			if podSecurityLevels[r.level] < enforcedLevel {
+				msgs.Add(msg.NewPodSecurityLevelIncompatible(ObjectToInstance(ns), enforced, r.component, r.level))
+			}
+		}
+	}
+	return msgs, nil
+}
+
+// injectionEnabled returns whether pods of the namespace get a sidecar injected, unless they opt out.
+func injectionEnabled(ns *corev1.Namespace) bool {
+	if ns.Labels["istio-injection"] == "disabled" || ns.Labels[label.IoIstioDataplaneMode.Name] == "ambient" {
+		return false
+	}
+	_, rev := ns.Labels[label.IoIstioRev.Name]
+	return ns.Labels["istio-injection"] == "enabled" || rev
+}
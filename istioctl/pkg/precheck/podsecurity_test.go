@@ -0,0 +1,113 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package precheck
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"istio.io/istio/pkg/config/analysis/msg"
+	"istio.io/istio/pkg/kube"
+)
+
+func TestCheckPodSecurity(t *testing.T) {
+	namespace := func(name string, labels map[string]string) *corev1.Namespace {
+		return &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels}}
+	}
+	enforce := func(level string, labels ...string) map[string]string {
+		l := map[string]string{podSecurityEnforceLabel: level}
+		for i := 0; i < len(labels); i += 2 {
+			l[labels[i]] = labels[i+1]
+		}
+		return l
+	}
+	gateway := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{
+		Name: "istio-ingressgateway", Namespace: "ingress", Labels: map[string]string{"istio": "ingressgateway"},
+	}}
+	cni := &appsv1.DaemonSet{ObjectMeta: metav1.ObjectMeta{
+		Name: "istio-cni-node", Namespace: "kube-system", Labels: map[string]string{"k8s-app": "istio-cni-node"},
+	}}
+
+	cases := []struct {
+		name    string
+		objects []runtime.Object
+		want    [][]any
+	}{
+		{
+			name: "compatible levels",
+			objects: []runtime.Object{
+				namespace("istio-system", enforce("baseline")),
+				namespace("ingress", enforce("baseline")),
+				namespace("kube-system", enforce("privileged")),
+				namespace("default", nil),
+				gateway, cni,
+			},
+		},
+		{
+			name: "restricted control plane and gateway namespaces",
+			objects: []runtime.Object{
+				namespace("istio-system", enforce("restricted")),
+				namespace("ingress", enforce("restricted")),
+				gateway,
+			},
+			want: [][]any{
+				{"restricted", "gateway istio-ingressgateway", "baseline"},
+				{"restricted", "istiod", "baseline"},
+			},
+		},
+		{
+			name: "CNI in a baseline namespace",
+			objects: []runtime.Object{
+				namespace("istio-system", nil),
+				namespace("kube-system", enforce("baseline")),
+				namespace("apps", enforce("baseline", "istio-injection", "enabled")),
+				cni,
+			},
+			want: [][]any{
+				{"baseline", "the Istio CNI node agent", "privileged"},
+			},
+		},
+		{
+			name: "injected namespace without CNI",
+			objects: []runtime.Object{
+				namespace("apps", enforce("baseline", "istio-injection", "enabled")),
+				namespace("canary", enforce("restricted", "istio.io/rev", "1-24")),
+				namespace("opted-out", enforce("baseline", "istio-injection", "disabled", "istio.io/rev", "1-24")),
+				namespace("ambient", enforce("baseline", "istio.io/dataplane-mode", "ambient")),
+			},
+			want: [][]any{
+				{"baseline", "the istio-init container of injected pods", "privileged"},
+				{"restricted", "the istio-init container of injected pods", "privileged"},
+			},
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			msgs, err := checkPodSecurity(kube.NewFakeClient(tt.objects...), "istio-system")
+			assert.NoError(t, err)
+			var got [][]any
+			for _, m := range msgs.SortedDedupedCopy() {
+				assert.Equal(t, msg.PodSecurityLevelIncompatible, m.Type)
+				got = append(got, m.Parameters)
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
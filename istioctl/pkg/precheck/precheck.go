@@ -20,6 +20,7 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
@@ -31,6 +32,7 @@ import (
 	"istio.io/istio/istioctl/pkg/cli"
 	"istio.io/istio/istioctl/pkg/clioptions"
 	"istio.io/istio/istioctl/pkg/install/k8sversion"
+	"istio.io/istio/istioctl/pkg/util"
 	"istio.io/istio/istioctl/pkg/util/formatting"
 	istiocluster "istio.io/istio/pkg/cluster"
 	"istio.io/istio/pkg/config/analysis"
@@ -46,6 +48,7 @@ import (
 	"istio.io/istio/pkg/kube/controllers"
 	"istio.io/istio/pkg/url"
 	"istio.io/istio/pkg/util/sets"
+	"istio.io/istio/pkg/version"
 )
 
 func Cmd(ctx cli.Context) *cobra.Command {
@@ -54,6 +57,10 @@ func Cmd(ctx cli.Context) *cobra.Command {
 	outputThreshold := formatting.MessageThreshold{Level: diag.Warning}
 	var msgOutputFormat string
 	var fromCompatibilityVersion string
+	var certExpiryWindow time.Duration
+	var manifestsPath string
+	var checkDataPlane bool
+	var maxProxySkew int
 	// cmd represents the upgradeCheck command
 	cmd := &cobra.Command{
 		Use:   "precheck",
@@ -66,11 +73,14 @@ func Cmd(ctx cli.Context) *cobra.Command {
   istioctl x precheck --namespace default
 
   # Check for behavioral changes since a specific version
-  istioctl x precheck --from-version 1.10`,
+  istioctl x precheck --from-version 1.10
+
+  # Check after an upgrade that no proxy is more than one minor version behind istiod
+  istioctl x precheck --data-plane --max-proxy-skew 1`,
 		RunE: func(cmd *cobra.Command, args []string) (err error) {
 			msgs := diag.Messages{}
 			if !skipControlPlane {
-				msgs, err = checkControlPlane(ctx)
+				msgs, err = checkControlPlane(ctx, certExpiryWindow, manifestsPath)
 				if err != nil {
 					return err
 				}
@@ -84,6 +94,18 @@ func Cmd(ctx cli.Context) *cobra.Command {
 				msgs = append(msgs, m...)
 			}
 
+			if checkDataPlane {
+				cli, err := ctx.CLIClient()
+				if err != nil {
+					return err
+				}
+				m, err := checkProxyVersionSkew(cli, ctx.IstioNamespace(), maxProxySkew)
+				if err != nil {
+					return err
+				}
+				msgs = append(msgs, m...)
+			}
+
 			// Print all the messages to stdout in the specified format
 			msgs = msgs.SortedDedupedCopy()
 			outputMsgs := diag.Messages{}
@@ -121,6 +143,14 @@ See %s for more information about causes and resolutions.`, url.ConfigAnalysis)
 		fmt.Sprintf("Output format: one of %v", formatting.MsgOutputFormatKeys))
 	cmd.PersistentFlags().StringVarP(&fromCompatibilityVersion, "from-version", "f", "",
 		"check changes since the provided version")
+	cmd.PersistentFlags().DurationVar(&certExpiryWindow, "cert-expiry-window", 30*24*time.Hour,
+		"warn about Istio CA certificates expiring within this duration")
+	cmd.PersistentFlags().StringVarP(&manifestsPath, "manifests", "d", "", util.ManifestsFlagHelpStr+
+		" The Istio CRDs are compared with the ones of these manifests, for a control plane of their version.")
+	cmd.PersistentFlags().BoolVar(&checkDataPlane, "data-plane", false,
+		"check the proxies of the injected pods against the istiod of their revision")
+	cmd.PersistentFlags().IntVar(&maxProxySkew, "max-proxy-skew", 2,
+		"with --data-plane, warn about the proxies more than this many minor versions behind istiod")
 	opts.AttachControlPlaneFlags(cmd)
 	return cmd
 }
@@ -188,7 +218,7 @@ func ObjectToInstance(c controllers.Object) *resource.Instance {
 	}
 }
 
-func checkControlPlane(ctx cli.Context) (diag.Messages, error) {
+func checkControlPlane(ctx cli.Context, certExpiryWindow time.Duration, manifestsPath string) (diag.Messages, error) {
 	cli, err := ctx.CLIClient()
 	if err != nil {
 		return nil, err
@@ -208,6 +238,90 @@ func checkControlPlane(ctx cli.Context) (diag.Messages, error) {
 	}
 	msgs = append(msgs, gwMsg...)
 
+	crdMsg, err := checkIstioCRDs(cli, ctx.IstioNamespace(), manifestsPath)
+	if err != nil {
+		return nil, err
+	}
+	msgs = append(msgs, crdMsg...)
+
+	certMsg, err := checkCACertificates(cli, ctx.IstioNamespace(), certExpiryWindow, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	msgs = append(msgs, certMsg...)
+
+	meshMsg, err := checkMeshConfig(cli, ctx.IstioNamespace())
+	if err != nil {
+		return nil, err
+	}
+	msgs = append(msgs, meshMsg...)
+
+	tagMsg, err := checkRevisionTags(cli)
+	if err != nil {
+		return nil, err
+	}
+	msgs = append(msgs, tagMsg...)
+
+	podSecurityMsg, err := checkPodSecurity(cli, ctx.IstioNamespace())
+	if err != nil {
+		return nil, err
+	}
+	msgs = append(msgs, podSecurityMsg...)
+
+	cniMsg, err := checkCNINodePools(cli)
+	if err != nil {
+		return nil, err
+	}
+	msgs = append(msgs, cniMsg...)
+
+	ambientMsg, err := checkAmbient(cli)
+	if err != nil {
+		return nil, err
+	}
+	msgs = append(msgs, ambientMsg...)
+
+	istiodMsg, err := checkIstiodHealth(cli, ctx.IstioNamespace(), time.Now())
+	if err != nil {
+		return nil, err
+	}
+	msgs = append(msgs, istiodMsg...)
+
+	endpointsMsg, err := checkServiceEndpoints(cli)
+	if err != nil {
+		return nil, err
+	}
+	msgs = append(msgs, endpointsMsg...)
+
+	gatewayMsg, err := checkGatewayAddresses(cli, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	msgs = append(msgs, gatewayMsg...)
+
+	autoscalingMsg, err := checkAutoscaling(cli)
+	if err != nil {
+		return nil, err
+	}
+	msgs = append(msgs, autoscalingMsg...)
+
+	managersMsg, err := checkResourceManagers(cli)
+	if err != nil {
+		return nil, err
+	}
+	msgs = append(msgs, managersMsg...)
+
+	helmMsg, err := checkHelmReleases(cli)
+	if err != nil {
+		return nil, err
+	}
+	msgs = append(msgs, helmMsg...)
+
+	efMsg, err := checkEnvoyFilters(cli, version.Info.Version)
+	if err != nil {
+		return nil, err
+	}
+	msgs = append(msgs, efMsg...)
+
 	// TODO: add more checks
 
 	sa := local.NewSourceAnalyzer(
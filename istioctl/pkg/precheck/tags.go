@@ -0,0 +1,106 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package precheck
+
+import (
+	"context"
+	"fmt"
+
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"istio.io/api/label"
+	"istio.io/istio/pkg/config/analysis/diag"
+	"istio.io/istio/pkg/config/analysis/msg"
+	"istio.io/istio/pkg/kube"
+	"istio.io/istio/pkg/util/sets"
+)
+
+// Checks the revision tags of the mesh: each webhook of a tag must point at the injection service of the revision of
+// the tag, and the revision must be installed. A dangling "default" tag is a common source of silent injection
+// breakage after a canary upgrade, as the revision it points at gets uninstalled.
+func checkRevisionTags(cli kube.CLIClient) (diag.Messages, error) {
+	msgs := diag.Messages{}
+	whs, err := cli.Kube().AdmissionregistrationV1().MutatingWebhookConfigurations().List(context.Background(), metav1.ListOptions{
+		LabelSelector: label.IoIstioTag.Name,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(whs.Items) == 0 {
+		return msgs, nil
+	}
+	revisions, err := installedRevisions(cli)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range whs.Items {
+		mwc := &whs.Items[i]
+		tag := mwc.Labels[label.IoIstioTag.Name]
+		revision := mwc.Labels[label.IoIstioRev.Name]
+		res := ObjectToInstance(mwc)
+		if revision != "" && !revisions.Contains(revision) {
+			msgs.Add(msg.NewRevisionTagRevisionNotInstalled(res, tag, revision, taggedNamespaces(tag)))
+		}
+		for _, wh := range mwc.Webhooks {
+			ref := wh.ClientConfig.Service
+			if ref == nil {
+				// Webhooks of external control planes are addressed by URL.
+				continue
+			}
+			name := ref.Namespace + "/" + ref.Name
+			svc, err := cli.Kube().CoreV1().Services(ref.Namespace).Get(context.Background(), ref.Name, metav1.GetOptions{})
+			if kerrors.IsNotFound(err) {
+				msgs.Add(msg.NewRevisionTagServiceNotFound(res, wh.Name, tag, name))
+				continue
+			}
+			if err != nil {
+				return nil, fmt.Errorf("failed to retrieve service %s: %v", name, err)
+			}
+			if svcRevision, ok := svc.Labels[label.IoIstioRev.Name]; ok && revision != "" && svcRevision != revision {
+				msgs.Add(msg.NewRevisionTagServiceMismatch(res, tag, revision, wh.Name, name, svcRevision))
+			}
+		}
+	}
+	return msgs, nil
+}
+
+// installedRevisions returns the revisions of the istiod Deployments of the cluster.
+func installedRevisions(cli kube.CLIClient) (sets.String, error) {
+	deployments, err := cli.Kube().AppsV1().Deployments(metav1.NamespaceAll).List(context.Background(), metav1.ListOptions{
+		LabelSelector: "app=istiod",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list istiod deployments: %v", err)
+	}
+	revisions := sets.New[string]()
+	for _, d := range deployments.Items {
+		rev := d.Labels[label.IoIstioRev.Name]
+		if rev == "" {
+			rev = "default"
+		}
+		revisions.Insert(rev)
+	}
+	return revisions, nil
+}
+
+// taggedNamespaces describes the namespaces injected through a tag.
+func taggedNamespaces(tag string) string {
+	if tag == "default" {
+		return "namespaces labeled istio-injection=enabled or istio.io/rev=default"
+	}
+	return fmt.Sprintf("namespaces labeled istio.io/rev=%s", tag)
+}
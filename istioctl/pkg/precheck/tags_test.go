@@ -0,0 +1,85 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package precheck
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	admitv1 "k8s.io/api/admissionregistration/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"istio.io/istio/pkg/config/analysis/diag"
+	"istio.io/istio/pkg/config/analysis/msg"
+	"istio.io/istio/pkg/kube"
+)
+
+func TestCheckRevisionTags(t *testing.T) {
+	istiod := func(name, revision string) (*appsv1.Deployment, *corev1.Service) {
+		labels := map[string]string{"app": "istiod", "istio.io/rev": revision}
+		return &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "istio-system", Labels: labels}},
+			&corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "istio-system", Labels: labels}}
+	}
+	tagWebhook := func(tag, revision, service string) *admitv1.MutatingWebhookConfiguration {
+		return &admitv1.MutatingWebhookConfiguration{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   "istio-revision-tag-" + tag,
+				Labels: map[string]string{"istio.io/tag": tag, "istio.io/rev": revision},
+			},
+			Webhooks: []admitv1.MutatingWebhook{{
+				Name: "rev.namespace.sidecar-injector.istio.io",
+				ClientConfig: admitv1.WebhookClientConfig{
+					Service: &admitv1.ServiceReference{Name: service, Namespace: "istio-system"},
+				},
+			}},
+		}
+	}
+	deployment, service := istiod("istiod-1-24", "1-24")
+	_, canaryService := istiod("istiod-1-25", "1-25")
+	cli := kube.NewFakeClient(
+		deployment, service, canaryService,
+		tagWebhook("prod", "1-24", "istiod-1-24"),
+		// The canary revision was uninstalled, but the default tag still points at it.
+		tagWebhook("default", "1-23", "istiod-1-23"),
+		// The tag is labeled for a revision, but points at the service of another.
+		tagWebhook("canary", "1-24", "istiod-1-25"),
+	)
+	msgs, err := checkRevisionTags(cli)
+	assert.NoError(t, err)
+	types := map[*diag.MessageType]int{}
+	for _, m := range msgs {
+		types[m.Type]++
+		switch m.Type {
+		case msg.RevisionTagRevisionNotInstalled:
+			assert.Equal(t, []any{"default", "1-23", "namespaces labeled istio-injection=enabled or istio.io/rev=default"}, m.Parameters)
+		case msg.RevisionTagServiceNotFound:
+			assert.Equal(t, []any{"rev.namespace.sidecar-injector.istio.io", "default", "istio-system/istiod-1-23"}, m.Parameters)
+		case msg.RevisionTagServiceMismatch:
+			assert.Equal(t, []any{"canary", "1-24", "rev.namespace.sidecar-injector.istio.io", "istio-system/istiod-1-25", "1-25"}, m.Parameters)
+		}
+	}
+	assert.Equal(t, map[*diag.MessageType]int{
+		msg.RevisionTagRevisionNotInstalled: 1,
+		msg.RevisionTagServiceNotFound:      1,
+		msg.RevisionTagServiceMismatch:      1,
+	}, types)
+
+	// Clusters without tags are skipped.
+	msgs, err = checkRevisionTags(kube.NewFakeClient(deployment, service))
+	assert.NoError(t, err)
+	assert.Len(t, msgs, 0)
+}
@@ -0,0 +1,338 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protocolcheck
+
+import (
+	"fmt"
+	"strings"
+
+	cluster "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	listener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	tlsv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
+
+	networking "istio.io/api/networking/v1alpha3"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/networking/util"
+	sec_model "istio.io/istio/pilot/pkg/security/model"
+	"istio.io/istio/pkg/wellknown"
+)
+
+// ClientMode is the way a client proxy originates connections for a cluster.
+type ClientMode string
+
+const (
+	ClientPlaintext ClientMode = "plaintext"
+	ClientIstioMTLS ClientMode = "istio mTLS"
+	// ClientTLS is TLS originated with user supplied settings, from a DestinationRule in SIMPLE or MUTUAL mode.
+	ClientTLS ClientMode = "TLS"
+)
+
+// ClientSettings is the outbound TLS configuration a client proxy uses for a cluster.
+type ClientSettings struct {
+	Mode ClientMode
+	// AutoMTLS is set when the mode was selected by auto mTLS, based on the destination endpoint labels.
+	AutoMTLS bool
+	ALPN     []string
+	SNI      string
+}
+
+func (c ClientSettings) String() string {
+	s := string(c.Mode)
+	if c.AutoMTLS {
+		s += " (auto mTLS)"
+	}
+	var details []string
+	if len(c.ALPN) > 0 {
+		details = append(details, "ALPN "+strings.Join(c.ALPN, ","))
+	}
+	if c.SNI != "" {
+		details = append(details, "SNI "+c.SNI)
+	}
+	if len(details) > 0 {
+		s += ", " + strings.Join(details, ", ")
+	}
+	return s
+}
+
+// ServerSettings is what the inbound listener of a server proxy accepts on a port.
+type ServerSettings struct {
+	// MTLS is set when a filter chain terminates Istio mTLS.
+	MTLS bool
+	// MTLSALPN is the set of ALPN values the mTLS filter chains match on. Empty means any ALPN is accepted.
+	MTLSALPN []string
+	// Plaintext is set when a filter chain accepts plaintext connections.
+	Plaintext bool
+	// TLSPassthrough is set when a filter chain forwards TLS connections it does not terminate to the application.
+	TLSPassthrough bool
+}
+
+func (s ServerSettings) String() string {
+	var accepts []string
+	if s.MTLS {
+		m := "istio mTLS"
+		if len(s.MTLSALPN) > 0 {
+			m += " (ALPN " + strings.Join(s.MTLSALPN, ",") + ")"
+		}
+		accepts = append(accepts, m)
+	}
+	if s.Plaintext {
+		accepts = append(accepts, "plaintext")
+	}
+	if s.TLSPassthrough {
+		accepts = append(accepts, "TLS passed through to the application")
+	}
+	if len(accepts) == 0 {
+		return "no connections"
+	}
+	return strings.Join(accepts, ", ")
+}
+
+// Expectation is the outcome expected from the DestinationRule and PeerAuthentication applying to the connection.
+type Expectation struct {
+	// DestinationRule is the namespace/name of the DestinationRule applied to the cluster, if any.
+	DestinationRule string
+	// TLSMode is the mode set by the DestinationRule, or nil if it does not set one.
+	TLSMode *networking.ClientTLSSettings_TLSmode
+	// ServerHasSidecar is set when the destination pod runs an Istio proxy able to terminate mTLS.
+	ServerHasSidecar bool
+	// PeerAuthentication is the effective mTLS mode of the destination port.
+	PeerAuthentication model.MutualTLSMode
+}
+
+// ClientMode returns the client mode expected from the DestinationRule, falling back to auto mTLS.
+func (e Expectation) ClientMode() ClientMode {
+	if e.TLSMode != nil {
+		switch *e.TLSMode {
+		case networking.ClientTLSSettings_DISABLE:
+			return ClientPlaintext
+		case networking.ClientTLSSettings_SIMPLE, networking.ClientTLSSettings_MUTUAL:
+			return ClientTLS
+		case networking.ClientTLSSettings_ISTIO_MUTUAL:
+			return ClientIstioMTLS
+		}
+	}
+	if e.ServerHasSidecar {
+		return ClientIstioMTLS
+	}
+	return ClientPlaintext
+}
+
+// Result is the outcome of a protocol check.
+type Result struct {
+	OK       bool
+	Outcome  string
+	Findings []string
+}
+
+// ClientSettingsForCluster extracts the TLS settings a client proxy uses for the cluster. serverIstioTLS is
+// whether the destination endpoint carries the security.istio.io/tlsMode=istio label, which auto mTLS keys on.
+func ClientSettingsForCluster(c *cluster.Cluster, serverIstioTLS bool) (ClientSettings, error) {
+	if len(c.TransportSocketMatches) > 0 {
+		for _, m := range c.TransportSocketMatches {
+			v := m.GetMatch().GetFields()[model.TLSModeLabelShortname].GetStringValue()
+			if (v == model.IstioMutualTLSModeLabel) != serverIstioTLS {
+				continue
+			}
+			settings, err := clientSettingsForSocket(m.TransportSocket)
+			settings.AutoMTLS = true
+			return settings, err
+		}
+		return ClientSettings{Mode: ClientPlaintext, AutoMTLS: true}, nil
+	}
+	return clientSettingsForSocket(c.TransportSocket)
+}
+
+func clientSettingsForSocket(ts *core.TransportSocket) (ClientSettings, error) {
+	if ts == nil || ts.Name != wellknown.TransportSocketTLS {
+		return ClientSettings{Mode: ClientPlaintext}, nil
+	}
+	ctx := &tlsv3.UpstreamTlsContext{}
+	if err := ts.GetTypedConfig().UnmarshalTo(ctx); err != nil {
+		return ClientSettings{}, fmt.Errorf("failed to parse upstream TLS context: %v", err)
+	}
+	settings := ClientSettings{Mode: ClientTLS, ALPN: ctx.GetCommonTlsContext().GetAlpnProtocols(), SNI: ctx.GetSni()}
+	for _, sds := range ctx.GetCommonTlsContext().GetTlsCertificateSdsSecretConfigs() {
+		if sds.GetName() == sec_model.SDSDefaultResourceName {
+			settings.Mode = ClientIstioMTLS
+		}
+	}
+	return settings, nil
+}
+
+// ServerSettingsForPort extracts what the inbound listener accepts on the given target port.
+func ServerSettingsForPort(l *listener.Listener, port uint32) ServerSettings {
+	var chains []*listener.FilterChain
+	for _, fc := range l.GetFilterChains() {
+		if fc.GetFilterChainMatch().GetDestinationPort().GetValue() == port {
+			chains = append(chains, fc)
+		}
+	}
+	if len(chains) == 0 {
+		// Ports which are not declared by a service are handled by the passthrough filter chains.
+		for _, fc := range l.GetFilterChains() {
+			if fc.GetFilterChainMatch().GetDestinationPort() == nil {
+				chains = append(chains, fc)
+			}
+		}
+	}
+
+	s := ServerSettings{}
+	anyALPN := false
+	for _, fc := range chains {
+		tp := fc.GetFilterChainMatch().GetTransportProtocol()
+		terminates := fc.GetTransportSocket().GetName() == wellknown.TransportSocketTLS
+		switch {
+		case terminates:
+			s.MTLS = true
+			alpn := fc.GetFilterChainMatch().GetApplicationProtocols()
+			if len(alpn) == 0 {
+				anyALPN = true
+			}
+			for _, a := range alpn {
+				if !contains(s.MTLSALPN, a) {
+					s.MTLSALPN = append(s.MTLSALPN, a)
+				}
+			}
+		case tp == "tls":
+			s.TLSPassthrough = true
+		default:
+			s.Plaintext = true
+		}
+	}
+	if anyALPN {
+		s.MTLSALPN = nil
+	}
+	return s
+}
+
+// Evaluate predicts the outcome of a connection from the client to the server and explains mismatches
+// between the configured settings and those expected from the DestinationRule and PeerAuthentication.
+// server is nil when the destination has no sidecar.
+func Evaluate(expected Expectation, client ClientSettings, server *ServerSettings) Result {
+	r := Result{}
+
+	if want := expected.ClientMode(); want != client.Mode {
+		source := "auto mTLS"
+		if expected.TLSMode != nil {
+			source = fmt.Sprintf("DestinationRule %s (tls mode %s)", expected.DestinationRule, expected.TLSMode.String())
+		}
+		r.Findings = append(r.Findings, fmt.Sprintf("client is configured for %s but %s expects %s; the client proxy may not have "+
+			"received the latest configuration, check istioctl proxy-status", client.Mode, source, want))
+	}
+	if server != nil {
+		wantMTLS, wantPlaintext := expected.PeerAuthentication != model.MTLSDisable, expected.PeerAuthentication != model.MTLSStrict
+		if server.MTLS != wantMTLS || server.Plaintext != wantPlaintext {
+			r.Findings = append(r.Findings, fmt.Sprintf("server accepts %s but PeerAuthentication mode %s expects otherwise; the server proxy may "+
+				"not have received the latest configuration, check istioctl proxy-status", server, expected.PeerAuthentication))
+		}
+	}
+
+	switch client.Mode {
+	case ClientIstioMTLS:
+		switch {
+		case server == nil:
+			r.Outcome = "handshake failure: the client originates istio mTLS but the destination has no sidecar to terminate it"
+			r.Findings = append(r.Findings, "the application receives the TLS handshake directly; set the DestinationRule tls mode "+
+				"to DISABLE, or inject a sidecar into the destination")
+		case server.MTLS && alpnAccepted(server.MTLSALPN, client.ALPN):
+			r.OK = true
+			r.Outcome = "istio mTLS, ALPN " + strings.Join(negotiated(server.MTLSALPN, client.ALPN), ",")
+		case server.MTLS && server.TLSPassthrough:
+			r.Outcome = "the mTLS connection is passed through to the application without being terminated"
+			r.Findings = append(r.Findings, fmt.Sprintf("the client advertises ALPN [%s] which matches none of the ALPN values [%s] "+
+				"the server terminates istio mTLS for, such as istio-peer-exchange; the server treats it as raw TLS",
+				strings.Join(client.ALPN, ","), strings.Join(server.MTLSALPN, ",")))
+		case server.MTLS:
+			r.Outcome = "connection reset: no server filter chain matches the client ALPN"
+			r.Findings = append(r.Findings, fmt.Sprintf("the client advertises ALPN [%s] but the server only terminates istio mTLS for [%s]",
+				strings.Join(client.ALPN, ","), strings.Join(server.MTLSALPN, ",")))
+		case server.TLSPassthrough:
+			r.Outcome = "the mTLS connection is passed through to the application without being terminated"
+			r.Findings = append(r.Findings, "the server does not terminate mTLS on this port; PeerAuthentication mode is DISABLE")
+		default:
+			r.Outcome = "connection reset: the server only accepts plaintext"
+			r.Findings = append(r.Findings, "the server does not accept mTLS on this port; PeerAuthentication mode is DISABLE")
+		}
+	case ClientPlaintext:
+		switch {
+		case server == nil || server.Plaintext:
+			r.OK = true
+			r.Outcome = "plaintext"
+		default:
+			r.Outcome = "connection reset: the server requires mTLS but the client sends plaintext"
+			if expected.TLSMode != nil && *expected.TLSMode == networking.ClientTLSSettings_DISABLE {
+				r.Findings = append(r.Findings, fmt.Sprintf("DestinationRule %s disables TLS while PeerAuthentication mode is STRICT",
+					expected.DestinationRule))
+			} else if client.AutoMTLS {
+				r.Findings = append(r.Findings, "auto mTLS selected plaintext because the destination endpoint lacks the "+
+					"security.istio.io/tlsMode=istio label while PeerAuthentication mode is STRICT")
+			}
+		}
+	case ClientTLS:
+		switch {
+		case server == nil || server.TLSPassthrough:
+			r.OK = true
+			r.Outcome = "TLS, terminated by the application"
+		case server.MTLS:
+			r.Outcome = "handshake failure: the client originates TLS with its own credentials and the server terminates it as istio mTLS"
+			r.Findings = append(r.Findings, fmt.Sprintf("DestinationRule %s originates raw TLS to a sidecar in STRICT mode; use tls mode "+
+				"ISTIO_MUTUAL for in-mesh destinations, or let the application terminate TLS with PeerAuthentication PERMISSIVE",
+				expected.DestinationRule))
+		default:
+			r.Outcome = "the application receives TLS on a port the server treats as plaintext"
+			r.Findings = append(r.Findings, "the server does not expect TLS on this port")
+		}
+	}
+	return r
+}
+
+func alpnAccepted(server, client []string) bool {
+	return len(server) == 0 || len(negotiated(server, client)) > 0
+}
+
+// negotiated returns the client ALPN values accepted by the server, in client preference order.
+func negotiated(server, client []string) []string {
+	if len(server) == 0 {
+		return client
+	}
+	var res []string
+	for _, c := range client {
+		if contains(server, c) {
+			res = append(res, c)
+		}
+	}
+	return res
+}
+
+func contains(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+	return false
+}
+
+// istioConfig returns the namespace/name of the Istio config recorded in the cluster metadata.
+func istioConfig(c *cluster.Cluster) (string, string, bool) {
+	path := c.GetMetadata().GetFilterMetadata()[util.IstioMetadataKey].GetFields()["config"].GetStringValue()
+	// Path is in the form /apis/networking.istio.io/v1/namespaces/<ns>/destination-rule/<name>
+	parts := strings.Split(path, "/")
+	if len(parts) != 8 || parts[6] != "destination-rule" {
+		return "", "", false
+	}
+	return parts[5], parts[7], true
+}
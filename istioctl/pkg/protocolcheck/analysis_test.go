@@ -0,0 +1,221 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protocolcheck
+
+import (
+	"strings"
+	"testing"
+
+	cluster "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	listener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	tlsv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	networking "istio.io/api/networking/v1alpha3"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/networking/util"
+	"istio.io/istio/pilot/pkg/util/protoconv"
+	xdsfilters "istio.io/istio/pilot/pkg/xds/filters"
+	"istio.io/istio/pkg/ptr"
+	"istio.io/istio/pkg/test/util/assert"
+	"istio.io/istio/pkg/wellknown"
+)
+
+func upstreamTLS(sds string, alpn ...string) *core.TransportSocket {
+	ctx := &tlsv3.UpstreamTlsContext{
+		Sni:              "outbound_.8000_._.httpbin.foo.svc.cluster.local",
+		CommonTlsContext: &tlsv3.CommonTlsContext{AlpnProtocols: alpn},
+	}
+	if sds != "" {
+		ctx.CommonTlsContext.TlsCertificateSdsSecretConfigs = []*tlsv3.SdsSecretConfig{{Name: sds}}
+	}
+	return &core.TransportSocket{
+		Name:       wellknown.TransportSocketTLS,
+		ConfigType: &core.TransportSocket_TypedConfig{TypedConfig: protoconv.MessageToAny(ctx)},
+	}
+}
+
+func autoMTLSCluster() *cluster.Cluster {
+	return &cluster.Cluster{
+		Name: "outbound|8000||httpbin.foo.svc.cluster.local",
+		TransportSocketMatches: []*cluster.Cluster_TransportSocketMatch{
+			{
+				Name: "tlsMode-istio",
+				Match: &structpb.Struct{Fields: map[string]*structpb.Value{
+					model.TLSModeLabelShortname: structpb.NewStringValue(model.IstioMutualTLSModeLabel),
+				}},
+				TransportSocket: upstreamTLS("default", util.ALPNInMeshWithMxc...),
+			},
+			{
+				Name:            "tlsMode-disabled",
+				Match:           &structpb.Struct{},
+				TransportSocket: xdsfilters.RawBufferTransportSocket,
+			},
+		},
+	}
+}
+
+func TestClientSettingsForCluster(t *testing.T) {
+	got, err := ClientSettingsForCluster(autoMTLSCluster(), true)
+	assert.NoError(t, err)
+	assert.Equal(t, got.Mode, ClientIstioMTLS)
+	assert.Equal(t, got.AutoMTLS, true)
+	assert.Equal(t, got.ALPN, util.ALPNInMeshWithMxc)
+
+	got, err = ClientSettingsForCluster(autoMTLSCluster(), false)
+	assert.NoError(t, err)
+	assert.Equal(t, got.Mode, ClientPlaintext)
+
+	got, err = ClientSettingsForCluster(&cluster.Cluster{TransportSocket: upstreamTLS("kubernetes://client-cert", "h2")}, true)
+	assert.NoError(t, err)
+	assert.Equal(t, got.Mode, ClientTLS)
+	assert.Equal(t, got.AutoMTLS, false)
+
+	got, err = ClientSettingsForCluster(&cluster.Cluster{}, true)
+	assert.NoError(t, err)
+	assert.Equal(t, got.Mode, ClientPlaintext)
+}
+
+func inbound(port uint32, chains ...*listener.FilterChain) *listener.Listener {
+	for _, c := range chains {
+		c.FilterChainMatch.DestinationPort = wrapperspb.UInt32(port)
+	}
+	return &listener.Listener{Name: virtualInboundListener, FilterChains: chains}
+}
+
+func mtlsChain(alpn ...string) *listener.FilterChain {
+	return &listener.FilterChain{
+		FilterChainMatch: &listener.FilterChainMatch{TransportProtocol: "tls", ApplicationProtocols: alpn},
+		TransportSocket:  &core.TransportSocket{Name: wellknown.TransportSocketTLS},
+	}
+}
+
+func plaintextChain() *listener.FilterChain {
+	return &listener.FilterChain{FilterChainMatch: &listener.FilterChainMatch{TransportProtocol: "raw_buffer"}}
+}
+
+func passthroughChain() *listener.FilterChain {
+	return &listener.FilterChain{FilterChainMatch: &listener.FilterChainMatch{TransportProtocol: "tls"}}
+}
+
+func TestServerSettingsForPort(t *testing.T) {
+	strict := inbound(8080, mtlsChain())
+	assert.Equal(t, ServerSettingsForPort(strict, 8080), ServerSettings{MTLS: true})
+
+	permissive := inbound(8080, mtlsChain("istio-peer-exchange", "istio"), plaintextChain(), passthroughChain())
+	assert.Equal(t, ServerSettingsForPort(permissive, 8080), ServerSettings{
+		MTLS:           true,
+		MTLSALPN:       []string{"istio-peer-exchange", "istio"},
+		Plaintext:      true,
+		TLSPassthrough: true,
+	})
+
+	// Undeclared ports fall back to the passthrough filter chains.
+	l := inbound(8080, mtlsChain())
+	l.FilterChains = append(l.FilterChains, plaintextChain())
+	assert.Equal(t, ServerSettingsForPort(l, 9090), ServerSettings{Plaintext: true})
+}
+
+func TestEvaluate(t *testing.T) {
+	disable := networking.ClientTLSSettings_DISABLE
+	simple := networking.ClientTLSSettings_SIMPLE
+	mtls := ClientSettings{Mode: ClientIstioMTLS, AutoMTLS: true, ALPN: util.ALPNInMeshWithMxc}
+	cases := []struct {
+		name     string
+		expected Expectation
+		client   ClientSettings
+		server   *ServerSettings
+		ok       bool
+		finding  string
+	}{
+		{
+			name:     "auto mTLS to strict",
+			expected: Expectation{ServerHasSidecar: true, PeerAuthentication: model.MTLSStrict},
+			client:   mtls,
+			server:   &ServerSettings{MTLS: true},
+			ok:       true,
+		},
+		{
+			name:     "mTLS without istio ALPN is passed through",
+			expected: Expectation{ServerHasSidecar: true, PeerAuthentication: model.MTLSPermissive},
+			client:   ClientSettings{Mode: ClientIstioMTLS, ALPN: []string{"h2"}},
+			server:   &ServerSettings{MTLS: true, MTLSALPN: []string{"istio-peer-exchange", "istio"}, Plaintext: true, TLSPassthrough: true},
+			finding:  "treats it as raw TLS",
+		},
+		{
+			name:     "mTLS to a workload without sidecar",
+			expected: Expectation{DestinationRule: "foo/httpbin", TLSMode: ptr.Of(networking.ClientTLSSettings_ISTIO_MUTUAL)},
+			client:   ClientSettings{Mode: ClientIstioMTLS, ALPN: util.ALPNInMeshWithMxc},
+			finding:  "inject a sidecar",
+		},
+		{
+			name:     "DestinationRule disables TLS to strict",
+			expected: Expectation{DestinationRule: "foo/httpbin", TLSMode: &disable, ServerHasSidecar: true, PeerAuthentication: model.MTLSStrict},
+			client:   ClientSettings{Mode: ClientPlaintext},
+			server:   &ServerSettings{MTLS: true},
+			finding:  "disables TLS while PeerAuthentication mode is STRICT",
+		},
+		{
+			name:     "raw TLS to strict",
+			expected: Expectation{DestinationRule: "foo/httpbin", TLSMode: &simple, ServerHasSidecar: true, PeerAuthentication: model.MTLSStrict},
+			client:   ClientSettings{Mode: ClientTLS},
+			server:   &ServerSettings{MTLS: true},
+			finding:  "originates raw TLS",
+		},
+		{
+			name:     "stale client",
+			expected: Expectation{ServerHasSidecar: true, PeerAuthentication: model.MTLSPermissive},
+			client:   ClientSettings{Mode: ClientPlaintext, AutoMTLS: true},
+			server:   &ServerSettings{MTLS: true, Plaintext: true, TLSPassthrough: true},
+			ok:       true,
+			finding:  "may not have received the latest configuration",
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Evaluate(tt.expected, tt.client, tt.server)
+			assert.Equal(t, got.OK, tt.ok)
+			if tt.finding == "" {
+				assert.Equal(t, len(got.Findings), 0)
+				return
+			}
+			found := false
+			for _, f := range got.Findings {
+				if strings.Contains(f, tt.finding) {
+					found = true
+				}
+			}
+			if !found {
+				t.Fatalf("expected a finding containing %q, got %v", tt.finding, got.Findings)
+			}
+		})
+	}
+}
+
+func TestIstioConfig(t *testing.T) {
+	c := &cluster.Cluster{Metadata: &core.Metadata{FilterMetadata: map[string]*structpb.Struct{
+		util.IstioMetadataKey: {Fields: map[string]*structpb.Value{
+			"config": structpb.NewStringValue("/apis/networking.istio.io/v1/namespaces/foo/destination-rule/httpbin"),
+		}},
+	}}}
+	ns, name, ok := istioConfig(c)
+	assert.Equal(t, ok, true)
+	assert.Equal(t, ns+"/"+name, "foo/httpbin")
+
+	_, _, ok = istioConfig(&cluster.Cluster{})
+	assert.Equal(t, ok, false)
+}
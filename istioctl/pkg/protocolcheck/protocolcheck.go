@@ -0,0 +1,364 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protocolcheck
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	cluster "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	listener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	klabels "k8s.io/apimachinery/pkg/labels"
+
+	"istio.io/api/label"
+	"istio.io/istio/istioctl/pkg/cli"
+	"istio.io/istio/istioctl/pkg/completion"
+	"istio.io/istio/istioctl/pkg/util/configdump"
+	"istio.io/istio/pilot/pkg/config/kube/crdclient"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/security/authn"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/kube"
+)
+
+const virtualInboundListener = "virtualInbound"
+
+func Cmd(ctx cli.Context) *cobra.Command {
+	var servicePort int32
+	cmd := &cobra.Command{
+		Use:   "protocol-check <source-pod>[.<namespace>] <destination-pod>[.<namespace>]",
+		Short: "Explains the TLS, mTLS and ALPN negotiation between two workloads",
+		Long: `Explains how connections between two workloads are secured, by comparing the TLS settings the source
+proxy uses for each service selecting the destination pod with what the destination proxy accepts on the
+corresponding port.
+
+The outcome is predicted from the configuration of both proxies: the client side TLS mode, ALPN and SNI, and the
+server side filter chains, which only terminate istio mTLS for the ALPN values istio proxies advertise (such as
+istio-peer-exchange). Both are compared with the outcome expected from the DestinationRule and PeerAuthentication
+applying to the connection, and each mismatch is explained. The TLS handshake counters of the source proxy
+for the destination are shown to confirm the outcome of actual connections.`,
+		Example: `  # Check connections from a sleep pod to all ports of the services selecting an httpbin pod
+  istioctl experimental protocol-check sleep-5d8c9b8b9b-x2v4q httpbin-7f8c9b8b9b-k9s8d.foo
+
+  # Only check connections through service port 8000
+  istioctl experimental protocol-check sleep-5d8c9b8b9b-x2v4q httpbin-7f8c9b8b9b-k9s8d.foo --port 8000`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 2 {
+				cmd.Println(cmd.UsageString())
+				return fmt.Errorf("protocol-check requires a source and a destination pod")
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			kubeClient, err := ctx.CLIClient()
+			if err != nil {
+				return err
+			}
+			srcName, srcNamespace, err := ctx.InferPodInfoFromTypedResource(args[0], ctx.NamespaceOrDefault(ctx.Namespace()))
+			if err != nil {
+				return err
+			}
+			dstName, dstNamespace, err := ctx.InferPodInfoFromTypedResource(args[1], ctx.NamespaceOrDefault(ctx.Namespace()))
+			if err != nil {
+				return err
+			}
+			return run(cmd.OutOrStdout(), kubeClient, ctx.IstioNamespace(), srcName, srcNamespace, dstName, dstNamespace, servicePort)
+		},
+		ValidArgsFunction: completion.ValidPodsNameArgs(ctx),
+	}
+	cmd.Flags().Int32Var(&servicePort, "port", 0, "Only check the service port with this number")
+	return cmd
+}
+
+func run(w io.Writer, kubeClient kube.CLIClient, istioNamespace, srcName, srcNamespace, dstName, dstNamespace string, servicePort int32) error {
+	dstPod, err := kubeClient.Kube().CoreV1().Pods(dstNamespace).Get(context.TODO(), dstName, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to retrieve destination pod %s.%s: %v", dstName, dstNamespace, err)
+	}
+	services, err := kubeClient.Kube().CoreV1().Services(dstNamespace).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to list services in %s: %v", dstNamespace, err)
+	}
+
+	srcDump, err := configDump(kubeClient, srcName, srcNamespace)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve the source proxy configuration: %v", err)
+	}
+	clusters, err := clustersByName(srcDump)
+	if err != nil {
+		return err
+	}
+
+	var inbound *listener.Listener
+	if hasSidecar(dstPod) {
+		dstDump, err := configDump(kubeClient, dstName, dstNamespace)
+		if err != nil {
+			return fmt.Errorf("failed to retrieve the destination proxy configuration: %v", err)
+		}
+		if inbound, err = inboundListener(dstDump); err != nil {
+			return err
+		}
+	}
+
+	peerAuthn, err := peerAuthentication(kubeClient, istioNamespace, dstPod)
+	if err != nil {
+		return err
+	}
+
+	checked := 0
+	for _, svc := range services.Items {
+		if len(svc.Spec.Selector) == 0 || !klabels.SelectorFromSet(svc.Spec.Selector).Matches(klabels.Set(dstPod.Labels)) {
+			continue
+		}
+		for _, port := range svc.Spec.Ports {
+			if servicePort != 0 && port.Port != servicePort {
+				continue
+			}
+			c := findCluster(clusters, fmt.Sprintf("outbound|%d||%s.%s.svc.", port.Port, svc.Name, svc.Namespace))
+			if c == nil {
+				_, _ = fmt.Fprintf(w, "Service %s.%s port %d: no cluster found in the source proxy; the service may not be visible to it\n\n",
+					svc.Name, svc.Namespace, port.Port)
+				continue
+			}
+			targetPort := resolveTargetPort(port, dstPod)
+			expected := Expectation{
+				ServerHasSidecar:   inbound != nil,
+				PeerAuthentication: peerAuthn.Mode,
+			}
+			if mode, ok := peerAuthn.PerPort[targetPort]; ok {
+				expected.PeerAuthentication = mode
+			}
+			if err := destinationRuleTLS(kubeClient, c, port.Port, &expected); err != nil {
+				return err
+			}
+			client, err := ClientSettingsForCluster(c, dstPod.Labels[label.SecurityTlsMode.Name] == model.IstioMutualTLSModeLabel)
+			if err != nil {
+				return err
+			}
+			var server *ServerSettings
+			if inbound != nil {
+				s := ServerSettingsForPort(inbound, targetPort)
+				server = &s
+			}
+			printResult(w, svc, port, targetPort, c.Name, expected, client, server, Evaluate(expected, client, server))
+			printHandshakes(w, kubeClient, srcName, srcNamespace, c.Name)
+			_, _ = fmt.Fprintln(w)
+			checked++
+		}
+	}
+	if checked == 0 {
+		return fmt.Errorf("no service port selecting pod %s.%s found in the source proxy configuration", dstName, dstNamespace)
+	}
+	return nil
+}
+
+func printResult(w io.Writer, svc corev1.Service, port corev1.ServicePort, targetPort uint32, clusterName string,
+	expected Expectation, client ClientSettings, server *ServerSettings, result Result,
+) {
+	_, _ = fmt.Fprintf(w, "Service %s.%s port %d (target port %d)\n", svc.Name, svc.Namespace, port.Port, targetPort)
+	_, _ = fmt.Fprintf(w, "  Client cluster:     %s\n", clusterName)
+	_, _ = fmt.Fprintf(w, "  Client sends:       %s\n", client)
+	if server == nil {
+		_, _ = fmt.Fprintf(w, "  Server accepts:     no sidecar, connections go directly to the application\n")
+	} else {
+		_, _ = fmt.Fprintf(w, "  Server accepts:     %s\n", server)
+	}
+	dr := "none"
+	if expected.DestinationRule != "" {
+		dr = expected.DestinationRule
+		if expected.TLSMode != nil {
+			dr += " (tls mode " + expected.TLSMode.String() + ")"
+		}
+	}
+	_, _ = fmt.Fprintf(w, "  DestinationRule:    %s\n", dr)
+	_, _ = fmt.Fprintf(w, "  PeerAuthentication: %s\n", expected.PeerAuthentication)
+	status := "FAIL"
+	if result.OK {
+		status = "OK"
+	}
+	_, _ = fmt.Fprintf(w, "  Outcome:            %s: %s\n", status, result.Outcome)
+	for _, f := range result.Findings {
+		_, _ = fmt.Fprintf(w, "    - %s\n", f)
+	}
+}
+
+// printHandshakes prints the TLS handshake counters the source proxy reports for the cluster.
+func printHandshakes(w io.Writer, kubeClient kube.CLIClient, podName, podNamespace, clusterName string) {
+	filter := fmt.Sprintf(`^cluster\.%s\.(ssl\.(handshake|connection_error|fail_verify_.*)|upstream_cx_(total|connect_fail))$`,
+		regexp.QuoteMeta(clusterName))
+	stats, err := kubeClient.EnvoyDo(context.TODO(), podName, podNamespace, "GET", "stats?filter="+url.QueryEscape(filter))
+	if err != nil {
+		_, _ = fmt.Fprintf(w, "  Observed:           failed to retrieve stats from the source proxy: %v\n", err)
+		return
+	}
+	var counters []string
+	for _, line := range strings.Split(strings.TrimSpace(string(stats)), "\n") {
+		name, value, ok := strings.Cut(line, ": ")
+		if !ok {
+			continue
+		}
+		counters = append(counters, strings.TrimPrefix(name, "cluster."+clusterName+".")+"="+value)
+	}
+	sort.Strings(counters)
+	if len(counters) == 0 {
+		_, _ = fmt.Fprintf(w, "  Observed:           no connections made yet\n")
+		return
+	}
+	_, _ = fmt.Fprintf(w, "  Observed:           %s\n", strings.Join(counters, " "))
+}
+
+func configDump(kubeClient kube.CLIClient, podName, podNamespace string) (*configdump.Wrapper, error) {
+	b, err := kubeClient.EnvoyDo(context.TODO(), podName, podNamespace, "GET", "config_dump")
+	if err != nil {
+		return nil, err
+	}
+	cd := &configdump.Wrapper{}
+	if err := cd.UnmarshalJSON(b); err != nil {
+		return nil, err
+	}
+	return cd, nil
+}
+
+func clustersByName(cd *configdump.Wrapper) (map[string]*cluster.Cluster, error) {
+	dump, err := cd.GetDynamicClusterDump(false)
+	if err != nil {
+		return nil, err
+	}
+	res := map[string]*cluster.Cluster{}
+	for _, dac := range dump.GetDynamicActiveClusters() {
+		c := &cluster.Cluster{}
+		if err := dac.GetCluster().UnmarshalTo(c); err != nil {
+			return nil, err
+		}
+		res[c.Name] = c
+	}
+	return res, nil
+}
+
+// findCluster returns the cluster whose name starts with the prefix, which omits the domain suffix.
+func findCluster(clusters map[string]*cluster.Cluster, prefix string) *cluster.Cluster {
+	for name, c := range clusters {
+		if strings.HasPrefix(name, prefix) {
+			return c
+		}
+	}
+	return nil
+}
+
+func inboundListener(cd *configdump.Wrapper) (*listener.Listener, error) {
+	dump, err := cd.GetDynamicListenerDump(false)
+	if err != nil {
+		return nil, err
+	}
+	for _, dl := range dump.GetDynamicListeners() {
+		l := &listener.Listener{}
+		if err := dl.GetActiveState().GetListener().UnmarshalTo(l); err != nil {
+			return nil, err
+		}
+		if l.Name == virtualInboundListener {
+			return l, nil
+		}
+	}
+	return nil, fmt.Errorf("listener %s not found in the destination proxy configuration", virtualInboundListener)
+}
+
+func hasSidecar(pod *corev1.Pod) bool {
+	for _, c := range append(pod.Spec.InitContainers, pod.Spec.Containers...) {
+		if c.Name == "istio-proxy" {
+			return true
+		}
+	}
+	return false
+}
+
+func resolveTargetPort(port corev1.ServicePort, pod *corev1.Pod) uint32 {
+	if port.TargetPort.IntValue() != 0 {
+		return uint32(port.TargetPort.IntValue())
+	}
+	if port.TargetPort.String() != "" && port.TargetPort.String() != "0" {
+		for _, c := range pod.Spec.Containers {
+			for _, p := range c.Ports {
+				if p.Name == port.TargetPort.String() {
+					return uint32(p.ContainerPort)
+				}
+			}
+		}
+	}
+	return uint32(port.Port)
+}
+
+// peerAuthentication computes the effective PeerAuthentication of the pod from the PeerAuthentications in its
+// namespace and in the root namespace, assumed to be the Istio namespace.
+func peerAuthentication(kubeClient kube.CLIClient, rootNamespace string, pod *corev1.Pod) (authn.MergedPeerAuthentication, error) {
+	var cfgs []*config.Config
+	for _, ns := range []string{rootNamespace, pod.Namespace} {
+		pas, err := kubeClient.Istio().SecurityV1().PeerAuthentications(ns).List(context.TODO(), metav1.ListOptions{})
+		if err != nil {
+			return authn.MergedPeerAuthentication{}, fmt.Errorf("failed to list PeerAuthentications in %s: %v", ns, err)
+		}
+		for _, pa := range pas.Items {
+			if !klabels.SelectorFromSet(pa.Spec.GetSelector().GetMatchLabels()).Matches(klabels.Set(pod.Labels)) {
+				continue
+			}
+			cfg := crdclient.TranslateObject(pa, config.GroupVersionKind(pa.GroupVersionKind()), "")
+			cfgs = append(cfgs, &cfg)
+		}
+		if rootNamespace == pod.Namespace {
+			break
+		}
+	}
+	return authn.ComposePeerAuthentication(rootNamespace, cfgs), nil
+}
+
+// destinationRuleTLS records the DestinationRule applied to the cluster and the TLS mode it sets for the port.
+func destinationRuleTLS(kubeClient kube.CLIClient, c *cluster.Cluster, port int32, expected *Expectation) error {
+	ns, name, ok := istioConfig(c)
+	if !ok {
+		return nil
+	}
+	expected.DestinationRule = ns + "/" + name
+	dr, err := kubeClient.Istio().NetworkingV1().DestinationRules(ns).Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to retrieve DestinationRule %s: %v", expected.DestinationRule, err)
+	}
+	policy := dr.Spec.GetTrafficPolicy()
+	// The subset is the third field of the cluster name, outbound|port|subset|host.
+	if parts := strings.Split(c.Name, "|"); len(parts) == 4 && parts[2] != "" {
+		for _, s := range dr.Spec.GetSubsets() {
+			if s.GetName() == parts[2] && s.GetTrafficPolicy() != nil {
+				policy = s.GetTrafficPolicy()
+			}
+		}
+	}
+	tls := policy.GetTls()
+	for _, pls := range policy.GetPortLevelSettings() {
+		if pls.GetPort().GetNumber() == uint32(port) && pls.GetTls() != nil {
+			tls = pls.GetTls()
+		}
+	}
+	if tls != nil {
+		mode := tls.GetMode()
+		expected.TLSMode = &mode
+	}
+	return nil
+}
@@ -0,0 +1,237 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package proxyanomaly
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	klabels "k8s.io/apimachinery/pkg/labels"
+
+	"istio.io/api/annotation"
+	"istio.io/istio/istioctl/pkg/cli"
+	"istio.io/istio/istioctl/pkg/configsize"
+	"istio.io/istio/istioctl/pkg/util/configdump"
+	"istio.io/istio/pkg/kube"
+	"istio.io/istio/pkg/kube/labels"
+)
+
+// podMetricsList is the subset of the PodMetricsList of the metrics API read by the command.
+type podMetricsList struct {
+	Items []struct {
+		Metadata   metav1.ObjectMeta `json:"metadata"`
+		Containers []struct {
+			Name  string              `json:"name"`
+			Usage corev1.ResourceList `json:"usage"`
+		} `json:"containers"`
+	} `json:"items"`
+}
+
+func Cmd(ctx cli.Context) *cobra.Command {
+	var threshold float64
+	cmd := &cobra.Command{
+		Use:   "proxy-anomalies",
+		Short: "Finds the proxies using much more CPU or memory than their peers, and their likely cause",
+		Long: `Scans the CPU and memory usage of the proxies of the mesh, as reported by the metrics API (metrics-server), and
+flags the proxies using at least --threshold times the median usage of their peers: the proxies of the pods of the same
+canonical service and revision.
+
+The configuration size, active connections and access log writes of the flagged proxies are then compared to those
+of their peers to suggest the likely cause, such as a missing Sidecar scope, huge route tables or the access log
+volume.`,
+		Example: `  # Find the proxies of the mesh using at least twice the CPU or memory of their peers
+  istioctl experimental proxy-anomalies
+
+  # Find the proxies of the default namespace using at least three times the CPU or memory of their peers
+  istioctl x proxy-anomalies -n default --threshold 3`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if threshold <= 1 {
+				return fmt.Errorf("--threshold must be greater than 1, got %v", threshold)
+			}
+			kubeClient, err := ctx.CLIClient()
+			if err != nil {
+				return err
+			}
+			pods, err := proxyPods(kubeClient, ctx.Namespace())
+			if err != nil {
+				return err
+			}
+			proxies, err := proxyUsage(kubeClient, ctx.Namespace(), pods)
+			if err != nil {
+				return err
+			}
+			outliers := Outliers(proxies, threshold)
+			if err := collectSignals(kubeClient, ctx.IstioNamespace(), pods, outliers); err != nil {
+				return err
+			}
+			printOutliers(cmd.OutOrStdout(), outliers, threshold, len(proxies))
+			return nil
+		},
+	}
+	cmd.Flags().Float64Var(&threshold, "threshold", 2,
+		"Flag the proxies using at least this many times the median CPU or memory usage of their peers")
+	return cmd
+}
+
+// proxyPods returns the running pods with a sidecar in the namespace, or in all namespaces when none is set, by name.
+func proxyPods(kubeClient kube.CLIClient, namespace string) (map[string]*corev1.Pod, error) {
+	pods, err := kubeClient.Kube().CoreV1().Pods(namespace).List(context.Background(), metav1.ListOptions{
+		FieldSelector: "status.phase=Running",
+	})
+	if err != nil {
+		return nil, err
+	}
+	res := map[string]*corev1.Pod{}
+	for i := range pods.Items {
+		if _, ok := pods.Items[i].Annotations[annotation.SidecarStatus.Name]; ok {
+			res[pods.Items[i].Name+"."+pods.Items[i].Namespace] = &pods.Items[i]
+		}
+	}
+	return res, nil
+}
+
+// proxyUsage returns the usage of the istio-proxy containers of the pods, from the metrics API.
+func proxyUsage(kubeClient kube.CLIClient, namespace string, pods map[string]*corev1.Pod) ([]*Proxy, error) {
+	path := "/apis/metrics.k8s.io/v1beta1/pods"
+	if namespace != "" {
+		path = "/apis/metrics.k8s.io/v1beta1/namespaces/" + namespace + "/pods"
+	}
+	b, err := kubeClient.Kube().CoreV1().RESTClient().Get().AbsPath(path).DoRaw(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve the pod metrics, is metrics-server installed? %v", err)
+	}
+	metrics := podMetricsList{}
+	if err := json.Unmarshal(b, &metrics); err != nil {
+		return nil, fmt.Errorf("failed to parse the pod metrics: %v", err)
+	}
+	var proxies []*Proxy
+	for _, item := range metrics.Items {
+		pod, ok := pods[item.Metadata.Name+"."+item.Metadata.Namespace]
+		if !ok {
+			continue
+		}
+		for _, c := range item.Containers {
+			if c.Name != "istio-proxy" {
+				continue
+			}
+			name, revision := labels.CanonicalService(pod.Labels, strings.TrimSuffix(pod.GenerateName, "-"))
+			proxies = append(proxies, &Proxy{
+				Name:      pod.Name,
+				Namespace: pod.Namespace,
+				Workload:  name + "/" + revision,
+				CPU:       c.Usage.Cpu().MilliValue(),
+				Memory:    c.Usage.Memory().Value(),
+			})
+		}
+	}
+	return proxies, nil
+}
+
+// collectSignals collects the signals of the outliers and their peers.
+func collectSignals(kubeClient kube.CLIClient, rootNamespace string, pods map[string]*corev1.Pod, outliers []Outlier) error {
+	if len(outliers) == 0 {
+		return nil
+	}
+	sidecars, err := kubeClient.Istio().NetworkingV1alpha3().Sidecars(metav1.NamespaceAll).List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	scoped := func(pod *corev1.Pod) bool {
+		for _, sc := range sidecars.Items {
+			selector := sc.Spec.GetWorkloadSelector().GetLabels()
+			if sc.Namespace == pod.Namespace && klabels.SelectorFromSet(selector).Matches(klabels.Set(pod.Labels)) {
+				return true
+			}
+			if sc.Namespace == rootNamespace && len(selector) == 0 {
+				return true
+			}
+		}
+		return false
+	}
+	for _, o := range outliers {
+		for _, p := range append([]*Proxy{o.Proxy}, o.Peers...) {
+			if p.Signals != nil {
+				continue
+			}
+			s, err := proxySignals(kubeClient, p)
+			if err != nil {
+				return fmt.Errorf("failed to collect the signals of %s.%s: %v", p.Name, p.Namespace, err)
+			}
+			s.SidecarScoped = scoped(pods[p.Name+"."+p.Namespace])
+			p.Signals = s
+		}
+	}
+	return nil
+}
+
+func proxySignals(kubeClient kube.CLIClient, p *Proxy) (*Signals, error) {
+	b, err := kubeClient.EnvoyDo(context.TODO(), p.Name, p.Namespace, "GET", "config_dump")
+	if err != nil {
+		return nil, err
+	}
+	cd := &configdump.Wrapper{}
+	if err := cd.UnmarshalJSON(b); err != nil {
+		return nil, err
+	}
+	s := &Signals{}
+	clusters, err := cd.GetDynamicClusterDump(false)
+	if err != nil {
+		return nil, err
+	}
+	s.Clusters = len(clusters.GetDynamicActiveClusters())
+	if s.Routes, err = configsize.CountRoutes(cd); err != nil {
+		return nil, err
+	}
+	stats, err := kubeClient.EnvoyDo(context.TODO(), p.Name, p.Namespace, "GET", "stats?filter="+url.QueryEscape(statsFilter))
+	if err != nil {
+		return nil, err
+	}
+	s.Connections, s.AccessLogWrites = parseStats(stats)
+	return s, nil
+}
+
+func formatUsage(resource string, v float64) string {
+	if resource == resourceCPU {
+		return fmt.Sprintf("%.0fm", v)
+	}
+	return fmt.Sprintf("%.0fMi", v/(1<<20))
+}
+
+func printOutliers(w io.Writer, outliers []Outlier, threshold float64, scanned int) {
+	if len(outliers) == 0 {
+		_, _ = fmt.Fprintf(w, "No proxy uses %vx the CPU or memory of its peers, out of %d proxies.\n", threshold, scanned)
+		return
+	}
+	for _, o := range outliers {
+		_, _ = fmt.Fprintf(w, "%s.%s (%s): %s %s, %.1fx the median of %d peers (%s)\n", o.Proxy.Name, o.Proxy.Namespace,
+			o.Proxy.Workload, o.Resource, formatUsage(o.Resource, o.Proxy.usage(o.Resource)), o.Ratio(), len(o.Peers),
+			formatUsage(o.Resource, o.PeerMedian))
+		causes := Causes(o, threshold)
+		if len(causes) == 0 {
+			_, _ = fmt.Fprintln(w, "  no likely cause found in the configuration size, connections or access log volume")
+		}
+		for _, c := range causes {
+			_, _ = fmt.Fprintf(w, "  likely cause: %s\n", c)
+		}
+	}
+}
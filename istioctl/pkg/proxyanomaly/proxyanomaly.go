@@ -0,0 +1,194 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package proxyanomaly
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	resourceCPU    = "cpu"
+	resourceMemory = "memory"
+)
+
+// Proxy is the resource usage of the proxy of a pod.
+type Proxy struct {
+	Name      string
+	Namespace string
+	// Workload identifies the peers of the proxy: the proxies of the pods of the same canonical service and revision.
+	Workload string
+	// CPU is in millicores.
+	CPU int64
+	// Memory is in bytes.
+	Memory int64
+	// Signals are collected only for the proxies of workloads with an outlier, nil otherwise.
+	Signals *Signals
+}
+
+func (p *Proxy) usage(resource string) float64 {
+	if resource == resourceCPU {
+		return float64(p.CPU)
+	}
+	return float64(p.Memory)
+}
+
+// Signals are the measures of the proxy which commonly explain its resource usage.
+type Signals struct {
+	Clusters int
+	Routes   int
+	// Connections are the downstream and upstream connections which are active.
+	Connections int
+	// AccessLogWrites are the writes to the access log file since the proxy started.
+	AccessLogWrites int
+	// SidecarScoped is whether a Sidecar limits the configuration sent to the proxy.
+	SidecarScoped bool
+}
+
+// Outlier is a proxy using much more of a resource than its peers.
+type Outlier struct {
+	Proxy    *Proxy
+	Resource string
+	// PeerMedian is the median usage of the resource by the peers.
+	PeerMedian float64
+	Peers      []*Proxy
+}
+
+// Ratio is how many times the median usage of the peers the proxy uses.
+func (o Outlier) Ratio() float64 {
+	return o.Proxy.usage(o.Resource) / o.PeerMedian
+}
+
+// Outliers returns the proxies using at least threshold times the median usage of CPU or memory of their peers.
+// Proxies without peers are not compared.
+func Outliers(proxies []*Proxy, threshold float64) []Outlier {
+	workloads := map[string][]*Proxy{}
+	for _, p := range proxies {
+		key := p.Namespace + "/" + p.Workload
+		workloads[key] = append(workloads[key], p)
+	}
+	var outliers []Outlier
+	for _, group := range workloads {
+		if len(group) < 2 {
+			continue
+		}
+		for _, p := range group {
+			peers := make([]*Proxy, 0, len(group)-1)
+			for _, peer := range group {
+				if peer != p {
+					peers = append(peers, peer)
+				}
+			}
+			for _, resource := range []string{resourceCPU, resourceMemory} {
+				m := median(peers, func(peer *Proxy) float64 { return peer.usage(resource) })
+				if m > 0 && p.usage(resource) >= threshold*m {
+					outliers = append(outliers, Outlier{Proxy: p, Resource: resource, PeerMedian: m, Peers: peers})
+				}
+			}
+		}
+	}
+	sort.Slice(outliers, func(i, j int) bool {
+		return outliers[i].Ratio() > outliers[j].Ratio()
+	})
+	return outliers
+}
+
+// Causes returns the likely causes of the usage of the outlier: the signals of the proxy which are at least threshold
+// times the median of its peers.
+func Causes(o Outlier, threshold float64) []string {
+	s := o.Proxy.Signals
+	if s == nil {
+		return nil
+	}
+	signal := func(value int, get func(*Signals) int) (float64, bool) {
+		m := median(o.Peers, func(peer *Proxy) float64 {
+			if peer.Signals == nil {
+				return 0
+			}
+			return float64(get(peer.Signals))
+		})
+		// A signal absent from the peers stands out as soon as the proxy has it.
+		ratio := float64(value) / math.Max(m, 1)
+		return ratio, value > 0 && ratio >= threshold
+	}
+
+	var causes []string
+	if ratio, ok := signal(s.Clusters, func(s *Signals) int { return s.Clusters }); ok {
+		cause := fmt.Sprintf("configuration size: %d clusters, %.1fx the peers", s.Clusters, ratio)
+		if !s.SidecarScoped {
+			cause += "; no Sidecar scopes the configuration of the proxy, which receives every service of the mesh"
+		}
+		causes = append(causes, cause)
+	}
+	if ratio, ok := signal(s.Routes, func(s *Signals) int { return s.Routes }); ok {
+		causes = append(causes, fmt.Sprintf("route tables: %d routes, %.1fx the peers", s.Routes, ratio))
+	}
+	if ratio, ok := signal(s.Connections, func(s *Signals) int { return s.Connections }); ok {
+		causes = append(causes, fmt.Sprintf("connections: %d active connections, %.1fx the peers", s.Connections, ratio))
+	}
+	if ratio, ok := signal(s.AccessLogWrites, func(s *Signals) int { return s.AccessLogWrites }); ok {
+		causes = append(causes, fmt.Sprintf("access log volume: %d access log writes, %.1fx the peers; "+
+			"consider filtering or sampling access logs with the Telemetry API", s.AccessLogWrites, ratio))
+	}
+	return causes
+}
+
+func median(proxies []*Proxy, value func(*Proxy) float64) float64 {
+	if len(proxies) == 0 {
+		return 0
+	}
+	values := make([]float64, 0, len(proxies))
+	for _, p := range proxies {
+		values = append(values, value(p))
+	}
+	sort.Float64s(values)
+	mid := len(values) / 2
+	if len(values)%2 == 0 {
+		return (values[mid-1] + values[mid]) / 2
+	}
+	return values[mid]
+}
+
+// statsFilter selects the stats parseStats reads.
+const statsFilter = `^(server\.total_connections|cluster\..*\.upstream_cx_active|filesystem\.write_completed)$`
+
+// parseStats returns the active connections and the access log writes of the stats of a proxy, in the text format.
+func parseStats(b []byte) (connections, accessLogWrites int) {
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	for scanner.Scan() {
+		name, value, ok := strings.Cut(scanner.Text(), ": ")
+		if !ok {
+			continue
+		}
+		v, err := strconv.Atoi(value)
+		if err != nil {
+			continue
+		}
+		switch {
+		case name == "server.total_connections":
+			connections += v
+		case strings.HasPrefix(name, "cluster.") && strings.HasSuffix(name, ".upstream_cx_active"):
+			connections += v
+		case name == "filesystem.write_completed":
+			accessLogWrites += v
+		}
+	}
+	return connections, accessLogWrites
+}
@@ -0,0 +1,76 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package proxyanomaly
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"istio.io/istio/pkg/test/util/assert"
+)
+
+func proxy(name, workload string, cpu, memoryMi int64, signals *Signals) *Proxy {
+	return &Proxy{Name: name, Namespace: "shop", Workload: workload, CPU: cpu, Memory: memoryMi << 20, Signals: signals}
+}
+
+func TestOutliers(t *testing.T) {
+	cart := proxy("cart-3", "cart/v1", 40, 400, &Signals{Clusters: 900, Routes: 40, Connections: 12})
+	proxies := []*Proxy{
+		proxy("cart-1", "cart/v1", 35, 100, &Signals{Clusters: 200, Routes: 40, Connections: 10, SidecarScoped: true}),
+		proxy("cart-2", "cart/v1", 45, 110, &Signals{Clusters: 200, Routes: 40, Connections: 14, SidecarScoped: true}),
+		cart,
+		proxy("checkout-1", "checkout/v1", 20, 80, &Signals{Connections: 20}),
+		proxy("checkout-2", "checkout/v1", 90, 90, &Signals{Connections: 30, AccessLogWrites: 50000}),
+		proxy("checkout-3", "checkout/v1", 25, 85, &Signals{Connections: 25}),
+		// Without peers, the usage of a proxy is not compared.
+		proxy("search-1", "search/v1", 500, 900, nil),
+	}
+
+	outliers := Outliers(proxies, 2)
+	var flagged []string
+	for _, o := range outliers {
+		flagged = append(flagged, o.Proxy.Name+" "+o.Resource)
+	}
+	assert.Equal(t, flagged, []string{"checkout-2 cpu", "cart-3 memory"})
+	assert.Equal(t, outliers[1].PeerMedian, float64(105<<20))
+
+	assert.Equal(t, Causes(outliers[1], 2), []string{
+		"configuration size: 900 clusters, 4.5x the peers; no Sidecar scopes the configuration of the proxy, " +
+			"which receives every service of the mesh",
+	})
+	// The access logs of the peers are not written, so any write stands out.
+	assert.Equal(t, Causes(outliers[0], 2), []string{
+		"access log volume: 50000 access log writes, 50000.0x the peers; consider filtering or sampling access logs with the Telemetry API",
+	})
+
+	var out bytes.Buffer
+	printOutliers(&out, outliers, 2, len(proxies))
+	assert.Equal(t, strings.Split(out.String(), "\n")[2], "cart-3.shop (cart/v1): memory 400Mi, 3.8x the median of 2 peers (105Mi)")
+
+	out.Reset()
+	printOutliers(&out, nil, 2, len(proxies))
+	assert.Equal(t, out.String(), "No proxy uses 2x the CPU or memory of its peers, out of 7 proxies.\n")
+}
+
+func TestParseStats(t *testing.T) {
+	connections, writes := parseStats([]byte(`cluster.outbound|80||cart.shop.svc.cluster.local.upstream_cx_active: 4
+cluster.xds-grpc.upstream_cx_active: 1
+filesystem.write_completed: 1200
+server.total_connections: 7
+`))
+	assert.Equal(t, connections, 12)
+	assert.Equal(t, writes, 1200)
+}
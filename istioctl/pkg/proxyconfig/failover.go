@@ -0,0 +1,100 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package proxyconfig
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"istio.io/istio/istioctl/pkg/cli"
+	"istio.io/istio/istioctl/pkg/completion"
+	"istio.io/istio/istioctl/pkg/writer/envoy/configdump"
+)
+
+// FailoverCmd shows the locality/priority ordering a proxy uses for a destination and simulates failover from its
+// endpoint configuration, without modifying the proxy.
+func FailoverCmd(ctx cli.Context) *cobra.Command {
+	var podName, podNamespace string
+	var filter configdump.FailoverFilter
+
+	failoverCmd := &cobra.Command{
+		Use:   "failover [<type>/]<name>[.<namespace>] --cluster <cluster-name>",
+		Short: "Shows the failover ordering of a destination's endpoints as seen by the specified pod, and simulates failover",
+		Long: `Shows the priority and locality ordering the Envoy instance in the specified pod uses for a destination,
+and the share of traffic assigned to each priority.
+
+With --simulate-failed-locality or --simulate-failed-cluster, endpoints in the given localities or Istio clusters
+are treated as unhealthy and the resulting traffic shift is computed from the proxy's endpoint configuration,
+following Envoy's priority load calculation. This is a simulation performed locally: the Envoy admin API cannot
+mark endpoints unhealthy, so the proxy is not modified and the traffic it actually sends is not observed.`,
+		Example: `  # Show the failover ordering for the reviews service from a pod.
+  istioctl experimental failover <pod-name[.namespace]> --cluster "outbound|9080||reviews.default.svc.cluster.local"
+
+  # Simulate the loss of the us-east1 region, without modifying the proxy.
+  istioctl experimental failover <pod-name[.namespace]> --cluster "outbound|9080||reviews.default.svc.cluster.local" \
+    --simulate-failed-locality us-east1
+
+  # Simulate the loss of a remote cluster in a multi-network mesh.
+  istioctl experimental failover <pod-name[.namespace]> --cluster "outbound|9080||reviews.default.svc.cluster.local" \
+    --simulate-failed-cluster cluster2
+
+  # Show the failover ordering without using Kubernetes API
+  ssh <user@hostname> 'curl localhost:15000/config_dump?include_eds=true' > envoy-config.json
+  istioctl experimental failover --file envoy-config.json --cluster "outbound|9080||reviews.default.svc.cluster.local"
+`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if (len(args) == 1) != (configDumpFile == "") {
+				cmd.Println(cmd.UsageString())
+				return fmt.Errorf("failover requires pod name or --file parameter")
+			}
+			if clusterName == "" {
+				cmd.Println(cmd.UsageString())
+				return fmt.Errorf("failover requires --cluster")
+			}
+			return nil
+		},
+		RunE: func(c *cobra.Command, args []string) error {
+			var configWriter *configdump.ConfigWriter
+			kubeClient, err := ctx.CLIClient()
+			if err != nil {
+				return err
+			}
+			if len(args) == 1 {
+				if podName, podNamespace, err = getPodName(ctx, args[0]); err != nil {
+					return err
+				}
+				configWriter, err = setupPodConfigdumpWriter(kubeClient, podName, podNamespace, edsPath, c.OutOrStdout())
+			} else {
+				configWriter, err = setupFileConfigdumpWriter(configDumpFile, c.OutOrStdout())
+			}
+			if err != nil {
+				return err
+			}
+			return configWriter.PrintFailoverSummary(clusterName, filter)
+		},
+		ValidArgsFunction: completion.ValidPodsNameArgs(ctx),
+	}
+
+	failoverCmd.PersistentFlags().StringVar(&clusterName, "cluster", "", "Envoy cluster name of the destination")
+	failoverCmd.PersistentFlags().StringSliceVar(&filter.Localities, "simulate-failed-locality", nil,
+		"Locality prefixes (region[/zone[/subzone]]) whose endpoints are treated as unhealthy in the simulation")
+	failoverCmd.PersistentFlags().StringSliceVar(&filter.Clusters, "simulate-failed-cluster", nil,
+		"Istio cluster IDs whose endpoints are treated as unhealthy in the simulation")
+	failoverCmd.PersistentFlags().StringVarP(&configDumpFile, "file", "f", "",
+		"Envoy config dump JSON file")
+
+	return failoverCmd
+}
@@ -30,10 +30,12 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/yaml"
 
+	networking "istio.io/api/networking/v1alpha3"
 	"istio.io/istio/istioctl/pkg/cli"
 	"istio.io/istio/istioctl/pkg/completion"
 	"istio.io/istio/istioctl/pkg/kubeinject"
 	istioctlutil "istio.io/istio/istioctl/pkg/util"
+	"istio.io/istio/istioctl/pkg/util/handlers"
 	sdscompare "istio.io/istio/istioctl/pkg/writer/compare/sds"
 	"istio.io/istio/istioctl/pkg/writer/envoy/clusters"
 	"istio.io/istio/istioctl/pkg/writer/envoy/configdump"
@@ -52,6 +54,9 @@ const (
 	prometheusMergedOutput = "prom-merged"
 
 	defaultProxyAdminPort = 15000
+
+	gatewayFlagUsage = "Name of a gateway whose proxy to use instead of a pod, deployed either by Gateway API automated " +
+		"deployment or as a classic gateway such as istio-ingressgateway, in the form <name>[.<namespace>]"
 )
 
 var (
@@ -59,9 +64,12 @@ var (
 	port                    int
 	verboseProxyConfig      bool
 	waypointProxyConfig     bool
+	clusterProvenance       bool
 
 	address, listenerType, statsType string
 
+	explainFor string
+
 	routeName string
 
 	clusterName, status string
@@ -73,6 +81,8 @@ var (
 
 	configDumpFile string
 
+	gatewayName string
+
 	labelSelector = ""
 	loggerName    string
 )
@@ -152,6 +162,20 @@ func setupPodConfigdumpWriter(kubeClient kube.CLIClient, podName, podNamespace s
 	return setupConfigdumpEnvoyConfigWriter(debug, out)
 }
 
+// listDestinationRules returns the DestinationRules of the cluster, keyed by name.namespace as in the config metadata
+// recorded by istiod.
+func listDestinationRules(kubeClient kube.CLIClient) (map[string]*networking.DestinationRule, error) {
+	drs, err := kubeClient.Istio().NetworkingV1().DestinationRules(metav1.NamespaceAll).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list DestinationRules: %v", err)
+	}
+	out := make(map[string]*networking.DestinationRule, len(drs.Items))
+	for _, dr := range drs.Items {
+		out[dr.Name+"."+dr.Namespace] = &dr.Spec
+	}
+	return out, nil
+}
+
 func readFile(filename string) ([]byte, error) {
 	file := os.Stdin
 	if filename != "-" {
@@ -349,9 +373,15 @@ func clusterConfigCmd(ctx cli.Context) *cobra.Command {
   # Retrieve summary about cluster configuration for a pod under a deployment from Envoy.
   istioctl proxy-config clusters deployment/<deployment-name[.namespace]>
 
+  # Retrieve summary about cluster configuration for a gateway, deployed by Gateway API or as a classic gateway.
+  istioctl proxy-config clusters --gateway <gateway-name[.namespace]>
+
   # Retrieve cluster summary for clusters with port 9080.
   istioctl proxy-config clusters <pod-name[.namespace]> --port 9080
 
+  # Show which DestinationRule and EnvoyFilter produced the TLS, load balancing and outlier detection settings.
+  istioctl proxy-config clusters <pod-name[.namespace]> --provenance
+
   # Retrieve full cluster dump for clusters that are inbound with a FQDN of details.default.svc.cluster.local.
   istioctl proxy-config clusters <pod-name[.namespace]> --fqdn details.default.svc.cluster.local --direction inbound -o json
 
@@ -361,9 +391,9 @@ func clusterConfigCmd(ctx cli.Context) *cobra.Command {
 `,
 		Aliases: []string{"clusters", "c"},
 		Args: func(cmd *cobra.Command, args []string) error {
-			if (len(args) == 1) != (configDumpFile == "") {
+			if !hasOneProxySource(args) {
 				cmd.Println(cmd.UsageString())
-				return fmt.Errorf("cluster requires pod name or --file parameter")
+				return fmt.Errorf("cluster requires pod name, --gateway or --file parameter")
 			}
 			return nil
 		},
@@ -373,8 +403,8 @@ func clusterConfigCmd(ctx cli.Context) *cobra.Command {
 				return err
 			}
 			var configWriter *configdump.ConfigWriter
-			if len(args) == 1 {
-				if podName, podNamespace, err = getPodName(ctx, args[0]); err != nil {
+			if configDumpFile == "" {
+				if podName, podNamespace, err = getProxyPodName(ctx, args); err != nil {
 					return err
 				}
 				configWriter, err = setupPodConfigdumpWriter(kubeClient, podName, podNamespace, "", c.OutOrStdout())
@@ -390,8 +420,20 @@ func clusterConfigCmd(ctx cli.Context) *cobra.Command {
 				Subset:    subset,
 				Direction: model.TrafficDirection(direction),
 			}
+			if clusterProvenance && outputFormat != summaryOutput {
+				return fmt.Errorf("--provenance is only supported with the %s output format", summaryOutput)
+			}
 			switch outputFormat {
 			case summaryOutput:
+				if clusterProvenance {
+					var destinationRules map[string]*networking.DestinationRule
+					if configDumpFile == "" {
+						if destinationRules, err = listDestinationRules(kubeClient); err != nil {
+							return err
+						}
+					}
+					return configWriter.PrintClusterProvenance(filter, destinationRules)
+				}
 				return configWriter.PrintClusterSummary(filter)
 			case jsonOutput, yamlOutput:
 				return configWriter.PrintClusterDump(filter, outputFormat)
@@ -407,6 +449,10 @@ func clusterConfigCmd(ctx cli.Context) *cobra.Command {
 	clusterConfigCmd.PersistentFlags().StringVar(&direction, "direction", "", "Filter clusters by Direction field")
 	clusterConfigCmd.PersistentFlags().StringVar(&subset, "subset", "", "Filter clusters by substring of Subset field")
 	clusterConfigCmd.PersistentFlags().IntVar(&port, "port", 0, "Filter clusters by Port field")
+	clusterConfigCmd.PersistentFlags().BoolVar(&clusterProvenance, "provenance", false,
+		"Show the Istio configuration that produced each cluster's TLS, load balancing and outlier detection settings. "+
+			"DestinationRules are read from the cluster, so with --file only EnvoyFilter patches are attributed")
+	clusterConfigCmd.PersistentFlags().StringVar(&gatewayName, "gateway", "", gatewayFlagUsage)
 	clusterConfigCmd.PersistentFlags().StringVarP(&configDumpFile, "file", "f", "",
 		"Envoy config dump JSON file")
 
@@ -436,9 +482,9 @@ func allConfigCmd(ctx cli.Context) *cobra.Command {
 `,
 		Aliases: []string{"a"},
 		Args: func(cmd *cobra.Command, args []string) error {
-			if (len(args) == 1) != (configDumpFile == "") {
+			if !hasOneProxySource(args) {
 				cmd.Println(cmd.UsageString())
-				return fmt.Errorf("all requires pod name or --file parameter")
+				return fmt.Errorf("all requires pod name, --gateway or --file parameter")
 			}
 			return nil
 		},
@@ -451,8 +497,8 @@ func allConfigCmd(ctx cli.Context) *cobra.Command {
 			case jsonOutput, yamlOutput:
 				var dump []byte
 				var err error
-				if len(args) == 1 {
-					podName, podNamespace, err := getPodName(ctx, args[0])
+				if configDumpFile == "" {
+					podName, podNamespace, err := getProxyPodName(ctx, args)
 					if err != nil {
 						return err
 					}
@@ -475,8 +521,8 @@ func allConfigCmd(ctx cli.Context) *cobra.Command {
 
 			case summaryOutput:
 				var configWriter *configdump.ConfigWriter
-				if len(args) == 1 {
-					podName, podNamespace, err := getPodName(ctx, args[0])
+				if configDumpFile == "" {
+					podName, podNamespace, err := getProxyPodName(ctx, args)
 					if err != nil {
 						return err
 					}
@@ -527,6 +573,7 @@ func allConfigCmd(ctx cli.Context) *cobra.Command {
 	}
 
 	allConfigCmd.PersistentFlags().StringVarP(&outputFormat, "output", "o", summaryOutput, "Output format: one of json|yaml|short")
+	allConfigCmd.PersistentFlags().StringVar(&gatewayName, "gateway", "", gatewayFlagUsage)
 	allConfigCmd.PersistentFlags().StringVarP(&configDumpFile, "file", "f", "",
 		"Envoy config dump file")
 	allConfigCmd.PersistentFlags().BoolVar(&verboseProxyConfig, "verbose", true, "Output more information")
@@ -571,23 +618,34 @@ func listenerConfigCmd(ctx cli.Context) *cobra.Command {
   # Retrieve listener summary without using Kubernetes API
   ssh <user@hostname> 'curl localhost:15000/config_dump' > envoy-config.json
   istioctl proxy-config listeners --file envoy-config.json
+
+  # Explain which listener and filter chain a TLS connection to 10.96.0.10:443 with SNI foo.example.com and
+  # ALPN h2 selects.
+  istioctl proxy-config listeners <pod-name[.namespace]> --explain-for 10.96.0.10:443,foo.example.com,h2
 `,
 		Aliases: []string{"listeners", "l"},
 		Args: func(cmd *cobra.Command, args []string) error {
-			if (len(args) == 1) != (configDumpFile == "") {
+			if !hasOneProxySource(args) {
 				cmd.Println(cmd.UsageString())
-				return fmt.Errorf("listener requires pod name or --file parameter")
+				return fmt.Errorf("listener requires pod name, --gateway or --file parameter")
 			}
 			return nil
 		},
 		RunE: func(c *cobra.Command, args []string) error {
+			var conn configdump.Connection
+			if explainFor != "" {
+				var err error
+				if conn, err = configdump.ParseConnection(explainFor); err != nil {
+					return err
+				}
+			}
 			kubeClient, err := ctx.CLIClient()
 			if err != nil {
 				return err
 			}
 			var configWriter *configdump.ConfigWriter
-			if len(args) == 1 {
-				if podName, podNamespace, err = getPodName(ctx, args[0]); err != nil {
+			if configDumpFile == "" {
+				if podName, podNamespace, err = getProxyPodName(ctx, args); err != nil {
 					return err
 				}
 				configWriter, err = setupPodConfigdumpWriter(kubeClient, podName, podNamespace, "", c.OutOrStdout())
@@ -597,6 +655,9 @@ func listenerConfigCmd(ctx cli.Context) *cobra.Command {
 			if err != nil {
 				return err
 			}
+			if explainFor != "" {
+				return configWriter.PrintFilterChainExplanation(conn)
+			}
 			filter := configdump.ListenerFilter{
 				Address: address,
 				Port:    uint32(port),
@@ -624,9 +685,12 @@ func listenerConfigCmd(ctx cli.Context) *cobra.Command {
 	listenerConfigCmd.PersistentFlags().StringVar(&listenerType, "type", "", "Filter listeners by type field")
 	listenerConfigCmd.PersistentFlags().IntVar(&port, "port", 0, "Filter listeners by Port field")
 	listenerConfigCmd.PersistentFlags().BoolVar(&verboseProxyConfig, "verbose", true, "Output more information")
+	listenerConfigCmd.PersistentFlags().StringVar(&explainFor, "explain-for", "",
+		"Explain which listener and filter chain a connection selects, given as <dst-ip>:<port>[,<sni>[,<alpn>...]]")
 	listenerConfigCmd.PersistentFlags().BoolVar(&waypointProxyConfig, "waypoint", false, "Output waypoint information")
 	// Until stabilized
 	_ = listenerConfigCmd.PersistentFlags().MarkHidden("waypoint")
+	listenerConfigCmd.PersistentFlags().StringVar(&gatewayName, "gateway", "", gatewayFlagUsage)
 	listenerConfigCmd.PersistentFlags().StringVarP(&configDumpFile, "file", "f", "",
 		"Envoy config dump JSON file")
 
@@ -872,9 +936,9 @@ func routeConfigCmd(ctx cli.Context) *cobra.Command {
 `,
 		Aliases: []string{"routes", "r"},
 		Args: func(cmd *cobra.Command, args []string) error {
-			if (len(args) == 1) != (configDumpFile == "") {
+			if !hasOneProxySource(args) {
 				cmd.Println(cmd.UsageString())
-				return fmt.Errorf("route requires pod name or --file parameter")
+				return fmt.Errorf("route requires pod name, --gateway or --file parameter")
 			}
 			return nil
 		},
@@ -884,8 +948,8 @@ func routeConfigCmd(ctx cli.Context) *cobra.Command {
 			if err != nil {
 				return err
 			}
-			if len(args) == 1 {
-				if podName, podNamespace, err = getPodName(ctx, args[0]); err != nil {
+			if configDumpFile == "" {
+				if podName, podNamespace, err = getProxyPodName(ctx, args); err != nil {
 					return err
 				}
 				configWriter, err = setupPodConfigdumpWriter(kubeClient, podName, podNamespace, "", c.OutOrStdout())
@@ -914,6 +978,7 @@ func routeConfigCmd(ctx cli.Context) *cobra.Command {
 	routeConfigCmd.PersistentFlags().StringVarP(&outputFormat, "output", "o", summaryOutput, "Output format: one of json|yaml|short")
 	routeConfigCmd.PersistentFlags().StringVar(&routeName, "name", "", "Filter listeners by route name field")
 	routeConfigCmd.PersistentFlags().BoolVar(&verboseProxyConfig, "verbose", true, "Output more information")
+	routeConfigCmd.PersistentFlags().StringVar(&gatewayName, "gateway", "", gatewayFlagUsage)
 	routeConfigCmd.PersistentFlags().StringVarP(&configDumpFile, "file", "f", "",
 		"Envoy config dump JSON file")
 
@@ -950,9 +1015,9 @@ func endpointConfigCmd(ctx cli.Context) *cobra.Command {
 `,
 		Aliases: []string{"endpoints", "ep"},
 		Args: func(cmd *cobra.Command, args []string) error {
-			if (len(args) == 1) != (configDumpFile == "") {
+			if !hasOneProxySource(args) {
 				cmd.Println(cmd.UsageString())
-				return fmt.Errorf("endpoints requires pod name or --file parameter")
+				return fmt.Errorf("endpoints requires pod name, --gateway or --file parameter")
 			}
 			return nil
 		},
@@ -962,8 +1027,8 @@ func endpointConfigCmd(ctx cli.Context) *cobra.Command {
 			if err != nil {
 				return err
 			}
-			if len(args) == 1 {
-				if podName, podNamespace, err = getPodName(ctx, args[0]); err != nil {
+			if configDumpFile == "" {
+				if podName, podNamespace, err = getProxyPodName(ctx, args); err != nil {
 					return err
 				}
 				configWriter, err = setupPodClustersWriter(kubeClient, podName, podNamespace, c.OutOrStdout())
@@ -998,6 +1063,7 @@ func endpointConfigCmd(ctx cli.Context) *cobra.Command {
 	endpointConfigCmd.PersistentFlags().IntVar(&port, "port", 0, "Filter endpoints by Port field")
 	endpointConfigCmd.PersistentFlags().StringVar(&clusterName, "cluster", "", "Filter endpoints by cluster name field")
 	endpointConfigCmd.PersistentFlags().StringVar(&status, "status", "", "Filter endpoints by status field")
+	endpointConfigCmd.PersistentFlags().StringVar(&gatewayName, "gateway", "", gatewayFlagUsage)
 	endpointConfigCmd.PersistentFlags().StringVarP(&configDumpFile, "file", "f", "",
 		"Envoy config dump JSON file")
 
@@ -1039,9 +1105,9 @@ func edsConfigCmd(ctx cli.Context) *cobra.Command {
   istioctl proxy-config eds --file envoy-config.json
 `,
 		Args: func(cmd *cobra.Command, args []string) error {
-			if (len(args) == 1) != (configDumpFile == "") {
+			if !hasOneProxySource(args) {
 				cmd.Println(cmd.UsageString())
-				return fmt.Errorf("eds requires pod name or --file parameter")
+				return fmt.Errorf("eds requires pod name, --gateway or --file parameter")
 			}
 			return nil
 		},
@@ -1051,8 +1117,8 @@ func edsConfigCmd(ctx cli.Context) *cobra.Command {
 			if err != nil {
 				return err
 			}
-			if len(args) == 1 {
-				if podName, podNamespace, err = getPodName(ctx, args[0]); err != nil {
+			if configDumpFile == "" {
+				if podName, podNamespace, err = getProxyPodName(ctx, args); err != nil {
 					return err
 				}
 				configWriter, err = setupPodConfigdumpWriter(kubeClient, podName, podNamespace, edsPath, c.OutOrStdout())
@@ -1087,6 +1153,7 @@ func edsConfigCmd(ctx cli.Context) *cobra.Command {
 	endpointConfigCmd.PersistentFlags().IntVar(&port, "port", 0, "Filter endpoints by Port field")
 	endpointConfigCmd.PersistentFlags().StringVar(&clusterName, "cluster", "", "Filter endpoints by cluster name field")
 	endpointConfigCmd.PersistentFlags().StringVar(&status, "status", "", "Filter endpoints by status field")
+	endpointConfigCmd.PersistentFlags().StringVar(&gatewayName, "gateway", "", gatewayFlagUsage)
 	endpointConfigCmd.PersistentFlags().StringVarP(&configDumpFile, "file", "f", "",
 		"Envoy config dump JSON file")
 
@@ -1118,9 +1185,9 @@ func bootstrapConfigCmd(ctx cli.Context) *cobra.Command {
 `,
 		Aliases: []string{"b"},
 		Args: func(cmd *cobra.Command, args []string) error {
-			if (len(args) == 1) != (configDumpFile == "") {
+			if !hasOneProxySource(args) {
 				cmd.Println(cmd.UsageString())
-				return fmt.Errorf("bootstrap requires pod name or --file parameter")
+				return fmt.Errorf("bootstrap requires pod name, --gateway or --file parameter")
 			}
 			return nil
 		},
@@ -1130,8 +1197,8 @@ func bootstrapConfigCmd(ctx cli.Context) *cobra.Command {
 			if err != nil {
 				return err
 			}
-			if len(args) == 1 {
-				if podName, podNamespace, err = getPodName(ctx, args[0]); err != nil {
+			if configDumpFile == "" {
+				if podName, podNamespace, err = getProxyPodName(ctx, args); err != nil {
 					return err
 				}
 				configWriter, err = setupPodConfigdumpWriter(kubeClient, podName, podNamespace, "", c.OutOrStdout())
@@ -1155,6 +1222,7 @@ func bootstrapConfigCmd(ctx cli.Context) *cobra.Command {
 	}
 
 	bootstrapConfigCmd.Flags().StringVarP(&outputFormat, "output", "o", jsonOutput, "Output format: one of json|yaml|short")
+	bootstrapConfigCmd.PersistentFlags().StringVar(&gatewayName, "gateway", "", gatewayFlagUsage)
 	bootstrapConfigCmd.PersistentFlags().StringVarP(&configDumpFile, "file", "f", "",
 		"Envoy config dump JSON file")
 
@@ -1179,9 +1247,9 @@ func secretConfigCmd(ctx cli.Context) *cobra.Command {
   istioctl proxy-config secret --file envoy-config.json`,
 		Aliases: []string{"secrets", "s"},
 		Args: func(cmd *cobra.Command, args []string) error {
-			if (len(args) == 1) != (configDumpFile == "") {
+			if !hasOneProxySource(args) {
 				cmd.Println(cmd.UsageString())
-				return fmt.Errorf("secret requires pod name or --file parameter")
+				return fmt.Errorf("secret requires pod name, --gateway or --file parameter")
 			}
 			return nil
 		},
@@ -1191,8 +1259,8 @@ func secretConfigCmd(ctx cli.Context) *cobra.Command {
 			if err != nil {
 				return err
 			}
-			if len(args) == 1 {
-				if podName, podNamespace, err = getPodName(ctx, args[0]); err != nil {
+			if configDumpFile == "" {
+				if podName, podNamespace, err = getProxyPodName(ctx, args); err != nil {
 					return err
 				}
 				cw, err = setupPodConfigdumpWriter(kubeClient, podName, podNamespace, secretPath, c.OutOrStdout())
@@ -1218,6 +1286,7 @@ func secretConfigCmd(ctx cli.Context) *cobra.Command {
 	}
 
 	secretConfigCmd.PersistentFlags().StringVarP(&outputFormat, "output", "o", summaryOutput, "Output format: one of json|yaml|short")
+	secretConfigCmd.PersistentFlags().StringVar(&gatewayName, "gateway", "", gatewayFlagUsage)
 	secretConfigCmd.PersistentFlags().StringVarP(&configDumpFile, "file", "f", "",
 		"Envoy config dump JSON file")
 	return secretConfigCmd
@@ -1337,6 +1406,34 @@ func getPodName(ctx cli.Context, podflag string) (string, string, error) {
 	return getPodNameWithNamespace(ctx, podflag, ctx.Namespace())
 }
 
+// hasOneProxySource returns whether exactly one of a pod name, --gateway or --file is given.
+func hasOneProxySource(args []string) bool {
+	sources := 0
+	for _, given := range []bool{len(args) == 1, gatewayName != "", configDumpFile != ""} {
+		if given {
+			sources++
+		}
+	}
+	return sources == 1
+}
+
+// getProxyPodName returns the pod of the pod name argument, or a pod of the gateway of --gateway.
+func getProxyPodName(ctx cli.Context, args []string) (string, string, error) {
+	if gatewayName == "" {
+		return getPodName(ctx, args[0])
+	}
+	kubeClient, err := ctx.CLIClient()
+	if err != nil {
+		return "", "", err
+	}
+	name, ns := handlers.InferPodInfo(gatewayName, ctx.NamespaceOrDefault(ctx.Namespace()))
+	pods, err := handlers.GatewayPods(context.TODO(), kubeClient.Kube(), name, ns)
+	if err != nil {
+		return "", "", err
+	}
+	return pods[0].Name, pods[0].Namespace, nil
+}
+
 func getPodNameWithNamespace(ctx cli.Context, podflag, ns string) (string, string, error) {
 	var podName, podNamespace string
 	podName, podNamespace, err := ctx.InferPodInfoFromTypedResource(podflag, ns)
@@ -1384,9 +1481,9 @@ func ecdsConfigCmd(ctx cli.Context) *cobra.Command {
   istioctl proxy-config ecds --file envoy-config.json
 `,
 		Args: func(cmd *cobra.Command, args []string) error {
-			if (len(args) == 1) != (configDumpFile == "") {
+			if !hasOneProxySource(args) {
 				cmd.Println(cmd.UsageString())
-				return fmt.Errorf("ecds requires pod name or --file parameter")
+				return fmt.Errorf("ecds requires pod name, --gateway or --file parameter")
 			}
 			return nil
 		},
@@ -1396,8 +1493,8 @@ func ecdsConfigCmd(ctx cli.Context) *cobra.Command {
 			if err != nil {
 				return err
 			}
-			if len(args) == 1 {
-				if podName, podNamespace, err = getPodName(ctx, args[0]); err != nil {
+			if configDumpFile == "" {
+				if podName, podNamespace, err = getProxyPodName(ctx, args); err != nil {
 					return err
 				}
 				configWriter, err = setupPodConfigdumpWriter(kubeClient, podName, podNamespace, edsPath, c.OutOrStdout())
@@ -1421,6 +1518,7 @@ func ecdsConfigCmd(ctx cli.Context) *cobra.Command {
 	}
 
 	ecdsConfigCmd.PersistentFlags().StringVarP(&outputFormat, "output", "o", summaryOutput, "Output format: one of json|yaml|short")
+	ecdsConfigCmd.PersistentFlags().StringVar(&gatewayName, "gateway", "", gatewayFlagUsage)
 	ecdsConfigCmd.PersistentFlags().StringVarP(&configDumpFile, "file", "f", "", "Envoy config dump JSON file")
 
 	return ecdsConfigCmd
@@ -22,6 +22,9 @@ import (
 	"testing"
 
 	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/cli-runtime/pkg/resource"
 	"k8s.io/client-go/rest/fake"
 	cmdtesting "k8s.io/kubectl/pkg/cmd/testing"
@@ -46,8 +49,17 @@ type execTestCase struct {
 
 func TestProxyConfig(t *testing.T) {
 	loggingConfig := map[string][]byte{
-		"details-v1-5b7f94f9bc-wp5tb": util.ReadFile(t, "../writer/envoy/logging/testdata/logging.txt"),
-		"httpbin-794b576b6c-qx6pf":    []byte("{}"),
+		"details-v1-5b7f94f9bc-wp5tb":     util.ReadFile(t, "../writer/envoy/logging/testdata/logging.txt"),
+		"httpbin-794b576b6c-qx6pf":        []byte("{}"),
+		"external-istio-7f8c9b8b9b-k9s8d": []byte("{}"),
+	}
+	gatewayPod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "external-istio-7f8c9b8b9b-k9s8d",
+			Namespace: "istio-system",
+			Labels:    map[string]string{"gateway.networking.k8s.io/gateway-name": "external"},
+		},
+		Status: corev1.PodStatus{Phase: corev1.PodRunning},
 	}
 	cases := []execTestCase{
 		{
@@ -163,6 +175,27 @@ func TestProxyConfig(t *testing.T) {
 			expectedString:   `config dump has no configuration type`,
 			wantException:    true,
 		},
+		{ // neither pod name, gateway nor file
+			args:           strings.Split("clusters", " "),
+			expectedString: "cluster requires pod name, --gateway or --file parameter",
+			wantException:  true,
+		},
+		{ // both pod name and gateway
+			args:           strings.Split("clusters httpbin-794b576b6c-qx6pf --gateway external.istio-system", " "),
+			expectedString: "cluster requires pod name, --gateway or --file parameter",
+			wantException:  true,
+		},
+		{ // supplying a gateway retrieves the Envoy config of its pod (fails because we don't check in Envoy config unit tests)
+			execClientConfig: loggingConfig,
+			args:             strings.Split("clusters --gateway external.istio-system", " "),
+			expectedString:   `config dump has no configuration type`,
+			wantException:    true,
+		},
+		{ // gateway invalid
+			args:           strings.Split("listeners --gateway invalid.istio-system", " "),
+			expectedString: `no running pods found for gateway "invalid" in the "istio-system" namespace`,
+			wantException:  true,
+		},
 	}
 
 	for i, c := range cases {
@@ -170,6 +203,7 @@ func TestProxyConfig(t *testing.T) {
 			verifyExecTestOutput(t, ProxyConfig(cli.NewFakeContext(&cli.NewFakeContextOption{
 				Results:   c.execClientConfig,
 				Namespace: "default",
+				Objects:   []runtime.Object{gatewayPod},
 			})), c)
 		})
 	}
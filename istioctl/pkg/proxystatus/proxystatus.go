@@ -16,9 +16,11 @@ package proxystatus
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"os"
+	"time"
 
 	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
 	"github.com/spf13/cobra"
@@ -36,6 +38,11 @@ import (
 
 var configDumpFile string
 
+const (
+	snapshotSave    = "save"
+	snapshotCompare = "compare"
+)
+
 func readConfigFile(filename string) ([]byte, error) {
 	file := os.Stdin
 	if filename != "-" {
@@ -57,6 +64,29 @@ func readConfigFile(filename string) ([]byte, error) {
 	return data, nil
 }
 
+func saveSnapshot(filename string, snapshot *pilot.Snapshot) error {
+	data, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filename, data, 0o644); err != nil {
+		return fmt.Errorf("could not save the snapshot: %v", err)
+	}
+	return nil
+}
+
+func loadSnapshot(filename string) (*pilot.Snapshot, error) {
+	data, err := readConfigFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("could not read the snapshot: %v", err)
+	}
+	snapshot := &pilot.Snapshot{}
+	if err := json.Unmarshal(data, snapshot); err != nil {
+		return nil, fmt.Errorf("could not parse the snapshot %s: %v", filename, err)
+	}
+	return snapshot, nil
+}
+
 func StableXdsStatusCommand(ctx cli.Context) *cobra.Command {
 	cmd := XdsStatusCommand(ctx)
 	unstableFlags := []string{"xds-via-agents", "xds-via-agents-limit"}
@@ -78,6 +108,7 @@ func XdsStatusCommand(ctx cli.Context) *cobra.Command {
 	var opts clioptions.ControlPlaneOptions
 	var centralOpts clioptions.CentralControlPlaneOptions
 	var multiXdsOpts multixds.Options
+	var snapshot, snapshotFile string
 
 	statusCmd := &cobra.Command{
 		Use:   "proxy-status [<type>/]<name>[.<namespace>]",
@@ -94,6 +125,10 @@ Retrieves last sent and last acknowledged xDS sync from Istiod to each Envoy in
   # Retrieve sync diff for a single Envoy and Istiod
   istioctl proxy-status istio-egressgateway-59585c5b9c-ndc59.istio-system
 
+  # Save the sync status of all Envoys before a maintenance, and compare it with their status after it
+  istioctl proxy-status --snapshot save --snapshot-file before.json
+  istioctl proxy-status --snapshot compare --snapshot-file before.json
+
   # SECURITY OPTIONS
 
   # Retrieve proxy status information directly from the control plane, using token security
@@ -114,6 +149,14 @@ Retrieves last sent and last acknowledged xDS sync from Istiod to each Envoy in
 `,
 		Aliases: []string{"ps"},
 		RunE: func(c *cobra.Command, args []string) error {
+			if snapshot != "" {
+				if snapshot != snapshotSave && snapshot != snapshotCompare {
+					return fmt.Errorf("unknown --snapshot %q, expected %s or %s", snapshot, snapshotSave, snapshotCompare)
+				}
+				if len(args) > 0 {
+					return fmt.Errorf("--snapshot cannot be used with a proxy name")
+				}
+			}
 			kubeClient, err := ctx.CLIClientWithRevision(opts.Revision)
 			if err != nil {
 				return err
@@ -166,6 +209,28 @@ Retrieves last sent and last acknowledged xDS sync from Istiod to each Envoy in
 				Writer:    c.OutOrStdout(),
 				Namespace: ctx.Namespace(),
 			}
+			switch snapshot {
+			case snapshotSave:
+				current, err := sw.Snapshot(xdsResponses, time.Now())
+				if err != nil {
+					return err
+				}
+				if err := saveSnapshot(snapshotFile, current); err != nil {
+					return err
+				}
+				_, _ = fmt.Fprintf(c.OutOrStdout(), "Saved the sync status of %d proxies to %s\n", len(current.Proxies), snapshotFile)
+				return nil
+			case snapshotCompare:
+				previous, err := loadSnapshot(snapshotFile)
+				if err != nil {
+					return err
+				}
+				current, err := sw.Snapshot(xdsResponses, time.Now())
+				if err != nil {
+					return err
+				}
+				return sw.PrintSnapshotDiff(previous, current)
+			}
 			return sw.PrintAll(xdsResponses)
 		},
 		ValidArgsFunction: completion.ValidPodsNameArgs(ctx),
@@ -175,6 +240,11 @@ Retrieves last sent and last acknowledged xDS sync from Istiod to each Envoy in
 	centralOpts.AttachControlPlaneFlags(statusCmd)
 	statusCmd.PersistentFlags().StringVarP(&configDumpFile, "file", "f", "",
 		"Envoy config dump JSON file")
+	statusCmd.PersistentFlags().StringVar(&snapshot, "snapshot", "",
+		"Save the sync status of all proxies to the snapshot file (save), or compare it with the snapshot file (compare), "+
+			"reporting the proxies which regressed to STALE or changed of Istiod instance")
+	statusCmd.PersistentFlags().StringVar(&snapshotFile, "snapshot-file", "proxy-status-snapshot.json",
+		"File the snapshot of the sync status of all proxies is saved to, or compared with")
 	statusCmd.PersistentFlags().BoolVar(&multiXdsOpts.XdsViaAgents, "xds-via-agents", false,
 		"Access Istiod via the tap service of each agent")
 	statusCmd.PersistentFlags().IntVar(&multiXdsOpts.XdsViaAgentsLimit, "xds-via-agents-limit", 100,
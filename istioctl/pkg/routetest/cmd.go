@@ -0,0 +1,308 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package routetest
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	kubeyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/yaml"
+
+	"istio.io/istio/istioctl/pkg/cli"
+	"istio.io/istio/pilot/pkg/config/kube/crd"
+	"istio.io/istio/pkg/config"
+)
+
+func Cmd(ctx cli.Context) *cobra.Command {
+	var (
+		filenames    []string
+		testsFile    string
+		outputFormat string
+	)
+	cmd := &cobra.Command{
+		Use:   "route-test -f <config> --tests <file>",
+		Short: "Tests which destinations requests are routed to by the VirtualServices of config files",
+		Long: `Routes a table of requests with the VirtualServices of config files, without a cluster, and checks that each
+request is routed to the destination expected. This allows testing VirtualService changes in pull requests.
+
+The matches of the routes are translated as istiod translates them for Envoy, and evaluated as Envoy does. The requests
+are sent by a client of the mesh, or through the gateway of the request. Matches on JWT claims are not supported.
+
+The tests are a YAML file such as:
+
+  tests:
+  - name: jason is routed to reviews v2
+    request:
+      host: reviews
+      path: /reviews/1
+      headers:
+        end-user: jason
+    expect:
+      host: reviews
+      subset: v2
+  - name: the bookinfo gateway routes /productpage
+    request:
+      host: bookinfo.example.com
+      path: /productpage
+      gateway: bookinfo/bookinfo-gateway
+    expect:
+      host: productpage.bookinfo.svc.cluster.local
+      port: 9080
+
+A request can also set its port, method, scheme, sourceLabels and the namespace of its client. An expectation can also
+set the weight of the destination and the virtualService routing the request, as namespace/name.`,
+		Example: `  # Test the routing of the VirtualServices of a directory
+  istioctl experimental route-test -f networking/ --tests routes-test.yaml
+
+  # Test the routing in CI, recording JUnit results
+  istioctl x route-test -f virtualservices.yaml --tests routes-test.yaml -o junit > route-test.xml`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(filenames) == 0 {
+				return errors.New("no config file specified (see --filename or -f)")
+			}
+			if testsFile == "" {
+				return errors.New("no tests file specified (see --tests)")
+			}
+			if outputFormat != "text" && outputFormat != "junit" {
+				return fmt.Errorf("unknown output format %q, must be text or junit", outputFormat)
+			}
+			namespace := ctx.NamespaceOrDefault(ctx.Namespace())
+			configs, err := readConfigs(filenames, namespace)
+			if err != nil {
+				return err
+			}
+			suite, err := readSuite(testsFile)
+			if err != nil {
+				return err
+			}
+			results := Run(NewRouter(configs), suite, namespace)
+			if outputFormat == "junit" {
+				err = printJUnit(cmd.OutOrStdout(), results)
+			} else {
+				printResults(cmd.OutOrStdout(), results)
+			}
+			if err != nil {
+				return err
+			}
+			if failed := failures(results); failed > 0 {
+				return fmt.Errorf("%d of %d route tests failed", failed, len(results))
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringSliceVarP(&filenames, "filename", "f", nil, "Istio config files or directories with the VirtualServices to test")
+	cmd.Flags().StringVar(&testsFile, "tests", "", "YAML file of the requests to route and their expected destination")
+	cmd.Flags().StringVarP(&outputFormat, "output", "o", "text", "Output format: one of text|junit")
+	return cmd
+}
+
+// TestResult is the outcome of a test.
+type TestResult struct {
+	Test   Test
+	Result Result
+	// Failure is why the test failed, empty if it passed.
+	Failure  string
+	Duration time.Duration
+}
+
+// Run routes the requests of the tests, sent from namespace unless set, and checks their expectations.
+func Run(router *Router, suite Suite, namespace string) []TestResult {
+	results := make([]TestResult, 0, len(suite.Tests))
+	for _, t := range suite.Tests {
+		start := time.Now()
+		tr := TestResult{Test: t}
+		res, err := router.Route(t.Request, namespace)
+		if err != nil {
+			tr.Failure = err.Error()
+		} else {
+			tr.Result = res
+			tr.Failure = router.Check(t, res, namespace)
+		}
+		tr.Duration = time.Since(start)
+		results = append(results, tr)
+	}
+	return results
+}
+
+func failures(results []TestResult) int {
+	failed := 0
+	for _, r := range results {
+		if r.Failure != "" {
+			failed++
+		}
+	}
+	return failed
+}
+
+func readSuite(path string) (Suite, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return Suite{}, fmt.Errorf("failed to read the tests: %v", err)
+	}
+	var suite Suite
+	if err := yaml.UnmarshalStrict(b, &suite); err != nil {
+		return Suite{}, fmt.Errorf("failed to parse the tests %s: %v", path, err)
+	}
+	for i, t := range suite.Tests {
+		if t.Name == "" {
+			return Suite{}, fmt.Errorf("test %d of %s has no name", i+1, path)
+		}
+		if t.Request.Host == "" || t.Expect.Host == "" {
+			return Suite{}, fmt.Errorf("test %q of %s requires a request host and an expected host", t.Name, path)
+		}
+	}
+	return suite, nil
+}
+
+// readConfigs returns the Istio configs of the files, and of the YAML files of the directories.
+func readConfigs(filenames []string, defaultNamespace string) ([]config.Config, error) {
+	var configs []config.Config
+	for _, f := range filenames {
+		fi, err := os.Stat(f)
+		if err != nil {
+			return nil, err
+		}
+		if fi.IsDir() {
+			entries, err := os.ReadDir(f)
+			if err != nil {
+				return nil, err
+			}
+			var files []string
+			for _, e := range entries {
+				if !e.IsDir() && isYAML(e.Name()) {
+					files = append(files, filepath.Join(f, e.Name()))
+				}
+			}
+			dirConfigs, err := readConfigs(files, defaultNamespace)
+			if err != nil {
+				return nil, err
+			}
+			configs = append(configs, dirConfigs...)
+			continue
+		}
+		b, err := os.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+		decoder := kubeyaml.NewYAMLOrJSONDecoder(bytes.NewReader(b), 512*1024)
+		for {
+			obj := &unstructured.Unstructured{}
+			err := decoder.Decode(&obj.Object)
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse %s: %v", f, err)
+			}
+			if len(obj.Object) == 0 {
+				continue
+			}
+			if obj.GetNamespace() == "" {
+				obj.SetNamespace(defaultNamespace)
+			}
+			js, err := obj.MarshalJSON()
+			if err != nil {
+				return nil, err
+			}
+			// Objects other than Istio config, such as the Deployments of the application, are skipped.
+			parsed, _, err := crd.ParseInputs(string(js))
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse %s %s in %s: %v", obj.GetKind(), obj.GetName(), f, err)
+			}
+			configs = append(configs, parsed...)
+		}
+	}
+	return configs, nil
+}
+
+func isYAML(name string) bool {
+	switch filepath.Ext(name) {
+	case ".yaml", ".yml", ".json":
+		return true
+	}
+	return false
+}
+
+func printResults(w io.Writer, results []TestResult) {
+	for _, r := range results {
+		if r.Failure != "" {
+			_, _ = fmt.Fprintf(w, "FAIL %s: %s\n", r.Test.Name, r.Failure)
+			continue
+		}
+		_, _ = fmt.Fprintf(w, "PASS %s: %s\n", r.Test.Name, r.Result)
+	}
+	_, _ = fmt.Fprintf(w, "%d tests, %d failed\n", len(results), failures(results))
+}
+
+type junitTestSuites struct {
+	XMLName xml.Name         `xml:"testsuites"`
+	Suites  []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Time     string          `xml:"time,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+}
+
+func printJUnit(w io.Writer, results []TestResult) error {
+	suite := junitTestSuite{Name: "route-test", Tests: len(results), Failures: failures(results)}
+	var total time.Duration
+	for _, r := range results {
+		tc := junitTestCase{
+			Name:      r.Test.Name,
+			ClassName: "route-test",
+			Time:      fmt.Sprintf("%.3f", r.Duration.Seconds()),
+		}
+		if r.Failure != "" {
+			tc.Failure = &junitFailure{Message: r.Failure}
+		} else {
+			tc.SystemOut = r.Result.String()
+		}
+		total += r.Duration
+		suite.Cases = append(suite.Cases, tc)
+	}
+	suite.Time = fmt.Sprintf("%.3f", total.Seconds())
+	b, err := xml.MarshalIndent(junitTestSuites{Suites: []junitTestSuite{suite}}, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s%s\n", xml.Header, b)
+	return err
+}
@@ -0,0 +1,539 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package routetest
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	route "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	matcher "github.com/envoyproxy/go-control-plane/envoy/type/matcher/v3"
+
+	networking "istio.io/api/networking/v1alpha3"
+	"istio.io/istio/pilot/pkg/model"
+	istioroute "istio.io/istio/pilot/pkg/networking/core/route"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/constants"
+	"istio.io/istio/pkg/config/host"
+	"istio.io/istio/pkg/config/labels"
+	"istio.io/istio/pkg/config/schema/gvk"
+	"istio.io/istio/pkg/maps"
+)
+
+// Suite is a table of requests and the destinations they are expected to be routed to, such as:
+//
+//	tests:
+//	- name: jason is routed to reviews v2
+//	  request:
+//	    host: reviews
+//	    headers:
+//	      end-user: jason
+//	  expect:
+//	    host: reviews
+//	    subset: v2
+type Suite struct {
+	Tests []Test `json:"tests"`
+}
+
+// Test is a request and the destination it is expected to be routed to.
+type Test struct {
+	Name    string  `json:"name"`
+	Request Request `json:"request"`
+	Expect  Expect  `json:"expect"`
+}
+
+// Request is an HTTP request sent by a client of the mesh, or through a gateway.
+type Request struct {
+	// Host is the host the request is sent to. Short names are resolved against Namespace.
+	Host string `json:"host"`
+	// Port is the port the request is sent to. Matches on the port are skipped when not set.
+	Port uint32 `json:"port,omitempty"`
+	// Path is the path of the request, with its query string. Defaults to "/".
+	Path string `json:"path,omitempty"`
+	// Method is the method of the request. Defaults to GET.
+	Method  string            `json:"method,omitempty"`
+	Scheme  string            `json:"scheme,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+	// Namespace is the namespace of the client. Defaults to the namespace of the command.
+	Namespace string `json:"namespace,omitempty"`
+	// SourceLabels are the labels of the client.
+	SourceLabels map[string]string `json:"sourceLabels,omitempty"`
+	// Gateway is the gateway the request is sent through, as namespace/name. Defaults to the mesh.
+	Gateway string `json:"gateway,omitempty"`
+}
+
+// Expect is the destination a request is expected to be routed to. When the route splits the traffic across several
+// destinations, the destination expected must be one of them.
+type Expect struct {
+	// Host is the host of the destination. Short names are resolved against the namespace of the request.
+	Host   string `json:"host"`
+	Subset string `json:"subset,omitempty"`
+	// Port is the port of the destination, checked only when set.
+	Port uint32 `json:"port,omitempty"`
+	// Weight is the percentage of the requests routed to the destination, checked only when set.
+	Weight int32 `json:"weight,omitempty"`
+	// VirtualService is the VirtualService routing the request, as namespace/name, checked only when set.
+	VirtualService string `json:"virtualService,omitempty"`
+}
+
+// Destination is a destination of a route.
+type Destination struct {
+	Host   host.Name
+	Subset string
+	Port   uint32
+	Weight int32
+}
+
+func (d Destination) String() string {
+	s := d.Host.String()
+	if d.Port != 0 {
+		s += fmt.Sprintf(":%d", d.Port)
+	}
+	if d.Subset != "" {
+		s += " subset " + d.Subset
+	}
+	if d.Weight != 0 {
+		s += fmt.Sprintf(" (%d%%)", d.Weight)
+	}
+	return s
+}
+
+// Result is how a request is routed.
+type Result struct {
+	// VirtualService is the VirtualService routing the request, as namespace/name, or empty if the request is routed
+	// to its host by default.
+	VirtualService string
+	// Route is the name of the route of the VirtualService, or its index when unnamed.
+	Route        string
+	Destinations []Destination
+	// Redirect or DirectResponse describe the response of the routes which do not forward the request.
+	Redirect       string
+	DirectResponse uint32
+}
+
+func (r Result) String() string {
+	var target string
+	switch {
+	case r.Redirect != "":
+		target = "redirect to " + r.Redirect
+	case r.DirectResponse != 0:
+		target = fmt.Sprintf("direct response %d", r.DirectResponse)
+	case len(r.Destinations) == 0:
+		target = "no destination"
+	default:
+		dests := make([]string, 0, len(r.Destinations))
+		for _, d := range r.Destinations {
+			dests = append(dests, d.String())
+		}
+		target = strings.Join(dests, ", ")
+	}
+	if r.VirtualService == "" {
+		return target + " (default route)"
+	}
+	return fmt.Sprintf("%s (VirtualService %s, route %s)", target, r.VirtualService, r.Route)
+}
+
+// Router routes requests with the VirtualServices of the configuration.
+type Router struct {
+	virtualServices []config.Config
+	domain          string
+}
+
+// NewRouter returns a router of the VirtualServices of the configs. Other configs are ignored.
+func NewRouter(configs []config.Config) *Router {
+	r := &Router{domain: constants.DefaultClusterLocalDomain}
+	for _, c := range configs {
+		if c.GroupVersionKind == gvk.VirtualService {
+			c.Domain = r.domain
+			r.virtualServices = append(r.virtualServices, c)
+		}
+	}
+	// The oldest VirtualService takes precedence among those of the same host, as it does in istiod.
+	sort.SliceStable(r.virtualServices, func(i, j int) bool {
+		a, b := r.virtualServices[i], r.virtualServices[j]
+		if !a.CreationTimestamp.Equal(b.CreationTimestamp) {
+			return a.CreationTimestamp.Before(b.CreationTimestamp)
+		}
+		return a.Namespace+"/"+a.Name < b.Namespace+"/"+b.Name
+	})
+	return r
+}
+
+// Route returns how the request, sent by a client of namespace, is routed.
+func (r *Router) Route(req Request, namespace string) (Result, error) {
+	if req.Namespace == "" {
+		req.Namespace = namespace
+	}
+	if req.Path == "" {
+		req.Path = "/"
+	}
+	if req.Method == "" {
+		req.Method = "GET"
+	}
+	if req.Scheme == "" {
+		req.Scheme = "http"
+	}
+	reqHost := r.resolve(req.Host, req.Namespace)
+	if reqHost == "" {
+		return Result{}, errors.New("the request has no host")
+	}
+
+	vs, ok := r.virtualServiceFor(reqHost, req)
+	if !ok {
+		return Result{Destinations: []Destination{{Host: reqHost, Port: req.Port}}}, nil
+	}
+	res, matched, err := r.routeWith(vs, req, nil)
+	if err != nil {
+		return Result{}, err
+	}
+	if !matched {
+		// Envoy answers 404 to the requests matching no route of the virtual host of the VirtualService.
+		return Result{VirtualService: vs.Namespace + "/" + vs.Name, Route: "none", DirectResponse: 404}, nil
+	}
+	return res, nil
+}
+
+func (r *Router) resolve(h, namespace string) host.Name {
+	return model.ResolveShortnameToFQDN(h, config.Meta{Namespace: namespace, Domain: r.domain})
+}
+
+// virtualServiceFor returns the VirtualService routing the host on the gateway of the request: the one with the most
+// specific host matching it.
+func (r *Router) virtualServiceFor(reqHost host.Name, req Request) (config.Config, bool) {
+	var best config.Config
+	var bestHost host.Name
+	found := false
+	for _, vs := range r.virtualServices {
+		spec := vs.Spec.(*networking.VirtualService)
+		if !bindsTo(spec.Gateways, req.Gateway, vs.Namespace) {
+			continue
+		}
+		for _, h := range spec.Hosts {
+			vsHost := model.ResolveShortnameToFQDN(h, vs.Meta)
+			if !reqHost.SubsetOf(vsHost) {
+				continue
+			}
+			if !found || moreSpecific(vsHost, bestHost) {
+				best, bestHost, found = vs, vsHost, true
+			}
+		}
+	}
+	return best, found
+}
+
+// moreSpecific returns whether host a is more specific than host b, both matching the same host.
+func moreSpecific(a, b host.Name) bool {
+	if a.IsWildCarded() != b.IsWildCarded() {
+		return !a.IsWildCarded()
+	}
+	return len(a) > len(b)
+}
+
+// bindsTo returns whether the gateways, as set in a VirtualService of namespace, include the gateway of the request, or
+// the mesh if it is not sent through a gateway.
+func bindsTo(gateways []string, gateway, namespace string) bool {
+	if len(gateways) == 0 {
+		return gateway == ""
+	}
+	for _, gw := range gateways {
+		if gw == constants.IstioMeshGateway {
+			if gateway == "" {
+				return true
+			}
+			continue
+		}
+		if gateway != "" && resolveGateway(gw, namespace) == gateway {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveGateway returns the gateway as namespace/name, resolving short names against namespace.
+func resolveGateway(gw, namespace string) string {
+	ns, name, ok := strings.Cut(gw, "/")
+	if !ok {
+		return namespace + "/" + gw
+	}
+	if ns == "." {
+		ns = namespace
+	}
+	return ns + "/" + name
+}
+
+// routeWith returns how the request is routed by the HTTP routes of the VirtualService, if any matches. Delegate
+// VirtualServices are followed.
+func (r *Router) routeWith(vs config.Config, req Request, visited map[string]bool) (Result, bool, error) {
+	key := vs.Namespace + "/" + vs.Name
+	if visited[key] {
+		return Result{}, false, fmt.Errorf("VirtualService %s delegates to itself", key)
+	}
+	visited = maps.Clone(visited)
+	if visited == nil {
+		visited = map[string]bool{}
+	}
+	visited[key] = true
+	spec := vs.Spec.(*networking.VirtualService)
+	for i, hr := range spec.Http {
+		ok, err := matchesAny(vs, hr.Match, req)
+		if err != nil {
+			return Result{}, false, fmt.Errorf("route %d of VirtualService %s: %v", i, key, err)
+		}
+		if !ok {
+			continue
+		}
+		if d := hr.Delegate; d != nil {
+			delegate, found := r.find(d.Name, d.Namespace, vs.Namespace)
+			if !found {
+				return Result{}, false, fmt.Errorf("VirtualService %s delegates to %s/%s, which is not in the configuration",
+					key, d.Namespace, d.Name)
+			}
+			res, matched, err := r.routeWith(delegate, req, visited)
+			if err != nil || matched {
+				return res, matched, err
+			}
+			// Delegate routes are merged under the route which delegates, so their miss is a miss of the route.
+			continue
+		}
+		res := Result{VirtualService: key, Route: hr.Name}
+		if res.Route == "" {
+			res.Route = fmt.Sprint(i)
+		}
+		switch {
+		case hr.Redirect != nil:
+			res.Redirect = redirectTarget(hr.Redirect, req)
+		case hr.DirectResponse != nil:
+			res.DirectResponse = hr.DirectResponse.Status
+		}
+		for _, d := range hr.Route {
+			res.Destinations = append(res.Destinations, Destination{
+				Host:   model.ResolveShortnameToFQDN(d.GetDestination().GetHost(), vs.Meta),
+				Subset: d.GetDestination().GetSubset(),
+				Port:   d.GetDestination().GetPort().GetNumber(),
+				Weight: d.Weight,
+			})
+		}
+		return res, true, nil
+	}
+	return Result{}, false, nil
+}
+
+func (r *Router) find(name, namespace, defaultNamespace string) (config.Config, bool) {
+	if namespace == "" {
+		namespace = defaultNamespace
+	}
+	for _, vs := range r.virtualServices {
+		if vs.Name == name && vs.Namespace == namespace {
+			return vs, true
+		}
+	}
+	return config.Config{}, false
+}
+
+func redirectTarget(rd *networking.HTTPRedirect, req Request) string {
+	target := rd.Authority
+	if target == "" {
+		target = req.Host
+	}
+	path := rd.Uri
+	if path == "" {
+		path, _, _ = strings.Cut(req.Path, "?")
+	}
+	return target + path
+}
+
+// matchesAny returns whether the request matches one of the matches of a route of the VirtualService. The matches are
+// translated to Envoy route matches as istiod does, and evaluated as Envoy does.
+func matchesAny(vs config.Config, matches []*networking.HTTPMatchRequest, req Request) (bool, error) {
+	if len(matches) == 0 {
+		return true, nil
+	}
+	for _, m := range matches {
+		if m.Port != 0 && req.Port != 0 && m.Port != req.Port {
+			continue
+		}
+		if len(m.SourceLabels) > 0 && !labels.Instance(m.SourceLabels).SubsetOf(req.SourceLabels) {
+			continue
+		}
+		if m.SourceNamespace != "" && m.SourceNamespace != req.Namespace {
+			continue
+		}
+		if len(m.Gateways) > 0 && !bindsTo(m.Gateways, req.Gateway, vs.Namespace) {
+			continue
+		}
+		ok, err := matchesRoute(istioroute.TranslateRouteMatch(vs, m), req)
+		if err != nil || ok {
+			return ok, err
+		}
+	}
+	return false, nil
+}
+
+func matchesRoute(rm *route.RouteMatch, req Request) (bool, error) {
+	if len(rm.DynamicMetadata) > 0 {
+		return false, errors.New("matches on JWT claims are not supported")
+	}
+	path, rawQuery, _ := strings.Cut(req.Path, "?")
+	caseSensitive := rm.GetCaseSensitive() == nil || rm.GetCaseSensitive().GetValue()
+	cmpPath := func(s string) string {
+		if caseSensitive {
+			return s
+		}
+		return strings.ToLower(s)
+	}
+	switch ps := rm.PathSpecifier.(type) {
+	case *route.RouteMatch_Prefix:
+		if !strings.HasPrefix(cmpPath(path), cmpPath(ps.Prefix)) {
+			return false, nil
+		}
+	case *route.RouteMatch_Path:
+		if cmpPath(path) != cmpPath(ps.Path) {
+			return false, nil
+		}
+	case *route.RouteMatch_PathSeparatedPrefix:
+		p, prefix := cmpPath(path), cmpPath(ps.PathSeparatedPrefix)
+		if p != prefix && !strings.HasPrefix(p, prefix+"/") {
+			return false, nil
+		}
+	case *route.RouteMatch_SafeRegex:
+		ok, err := fullMatch(ps.SafeRegex.GetRegex(), path)
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+
+	headers := map[string]string{
+		istioroute.HeaderMethod:    req.Method,
+		istioroute.HeaderAuthority: req.Host,
+		istioroute.HeaderScheme:    req.Scheme,
+	}
+	for k, v := range req.Headers {
+		headers[strings.ToLower(k)] = v
+	}
+	for _, hm := range rm.Headers {
+		value, present := headers[strings.ToLower(hm.Name)]
+		var ok bool
+		switch spec := hm.HeaderMatchSpecifier.(type) {
+		case *route.HeaderMatcher_PresentMatch:
+			ok = present == spec.PresentMatch
+		case *route.HeaderMatcher_StringMatch:
+			if present || hm.TreatMissingHeaderAsEmpty {
+				var err error
+				if ok, err = matchString(spec.StringMatch, value); err != nil {
+					return false, err
+				}
+			}
+		default:
+			return false, fmt.Errorf("unsupported match on header %s", hm.Name)
+		}
+		if ok == hm.InvertMatch {
+			return false, nil
+		}
+	}
+
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return false, fmt.Errorf("invalid query string %q: %v", rawQuery, err)
+	}
+	for _, qm := range rm.QueryParameters {
+		values, present := query[qm.Name]
+		switch spec := qm.QueryParameterMatchSpecifier.(type) {
+		case *route.QueryParameterMatcher_PresentMatch:
+			if present != spec.PresentMatch {
+				return false, nil
+			}
+		case *route.QueryParameterMatcher_StringMatch:
+			if !present {
+				return false, nil
+			}
+			ok, err := matchString(spec.StringMatch, values[0])
+			if err != nil || !ok {
+				return false, err
+			}
+		}
+	}
+	return true, nil
+}
+
+func matchString(sm *matcher.StringMatcher, value string) (bool, error) {
+	if sm.IgnoreCase {
+		value = strings.ToLower(value)
+	}
+	lower := func(s string) string {
+		if sm.IgnoreCase {
+			return strings.ToLower(s)
+		}
+		return s
+	}
+	switch p := sm.MatchPattern.(type) {
+	case *matcher.StringMatcher_Exact:
+		return value == lower(p.Exact), nil
+	case *matcher.StringMatcher_Prefix:
+		return strings.HasPrefix(value, lower(p.Prefix)), nil
+	case *matcher.StringMatcher_Suffix:
+		return strings.HasSuffix(value, lower(p.Suffix)), nil
+	case *matcher.StringMatcher_Contains:
+		return strings.Contains(value, lower(p.Contains)), nil
+	case *matcher.StringMatcher_SafeRegex:
+		return fullMatch(p.SafeRegex.GetRegex(), value)
+	}
+	return false, fmt.Errorf("unsupported string match %T", sm.MatchPattern)
+}
+
+// fullMatch returns whether the regex matches the whole value, as Envoy requires.
+func fullMatch(expr, value string) (bool, error) {
+	re, err := regexp.Compile("^(?:" + expr + ")$")
+	if err != nil {
+		return false, fmt.Errorf("invalid regex %q: %v", expr, err)
+	}
+	return re.MatchString(value), nil
+}
+
+// Check returns why the result does not meet the expectation of the test, or an empty string if it does.
+func (r *Router) Check(t Test, res Result, namespace string) string {
+	if t.Request.Namespace != "" {
+		namespace = t.Request.Namespace
+	}
+	if t.Expect.VirtualService != "" && t.Expect.VirtualService != res.VirtualService {
+		return fmt.Sprintf("routed by %s, want VirtualService %s", describeVS(res.VirtualService), t.Expect.VirtualService)
+	}
+	want := Destination{
+		Host:   r.resolve(t.Expect.Host, namespace),
+		Subset: t.Expect.Subset,
+		Port:   t.Expect.Port,
+		Weight: t.Expect.Weight,
+	}
+	for _, d := range res.Destinations {
+		if d.Host != want.Host || d.Subset != want.Subset || (want.Port != 0 && d.Port != want.Port) {
+			continue
+		}
+		if want.Weight != 0 && d.Weight != want.Weight && !(len(res.Destinations) == 1 && want.Weight == 100) {
+			return fmt.Sprintf("routed to %s, want weight %d%%", res, want.Weight)
+		}
+		return ""
+	}
+	return fmt.Sprintf("routed to %s, want %s", res, want)
+}
+
+func describeVS(vs string) string {
+	if vs == "" {
+		return "default"
+	}
+	return "VirtualService " + vs
+}
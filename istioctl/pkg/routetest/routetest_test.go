@@ -0,0 +1,82 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package routetest
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"istio.io/istio/pkg/test/util/assert"
+)
+
+func TestRun(t *testing.T) {
+	configs, err := readConfigs([]string{"testdata"}, "default")
+	assert.NoError(t, err)
+	// The Deployment is skipped, and the tests are not Istio config.
+	assert.Equal(t, len(configs), 3)
+	suite, err := readSuite("testdata/routes-test.yaml")
+	assert.NoError(t, err)
+
+	results := Run(NewRouter(configs), suite, "bookinfo")
+	failed := map[string]string{}
+	for _, r := range results {
+		if r.Failure != "" {
+			failed[r.Test.Name] = r.Failure
+		}
+	}
+	assert.Equal(t, failed, map[string]string{
+		"the reviews API is only served on GET": "routed to direct response 404 (VirtualService bookinfo/reviews, route none), " +
+			"want reviews.bookinfo.svc.cluster.local subset v1",
+		"the gateway routes the product page in debug": "routed to productpage-debug.bookinfo.svc.cluster.local:9080 " +
+			"(VirtualService bookinfo/productpage, route 0), want productpage.bookinfo.svc.cluster.local",
+	})
+	assert.Equal(t, results[1].Result.String(), "reviews.bookinfo.svc.cluster.local subset v1 (90%), "+
+		"reviews.bookinfo.svc.cluster.local subset v3 (10%) (VirtualService bookinfo/reviews, route api)")
+	assert.Equal(t, results[5].Result.String(), "ratings.bookinfo.svc.cluster.local:9080 (default route)")
+
+	var out bytes.Buffer
+	assert.NoError(t, printJUnit(&out, results))
+	junit := out.String()
+	for _, want := range []string{
+		`<testsuite name="route-test" tests="6" failures="2"`,
+		`<testcase name="jason is routed to reviews v2" classname="route-test"`,
+		`<failure message="routed to direct response 404 (VirtualService bookinfo/reviews, route none), `,
+	} {
+		if !strings.Contains(junit, want) {
+			t.Fatalf("expected %q in the JUnit results, got %s", want, junit)
+		}
+	}
+}
+
+func TestRoute(t *testing.T) {
+	configs, err := readConfigs([]string{"testdata/virtualservices.yaml"}, "default")
+	assert.NoError(t, err)
+	router := NewRouter(configs)
+
+	res, err := router.Route(Request{Host: "bookinfo.example.com", Path: "/login", Gateway: "bookinfo/bookinfo-gateway"}, "bookinfo")
+	assert.NoError(t, err)
+	assert.Equal(t, res.Redirect, "bookinfo.example.com/productpage/login")
+
+	// The VirtualService of the gateway does not apply to the mesh.
+	res, err = router.Route(Request{Host: "bookinfo.example.com", Path: "/login"}, "bookinfo")
+	assert.NoError(t, err)
+	assert.Equal(t, res.VirtualService, "")
+
+	// Envoy requires regexes to match the whole path.
+	res, err = router.Route(Request{Host: "reviews", Path: "/reviews/1/ratings"}, "bookinfo")
+	assert.NoError(t, err)
+	assert.Equal(t, res.DirectResponse, uint32(404))
+}
@@ -0,0 +1,72 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scaffold
+
+// Presets are the built-in presets, as templates of the resources of a namespace. Templates are given the Namespace
+// and the trust Domain of the cluster.
+var Presets = map[string]string{
+	"production": `apiVersion: networking.istio.io/v1
+kind: DestinationRule
+metadata:
+  name: default
+  namespace: {{ .Namespace }}
+spec:
+  # Applies to the services of the namespace without a more specific DestinationRule, which replaces it entirely.
+  host: "*.{{ .Namespace }}.svc.{{ .Domain }}"
+  trafficPolicy:
+    outlierDetection:
+      consecutive5xxErrors: 5
+      interval: 10s
+      baseEjectionTime: 30s
+      maxEjectionPercent: 50
+---
+apiVersion: telemetry.istio.io/v1
+kind: Telemetry
+metadata:
+  name: default
+  namespace: {{ .Namespace }}
+spec:
+  tracing:
+  - randomSamplingPercentage: 1.0
+---
+apiVersion: security.istio.io/v1
+kind: PeerAuthentication
+metadata:
+  name: default
+  namespace: {{ .Namespace }}
+spec:
+  mtls:
+    mode: STRICT
+`,
+	"development": `apiVersion: telemetry.istio.io/v1
+kind: Telemetry
+metadata:
+  name: default
+  namespace: {{ .Namespace }}
+spec:
+  tracing:
+  - randomSamplingPercentage: 100.0
+---
+apiVersion: security.istio.io/v1
+kind: PeerAuthentication
+metadata:
+  name: default
+  namespace: {{ .Namespace }}
+spec:
+  # Workloads outside of the mesh can still connect in plain text.
+  mtls:
+    mode: PERMISSIVE
+`,
+}
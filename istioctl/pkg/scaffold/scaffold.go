@@ -0,0 +1,298 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scaffold
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/pmezard/go-difflib/difflib"
+	"github.com/spf13/cobra"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/yaml"
+
+	"istio.io/istio/istioctl/pkg/cli"
+	"istio.io/istio/pilot/pkg/config/kube/crd"
+	"istio.io/istio/pkg/config/constants"
+	"istio.io/istio/pkg/kube"
+	"istio.io/istio/pkg/maps"
+	"istio.io/istio/pkg/test/util/yml"
+	"istio.io/istio/pkg/util/sets"
+)
+
+// fieldManager is the field manager of the resources applied by scaffold.
+const fieldManager = "istioctl-scaffold"
+
+// templateData is given to the templates of the presets.
+type templateData struct {
+	Namespace string
+	Domain    string
+}
+
+func Cmd(ctx cli.Context) *cobra.Command {
+	var (
+		preset     string
+		presetFile string
+		apply      bool
+		dryRun     bool
+		diff       bool
+	)
+	cmd := &cobra.Command{
+		Use:   "scaffold",
+		Short: "Generates the recommended baseline Istio resources of a namespace",
+		Long: fmt.Sprintf(`Generates the baseline Istio resources of a namespace from a preset, to codify best practices rather
+than copying them from the documentation. The built-in presets are:
+
+  production   a DestinationRule with outlier detection for the services of the namespace, a Telemetry sampling
+               1%% of the requests for tracing, and a STRICT mTLS PeerAuthentication
+  development  a Telemetry sampling all the requests for tracing, and a PERMISSIVE mTLS PeerAuthentication
+
+Organizations can maintain their own presets with --preset-file: a file of Istio resources in YAML, as a Go template
+given {{ .Namespace }} and {{ .Domain }}, the domain of the services of the cluster.
+
+The resources are printed by default. With --diff, the changes to the resources in the cluster are printed instead,
+and with --apply they are applied with server-side apply, by the %s field manager. --dry-run validates the
+changes against the cluster without persisting them.`, fieldManager),
+		Example: `  # Print the production baseline of the bookinfo namespace
+  istioctl experimental scaffold -n bookinfo --preset production
+
+  # Show the changes the preset makes to the resources of the namespace, then apply it
+  istioctl experimental scaffold -n bookinfo --diff
+  istioctl experimental scaffold -n bookinfo --apply
+
+  # Validate an organization preset against the cluster without applying it
+  istioctl experimental scaffold -n bookinfo --preset-file acme-baseline.yaml --apply --dry-run`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if dryRun && !apply {
+				return fmt.Errorf("--dry-run requires --apply")
+			}
+			tmpl, ok := Presets[preset]
+			if presetFile != "" {
+				b, err := os.ReadFile(presetFile)
+				if err != nil {
+					return err
+				}
+				tmpl, ok = string(b), true
+			}
+			if !ok {
+				return fmt.Errorf("unknown preset %q, must be one of %s", preset, strings.Join(sets.SortedList(sets.New(maps.Keys(Presets)...)), ", "))
+			}
+			namespace := ctx.NamespaceOrDefault(ctx.Namespace())
+			objects, err := Render(tmpl, namespace, constants.DefaultClusterLocalDomain)
+			if err != nil {
+				return err
+			}
+			if !diff && !apply {
+				return printObjects(cmd.OutOrStdout(), objects)
+			}
+			kubeClient, err := ctx.CLIClient()
+			if err != nil {
+				return err
+			}
+			if diff {
+				if err := printDiff(cmd.OutOrStdout(), kubeClient, objects); err != nil {
+					return err
+				}
+			}
+			if apply {
+				return applyObjects(cmd.OutOrStdout(), kubeClient, objects, dryRun)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&preset, "preset", "production", "Built-in preset: one of development|production")
+	cmd.Flags().StringVar(&presetFile, "preset-file", "", "File of a custom preset, as a template of Istio resources, overriding --preset")
+	cmd.Flags().BoolVar(&apply, "apply", false, "Apply the resources to the cluster")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "With --apply, validate the resources against the cluster without persisting them")
+	cmd.Flags().BoolVar(&diff, "diff", false, "Print the changes to the resources in the cluster")
+	return cmd
+}
+
+// Render renders the template of a preset for the namespace, and validates the resulting Istio resources.
+func Render(tmpl, namespace, domain string) ([]*unstructured.Unstructured, error) {
+	t, err := template.New("preset").Option("missingkey=error").Parse(tmpl)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the preset: %v", err)
+	}
+	var out bytes.Buffer
+	if err := t.Execute(&out, templateData{Namespace: namespace, Domain: domain}); err != nil {
+		return nil, fmt.Errorf("failed to render the preset: %v", err)
+	}
+	var objects []*unstructured.Unstructured
+	for _, doc := range yml.SplitString(out.String()) {
+		if strings.TrimSpace(doc) == "" {
+			continue
+		}
+		obj := &unstructured.Unstructured{}
+		if err := yaml.Unmarshal([]byte(doc), &obj.Object); err != nil {
+			return nil, fmt.Errorf("failed to parse the preset: %v", err)
+		}
+		if len(obj.Object) == 0 {
+			continue
+		}
+		if obj.GetNamespace() == "" {
+			obj.SetNamespace(namespace)
+		}
+		if obj.GetNamespace() != namespace {
+			return nil, fmt.Errorf("%s %s of the preset is in namespace %s rather than %s", obj.GetKind(), obj.GetName(), obj.GetNamespace(), namespace)
+		}
+		js, err := obj.MarshalJSON()
+		if err != nil {
+			return nil, err
+		}
+		// Only valid Istio resources are accepted, so presets cannot carry arbitrary Kubernetes objects.
+		if _, others, err := crd.ParseInputs(string(js)); err != nil {
+			return nil, fmt.Errorf("invalid %s %s in the preset: %v", obj.GetKind(), obj.GetName(), err)
+		} else if len(others) > 0 {
+			return nil, fmt.Errorf("%s %s of the preset is not an Istio resource", obj.GetKind(), obj.GetName())
+		}
+		objects = append(objects, obj)
+	}
+	if len(objects) == 0 {
+		return nil, fmt.Errorf("the preset has no resource")
+	}
+	return objects, nil
+}
+
+func printObjects(w io.Writer, objects []*unstructured.Unstructured) error {
+	for i, obj := range objects {
+		b, err := yaml.Marshal(obj.Object)
+		if err != nil {
+			return err
+		}
+		if i > 0 {
+			_, _ = fmt.Fprintln(w, "---")
+		}
+		_, _ = w.Write(b)
+	}
+	return nil
+}
+
+func printDiff(w io.Writer, kubeClient kube.CLIClient, objects []*unstructured.Unstructured) error {
+	for _, obj := range objects {
+		dr, err := kubeClient.DynamicClientFor(obj.GroupVersionKind(), obj, obj.GetNamespace())
+		if err != nil {
+			return err
+		}
+		var live map[string]any
+		current, err := dr.Get(context.TODO(), obj.GetName(), metav1.GetOptions{})
+		if err != nil && !kerrors.IsNotFound(err) {
+			return fmt.Errorf("failed to retrieve %s %s: %v", obj.GetKind(), obj.GetName(), err)
+		}
+		if err == nil {
+			live = comparable(current)
+		}
+		text, err := diffObjects(obj, live)
+		if err != nil {
+			return err
+		}
+		_, _ = fmt.Fprint(w, text)
+	}
+	return nil
+}
+
+// comparable returns the fields of a live object which a preset sets, leaving out those managed by the cluster.
+func comparable(obj *unstructured.Unstructured) map[string]any {
+	out := map[string]any{
+		"apiVersion": obj.GetAPIVersion(),
+		"kind":       obj.GetKind(),
+		"metadata":   map[string]any{"name": obj.GetName(), "namespace": obj.GetNamespace()},
+	}
+	if labels := obj.GetLabels(); len(labels) > 0 {
+		out["metadata"].(map[string]any)["labels"] = labels
+	}
+	for k, v := range obj.Object {
+		if k != "apiVersion" && k != "kind" && k != "metadata" && k != "status" {
+			out[k] = v
+		}
+	}
+	return out
+}
+
+// diffObjects returns the unified diff from the live object, nil if it does not exist, to the object of the preset.
+func diffObjects(obj *unstructured.Unstructured, live map[string]any) (string, error) {
+	// Round trip through JSON, so both objects are marshaled the same way.
+	want, err := normalize(obj.Object)
+	if err != nil {
+		return "", err
+	}
+	var current string
+	if live != nil {
+		if current, err = normalize(live); err != nil {
+			return "", err
+		}
+	}
+	name := fmt.Sprintf("%s %s/%s", obj.GetKind(), obj.GetNamespace(), obj.GetName())
+	return difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        splitLines(current),
+		B:        splitLines(want),
+		FromFile: "live " + name,
+		ToFile:   "scaffold " + name,
+		Context:  3,
+	})
+}
+
+// splitLines splits the text into lines for difflib, without a spurious empty last line.
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return difflib.SplitLines(strings.TrimSuffix(s, "\n"))
+}
+
+func normalize(obj map[string]any) (string, error) {
+	js, err := json.Marshal(obj)
+	if err != nil {
+		return "", err
+	}
+	b, err := yaml.JSONToYAML(js)
+	return string(b), err
+}
+
+func applyObjects(w io.Writer, kubeClient kube.CLIClient, objects []*unstructured.Unstructured, dryRun bool) error {
+	opts := metav1.PatchOptions{FieldManager: fieldManager}
+	suffix := ""
+	if dryRun {
+		opts.DryRun = []string{metav1.DryRunAll}
+		suffix = " (server dry run)"
+	}
+	sort.SliceStable(objects, func(i, j int) bool { return objects[i].GetKind() < objects[j].GetKind() })
+	for _, obj := range objects {
+		dr, err := kubeClient.DynamicClientFor(obj.GroupVersionKind(), obj, obj.GetNamespace())
+		if err != nil {
+			return err
+		}
+		data, err := json.Marshal(obj)
+		if err != nil {
+			return err
+		}
+		if _, err := dr.Patch(context.TODO(), obj.GetName(), types.ApplyPatchType, data, opts); err != nil {
+			return fmt.Errorf("failed to apply %s %s/%s: %v", obj.GetKind(), obj.GetNamespace(), obj.GetName(), err)
+		}
+		_, _ = fmt.Fprintf(w, "%s %s/%s applied%s\n", obj.GetKind(), obj.GetNamespace(), obj.GetName(), suffix)
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scaffold
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"istio.io/istio/istioctl/pkg/cli"
+	"istio.io/istio/pkg/test/util/assert"
+)
+
+func TestRenderPresets(t *testing.T) {
+	for name, tmpl := range Presets {
+		t.Run(name, func(t *testing.T) {
+			objects, err := Render(tmpl, "bookinfo", "cluster.local")
+			assert.NoError(t, err)
+			for _, obj := range objects {
+				assert.Equal(t, obj.GetNamespace(), "bookinfo")
+				assert.Equal(t, obj.GetName(), "default")
+			}
+		})
+	}
+
+	objects, err := Render(Presets["production"], "bookinfo", "cluster.local")
+	assert.NoError(t, err)
+	var kinds []string
+	for _, obj := range objects {
+		kinds = append(kinds, obj.GetKind())
+	}
+	assert.Equal(t, kinds, []string{"DestinationRule", "Telemetry", "PeerAuthentication"})
+	host, _, _ := unstructured.NestedString(objects[0].Object, "spec", "host")
+	assert.Equal(t, host, "*.bookinfo.svc.cluster.local")
+}
+
+func TestRenderInvalid(t *testing.T) {
+	cases := []struct {
+		name  string
+		tmpl  string
+		error string
+	}{
+		{
+			name:  "unknown field of the template",
+			tmpl:  "metadata:\n  name: {{ .Cluster }}\n",
+			error: "failed to render the preset",
+		},
+		{
+			name:  "not an Istio resource",
+			tmpl:  "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: default\n",
+			error: "ConfigMap default of the preset is not an Istio resource",
+		},
+		{
+			name: "other namespace",
+			tmpl: "apiVersion: security.istio.io/v1\nkind: PeerAuthentication\nmetadata:\n  name: default\n  namespace: istio-system\n" +
+				"spec:\n  mtls:\n    mode: STRICT\n",
+			error: "is in namespace istio-system rather than bookinfo",
+		},
+		{
+			name: "invalid spec",
+			tmpl: "apiVersion: security.istio.io/v1\nkind: PeerAuthentication\nmetadata:\n  name: default\n" +
+				"spec:\n  mtls:\n    mode: SOMETIMES\n",
+			error: "invalid PeerAuthentication default in the preset",
+		},
+		{
+			name:  "empty",
+			tmpl:  "---\n",
+			error: "the preset has no resource",
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Render(tt.tmpl, "bookinfo", "cluster.local")
+			assert.Error(t, err)
+			if !strings.Contains(err.Error(), tt.error) {
+				t.Fatalf("expected error containing %q, got %v", tt.error, err)
+			}
+		})
+	}
+}
+
+func TestDiffObjects(t *testing.T) {
+	objects, err := Render(Presets["development"], "bookinfo", "cluster.local")
+	assert.NoError(t, err)
+	pa := objects[1]
+
+	// Objects missing from the cluster are entirely added.
+	diff, err := diffObjects(pa, nil)
+	assert.NoError(t, err)
+	if !strings.Contains(diff, "+    mode: PERMISSIVE") {
+		t.Fatalf("expected the new object, got:\n%s", diff)
+	}
+
+	live := pa.DeepCopy()
+	live.SetResourceVersion("42")
+	live.SetUID("abc")
+	assert.NoError(t, unstructured.SetNestedField(live.Object, "STRICT", "spec", "mtls", "mode"))
+	diff, err = diffObjects(pa, comparable(live))
+	assert.NoError(t, err)
+	assert.Equal(t, diff, `--- live PeerAuthentication bookinfo/default
++++ scaffold PeerAuthentication bookinfo/default
+@@ -5,4 +5,4 @@
+   namespace: bookinfo
+ spec:
+   mtls:
+-    mode: STRICT
++    mode: PERMISSIVE
+`)
+
+	// Unchanged objects have no diff, regardless of the fields managed by the cluster.
+	diff, err = diffObjects(pa, comparable(pa.DeepCopy()))
+	assert.NoError(t, err)
+	assert.Equal(t, diff, "")
+}
+
+func TestCmdPrint(t *testing.T) {
+	cmd := Cmd(cli.NewFakeContext(&cli.NewFakeContextOption{Namespace: "bookinfo"}))
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetArgs([]string{"--preset", "development"})
+	assert.NoError(t, cmd.Execute())
+	assert.Equal(t, strings.Count(out.String(), "namespace: bookinfo"), 2)
+
+	cmd.SetArgs([]string{"--preset", "staging"})
+	assert.Error(t, cmd.Execute())
+	cmd.SetArgs([]string{"--preset", "production", "--dry-run"})
+	assert.Error(t, cmd.Execute())
+}
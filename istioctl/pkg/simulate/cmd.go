@@ -0,0 +1,127 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package simulate
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+
+	"istio.io/istio/istioctl/pkg/cli"
+)
+
+func Cmd(ctx cli.Context) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "simulate",
+		Short: "Simulates changes to the installation of Istio without applying them",
+	}
+	cmd.AddCommand(upgradeCmd())
+	return cmd
+}
+
+type upgradeArgs struct {
+	files              []string
+	setFlags           []string
+	installedManifests string
+	targetManifests    string
+	force              bool
+	diff               bool
+}
+
+func upgradeCmd() *cobra.Command {
+	args := &upgradeArgs{}
+	cmd := &cobra.Command{
+		Use:   "upgrade",
+		Short: "Renders an IstioOperator with the installed and the target release and reports the differences",
+		Long: `Renders the IstioOperator with the charts and profiles of the installed release and of the target release,
+without contacting the cluster, and reports what the upgrade changes: the settings whose defaults differ between the
+releases, the resources added or removed, the images, environment and arguments of the containers, and the resources
+modified. The images of each release are those its charts default to, unless the IstioOperator or --set pin a tag.
+
+Each release is read from a directory of manifests, as found in the release archives, or the charts compiled into
+istioctl when not set.`,
+		Example: `  # Compare the installation of an IstioOperator with 1.23 to its installation with this istioctl
+  istioctl x simulate upgrade -f iop.yaml --installed-manifests istio-1.23.0/manifests
+
+  # Compare two releases, printing the changes to each resource
+  istioctl x simulate upgrade -f iop.yaml --installed-manifests istio-1.23.0/manifests \
+    --target-manifests istio-1.24.0/manifests --diff`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if args.installedManifests == "" && args.targetManifests == "" {
+				return fmt.Errorf("at least one of --installed-manifests and --target-manifests is required")
+			}
+			installed, err := Render(args.files, args.setFlags, args.installedManifests, args.force)
+			if err != nil {
+				return fmt.Errorf("failed to render the installed release: %v", err)
+			}
+			target, err := Render(args.files, args.setFlags, args.targetManifests, args.force)
+			if err != nil {
+				return fmt.Errorf("failed to render the target release: %v", err)
+			}
+			c, err := Compare(installed, target)
+			if err != nil {
+				return err
+			}
+			printComparison(cmd.OutOrStdout(), c, args.diff)
+			return nil
+		},
+	}
+	cmd.Flags().StringSliceVarP(&args.files, "filename", "f", nil, "Path to the IstioOperator YAML file to render")
+	cmd.Flags().StringArrayVarP(&args.setFlags, "set", "s", nil, "Override an IstioOperator value, e.g. to choose a profile (--set profile=demo)")
+	cmd.Flags().StringVar(&args.installedManifests, "installed-manifests", "",
+		"Directory of the manifests of the installed release, the charts of this istioctl if not set")
+	cmd.Flags().StringVar(&args.targetManifests, "target-manifests", "",
+		"Directory of the manifests of the target release, the charts of this istioctl if not set")
+	cmd.Flags().BoolVar(&args.force, "force", false, "Proceed even with validation errors")
+	cmd.Flags().BoolVar(&args.diff, "diff", false, "Print the unified diff of each modified resource")
+	return cmd
+}
+
+func printComparison(w io.Writer, c *Comparison, diff bool) {
+	section := func(title string, lines []string) {
+		if len(lines) == 0 {
+			return
+		}
+		_, _ = fmt.Fprintf(w, "%s:\n", title)
+		for _, l := range lines {
+			_, _ = fmt.Fprintf(w, "  %s\n", l)
+		}
+		_, _ = fmt.Fprintln(w)
+	}
+	changes := func(cs []Change) []string {
+		out := make([]string, 0, len(cs))
+		for _, ch := range cs {
+			out = append(out, ch.String())
+		}
+		return out
+	}
+	modified := sortedKeys(c.Modified)
+	if len(c.Settings) == 0 && len(c.Added) == 0 && len(c.Removed) == 0 && len(modified) == 0 {
+		_, _ = fmt.Fprintln(w, "The upgrade does not change the installation.")
+		return
+	}
+	section("Settings", changes(c.Settings))
+	section("Added resources", c.Added)
+	section("Removed resources", c.Removed)
+	section("Workloads", changes(c.Workloads))
+	section("Modified resources", modified)
+	if diff {
+		for _, key := range modified {
+			_, _ = fmt.Fprintln(w, c.Modified[key])
+		}
+	}
+}
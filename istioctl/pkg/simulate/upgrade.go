@@ -0,0 +1,300 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package simulate
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+
+	"istio.io/istio/operator/pkg/manifest"
+	"istio.io/istio/operator/pkg/render"
+	"istio.io/istio/operator/pkg/values"
+)
+
+// Rendering is the installation an IstioOperator renders to with a release of the charts.
+type Rendering struct {
+	// Settings are the leaves of the merged IstioOperator spec, including the defaults of the profile, by path.
+	Settings map[string]string
+	// Objects are the rendered objects, by manifest.ObjectHash.
+	Objects map[string]*unstructured.Unstructured
+}
+
+// Render renders the IstioOperator files and --set flags with the charts and profiles of the manifests directory, or
+// the ones compiled into istioctl if empty. No cluster is contacted.
+func Render(files, setFlags []string, manifestsPath string, force bool) (*Rendering, error) {
+	if manifestsPath != "" {
+		pinned, err := pinsImages(files, setFlags)
+		if err != nil {
+			return nil, err
+		}
+		// istioctl overrides the images of the charts with its own, unless the configuration pins them, so the images of
+		// the release are set explicitly.
+		if !pinned {
+			setFlags = append(chartImages(manifestsPath), setFlags...)
+		}
+		setFlags = append(setFlags, "installPackagePath="+manifestsPath)
+	}
+	sets, merged, err := render.GenerateManifest(files, setFlags, force, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	r := &Rendering{Settings: map[string]string{}, Objects: map[string]*unstructured.Unstructured{}}
+	spec, _ := merged.GetPathMap("spec")
+	// The location of the charts differs by design.
+	delete(spec, "installPackagePath")
+	flatten("", map[string]any(spec), r.Settings)
+	for _, set := range sets {
+		for _, m := range set.Manifests {
+			r.Objects[manifest.ObjectHash(m.Unstructured)] = m.Unstructured
+		}
+	}
+	return r, nil
+}
+
+// pinsImages returns whether the IstioOperator files or --set flags set the tag of the images.
+func pinsImages(files, setFlags []string) (bool, error) {
+	for _, f := range setFlags {
+		if strings.HasPrefix(f, "tag=") || strings.HasPrefix(f, "spec.tag=") || strings.HasPrefix(f, "values.global.tag=") {
+			return true, nil
+		}
+	}
+	for _, fn := range files {
+		b, err := os.ReadFile(fn)
+		if err != nil {
+			return false, err
+		}
+		m, err := values.MapFromYaml(b)
+		if err != nil {
+			return false, fmt.Errorf("failed to parse %s: %v", fn, err)
+		}
+		if m.GetPathString("spec.tag") != "" || m.GetPathString("spec.values.global.tag") != "" {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// chartImages returns the settings of the hub and tag the charts of the release default to.
+func chartImages(manifestsPath string) []string {
+	b, err := os.ReadFile(filepath.Join(manifestsPath, "charts", "istio-control", "istio-discovery", "values.yaml"))
+	if err != nil {
+		return nil
+	}
+	m, err := values.MapFromYaml(b)
+	if err != nil {
+		return nil
+	}
+	var settings []string
+	for _, field := range []string{"hub", "tag"} {
+		if v := m.GetPathString("_internal_defaults_do_not_set.global." + field); v != "" {
+			settings = append(settings, field+"="+v)
+		}
+	}
+	return settings
+}
+
+func flatten(prefix string, v any, out map[string]string) {
+	if m, ok := v.(map[string]any); ok && len(m) > 0 {
+		for k, child := range m {
+			path := k
+			if prefix != "" {
+				path = prefix + "." + k
+			}
+			flatten(path, child, out)
+		}
+		return
+	}
+	if v == nil {
+		return
+	}
+	b, _ := json.Marshal(v)
+	out[prefix] = string(b)
+}
+
+// Change is a change between the installed and the target release.
+type Change struct {
+	// Kind is the kind of change: Setting, Image, Environment or Args.
+	Kind    string
+	Subject string
+	From    string
+	To      string
+}
+
+func (c Change) String() string {
+	switch {
+	case c.From == "":
+		return fmt.Sprintf("%s %s: added %s", c.Kind, c.Subject, c.To)
+	case c.To == "":
+		return fmt.Sprintf("%s %s: removed (was %s)", c.Kind, c.Subject, c.From)
+	}
+	return fmt.Sprintf("%s %s: %s -> %s", c.Kind, c.Subject, c.From, c.To)
+}
+
+// Comparison is the difference between the renderings of the installed and the target release.
+type Comparison struct {
+	// Settings are the changes of the settings, in particular of the defaults of the profile.
+	Settings []Change
+	// Added and Removed are the objects only the target, or only the installed release renders.
+	Added   []string
+	Removed []string
+	// Workloads are the changes of the images, environment and arguments of the containers.
+	Workloads []Change
+	// Modified are the objects modified, by manifest.ObjectHash, with the unified diff of each.
+	Modified map[string]string
+}
+
+// Compare returns the changes from the installed to the target rendering.
+func Compare(installed, target *Rendering) (*Comparison, error) {
+	c := &Comparison{Modified: map[string]string{}}
+	c.Settings = compareMaps("Setting", "", installed.Settings, target.Settings)
+
+	for _, key := range sortedKeys(installed.Objects, target.Objects) {
+		from, to := installed.Objects[key], target.Objects[key]
+		switch {
+		case to == nil:
+			c.Removed = append(c.Removed, key)
+		case from == nil:
+			c.Added = append(c.Added, key)
+		default:
+			diff, err := diffObjects(key, from, to)
+			if err != nil {
+				return nil, err
+			}
+			if diff == "" {
+				continue
+			}
+			c.Modified[key] = diff
+			c.Workloads = append(c.Workloads, compareContainers(key, from, to)...)
+		}
+	}
+	return c, nil
+}
+
+// compareContainers returns the changes of the images, environment and arguments of the containers of a workload.
+func compareContainers(key string, from, to *unstructured.Unstructured) []Change {
+	fromContainers, toContainers := containersOf(from), containersOf(to)
+	var changes []Change
+	for _, name := range sortedKeys(fromContainers, toContainers) {
+		f, t := fromContainers[name], toContainers[name]
+		subject := key + " container " + name
+		if f.image != t.image {
+			changes = append(changes, Change{Kind: "Image", Subject: subject, From: f.image, To: t.image})
+		}
+		if f.args != t.args {
+			changes = append(changes, Change{Kind: "Args", Subject: subject, From: f.args, To: t.args})
+		}
+		changes = append(changes, compareMaps("Environment", subject+" ", f.env, t.env)...)
+	}
+	return changes
+}
+
+type container struct {
+	image string
+	args  string
+	env   map[string]string
+}
+
+func containersOf(obj *unstructured.Unstructured) map[string]container {
+	out := map[string]container{}
+	for _, field := range []string{"initContainers", "containers"} {
+		list, _, _ := unstructured.NestedSlice(obj.Object, "spec", "template", "spec", field)
+		for _, item := range list {
+			m, ok := item.(map[string]any)
+			if !ok {
+				continue
+			}
+			c := container{env: map[string]string{}}
+			c.image, _ = m["image"].(string)
+			if args, ok := m["args"].([]any); ok {
+				strs := make([]string, 0, len(args))
+				for _, a := range args {
+					strs = append(strs, fmt.Sprint(a))
+				}
+				c.args = strings.Join(strs, " ")
+			}
+			env, _ := m["env"].([]any)
+			for _, e := range env {
+				em, ok := e.(map[string]any)
+				if !ok {
+					continue
+				}
+				name, _ := em["name"].(string)
+				if value, ok := em["value"]; ok {
+					c.env[name] = fmt.Sprint(value)
+				} else {
+					b, _ := json.Marshal(em["valueFrom"])
+					c.env[name] = string(b)
+				}
+			}
+			name, _ := m["name"].(string)
+			out[name] = c
+		}
+	}
+	return out
+}
+
+func compareMaps(kind, prefix string, from, to map[string]string) []Change {
+	var changes []Change
+	for _, k := range sortedKeys(from, to) {
+		if from[k] != to[k] {
+			changes = append(changes, Change{Kind: kind, Subject: prefix + k, From: from[k], To: to[k]})
+		}
+	}
+	return changes
+}
+
+func diffObjects(key string, from, to *unstructured.Unstructured) (string, error) {
+	a, err := yaml.Marshal(from.Object)
+	if err != nil {
+		return "", err
+	}
+	b, err := yaml.Marshal(to.Object)
+	if err != nil {
+		return "", err
+	}
+	if string(a) == string(b) {
+		return "", nil
+	}
+	return difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(strings.TrimSuffix(string(a), "\n")),
+		B:        difflib.SplitLines(strings.TrimSuffix(string(b), "\n")),
+		FromFile: "installed " + key,
+		ToFile:   "target " + key,
+		Context:  3,
+	})
+}
+
+func sortedKeys[T any](maps ...map[string]T) []string {
+	keys := map[string]struct{}{}
+	for _, m := range maps {
+		for k := range m {
+			keys[k] = struct{}{}
+		}
+	}
+	out := make([]string, 0, len(keys))
+	for k := range keys {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
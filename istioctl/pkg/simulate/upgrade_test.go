@@ -0,0 +1,105 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package simulate
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"istio.io/istio/pkg/test/env"
+	"istio.io/istio/pkg/test/util/assert"
+)
+
+func deployment(image string, env map[string]any) *unstructured.Unstructured {
+	var envList []any
+	for k, v := range env {
+		envList = append(envList, map[string]any{"name": k, "value": v})
+	}
+	return &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"metadata":   map[string]any{"name": "istiod", "namespace": "istio-system"},
+		"spec": map[string]any{"template": map[string]any{"spec": map[string]any{"containers": []any{
+			map[string]any{"name": "discovery", "image": image, "args": []any{"discovery"}, "env": envList},
+		}}}},
+	}}
+}
+
+func TestCompare(t *testing.T) {
+	installed := &Rendering{
+		Settings: map[string]string{"values.pilot.autoscaleMin": "1", "values.global.logAsJson": "false"},
+		Objects: map[string]*unstructured.Unstructured{
+			"Deployment:istio-system:istiod":         deployment("istio/pilot:1.23.0", map[string]any{"PILOT_ENABLE_X": "true"}),
+			"ServiceAccount:istio-system:istio-cni":  {Object: map[string]any{"kind": "ServiceAccount"}},
+			"ConfigMap:istio-system:istio-unchanged": {Object: map[string]any{"kind": "ConfigMap"}},
+		},
+	}
+	target := &Rendering{
+		Settings: map[string]string{"values.pilot.autoscaleMin": "2", "values.pilot.cni.enabled": "true"},
+		Objects: map[string]*unstructured.Unstructured{
+			"Deployment:istio-system:istiod":         deployment("istio/pilot:1.24.0", map[string]any{"PILOT_ENABLE_Y": "1"}),
+			"ConfigMap:istio-system:istio-unchanged": {Object: map[string]any{"kind": "ConfigMap"}},
+			"Service:istio-system:istiod-revision":   {Object: map[string]any{"kind": "Service"}},
+		},
+	}
+	c, err := Compare(installed, target)
+	assert.NoError(t, err)
+	assert.Equal(t, c.Settings, []Change{
+		{Kind: "Setting", Subject: "values.global.logAsJson", From: "false"},
+		{Kind: "Setting", Subject: "values.pilot.autoscaleMin", From: "1", To: "2"},
+		{Kind: "Setting", Subject: "values.pilot.cni.enabled", To: "true"},
+	})
+	assert.Equal(t, c.Added, []string{"Service:istio-system:istiod-revision"})
+	assert.Equal(t, c.Removed, []string{"ServiceAccount:istio-system:istio-cni"})
+	subject := "Deployment:istio-system:istiod container discovery"
+	assert.Equal(t, c.Workloads, []Change{
+		{Kind: "Image", Subject: subject, From: "istio/pilot:1.23.0", To: "istio/pilot:1.24.0"},
+		{Kind: "Environment", Subject: subject + " PILOT_ENABLE_X", From: "true"},
+		{Kind: "Environment", Subject: subject + " PILOT_ENABLE_Y", To: "1"},
+	})
+	assert.Equal(t, sortedKeys(c.Modified), []string{"Deployment:istio-system:istiod"})
+	assert.Equal(t, strings.Contains(c.Modified["Deployment:istio-system:istiod"], "+        image: istio/pilot:1.24.0"), true)
+
+	var out bytes.Buffer
+	printComparison(&out, c, false)
+	assert.Equal(t, strings.Contains(out.String(), "Workloads:\n  Image "+subject+": istio/pilot:1.23.0 -> istio/pilot:1.24.0\n"), true)
+}
+
+func TestRenderSameRelease(t *testing.T) {
+	manifests := env.IstioSrc + "/manifests"
+	installed, err := Render(nil, []string{"profile=minimal"}, manifests, false)
+	assert.NoError(t, err)
+	target, err := Render(nil, []string{"profile=minimal"}, manifests, false)
+	assert.NoError(t, err)
+	assert.Equal(t, installed.Settings["profile"], `"minimal"`)
+	c, err := Compare(installed, target)
+	assert.NoError(t, err)
+
+	var out bytes.Buffer
+	printComparison(&out, c, true)
+	assert.Equal(t, out.String(), "The upgrade does not change the installation.\n")
+}
+
+func TestPinsImages(t *testing.T) {
+	pinned, err := pinsImages(nil, []string{"profile=demo"})
+	assert.NoError(t, err)
+	assert.Equal(t, pinned, false)
+	pinned, err = pinsImages(nil, []string{"tag=1.23.1"})
+	assert.NoError(t, err)
+	assert.Equal(t, pinned, true)
+}
@@ -0,0 +1,395 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package slowstart
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"text/tabwriter"
+	"time"
+
+	"github.com/prometheus/client_golang/api"
+	promv1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	klabels "k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/yaml"
+
+	clientnetworking "istio.io/client-go/pkg/apis/networking/v1"
+	"istio.io/istio/istioctl/pkg/cli"
+	"istio.io/istio/istioctl/pkg/dashboard"
+	"istio.io/istio/pkg/config/constants"
+	"istio.io/istio/pkg/kube"
+	"istio.io/istio/pkg/kube/inject"
+)
+
+// step is the resolution of the errors and latency queried around the scale ups.
+const step = 15 * time.Second
+
+func Cmd(ctx cli.Context) *cobra.Command {
+	var (
+		lookback     time.Duration
+		window       time.Duration
+		generate     bool
+		preconnect   bool
+		verify       bool
+		changedAt    string
+		outputFormat string
+	)
+	cmd := &cobra.Command{
+		Use:   "slow-start",
+		Short: "Recommends warmup settings for destinations whose errors or latency spike after scale ups",
+		Long: `Detects the destinations whose errors or latency spike right after new pods become ready, as after a scale up or
+a rollout, and recommends the warmup of their DestinationRule, ramping up the traffic of new endpoints.
+
+The scale ups are the times the current pods of the mesh became ready within --lookback. The error ratio and the p99
+latency of the requests to their workload are queried from Prometheus over --window before and after each scale up: a
+scale up spikes when they rise well above the baseline before it. The recommended warmup covers the longest time to
+recover from the spikes of the destination.
+
+With --generate, the DestinationRules applying the recommendations are printed, updating the existing DestinationRule
+of the host if any. With --preconnect, an EnvoyFilter having the clients of the host establish connections ahead of
+their requests is added, which spreads the connection setup to new endpoints.
+
+With --verify, the scale ups of each destination before and after the last change of its DestinationRule, or
+--changed-at, are compared instead, to check that the warmup reduced the spikes.
+
+Requires a Prometheus pod labeled app.kubernetes.io/name=prometheus in the Istio namespace.`,
+		Example: `  # Recommend warmup settings for the destinations of the bookinfo namespace
+  istioctl experimental slow-start -n bookinfo
+
+  # Print the DestinationRules and EnvoyFilters applying the recommendations
+  istioctl experimental slow-start -n bookinfo --generate --preconnect > warmup.yaml
+
+  # Compare the scale ups before and after the warmup was configured
+  istioctl experimental slow-start -n bookinfo --verify --lookback 168h`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if outputFormat != "table" && outputFormat != "json" {
+				return fmt.Errorf("unknown output format %q, must be table or json", outputFormat)
+			}
+			if generate && verify {
+				return errors.New("--generate and --verify are mutually exclusive")
+			}
+			if preconnect && !generate {
+				return errors.New("--preconnect requires --generate")
+			}
+			var changed time.Time
+			if changedAt != "" {
+				if !verify {
+					return errors.New("--changed-at requires --verify")
+				}
+				var err error
+				if changed, err = time.Parse(time.RFC3339, changedAt); err != nil {
+					return fmt.Errorf("invalid --changed-at %q, must be RFC3339: %v", changedAt, err)
+				}
+			}
+			kubeClient, err := ctx.CLIClient()
+			if err != nil {
+				return err
+			}
+			now := time.Now()
+			pods, err := kubeClient.Kube().CoreV1().Pods(ctx.Namespace()).List(context.TODO(), metav1.ListOptions{})
+			if err != nil {
+				return fmt.Errorf("failed to list pods: %v", err)
+			}
+			var proxies []corev1.Pod
+			for _, pod := range pods.Items {
+				if inject.FindSidecar(&pod) != nil {
+					proxies = append(proxies, pod)
+				}
+			}
+			events := ScaleUps(proxies, now.Add(-lookback), now.Add(-window), window)
+			destinations, err := listDestinations(kubeClient, proxies, events)
+			if err != nil {
+				return err
+			}
+			impacts, err := measureImpacts(kubeClient, ctx.IstioNamespace(), events, window)
+			if err != nil {
+				return err
+			}
+			w := cmd.OutOrStdout()
+			if verify {
+				changes := map[string]time.Time{}
+				for _, d := range destinations {
+					switch {
+					case !changed.IsZero():
+						changes[d.Namespace+"/"+d.Workload] = changed
+					case d.DestinationRule != nil:
+						changes[d.Namespace+"/"+d.Workload] = lastUpdated(d.DestinationRule.ObjectMeta)
+					}
+				}
+				return printVerifications(w, Verify(impacts, changes), outputFormat)
+			}
+			recommendations := Recommend(destinations, impacts)
+			if generate {
+				objects, err := Generate(destinations, recommendations, preconnect)
+				if err != nil {
+					return err
+				}
+				return printObjects(w, objects)
+			}
+			return printRecommendations(w, recommendations, outputFormat)
+		},
+	}
+	cmd.Flags().DurationVar(&lookback, "lookback", 24*time.Hour, "How far back to search for scale ups")
+	cmd.Flags().DurationVar(&window, "window", 5*time.Minute, "Duration before and after each scale up to compare")
+	cmd.Flags().BoolVar(&generate, "generate", false, "Print the config applying the recommendations")
+	cmd.Flags().BoolVar(&preconnect, "preconnect", false, "Also generate EnvoyFilters preconnecting the clients of the destinations")
+	cmd.Flags().BoolVar(&verify, "verify", false, "Compare the scale ups before and after the last change of the DestinationRules")
+	cmd.Flags().StringVar(&changedAt, "changed-at", "", "The RFC3339 time of the change to verify, instead of the last change of the DestinationRules")
+	cmd.Flags().StringVarP(&outputFormat, "output", "o", "table", "Output format: one of table|json")
+	return cmd
+}
+
+// listDestinations returns the destinations of the workloads of the events: the Service selecting their pods, and
+// its DestinationRule.
+func listDestinations(kubeClient kube.CLIClient, pods []corev1.Pod, events []Event) ([]Destination, error) {
+	var res []Destination
+	drs, err := kubeClient.Istio().NetworkingV1().DestinationRules(metav1.NamespaceAll).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list destination rules: %v", err)
+	}
+	services := map[string][]corev1.Service{}
+	for _, e := range events {
+		if len(res) > 0 && res[len(res)-1].Namespace == e.Namespace && res[len(res)-1].Workload == e.Workload {
+			continue
+		}
+		d := Destination{Namespace: e.Namespace, Workload: e.Workload}
+		if _, ok := services[e.Namespace]; !ok {
+			svcs, err := kubeClient.Kube().CoreV1().Services(e.Namespace).List(context.TODO(), metav1.ListOptions{})
+			if err != nil {
+				return nil, fmt.Errorf("failed to list services: %v", err)
+			}
+			services[e.Namespace] = svcs.Items
+		}
+		if svc := selectingService(services[e.Namespace], pods, e); svc != nil {
+			d.Host = fmt.Sprintf("%s.%s.svc.%s", svc.Name, svc.Namespace, constants.DefaultClusterLocalDomain)
+			d.DestinationRule = destinationRuleFor(drs.Items, svc)
+		}
+		res = append(res, d)
+	}
+	return res, nil
+}
+
+// selectingService returns the first Service selecting a pod of the workload of the event, nil if none.
+func selectingService(services []corev1.Service, pods []corev1.Pod, e Event) *corev1.Service {
+	for i := range pods {
+		workload, _ := kube.GetDeployMetaFromPod(&pods[i])
+		if workload.Namespace != e.Namespace || workload.Name != e.Workload {
+			continue
+		}
+		for j := range services {
+			selector := services[j].Spec.Selector
+			if len(selector) > 0 && klabels.SelectorFromSet(selector).Matches(klabels.Set(pods[i].Labels)) {
+				return &services[j]
+			}
+		}
+	}
+	return nil
+}
+
+// destinationRuleFor returns the DestinationRule of the host of the Service, nil if none.
+func destinationRuleFor(drs []*clientnetworking.DestinationRule, svc *corev1.Service) *clientnetworking.DestinationRule {
+	fqdn := fmt.Sprintf("%s.%s.svc.%s", svc.Name, svc.Namespace, constants.DefaultClusterLocalDomain)
+	for _, dr := range drs {
+		host := dr.Spec.GetHost()
+		if host == fqdn || dr.Namespace == svc.Namespace && (host == svc.Name || host == svc.Name+"."+svc.Namespace) {
+			return dr
+		}
+	}
+	return nil
+}
+
+func lastUpdated(meta metav1.ObjectMeta) time.Time {
+	last := meta.CreationTimestamp.Time
+	for _, f := range meta.ManagedFields {
+		if f.Time != nil && f.Time.After(last) {
+			last = f.Time.Time
+		}
+	}
+	return last
+}
+
+// measureImpacts queries the errors and latency of the workload of each event around it from Prometheus.
+func measureImpacts(kubeClient kube.CLIClient, istioNamespace string, events []Event, window time.Duration) ([]Impact, error) {
+	if len(events) == 0 {
+		return nil, nil
+	}
+	promAPI, closer, err := prometheusAPI(kubeClient, istioNamespace)
+	if err != nil {
+		return nil, err
+	}
+	defer closer()
+	var res []Impact
+	for _, e := range events {
+		selector := fmt.Sprintf(`reporter="source",destination_workload=%q,destination_workload_namespace=%q`, e.Workload, e.Namespace)
+		r := promv1.Range{Start: e.Time.Add(-window), End: e.Time.Add(window), Step: step}
+		errorRatios, err := queryRange(promAPI, fmt.Sprintf(
+			`sum(rate(istio_requests_total{%s,response_code=~"5.."}[1m])) / sum(rate(istio_requests_total{%s}[1m]))`, selector, selector), r)
+		if err != nil {
+			return nil, err
+		}
+		p99s, err := queryRange(promAPI, fmt.Sprintf(
+			`histogram_quantile(0.99, sum by (le) (rate(istio_request_duration_milliseconds_bucket{%s}[1m])))`, selector), r)
+		if err != nil {
+			return nil, err
+		}
+		var points []Point
+		for t := r.Start; !t.After(r.End); t = t.Add(step) {
+			p := Point{Time: t, ErrorRatio: math.NaN(), P99: math.NaN()}
+			if v, ok := errorRatios[t.Unix()]; ok {
+				p.ErrorRatio = v
+			}
+			if v, ok := p99s[t.Unix()]; ok {
+				p.P99 = v
+			}
+			points = append(points, p)
+		}
+		if impact, ok := Measure(e, points); ok {
+			res = append(res, impact)
+		}
+	}
+	return res, nil
+}
+
+// queryRange returns the values of the single series of the query, by unix time.
+func queryRange(promAPI promv1.API, query string, r promv1.Range) (map[int64]float64, error) {
+	val, _, err := promAPI.QueryRange(context.Background(), query, r)
+	if err != nil {
+		return nil, fmt.Errorf("query_range() failure for '%s': %v", query, err)
+	}
+	matrix, ok := val.(model.Matrix)
+	if !ok {
+		return nil, errors.New("bad metric value type returned for query")
+	}
+	res := map[int64]float64{}
+	for _, s := range matrix {
+		for _, v := range s.Values {
+			res[v.Timestamp.Unix()] = float64(v.Value)
+		}
+	}
+	return res, nil
+}
+
+func prometheusAPI(kubeClient kube.CLIClient, istioNamespace string) (promv1.API, func(), error) {
+	pl, err := kubeClient.PodsForSelector(context.TODO(), istioNamespace, "app.kubernetes.io/name=prometheus")
+	if err != nil {
+		return nil, nil, fmt.Errorf("not able to locate Prometheus pod: %v", err)
+	}
+	if len(pl.Items) < 1 {
+		return nil, nil, errors.New("no Prometheus pods found")
+	}
+	fw, err := kubeClient.NewPortForwarder(pl.Items[0].Name, istioNamespace, "", 0, 9090)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not build port forwarder for prometheus: %v", err)
+	}
+	if err = fw.Start(); err != nil {
+		return nil, nil, fmt.Errorf("failure running port forward process: %v", err)
+	}
+	dashboard.ClosePortForwarderOnInterrupt(fw)
+	promClient, err := api.NewClient(api.Config{Address: fmt.Sprintf("http://%s", fw.Address())})
+	if err != nil {
+		fw.Close()
+		return nil, nil, fmt.Errorf("could not build prometheus client: %v", err)
+	}
+	return promv1.NewAPI(promClient), fw.Close, nil
+}
+
+func printJSON(w io.Writer, v any) error {
+	out, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(out))
+	return err
+}
+
+func printRecommendations(w io.Writer, recommendations []Recommendation, outputFormat string) error {
+	if outputFormat == "json" {
+		if recommendations == nil {
+			recommendations = []Recommendation{}
+		}
+		return printJSON(w, recommendations)
+	}
+	if len(recommendations) == 0 {
+		_, err := fmt.Fprintln(w, "No scale up with traffic found.")
+		return err
+	}
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "NAMESPACE\tWORKLOAD\tHOST\tSCALE UPS\tSPIKES\tCURRENT WARMUP\tRECOMMENDED WARMUP\tNOTE")
+	for _, r := range recommendations {
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%d\t%s\t%s\t%s\n", r.Namespace, r.Workload, orDash(r.Host), r.Events, r.Spikes,
+			durationOrDash(r.Current), durationOrDash(r.Warmup), orDash(r.Note))
+	}
+	return tw.Flush()
+}
+
+func printVerifications(w io.Writer, verifications []Verification, outputFormat string) error {
+	if outputFormat == "json" {
+		if verifications == nil {
+			verifications = []Verification{}
+		}
+		return printJSON(w, verifications)
+	}
+	if len(verifications) == 0 {
+		_, err := fmt.Fprintln(w, "No scale up of a destination with a DestinationRule found.")
+		return err
+	}
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "NAMESPACE\tWORKLOAD\tCHANGED AT\tSPIKES BEFORE\tSPIKES AFTER\tRECOVERY BEFORE\tRECOVERY AFTER\tIMPROVED")
+	for _, v := range verifications {
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%d/%d\t%d/%d\t%s\t%s\t%t\n", v.Namespace, v.Workload, v.ChangedAt.Format(time.RFC3339),
+			v.Before.Spikes, v.Before.Events, v.After.Spikes, v.After.Events,
+			durationOrDash(v.Before.MaxRecovery), durationOrDash(v.After.MaxRecovery), v.Improved())
+	}
+	return tw.Flush()
+}
+
+func printObjects(w io.Writer, objects []*unstructured.Unstructured) error {
+	if len(objects) == 0 {
+		_, err := fmt.Fprintln(w, "# No warmup to configure.")
+		return err
+	}
+	for _, obj := range objects {
+		b, err := yaml.Marshal(obj.Object)
+		if err != nil {
+			return err
+		}
+		_, _ = fmt.Fprintf(w, "---\n%s", b)
+	}
+	return nil
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func durationOrDash(d time.Duration) string {
+	if d == 0 {
+		return "-"
+	}
+	return d.String()
+}
@@ -0,0 +1,450 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package slowstart
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	networking "istio.io/api/networking/v1alpha3"
+	clientnetworking "istio.io/client-go/pkg/apis/networking/v1"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/schema/gvk"
+	"istio.io/istio/pkg/kube"
+	"istio.io/istio/pkg/util/protomarshal"
+)
+
+const (
+	// errorSpikeFactor and minErrorRatioIncrease define an error spike: an error ratio at least twice the baseline,
+	// and one point above it.
+	errorSpikeFactor      = 2
+	minErrorRatioIncrease = 0.01
+	// latencySpikeFactor and minLatencyIncrease define a latency spike: a p99 latency 50% above the baseline, and
+	// 20ms above it.
+	latencySpikeFactor = 1.5
+	minLatencyIncrease = 20
+
+	// minWarmup is the shortest warmup recommended, as shorter ones barely ramp up the traffic of new endpoints.
+	minWarmup = 30 * time.Second
+	// warmupMinimumPercent is the share of its traffic a new endpoint receives at the start of the recommended warmup.
+	warmupMinimumPercent = 10
+	// preconnectRatio is the number of connections established ahead of the requests, per request in flight.
+	preconnectRatio = 1.5
+)
+
+// Event is the time new pods of a destination workload became ready, after a scale up or a rollout.
+type Event struct {
+	Namespace string    `json:"namespace"`
+	Workload  string    `json:"workload"`
+	Time      time.Time `json:"time"`
+	// Pods is the number of pods which became ready.
+	Pods int `json:"pods"`
+}
+
+// ScaleUps returns the events of the pods becoming ready between the times. The pods of a workload becoming ready
+// within the window of the first one are a single event. Only the current pods are known, so the scale ups of pods
+// which were since deleted are missing.
+func ScaleUps(pods []corev1.Pod, since, until time.Time, window time.Duration) []Event {
+	readyTimes := map[Event][]time.Time{}
+	for i := range pods {
+		pod := &pods[i]
+		ready := readySince(pod)
+		if ready.IsZero() || ready.Before(since) || ready.After(until) {
+			continue
+		}
+		workload, _ := kube.GetDeployMetaFromPod(pod)
+		key := Event{Namespace: workload.Namespace, Workload: workload.Name}
+		readyTimes[key] = append(readyTimes[key], ready)
+	}
+	var events []Event
+	for key, times := range readyTimes {
+		sort.Slice(times, func(i, j int) bool {
+			return times[i].Before(times[j])
+		})
+		for _, t := range times {
+			if n := len(events) - 1; n >= 0 && events[n].Namespace == key.Namespace && events[n].Workload == key.Workload &&
+				t.Sub(events[n].Time) < window {
+				events[n].Pods++
+				continue
+			}
+			events = append(events, Event{Namespace: key.Namespace, Workload: key.Workload, Time: t, Pods: 1})
+		}
+	}
+	sort.Slice(events, func(i, j int) bool {
+		if events[i].Namespace != events[j].Namespace {
+			return events[i].Namespace < events[j].Namespace
+		}
+		if events[i].Workload != events[j].Workload {
+			return events[i].Workload < events[j].Workload
+		}
+		return events[i].Time.Before(events[j].Time)
+	})
+	return events
+}
+
+// readySince returns the time the pod became ready, zero if it is not ready.
+func readySince(pod *corev1.Pod) time.Time {
+	for _, c := range pod.Status.Conditions {
+		if c.Type == corev1.PodReady && c.Status == corev1.ConditionTrue {
+			return c.LastTransitionTime.Time
+		}
+	}
+	return time.Time{}
+}
+
+// Point is the error ratio and the p99 latency, in milliseconds, of the requests to a destination at a time. Values
+// are NaN when unknown, such as without requests.
+type Point struct {
+	Time       time.Time
+	ErrorRatio float64
+	P99        float64
+}
+
+// Impact is the change of the errors and latency of a destination after an event, relative to the baseline before it.
+type Impact struct {
+	Event
+	BaselineErrorRatio float64 `json:"baselineErrorRatio"`
+	PeakErrorRatio     float64 `json:"peakErrorRatio"`
+	BaselineP99        float64 `json:"baselineP99Ms"`
+	PeakP99            float64 `json:"peakP99Ms"`
+	// Recovery is the time from the event to the last spike of errors or latency, zero without spike.
+	Recovery time.Duration `json:"recovery"`
+}
+
+// Spiking returns whether the errors or latency of the destination spiked after the event.
+func (i Impact) Spiking() bool {
+	return i.Recovery > 0
+}
+
+// Measure returns the impact of the event on the errors and latency of its destination, from the points of the
+// destination around the event. It returns false if there is no baseline before the event, or no point after it.
+func Measure(e Event, points []Point) (Impact, bool) {
+	impact := Impact{Event: e}
+	var errorRatios, p99s []float64
+	var after []Point
+	for _, p := range points {
+		if p.Time.After(e.Time) {
+			after = append(after, p)
+			continue
+		}
+		if !math.IsNaN(p.ErrorRatio) {
+			errorRatios = append(errorRatios, p.ErrorRatio)
+		}
+		if !math.IsNaN(p.P99) {
+			p99s = append(p99s, p.P99)
+		}
+	}
+	if len(errorRatios) == 0 || len(after) == 0 {
+		return impact, false
+	}
+	impact.BaselineErrorRatio = mean(errorRatios)
+	if len(p99s) > 0 {
+		impact.BaselineP99 = mean(p99s)
+	}
+	for _, p := range after {
+		spiking := false
+		if !math.IsNaN(p.ErrorRatio) {
+			impact.PeakErrorRatio = math.Max(impact.PeakErrorRatio, p.ErrorRatio)
+			spiking = p.ErrorRatio >= math.Max(impact.BaselineErrorRatio*errorSpikeFactor, impact.BaselineErrorRatio+minErrorRatioIncrease)
+		}
+		if !math.IsNaN(p.P99) {
+			impact.PeakP99 = math.Max(impact.PeakP99, p.P99)
+			spiking = spiking || len(p99s) > 0 &&
+				p.P99 >= impact.BaselineP99*latencySpikeFactor && p.P99-impact.BaselineP99 >= minLatencyIncrease
+		}
+		if spiking {
+			impact.Recovery = p.Time.Sub(e.Time)
+		}
+	}
+	return impact, true
+}
+
+func mean(values []float64) float64 {
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
+
+// Destination is a workload receiving the traffic of a Service, and the DestinationRule of its host, if any.
+type Destination struct {
+	Namespace       string
+	Workload        string
+	Host            string
+	DestinationRule *clientnetworking.DestinationRule
+}
+
+func (d Destination) loadBalancer() *networking.LoadBalancerSettings {
+	if d.DestinationRule == nil {
+		return nil
+	}
+	return d.DestinationRule.Spec.GetTrafficPolicy().GetLoadBalancer()
+}
+
+// Recommendation is the slow start config recommended for a destination, from the impact of its scale ups.
+type Recommendation struct {
+	Namespace string `json:"namespace"`
+	Workload  string `json:"workload"`
+	Host      string `json:"host"`
+	Events    int    `json:"events"`
+	Spikes    int    `json:"spikes"`
+	// Warmup is the warmup duration recommended, zero if the destination does not need one.
+	Warmup time.Duration `json:"warmup,omitempty"`
+	// Current is the warmup currently configured by the DestinationRule of the host, if any.
+	Current time.Duration `json:"current,omitempty"`
+	// Note explains why the recommendation cannot be applied as is, if so.
+	Note string `json:"note,omitempty"`
+}
+
+// Recommend returns the recommendation for each destination with impacts: a warmup covering the longest recovery of
+// its spikes, if any spiked.
+func Recommend(destinations []Destination, impacts []Impact) []Recommendation {
+	var res []Recommendation
+	for _, d := range destinations {
+		r := Recommendation{Namespace: d.Namespace, Workload: d.Workload, Host: d.Host}
+		var recovery time.Duration
+		for _, i := range impacts {
+			if i.Namespace != d.Namespace || i.Workload != d.Workload {
+				continue
+			}
+			r.Events++
+			if i.Spiking() {
+				r.Spikes++
+				recovery = max(recovery, i.Recovery)
+			}
+		}
+		if r.Events == 0 {
+			continue
+		}
+		lb := d.loadBalancer()
+		r.Current = configuredWarmup(lb)
+		if r.Spikes > 0 {
+			r.Warmup = max(recovery.Round(10*time.Second), minWarmup)
+			if r.Warmup < recovery {
+				r.Warmup += 10 * time.Second
+			}
+		}
+		switch {
+		case r.Warmup > 0 && d.Host == "":
+			r.Note = "no Service selects the workload"
+		case r.Warmup > 0 && lb.GetConsistentHash() != nil:
+			r.Note = "warmup requires the ROUND_ROBIN or LEAST_REQUEST load balancer, not consistent hashing"
+		case r.Warmup > 0 && r.Current >= r.Warmup:
+			r.Note = "the current warmup does not prevent the spikes, check the readiness probe of the workload"
+		}
+		res = append(res, r)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Namespace != res[j].Namespace {
+			return res[i].Namespace < res[j].Namespace
+		}
+		return res[i].Workload < res[j].Workload
+	})
+	return res
+}
+
+// configuredWarmup returns the warmup duration set by the load balancer settings, zero if none.
+func configuredWarmup(lb *networking.LoadBalancerSettings) time.Duration {
+	if lb.GetWarmup().GetDuration() != nil {
+		return lb.GetWarmup().GetDuration().AsDuration()
+	}
+	return lb.GetWarmupDurationSecs().AsDuration()
+}
+
+// Generate returns the config applying the recommendations which can be applied as is: the DestinationRules of their
+// hosts with the warmup set, updating the existing DestinationRule if any. With preconnect, it also returns an
+// EnvoyFilter having the clients of each host establish connections ahead of their requests, which spreads the
+// connection setup to new endpoints over the warmup.
+func Generate(destinations []Destination, recommendations []Recommendation, preconnect bool) ([]*unstructured.Unstructured, error) {
+	var res []*unstructured.Unstructured
+	for _, r := range recommendations {
+		if r.Warmup == 0 || r.Note != "" {
+			continue
+		}
+		var d Destination
+		for _, dest := range destinations {
+			if dest.Namespace == r.Namespace && dest.Workload == r.Workload {
+				d = dest
+			}
+		}
+		name := strings.Split(r.Host, ".")[0]
+		spec := &networking.DestinationRule{Host: r.Host}
+		if d.DestinationRule != nil {
+			name = d.DestinationRule.Name
+			spec = d.DestinationRule.Spec.DeepCopy()
+		}
+		if spec.TrafficPolicy == nil {
+			spec.TrafficPolicy = &networking.TrafficPolicy{}
+		}
+		if spec.TrafficPolicy.LoadBalancer == nil {
+			spec.TrafficPolicy.LoadBalancer = &networking.LoadBalancerSettings{
+				LbPolicy: &networking.LoadBalancerSettings_Simple{Simple: networking.LoadBalancerSettings_LEAST_REQUEST},
+			}
+		}
+		spec.TrafficPolicy.LoadBalancer.WarmupDurationSecs = nil
+		spec.TrafficPolicy.LoadBalancer.Warmup = &networking.WarmupConfiguration{
+			Duration:       durationpb.New(r.Warmup),
+			MinimumPercent: wrapperspb.Double(warmupMinimumPercent),
+		}
+		dr, err := object(gvk.DestinationRule, r.Namespace, name, spec)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, dr)
+		if !preconnect {
+			continue
+		}
+		ef, err := object(gvk.EnvoyFilter, r.Namespace, name+"-preconnect", preconnectFilter(r.Host))
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, ef)
+	}
+	return res, nil
+}
+
+// preconnectFilter returns the EnvoyFilter setting the preconnect policy of the outbound clusters of the host.
+func preconnectFilter(host string) *networking.EnvoyFilter {
+	// The value only holds numbers and maps, which cannot fail to convert.
+	patch, _ := structpb.NewStruct(map[string]any{
+		"preconnect_policy": map[string]any{"per_upstream_preconnect_ratio": preconnectRatio},
+	})
+	return &networking.EnvoyFilter{
+		ConfigPatches: []*networking.EnvoyFilter_EnvoyConfigObjectPatch{{
+			ApplyTo: networking.EnvoyFilter_CLUSTER,
+			Match: &networking.EnvoyFilter_EnvoyConfigObjectMatch{
+				Context: networking.EnvoyFilter_SIDECAR_OUTBOUND,
+				ObjectTypes: &networking.EnvoyFilter_EnvoyConfigObjectMatch_Cluster{
+					Cluster: &networking.EnvoyFilter_ClusterMatch{Service: host},
+				},
+			},
+			Patch: &networking.EnvoyFilter_Patch{Operation: networking.EnvoyFilter_Patch_MERGE, Value: patch},
+		}},
+	}
+}
+
+// object returns the Istio config object of the kind with the spec.
+func object(kind config.GroupVersionKind, namespace, name string, spec proto.Message) (*unstructured.Unstructured, error) {
+	m, err := protomarshal.ToJSONMap(spec)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert the %s %s/%s: %v", kind.Kind, namespace, name, err)
+	}
+	return &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": kind.GroupVersion(),
+		"kind":       kind.Kind,
+		"metadata":   map[string]any{"name": name, "namespace": namespace},
+		"spec":       m,
+	}}, nil
+}
+
+// Summary is the impact of the scale ups of a destination over a period.
+type Summary struct {
+	Events int `json:"events"`
+	Spikes int `json:"spikes"`
+	// MeanPeakP99 and MeanPeakErrorRatio are the means of the peaks after the events.
+	MeanPeakP99        float64 `json:"meanPeakP99Ms"`
+	MeanPeakErrorRatio float64 `json:"meanPeakErrorRatio"`
+	// MaxRecovery is the longest recovery of the spikes.
+	MaxRecovery time.Duration `json:"maxRecovery"`
+}
+
+func summarize(impacts []Impact) Summary {
+	s := Summary{Events: len(impacts)}
+	if len(impacts) == 0 {
+		return s
+	}
+	var p99s, errorRatios []float64
+	for _, i := range impacts {
+		if i.Spiking() {
+			s.Spikes++
+		}
+		p99s = append(p99s, i.PeakP99)
+		errorRatios = append(errorRatios, i.PeakErrorRatio)
+		s.MaxRecovery = max(s.MaxRecovery, i.Recovery)
+	}
+	s.MeanPeakP99 = mean(p99s)
+	s.MeanPeakErrorRatio = mean(errorRatios)
+	return s
+}
+
+// Verification compares the impact of the scale ups of a destination before and after a change of its config.
+type Verification struct {
+	Namespace string    `json:"namespace"`
+	Workload  string    `json:"workload"`
+	ChangedAt time.Time `json:"changedAt"`
+	Before    Summary   `json:"before"`
+	After     Summary   `json:"after"`
+}
+
+// Improved returns whether the scale ups after the change spiked less than before. It returns false without events
+// on either side of the change.
+func (v Verification) Improved() bool {
+	if v.Before.Events == 0 || v.After.Events == 0 {
+		return false
+	}
+	return float64(v.After.Spikes)/float64(v.After.Events) < float64(v.Before.Spikes)/float64(v.Before.Events) ||
+		v.After.Spikes > 0 && v.After.MaxRecovery < v.Before.MaxRecovery
+}
+
+// Verify compares the impacts of the scale ups of each destination before and after the time its config changed, by
+// destination as namespace/workload. Destinations without change time are skipped.
+func Verify(impacts []Impact, changedAt map[string]time.Time) []Verification {
+	byDestination := map[string][]Impact{}
+	for _, i := range impacts {
+		key := i.Namespace + "/" + i.Workload
+		byDestination[key] = append(byDestination[key], i)
+	}
+	var res []Verification
+	for key, is := range byDestination {
+		at, ok := changedAt[key]
+		if !ok {
+			continue
+		}
+		var before, after []Impact
+		for _, i := range is {
+			if i.Time.Before(at) {
+				before = append(before, i)
+			} else {
+				after = append(after, i)
+			}
+		}
+		ns, workload, _ := strings.Cut(key, "/")
+		res = append(res, Verification{
+			Namespace: ns,
+			Workload:  workload,
+			ChangedAt: at,
+			Before:    summarize(before),
+			After:     summarize(after),
+		})
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Namespace != res[j].Namespace {
+			return res[i].Namespace < res[j].Namespace
+		}
+		return res[i].Workload < res[j].Workload
+	})
+	return res
+}
@@ -0,0 +1,243 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package slowstart
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/types/known/durationpb"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+
+	networking "istio.io/api/networking/v1alpha3"
+	clientnetworking "istio.io/client-go/pkg/apis/networking/v1"
+	"istio.io/istio/pkg/ptr"
+	"istio.io/istio/pkg/test/util/assert"
+)
+
+var start = time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+func pod(name, replicaSet string, ready time.Time) corev1.Pod {
+	p := corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+		Name:            name,
+		Namespace:       "default",
+		GenerateName:    replicaSet + "-",
+		Labels:          map[string]string{"pod-template-hash": "5d4f8"},
+		OwnerReferences: []metav1.OwnerReference{{Kind: "ReplicaSet", Name: replicaSet, Controller: ptr.Of(true)}},
+	}}
+	if !ready.IsZero() {
+		p.Status.Conditions = []corev1.PodCondition{{
+			Type:               corev1.PodReady,
+			Status:             corev1.ConditionTrue,
+			LastTransitionTime: metav1.NewTime(ready),
+		}}
+	}
+	return p
+}
+
+func TestScaleUps(t *testing.T) {
+	pods := []corev1.Pod{
+		pod("reviews-5d4f8-a", "reviews-5d4f8", start.Add(-time.Hour)),
+		pod("reviews-5d4f8-b", "reviews-5d4f8", start.Add(time.Hour)),
+		pod("reviews-5d4f8-c", "reviews-5d4f8", start.Add(time.Hour+time.Minute)),
+		pod("reviews-5d4f8-d", "reviews-5d4f8", start.Add(3*time.Hour)),
+		pod("ratings-5d4f8-a", "ratings-5d4f8", start.Add(2*time.Hour)),
+		pod("ratings-5d4f8-b", "ratings-5d4f8", time.Time{}),
+	}
+	assert.Equal(t, ScaleUps(pods, start, start.Add(4*time.Hour), 5*time.Minute), []Event{
+		{Namespace: "default", Workload: "ratings", Time: start.Add(2 * time.Hour), Pods: 1},
+		{Namespace: "default", Workload: "reviews", Time: start.Add(time.Hour), Pods: 2},
+		{Namespace: "default", Workload: "reviews", Time: start.Add(3 * time.Hour), Pods: 1},
+	})
+}
+
+// points returns a point each 15s from 1m before the event, with the error ratios and p99 latencies after it.
+func points(e Event, errorRatios, p99s []float64) []Point {
+	var res []Point
+	for i := -4; i <= 0; i++ {
+		res = append(res, Point{Time: e.Time.Add(time.Duration(i) * step), ErrorRatio: 0.001, P99: 40})
+	}
+	for i := range errorRatios {
+		res = append(res, Point{Time: e.Time.Add(time.Duration(i+1) * step), ErrorRatio: errorRatios[i], P99: p99s[i]})
+	}
+	return res
+}
+
+func TestMeasure(t *testing.T) {
+	e := Event{Namespace: "default", Workload: "reviews", Time: start, Pods: 2}
+
+	impact, ok := Measure(e, points(e, []float64{0.05, 0.02, 0.001, 0.001}, []float64{45, 200, 90, 42}))
+	assert.Equal(t, ok, true)
+	assert.Equal(t, impact.Spiking(), true)
+	assert.Equal(t, impact.Recovery, 45*time.Second)
+	assert.Equal(t, impact.PeakErrorRatio, 0.05)
+	assert.Equal(t, impact.PeakP99, 200.0)
+
+	impact, ok = Measure(e, points(e, []float64{0.001, math.NaN(), 0.002}, []float64{50, math.NaN(), 45}))
+	assert.Equal(t, ok, true)
+	assert.Equal(t, impact.Spiking(), false)
+
+	_, ok = Measure(e, []Point{{Time: start.Add(step), ErrorRatio: 0.5, P99: 100}})
+	assert.Equal(t, ok, false)
+}
+
+func TestRecommend(t *testing.T) {
+	spike := func(workload string, recovery time.Duration) Impact {
+		return Impact{Event: Event{Namespace: "default", Workload: workload}, Recovery: recovery}
+	}
+	hashed := &clientnetworking.DestinationRule{
+		ObjectMeta: metav1.ObjectMeta{Name: "ratings", Namespace: "default"},
+		Spec: networking.DestinationRule{
+			Host: "ratings",
+			TrafficPolicy: &networking.TrafficPolicy{LoadBalancer: &networking.LoadBalancerSettings{
+				LbPolicy: &networking.LoadBalancerSettings_ConsistentHash{ConsistentHash: &networking.LoadBalancerSettings_ConsistentHashLB{}},
+			}},
+		},
+	}
+	existing := &clientnetworking.DestinationRule{
+		ObjectMeta: metav1.ObjectMeta{Name: "details", Namespace: "default"},
+		Spec: networking.DestinationRule{
+			Host: "details.default.svc.cluster.local",
+			TrafficPolicy: &networking.TrafficPolicy{LoadBalancer: &networking.LoadBalancerSettings{
+				LbPolicy:           &networking.LoadBalancerSettings_Simple{Simple: networking.LoadBalancerSettings_ROUND_ROBIN},
+				WarmupDurationSecs: durationpb.New(20 * time.Second),
+			}},
+		},
+	}
+	destinations := []Destination{
+		{Namespace: "default", Workload: "reviews", Host: "reviews.default.svc.cluster.local"},
+		{Namespace: "default", Workload: "ratings", Host: "ratings.default.svc.cluster.local", DestinationRule: hashed},
+		{Namespace: "default", Workload: "details", Host: "details.default.svc.cluster.local", DestinationRule: existing},
+		{Namespace: "default", Workload: "productpage", Host: "productpage.default.svc.cluster.local"},
+	}
+	impacts := []Impact{
+		spike("reviews", 45*time.Second),
+		spike("reviews", 0),
+		spike("ratings", time.Minute),
+		spike("details", 10*time.Second),
+		spike("productpage", 0),
+	}
+	recommendations := Recommend(destinations, impacts)
+	assert.Equal(t, recommendations, []Recommendation{
+		{
+			Namespace: "default", Workload: "details", Host: "details.default.svc.cluster.local", Events: 1, Spikes: 1,
+			Warmup: 30 * time.Second, Current: 20 * time.Second,
+		},
+		{Namespace: "default", Workload: "productpage", Host: "productpage.default.svc.cluster.local", Events: 1},
+		{
+			Namespace: "default", Workload: "ratings", Host: "ratings.default.svc.cluster.local", Events: 1, Spikes: 1,
+			Warmup: time.Minute, Note: "warmup requires the ROUND_ROBIN or LEAST_REQUEST load balancer, not consistent hashing",
+		},
+		{Namespace: "default", Workload: "reviews", Host: "reviews.default.svc.cluster.local", Events: 2, Spikes: 1, Warmup: 50 * time.Second},
+	})
+
+	objects, err := Generate(destinations, recommendations, true)
+	assert.NoError(t, err)
+	var out string
+	for _, obj := range objects {
+		b, err := yaml.Marshal(obj.Object)
+		assert.NoError(t, err)
+		out += "---\n" + string(b)
+	}
+	assert.Equal(t, out, `---
+apiVersion: networking.istio.io/v1
+kind: DestinationRule
+metadata:
+  name: details
+  namespace: default
+spec:
+  host: details.default.svc.cluster.local
+  trafficPolicy:
+    loadBalancer:
+      simple: ROUND_ROBIN
+      warmup:
+        duration: 30s
+        minimumPercent: 10
+---
+apiVersion: networking.istio.io/v1alpha3
+kind: EnvoyFilter
+metadata:
+  name: details-preconnect
+  namespace: default
+spec:
+  configPatches:
+  - applyTo: CLUSTER
+    match:
+      cluster:
+        service: details.default.svc.cluster.local
+      context: SIDECAR_OUTBOUND
+    patch:
+      operation: MERGE
+      value:
+        preconnect_policy:
+          per_upstream_preconnect_ratio: 1.5
+---
+apiVersion: networking.istio.io/v1
+kind: DestinationRule
+metadata:
+  name: reviews
+  namespace: default
+spec:
+  host: reviews.default.svc.cluster.local
+  trafficPolicy:
+    loadBalancer:
+      simple: LEAST_REQUEST
+      warmup:
+        duration: 50s
+        minimumPercent: 10
+---
+apiVersion: networking.istio.io/v1alpha3
+kind: EnvoyFilter
+metadata:
+  name: reviews-preconnect
+  namespace: default
+spec:
+  configPatches:
+  - applyTo: CLUSTER
+    match:
+      cluster:
+        service: reviews.default.svc.cluster.local
+      context: SIDECAR_OUTBOUND
+    patch:
+      operation: MERGE
+      value:
+        preconnect_policy:
+          per_upstream_preconnect_ratio: 1.5
+`)
+}
+
+func TestVerify(t *testing.T) {
+	impact := func(at time.Time, recovery time.Duration) Impact {
+		return Impact{Event: Event{Namespace: "default", Workload: "reviews", Time: at}, PeakP99: 100, Recovery: recovery}
+	}
+	verifications := Verify([]Impact{
+		impact(start, time.Minute),
+		impact(start.Add(time.Hour), 2*time.Minute),
+		impact(start.Add(3*time.Hour), 0),
+		impact(start.Add(4*time.Hour), 15*time.Second),
+		{Event: Event{Namespace: "default", Workload: "ratings", Time: start}},
+	}, map[string]time.Time{"default/reviews": start.Add(2 * time.Hour)})
+	assert.Equal(t, verifications, []Verification{{
+		Namespace: "default",
+		Workload:  "reviews",
+		ChangedAt: start.Add(2 * time.Hour),
+		Before:    Summary{Events: 2, Spikes: 2, MeanPeakP99: 100, MaxRecovery: 2 * time.Minute},
+		After:     Summary{Events: 2, Spikes: 1, MeanPeakP99: 100, MaxRecovery: 15 * time.Second},
+	}})
+	assert.Equal(t, verifications[0].Improved(), true)
+}
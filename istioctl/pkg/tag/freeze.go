@@ -0,0 +1,182 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tag
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	admitv1 "k8s.io/api/admissionregistration/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// FrozenAnnotation marks the webhooks of a revision tag as frozen, with the reason of the freeze as value.
+	FrozenAnnotation = "istio.io/tag-frozen"
+	// FreezeOverrideAnnotation records the last change made to a frozen revision tag: who made it, when and what.
+	FreezeOverrideAnnotation = "istio.io/tag-freeze-override"
+
+	// freezeFieldManager owns the freeze annotations, so that applying the webhooks of the tag leaves them in place.
+	freezeFieldManager = "istioctl-tag-freeze"
+
+	OverrideFreezeHelpStr = "If true, allow changes to frozen revision tags. The change is recorded with the user who made it."
+)
+
+// FrozenReason returns the reason the revision tag is frozen, or an empty string if it is not.
+func FrozenReason(webhooks []admitv1.MutatingWebhookConfiguration) string {
+	for _, wh := range webhooks {
+		if reason, ok := wh.Annotations[FrozenAnnotation]; ok {
+			return reason
+		}
+	}
+	return ""
+}
+
+// FreezeOverride is the override of the freeze of a revision tag, to be recorded with RecordFreezeOverride once the
+// change is made, so that changes cancelled or failing are not recorded.
+type FreezeOverride struct {
+	Tag    string
+	Reason string
+	User   string
+	Action string
+	Time   time.Time
+	// webhook is the name of a webhook of the tag, which the event recording the override is about.
+	webhook string
+}
+
+// String returns the record kept on the webhooks of the revision tag: who made the change, when and what.
+func (o *FreezeOverride) String() string {
+	return fmt.Sprintf("%s at %s: %s", o.User, o.Time.UTC().Format(time.RFC3339), o.Action)
+}
+
+// CheckFreeze fails if the revision tag is frozen, unless override is set, in which case the override is returned.
+// It is nil if the tag is not frozen. action describes the change, such as "set to revision 1-24-0".
+func CheckFreeze(ctx context.Context, client kubernetes.Interface, tagName, action string, override bool, w io.Writer,
+) (*FreezeOverride, error) {
+	webhooks, err := GetWebhooksWithTag(ctx, client, tagName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve tag with name %s: %v", tagName, err)
+	}
+	reason := FrozenReason(webhooks)
+	if reason == "" {
+		return nil, nil
+	}
+	if !override {
+		return nil, fmt.Errorf("revision tag %q is frozen (%s), pass --override-freeze to %s anyway", tagName, reason, action)
+	}
+	o := &FreezeOverride{
+		Tag:     tagName,
+		Reason:  reason,
+		User:    currentUser(ctx, client),
+		Action:  action,
+		Time:    time.Now(),
+		webhook: webhooks[0].Name,
+	}
+	fmt.Fprintf(w, "Warning: overriding the freeze of revision tag %q (%s) as %s\n", tagName, reason, o.User)
+	return o, nil
+}
+
+// RecordFreezeOverride records an override returned by CheckFreeze as a Kubernetes event in the Istio namespace, and on
+// the webhooks of the revision tag unless it was removed.
+func RecordFreezeOverride(ctx context.Context, client kubernetes.Interface, istioNS string, o *FreezeOverride) error {
+	_, err := client.CoreV1().Events(istioNS).Create(ctx, &corev1.Event{
+		ObjectMeta: metav1.ObjectMeta{GenerateName: "istio-revision-tag-" + o.Tag + "-"},
+		InvolvedObject: corev1.ObjectReference{
+			APIVersion: admitv1.SchemeGroupVersion.String(),
+			Kind:       "MutatingWebhookConfiguration",
+			Name:       o.webhook,
+		},
+		Reason:         "FreezeOverridden",
+		Message:        fmt.Sprintf("%s overrode the freeze of revision tag %q (%s) to %s", o.User, o.Tag, o.Reason, o.Action),
+		Type:           corev1.EventTypeWarning,
+		Source:         corev1.EventSource{Component: "istioctl"},
+		FirstTimestamp: metav1.NewTime(o.Time),
+		LastTimestamp:  metav1.NewTime(o.Time),
+		Count:          1,
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to record the override of the freeze of revision tag %q: %v", o.Tag, err)
+	}
+	webhooks, err := GetWebhooksWithTag(ctx, client, o.Tag)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve tag with name %s: %v", o.Tag, err)
+	}
+	if len(webhooks) == 0 {
+		return nil
+	}
+	record := o.String()
+	return annotateTag(ctx, client, o.Tag, FreezeOverrideAnnotation, &record)
+}
+
+// currentUser returns the name the API server authenticates the client as, or the local user if it cannot tell.
+func currentUser(ctx context.Context, client kubernetes.Interface) string {
+	review, err := client.AuthenticationV1().SelfSubjectReviews().Create(ctx, &authenticationv1.SelfSubjectReview{}, metav1.CreateOptions{})
+	if err == nil && review.Status.UserInfo.Username != "" {
+		return review.Status.UserInfo.Username
+	}
+	if user := os.Getenv("USER"); user != "" {
+		return "local user " + user
+	}
+	return "unknown user"
+}
+
+// annotateTag sets the annotation on the webhooks of the revision tag, or removes it if value is nil.
+func annotateTag(ctx context.Context, client kubernetes.Interface, tagName, annotation string, value *string) error {
+	webhooks, err := GetWebhooksWithTag(ctx, client, tagName)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve tag with name %s: %v", tagName, err)
+	}
+	if len(webhooks) == 0 {
+		return fmt.Errorf("cannot find MutatingWebhookConfiguration for tag %q", tagName)
+	}
+	patch, err := json.Marshal(map[string]any{"metadata": map[string]any{"annotations": map[string]*string{annotation: value}}})
+	if err != nil {
+		return err
+	}
+	for _, wh := range webhooks {
+		_, err := client.AdmissionregistrationV1().MutatingWebhookConfigurations().Patch(ctx, wh.Name, types.MergePatchType, patch,
+			metav1.PatchOptions{FieldManager: freezeFieldManager})
+		if err != nil {
+			return fmt.Errorf("failed to annotate %s: %v", wh.Name, err)
+		}
+	}
+	return nil
+}
+
+// freezeTag marks the revision tag as frozen.
+func freezeTag(ctx context.Context, client kubernetes.Interface, tagName, reason string, w io.Writer) error {
+	if err := annotateTag(ctx, client, tagName, FrozenAnnotation, &reason); err != nil {
+		return fmt.Errorf("cannot freeze tag %q: %v", tagName, err)
+	}
+	fmt.Fprintf(w, "Revision tag %s frozen: %s\n", tagName, reason)
+	return nil
+}
+
+// unfreezeTag lifts the freeze of the revision tag.
+func unfreezeTag(ctx context.Context, client kubernetes.Interface, tagName string, w io.Writer) error {
+	if err := annotateTag(ctx, client, tagName, FrozenAnnotation, nil); err != nil {
+		return fmt.Errorf("cannot unfreeze tag %q: %v", tagName, err)
+	}
+	fmt.Fprintf(w, "Revision tag %s unfrozen\n", tagName)
+	return nil
+}
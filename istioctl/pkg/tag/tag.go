@@ -66,6 +66,8 @@ var (
 	webhookName          = ""
 	autoInjectNamespaces = false
 	outputFormat         = util.TableFormat
+	overrideFreeze       = false
+	freezeReason         = ""
 )
 
 type tagDescription struct {
@@ -105,6 +107,8 @@ without manual relabeling of the "istio.io/rev" tag.
 	cmd.AddCommand(tagGenerateCommand(ctx))
 	cmd.AddCommand(tagListCommand(ctx))
 	cmd.AddCommand(tagRemoveCommand(ctx))
+	cmd.AddCommand(tagFreezeCommand(ctx))
+	cmd.AddCommand(tagUnfreezeCommand(ctx))
 
 	return cmd
 }
@@ -157,6 +161,7 @@ injection labels.`,
 	cmd.PersistentFlags().StringVarP(&revision, "revision", "r", "", revisionHelpStr)
 	cmd.PersistentFlags().StringVarP(&webhookName, "webhook-name", "", "", webhookNameHelpStr)
 	cmd.PersistentFlags().BoolVar(&autoInjectNamespaces, "auto-inject-namespaces", false, autoInjectNamespacesHelpStr)
+	cmd.PersistentFlags().BoolVar(&overrideFreeze, "override-freeze", false, OverrideFreezeHelpStr)
 	_ = cmd.MarkPersistentFlagRequired("revision")
 
 	return cmd
@@ -265,11 +270,69 @@ revision tag before removing using the "istioctl tag list" command.
 				return fmt.Errorf("failed to create Kubernetes client: %v", err)
 			}
 
-			return removeTag(context.Background(), kubeClient.Kube(), args[0], skipConfirmation, cmd.OutOrStdout())
+			return removeTag(context.Background(), kubeClient.Kube(), args[0], ctx.IstioNamespace(), skipConfirmation, overrideFreeze,
+				cmd.OutOrStdout())
 		},
 	}
 
 	cmd.PersistentFlags().BoolVarP(&skipConfirmation, "skip-confirmation", "y", false, skipConfirmationFlagHelpStr)
+	cmd.PersistentFlags().BoolVar(&overrideFreeze, "override-freeze", false, OverrideFreezeHelpStr)
+	return cmd
+}
+
+func tagFreezeCommand(ctx cli.Context) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "freeze <revision-tag>",
+		Short: "Freeze a revision tag during a change freeze",
+		Long: `Freeze a revision tag, such as the tag of a production revision during a change freeze or outside its maintenance
+window. "istioctl tag set", "istioctl tag remove" and "istioctl install" refuse to change a frozen revision tag unless
+--override-freeze is passed, in which case the user making the change is recorded in an event of the Istio namespace
+and in the "istio.io/tag-freeze-override" annotation of the tag.
+`,
+		Example: `  # Freeze the revision tag "prod" for the holidays
+  istioctl tag freeze prod --reason "holiday change freeze until 2025-01-06"
+
+  # Lift the freeze
+  istioctl tag unfreeze prod
+`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return fmt.Errorf("must provide a single tag to freeze")
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			kubeClient, err := ctx.CLIClient()
+			if err != nil {
+				return fmt.Errorf("failed to create Kubernetes client: %v", err)
+			}
+			return freezeTag(context.Background(), kubeClient.Kube(), args[0], freezeReason, cmd.OutOrStdout())
+		},
+	}
+
+	cmd.PersistentFlags().StringVar(&freezeReason, "reason", "change freeze", "Reason for the freeze, reported to those changing the tag")
+	return cmd
+}
+
+func tagUnfreezeCommand(ctx cli.Context) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "unfreeze <revision-tag>",
+		Short:   "Lift the freeze of a revision tag",
+		Example: "  istioctl tag unfreeze prod",
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return fmt.Errorf("must provide a single tag to unfreeze")
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			kubeClient, err := ctx.CLIClient()
+			if err != nil {
+				return fmt.Errorf("failed to create Kubernetes client: %v", err)
+			}
+			return unfreezeTag(context.Background(), kubeClient.Kube(), args[0], cmd.OutOrStdout())
+		},
+	}
 	return cmd
 }
 
@@ -285,6 +348,14 @@ func setTag(ctx context.Context, kubeClient kube.CLIClient, tagName, revision, i
 		AutoInjectNamespaces: autoInjectNamespaces,
 		UserManaged:          true,
 	}
+	var override *FreezeOverride
+	if !generate {
+		var err error
+		override, err = CheckFreeze(ctx, kubeClient.Kube(), tagName, "set to revision "+revision, overrideFreeze, stderr)
+		if err != nil {
+			return err
+		}
+	}
 	tagWhYAML, err := Generate(ctx, kubeClient, opts, istioNS)
 	if err != nil {
 		return err
@@ -319,6 +390,11 @@ func setTag(ctx context.Context, kubeClient kube.CLIClient, tagName, revision, i
 	if err := Create(kubeClient, tagWhYAML, istioNS); err != nil {
 		return fmt.Errorf("failed to apply tag webhook MutatingWebhookConfiguration to cluster: %v", err)
 	}
+	if override != nil {
+		if err := RecordFreezeOverride(ctx, kubeClient.Kube(), istioNS, override); err != nil {
+			return err
+		}
+	}
 	fmt.Fprintf(w, tagCreatedStr, tagName, revision, tagName)
 	return nil
 }
@@ -355,7 +431,9 @@ func analyzeWebhook(name, istioNamespace, wh, revision string, config *rest.Conf
 }
 
 // removeTag removes an existing revision tag.
-func removeTag(ctx context.Context, kubeClient kubernetes.Interface, tagName string, skipConfirmation bool, w io.Writer) error {
+func removeTag(ctx context.Context, kubeClient kubernetes.Interface, tagName, istioNS string, skipConfirmation, override bool,
+	w io.Writer,
+) error {
 	webhooks, err := GetWebhooksWithTag(ctx, kubeClient, tagName)
 	if err != nil {
 		return fmt.Errorf("failed to retrieve tag with name %s: %v", tagName, err)
@@ -363,6 +441,10 @@ func removeTag(ctx context.Context, kubeClient kubernetes.Interface, tagName str
 	if len(webhooks) == 0 {
 		return fmt.Errorf("cannot remove tag %q: cannot find MutatingWebhookConfiguration for tag", tagName)
 	}
+	freezeOverride, err := CheckFreeze(ctx, kubeClient, tagName, "remove it", override, w)
+	if err != nil {
+		return err
+	}
 
 	taggedNamespaces, err := GetNamespacesWithTag(ctx, kubeClient, tagName)
 	if err != nil {
@@ -381,6 +463,11 @@ func removeTag(ctx context.Context, kubeClient kubernetes.Interface, tagName str
 	if err != nil {
 		return fmt.Errorf("failed to delete Istio revision tag MutatingConfigurationWebhook: %v", err)
 	}
+	if freezeOverride != nil {
+		if err := RecordFreezeOverride(ctx, kubeClient, istioNS, freezeOverride); err != nil {
+			return err
+		}
+	}
 
 	fmt.Fprintf(w, "Revision tag %s removed\n", tagName)
 	return nil
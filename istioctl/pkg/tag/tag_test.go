@@ -233,7 +233,7 @@ func TestRemoveTag(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			var out bytes.Buffer
 			client := fake.NewClientset(tc.webhooksBefore.DeepCopyObject(), tc.namespaces.DeepCopyObject())
-			err := removeTag(context.Background(), client, tc.tag, tc.skipConfirmation, &out)
+			err := removeTag(context.Background(), client, tc.tag, "istio-system", tc.skipConfirmation, false, &out)
 			if tc.error == "" && err != nil {
 				t.Fatalf("expected no error, got %v", err)
 			}
@@ -302,3 +302,75 @@ func TestSetTagErrors(t *testing.T) {
 		})
 	}
 }
+
+func TestFreezeTag(t *testing.T) {
+	t.Setenv("USER", "jane")
+	ctx := context.Background()
+	client := fake.NewClientset(&admitv1.MutatingWebhookConfiguration{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "istio-revision-tag-prod",
+			Labels: map[string]string{label.IoIstioTag.Name: "prod"},
+		},
+	})
+	var out bytes.Buffer
+	if err := freezeTag(ctx, client, "prod", "black friday", &out); err != nil {
+		t.Fatal(err)
+	}
+
+	err := removeTag(ctx, client, "prod", "istio-system", true, false, &out)
+	if err == nil || !strings.Contains(err.Error(), `revision tag "prod" is frozen (black friday)`) {
+		t.Fatalf("expected the freeze to block the removal, got %v", err)
+	}
+
+	override, err := CheckFreeze(ctx, client, "prod", "set to revision canary", true, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	record := override.String()
+	if !strings.HasPrefix(record, "local user jane at ") || !strings.HasSuffix(record, ": set to revision canary") {
+		t.Fatalf("unexpected override record %q", record)
+	}
+	// The override is only recorded once the change is made.
+	events, _ := client.CoreV1().Events("istio-system").List(ctx, metav1.ListOptions{})
+	if len(events.Items) != 0 {
+		t.Fatalf("expected the override not to be recorded before the change, got %v", events.Items)
+	}
+	if err := RecordFreezeOverride(ctx, client, "istio-system", override); err != nil {
+		t.Fatal(err)
+	}
+	events, _ = client.CoreV1().Events("istio-system").List(ctx, metav1.ListOptions{})
+	if len(events.Items) != 1 || events.Items[0].Reason != "FreezeOverridden" {
+		t.Fatalf("expected the override to be recorded as an event, got %v", events.Items)
+	}
+	wh, _ := client.AdmissionregistrationV1().MutatingWebhookConfigurations().Get(ctx, "istio-revision-tag-prod", metav1.GetOptions{})
+	if wh.Annotations[FreezeOverrideAnnotation] != record {
+		t.Fatalf("expected the override to be recorded on the webhook, got %v", wh.Annotations)
+	}
+
+	if err := unfreezeTag(ctx, client, "prod", &out); err != nil {
+		t.Fatal(err)
+	}
+	if err := removeTag(ctx, client, "prod", "istio-system", true, false, &out); err != nil {
+		t.Fatalf("expected the removal to succeed once unfrozen, got %v", err)
+	}
+}
+
+func TestRemoveFrozenTag(t *testing.T) {
+	ctx := context.Background()
+	client := fake.NewClientset(&admitv1.MutatingWebhookConfiguration{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "istio-revision-tag-prod",
+			Labels:      map[string]string{label.IoIstioTag.Name: "prod"},
+			Annotations: map[string]string{FrozenAnnotation: "black friday"},
+		},
+	})
+	var out bytes.Buffer
+	if err := removeTag(ctx, client, "prod", "istio-system", true, true, &out); err != nil {
+		t.Fatalf("expected the override to allow the removal, got %v", err)
+	}
+	// The webhooks are gone, leaving the event as the record of the override.
+	events, _ := client.CoreV1().Events("istio-system").List(ctx, metav1.ListOptions{})
+	if len(events.Items) != 1 || events.Items[0].Reason != "FreezeOverridden" {
+		t.Fatalf("expected the override to be recorded as an event, got %v", events.Items)
+	}
+}
@@ -0,0 +1,325 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package throttletest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	cluster "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"istio.io/istio/istioctl/pkg/cli"
+	"istio.io/istio/istioctl/pkg/util/configdump"
+	"istio.io/istio/pkg/config/constants"
+	"istio.io/istio/pkg/kube"
+	"istio.io/istio/pkg/kube/inject"
+)
+
+const (
+	defaultImage  = "fortio/fortio:latest_release"
+	clientLabel   = "istioctl-throttle-test"
+	loadContainer = "fortio"
+)
+
+// Report is the result of a throttle test.
+type Report struct {
+	Cluster     string         `json:"cluster"`
+	Concurrency int            `json:"concurrency"`
+	Requests    int            `json:"requests"`
+	Limits      Limits         `json:"limits"`
+	Checks      []Check        `json:"checks"`
+	Responses   map[string]int `json:"responses"`
+}
+
+type options struct {
+	port        int32
+	path        string
+	concurrency int
+	requests    int
+	image       string
+	timeout     time.Duration
+}
+
+func Cmd(ctx cli.Context) *cobra.Command {
+	var (
+		o            options
+		outputFormat string
+	)
+	cmd := &cobra.Command{
+		Use:   "throttle-test <service>[.<namespace>]",
+		Short: "Verifies that the circuit breaking limits of a service trip under load",
+		Long: `Drives concurrent load against a service from an ephemeral client pod, to verify that the connectionPool
+and outlierDetection limits of the DestinationRule of the service trip as expected.
+
+The client pod runs fortio with an injected sidecar in the namespace of the command, so the limits applied are those
+of the clusters of a sidecar of that namespace. After the load, each limit configured on the cluster of the service
+port is compared with the Envoy statistics counting the times it tripped:
+
+  PASS  the limit tripped as expected at the concurrency of the load, or did not trip below it
+  FAIL  the limit tripped below its threshold, or did not trip above it
+  INFO  whether the limit trips depends on the destination, e.g. outlier detection on server errors
+
+By default, the concurrency exceeds the tightest limit on connections or requests. The namespace must have sidecar
+injection enabled, and the destination receives real requests: run the test against a non-production deployment.`,
+		Example: `  # Verify the circuit breaking limits of the httpbin service
+  istioctl experimental throttle-test httpbin.foo --port 8000
+
+  # Drive 1000 requests over 50 concurrent connections, as JSON
+  istioctl experimental throttle-test httpbin.foo --port 8000 --concurrency 50 --requests 1000 -o json`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				cmd.Println(cmd.UsageString())
+				return fmt.Errorf("throttle-test requires a service")
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if outputFormat != "table" && outputFormat != "json" {
+				return fmt.Errorf("unknown output format %q, must be table or json", outputFormat)
+			}
+			kubeClient, err := ctx.CLIClient()
+			if err != nil {
+				return err
+			}
+			clientNamespace := ctx.NamespaceOrDefault(ctx.Namespace())
+			service, serviceNamespace, _ := strings.Cut(args[0], ".")
+			if serviceNamespace == "" {
+				serviceNamespace = clientNamespace
+			}
+			report, err := run(cmd.ErrOrStderr(), kubeClient, clientNamespace, service, serviceNamespace, o)
+			if err != nil {
+				return err
+			}
+			return printReport(cmd.OutOrStdout(), report, outputFormat)
+		},
+	}
+	cmd.Flags().Int32Var(&o.port, "port", 0, "Service port to send the requests to, by default the first port of the service")
+	cmd.Flags().StringVar(&o.path, "path", "/", "Path of the HTTP requests")
+	cmd.Flags().IntVar(&o.concurrency, "concurrency", 0,
+		"Number of concurrent connections, by default exceeding the tightest limit on connections or requests")
+	cmd.Flags().IntVar(&o.requests, "requests", 0, "Total number of requests, by default 10 per connection")
+	cmd.Flags().StringVar(&o.image, "image", defaultImage, "Image of the fortio load generator of the client pod")
+	cmd.Flags().DurationVar(&o.timeout, "timeout", 2*time.Minute, "Time to wait for the client pod to be ready")
+	cmd.Flags().StringVarP(&outputFormat, "output", "o", "table", "Output format: one of table|json")
+	return cmd
+}
+
+func run(log io.Writer, kubeClient kube.CLIClient, clientNamespace, service, serviceNamespace string, o options) (*Report, error) {
+	svc, err := kubeClient.Kube().CoreV1().Services(serviceNamespace).Get(context.TODO(), service, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve service %s.%s: %v", service, serviceNamespace, err)
+	}
+	port, err := servicePort(svc, o.port)
+	if err != nil {
+		return nil, err
+	}
+	host := fmt.Sprintf("%s.%s.svc.%s", svc.Name, svc.Namespace, constants.DefaultClusterLocalDomain)
+	clusterName := fmt.Sprintf("outbound|%d||%s", port, host)
+
+	_, _ = fmt.Fprintf(log, "Starting the client pod in namespace %s...\n", clientNamespace)
+	pod, err := startClient(kubeClient, clientNamespace, o.image, o.timeout)
+	if pod != nil {
+		defer func() {
+			if err := kubeClient.Kube().CoreV1().Pods(pod.Namespace).Delete(context.TODO(), pod.Name, metav1.DeleteOptions{}); err != nil {
+				_, _ = fmt.Fprintf(log, "Failed to delete the client pod %s.%s: %v\n", pod.Name, pod.Namespace, err)
+			}
+		}()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := clientCluster(kubeClient, pod, clusterName)
+	if err != nil {
+		return nil, err
+	}
+	limits := LimitsOf(c)
+	if limits.Empty() {
+		return nil, fmt.Errorf("cluster %s has no connectionPool or outlierDetection limit to test, "+
+			"check the DestinationRule of %s", clusterName, host)
+	}
+	r := &Report{Cluster: clusterName, Limits: limits, Concurrency: o.concurrency, Requests: o.requests}
+	if r.Concurrency <= 0 {
+		r.Concurrency = limits.DefaultConcurrency()
+	}
+	if r.Requests <= 0 {
+		r.Requests = 10 * r.Concurrency
+	}
+
+	_, _ = fmt.Fprintf(log, "Sending %d requests over %d connections to %s:%d%s...\n", r.Requests, r.Concurrency, host, port, o.path)
+	target := fmt.Sprintf("http://%s:%d%s", host, port, o.path)
+	stdout, stderr, err := kubeClient.PodExecCommands(pod.Name, pod.Namespace, loadContainer, []string{
+		"fortio", "load", "-c", strconv.Itoa(r.Concurrency), "-n", strconv.Itoa(r.Requests), "-qps", "0",
+		"-allow-initial-errors", "-json", "-", target,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to run the load: %v: %s", err, stderr)
+	}
+	if r.Responses, err = parseResponses([]byte(stdout)); err != nil {
+		return nil, err
+	}
+
+	filter := fmt.Sprintf(`^cluster\.%s\.`, regexp.QuoteMeta(clusterName))
+	stats, err := kubeClient.EnvoyDo(context.TODO(), pod.Name, pod.Namespace, "GET", "stats?filter="+url.QueryEscape(filter))
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve stats from the client proxy: %v", err)
+	}
+	r.Checks = Evaluate(limits, r.Concurrency, parseCounters(stats, "cluster."+clusterName+"."))
+	return r, nil
+}
+
+func servicePort(svc *corev1.Service, port int32) (int32, error) {
+	for _, p := range svc.Spec.Ports {
+		if port == 0 || p.Port == port {
+			return p.Port, nil
+		}
+	}
+	if port == 0 {
+		return 0, fmt.Errorf("service %s.%s has no port", svc.Name, svc.Namespace)
+	}
+	return 0, fmt.Errorf("service %s.%s has no port %d", svc.Name, svc.Namespace, port)
+}
+
+// startClient creates the client pod and waits for it to be ready. The pod is returned whenever it was created, so
+// it can be deleted.
+func startClient(kubeClient kube.CLIClient, namespace, image string, timeout time.Duration) (*corev1.Pod, error) {
+	pod, err := kubeClient.Kube().CoreV1().Pods(namespace).Create(context.TODO(), &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: clientLabel + "-",
+			Namespace:    namespace,
+			Labels:       map[string]string{"app": clientLabel, "sidecar.istio.io/inject": "true"},
+			// The load starts once the proxy has its config.
+			Annotations: map[string]string{"proxy.istio.io/config": `{"holdApplicationUntilProxyStarts": true}`},
+		},
+		Spec: corev1.PodSpec{
+			RestartPolicy: corev1.RestartPolicyNever,
+			Containers:    []corev1.Container{{Name: loadContainer, Image: image, Args: []string{"server"}}},
+		},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the client pod: %v", err)
+	}
+	if inject.FindSidecar(pod) == nil {
+		return pod, fmt.Errorf("the client pod was not injected with a sidecar, enable injection in namespace %s", namespace)
+	}
+	deadline := time.Now().Add(timeout)
+	for {
+		pod, err = kubeClient.Kube().CoreV1().Pods(pod.Namespace).Get(context.TODO(), pod.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to retrieve the client pod: %v", err)
+		}
+		if podReady(pod) {
+			return pod, nil
+		}
+		if time.Now().After(deadline) {
+			return pod, fmt.Errorf("timed out waiting for the client pod %s.%s to be ready", pod.Name, pod.Namespace)
+		}
+		time.Sleep(time.Second)
+	}
+}
+
+func podReady(pod *corev1.Pod) bool {
+	for _, c := range pod.Status.Conditions {
+		if c.Type == corev1.PodReady {
+			return c.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+func clientCluster(kubeClient kube.CLIClient, pod *corev1.Pod, clusterName string) (*cluster.Cluster, error) {
+	b, err := kubeClient.EnvoyDo(context.TODO(), pod.Name, pod.Namespace, "GET", "config_dump")
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve the config of the client proxy: %v", err)
+	}
+	cd := &configdump.Wrapper{}
+	if err := cd.UnmarshalJSON(b); err != nil {
+		return nil, err
+	}
+	clusters, err := cd.GetDynamicClusterDump(true)
+	if err != nil {
+		return nil, err
+	}
+	for _, dac := range clusters.DynamicActiveClusters {
+		c := &cluster.Cluster{}
+		if err := dac.GetCluster().UnmarshalTo(c); err != nil {
+			return nil, err
+		}
+		if c.Name == clusterName {
+			return c, nil
+		}
+	}
+	return nil, fmt.Errorf("cluster %s not found in the client proxy, check the Sidecar resources of the namespace", clusterName)
+}
+
+// parseResponses returns the number of responses by status code from the JSON output of fortio.
+func parseResponses(b []byte) (map[string]int, error) {
+	var out struct {
+		RetCodes map[string]int `json:"RetCodes"`
+	}
+	if err := json.Unmarshal(b, &out); err != nil {
+		return nil, fmt.Errorf("failed to parse the result of the load: %v", err)
+	}
+	return out.RetCodes, nil
+}
+
+func printReport(w io.Writer, r *Report, outputFormat string) error {
+	if outputFormat == "json" {
+		out, err := json.MarshalIndent(r, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(out))
+		return err
+	}
+	_, _ = fmt.Fprintf(w, "Cluster:     %s\n", r.Cluster)
+	_, _ = fmt.Fprintf(w, "Load:        %d requests over %d connections\n", r.Requests, r.Concurrency)
+	codes := make([]string, 0, len(r.Responses))
+	for code := range r.Responses {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	responses := make([]string, 0, len(codes))
+	for _, code := range codes {
+		responses = append(responses, fmt.Sprintf("%s: %d", code, r.Responses[code]))
+	}
+	_, _ = fmt.Fprintf(w, "Responses:   %s\n\n", strings.Join(responses, ", "))
+
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "SETTING\tCONFIGURED\tEXPECTED\tOBSERVED\tVERDICT")
+	for _, c := range r.Checks {
+		expected := "-"
+		if c.Expected != nil {
+			expected = "no trip"
+			if *c.Expected {
+				expected = "trip"
+			}
+		}
+		_, _ = fmt.Fprintf(tw, "%s\t%d\t%s\t%d %s\t%s\n", c.Setting, c.Configured, expected, c.Observed, c.Stat, c.Verdict())
+	}
+	return tw.Flush()
+}
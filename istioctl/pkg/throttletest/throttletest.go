@@ -0,0 +1,187 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package throttletest
+
+import (
+	"bufio"
+	"bytes"
+	"math"
+	"strconv"
+	"strings"
+
+	cluster "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+)
+
+// Limits are the circuit breaking and outlier detection settings of a cluster of the client proxy, as configured by
+// the connectionPool and outlierDetection of a DestinationRule. Zero is unlimited, or disabled.
+type Limits struct {
+	MaxConnections           uint32 `json:"maxConnections,omitempty"`
+	MaxPendingRequests       uint32 `json:"maxPendingRequests,omitempty"`
+	MaxRequests              uint32 `json:"maxRequests,omitempty"`
+	MaxRetries               uint32 `json:"maxRetries,omitempty"`
+	Consecutive5xxErrors     uint32 `json:"consecutive5xxErrors,omitempty"`
+	ConsecutiveGatewayErrors uint32 `json:"consecutiveGatewayErrors,omitempty"`
+}
+
+// LimitsOf returns the limits of the cluster. Istio sets the thresholds it does not configure to the maximum, which
+// is considered unlimited.
+func LimitsOf(c *cluster.Cluster) Limits {
+	var l Limits
+	for _, t := range c.GetCircuitBreakers().GetThresholds() {
+		if t.GetPriority() != core.RoutingPriority_DEFAULT {
+			continue
+		}
+		l.MaxConnections = limit(t.GetMaxConnections().GetValue())
+		l.MaxPendingRequests = limit(t.GetMaxPendingRequests().GetValue())
+		l.MaxRequests = limit(t.GetMaxRequests().GetValue())
+		l.MaxRetries = limit(t.GetMaxRetries().GetValue())
+	}
+	if od := c.GetOutlierDetection(); od != nil {
+		// Envoy enforces consecutive 5xx ejections unless told otherwise, and never consecutive gateway failures.
+		if od.GetEnforcingConsecutive_5Xx() == nil || od.GetEnforcingConsecutive_5Xx().GetValue() > 0 {
+			l.Consecutive5xxErrors = od.GetConsecutive_5Xx().GetValue()
+		}
+		if od.GetEnforcingConsecutiveGatewayFailure().GetValue() > 0 {
+			l.ConsecutiveGatewayErrors = od.GetConsecutiveGatewayFailure().GetValue()
+		}
+	}
+	return l
+}
+
+func limit(v uint32) uint32 {
+	if v == math.MaxUint32 {
+		return 0
+	}
+	return v
+}
+
+// Empty returns whether the cluster has no limit to test.
+func (l Limits) Empty() bool {
+	return l == Limits{}
+}
+
+// DefaultConcurrency returns a concurrency exceeding the tightest limit on concurrent requests.
+func (l Limits) DefaultConcurrency() int {
+	capacity := uint32(0)
+	tighten := func(v uint32) {
+		if v != 0 && (capacity == 0 || v < capacity) {
+			capacity = v
+		}
+	}
+	tighten(l.MaxConnections)
+	tighten(l.MaxRequests)
+	if capacity == 0 {
+		return 16
+	}
+	return int(min(2*capacity+1, 512))
+}
+
+// Check compares a configured limit with the behavior of the proxy under load.
+type Check struct {
+	// Setting is the DestinationRule field of the limit.
+	Setting    string `json:"setting"`
+	Configured uint32 `json:"configured"`
+	// Stat is the Envoy statistic of the cluster counting the times the limit tripped.
+	Stat     string `json:"stat"`
+	Observed int64  `json:"observed"`
+	// Expected is whether the limit should trip at the concurrency of the load, or nil when this depends on the
+	// destination.
+	Expected *bool `json:"expected,omitempty"`
+}
+
+// Tripped returns whether the limit tripped during the load.
+func (c Check) Tripped() bool {
+	return c.Observed > 0
+}
+
+// Verdict is PASS when the limit tripped as expected, FAIL when it did not, and INFO when it cannot be predicted.
+func (c Check) Verdict() string {
+	switch {
+	case c.Expected == nil:
+		return "INFO"
+	case *c.Expected == c.Tripped():
+		return "PASS"
+	default:
+		return "FAIL"
+	}
+}
+
+// Evaluate returns the checks of the configured limits, given the counters of the cluster after a load with the
+// concurrency.
+func Evaluate(l Limits, concurrency int, counters map[string]int64) []Check {
+	c := uint64(concurrency)
+	expect := func(b bool) *bool { return &b }
+	var checks []Check
+	if l.MaxConnections != 0 {
+		checks = append(checks, Check{
+			Setting: "tcp.maxConnections", Configured: l.MaxConnections, Stat: "upstream_cx_overflow",
+			Expected: expect(c > uint64(l.MaxConnections)),
+		})
+	}
+	if l.MaxPendingRequests != 0 {
+		// Requests only queue when no connection is available.
+		expected := l.MaxConnections != 0 && c > uint64(l.MaxConnections)+uint64(l.MaxPendingRequests)
+		checks = append(checks, Check{
+			Setting: "http.http1MaxPendingRequests", Configured: l.MaxPendingRequests, Stat: "upstream_rq_pending_overflow",
+			Expected: expect(expected),
+		})
+	}
+	if l.MaxRequests != 0 {
+		checks = append(checks, Check{
+			Setting: "http.http2MaxRequests", Configured: l.MaxRequests, Stat: "upstream_rq_pending_overflow",
+			Expected: expect(c > uint64(l.MaxRequests)),
+		})
+	}
+	if l.MaxRetries != 0 {
+		checks = append(checks, Check{Setting: "http.maxRetries", Configured: l.MaxRetries, Stat: "upstream_rq_retry_overflow"})
+	}
+	if l.Consecutive5xxErrors != 0 {
+		checks = append(checks, Check{
+			Setting: "outlierDetection.consecutive5xxErrors", Configured: l.Consecutive5xxErrors,
+			Stat: "outlier_detection.ejections_enforced_consecutive_5xx",
+		})
+	}
+	if l.ConsecutiveGatewayErrors != 0 {
+		checks = append(checks, Check{
+			Setting: "outlierDetection.consecutiveGatewayErrors", Configured: l.ConsecutiveGatewayErrors,
+			Stat: "outlier_detection.ejections_enforced_consecutive_gateway_failure",
+		})
+	}
+	for i := range checks {
+		checks[i].Observed = counters[checks[i].Stat]
+	}
+	return checks
+}
+
+// parseCounters parses the counters of the Envoy admin API stats in text format, keeping those with the prefix.
+func parseCounters(b []byte, prefix string) map[string]int64 {
+	counters := map[string]int64{}
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	for scanner.Scan() {
+		name, value, ok := strings.Cut(scanner.Text(), ": ")
+		if !ok {
+			continue
+		}
+		name, ok = strings.CutPrefix(name, prefix)
+		if !ok {
+			continue
+		}
+		if v, err := strconv.ParseInt(value, 10, 64); err == nil {
+			counters[name] = v
+		}
+	}
+	return counters
+}
@@ -0,0 +1,94 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package throttletest
+
+import (
+	"math"
+	"testing"
+
+	cluster "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	"istio.io/istio/pkg/test/util/assert"
+)
+
+func TestLimitsOf(t *testing.T) {
+	c := &cluster.Cluster{
+		CircuitBreakers: &cluster.CircuitBreakers{Thresholds: []*cluster.CircuitBreakers_Thresholds{{
+			MaxConnections:     wrapperspb.UInt32(1),
+			MaxPendingRequests: wrapperspb.UInt32(1),
+			MaxRequests:        wrapperspb.UInt32(math.MaxUint32),
+			MaxRetries:         wrapperspb.UInt32(math.MaxUint32),
+		}}},
+		OutlierDetection: &cluster.OutlierDetection{
+			Consecutive_5Xx:                    wrapperspb.UInt32(5),
+			EnforcingConsecutive_5Xx:           wrapperspb.UInt32(0),
+			ConsecutiveGatewayFailure:          wrapperspb.UInt32(3),
+			EnforcingConsecutiveGatewayFailure: wrapperspb.UInt32(100),
+		},
+	}
+	assert.Equal(t, LimitsOf(c), Limits{MaxConnections: 1, MaxPendingRequests: 1, ConsecutiveGatewayErrors: 3})
+
+	// Clusters without DestinationRule only have the defaults of Istio.
+	c = &cluster.Cluster{CircuitBreakers: &cluster.CircuitBreakers{Thresholds: []*cluster.CircuitBreakers_Thresholds{{
+		MaxConnections:     wrapperspb.UInt32(math.MaxUint32),
+		MaxPendingRequests: wrapperspb.UInt32(math.MaxUint32),
+		MaxRequests:        wrapperspb.UInt32(math.MaxUint32),
+		MaxRetries:         wrapperspb.UInt32(math.MaxUint32),
+	}}}}
+	assert.Equal(t, LimitsOf(c).Empty(), true)
+}
+
+func TestDefaultConcurrency(t *testing.T) {
+	assert.Equal(t, Limits{MaxConnections: 1, MaxPendingRequests: 1}.DefaultConcurrency(), 3)
+	assert.Equal(t, Limits{MaxConnections: 100, MaxRequests: 10}.DefaultConcurrency(), 21)
+	assert.Equal(t, Limits{MaxRequests: 1000}.DefaultConcurrency(), 512)
+	assert.Equal(t, Limits{Consecutive5xxErrors: 5}.DefaultConcurrency(), 16)
+}
+
+func TestEvaluate(t *testing.T) {
+	stats := []byte(`cluster.outbound|8000||httpbin.foo.svc.cluster.local.upstream_cx_overflow: 12
+cluster.outbound|8000||httpbin.foo.svc.cluster.local.upstream_rq_pending_overflow: 0
+cluster.outbound|8000||httpbin.foo.svc.cluster.local.outlier_detection.ejections_enforced_consecutive_5xx: 1
+cluster.outbound|8000||httpbin.foo.svc.cluster.local.upstream_rq_time: P0(nan,1) P25(nan,1.025)
+`)
+	counters := parseCounters(stats, "cluster.outbound|8000||httpbin.foo.svc.cluster.local.")
+	assert.Equal(t, counters, map[string]int64{
+		"upstream_cx_overflow":                                 12,
+		"upstream_rq_pending_overflow":                         0,
+		"outlier_detection.ejections_enforced_consecutive_5xx": 1,
+	})
+
+	checks := Evaluate(Limits{MaxConnections: 1, MaxPendingRequests: 1, Consecutive5xxErrors: 5}, 3, counters)
+	var verdicts []string
+	for _, c := range checks {
+		verdicts = append(verdicts, c.Setting+" "+c.Verdict())
+	}
+	assert.Equal(t, verdicts, []string{
+		"tcp.maxConnections PASS",
+		// 3 concurrent requests exceed the connection and the pending request, but none overflowed.
+		"http.http1MaxPendingRequests FAIL",
+		"outlierDetection.consecutive5xxErrors INFO",
+	})
+}
+
+func TestParseResponses(t *testing.T) {
+	responses, err := parseResponses([]byte(`{"RunType": "HTTP", "RetCodes": {"200": 18, "503": 12}}`))
+	assert.NoError(t, err)
+	assert.Equal(t, responses, map[string]int{"200": 18, "503": 12})
+
+	_, err = parseResponses([]byte("Aborting because of error"))
+	assert.Error(t, err)
+}
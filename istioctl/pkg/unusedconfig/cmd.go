@@ -0,0 +1,233 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package unusedconfig
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/prometheus/client_golang/api"
+	promv1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/duration"
+
+	"istio.io/istio/istioctl/pkg/cli"
+	"istio.io/istio/istioctl/pkg/dashboard"
+	"istio.io/istio/pkg/kube"
+	"istio.io/istio/pkg/kube/inject"
+	"istio.io/istio/pkg/slices"
+)
+
+func Cmd(ctx cli.Context) *cobra.Command {
+	var (
+		telemetry    bool
+		idle         time.Duration
+		lookback     time.Duration
+		outputFormat string
+	)
+	cmd := &cobra.Command{
+		Use:   "unused-config",
+		Short: "Lists Istio config objects which have no effect, to help cleaning them up",
+		Long: `Lists Istio config objects which have no effect on the mesh:
+
+  * VirtualServices without gateway binding whose hosts match no Service or ServiceEntry, or receive no traffic
+  * DestinationRules whose host matches no Service or ServiceEntry
+  * ServiceEntries whose hosts have no endpoints, or receive no traffic
+  * WasmPlugins whose selector matches no proxy
+
+Each object is listed with its age and the time of its last update. The time of the last request to the hosts of
+the object is queried from Prometheus, when available: objects whose hosts received no request for --idle are
+listed, as their routing or policies are unused.
+
+Telemetry requires a Prometheus pod labeled app.kubernetes.io/name=prometheus in the Istio namespace, and is skipped
+otherwise. The findings are hints: confirm that an object is not needed, e.g. for a failover or seasonal traffic,
+before deleting it.`,
+		Example: `  # List the unused config of all namespaces
+  istioctl experimental unused-config
+
+  # List the unused config of a namespace, considering hosts without traffic for 30 days as unused
+  istioctl experimental unused-config -n bookinfo --idle 720h --lookback 2160h
+
+  # List config with no effect without querying Prometheus, as JSON
+  istioctl experimental unused-config --telemetry=false -o json`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if outputFormat != "table" && outputFormat != "json" {
+				return fmt.Errorf("unknown output format %q, must be table or json", outputFormat)
+			}
+			if idle > lookback {
+				return fmt.Errorf("--idle %v must not exceed --lookback %v", idle, lookback)
+			}
+			kubeClient, err := ctx.CLIClient()
+			if err != nil {
+				return err
+			}
+			inv, err := listInventory(kubeClient)
+			if err != nil {
+				return err
+			}
+			if telemetry {
+				inv.Lookback = lookback
+				inv.LastRequest, err = lastRequests(kubeClient, ctx.IstioNamespace(), lookback)
+				if err != nil {
+					_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Skipping the traffic of the hosts: %v\n", err)
+				}
+			}
+			findings := Find(inv, ctx.IstioNamespace(), idle, time.Now())
+			if ns := ctx.Namespace(); ns != "" {
+				findings = slices.Filter(findings, func(f Finding) bool { return f.Namespace == ns })
+			}
+			return printFindings(cmd.OutOrStdout(), findings, outputFormat, time.Now())
+		},
+	}
+	cmd.Flags().BoolVar(&telemetry, "telemetry", true, "Query Prometheus for the last request to the hosts of the config")
+	cmd.Flags().DurationVar(&idle, "idle", 7*24*time.Hour, "Duration without requests after which the hosts of the config are unused")
+	cmd.Flags().DurationVar(&lookback, "lookback", 30*24*time.Hour, "How far back to search for the last request to the hosts")
+	cmd.Flags().StringVarP(&outputFormat, "output", "o", "table", "Output format: one of table|json")
+	return cmd
+}
+
+func listInventory(kubeClient kube.CLIClient) (Inventory, error) {
+	ctx := context.TODO()
+	opts := metav1.ListOptions{}
+	var inv Inventory
+	vss, err := kubeClient.Istio().NetworkingV1().VirtualServices(metav1.NamespaceAll).List(ctx, opts)
+	if err != nil {
+		return inv, fmt.Errorf("failed to list virtual services: %v", err)
+	}
+	inv.VirtualServices = vss.Items
+	drs, err := kubeClient.Istio().NetworkingV1().DestinationRules(metav1.NamespaceAll).List(ctx, opts)
+	if err != nil {
+		return inv, fmt.Errorf("failed to list destination rules: %v", err)
+	}
+	inv.DestinationRules = drs.Items
+	ses, err := kubeClient.Istio().NetworkingV1().ServiceEntries(metav1.NamespaceAll).List(ctx, opts)
+	if err != nil {
+		return inv, fmt.Errorf("failed to list service entries: %v", err)
+	}
+	inv.ServiceEntries = ses.Items
+	wes, err := kubeClient.Istio().NetworkingV1().WorkloadEntries(metav1.NamespaceAll).List(ctx, opts)
+	if err != nil {
+		return inv, fmt.Errorf("failed to list workload entries: %v", err)
+	}
+	inv.WorkloadEntries = wes.Items
+	wps, err := kubeClient.Istio().ExtensionsV1alpha1().WasmPlugins(metav1.NamespaceAll).List(ctx, opts)
+	if err != nil {
+		return inv, fmt.Errorf("failed to list wasm plugins: %v", err)
+	}
+	inv.WasmPlugins = wps.Items
+	svcs, err := kubeClient.Kube().CoreV1().Services(metav1.NamespaceAll).List(ctx, opts)
+	if err != nil {
+		return inv, fmt.Errorf("failed to list services: %v", err)
+	}
+	for i := range svcs.Items {
+		inv.Services = append(inv.Services, &svcs.Items[i])
+	}
+	pods, err := kubeClient.Kube().CoreV1().Pods(metav1.NamespaceAll).List(ctx, opts)
+	if err != nil {
+		return inv, fmt.Errorf("failed to list pods: %v", err)
+	}
+	for i := range pods.Items {
+		if inject.FindSidecar(&pods.Items[i]) != nil {
+			inv.Proxies = append(inv.Proxies, &pods.Items[i])
+		}
+	}
+	return inv, nil
+}
+
+// lastRequests returns the time of the last request to each destination service within the lookback.
+func lastRequests(kubeClient kube.CLIClient, istioNamespace string, lookback time.Duration) (map[string]time.Time, error) {
+	promAPI, closer, err := prometheusAPI(kubeClient, istioNamespace)
+	if err != nil {
+		return nil, err
+	}
+	defer closer()
+	// The rate is sampled hourly over the lookback, with a window of the same size, so no request is missed.
+	query := fmt.Sprintf(`max_over_time(timestamp(sum by (destination_service) (rate(istio_requests_total[1h])) > 0)[%s:1h])`,
+		model.Duration(lookback))
+	val, _, err := promAPI.Query(context.Background(), query, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("query() failure for '%s': %v", query, err)
+	}
+	vector, ok := val.(model.Vector)
+	if !ok {
+		return nil, errors.New("bad metric value type returned for query")
+	}
+	last := map[string]time.Time{}
+	for _, s := range vector {
+		last[string(s.Metric["destination_service"])] = time.Unix(int64(s.Value), 0)
+	}
+	return last, nil
+}
+
+func prometheusAPI(kubeClient kube.CLIClient, istioNamespace string) (promv1.API, func(), error) {
+	pl, err := kubeClient.PodsForSelector(context.TODO(), istioNamespace, "app.kubernetes.io/name=prometheus")
+	if err != nil {
+		return nil, nil, fmt.Errorf("not able to locate Prometheus pod: %v", err)
+	}
+	if len(pl.Items) < 1 {
+		return nil, nil, errors.New("no Prometheus pods found")
+	}
+	fw, err := kubeClient.NewPortForwarder(pl.Items[0].Name, istioNamespace, "", 0, 9090)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not build port forwarder for prometheus: %v", err)
+	}
+	if err = fw.Start(); err != nil {
+		return nil, nil, fmt.Errorf("failure running port forward process: %v", err)
+	}
+	dashboard.ClosePortForwarderOnInterrupt(fw)
+	promClient, err := api.NewClient(api.Config{Address: fmt.Sprintf("http://%s", fw.Address())})
+	if err != nil {
+		fw.Close()
+		return nil, nil, fmt.Errorf("could not build prometheus client: %v", err)
+	}
+	return promv1.NewAPI(promClient), fw.Close, nil
+}
+
+func printFindings(w io.Writer, findings []Finding, outputFormat string, now time.Time) error {
+	if outputFormat == "json" {
+		if findings == nil {
+			findings = []Finding{}
+		}
+		out, err := json.MarshalIndent(findings, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(out))
+		return err
+	}
+	if len(findings) == 0 {
+		_, err := fmt.Fprintln(w, "No unused Istio config found.")
+		return err
+	}
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "KIND\tNAMESPACE\tNAME\tAGE\tLAST UPDATED\tLAST REQUEST\tREASON")
+	for _, f := range findings {
+		lastRequest := f.LastRequest
+		if lastRequest == "" {
+			lastRequest = "-"
+		}
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s ago\t%s\t%s\n", f.Kind, f.Namespace, f.Name,
+			duration.HumanDuration(now.Sub(f.Created)), duration.HumanDuration(now.Sub(f.LastUpdated)), lastRequest, f.Reason)
+	}
+	return tw.Flush()
+}
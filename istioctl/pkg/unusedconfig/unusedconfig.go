@@ -0,0 +1,213 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package unusedconfig
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	klabels "k8s.io/apimachinery/pkg/labels"
+
+	networking "istio.io/api/networking/v1alpha3"
+	clientextensions "istio.io/client-go/pkg/apis/extensions/v1alpha1"
+	clientnetworking "istio.io/client-go/pkg/apis/networking/v1"
+	"istio.io/istio/pkg/config/constants"
+	"istio.io/istio/pkg/config/host"
+	"istio.io/istio/pkg/slices"
+)
+
+// Inventory is the config of the cluster the advisor looks at.
+type Inventory struct {
+	VirtualServices  []*clientnetworking.VirtualService
+	DestinationRules []*clientnetworking.DestinationRule
+	ServiceEntries   []*clientnetworking.ServiceEntry
+	WorkloadEntries  []*clientnetworking.WorkloadEntry
+	WasmPlugins      []*clientextensions.WasmPlugin
+	Services         []*corev1.Service
+	// Proxies are the pods running an Istio proxy.
+	Proxies []*corev1.Pod
+
+	// LastRequest is the time of the last request to each destination service seen within the lookback, by the
+	// destination_service label of the Istio metrics. It is nil when telemetry is unavailable.
+	LastRequest map[string]time.Time
+	// Lookback is how far back LastRequest was searched.
+	Lookback time.Duration
+}
+
+// Finding is a config object with no effect on the mesh.
+type Finding struct {
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	Reason    string `json:"reason"`
+	// Created and LastUpdated hint at whether the object is still maintained.
+	Created     time.Time `json:"created"`
+	LastUpdated time.Time `json:"lastUpdated"`
+	// LastRequest hints at when the hosts of the object were last used, when telemetry is available.
+	LastRequest string `json:"lastRequest,omitempty"`
+}
+
+// Find returns the config objects of the inventory with no effect, and those whose hosts received no request since
+// idle before now.
+func Find(inv Inventory, rootNamespace string, idle time.Duration, now time.Time) []Finding {
+	known := knownHosts(inv)
+	var findings []Finding
+	add := func(kind string, meta metav1.ObjectMeta, reason, lastRequest string) {
+		findings = append(findings, Finding{
+			Kind:        kind,
+			Namespace:   meta.Namespace,
+			Name:        meta.Name,
+			Reason:      reason,
+			Created:     meta.CreationTimestamp.Time,
+			LastUpdated: lastUpdated(meta),
+			LastRequest: lastRequest,
+		})
+	}
+	// idleHosts returns whether the hosts received no request since idle before now, and describes the last one.
+	idleHosts := func(hosts []host.Name) (bool, string) {
+		if inv.LastRequest == nil {
+			return false, ""
+		}
+		var last time.Time
+		for svc, t := range inv.LastRequest {
+			if slices.FindFunc(hosts, func(h host.Name) bool { return h.Matches(host.Name(svc)) }) != nil && t.After(last) {
+				last = t
+			}
+		}
+		if last.IsZero() {
+			return true, fmt.Sprintf("none in the last %v", inv.Lookback)
+		}
+		return now.Sub(last) > idle, fmt.Sprintf("%s (%v ago)", last.UTC().Format(time.RFC3339), now.Sub(last).Round(time.Minute))
+	}
+
+	for _, vs := range inv.VirtualServices {
+		// Virtual services bound to gateways route traffic from outside the mesh, which is not attributed to their hosts.
+		if slices.FindFunc(vs.Spec.Gateways, func(g string) bool { return g != constants.IstioMeshGateway }) != nil {
+			continue
+		}
+		hosts := qualify(vs.Spec.Hosts, vs.Namespace)
+		idle, last := idleHosts(hosts)
+		switch {
+		case slices.FindFunc(hosts, known.matches) == nil:
+			add("VirtualService", vs.ObjectMeta, "no gateway binding, and its hosts match no Service or ServiceEntry", last)
+		case idle:
+			add("VirtualService", vs.ObjectMeta, "no gateway binding, and no traffic to its hosts", last)
+		}
+	}
+
+	for _, dr := range inv.DestinationRules {
+		hosts := qualify([]string{dr.Spec.Host}, dr.Namespace)
+		if !known.matches(hosts[0]) {
+			_, last := idleHosts(hosts)
+			add("DestinationRule", dr.ObjectMeta, fmt.Sprintf("host %s matches no Service or ServiceEntry", hosts[0]), last)
+		}
+	}
+
+	for _, se := range inv.ServiceEntries {
+		hosts := qualify(se.Spec.Hosts, se.Namespace)
+		idle, last := idleHosts(hosts)
+		selector := se.Spec.GetWorkloadSelector().GetLabels()
+		switch {
+		case len(selector) > 0 && !selectsWorkload(inv, se.Namespace, selector):
+			add("ServiceEntry", se.ObjectMeta, "workloadSelector matches no pod or WorkloadEntry, the hosts have no endpoints", last)
+		case len(selector) == 0 && se.Spec.Resolution == networking.ServiceEntry_STATIC && len(se.Spec.Endpoints) == 0:
+			add("ServiceEntry", se.ObjectMeta, "STATIC resolution without endpoints, the hosts have no endpoints", last)
+		case idle:
+			add("ServiceEntry", se.ObjectMeta, "no traffic to its hosts", last)
+		}
+	}
+
+	for _, wp := range inv.WasmPlugins {
+		// Plugins attached to Gateway API resources are applied to their proxies, which are not resolved here.
+		if wp.Spec.TargetRef != nil || len(wp.Spec.TargetRefs) > 0 {
+			continue
+		}
+		selector := klabels.SelectorFromSet(wp.Spec.GetSelector().GetMatchLabels())
+		if slices.FindFunc(inv.Proxies, func(p *corev1.Pod) bool {
+			return (wp.Namespace == rootNamespace || p.Namespace == wp.Namespace) && selector.Matches(klabels.Set(p.Labels))
+		}) == nil {
+			add("WasmPlugin", wp.ObjectMeta, "selects no proxy", "")
+		}
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		a, b := findings[i], findings[j]
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		return a.Name < b.Name
+	})
+	return findings
+}
+
+type hostSet []host.Name
+
+func (s hostSet) matches(h host.Name) bool {
+	return slices.FindFunc(s, h.Matches) != nil
+}
+
+// knownHosts returns the hosts of the services and service entries of the inventory.
+func knownHosts(inv Inventory) hostSet {
+	var hosts hostSet
+	for _, svc := range inv.Services {
+		hosts = append(hosts, host.Name(fmt.Sprintf("%s.%s.svc.%s", svc.Name, svc.Namespace, constants.DefaultClusterLocalDomain)))
+	}
+	for _, se := range inv.ServiceEntries {
+		hosts = append(hosts, qualify(se.Spec.Hosts, se.Namespace)...)
+	}
+	return hosts
+}
+
+// qualify returns the hosts of a config object of the namespace, with short names relative to the namespace.
+func qualify(hosts []string, namespace string) []host.Name {
+	out := make([]host.Name, 0, len(hosts))
+	for _, h := range hosts {
+		if !strings.Contains(h, ".") && h != "*" {
+			h = fmt.Sprintf("%s.%s.svc.%s", h, namespace, constants.DefaultClusterLocalDomain)
+		}
+		out = append(out, host.Name(h))
+	}
+	return out
+}
+
+func selectsWorkload(inv Inventory, namespace string, labels map[string]string) bool {
+	selector := klabels.SelectorFromSet(labels)
+	if slices.FindFunc(inv.Proxies, func(p *corev1.Pod) bool {
+		return p.Namespace == namespace && selector.Matches(klabels.Set(p.Labels))
+	}) != nil {
+		return true
+	}
+	return slices.FindFunc(inv.WorkloadEntries, func(we *clientnetworking.WorkloadEntry) bool {
+		return we.Namespace == namespace && selector.Matches(klabels.Set(we.Spec.Labels))
+	}) != nil
+}
+
+// lastUpdated returns the time of the last write of the object recorded in its managed fields, or its creation time.
+func lastUpdated(meta metav1.ObjectMeta) time.Time {
+	last := meta.CreationTimestamp.Time
+	for _, f := range meta.ManagedFields {
+		if f.Time != nil && f.Time.After(last) {
+			last = f.Time.Time
+		}
+	}
+	return last
+}
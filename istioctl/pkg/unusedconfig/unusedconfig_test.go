@@ -0,0 +1,142 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package unusedconfig
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	extensions "istio.io/api/extensions/v1alpha1"
+	networking "istio.io/api/networking/v1alpha3"
+	typev1beta1 "istio.io/api/type/v1beta1"
+	clientextensions "istio.io/client-go/pkg/apis/extensions/v1alpha1"
+	clientnetworking "istio.io/client-go/pkg/apis/networking/v1"
+	"istio.io/istio/pkg/test/util/assert"
+)
+
+var created = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func meta(namespace, name string) metav1.ObjectMeta {
+	return metav1.ObjectMeta{Name: name, Namespace: namespace, CreationTimestamp: metav1.NewTime(created)}
+}
+
+func inventory() Inventory {
+	return Inventory{
+		VirtualServices: []*clientnetworking.VirtualService{
+			{ObjectMeta: meta("bookinfo", "reviews"), Spec: networking.VirtualService{Hosts: []string{"reviews"}}},
+			{ObjectMeta: meta("bookinfo", "ratings"), Spec: networking.VirtualService{Hosts: []string{"ratings"}, Gateways: []string{"mesh"}}},
+			{ObjectMeta: meta("bookinfo", "legacy"), Spec: networking.VirtualService{Hosts: []string{"legacy"}}},
+			{ObjectMeta: meta("bookinfo", "ingress"), Spec: networking.VirtualService{Hosts: []string{"bookinfo.example.com"}, Gateways: []string{"bookinfo"}}},
+		},
+		DestinationRules: []*clientnetworking.DestinationRule{
+			{ObjectMeta: meta("bookinfo", "reviews"), Spec: networking.DestinationRule{Host: "reviews"}},
+			{ObjectMeta: meta("bookinfo", "legacy"), Spec: networking.DestinationRule{Host: "legacy.bookinfo.svc.cluster.local"}},
+			{ObjectMeta: meta("bookinfo", "external"), Spec: networking.DestinationRule{Host: "api.example.com"}},
+		},
+		ServiceEntries: []*clientnetworking.ServiceEntry{
+			{ObjectMeta: meta("bookinfo", "external"), Spec: networking.ServiceEntry{
+				Hosts: []string{"*.example.com"}, Resolution: networking.ServiceEntry_DNS,
+			}},
+			{ObjectMeta: meta("bookinfo", "vms"), Spec: networking.ServiceEntry{
+				Hosts: []string{"vm.bookinfo.internal"}, WorkloadSelector: &networking.WorkloadSelector{Labels: map[string]string{"app": "vm"}},
+			}},
+			{ObjectMeta: meta("bookinfo", "static"), Spec: networking.ServiceEntry{
+				Hosts: []string{"db.bookinfo.internal"}, Resolution: networking.ServiceEntry_STATIC,
+			}},
+		},
+		WasmPlugins: []*clientextensions.WasmPlugin{
+			{ObjectMeta: meta("bookinfo", "auth"), Spec: extensions.WasmPlugin{
+				Selector: &typev1beta1.WorkloadSelector{MatchLabels: map[string]string{"app": "reviews"}},
+			}},
+			{ObjectMeta: meta("bookinfo", "stale"), Spec: extensions.WasmPlugin{
+				Selector: &typev1beta1.WorkloadSelector{MatchLabels: map[string]string{"app": "details"}},
+			}},
+			{ObjectMeta: meta("istio-system", "global"), Spec: extensions.WasmPlugin{}},
+		},
+		Services: []*corev1.Service{
+			{ObjectMeta: meta("bookinfo", "reviews")},
+			{ObjectMeta: meta("bookinfo", "ratings")},
+		},
+		Proxies: []*corev1.Pod{
+			{ObjectMeta: metav1.ObjectMeta{Name: "reviews-1", Namespace: "bookinfo", Labels: map[string]string{"app": "reviews"}}},
+		},
+	}
+}
+
+func names(findings []Finding) []string {
+	var out []string
+	for _, f := range findings {
+		out = append(out, f.Kind+" "+f.Namespace+"/"+f.Name)
+	}
+	return out
+}
+
+func TestFind(t *testing.T) {
+	now := created.Add(60 * 24 * time.Hour)
+	findings := Find(inventory(), "istio-system", 7*24*time.Hour, now)
+	assert.Equal(t, names(findings), []string{
+		"DestinationRule bookinfo/legacy",
+		"ServiceEntry bookinfo/static",
+		"ServiceEntry bookinfo/vms",
+		"VirtualService bookinfo/legacy",
+		"WasmPlugin bookinfo/stale",
+	})
+	assert.Equal(t, findings[0].Reason, "host legacy.bookinfo.svc.cluster.local matches no Service or ServiceEntry")
+	assert.Equal(t, findings[0].LastRequest, "")
+}
+
+func TestFindWithTelemetry(t *testing.T) {
+	now := created.Add(60 * 24 * time.Hour)
+	inv := inventory()
+	inv.Lookback = 30 * 24 * time.Hour
+	inv.LastRequest = map[string]time.Time{
+		"reviews.bookinfo.svc.cluster.local": now.Add(-time.Hour),
+		"ratings.bookinfo.svc.cluster.local": now.Add(-10 * 24 * time.Hour),
+	}
+	findings := Find(inv, "istio-system", 7*24*time.Hour, now)
+	assert.Equal(t, names(findings), []string{
+		"DestinationRule bookinfo/legacy",
+		"ServiceEntry bookinfo/external",
+		"ServiceEntry bookinfo/static",
+		"ServiceEntry bookinfo/vms",
+		"VirtualService bookinfo/legacy",
+		"VirtualService bookinfo/ratings",
+		"WasmPlugin bookinfo/stale",
+	})
+	assert.Equal(t, findings[1].LastRequest, "none in the last 720h0m0s")
+	assert.Equal(t, findings[5].Reason, "no gateway binding, and no traffic to its hosts")
+	assert.Equal(t, findings[5].LastRequest, "2024-02-20T00:00:00Z (240h0m0s ago)")
+}
+
+func TestLastUpdated(t *testing.T) {
+	m := meta("bookinfo", "reviews")
+	updated := metav1.NewTime(created.Add(time.Hour))
+	m.ManagedFields = []metav1.ManagedFieldsEntry{{Manager: "kubectl", Time: &updated}, {Manager: "helm"}}
+	assert.Equal(t, lastUpdated(m), updated.Time)
+}
+
+func TestPrintFindings(t *testing.T) {
+	var out bytes.Buffer
+	assert.NoError(t, printFindings(&out, nil, "table", created))
+	assert.Equal(t, out.String(), "No unused Istio config found.\n")
+
+	out.Reset()
+	assert.NoError(t, printFindings(&out, nil, "json", created))
+	assert.Equal(t, out.String(), "[]\n")
+}
@@ -15,11 +15,16 @@
 package formatting
 
 import (
+	"encoding/json"
 	"testing"
 
 	. "github.com/onsi/gomega"
 
+	"istio.io/istio/pkg/config"
 	"istio.io/istio/pkg/config/analysis/diag"
+	"istio.io/istio/pkg/config/analysis/legacy/source/kube"
+	"istio.io/istio/pkg/config/analysis/msg"
+	"istio.io/istio/pkg/config/resource"
 	"istio.io/istio/pkg/url"
 )
 
@@ -138,6 +143,106 @@ func TestFormatter_PrintYAML(t *testing.T) {
 	g.Expect(output).To(Equal(expectedOutput))
 }
 
+func TestFormatter_PrintSARIF(t *testing.T) {
+	g := NewWithT(t)
+
+	fileResource := &resource.Instance{
+		Origin: &kube.Origin{
+			Type:     config.GroupVersionKind{Group: "networking.istio.io", Version: "v1", Kind: "VirtualService"},
+			FullName: resource.NewFullName("default", "reviews"),
+			Ref:      &kube.Position{Filename: "samples/reviews.yaml", Line: 3},
+		},
+	}
+	firstMsg := diag.NewMessage(
+		diag.NewMessageType(diag.Error, "B1", "Explosion accident: %v"),
+		fileResource,
+		"the bubble is too big",
+	)
+	firstMsg.Line = 12
+	secondMsg := diag.NewMessage(
+		diag.NewMessageType(diag.Info, "C1", "Collapse danger: %v"),
+		diag.MockResource("GrandCastle"),
+		"the castle is too old",
+	)
+	thirdMsg := diag.NewMessage(
+		diag.NewMessageType(diag.Error, "B1", "Explosion accident: %v"),
+		fileResource,
+		"the bubble is still too big",
+	)
+
+	msgs := diag.Messages{firstMsg, secondMsg, thirdMsg}
+	output, err := Print(msgs, SARIFFormat, false)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	var log sarifLog
+	g.Expect(json.Unmarshal([]byte(output), &log)).To(Succeed())
+	g.Expect(log.Version).To(Equal("2.1.0"))
+	g.Expect(log.Runs).To(HaveLen(1))
+	run := log.Runs[0]
+	g.Expect(run.Tool.Driver.Name).To(Equal("istioctl"))
+	g.Expect(run.Tool.Driver.Rules).To(Equal([]sarifRule{
+		{ID: "B1", HelpURI: url.ConfigAnalysis + "/b1/", DefaultConfiguration: sarifConfiguration{Level: "error"}},
+		{ID: "C1", HelpURI: url.ConfigAnalysis + "/c1/", DefaultConfiguration: sarifConfiguration{Level: "note"}},
+	}))
+	g.Expect(run.Results).To(Equal([]sarifResult{
+		{
+			RuleID:  "B1",
+			Level:   "error",
+			Message: sarifMessage{Text: "Explosion accident: the bubble is too big"},
+			Locations: []sarifLocation{{
+				PhysicalLocation: &sarifPhysicalLocation{
+					ArtifactLocation: sarifArtifactLocation{URI: "samples/reviews.yaml"},
+					Region:           &sarifRegion{StartLine: 12},
+				},
+				LogicalLocations: []sarifLogicalLocation{{FullyQualifiedName: "VirtualService default/reviews", Kind: "resource"}},
+			}},
+		},
+		{
+			RuleID:    "C1",
+			RuleIndex: 1,
+			Level:     "note",
+			Message:   sarifMessage{Text: "Collapse danger: the castle is too old"},
+			Locations: []sarifLocation{{
+				LogicalLocations: []sarifLogicalLocation{{FullyQualifiedName: "GrandCastle", Kind: "resource"}},
+			}},
+		},
+		{
+			RuleID:  "B1",
+			Level:   "error",
+			Message: sarifMessage{Text: "Explosion accident: the bubble is still too big"},
+			Locations: []sarifLocation{{
+				PhysicalLocation: &sarifPhysicalLocation{
+					ArtifactLocation: sarifArtifactLocation{URI: "samples/reviews.yaml"},
+					Region:           &sarifRegion{StartLine: 3},
+				},
+				LogicalLocations: []sarifLogicalLocation{{FullyQualifiedName: "VirtualService default/reviews", Kind: "resource"}},
+			}},
+		},
+	}))
+}
+
+func TestFormatter_PrintSARIFOverriddenLevel(t *testing.T) {
+	g := NewWithT(t)
+
+	// The messages of IST0118, an Info code by default, promoted to errors by --severities-file.
+	t118 := msg.PortNameIsNotUnderNamingConvention
+	m := diag.NewMessage(
+		diag.NewMessageType(diag.Error, t118.Code(), t118.Template()),
+		diag.MockResource("reviews"),
+		"http", 9080, "9080",
+	)
+	output, err := Print(diag.Messages{m}, SARIFFormat, false)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	var log sarifLog
+	g.Expect(json.Unmarshal([]byte(output), &log)).To(Succeed())
+	run := log.Runs[0]
+	g.Expect(run.Tool.Driver.Rules).To(HaveLen(1))
+	g.Expect(run.Tool.Driver.Rules[0].DefaultConfiguration.Level).To(Equal("note"))
+	g.Expect(run.Results).To(HaveLen(1))
+	g.Expect(run.Results[0].Level).To(Equal("error"))
+}
+
 func TestFormatter_PrintEmpty(t *testing.T) {
 	g := NewWithT(t)
 
@@ -151,4 +256,7 @@ func TestFormatter_PrintEmpty(t *testing.T) {
 
 	yamlOutput, _ := Print(msgs, YAMLFormat, false)
 	g.Expect(yamlOutput).To(Equal("[]\n"))
+
+	sarifOutput, _ := Print(msgs, SARIFFormat, false)
+	g.Expect(sarifOutput).To(ContainSubstring(`"results": []`))
 }
@@ -0,0 +1,186 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package formatting
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"istio.io/istio/pkg/config/analysis/diag"
+	"istio.io/istio/pkg/config/analysis/legacy/source/kube"
+	"istio.io/istio/pkg/config/analysis/msg"
+	"istio.io/istio/pkg/url"
+	"istio.io/istio/pkg/version"
+)
+
+const (
+	sarifVersion = "2.1.0"
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+)
+
+// The subset of the SARIF 2.1.0 log format (https://docs.oasis-open.org/sarif/sarif/v2.1.0/sarif-v2.1.0.html) used to
+// report analysis messages, as consumed by GitHub code scanning.
+type sarifLog struct {
+	Version string     `json:"version"`
+	Schema  string     `json:"$schema"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	Version        string      `json:"version,omitempty"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID                   string             `json:"id"`
+	HelpURI              string             `json:"helpUri"`
+	DefaultConfiguration sarifConfiguration `json:"defaultConfiguration"`
+}
+
+type sarifConfiguration struct {
+	Level string `json:"level"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	RuleIndex int             `json:"ruleIndex"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations,omitempty"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation *sarifPhysicalLocation `json:"physicalLocation,omitempty"`
+	LogicalLocations []sarifLogicalLocation `json:"logicalLocations,omitempty"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine int `json:"startLine"`
+}
+
+type sarifLogicalLocation struct {
+	FullyQualifiedName string `json:"fullyQualifiedName"`
+	Kind               string `json:"kind"`
+}
+
+// sarifLevels maps the message levels to the SARIF levels, which call informational results notes.
+var sarifLevels = map[diag.Level]string{
+	diag.Info:    "note",
+	diag.Warning: "warning",
+	diag.Error:   "error",
+}
+
+func printSARIF(ms diag.Messages) (string, error) {
+	run := sarifRun{
+		Tool: sarifTool{Driver: sarifDriver{
+			Name:           "istioctl",
+			Version:        version.Info.Version,
+			InformationURI: "https://istio.io/latest/docs/reference/commands/istioctl/#istioctl-analyze",
+			Rules:          []sarifRule{},
+		}},
+		Results: []sarifResult{},
+	}
+	ruleIndex := map[string]int{}
+	for i := range ms {
+		m := &ms[i]
+		code := m.Type.Code()
+		index, ok := ruleIndex[code]
+		if !ok {
+			index = len(run.Tool.Driver.Rules)
+			ruleIndex[code] = index
+			run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRule{
+				ID:                   code,
+				HelpURI:              fmt.Sprintf("%s/%s/", url.ConfigAnalysis, strings.ToLower(code)),
+				DefaultConfiguration: sarifConfiguration{Level: sarifLevels[defaultLevel(m.Type)]},
+			})
+		}
+		result := sarifResult{
+			RuleID:    code,
+			RuleIndex: index,
+			Level:     sarifLevels[m.Type.Level()],
+			Message:   sarifMessage{Text: fmt.Sprintf(m.Type.Template(), m.Parameters...)},
+		}
+		if location := sarifLocationOf(m); location != nil {
+			result.Locations = []sarifLocation{*location}
+		}
+		run.Results = append(run.Results, result)
+	}
+
+	out, err := json.MarshalIndent(sarifLog{Version: sarifVersion, Schema: sarifSchema, Runs: []sarifRun{run}}, "", "  ")
+	return string(out), err
+}
+
+// defaultLevel returns the level the messages of the type are reported at by default. The level of a message differs
+// from it when overridden with --severities-file. Types which are not built in, such as the ones of plugins, have no
+// other level than their own.
+func defaultLevel(t *diag.MessageType) diag.Level {
+	for _, builtin := range msg.All() {
+		if builtin.Code() == t.Code() {
+			return builtin.Level()
+		}
+	}
+	return t.Level()
+}
+
+// sarifLocationOf returns the location of the resource of the message: the file and line it was read from for file
+// based analysis, and its name in any case. The resources of a live cluster are not read from a file, so their results
+// only have a logical location, which SARIF consumers requiring a physical one, such as GitHub code scanning, do not
+// display: use file based analysis (--use-kube=false) for them.
+func sarifLocationOf(m *diag.Message) *sarifLocation {
+	if m.Resource == nil || m.Resource.Origin == nil {
+		return nil
+	}
+	location := &sarifLocation{
+		LogicalLocations: []sarifLogicalLocation{{FullyQualifiedName: m.Resource.Origin.FriendlyName(), Kind: "resource"}},
+	}
+	if position, ok := m.Resource.Origin.Reference().(*kube.Position); ok && position.Filename != "" {
+		physical := &sarifPhysicalLocation{ArtifactLocation: sarifArtifactLocation{URI: filepath.ToSlash(position.Filename)}}
+		line := position.Line
+		if m.Line != 0 {
+			line = m.Line
+		}
+		if line > 0 {
+			physical.Region = &sarifRegion{StartLine: line}
+		}
+		location.PhysicalLocation = physical
+	}
+	return location
+}
@@ -26,6 +26,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
 	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
 	cmdutil "k8s.io/kubectl/pkg/cmd/util"
 	"k8s.io/kubectl/pkg/polymorphichelpers"
@@ -157,6 +158,29 @@ func InferPodInfoFromTypedResource(name, defaultNS string, factory cmdutil.Facto
 	return pod.Name, namespace, nil
 }
 
+// GatewayPods returns the running pods of a gateway from its name. Gateways deployed by Gateway API automated
+// deployment have their pods labeled with the name of the Gateway, while classic gateway installations have them
+// labeled istio=<name> and app=<name>, such as istio=ingressgateway or app=istio-ingressgateway.
+func GatewayPods(ctx context.Context, client kubernetes.Interface, name, namespace string) ([]corev1.Pod, error) {
+	for _, selector := range []string{
+		label.IoK8sNetworkingGatewayGatewayName.Name + "=" + name,
+		"istio=" + name,
+		"app=" + name,
+	} {
+		pods, err := client.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{
+			LabelSelector: selector,
+			FieldSelector: "status.phase=Running",
+		})
+		if err != nil {
+			return nil, err
+		}
+		if len(pods.Items) > 0 {
+			return pods.Items, nil
+		}
+	}
+	return nil, fmt.Errorf("no running pods found for gateway %q in the %q namespace", name, namespace)
+}
+
 // SelectorsForObject is a fork of upstream function to add additional Istio type support
 func SelectorsForObject(object runtime.Object) (namespace string, selector labels.Selector, err error) {
 	switch t := object.(type) {
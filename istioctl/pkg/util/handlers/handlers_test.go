@@ -15,6 +15,7 @@
 package handlers
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"sort"
@@ -26,6 +27,7 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	kubefake "k8s.io/client-go/kubernetes/fake"
 	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
 	"k8s.io/client-go/rest/fake"
 	cmdtesting "k8s.io/kubectl/pkg/cmd/testing"
@@ -181,6 +183,45 @@ func TestInferPodInfoFromTypedResource(t *testing.T) {
 	}
 }
 
+func TestGatewayPods(t *testing.T) {
+	gatewayPod := func(name string, labels map[string]string) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "istio-system", Labels: labels},
+			Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+		}
+	}
+	client := kubefake.NewClientset(
+		gatewayPod("istio-ingressgateway-8d9697654-qdzgh", map[string]string{"app": "istio-ingressgateway", "istio": "ingressgateway"}),
+		gatewayPod("external-istio-7f8c9b8b9b-k9s8d", map[string]string{"gateway.networking.k8s.io/gateway-name": "external"}),
+	)
+	tests := []struct {
+		name     string
+		gateway  string
+		wantPods []string
+		wantErr  bool
+	}{
+		{name: "managed gateway", gateway: "external", wantPods: []string{"external-istio-7f8c9b8b9b-k9s8d"}},
+		{name: "classic gateway by istio label", gateway: "ingressgateway", wantPods: []string{"istio-ingressgateway-8d9697654-qdzgh"}},
+		{name: "classic gateway by app label", gateway: "istio-ingressgateway", wantPods: []string{"istio-ingressgateway-8d9697654-qdzgh"}},
+		{name: "unknown gateway", gateway: "internal", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pods, err := GatewayPods(context.Background(), client, tt.gateway, "istio-system")
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			var names []string
+			for _, p := range pods {
+				names = append(names, p.Name)
+			}
+			assert.Equal(t, names, tt.wantPods)
+		})
+	}
+}
+
 func attachPod(ns string) *corev1.Pod {
 	return &corev1.Pod{
 		ObjectMeta: metav1.ObjectMeta{Name: "foo-abc", Namespace: ns, ResourceVersion: "10"},
@@ -0,0 +1,109 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhookorder
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"istio.io/istio/istioctl/pkg/cli"
+)
+
+func Cmd(ctx cli.Context) *cobra.Command {
+	var labelPairs string
+	cmd := &cobra.Command{
+		Use:   "webhook-order",
+		Short: "Audits the order of the mutating webhooks relative to the Istio sidecar injector",
+		Long: `Lists the mutating webhooks which intercept the creation of pods, in the order the API server calls them, with
+their reinvocation policy and whether their selectors match a sample pod of the namespace. The calls made when creating
+the sample pod are then simulated, and webhooks which mutate the pod after the last call of the sidecar injector are
+flagged: their changes to the containers, such as stripping resources or reordering containers, apply to istio-proxy
+without the injector accounting for them.`,
+		Example: `  # Audit the webhooks called for the pods of the default namespace
+  istioctl experimental webhook-order
+
+  # Audit the webhooks called for the pods labeled app=helloworld in namespace test
+  istioctl x webhook-order -n test -l app=helloworld`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			kubeClient, err := ctx.CLIClient()
+			if err != nil {
+				return err
+			}
+			namespace := ctx.NamespaceOrDefault(ctx.Namespace())
+			ns, err := kubeClient.Kube().CoreV1().Namespaces().Get(context.TODO(), namespace, metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+			ls, err := metav1.ParseToLabelSelector(labelPairs)
+			if err != nil {
+				return err
+			}
+			mwcs, err := kubeClient.Kube().AdmissionregistrationV1().MutatingWebhookConfigurations().List(context.TODO(), metav1.ListOptions{})
+			if err != nil {
+				return err
+			}
+			webhooks := Webhooks(mwcs.Items, ls.MatchLabels, ns.Labels)
+			invocations := Simulate(webhooks)
+			printReport(cmd.OutOrStdout(), webhooks, invocations, Audit(webhooks, invocations))
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(&labelPairs, "labels", "l", "", "Labels of the sample pod, split multiple labels by commas")
+	return cmd
+}
+
+func printReport(writer io.Writer, webhooks []*Webhook, invocations []Invocation, findings []Finding) {
+	w := tabwriter.NewWriter(writer, 0, 8, 1, ' ', 0)
+	_, _ = fmt.Fprintln(w, "CONFIGURATION\tWEBHOOK\tREINVOCATION\tFAILURE POLICY\tSAMPLE POD")
+	for _, wh := range webhooks {
+		match := "called"
+		if !wh.Matches {
+			match = "skipped: " + wh.Reason
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", wh.Configuration, wh.Name, wh.ReinvocationPolicy, wh.FailurePolicy, match)
+	}
+	_ = w.Flush()
+
+	_, _ = fmt.Fprintln(writer)
+	_, _ = fmt.Fprintln(writer, "Simulated mutation order of the sample pod:")
+	if len(invocations) == 0 {
+		_, _ = fmt.Fprintln(writer, "  no webhook is called")
+	}
+	for i, inv := range invocations {
+		note := ""
+		if inv.Reinvocation {
+			note += " (reinvocation)"
+		}
+		if inv.Webhook.Injector {
+			note += " [sidecar injection]"
+		}
+		_, _ = fmt.Fprintf(writer, "  %d. %s%s\n", i+1, inv.Webhook.Name, note)
+	}
+
+	if len(findings) == 0 {
+		_, _ = fmt.Fprintln(writer, "\nNo webhook mutates the pod after sidecar injection.")
+		return
+	}
+	_, _ = fmt.Fprintln(writer)
+	for _, f := range findings {
+		_, _ = fmt.Fprintf(writer, "%s: %s\n", f.Level, f.Message)
+	}
+}
@@ -0,0 +1,177 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhookorder
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	admitv1 "k8s.io/api/admissionregistration/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"istio.io/istio/pkg/ptr"
+	"istio.io/istio/pkg/slices"
+)
+
+// injectorSuffix is the suffix of the names of the webhooks of the Istio sidecar injector.
+const injectorSuffix = "sidecar-injector.istio.io"
+
+// Webhook is a mutating webhook which intercepts the creation of pods.
+type Webhook struct {
+	Configuration      string
+	Name               string
+	ReinvocationPolicy admitv1.ReinvocationPolicyType
+	FailurePolicy      admitv1.FailurePolicyType
+	// Injector is whether the webhook is the Istio sidecar injector.
+	Injector bool
+	// Matches is whether the webhook is called for the sample pod, otherwise Reason is why not.
+	Matches bool
+	Reason  string
+}
+
+// Invocation is a call of a webhook when creating the sample pod.
+type Invocation struct {
+	Webhook      *Webhook
+	Reinvocation bool
+}
+
+// Finding is a problem of the order of the webhooks.
+type Finding struct {
+	Level   string
+	Message string
+}
+
+// Webhooks returns the mutating webhooks which intercept the creation of pods, in the order the API server calls them:
+// by name of their configuration, then in the order of the configuration.
+func Webhooks(configs []admitv1.MutatingWebhookConfiguration, podLabels, nsLabels map[string]string) []*Webhook {
+	configs = slices.Clone(configs)
+	sort.Slice(configs, func(i, j int) bool { return configs[i].Name < configs[j].Name })
+	var webhooks []*Webhook
+	for _, mwc := range configs {
+		for _, wh := range mwc.Webhooks {
+			if !interceptsPodCreation(wh.Rules) {
+				continue
+			}
+			w := &Webhook{
+				Configuration:      mwc.Name,
+				Name:               wh.Name,
+				ReinvocationPolicy: ptr.OrDefault(wh.ReinvocationPolicy, admitv1.NeverReinvocationPolicy),
+				FailurePolicy:      ptr.OrDefault(wh.FailurePolicy, admitv1.Fail),
+				Injector:           strings.HasSuffix(wh.Name, injectorSuffix),
+			}
+			switch {
+			case !selects(wh.NamespaceSelector, nsLabels):
+				w.Reason = "namespace selector does not match"
+			case !selects(wh.ObjectSelector, podLabels):
+				w.Reason = "object selector does not match"
+			default:
+				w.Matches = true
+			}
+			webhooks = append(webhooks, w)
+		}
+	}
+	return webhooks
+}
+
+// Simulate returns the invocations of the webhooks when creating the sample pod. Webhooks with the IfNeeded
+// reinvocation policy are called again once all the webhooks were called, if any later webhook was called, as it may
+// have mutated the pod.
+func Simulate(webhooks []*Webhook) []Invocation {
+	var invocations []Invocation
+	for _, w := range webhooks {
+		if w.Matches {
+			invocations = append(invocations, Invocation{Webhook: w})
+		}
+	}
+	called := len(invocations)
+	for i := 0; i < called; i++ {
+		w := invocations[i].Webhook
+		if w.ReinvocationPolicy == admitv1.IfNeededReinvocationPolicy && i < called-1 {
+			invocations = append(invocations, Invocation{Webhook: w, Reinvocation: true})
+		}
+	}
+	return invocations
+}
+
+// Audit returns the problems of the invocations of the webhooks, in particular the webhooks called after the last call
+// of the sidecar injector, whose mutations of the pod it does not account for.
+func Audit(webhooks []*Webhook, invocations []Invocation) []Finding {
+	last := -1
+	for i, inv := range invocations {
+		if inv.Webhook.Injector {
+			last = i
+		}
+	}
+	if last < 0 {
+		injectors := slices.Filter(webhooks, func(w *Webhook) bool { return w.Injector })
+		if len(injectors) == 0 {
+			return []Finding{{Level: "Warning", Message: "no Istio sidecar injector webhook intercepts the creation of pods"}}
+		}
+		return []Finding{{Level: "Info", Message: "the sidecar injector is not called for the sample pod, which is not injected"}}
+	}
+
+	var findings []Finding
+	injector := invocations[last].Webhook
+	for _, inv := range invocations[last+1:] {
+		w := inv.Webhook
+		if w.Injector {
+			continue
+		}
+		msg := fmt.Sprintf("%s (%s) mutates the pod after sidecar injection: changes to the containers, such as "+
+			"stripping their resources or reordering them, apply to istio-proxy and may break it", w.Name, w.Configuration)
+		if injector.ReinvocationPolicy != admitv1.IfNeededReinvocationPolicy {
+			msg += "; setting the reinvocationPolicy of the sidecar injector to IfNeeded " +
+				"(sidecarInjectorWebhook.reinvocationPolicy) has it reapply injection after this webhook"
+		}
+		findings = append(findings, Finding{Level: "Warning", Message: msg})
+	}
+	return findings
+}
+
+// interceptsPodCreation returns whether the rules of a webhook include the creation of pods.
+func interceptsPodCreation(rules []admitv1.RuleWithOperations) bool {
+	for _, r := range rules {
+		if matchesAny(r.Operations, admitv1.Create, admitv1.OperationAll) &&
+			matchesAny(r.APIGroups, "", "*") &&
+			matchesAny(r.APIVersions, "v1", "*") &&
+			matchesAny(r.Resources, "pods", "*", "*/*") &&
+			(r.Scope == nil || *r.Scope == admitv1.AllScopes || *r.Scope == admitv1.NamespacedScope) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesAny[T comparable](values []T, want ...T) bool {
+	for _, v := range values {
+		if slices.Contains(want, v) {
+			return true
+		}
+	}
+	return false
+}
+
+func selects(ls *metav1.LabelSelector, objLabels map[string]string) bool {
+	if ls == nil {
+		return true
+	}
+	selector, err := metav1.LabelSelectorAsSelector(ls)
+	if err != nil {
+		return false
+	}
+	return selector.Matches(labels.Set(objLabels))
+}
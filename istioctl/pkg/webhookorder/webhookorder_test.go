@@ -0,0 +1,117 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhookorder
+
+import (
+	"strings"
+	"testing"
+
+	admitv1 "k8s.io/api/admissionregistration/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"istio.io/istio/pkg/ptr"
+	"istio.io/istio/pkg/test/util/assert"
+)
+
+func mutatingConfig(name string, webhooks ...admitv1.MutatingWebhook) admitv1.MutatingWebhookConfiguration {
+	return admitv1.MutatingWebhookConfiguration{ObjectMeta: metav1.ObjectMeta{Name: name}, Webhooks: webhooks}
+}
+
+func mutatingWebhook(name string, resource string, policy admitv1.ReinvocationPolicyType, nsLabels map[string]string) admitv1.MutatingWebhook {
+	wh := admitv1.MutatingWebhook{
+		Name: name,
+		Rules: []admitv1.RuleWithOperations{{
+			Operations: []admitv1.OperationType{admitv1.Create},
+			Rule:       admitv1.Rule{APIGroups: []string{""}, APIVersions: []string{"v1"}, Resources: []string{resource}},
+		}},
+		ReinvocationPolicy: ptr.Of(policy),
+	}
+	if nsLabels != nil {
+		wh.NamespaceSelector = &metav1.LabelSelector{MatchLabels: nsLabels}
+	}
+	return wh
+}
+
+func names(invocations []Invocation) []string {
+	var out []string
+	for _, inv := range invocations {
+		name := inv.Webhook.Name
+		if inv.Reinvocation {
+			name += " (reinvocation)"
+		}
+		out = append(out, name)
+	}
+	return out
+}
+
+func TestWebhookOrder(t *testing.T) {
+	injectionLabels := map[string]string{"istio-injection": "enabled"}
+	injector := mutatingConfig("istio-sidecar-injector",
+		mutatingWebhook("namespace.sidecar-injector.istio.io", "pods", admitv1.NeverReinvocationPolicy, injectionLabels))
+	configs := []admitv1.MutatingWebhookConfiguration{
+		mutatingConfig("vpa-webhook-config", mutatingWebhook("vpa.k8s.io", "pods", admitv1.NeverReinvocationPolicy, nil)),
+		injector,
+		mutatingConfig("cert-manager-webhook", mutatingWebhook("webhook.cert-manager.io", "certificates", admitv1.NeverReinvocationPolicy, nil)),
+		mutatingConfig("aws-pod-identity", mutatingWebhook("pod-identity-webhook.amazonaws.com", "pods", admitv1.IfNeededReinvocationPolicy, nil)),
+		mutatingConfig("gatekeeper", mutatingWebhook("mutation.gatekeeper.sh", "pods", admitv1.NeverReinvocationPolicy, map[string]string{"team": "a"})),
+	}
+
+	webhooks := Webhooks(configs, map[string]string{"app": "reviews"}, injectionLabels)
+	var listed []string
+	for _, w := range webhooks {
+		listed = append(listed, w.Configuration+"/"+w.Name)
+	}
+	// Webhooks are called by name of their configuration, and those which do not intercept pods are left out.
+	assert.Equal(t, listed, []string{
+		"aws-pod-identity/pod-identity-webhook.amazonaws.com",
+		"gatekeeper/mutation.gatekeeper.sh",
+		"istio-sidecar-injector/namespace.sidecar-injector.istio.io",
+		"vpa-webhook-config/vpa.k8s.io",
+	})
+	assert.Equal(t, webhooks[1].Reason, "namespace selector does not match")
+
+	invocations := Simulate(webhooks)
+	assert.Equal(t, names(invocations), []string{
+		"pod-identity-webhook.amazonaws.com",
+		"namespace.sidecar-injector.istio.io",
+		"vpa.k8s.io",
+		"pod-identity-webhook.amazonaws.com (reinvocation)",
+	})
+
+	findings := Audit(webhooks, invocations)
+	assert.Equal(t, len(findings), 2)
+	assert.Equal(t, strings.HasPrefix(findings[0].Message, "vpa.k8s.io (vpa-webhook-config) mutates the pod after sidecar injection"), true)
+	assert.Equal(t, strings.Contains(findings[0].Message, "reinvocationPolicy of the sidecar injector to IfNeeded"), true)
+
+	// Reinvoked, the injector accounts for the mutations of all the webhooks which are not reinvoked after it.
+	injector.Webhooks[0].ReinvocationPolicy = ptr.Of(admitv1.IfNeededReinvocationPolicy)
+	configs[1] = injector
+	webhooks = Webhooks(configs, map[string]string{"app": "reviews"}, injectionLabels)
+	invocations = Simulate(webhooks)
+	assert.Equal(t, names(invocations), []string{
+		"pod-identity-webhook.amazonaws.com",
+		"namespace.sidecar-injector.istio.io",
+		"vpa.k8s.io",
+		"pod-identity-webhook.amazonaws.com (reinvocation)",
+		"namespace.sidecar-injector.istio.io (reinvocation)",
+	})
+	assert.Equal(t, Audit(webhooks, invocations), nil)
+
+	// Pods of namespaces without injection are not injected.
+	webhooks = Webhooks(configs, nil, nil)
+	assert.Equal(t, Audit(webhooks, Simulate(webhooks)), []Finding{
+		{Level: "Info", Message: "the sidecar injector is not called for the sample pod, which is not injected"},
+	})
+}
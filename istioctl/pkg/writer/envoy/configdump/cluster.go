@@ -18,16 +18,22 @@ import (
 	"encoding/json"
 	"fmt"
 	"sort"
+	"strconv"
 	"strings"
 	"text/tabwriter"
 
 	cluster "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	"google.golang.org/protobuf/reflect/protoreflect"
 	"sigs.k8s.io/yaml"
 
+	networking "istio.io/api/networking/v1alpha3"
 	"istio.io/istio/istioctl/pkg/util/proto"
 	"istio.io/istio/pilot/pkg/model"
+	pilot_util "istio.io/istio/pilot/pkg/networking/util"
 	v3 "istio.io/istio/pilot/pkg/xds/v3"
 	"istio.io/istio/pkg/config/host"
+	"istio.io/istio/pkg/wellknown"
 )
 
 // ClusterFilter is used to pass filter information into cluster based config writer print functions
@@ -103,6 +109,134 @@ func (c *ConfigWriter) PrintClusterSummary(filter ClusterFilter) error {
 	return w.Flush()
 }
 
+// PrintClusterProvenance prints the notable settings (TLS, load balancing, outlier detection) of the relevant clusters
+// along with the Istio configuration that produced them, as recorded by istiod in the cluster metadata. The
+// DestinationRules are keyed by name.namespace, and settings are only attributed to those which set them.
+func (c *ConfigWriter) PrintClusterProvenance(filter ClusterFilter, destinationRules map[string]*networking.DestinationRule) error {
+	w, clusters, err := c.setupClusterConfigWriter()
+	if err != nil {
+		return err
+	}
+	_, _ = fmt.Fprintln(w, "SERVICE FQDN\tPORT\tSUBSET\tDIRECTION\tTLS\tLB POLICY\tOUTLIER DETECTION\tENVOY FILTERS")
+	for _, c := range clusters {
+		if !filter.Verify(c) {
+			continue
+		}
+		fqdn, port, subset, direction := c.Name, "-", "-", "-"
+		if len(strings.Split(c.Name, "|")) > 3 {
+			d, s, f, p := model.ParseSubsetKey(c.Name)
+			fqdn, port, direction = string(f), strconv.Itoa(p), string(d)
+			if s != "" {
+				subset = s
+			}
+		}
+		_, _ = fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%s\t%s\t%s\t%s\n", fqdn, port, subset, direction,
+			describeClusterTLS(c, destinationRules), describeClusterLB(c, destinationRules),
+			describeClusterOutlierDetection(c, destinationRules), describeEnvoyFilters(c.GetMetadata()))
+	}
+	return w.Flush()
+}
+
+// clusterSource describes where a setting on the cluster came from: the EnvoyFilters which patched the cluster, if
+// any, or else the DestinationRule recorded in the cluster metadata if its trafficPolicy sets the field. It is empty
+// if the setting cannot be attributed.
+func clusterSource(c *cluster.Cluster, destinationRules map[string]*networking.DestinationRule, field protoreflect.Name) string {
+	if efs := describeEnvoyFilters(c.GetMetadata()); efs != "-" {
+		return fmt.Sprintf(" (EnvoyFilter %s)", efs)
+	}
+	name := describeManagement(c.GetMetadata())
+	dr := destinationRules[name]
+	if dr == nil {
+		return ""
+	}
+	_, subset, _, port := model.ParseSubsetKey(c.Name)
+	if path := trafficPolicyPath(dr, subset, port, field); path != "" {
+		return fmt.Sprintf(" (%s %s)", name, path)
+	}
+	return ""
+}
+
+// trafficPolicyPath returns the path of the most specific trafficPolicy of the DestinationRule setting the field for
+// the subset and port, or an empty string if none sets it.
+func trafficPolicyPath(dr *networking.DestinationRule, subset string, port int, field protoreflect.Name) string {
+	type level struct {
+		path   string
+		policy *networking.TrafficPolicy
+	}
+	levels := []level{{path: "trafficPolicy", policy: dr.GetTrafficPolicy()}}
+	for _, s := range dr.GetSubsets() {
+		if s.GetName() == subset && subset != "" {
+			levels = append([]level{{path: fmt.Sprintf("subsets[%s].trafficPolicy", subset), policy: s.GetTrafficPolicy()}}, levels...)
+		}
+	}
+	for _, l := range levels {
+		for _, pls := range l.policy.GetPortLevelSettings() {
+			if int(pls.GetPort().GetNumber()) == port && hasField(pls, field) {
+				return fmt.Sprintf("%s.portLevelSettings[%d].%s", l.path, port, jsonName(pls, field))
+			}
+		}
+		if l.policy != nil && hasField(l.policy, field) {
+			return l.path + "." + jsonName(l.policy, field)
+		}
+	}
+	return ""
+}
+
+func hasField(m protoreflect.ProtoMessage, field protoreflect.Name) bool {
+	fd := m.ProtoReflect().Descriptor().Fields().ByName(field)
+	return fd != nil && m.ProtoReflect().Has(fd)
+}
+
+func jsonName(m protoreflect.ProtoMessage, field protoreflect.Name) string {
+	return m.ProtoReflect().Descriptor().Fields().ByName(field).JSONName()
+}
+
+func describeClusterTLS(c *cluster.Cluster, destinationRules map[string]*networking.DestinationRule) string {
+	if ts := c.GetTransportSocket(); ts != nil {
+		name := ts.GetName()
+		if name == wellknown.TransportSocketTLS {
+			name = "TLS"
+		}
+		return name + clusterSource(c, destinationRules, "tls")
+	}
+	for _, tsm := range c.GetTransportSocketMatches() {
+		if tsm.GetName() == "tlsMode-istio" {
+			return "ISTIO_MUTUAL (auto mTLS)"
+		}
+	}
+	return "-"
+}
+
+func describeClusterLB(c *cluster.Cluster, destinationRules map[string]*networking.DestinationRule) string {
+	lb := c.GetLbPolicy()
+	source := clusterSource(c, destinationRules, "load_balancer")
+	// LEAST_REQUEST is the default Istio load balancer, so it is only attributed to a configuration setting it explicitly.
+	if source == "" && lb == cluster.Cluster_LEAST_REQUEST {
+		return lb.String() + " (default)"
+	}
+	return lb.String() + source
+}
+
+func describeClusterOutlierDetection(c *cluster.Cluster, destinationRules map[string]*networking.DestinationRule) string {
+	if c.GetOutlierDetection() == nil {
+		return "-"
+	}
+	return "enabled" + clusterSource(c, destinationRules, "outlier_detection")
+}
+
+// describeEnvoyFilters returns the EnvoyFilters which patched a resource, as recorded by istiod.
+func describeEnvoyFilters(metadata *core.Metadata) string {
+	values := metadata.GetFilterMetadata()[pilot_util.IstioMetadataKey].GetFields()[pilot_util.EnvoyFiltersMetadataKey].GetListValue().GetValues()
+	if len(values) == 0 {
+		return "-"
+	}
+	names := make([]string, 0, len(values))
+	for _, v := range values {
+		names = append(names, renderConfig(v.GetStringValue()))
+	}
+	return strings.Join(names, ",")
+}
+
 // PrintClusterDump prints the relevant clusters in the config dump to the ConfigWriter stdout
 func (c *ConfigWriter) PrintClusterDump(filter ClusterFilter, outputFormat string) error {
 	_, clusters, err := c.setupClusterConfigWriter()
@@ -13,3 +13,156 @@
 // limitations under the License.
 
 package configdump
+
+import (
+	"testing"
+
+	cluster "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+
+	networking "istio.io/api/networking/v1alpha3"
+	pilot_util "istio.io/istio/pilot/pkg/networking/util"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/schema/gvk"
+	"istio.io/istio/pkg/test/util/assert"
+	"istio.io/istio/pkg/wellknown"
+)
+
+func TestDescribeClusterProvenance(t *testing.T) {
+	drMetadata := pilot_util.BuildConfigInfoMetadata(config.Meta{
+		GroupVersionKind: gvk.DestinationRule,
+		Name:             "reviews",
+		Namespace:        "default",
+	})
+	efMetadata := pilot_util.AddEnvoyFilterToMetadata(pilot_util.BuildConfigInfoMetadata(config.Meta{
+		GroupVersionKind: gvk.DestinationRule,
+		Name:             "reviews",
+		Namespace:        "default",
+	}), "istio-system", "tune-cluster")
+	destinationRules := map[string]*networking.DestinationRule{
+		"reviews.default": {
+			Host: "reviews",
+			TrafficPolicy: &networking.TrafficPolicy{
+				Tls:              &networking.ClientTLSSettings{Mode: networking.ClientTLSSettings_SIMPLE},
+				OutlierDetection: &networking.OutlierDetection{},
+				PortLevelSettings: []*networking.TrafficPolicy_PortTrafficPolicy{{
+					Port: &networking.PortSelector{Number: 9080},
+					LoadBalancer: &networking.LoadBalancerSettings{
+						LbPolicy: &networking.LoadBalancerSettings_ConsistentHash{},
+					},
+				}},
+			},
+			Subsets: []*networking.Subset{{
+				Name: "v2",
+				TrafficPolicy: &networking.TrafficPolicy{
+					OutlierDetection: &networking.OutlierDetection{},
+				},
+			}, {
+				Name: "v3",
+				TrafficPolicy: &networking.TrafficPolicy{
+					LoadBalancer: &networking.LoadBalancerSettings{
+						LbPolicy: &networking.LoadBalancerSettings_Simple{Simple: networking.LoadBalancerSettings_LEAST_REQUEST},
+					},
+				},
+			}},
+		},
+	}
+
+	tests := []struct {
+		desc         string
+		cluster      *cluster.Cluster
+		tls          string
+		lb           string
+		outlier      string
+		envoyFilters string
+	}{
+		{
+			desc: "defaults with auto mTLS",
+			cluster: &cluster.Cluster{
+				Name:                   "outbound|9080||reviews.default.svc.cluster.local",
+				LbPolicy:               cluster.Cluster_LEAST_REQUEST,
+				TransportSocketMatches: []*cluster.Cluster_TransportSocketMatch{{Name: "tlsMode-istio"}},
+			},
+			tls:          "ISTIO_MUTUAL (auto mTLS)",
+			lb:           "LEAST_REQUEST (default)",
+			outlier:      "-",
+			envoyFilters: "-",
+		},
+		{
+			desc: "destination rule settings",
+			cluster: &cluster.Cluster{
+				Name:             "outbound|9080||reviews.default.svc.cluster.local",
+				LbPolicy:         cluster.Cluster_RING_HASH,
+				TransportSocket:  &core.TransportSocket{Name: wellknown.TransportSocketTLS},
+				OutlierDetection: &cluster.OutlierDetection{},
+				Metadata:         drMetadata,
+			},
+			tls:          "TLS (reviews.default trafficPolicy.tls)",
+			lb:           "RING_HASH (reviews.default trafficPolicy.portLevelSettings[9080].loadBalancer)",
+			outlier:      "enabled (reviews.default trafficPolicy.outlierDetection)",
+			envoyFilters: "-",
+		},
+		{
+			desc: "subset settings",
+			cluster: &cluster.Cluster{
+				Name:             "outbound|8080|v2|reviews.default.svc.cluster.local",
+				LbPolicy:         cluster.Cluster_ROUND_ROBIN,
+				OutlierDetection: &cluster.OutlierDetection{},
+				Metadata:         drMetadata,
+			},
+			tls:          "-",
+			lb:           "ROUND_ROBIN",
+			outlier:      "enabled (reviews.default subsets[v2].trafficPolicy.outlierDetection)",
+			envoyFilters: "-",
+		},
+		{
+			desc: "default load balancer set by destination rule",
+			cluster: &cluster.Cluster{
+				Name:     "outbound|8080|v3|reviews.default.svc.cluster.local",
+				LbPolicy: cluster.Cluster_LEAST_REQUEST,
+				Metadata: drMetadata,
+			},
+			tls:          "-",
+			lb:           "LEAST_REQUEST (reviews.default subsets[v3].trafficPolicy.loadBalancer)",
+			outlier:      "-",
+			envoyFilters: "-",
+		},
+		{
+			desc: "destination rule unknown",
+			cluster: &cluster.Cluster{
+				Name:             "outbound|9080||ratings.default.svc.cluster.local",
+				LbPolicy:         cluster.Cluster_RANDOM,
+				OutlierDetection: &cluster.OutlierDetection{},
+				Metadata: pilot_util.BuildConfigInfoMetadata(config.Meta{
+					GroupVersionKind: gvk.DestinationRule,
+					Name:             "ratings",
+					Namespace:        "default",
+				}),
+			},
+			tls:          "-",
+			lb:           "RANDOM",
+			outlier:      "enabled",
+			envoyFilters: "-",
+		},
+		{
+			desc: "patched by envoy filter",
+			cluster: &cluster.Cluster{
+				Name:     "outbound|9080||reviews.default.svc.cluster.local",
+				LbPolicy: cluster.Cluster_ROUND_ROBIN,
+				Metadata: efMetadata,
+			},
+			tls:          "-",
+			lb:           "ROUND_ROBIN (EnvoyFilter tune-cluster.istio-system)",
+			outlier:      "-",
+			envoyFilters: "tune-cluster.istio-system",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			assert.Equal(t, describeClusterTLS(tt.cluster, destinationRules), tt.tls)
+			assert.Equal(t, describeClusterLB(tt.cluster, destinationRules), tt.lb)
+			assert.Equal(t, describeClusterOutlierDetection(tt.cluster, destinationRules), tt.outlier)
+			assert.Equal(t, describeEnvoyFilters(tt.cluster.GetMetadata()), tt.envoyFilters)
+		})
+	}
+}
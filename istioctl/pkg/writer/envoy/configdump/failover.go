@@ -0,0 +1,210 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configdump
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	endpoint "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
+
+	"istio.io/istio/pilot/pkg/networking/util"
+)
+
+// defaultOverprovisioningFactor is the Envoy default, expressed as a percentage.
+const defaultOverprovisioningFactor = 140
+
+// FailoverFilter selects endpoints that should be treated as unhealthy when simulating a failover.
+type FailoverFilter struct {
+	// Localities are locality prefixes, in region/zone/subzone form, to mark as unhealthy.
+	Localities []string
+	// Clusters are Istio cluster IDs whose endpoints should be marked as unhealthy.
+	Clusters []string
+}
+
+// Empty returns true if the filter does not fail any endpoints.
+func (f FailoverFilter) Empty() bool {
+	return len(f.Localities) == 0 && len(f.Clusters) == 0
+}
+
+func (f FailoverFilter) fails(locality string, ep *endpoint.LbEndpoint) bool {
+	for _, l := range f.Localities {
+		if locality == l || strings.HasPrefix(locality, strings.TrimSuffix(l, "/")+"/") {
+			return true
+		}
+	}
+	if len(f.Clusters) > 0 {
+		cluster := endpointClusterID(ep)
+		for _, c := range f.Clusters {
+			if cluster == c {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// endpointClusterID extracts the cluster ID from the compressed workload metadata added by istiod, in the form
+// workload-name;namespace;canonical-service-name;canonical-service-revision;cluster-id.
+func endpointClusterID(ep *endpoint.LbEndpoint) string {
+	workload := ep.GetMetadata().GetFilterMetadata()[util.IstioMetadataKey].GetFields()["workload"].GetStringValue()
+	parts := strings.Split(workload, ";")
+	if len(parts) < 5 {
+		return ""
+	}
+	return parts[4]
+}
+
+type localityHealth struct {
+	priority uint32
+	locality string
+	cluster  string
+	healthy  int
+	total    int
+}
+
+// PrintFailoverSummary prints the priority and locality ordering the proxy uses for the given cluster, and the
+// share of traffic Envoy assigns to each priority. If the filter fails any endpoints, the traffic share after
+// those endpoints become unhealthy is printed alongside, simulating a failover without affecting the proxy.
+func (c *ConfigWriter) PrintFailoverSummary(clusterName string, filter FailoverFilter) error {
+	dump, err := c.retrieveSortedEndpointsSlice(EndpointFilter{Cluster: clusterName})
+	if err != nil {
+		return err
+	}
+	if len(dump) == 0 {
+		return fmt.Errorf("no endpoints found for cluster %q", clusterName)
+	}
+	cla := dump[0]
+	current, currentLoad := priorityLoad(cla, FailoverFilter{})
+
+	w := new(tabwriter.Writer).Init(c.Stdout, 0, 8, 5, ' ', 0)
+	if filter.Empty() {
+		_, _ = fmt.Fprintln(w, "PRIORITY\tLOCALITY\tCLUSTER\tHEALTHY\tPRIORITY TRAFFIC")
+	} else {
+		_, _ = fmt.Fprintln(w, "PRIORITY\tLOCALITY\tCLUSTER\tHEALTHY\tPRIORITY TRAFFIC\tSIMULATED HEALTHY\tSIMULATED TRAFFIC")
+	}
+	simulated, simulatedLoad := priorityLoad(cla, filter)
+	for i, lh := range current {
+		locality := lh.locality
+		if locality == "" {
+			locality = "-"
+		}
+		cluster := lh.cluster
+		if cluster == "" {
+			cluster = "-"
+		}
+		if filter.Empty() {
+			_, _ = fmt.Fprintf(w, "%d\t%s\t%s\t%d/%d\t%d%%\n", lh.priority, locality, cluster, lh.healthy, lh.total,
+				currentLoad[lh.priority])
+			continue
+		}
+		_, _ = fmt.Fprintf(w, "%d\t%s\t%s\t%d/%d\t%d%%\t%d/%d\t%d%%\n", lh.priority, locality, cluster, lh.healthy, lh.total,
+			currentLoad[lh.priority], simulated[i].healthy, simulated[i].total, simulatedLoad[lh.priority])
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if !filter.Empty() && sum(simulatedLoad) == 0 {
+		_, _ = fmt.Fprintln(c.Stdout, "\nNo healthy endpoints remain in the simulation; Envoy will enter panic mode and route to all endpoints.")
+	}
+	return nil
+}
+
+// priorityLoad returns the health of each locality in the cluster and the percentage of traffic Envoy assigns
+// to each priority, following Envoy's priority level load calculation with the cluster's overprovisioning factor.
+func priorityLoad(cla *endpoint.ClusterLoadAssignment, filter FailoverFilter) ([]localityHealth, map[uint32]int) {
+	var localities []localityHealth
+	maxPriority := uint32(0)
+	healthy := map[uint32]int{}
+	total := map[uint32]int{}
+	for _, llb := range cla.GetEndpoints() {
+		locality := util.LocalityToString(llb.GetLocality())
+		clusters := map[string]struct{}{}
+		lh := localityHealth{priority: llb.GetPriority(), locality: locality}
+		for _, ep := range llb.GetLbEndpoints() {
+			lh.total++
+			if id := endpointClusterID(ep); id != "" {
+				clusters[id] = struct{}{}
+			}
+			status := ep.GetHealthStatus()
+			if (status == core.HealthStatus_HEALTHY || status == core.HealthStatus_UNKNOWN) && !filter.fails(locality, ep) {
+				lh.healthy++
+			}
+		}
+		ids := make([]string, 0, len(clusters))
+		for id := range clusters {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+		lh.cluster = strings.Join(ids, ",")
+		healthy[lh.priority] += lh.healthy
+		total[lh.priority] += lh.total
+		if lh.priority > maxPriority {
+			maxPriority = lh.priority
+		}
+		localities = append(localities, lh)
+	}
+	sort.SliceStable(localities, func(i, j int) bool {
+		if localities[i].priority != localities[j].priority {
+			return localities[i].priority < localities[j].priority
+		}
+		return localities[i].locality < localities[j].locality
+	})
+
+	overprovisioning := defaultOverprovisioningFactor
+	if f := cla.GetPolicy().GetOverprovisioningFactor(); f != nil {
+		overprovisioning = int(f.GetValue())
+	}
+	health := map[uint32]int{}
+	totalHealth := 0
+	for p := uint32(0); p <= maxPriority; p++ {
+		if total[p] == 0 {
+			continue
+		}
+		health[p] = min(100, overprovisioning*healthy[p]/total[p])
+		totalHealth += health[p]
+	}
+	load := map[uint32]int{}
+	if totalHealth == 0 {
+		return localities, load
+	}
+	// When the total health is below 100, Envoy normalizes the load so that all traffic is still assigned.
+	totalHealth = min(100, totalHealth)
+	remaining := 100
+	for p := uint32(0); p <= maxPriority; p++ {
+		l := min(remaining, health[p]*100/totalHealth)
+		load[p] = l
+		remaining -= l
+	}
+	// Any rounding leftovers go to the first priority with load.
+	for p := uint32(0); p <= maxPriority && remaining > 0; p++ {
+		if load[p] > 0 {
+			load[p] += remaining
+			remaining = 0
+		}
+	}
+	return localities, load
+}
+
+func sum(m map[uint32]int) int {
+	total := 0
+	for _, v := range m {
+		total += v
+	}
+	return total
+}
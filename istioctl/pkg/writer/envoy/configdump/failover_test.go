@@ -0,0 +1,128 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configdump
+
+import (
+	"testing"
+
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	endpoint "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"istio.io/istio/pilot/pkg/networking/util"
+	"istio.io/istio/pkg/test/util/assert"
+)
+
+func lbEndpoint(cluster string) *endpoint.LbEndpoint {
+	return &endpoint.LbEndpoint{
+		HealthStatus: core.HealthStatus_HEALTHY,
+		Metadata: &core.Metadata{
+			FilterMetadata: map[string]*structpb.Struct{
+				util.IstioMetadataKey: {
+					Fields: map[string]*structpb.Value{
+						"workload": structpb.NewStringValue("reviews-v1;default;reviews;v1;" + cluster),
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestPriorityLoad(t *testing.T) {
+	cla := &endpoint.ClusterLoadAssignment{
+		ClusterName: "outbound|9080||reviews.default.svc.cluster.local",
+		Endpoints: []*endpoint.LocalityLbEndpoints{
+			{
+				Locality:    &core.Locality{Region: "us-east1", Zone: "a"},
+				LbEndpoints: []*endpoint.LbEndpoint{lbEndpoint("cluster1"), lbEndpoint("cluster1")},
+			},
+			{
+				Locality:    &core.Locality{Region: "us-west1", Zone: "b"},
+				LbEndpoints: []*endpoint.LbEndpoint{lbEndpoint("cluster2"), lbEndpoint("cluster2")},
+				Priority:    1,
+			},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		filter  FailoverFilter
+		healthy []int
+		load    map[uint32]int
+	}{
+		{
+			name:    "all healthy",
+			healthy: []int{2, 2},
+			load:    map[uint32]int{0: 100, 1: 0},
+		},
+		{
+			name:    "region failed",
+			filter:  FailoverFilter{Localities: []string{"us-east1"}},
+			healthy: []int{0, 2},
+			load:    map[uint32]int{0: 0, 1: 100},
+		},
+		{
+			name:    "zone prefix must match a full segment",
+			filter:  FailoverFilter{Localities: []string{"us-east"}},
+			healthy: []int{2, 2},
+			load:    map[uint32]int{0: 100, 1: 0},
+		},
+		{
+			name:    "remote cluster failed",
+			filter:  FailoverFilter{Clusters: []string{"cluster2"}},
+			healthy: []int{2, 0},
+			load:    map[uint32]int{0: 100, 1: 0},
+		},
+		{
+			name:    "everything failed",
+			filter:  FailoverFilter{Clusters: []string{"cluster1", "cluster2"}},
+			healthy: []int{0, 0},
+			load:    map[uint32]int{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			localities, load := priorityLoad(cla, tt.filter)
+			healthy := make([]int, 0, len(localities))
+			for _, l := range localities {
+				healthy = append(healthy, l.healthy)
+			}
+			assert.Equal(t, healthy, tt.healthy)
+			assert.Equal(t, load, tt.load)
+		})
+	}
+}
+
+func TestPriorityLoadPartialHealth(t *testing.T) {
+	cla := &endpoint.ClusterLoadAssignment{
+		Endpoints: []*endpoint.LocalityLbEndpoints{
+			{
+				Locality: &core.Locality{Region: "us-east1", Zone: "a"},
+				LbEndpoints: []*endpoint.LbEndpoint{
+					lbEndpoint("cluster1"),
+					{HealthStatus: core.HealthStatus_UNHEALTHY},
+				},
+			},
+			{
+				Locality:    &core.Locality{Region: "us-west1"},
+				LbEndpoints: []*endpoint.LbEndpoint{lbEndpoint("cluster2")},
+				Priority:    1,
+			},
+		},
+	}
+	// With the default overprovisioning factor of 1.4, a priority with half of its endpoints healthy keeps 70% of the traffic.
+	_, load := priorityLoad(cla, FailoverFilter{})
+	assert.Equal(t, load, map[uint32]int{0: 70, 1: 30})
+}
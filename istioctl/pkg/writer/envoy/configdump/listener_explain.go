@@ -0,0 +1,317 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configdump
+
+import (
+	"fmt"
+	"net/netip"
+	"strconv"
+	"strings"
+
+	listener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+
+	"istio.io/istio/pkg/slices"
+)
+
+// Connection is a downstream connection for which the selection of a listener and filter chain is explained.
+type Connection struct {
+	DestinationIP   netip.Addr
+	DestinationPort uint32
+	SNI             string
+	ALPN            []string
+}
+
+// ParseConnection parses a connection in the form <dst-ip>:<port>[,<sni>[,<alpn>...]].
+func ParseConnection(s string) (Connection, error) {
+	parts := strings.Split(s, ",")
+	addrPort, err := netip.ParseAddrPort(strings.TrimSpace(parts[0]))
+	if err != nil {
+		return Connection{}, fmt.Errorf("invalid destination %q, expected <ip>:<port>: %v", parts[0], err)
+	}
+	c := Connection{DestinationIP: addrPort.Addr().Unmap(), DestinationPort: uint32(addrPort.Port())}
+	if len(parts) > 1 {
+		c.SNI = strings.TrimSpace(parts[1])
+	}
+	for _, alpn := range parts[min(len(parts), 2):] {
+		if alpn = strings.TrimSpace(alpn); alpn != "" {
+			c.ALPN = append(c.ALPN, alpn)
+		}
+	}
+	return c, nil
+}
+
+// TransportProtocol returns the transport protocol the TLS inspector detects for the connection: TLS connections
+// have an SNI or negotiate an ALPN other than the plaintext HTTP ones the HTTP inspector detects.
+func (c Connection) TransportProtocol() string {
+	if c.SNI != "" {
+		return "tls"
+	}
+	for _, alpn := range c.ALPN {
+		if !slices.Contains(plaintextHTTPALPNs, alpn) {
+			return "tls"
+		}
+	}
+	return "raw_buffer"
+}
+
+func (c Connection) String() string {
+	s := netip.AddrPortFrom(c.DestinationIP, uint16(c.DestinationPort)).String()
+	if c.SNI != "" {
+		s += fmt.Sprintf(", SNI %q", c.SNI)
+	}
+	if len(c.ALPN) > 0 {
+		s += ", ALPN " + strings.Join(c.ALPN, ",")
+	}
+	return s + ", transport " + c.TransportProtocol()
+}
+
+// FilterChainExplanation is how a listener and filter chain are selected for a connection.
+type FilterChainExplanation struct {
+	Listener *listener.Listener
+	// ListenerReason is why the listener accepts the connection.
+	ListenerReason string
+	// Steps are the stages of the filter chain match, in the order Envoy evaluates them.
+	Steps []string
+	// Chains are the filter chains selected. More than one chain is selected when the choice depends on the source
+	// of the connection, which is not evaluated. None are selected when the connection is closed.
+	Chains []*listener.FilterChain
+	// Default is whether the default filter chain of the listener is selected.
+	Default bool
+}
+
+// ExplainFilterChain selects the listener accepting the connection and evaluates the filter chain match of the
+// listener, as Envoy does. instanceIPs are the IPs of the workload, whose traffic is redirected to the virtual inbound
+// listener of sidecars.
+func ExplainFilterChain(listeners []*listener.Listener, instanceIPs []string, conn Connection) (FilterChainExplanation, error) {
+	e := FilterChainExplanation{}
+	e.Listener, e.ListenerReason = selectListener(listeners, instanceIPs, conn)
+	if e.Listener == nil {
+		return e, fmt.Errorf("no listener accepts connections to %s", netip.AddrPortFrom(conn.DestinationIP, uint16(conn.DestinationPort)))
+	}
+
+	candidates := e.Listener.FilterChains
+	stages := []struct {
+		name   string
+		narrow func([]*listener.FilterChain) ([]*listener.FilterChain, string)
+	}{
+		{fmt.Sprintf("destination port %d", conn.DestinationPort), func(fcs []*listener.FilterChain) ([]*listener.FilterChain, string) {
+			return narrowExact(fcs, func(m *listener.FilterChainMatch) (bool, bool) {
+				return m.GetDestinationPort() != nil, m.GetDestinationPort().GetValue() == conn.DestinationPort
+			})
+		}},
+		{"destination IP " + conn.DestinationIP.String(), func(fcs []*listener.FilterChain) ([]*listener.FilterChain, string) {
+			return narrowPrefix(fcs, conn.DestinationIP)
+		}},
+		{fmt.Sprintf("server name %q", conn.SNI), func(fcs []*listener.FilterChain) ([]*listener.FilterChain, string) {
+			return narrowServerName(fcs, conn.SNI)
+		}},
+		{"transport protocol " + conn.TransportProtocol(), func(fcs []*listener.FilterChain) ([]*listener.FilterChain, string) {
+			return narrowExact(fcs, func(m *listener.FilterChainMatch) (bool, bool) {
+				return m.GetTransportProtocol() != "", m.GetTransportProtocol() == conn.TransportProtocol()
+			})
+		}},
+		{"application protocols " + strings.Join(conn.ALPN, ","), func(fcs []*listener.FilterChain) ([]*listener.FilterChain, string) {
+			return narrowALPN(fcs, conn.ALPN)
+		}},
+	}
+	for i, stage := range stages {
+		var reason string
+		candidates, reason = stage.narrow(candidates)
+		e.Steps = append(e.Steps, fmt.Sprintf("%d. %s: %s, %d remaining", i+1, stage.name, reason, len(candidates)))
+		if len(candidates) == 0 {
+			break
+		}
+	}
+	if len(candidates) > 1 {
+		e.Steps = append(e.Steps, fmt.Sprintf("%d. source IP, type and port: not evaluated, the selected chain depends on the source", len(stages)+1))
+	}
+	e.Chains = candidates
+	if len(candidates) == 0 && e.Listener.DefaultFilterChain != nil {
+		e.Chains = []*listener.FilterChain{e.Listener.DefaultFilterChain}
+		e.Default = true
+	}
+	return e, nil
+}
+
+// selectListener returns the listener accepting the connection. Sidecars redirect inbound traffic to the virtual
+// inbound listener and outbound traffic to the virtual outbound listener, which hands connections off to the listener
+// of the original destination, by exact address and then by wildcard address.
+func selectListener(listeners []*listener.Listener, instanceIPs []string, conn Connection) (*listener.Listener, string) {
+	byName := func(name string) *listener.Listener {
+		if l := slices.FindFunc(listeners, func(l *listener.Listener) bool { return l.Name == name }); l != nil {
+			return *l
+		}
+		return nil
+	}
+	find := func(match func(addr netip.Addr) bool) *listener.Listener {
+		for _, l := range listeners {
+			if retrieveListenerPort(l) != conn.DestinationPort {
+				continue
+			}
+			for _, a := range append([]string{retrieveListenerAddress(l)}, retrieveListenerAdditionalAddresses(l)...) {
+				if addr, err := netip.ParseAddr(a); err == nil && match(addr.Unmap()) {
+					return l
+				}
+			}
+		}
+		return nil
+	}
+
+	if slices.Contains(instanceIPs, conn.DestinationIP.String()) {
+		if l := byName("virtualInbound"); l != nil {
+			return l, "inbound traffic to the workload is redirected to the virtual inbound listener"
+		}
+	}
+	if l := find(func(addr netip.Addr) bool { return addr == conn.DestinationIP }); l != nil {
+		return l, "the listener address matches the destination"
+	}
+	if l := find(func(addr netip.Addr) bool { return addr.IsUnspecified() }); l != nil {
+		return l, "the wildcard listener of the destination port"
+	}
+	if l := byName("virtualOutbound"); l != nil {
+		return l, "no listener matches the destination, the virtual outbound listener handles the connection"
+	}
+	return nil, ""
+}
+
+// narrowExact keeps the chains matching the connection on a criterion, or those not setting it if none match.
+// Criteria returns whether the chain sets the criterion and whether it matches.
+func narrowExact(fcs []*listener.FilterChain, criteria func(m *listener.FilterChainMatch) (set bool, matches bool)) ([]*listener.FilterChain, string) {
+	matched := slices.Filter(fcs, func(fc *listener.FilterChain) bool {
+		set, matches := criteria(fc.GetFilterChainMatch())
+		return set && matches
+	})
+	if len(matched) > 0 {
+		return matched, "chains matching it"
+	}
+	return slices.Filter(fcs, func(fc *listener.FilterChain) bool {
+		set, _ := criteria(fc.GetFilterChainMatch())
+		return !set
+	}), "no chain matches it, chains not setting it"
+}
+
+// narrowPrefix keeps the chains with the most specific prefix range containing the IP, or those without prefix
+// ranges if none contain it.
+func narrowPrefix(fcs []*listener.FilterChain, ip netip.Addr) ([]*listener.FilterChain, string) {
+	longest := func(fc *listener.FilterChain) int {
+		best := -1
+		for _, r := range fc.GetFilterChainMatch().GetPrefixRanges() {
+			addr, err := netip.ParseAddr(r.GetAddressPrefix())
+			if err != nil {
+				continue
+			}
+			bits := addr.BitLen()
+			if r.GetPrefixLen() != nil {
+				bits = int(r.GetPrefixLen().GetValue())
+			}
+			if p, err := addr.Unmap().Prefix(bits); err == nil && p.Contains(ip) && bits > best {
+				best = bits
+			}
+		}
+		return best
+	}
+	best := -1
+	for _, fc := range fcs {
+		best = max(best, longest(fc))
+	}
+	if best >= 0 {
+		return slices.Filter(fcs, func(fc *listener.FilterChain) bool { return longest(fc) == best }),
+			"chains with the most specific prefix range /" + strconv.Itoa(best)
+	}
+	return slices.Filter(fcs, func(fc *listener.FilterChain) bool {
+		return len(fc.GetFilterChainMatch().GetPrefixRanges()) == 0
+	}), "no prefix range contains it, chains without prefix ranges"
+}
+
+// narrowServerName keeps the chains matching the SNI exactly, then those with the longest matching wildcard, or
+// those without server names if none match.
+func narrowServerName(fcs []*listener.FilterChain, sni string) ([]*listener.FilterChain, string) {
+	if sni != "" {
+		names := []string{sni}
+		for rest := sni; strings.Contains(rest, "."); {
+			rest = rest[strings.Index(rest, ".")+1:]
+			names = append(names, "*."+rest)
+		}
+		for _, name := range names {
+			matched := slices.Filter(fcs, func(fc *listener.FilterChain) bool {
+				return slices.Contains(fc.GetFilterChainMatch().GetServerNames(), name)
+			})
+			if len(matched) > 0 {
+				return matched, fmt.Sprintf("chains with server name %q", name)
+			}
+		}
+	}
+	return slices.Filter(fcs, func(fc *listener.FilterChain) bool {
+		return len(fc.GetFilterChainMatch().GetServerNames()) == 0
+	}), "no server name matches, chains without server names"
+}
+
+// narrowALPN keeps the chains with the first application protocol of the connection they match, or those without
+// application protocols if none match.
+func narrowALPN(fcs []*listener.FilterChain, alpns []string) ([]*listener.FilterChain, string) {
+	for _, alpn := range alpns {
+		matched := slices.Filter(fcs, func(fc *listener.FilterChain) bool {
+			return slices.Contains(fc.GetFilterChainMatch().GetApplicationProtocols(), alpn)
+		})
+		if len(matched) > 0 {
+			return matched, fmt.Sprintf("chains with application protocol %q", alpn)
+		}
+	}
+	return slices.Filter(fcs, func(fc *listener.FilterChain) bool {
+		return len(fc.GetFilterChainMatch().GetApplicationProtocols()) == 0
+	}), "no application protocol matches, chains without application protocols"
+}
+
+// PrintFilterChainExplanation prints how the listener and filter chain are selected for the connection.
+func (c *ConfigWriter) PrintFilterChainExplanation(conn Connection) error {
+	listeners, err := c.retrieveSortedListenerSlice()
+	if err != nil {
+		return err
+	}
+	var instanceIPs []string
+	if bootstrap, err := c.configDump.GetBootstrapConfigDump(); err == nil {
+		ips := bootstrap.GetBootstrap().GetNode().GetMetadata().GetFields()["INSTANCE_IPS"].GetStringValue()
+		instanceIPs = strings.Split(ips, ",")
+	}
+	e, err := ExplainFilterChain(listeners, instanceIPs, conn)
+	if err != nil {
+		return err
+	}
+
+	addresses := append([]string{retrieveListenerAddress(e.Listener)}, retrieveListenerAdditionalAddresses(e.Listener)...)
+	fmt.Fprintf(c.Stdout, "Connection: %s\n", conn)
+	fmt.Fprintf(c.Stdout, "Listener:   %s (%s:%d), %s\n", e.Listener.Name, strings.Join(addresses, ","), retrieveListenerPort(e.Listener), e.ListenerReason)
+	fmt.Fprintf(c.Stdout, "Filter chain match over %d chains:\n", len(e.Listener.FilterChains))
+	for _, s := range e.Steps {
+		fmt.Fprintf(c.Stdout, "  %s\n", s)
+	}
+	switch {
+	case len(e.Chains) == 0:
+		fmt.Fprintln(c.Stdout, "No filter chain matches and the listener has no default filter chain: the connection is closed.")
+		return nil
+	case e.Default:
+		fmt.Fprintln(c.Stdout, "No filter chain matches, the default filter chain is selected:")
+	case len(e.Chains) > 1:
+		fmt.Fprintln(c.Stdout, "One of the filter chains is selected, depending on the source:")
+	default:
+		fmt.Fprintln(c.Stdout, "Selected filter chain:")
+	}
+	for _, fc := range e.Chains {
+		fmt.Fprintf(c.Stdout, "  Name:        %s\n", fc.Name)
+		fmt.Fprintf(c.Stdout, "  Match:       %s\n", getMatches(fc.FilterChainMatch))
+		fmt.Fprintf(c.Stdout, "  Destination: %s\n", getFilterType(fc.GetFilters()))
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configdump
+
+import (
+	"testing"
+
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	listener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	"istio.io/istio/pkg/test/util/assert"
+)
+
+func testListener(name, address string, port uint32, chains ...*listener.FilterChain) *listener.Listener {
+	return &listener.Listener{
+		Name: name,
+		Address: &core.Address{Address: &core.Address_SocketAddress{SocketAddress: &core.SocketAddress{
+			Address:       address,
+			PortSpecifier: &core.SocketAddress_PortValue{PortValue: port},
+		}}},
+		FilterChains: chains,
+	}
+}
+
+func TestParseConnection(t *testing.T) {
+	c, err := ParseConnection("10.96.0.10:443,foo.example.com,h2,http/1.1")
+	assert.NoError(t, err)
+	assert.Equal(t, c.String(), `10.96.0.10:443, SNI "foo.example.com", ALPN h2,http/1.1, transport tls`)
+
+	c, err = ParseConnection("10.96.0.10:80,,http/1.1")
+	assert.NoError(t, err)
+	assert.Equal(t, c.TransportProtocol(), "raw_buffer")
+
+	_, err = ParseConnection("10.96.0.10")
+	assert.Error(t, err)
+}
+
+func TestExplainFilterChain(t *testing.T) {
+	virtualInbound := testListener("virtualInbound", "0.0.0.0", 15006,
+		&listener.FilterChain{Name: "mtls", FilterChainMatch: &listener.FilterChainMatch{
+			DestinationPort:      wrapperspb.UInt32(8080),
+			TransportProtocol:    "tls",
+			ApplicationProtocols: []string{"istio-http/1.1", "istio-h2"},
+		}},
+		&listener.FilterChain{Name: "plaintext", FilterChainMatch: &listener.FilterChainMatch{
+			DestinationPort:   wrapperspb.UInt32(8080),
+			TransportProtocol: "raw_buffer",
+		}},
+		&listener.FilterChain{Name: "passthrough", FilterChainMatch: &listener.FilterChainMatch{
+			PrefixRanges: []*core.CidrRange{{AddressPrefix: "0.0.0.0", PrefixLen: wrapperspb.UInt32(0)}},
+		}},
+	)
+	outbound := testListener("0.0.0.0_443", "0.0.0.0", 443,
+		&listener.FilterChain{Name: "exact", FilterChainMatch: &listener.FilterChainMatch{ServerNames: []string{"foo.example.com"}}},
+		&listener.FilterChain{Name: "wildcard", FilterChainMatch: &listener.FilterChainMatch{ServerNames: []string{"*.example.com"}}},
+		&listener.FilterChain{Name: "other", FilterChainMatch: &listener.FilterChainMatch{ServerNames: []string{"other.org"}}},
+	)
+	outbound.DefaultFilterChain = &listener.FilterChain{Name: "default"}
+	virtualOutbound := testListener("virtualOutbound", "0.0.0.0", 15001, &listener.FilterChain{Name: "PassthroughFilterChain"})
+	listeners := []*listener.Listener{virtualInbound, outbound, virtualOutbound}
+	instanceIPs := []string{"10.244.0.5"}
+
+	cases := []struct {
+		conn     string
+		listener string
+		chains   []string
+		def      bool
+	}{
+		{conn: "10.244.0.5:8080,,istio-http/1.1", listener: "virtualInbound", chains: []string{"mtls"}},
+		{conn: "10.244.0.5:8080,,http/1.1", listener: "virtualInbound", chains: []string{"plaintext"}},
+		{conn: "10.244.0.5:9090", listener: "virtualInbound", chains: []string{"passthrough"}},
+		{conn: "10.96.0.10:443,foo.example.com", listener: "0.0.0.0_443", chains: []string{"exact"}},
+		{conn: "10.96.0.10:443,bar.foo.example.com", listener: "0.0.0.0_443", chains: []string{"wildcard"}},
+		{conn: "10.96.0.10:443,unknown.io", listener: "0.0.0.0_443", chains: []string{"default"}, def: true},
+		{conn: "10.96.0.10:5432", listener: "virtualOutbound", chains: []string{"PassthroughFilterChain"}},
+	}
+	for _, tt := range cases {
+		t.Run(tt.conn, func(t *testing.T) {
+			conn, err := ParseConnection(tt.conn)
+			assert.NoError(t, err)
+			e, err := ExplainFilterChain(listeners, instanceIPs, conn)
+			assert.NoError(t, err)
+			assert.Equal(t, e.Listener.Name, tt.listener)
+			var chains []string
+			for _, fc := range e.Chains {
+				chains = append(chains, fc.Name)
+			}
+			assert.Equal(t, chains, tt.chains)
+			assert.Equal(t, e.Default, tt.def)
+		})
+	}
+
+	conn, _ := ParseConnection("10.96.0.10:5432")
+	_, err := ExplainFilterChain([]*listener.Listener{outbound}, nil, conn)
+	assert.Error(t, err)
+}
@@ -0,0 +1,185 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pilot
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	xdsstatus "github.com/envoyproxy/go-control-plane/envoy/service/status/v3"
+
+	"istio.io/istio/istioctl/pkg/multixds"
+	"istio.io/istio/pilot/pkg/model"
+	xdsresource "istio.io/istio/pilot/pkg/xds/v3"
+)
+
+// Snapshot is the sync status of the proxies at a point in time, saved to be compared with a later one.
+type Snapshot struct {
+	Time    time.Time       `json:"time"`
+	Proxies []ProxySnapshot `json:"proxies"`
+}
+
+// ProxySnapshot is the sync status of a proxy, by xDS type, and the Istiod instance it is connected to.
+type ProxySnapshot struct {
+	Proxy   string `json:"proxy"`
+	Cluster string `json:"cluster"`
+	Istiod  string `json:"istiod"`
+	Version string `json:"version"`
+	CDS     string `json:"cds"`
+	LDS     string `json:"lds"`
+	EDS     string `json:"eds"`
+	RDS     string `json:"rds"`
+	ECDS    string `json:"ecds"`
+}
+
+func (p ProxySnapshot) statuses() [][2]string {
+	return [][2]string{{"CDS", p.CDS}, {"LDS", p.LDS}, {"EDS", p.EDS}, {"RDS", p.RDS}, {"ECDS", p.ECDS}}
+}
+
+// Snapshot takes a slice of Istiod syncz responses and returns the sync status of their proxies at the given time.
+func (s *XdsStatusWriter) Snapshot(drs map[string]*discovery.DiscoveryResponse, now time.Time) (*Snapshot, error) {
+	snapshot := &Snapshot{Time: now}
+	for _, dr := range drs {
+		for _, resource := range dr.Resources {
+			clientConfig := xdsstatus.ClientConfig{}
+			if err := resource.UnmarshalTo(&clientConfig); err != nil {
+				return nil, fmt.Errorf("could not unmarshal ClientConfig: %w", err)
+			}
+			meta, err := model.ParseMetadata(clientConfig.GetNode().GetMetadata())
+			if err != nil {
+				return nil, fmt.Errorf("could not parse node metadata: %w", err)
+			}
+			if s.Namespace != "" && meta.Namespace != s.Namespace {
+				continue
+			}
+			proxy := ProxySnapshot{
+				Proxy:   clientConfig.GetNode().GetId(),
+				Cluster: meta.ClusterID.String(),
+				Istiod:  multixds.CpInfo(dr).ID,
+				Version: meta.IstioVersion,
+				CDS:     ignoredStatus,
+				LDS:     ignoredStatus,
+				EDS:     ignoredStatus,
+				RDS:     ignoredStatus,
+				ECDS:    ignoredStatus,
+			}
+			for _, config := range handleAndGetXdsConfigs(&clientConfig) {
+				switch config.GetTypeUrl() {
+				case xdsresource.ClusterType:
+					proxy.CDS = configStatus(config)
+				case xdsresource.ListenerType:
+					proxy.LDS = configStatus(config)
+				case xdsresource.EndpointType:
+					proxy.EDS = configStatus(config)
+				case xdsresource.RouteType:
+					proxy.RDS = configStatus(config)
+				case xdsresource.ExtensionConfigurationType:
+					proxy.ECDS = configStatus(config)
+				}
+			}
+			snapshot.Proxies = append(snapshot.Proxies, proxy)
+		}
+	}
+	sort.Slice(snapshot.Proxies, func(i, j int) bool {
+		return snapshot.Proxies[i].Proxy < snapshot.Proxies[j].Proxy
+	})
+	return snapshot, nil
+}
+
+// snapshotChange is a difference between the sync status of a proxy in two snapshots.
+type snapshotChange struct {
+	proxy  string
+	change string
+	before string
+	after  string
+}
+
+// compareSnapshots returns the proxies which regressed to STALE, or to ERROR, between the snapshots, those which changed of
+// Istiod instance, and those which were added or removed.
+func compareSnapshots(before, after *Snapshot) []snapshotChange {
+	previous := map[string]ProxySnapshot{}
+	for _, p := range before.Proxies {
+		previous[p.Proxy] = p
+	}
+	var changes []snapshotChange
+	for _, p := range after.Proxies {
+		b, ok := previous[p.Proxy]
+		if !ok {
+			changes = append(changes, snapshotChange{proxy: p.Proxy, change: "ADDED", before: "-", after: p.Istiod})
+			continue
+		}
+		delete(previous, p.Proxy)
+		var was, is []string
+		statuses := b.statuses()
+		for i, status := range p.statuses() {
+			if regressed(statuses[i][1], status[1]) {
+				was = append(was, statuses[i][0]+" "+statuses[i][1])
+				is = append(is, status[0]+" "+status[1])
+			}
+		}
+		if len(is) > 0 {
+			changes = append(changes, snapshotChange{
+				proxy: p.Proxy, change: "REGRESSED", before: strings.Join(was, ", "), after: strings.Join(is, ", "),
+			})
+		}
+		if b.Istiod != p.Istiod {
+			changes = append(changes, snapshotChange{proxy: p.Proxy, change: "ISTIOD CHANGED", before: b.Istiod, after: p.Istiod})
+		}
+	}
+	for _, p := range previous {
+		changes = append(changes, snapshotChange{proxy: p.Proxy, change: "REMOVED", before: p.Istiod, after: "-"})
+	}
+	sort.SliceStable(changes, func(i, j int) bool {
+		return changes[i].proxy < changes[j].proxy
+	})
+	return changes
+}
+
+// regressed returns whether a config of the given status is now STALE, or failed to apply.
+func regressed(before, after string) bool {
+	unhealthy := func(status string) bool {
+		return status == xdsstatus.ConfigStatus_STALE.String() || status == xdsstatus.ConfigStatus_ERROR.String()
+	}
+	return unhealthy(after) && !unhealthy(before)
+}
+
+// PrintSnapshotDiff outputs the changes between the snapshots using a tabwriter, followed by a summary.
+func (s *XdsStatusWriter) PrintSnapshotDiff(before, after *Snapshot) error {
+	changes := compareSnapshots(before, after)
+	counts := map[string]int{}
+	for _, c := range changes {
+		counts[c.change]++
+	}
+	if len(changes) > 0 {
+		w := new(tabwriter.Writer).Init(s.Writer, 0, 8, 5, ' ', 0)
+		_, _ = fmt.Fprintln(w, "NAME\tCHANGE\tBEFORE\tAFTER")
+		for _, c := range changes {
+			_, _ = fmt.Fprintf(w, "%v\t%v\t%v\t%v\n", c.proxy, c.change, c.before, c.after)
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+		_, _ = fmt.Fprintln(s.Writer)
+	}
+	_, err := fmt.Fprintf(s.Writer,
+		"Compared %d proxies at %s with %d proxies at %s: %d regressed, %d changed of Istiod, %d added, %d removed.\n",
+		len(before.Proxies), before.Time.Format(time.RFC3339), len(after.Proxies), after.Time.Format(time.RFC3339),
+		counts["REGRESSED"], counts["ISTIOD CHANGED"], counts["ADDED"], counts["REMOVED"])
+	return err
+}
@@ -51,7 +51,10 @@ type xdsWriterStatus struct {
 	extensionconfigStatus string
 }
 
-const ignoredStatus = "IGNORED"
+const (
+	ignoredStatus = "IGNORED"
+	notSentStatus = "NOT SENT"
+)
 
 // PrintAll takes a slice of Istiod syncz responses and outputs them using a tabwriter
 func (s *XdsStatusWriter) PrintAll(statuses map[string]*discovery.DiscoveryResponse) error {
@@ -134,17 +137,22 @@ func xdsStatusPrintln(w io.Writer, status *xdsWriterStatus) error {
 }
 
 func formatStatus(s *xdsstatus.ClientConfig_GenericXdsConfig) string {
+	status := configStatus(s)
+	if status != ignoredStatus && status != notSentStatus && s.LastUpdated != nil {
+		status += " (" + duration.HumanDuration(time.Since(s.LastUpdated.AsTime())) + ")"
+	}
+	return status
+}
+
+// configStatus returns the sync status of the config, without the time since its last update.
+func configStatus(s *xdsstatus.ClientConfig_GenericXdsConfig) string {
 	switch s.GetConfigStatus() {
 	case xdsstatus.ConfigStatus_UNKNOWN:
 		return ignoredStatus
 	case xdsstatus.ConfigStatus_NOT_SENT:
-		return "NOT SENT"
+		return notSentStatus
 	default:
-		status := s.GetConfigStatus().String()
-		if s.LastUpdated != nil {
-			status += " (" + duration.HumanDuration(time.Since(s.LastUpdated.AsTime())) + ")"
-		}
-		return status
+		return s.GetConfigStatus().String()
 	}
 }
 
@@ -19,6 +19,7 @@ import (
 	"encoding/json"
 	"os"
 	"testing"
+	"time"
 
 	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
 	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
@@ -148,6 +149,57 @@ func TestXdsStatusWriter_PrintAll(t *testing.T) {
 	}
 }
 
+func TestXdsStatusWriter_PrintSnapshotDiff(t *testing.T) {
+	proxy := func(id string, cds, eds status.ConfigStatus) clientConfigInput {
+		return clientConfigInput{
+			proxyID:        id,
+			clusterID:      "cluster1",
+			version:        "1.20",
+			cdsSyncStatus:  cds,
+			ldsSyncStatus:  status.ConfigStatus_SYNCED,
+			rdsSyncStatus:  status.ConfigStatus_SYNCED,
+			edsSyncStatus:  eds,
+			ecdsSyncStatus: status.ConfigStatus_NOT_SENT,
+		}
+	}
+	sw := XdsStatusWriter{}
+	before, err := sw.Snapshot(map[string]*discovery.DiscoveryResponse{
+		"istiod1": xdsResponseInput("istiod1", []clientConfigInput{
+			proxy("proxy1", status.ConfigStatus_SYNCED, status.ConfigStatus_SYNCED),
+			proxy("proxy2", status.ConfigStatus_SYNCED, status.ConfigStatus_SYNCED),
+			proxy("proxy3", status.ConfigStatus_STALE, status.ConfigStatus_SYNCED),
+		}),
+		"istiod2": xdsResponseInput("istiod2", []clientConfigInput{
+			proxy("proxy4", status.ConfigStatus_SYNCED, status.ConfigStatus_SYNCED),
+		}),
+	}, time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC))
+	assert.NoError(t, err)
+	after, err := sw.Snapshot(map[string]*discovery.DiscoveryResponse{
+		"istiod1": xdsResponseInput("istiod1", []clientConfigInput{
+			proxy("proxy1", status.ConfigStatus_STALE, status.ConfigStatus_ERROR),
+			proxy("proxy3", status.ConfigStatus_STALE, status.ConfigStatus_SYNCED),
+		}),
+		"istiod3": xdsResponseInput("istiod3", []clientConfigInput{
+			proxy("proxy4", status.ConfigStatus_SYNCED, status.ConfigStatus_STALE),
+			proxy("proxy5", status.ConfigStatus_SYNCED, status.ConfigStatus_SYNCED),
+		}),
+	}, time.Date(2024, 6, 1, 14, 0, 0, 0, time.UTC))
+	assert.NoError(t, err)
+
+	got := &bytes.Buffer{}
+	sw.Writer = got
+	assert.NoError(t, sw.PrintSnapshotDiff(before, after))
+	assert.Equal(t, got.String(), `NAME       CHANGE             BEFORE                     AFTER
+proxy1     REGRESSED          CDS SYNCED, EDS SYNCED     CDS STALE, EDS ERROR
+proxy2     REMOVED            istiod1                    -
+proxy4     REGRESSED          EDS SYNCED                 EDS STALE
+proxy4     ISTIOD CHANGED     istiod2                    istiod3
+proxy5     ADDED              -                          istiod3
+
+Compared 4 proxies at 2024-06-01T12:00:00Z with 4 proxies at 2024-06-01T14:00:00Z: 2 regressed, 1 changed of Istiod, 1 added, 1 removed.
+`)
+}
+
 const clientConfigType = "type.googleapis.com/envoy.service.status.v3.ClientConfig"
 
 type clientConfigInput struct {
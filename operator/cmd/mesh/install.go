@@ -26,10 +26,13 @@ import (
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
 
+	"istio.io/api/label"
 	"istio.io/istio/istioctl/pkg/cli"
 	"istio.io/istio/istioctl/pkg/install/k8sversion"
+	revtag "istio.io/istio/istioctl/pkg/tag"
 	"istio.io/istio/istioctl/pkg/util"
 	"istio.io/istio/operator/pkg/install"
+	"istio.io/istio/operator/pkg/manifest"
 	"istio.io/istio/operator/pkg/render"
 	"istio.io/istio/operator/pkg/util/clog"
 	"istio.io/istio/operator/pkg/util/progress"
@@ -37,8 +40,10 @@ import (
 	operatorVer "istio.io/istio/operator/version"
 	"istio.io/istio/pkg/art"
 	"istio.io/istio/pkg/config/labels"
+	"istio.io/istio/pkg/config/schema/gvk"
 	"istio.io/istio/pkg/kube"
 	"istio.io/istio/pkg/ptr"
+	"istio.io/istio/pkg/util/sets"
 )
 
 type InstallArgs struct {
@@ -61,6 +66,10 @@ type InstallArgs struct {
 	ManifestsPath string
 	// Revision is the Istio control plane revision the command targets.
 	Revision string
+	// OverrideFreeze allows changes to frozen revision tags.
+	OverrideFreeze bool
+	// ExplainComponents reports the components enabled and disabled, and what decided each of them.
+	ExplainComponents bool
 }
 
 func (a *InstallArgs) String() string {
@@ -73,6 +82,7 @@ func (a *InstallArgs) String() string {
 	b.WriteString("Set:              " + fmt.Sprint(a.Set) + "\n")
 	b.WriteString("ManifestsPath:    " + a.ManifestsPath + "\n")
 	b.WriteString("Revision:         " + a.Revision + "\n")
+	b.WriteString("OverrideFreeze:   " + fmt.Sprint(a.OverrideFreeze) + "\n")
 	return b.String()
 }
 
@@ -87,6 +97,8 @@ func addInstallFlags(cmd *cobra.Command, args *InstallArgs) {
 	cmd.PersistentFlags().StringVarP(&args.ManifestsPath, "charts", "", "", ChartsDeprecatedStr)
 	cmd.PersistentFlags().StringVarP(&args.ManifestsPath, "manifests", "d", "", ManifestsFlagHelpStr)
 	cmd.PersistentFlags().StringVarP(&args.Revision, "revision", "r", "", revisionFlagHelpStr)
+	cmd.PersistentFlags().BoolVar(&args.OverrideFreeze, "override-freeze", false, revtag.OverrideFreezeHelpStr)
+	cmd.PersistentFlags().BoolVar(&args.ExplainComponents, "explain-components", false, explainComponentsFlagHelpStr)
 }
 
 // InstallCmdWithArgs generates an Istio install manifest and applies it to a cluster
@@ -158,10 +170,13 @@ func Install(kubeClient kube.CLIClient, rootArgs *RootArgs, iArgs *InstallArgs,
 
 	setFlags := applyFlagAliases(iArgs.Set, iArgs.ManifestsPath, iArgs.Revision)
 
-	manifests, vals, err := render.GenerateManifest(iArgs.InFilenames, setFlags, iArgs.Force, kubeClient, l)
+	manifests, vals, inclusions, err := render.GenerateManifestWithInclusions(iArgs.InFilenames, setFlags, iArgs.Force, kubeClient, l)
 	if err != nil {
 		return fmt.Errorf("generate config: %v", err)
 	}
+	if iArgs.ExplainComponents {
+		p.Printf("%s\n", formatComponentInclusions(inclusions))
+	}
 
 	namespace := vals.GetPathString("metadata.namespace")
 	revision := vals.GetPathString("spec.values.revision")
@@ -170,6 +185,22 @@ func Install(kubeClient kube.CLIClient, rootArgs *RootArgs, iArgs *InstallArgs,
 	// Print information about version changing
 	detectIstioVersionDiff(p, tag, namespace, kubeClient, revision)
 
+	// The revision tags rendered are changed by the installation, which frozen tags only allow with an override. The
+	// overrides are only recorded once installed.
+	var overrides []*revtag.FreezeOverride
+	if !rootArgs.DryRun {
+		action := fmt.Sprintf("install revision %q", ptr.NonEmptyOrDefault(revision, util.DefaultRevisionName))
+		for _, tagName := range renderedRevisionTags(manifests) {
+			override, err := revtag.CheckFreeze(context.Background(), kubeClient.Kube(), tagName, action, iArgs.OverrideFreeze, stdOut)
+			if err != nil {
+				return err
+			}
+			if override != nil {
+				overrides = append(overrides, override)
+			}
+		}
+	}
+
 	// Install is mutating state in the cluster; give users a confirmation to ensure they want this.
 	if !rootArgs.DryRun && !iArgs.SkipConfirmation {
 		prompt := fmt.Sprintf("This will install the Istio %s profile %q into the cluster. Proceed? (y/N)", tag, profile)
@@ -192,6 +223,11 @@ func Install(kubeClient kube.CLIClient, rootArgs *RootArgs, iArgs *InstallArgs,
 	if err := i.InstallManifests(manifests); err != nil {
 		return fmt.Errorf("failed to install manifests: %v", err)
 	}
+	for _, override := range overrides {
+		if err := revtag.RecordFreezeOverride(context.Background(), kubeClient.Kube(), namespace, override); err != nil {
+			return err
+		}
+	}
 
 	// Post-install message
 	if profile == "ambient" {
@@ -200,6 +236,22 @@ func Install(kubeClient kube.CLIClient, rootArgs *RootArgs, iArgs *InstallArgs,
 	return nil
 }
 
+// renderedRevisionTags returns the revision tags of the webhooks of the manifests.
+func renderedRevisionTags(manifests []manifest.ManifestSet) []string {
+	tags := sets.New[string]()
+	for _, set := range manifests {
+		for _, m := range set.Manifests {
+			if m.GetKind() != gvk.MutatingWebhookConfiguration.Kind {
+				continue
+			}
+			if tagName, ok := m.GetLabels()[label.IoIstioTag.Name]; ok {
+				tags.Insert(tagName)
+			}
+		}
+	}
+	return sets.SortedList(tags)
+}
+
 // detectIstioVersionDiff will show warning if istioctl version and control plane version are different
 // nolint: interfacer
 func detectIstioVersionDiff(p Printer, tag string, ns string, kubeClient kube.CLIClient, revision string) {
@@ -49,6 +49,8 @@ type ManifestGenerateArgs struct {
 	Revision string
 	// Filter is the list of components to render
 	Filter []string
+	// ExplainComponents reports the components enabled and disabled, and what decided each of them.
+	ExplainComponents bool
 }
 
 var kubeClientFunc func() (kube.CLIClient, error)
@@ -70,6 +72,7 @@ func addManifestGenerateFlags(cmd *cobra.Command, args *ManifestGenerateArgs) {
 	cmd.PersistentFlags().StringVarP(&args.ManifestsPath, "charts", "", "", ChartsDeprecatedStr)
 	cmd.PersistentFlags().StringVarP(&args.ManifestsPath, "manifests", "d", "", ManifestsFlagHelpStr)
 	cmd.PersistentFlags().StringVarP(&args.Revision, "revision", "r", "", revisionFlagHelpStr)
+	cmd.PersistentFlags().BoolVar(&args.ExplainComponents, "explain-components", false, explainComponentsFlagHelpStr)
 	cmd.PersistentFlags().StringSliceVar(&args.Filter, "filter", nil, "")
 	_ = cmd.PersistentFlags().MarkHidden("filter")
 
@@ -126,10 +129,14 @@ const (
 
 func ManifestGenerate(kubeClient kube.CLIClient, mgArgs *ManifestGenerateArgs, l clog.Logger) error {
 	setFlags := applyFlagAliases(mgArgs.Set, mgArgs.ManifestsPath, mgArgs.Revision)
-	manifests, _, err := render.GenerateManifest(mgArgs.InFilenames, setFlags, mgArgs.Force, kubeClient, nil)
+	manifests, _, inclusions, err := render.GenerateManifestWithInclusions(mgArgs.InFilenames, setFlags, mgArgs.Force, kubeClient, nil)
 	if err != nil {
 		return err
 	}
+	if mgArgs.ExplainComponents {
+		// The manifests are written to stdout, so the components rendered are reported on stderr.
+		l.PrintErr(formatComponentInclusions(inclusions))
+	}
 	for _, manifest := range sortManifestSet(manifests) {
 		l.Print(manifest + YAMLSeparator)
 	}
@@ -33,12 +33,15 @@ import (
 	. "github.com/onsi/gomega"
 	v1 "k8s.io/api/admissionregistration/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	klabels "k8s.io/apimachinery/pkg/labels"
 	"sigs.k8s.io/yaml"
 
+	"istio.io/istio/operator/pkg/component"
 	"istio.io/istio/operator/pkg/manifest"
 	"istio.io/istio/operator/pkg/render"
 	uninstall2 "istio.io/istio/operator/pkg/uninstall"
+	"istio.io/istio/operator/pkg/util/clog"
 	"istio.io/istio/operator/pkg/util/testhelpers"
 	tutil "istio.io/istio/pilot/test/util"
 	"istio.io/istio/pkg/config/schema/gvk"
@@ -292,6 +295,166 @@ func TestManifestGenerateGateways(t *testing.T) {
 	}
 }
 
+func TestManifestGenerateGatewayValues(t *testing.T) {
+	g := NewWithT(t)
+
+	objss := runManifestCommands(t, "gateway_values", "", liveCharts, nil)
+
+	for _, objs := range objss {
+		dobj := mustGetDeployment(g, objs, "istio-ingressgateway")
+		d := dobj.Unstructured.Object
+		g.Expect(d).Should(HavePathValueContain(PathValue{"spec.template.metadata.annotations", toMap("aaa:aaa-val")}))
+		g.Expect(getContainer(dobj, "sidecar-logger")).Should(HavePathValueEqual(PathValue{"image", "busybox"}))
+
+		// Values only apply to the gateway they are set on.
+		d = mustGetDeployment(g, objs, "user-ingressgateway").Unstructured.Object
+		annotations, _, _ := unstructured.NestedStringMap(d, "spec", "template", "metadata", "annotations")
+		g.Expect(annotations).ShouldNot(HaveKey("aaa"))
+	}
+
+	inPath := filepath.Join(testDataDir, "input", "gateway_values.yaml")
+	for _, tt := range []struct {
+		flags string
+		want  string
+	}{
+		{
+			flags: "--set components.ingressGateways.[0].values.extraVolumes.foo=bar",
+			want:  "values.extraVolumes is not a value of the gateways/istio-ingress chart",
+		},
+		{
+			flags: "--set components.ingressGateways.[0].values.podAnnotations=foo",
+			want:  "values.podAnnotations must be a map, got a scalar",
+		},
+		{
+			flags: "--set components.ingressGateways.[0].values.loadBalancerSourceRanges=foo",
+			want:  "values.loadBalancerSourceRanges must be a list, got a scalar",
+		},
+		{
+			flags: "--set components.ingressGateways.[0].values.ports.[0].nodeport=30000",
+			want:  "values.ports[0].nodeport is not a field of PortsConfig",
+		},
+		{
+			flags: "--set components.ingressGateways.[0].values.zvpn.suffix.foo=bar",
+			want:  "values.zvpn.suffix must be a scalar, got a map",
+		},
+		{
+			flags: "--set components.ingressGateways.[0].values.name=foo",
+			want:  "values.name cannot be set, use the name field of the component instead",
+		},
+		{
+			flags: "--set components.ingressGateways.[0].values.enabled=false",
+			want:  "values.enabled cannot be set, use the enabled field of the component instead",
+		},
+	} {
+		_, err := runManifestGenerate([]string{inPath}, tt.flags, liveCharts, nil)
+		g.Expect(err).Should(MatchError(ContainSubstring(tt.want)))
+	}
+}
+
+func TestManifestGenerateNetworkPolicies(t *testing.T) {
+	g := NewWithT(t)
+
+	objss := runManifestCommands(t, "network_policies", "", liveCharts, nil)
+
+	for _, objs := range objss {
+		policies := objs.kind("NetworkPolicy")
+		g.Expect(policies.size()).Should(Equal(3))
+		for _, name := range []string{"istiod", "istio-ingressgateway", "istio-egressgateway"} {
+			g.Expect(policies.nameEquals(name)).ShouldNot(BeNil(), name)
+		}
+
+		istiod := policies.nameEquals("istiod").Unstructured.Object
+		rules, _, _ := unstructured.NestedSlice(istiod, "spec", "ingress")
+		g.Expect(rules).Should(HaveLen(3))
+		g.Expect(rules[1]).Should(HavePathValueEqual(PathValue{"from.[0].ipBlock.cidr", "10.0.0.0/8"}))
+		g.Expect(rules[2]).Should(HavePathValueEqual(PathValue{"from.[0].namespaceSelector.matchLabels", toMap("kubernetes.io/metadata.name:monitoring")}))
+	}
+
+	// Not rendered by default.
+	for _, objs := range runManifestCommands(t, "default", "", liveCharts, nil) {
+		g.Expect(objs.kind("NetworkPolicy").size()).Should(Equal(0))
+	}
+}
+
+func TestManifestGenerateCNINodePools(t *testing.T) {
+	g := NewWithT(t)
+
+	objss := runManifestCommands(t, "cni_node_pools", "", liveCharts, nil)
+
+	for _, objs := range objss {
+		daemonSets := objs.kind("DaemonSet").nameMatches("istio-cni-node.*")
+		g.Expect(daemonSets.size()).Should(Equal(3))
+
+		def := daemonSets.nameEquals("istio-cni-node").Unstructured.Object
+		g.Expect(def).Should(HavePathValueEqual(PathValue{"spec.selector.matchLabels", toMap("k8s-app:istio-cni-node")}))
+		terms, _, _ := unstructured.NestedSlice(def, "spec", "template", "spec", "affinity", "nodeAffinity",
+			"requiredDuringSchedulingIgnoredDuringExecution", "nodeSelectorTerms")
+		g.Expect(terms).Should(HaveLen(1))
+		g.Expect(terms[0]).Should(HavePathValueEqual(PathValue{"matchExpressions.[0].key", "cloud.google.com/gke-nodepool"}))
+		g.Expect(terms[0]).Should(HavePathValueEqual(PathValue{"matchExpressions.[0].operator", "NotIn"}))
+		g.Expect(terms[0]).Should(HavePathValueEqual(PathValue{"matchExpressions.[1].key", "node.example.com/cni"}))
+		g.Expect(def).Should(HavePathValueEqual(PathValue{"spec.template.spec.volumes.[0].hostPath.path", "/opt/cni/bin"}))
+
+		legacy := daemonSets.nameEquals("istio-cni-node-legacy").Unstructured.Object
+		g.Expect(legacy).Should(HavePathValueEqual(PathValue{"spec.selector.matchLabels",
+			toMap("k8s-app:istio-cni-node,istio.io/cni-node-pool:legacy")}))
+		g.Expect(legacy).Should(HavePathValueEqual(PathValue{"spec.template.spec.nodeSelector",
+			toMap("kubernetes.io/os:linux,cloud.google.com/gke-nodepool:legacy")}))
+		g.Expect(legacy).Should(HavePathValueEqual(PathValue{"spec.template.spec.volumes.[0].hostPath.path", "/home/kubernetes/bin"}))
+		_, hasAffinity, _ := unstructured.NestedMap(legacy, "spec", "template", "spec", "affinity")
+		g.Expect(hasAffinity).Should(BeFalse())
+
+		custom := daemonSets.nameEquals("istio-cni-node-custom").Unstructured.Object
+		volumes, _, _ := unstructured.NestedSlice(custom, "spec", "template", "spec", "volumes")
+		paths := map[string]any{}
+		for _, v := range volumes {
+			path, _, _ := unstructured.NestedString(v.(map[string]any), "hostPath", "path")
+			paths[v.(map[string]any)["name"].(string)] = path
+		}
+		g.Expect(paths).Should(HaveKeyWithValue("cni-bin-dir", "/var/lib/cni/bin"))
+		g.Expect(paths).Should(HaveKeyWithValue("cni-net-dir", "/var/lib/cni/net.d"))
+	}
+}
+
+func TestManifestGenerateComponentInclusions(t *testing.T) {
+	inPath := filepath.Join(t.TempDir(), "no-ingress.yaml")
+	assert.NoError(t, os.WriteFile(inPath, []byte(`apiVersion: install.istio.io/v1alpha1
+kind: IstioOperator
+spec:
+  components:
+    ingressGateways:
+    - name: istio-ingressgateway
+      enabled: false
+`), 0o644))
+	setFlags := []string{"installPackagePath=" + string(liveCharts), "profile=demo", "components.cni.enabled=true"}
+	_, _, got, err := render.GenerateManifestWithInclusions([]string{inPath}, setFlags, false, nil, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, got, []render.ComponentInclusion{
+		{Component: component.BaseComponentName, Enabled: true, SetBy: `profile "demo"`},
+		{Component: component.PilotComponentName, Enabled: true, SetBy: `profile "demo"`},
+		{Component: component.IngressComponentName, Name: "istio-ingressgateway", Enabled: false, SetBy: "file " + inPath},
+		{Component: component.EgressComponentName, Name: "istio-egressgateway", Enabled: true, SetBy: `profile "demo"`},
+		{Component: component.CNIComponentName, Enabled: true, SetBy: "--set components.cni.enabled=true"},
+		{Component: component.IstiodRemoteComponentName, Enabled: false, SetBy: "component default"},
+		{Component: component.ZtunnelComponentName, Enabled: false, SetBy: "component default"},
+	})
+}
+
+func TestManifestGenerateExplainComponents(t *testing.T) {
+	for _, explain := range []bool{false, true} {
+		t.Run(fmt.Sprint(explain), func(t *testing.T) {
+			var stdout, stderr strings.Builder
+			args := &ManifestGenerateArgs{
+				Set:               []string{"installPackagePath=" + string(liveCharts)},
+				ExplainComponents: explain,
+			}
+			assert.NoError(t, ManifestGenerate(nil, args, clog.NewConsoleLogger(&stdout, &stderr, nil)))
+			assert.Equal(t, strings.Contains(stderr.String(), "COMPONENT"), explain)
+			assert.Equal(t, strings.Contains(stdout.String(), "COMPONENT"), false)
+		})
+	}
+}
+
 func TestManifestGenerateWithDuplicateMutatingWebhookConfig(t *testing.T) {
 	testResourceFile := "duplicate_mwc"
 
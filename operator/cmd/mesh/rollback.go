@@ -0,0 +1,135 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mesh
+
+import (
+	"context"
+	"fmt"
+	"maps"
+
+	"github.com/hashicorp/go-multierror"
+	admitv1 "k8s.io/api/admissionregistration/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"istio.io/api/label"
+	"istio.io/istio/pkg/kube"
+	"istio.io/istio/pkg/slices"
+	"istio.io/istio/pkg/util/sets"
+)
+
+// webhookSnapshot holds the revision and tag webhooks of the cluster, which decide the revision injecting and
+// validating each namespace, so that they can be restored after a failed upgrade.
+type webhookSnapshot struct {
+	mutating   []admitv1.MutatingWebhookConfiguration
+	validating []admitv1.ValidatingWebhookConfiguration
+}
+
+func takeWebhookSnapshot(client kube.CLIClient) (*webhookSnapshot, error) {
+	opts := metav1.ListOptions{LabelSelector: label.IoIstioRev.Name}
+	mwcs, err := client.Kube().AdmissionregistrationV1().MutatingWebhookConfigurations().List(context.TODO(), opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list mutating webhooks: %v", err)
+	}
+	vwcs, err := client.Kube().AdmissionregistrationV1().ValidatingWebhookConfigurations().List(context.TODO(), opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list validating webhooks: %v", err)
+	}
+	return &webhookSnapshot{mutating: mwcs.Items, validating: vwcs.Items}, nil
+}
+
+// restore reverts the revision and tag webhooks of the cluster to the snapshot: webhooks changed or deleted since are
+// restored, and those created since are deleted. It returns a description of each change.
+func (s *webhookSnapshot) restore(client kube.CLIClient) ([]string, error) {
+	var changes []string
+	var errs error
+	opts := metav1.ListOptions{LabelSelector: label.IoIstioRev.Name}
+
+	mwcClient := client.Kube().AdmissionregistrationV1().MutatingWebhookConfigurations()
+	current, err := mwcClient.List(context.TODO(), opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list mutating webhooks: %v", err)
+	}
+	snapshotted := sets.New[string]()
+	for _, want := range s.mutating {
+		snapshotted.Insert(want.Name)
+		got := slices.FindFunc(current.Items, func(m admitv1.MutatingWebhookConfiguration) bool { return m.Name == want.Name })
+		switch {
+		case got == nil:
+			want.ResourceVersion = ""
+			if _, err := mwcClient.Create(context.TODO(), &want, metav1.CreateOptions{}); err != nil {
+				errs = multierror.Append(errs, err)
+				continue
+			}
+			changes = append(changes, fmt.Sprintf("recreated MutatingWebhookConfiguration %s", want.Name))
+		case !equality.Semantic.DeepEqual(got.Webhooks, want.Webhooks) || !maps.Equal(got.Labels, want.Labels):
+			want.ResourceVersion = got.ResourceVersion
+			if _, err := mwcClient.Update(context.TODO(), &want, metav1.UpdateOptions{}); err != nil {
+				errs = multierror.Append(errs, err)
+				continue
+			}
+			changes = append(changes, fmt.Sprintf("restored MutatingWebhookConfiguration %s", want.Name))
+		}
+	}
+	for _, got := range current.Items {
+		if snapshotted.Contains(got.Name) {
+			continue
+		}
+		if err := mwcClient.Delete(context.TODO(), got.Name, metav1.DeleteOptions{}); err != nil && !kerrors.IsNotFound(err) {
+			errs = multierror.Append(errs, err)
+			continue
+		}
+		changes = append(changes, fmt.Sprintf("deleted MutatingWebhookConfiguration %s", got.Name))
+	}
+
+	vwcClient := client.Kube().AdmissionregistrationV1().ValidatingWebhookConfigurations()
+	currentValidating, err := vwcClient.List(context.TODO(), opts)
+	if err != nil {
+		return changes, fmt.Errorf("failed to list validating webhooks: %v", err)
+	}
+	snapshotted = sets.New[string]()
+	for _, want := range s.validating {
+		snapshotted.Insert(want.Name)
+		got := slices.FindFunc(currentValidating.Items, func(v admitv1.ValidatingWebhookConfiguration) bool { return v.Name == want.Name })
+		switch {
+		case got == nil:
+			want.ResourceVersion = ""
+			if _, err := vwcClient.Create(context.TODO(), &want, metav1.CreateOptions{}); err != nil {
+				errs = multierror.Append(errs, err)
+				continue
+			}
+			changes = append(changes, fmt.Sprintf("recreated ValidatingWebhookConfiguration %s", want.Name))
+		case !equality.Semantic.DeepEqual(got.Webhooks, want.Webhooks) || !maps.Equal(got.Labels, want.Labels):
+			want.ResourceVersion = got.ResourceVersion
+			if _, err := vwcClient.Update(context.TODO(), &want, metav1.UpdateOptions{}); err != nil {
+				errs = multierror.Append(errs, err)
+				continue
+			}
+			changes = append(changes, fmt.Sprintf("restored ValidatingWebhookConfiguration %s", want.Name))
+		}
+	}
+	for _, got := range currentValidating.Items {
+		if snapshotted.Contains(got.Name) {
+			continue
+		}
+		if err := vwcClient.Delete(context.TODO(), got.Name, metav1.DeleteOptions{}); err != nil && !kerrors.IsNotFound(err) {
+			errs = multierror.Append(errs, err)
+			continue
+		}
+		changes = append(changes, fmt.Sprintf("deleted ValidatingWebhookConfiguration %s", got.Name))
+	}
+	return changes, errs
+}
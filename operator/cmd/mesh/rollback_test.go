@@ -0,0 +1,77 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mesh
+
+import (
+	"context"
+	"testing"
+
+	admitv1 "k8s.io/api/admissionregistration/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"istio.io/istio/pkg/kube"
+	"istio.io/istio/pkg/test/util/assert"
+)
+
+func TestWebhookSnapshotRestore(t *testing.T) {
+	mwc := func(name, revision, service string) *admitv1.MutatingWebhookConfiguration {
+		return &admitv1.MutatingWebhookConfiguration{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{"istio.io/rev": revision}},
+			Webhooks: []admitv1.MutatingWebhook{{
+				Name:         "rev.namespace.sidecar-injector.istio.io",
+				ClientConfig: admitv1.WebhookClientConfig{Service: &admitv1.ServiceReference{Name: service, Namespace: "istio-system"}},
+			}},
+		}
+	}
+	vwc := &admitv1.ValidatingWebhookConfiguration{
+		ObjectMeta: metav1.ObjectMeta{Name: "istio-validator-1-24-istio-system", Labels: map[string]string{"istio.io/rev": "1-24"}},
+	}
+	client := kube.NewFakeClient(
+		mwc("istio-revision-tag-default", "1-24", "istiod-1-24"),
+		mwc("istio-sidecar-injector-1-24", "1-24", "istiod-1-24"),
+		vwc,
+		// Not an Istio revision webhook, never touched.
+		&admitv1.MutatingWebhookConfiguration{ObjectMeta: metav1.ObjectMeta{Name: "other"}},
+	)
+	snapshot, err := takeWebhookSnapshot(client)
+	assert.NoError(t, err)
+
+	// The failed upgrade moved the default tag, installed the webhooks of the new revision, and removed a validator.
+	mwcs := client.Kube().AdmissionregistrationV1().MutatingWebhookConfigurations()
+	_, err = mwcs.Update(context.TODO(), mwc("istio-revision-tag-default", "1-25", "istiod-1-25"), metav1.UpdateOptions{})
+	assert.NoError(t, err)
+	_, err = mwcs.Create(context.TODO(), mwc("istio-sidecar-injector-1-25", "1-25", "istiod-1-25"), metav1.CreateOptions{})
+	assert.NoError(t, err)
+	assert.NoError(t, client.Kube().AdmissionregistrationV1().ValidatingWebhookConfigurations().Delete(context.TODO(), vwc.Name, metav1.DeleteOptions{}))
+
+	changes, err := snapshot.restore(client)
+	assert.NoError(t, err)
+	assert.Equal(t, changes, []string{
+		"restored MutatingWebhookConfiguration istio-revision-tag-default",
+		"deleted MutatingWebhookConfiguration istio-sidecar-injector-1-25",
+		"recreated ValidatingWebhookConfiguration istio-validator-1-24-istio-system",
+	})
+	tag, err := mwcs.Get(context.TODO(), "istio-revision-tag-default", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, tag.Labels["istio.io/rev"], "1-24")
+	assert.Equal(t, tag.Webhooks[0].ClientConfig.Service.Name, "istiod-1-24")
+	_, err = mwcs.Get(context.TODO(), "other", metav1.GetOptions{})
+	assert.NoError(t, err)
+
+	// Restoring again is a no-op.
+	changes, err = snapshot.restore(client)
+	assert.NoError(t, err)
+	assert.Equal(t, len(changes), 0)
+}
@@ -41,6 +41,9 @@ If set to true, the user is not prompted and a Yes response is assumed in all ca
 This flag can be specified multiple times to overlay multiple files. Multiple files are overlaid in left to right order.`
 	ForceFlagHelpStr       = `Proceed even with validation errors.`
 	VerifyCRInstallHelpStr = "Verify the Istio control plane after installation/in-place upgrade"
+
+	explainComponentsFlagHelpStr = `Report the components enabled and disabled, with the profile, file or --set flag
+deciding each of them.`
 )
 
 type RootArgs struct {
@@ -19,8 +19,12 @@ import (
 	"fmt"
 	"io"
 	"strings"
+	"text/tabwriter"
 
+	"istio.io/istio/operator/pkg/component"
+	"istio.io/istio/operator/pkg/render"
 	"istio.io/istio/pkg/log"
+	"istio.io/istio/pkg/ptr"
 )
 
 // installerScope is the scope for all commands in the mesh package.
@@ -76,3 +80,17 @@ func applyFlagAliases(flags []string, manifestsPath, revision string) []string {
 	}
 	return flags
 }
+
+// formatComponentInclusions returns a table of the components, whether they are rendered, and the profile, file or --set
+// flag deciding it, so that a component missing from the installation can be traced back to the input disabling it.
+func formatComponentInclusions(inclusions []render.ComponentInclusion) string {
+	var b strings.Builder
+	w := tabwriter.NewWriter(&b, 0, 8, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "COMPONENT\tNAME\tENABLED\tSET BY")
+	for _, inc := range inclusions {
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%t\t%s\n", component.UserFacingComponentName(inc.Component), ptr.NonEmptyOrDefault(inc.Name, "-"),
+			inc.Enabled, inc.SetBy)
+	}
+	_ = w.Flush()
+	return b.String()
+}
@@ -15,6 +15,8 @@
 package mesh
 
 import (
+	"fmt"
+
 	"github.com/spf13/cobra"
 
 	"istio.io/istio/istioctl/pkg/cli"
@@ -23,6 +25,8 @@ import (
 
 type upgradeArgs struct {
 	*InstallArgs
+	// withRollback restores the revision and tag webhooks if the upgrade fails.
+	withRollback bool
 }
 
 // UpgradeCmd upgrades Istio control plane in-place with eligibility checks.
@@ -34,7 +38,12 @@ func UpgradeCmd(ctx cli.Context) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "upgrade",
 		Short: "Upgrade Istio control plane in-place",
-		Long:  "The upgrade command is an alias for the install command",
+		Long: `The upgrade command is an alias for the install command.
+
+With --with-rollback, the revision and tag webhooks of the cluster are snapshotted before upgrading. If applying the
+manifests or waiting for the upgraded components to become ready fails, the webhooks are restored from the snapshot,
+so that namespaces are injected and validated by the revisions they were before, and the changes are reported.
+Other resources of the upgraded revision are left as is, which makes this most useful for revisioned upgrades.`,
 		RunE: func(cmd *cobra.Command, args []string) (e error) {
 			l := clog.NewConsoleLogger(cmd.OutOrStdout(), cmd.ErrOrStderr(), installerScope)
 			p := NewPrinterForWriter(cmd.OutOrStderr())
@@ -42,10 +51,34 @@ func UpgradeCmd(ctx cli.Context) *cobra.Command {
 			if err != nil {
 				return err
 			}
-			return Install(client, rootArgs, upgradeArgs.InstallArgs, cmd.OutOrStdout(), l, p)
+			if !upgradeArgs.withRollback || rootArgs.DryRun {
+				return Install(client, rootArgs, upgradeArgs.InstallArgs, cmd.OutOrStdout(), l, p)
+			}
+			snapshot, err := takeWebhookSnapshot(client)
+			if err != nil {
+				return err
+			}
+			installErr := Install(client, rootArgs, upgradeArgs.InstallArgs, cmd.OutOrStdout(), l, p)
+			if installErr == nil {
+				return nil
+			}
+			p.Println("Upgrade failed, restoring the revision and tag webhooks.")
+			changes, err := snapshot.restore(client)
+			for _, c := range changes {
+				p.Printf("  %s\n", c)
+			}
+			if len(changes) == 0 && err == nil {
+				p.Println("  the webhooks were not changed by the upgrade")
+			}
+			if err != nil {
+				return fmt.Errorf("%v; failed to restore the webhooks: %v", installErr, err)
+			}
+			return installErr
 		},
 	}
 	addFlags(cmd, rootArgs)
 	addInstallFlags(cmd, upgradeArgs.InstallArgs)
+	cmd.PersistentFlags().BoolVar(&upgradeArgs.withRollback, "with-rollback", false,
+		"Restore the revision and tag webhooks if the upgrade fails")
 	return cmd
 }
@@ -141,6 +141,9 @@ type GatewayComponentSpec struct {
 	Name string `json:"name,omitempty"`
 	// Labels for the component.
 	Label map[string]string `json:"label,omitempty"`
+	// Overrides for the values of the gateway chart, such as `podAnnotations` or `additionalContainers`, applied to
+	// this gateway only. Unlike `spec.values`, these are checked against the values of the gateway chart when rendering.
+	Values json.RawMessage `json:"values,omitempty"`
 }
 
 // KubernetesResources is a common set of Kubernetes resource configs for components.
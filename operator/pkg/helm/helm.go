@@ -195,3 +195,16 @@ func getFilesRecursive(f fs.FS, root string) ([]string, error) {
 	})
 	return res, err
 }
+
+// DefaultValues returns the defaults of the chart values under a path, such as "gateways.istio-ingressgateway".
+func DefaultValues(installPackagePath string, directory string, path string) (map[string]any, error) {
+	chrt, err := loadChart(manifests.BuiltinOrDir(installPackagePath), pathJoin("charts", directory))
+	if err != nil {
+		return nil, fmt.Errorf("load chart: %v", err)
+	}
+	defaults := values.Map(chrt.Values)
+	if d, ok := defaults.GetPathMap("_internal_defaults_do_not_set"); ok {
+		defaults = d
+	}
+	return values.TryGetPathAs[map[string]any](defaults, path), nil
+}
@@ -0,0 +1,129 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package render
+
+import (
+	"fmt"
+	"strings"
+
+	"istio.io/istio/operator/pkg/apis"
+	"istio.io/istio/operator/pkg/component"
+	"istio.io/istio/operator/pkg/values"
+	"istio.io/istio/pkg/ptr"
+)
+
+// ComponentInclusion is whether a component, or a gateway of a component, is rendered, and the input deciding it.
+type ComponentInclusion struct {
+	Component component.Name
+	// Name is the name of the gateway, for components allowing several of them.
+	Name    string
+	Enabled bool
+	// SetBy is the profile, file or --set flag enabling or disabling the component, or "component default".
+	SetBy string
+}
+
+const componentDefault = "component default"
+
+// inputLayer is one of the inputs merged into the IstioOperator: the profile, a file, or a --set flag.
+type inputLayer struct {
+	source string
+	// config is the IstioOperator of the profile or file, unset for a --set flag.
+	config values.Map
+	// setPath is the path, from the IstioOperator, set by the --set flag.
+	setPath string
+}
+
+// sets returns whether the layer sets the path of the IstioOperator.
+func (l inputLayer) sets(path string) bool {
+	if l.config != nil {
+		_, ok := l.config.GetPath(path)
+		return ok
+	}
+	return strings.ReplaceAll(l.setPath, ".[", "[") == strings.ReplaceAll(path, ".[", "[")
+}
+
+// lastSetting returns the source of the last layer setting any of the paths, or def if there is none.
+func lastSetting(layers []inputLayer, def string, paths ...string) string {
+	for i := len(layers) - 1; i >= 0; i-- {
+		for _, p := range paths {
+			if layers[i].sets(p) {
+				return layers[i].source
+			}
+		}
+	}
+	return def
+}
+
+// componentInclusions returns whether each component is rendered, following component.Get, with the last of the layers
+// setting it.
+func componentInclusions(merged values.Map, layers []inputLayer) ([]ComponentInclusion, error) {
+	var res []ComponentInclusion
+	for _, c := range component.AllComponents {
+		path := "spec.components." + c.SpecName
+		altEnabled := c.AltEnablementPath != "" && merged.GetPathBool(c.AltEnablementPath)
+		altSetBy := lastSetting(layers, componentDefault, c.AltEnablementPath)
+
+		var specs []any
+		if c.Multi {
+			if s, ok := merged.GetPath(path); ok {
+				specs, _ = s.([]any)
+			}
+		} else if s, ok := merged.GetPathMap(path); ok {
+			specs = []any{s}
+		}
+		if specs == nil {
+			inc := ComponentInclusion{Component: c.UserFacingName, Enabled: c.Default || altEnabled, SetBy: componentDefault}
+			if c.Multi {
+				inc.Name = c.ResourceName
+			}
+			if altEnabled {
+				inc.SetBy = altSetBy
+			}
+			res = append(res, inc)
+			continue
+		}
+
+		for i, cur := range specs {
+			m, _ := values.CastAsMap(cur)
+			spec, err := values.ConvertMap[apis.GatewayComponentSpec](m)
+			if err != nil {
+				return nil, fmt.Errorf("fail to convert %v: %v", c.SpecName, err)
+			}
+			inc := ComponentInclusion{Component: c.UserFacingName, Enabled: spec.Enabled.GetValueOrTrue() || altEnabled}
+			if c.Multi {
+				inc.Name = ptr.NonEmptyOrDefault(spec.Name, c.ResourceName)
+			}
+			switch {
+			case !spec.Enabled.GetValueOrTrue() && altEnabled:
+				inc.SetBy = altSetBy
+			case c.Multi:
+				// A file or profile listing the gateways replaces the gateways of the previous ones.
+				inc.SetBy = componentDefault
+				for j := len(layers) - 1; j >= 0; j-- {
+					l := layers[j]
+					if l.sets(fmt.Sprintf("%s.[%d].enabled", path, i)) || l.sets(fmt.Sprintf("%s.[name:%s].enabled", path, spec.Name)) ||
+						(l.config != nil && l.sets(path)) {
+						inc.SetBy = l.source
+						break
+					}
+				}
+			default:
+				inc.SetBy = lastSetting(layers, lastSetting(layers, componentDefault, path), path+".enabled")
+			}
+			res = append(res, inc)
+		}
+	}
+	return res, nil
+}
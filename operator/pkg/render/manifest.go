@@ -21,6 +21,7 @@ import (
 	"os"
 	"strings"
 
+	"google.golang.org/protobuf/reflect/protoreflect"
 	"k8s.io/apimachinery/pkg/version"
 
 	"istio.io/istio/manifests"
@@ -33,6 +34,9 @@ import (
 	"istio.io/istio/operator/pkg/util/clog"
 	"istio.io/istio/operator/pkg/values"
 	"istio.io/istio/pkg/kube"
+	"istio.io/istio/pkg/maps"
+	"istio.io/istio/pkg/ptr"
+	"istio.io/istio/pkg/slices"
 	pkgversion "istio.io/istio/pkg/version"
 )
 
@@ -41,15 +45,23 @@ import (
 // Client is option; if it is provided, cluster-specific settings can be auto-detected.
 // Logger is also option; if it is provided warning messages may be logged.
 func GenerateManifest(files []string, setFlags []string, force bool, client kube.Client, logger clog.Logger) ([]manifest.ManifestSet, values.Map, error) {
+	manifests, merged, _, err := GenerateManifestWithInclusions(files, setFlags, force, client, logger)
+	return manifests, merged, err
+}
+
+// GenerateManifestWithInclusions is GenerateManifest, also returning whether each component is rendered, and which input
+// decided it.
+func GenerateManifestWithInclusions(files []string, setFlags []string, force bool, client kube.Client, logger clog.Logger,
+) ([]manifest.ManifestSet, values.Map, []ComponentInclusion, error) {
 	// First, compute our final configuration input. This will be in the form of an IstioOperator, but as an unstructured values.Map.
 	// This allows safe access to get/fetch values dynamically, and avoids issues are typing and whether we should emit empty fields.
-	merged, err := MergeInputs(files, setFlags, client)
+	merged, layers, err := mergeInputs(files, setFlags, client)
 	if err != nil {
-		return nil, nil, fmt.Errorf("merge inputs: %v", err)
+		return nil, nil, nil, fmt.Errorf("merge inputs: %v", err)
 	}
 	// Validate the config. This can emit warnings to the logger. If force is set, errors will be logged as warnings but not returned.
 	if err := validateIstioOperator(merged, client, logger, force); err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 	// After validation, apply any unvalidatedValues they may have set.
 	if unvalidatedValues, _ := merged.GetPathMap("spec.unvalidatedValues"); unvalidatedValues != nil {
@@ -59,7 +71,7 @@ func GenerateManifest(files []string, setFlags []string, force bool, client kube
 	if client != nil {
 		v, err := client.GetKubernetesVersion()
 		if err != nil {
-			return nil, nil, fmt.Errorf("fail to get Kubernetes version: %v", err)
+			return nil, nil, nil, fmt.Errorf("fail to get Kubernetes version: %v", err)
 		}
 		kubernetesVersion = v
 	}
@@ -70,21 +82,30 @@ func GenerateManifest(files []string, setFlags []string, force bool, client kube
 	for _, comp := range component.AllComponents {
 		specs, err := comp.Get(merged)
 		if err != nil {
-			return nil, nil, fmt.Errorf("get component %v: %v", comp.UserFacingName, err)
+			return nil, nil, nil, fmt.Errorf("get component %v: %v", comp.UserFacingName, err)
 		}
 		for _, spec := range specs {
+			// Check the values passthrough of the component against the chart before applying it.
+			if err := validateComponentValues(comp, spec, merged); err != nil {
+				if !force {
+					return nil, nil, nil, err
+				}
+				if logger != nil {
+					logger.PrintErr(fmt.Sprintf("component values invalid; continuing because of --force: %v", err))
+				}
+			}
 			// Each component may get a different view of the values; modify them as needed (with a copy)
 			compVals := applyComponentValuesToHelmValues(comp, spec, merged)
 			// Render the chart
 			rendered, warnings, err := helm.Render(spec.Namespace, comp.HelmSubdir, compVals, kubernetesVersion)
 			if err != nil {
-				return nil, nil, fmt.Errorf("helm render: %v", err)
+				return nil, nil, nil, fmt.Errorf("helm render: %v", err)
 			}
 			chartWarnings = util.AppendErrs(chartWarnings, warnings)
 			// IstioOperator has a variety of processing steps that are done *after* Helm, such as patching. Apply any of these steps.
 			finalized, err := postProcess(comp, spec, rendered, compVals)
 			if err != nil {
-				return nil, nil, fmt.Errorf("post processing: %v", err)
+				return nil, nil, nil, fmt.Errorf("post processing: %v", err)
 			}
 			manifests, found := allManifests[comp.UserFacingName]
 			if found {
@@ -112,7 +133,11 @@ func GenerateManifest(files []string, setFlags []string, force bool, client kube
 		values = append(values, v)
 	}
 
-	return values, merged, nil
+	inclusions, err := componentInclusions(merged, layers)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return values, merged, inclusions, nil
 }
 
 type MigrationResult struct {
@@ -182,9 +207,13 @@ func Migrate(files []string, setFlags []string, client kube.Client) (MigrationRe
 func applyComponentValuesToHelmValues(comp component.Component, spec apis.GatewayComponentSpec, merged values.Map) values.Map {
 	root := comp.ToHelmValuesTreeRoot
 
-	// Gateways allow providing 'name' and 'label' overrides.
+	// Gateways allow providing 'name' and 'label' overrides, and a passthrough of chart values.
 	if comp.IsGateway() {
 		merged = merged.DeepClone()
+		if len(spec.Values) > 0 {
+			vals, _ := values.MapFromJSON(spec.Values)
+			merged.MergeFrom(values.MakeMap(map[string]any(vals), append([]string{"spec", "values"}, strings.Split(root, ".")...)...))
+		}
 		_ = merged.SetPath(fmt.Sprintf("spec.values.%s.name", root), spec.Name)
 		_ = merged.SetPath(fmt.Sprintf("spec.values.%s.labels", root), spec.Label)
 		if spec.Kubernetes != nil && spec.Kubernetes.Service != nil && len(spec.Kubernetes.Service.Ports) > 0 {
@@ -234,6 +263,179 @@ func applyComponentValuesToHelmValues(comp component.Component, spec apis.Gatewa
 	return merged
 }
 
+// reservedComponentValues are chart values set from other fields of the component, which cannot be passed through.
+var reservedComponentValues = map[string]string{
+	"name":    "name",
+	"labels":  "label",
+	"enabled": "enabled",
+}
+
+// validateComponentValues checks the values passthrough of a gateway against the values of the chart of the component:
+// each value must be a field of the values API for the component, or have a default in the chart, as the chart would
+// otherwise silently ignore it, and have the kind of this field or default. The values nested in a field of the values
+// API are checked the same way against the messages of the values API, while those nested in a value only known from
+// the chart defaults are not.
+func validateComponentValues(comp component.Component, spec apis.GatewayComponentSpec, merged values.Map) error {
+	if !comp.IsGateway() || len(spec.Values) == 0 {
+		return nil
+	}
+	vals, err := values.MapFromJSON(spec.Values)
+	if err != nil {
+		return fmt.Errorf("%s %s: invalid values: %v", comp.SpecName, spec.Name, err)
+	}
+	schema, md, err := componentValuesSchema(comp, merged.GetPathString("spec.installPackagePath"))
+	if err != nil {
+		return fmt.Errorf("%s %s: %v", comp.SpecName, spec.Name, err)
+	}
+	var errs util.Errors
+	for _, k := range slices.Sort(maps.Keys(vals)) {
+		if field, f := reservedComponentValues[k]; f {
+			errs = util.AppendErr(errs, fmt.Errorf("%s %s: values.%s cannot be set, use the %s field of the component instead",
+				comp.SpecName, spec.Name, k, field))
+			continue
+		}
+		kind, f := schema[k]
+		if !f {
+			errs = util.AppendErr(errs, fmt.Errorf("%s %s: values.%s is not a value of the %s chart", comp.SpecName, spec.Name, k, comp.HelmSubdir))
+			continue
+		}
+		if kind != "" && vals[k] != nil && kind != valueKind(vals[k]) {
+			errs = util.AppendErr(errs, fmt.Errorf("%s %s: values.%s must be a %s, got a %s",
+				comp.SpecName, spec.Name, k, kind, valueKind(vals[k])))
+			continue
+		}
+		if fd := valuesField(md, k); fd != nil {
+			for _, err := range validateFieldValues(fd, "values."+k, vals[k]) {
+				errs = util.AppendErr(errs, fmt.Errorf("%s %s: %v", comp.SpecName, spec.Name, err))
+			}
+		}
+	}
+	return errs.ToError()
+}
+
+// validateFieldValues checks the value of a field of the values API against the message of the field, or of its
+// elements for lists and maps.
+func validateFieldValues(fd protoreflect.FieldDescriptor, path string, v any) []error {
+	var errs []error
+	switch {
+	case fd.IsMap():
+		m, _ := v.(map[string]any)
+		for _, k := range slices.Sort(maps.Keys(m)) {
+			errs = append(errs, validateMessageValues(fd.MapValue().Message(), path+"."+k, m[k])...)
+		}
+	case fd.IsList():
+		l, _ := v.([]any)
+		for i, e := range l {
+			errs = append(errs, validateMessageValues(fd.Message(), fmt.Sprintf("%s[%d]", path, i), e)...)
+		}
+	default:
+		errs = validateMessageValues(fd.Message(), path, v)
+	}
+	return errs
+}
+
+// validateMessageValues checks that each key of a map value is a field of the message, with the kind of this field.
+// Messages from outside of the values API, such as the Kubernetes ones or google.protobuf.Struct, are not checked.
+func validateMessageValues(md protoreflect.MessageDescriptor, path string, v any) []error {
+	m, ok := v.(map[string]any)
+	if md == nil || !ok || md.ParentFile().Package() != valuesPackage {
+		return nil
+	}
+	var errs []error
+	for _, k := range slices.Sort(maps.Keys(m)) {
+		fd := valuesField(md, k)
+		if fd == nil {
+			errs = append(errs, fmt.Errorf("%s.%s is not a field of %s", path, k, md.Name()))
+			continue
+		}
+		if kind := fieldKind(fd); kind != "" && m[k] != nil && kind != valueKind(m[k]) {
+			errs = append(errs, fmt.Errorf("%s.%s must be a %s, got a %s", path, k, kind, valueKind(m[k])))
+			continue
+		}
+		errs = append(errs, validateFieldValues(fd, path+"."+k, m[k])...)
+	}
+	return errs
+}
+
+// valuesPackage is the proto package of the values API.
+var valuesPackage = (&apis.Values{}).ProtoReflect().Descriptor().ParentFile().Package()
+
+// valuesField returns the field of the message with the given JSON or proto name, or nil if there is none.
+func valuesField(md protoreflect.MessageDescriptor, name string) protoreflect.FieldDescriptor {
+	if md == nil {
+		return nil
+	}
+	if fd := md.Fields().ByJSONName(name); fd != nil {
+		return fd
+	}
+	return md.Fields().ByName(protoreflect.Name(name))
+}
+
+// componentValuesSchema returns the values of the chart of the component, mapped to their kind, or to an empty string
+// if they can be of any kind: the fields of the message of the values API at the values root of the component, and the
+// values with a default in the chart. It also returns this message, or nil if the values API has none for the component.
+func componentValuesSchema(comp component.Component, installPackagePath string) (map[string]string, protoreflect.MessageDescriptor, error) {
+	defaults, err := helm.DefaultValues(installPackagePath, comp.HelmSubdir, comp.ToHelmValuesTreeRoot)
+	if err != nil {
+		return nil, nil, err
+	}
+	schema := map[string]string{}
+	for k, v := range defaults {
+		if v != nil {
+			schema[k] = valueKind(v)
+		} else {
+			schema[k] = ""
+		}
+	}
+	md := (&apis.Values{}).ProtoReflect().Descriptor()
+	for _, segment := range strings.Split(comp.ToHelmValuesTreeRoot, ".") {
+		fd := md.Fields().ByJSONName(segment)
+		if fd == nil || fd.Message() == nil || fd.IsMap() || fd.IsList() {
+			return schema, nil, nil
+		}
+		md = fd.Message()
+	}
+	for i := 0; i < md.Fields().Len(); i++ {
+		fd := md.Fields().Get(i)
+		schema[fd.JSONName()] = fieldKind(fd)
+	}
+	return schema, md, nil
+}
+
+// fieldKind returns the kind of the values of a field of the values API, or an empty string if they can be of any kind.
+func fieldKind(fd protoreflect.FieldDescriptor) string {
+	switch {
+	case fd.IsMap():
+		return "map"
+	case fd.IsList():
+		return "list"
+	case fd.Message() == nil:
+		return "scalar"
+	}
+	switch name := fd.Message().FullName(); {
+	case name == "google.protobuf.Value" || name == "google.protobuf.Any":
+		return ""
+	case name == "google.protobuf.ListValue":
+		return "list"
+	case name.Parent() == "google.protobuf" && strings.HasSuffix(string(name), "Value"), name.Name() == "IntOrString":
+		// Wrappers of scalars.
+		return "scalar"
+	}
+	return "map"
+}
+
+// valueKind returns the kind of a value decoded from YAML or JSON, distinguishing maps, lists and scalars.
+func valueKind(v any) string {
+	switch v.(type) {
+	case map[string]any:
+		return "map"
+	case []any:
+		return "list"
+	default:
+		return "scalar"
+	}
+}
+
 // hubTagOverlay returns settings to override the default hub/tag, if the binary is compiled with specific versions
 func hubTagOverlay() []string {
 	hub := pkgversion.DockerInfo.Hub
@@ -246,6 +448,12 @@ func hubTagOverlay() []string {
 
 // MergeInputs merges the various configuration inputs into one single IstioOperator.
 func MergeInputs(filenames []string, flags []string, client kube.Client) (values.Map, error) {
+	merged, _, err := mergeInputs(filenames, flags, client)
+	return merged, err
+}
+
+// mergeInputs is MergeInputs, also returning the profile, files and --set flags merged, in order of precedence.
+func mergeInputs(filenames []string, flags []string, client kube.Client) (values.Map, []inputLayer, error) {
 	// We want our precedence order to be: base < profile < auto detected settings < files (in order) < --set flags (in order).
 	// The tricky bit is we don't know where to read the profile from until we read the files/--set flags.
 	// To handle this, we will build up these first, then apply it on top of the base once we know what base to use.
@@ -257,41 +465,51 @@ func MergeInputs(filenames []string, flags []string, client kube.Client) (values
   "spec": {}
 }`))
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
+	var layers []inputLayer
 	// Apply all passed in files
 	for i, fn := range filenames {
 		var b []byte
 		var err error
 		if fn == "-" {
 			if i != len(filenames)-1 {
-				return nil, fmt.Errorf("stdin is only allowed as the last filename")
+				return nil, nil, fmt.Errorf("stdin is only allowed as the last filename")
 			}
 			b, err = io.ReadAll(os.Stdin)
 		} else {
 			b, err = os.ReadFile(strings.TrimSpace(fn))
 		}
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		if err := checkNoMultipleIOPs(string(b)); err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		m, err := values.MapFromYaml(b)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		// Special hack to allow an empty spec to work. Should this be more generic?
 		if m["spec"] == nil {
 			delete(m, "spec")
 		}
+		source := "file " + fn
+		if fn == "-" {
+			source = "stdin"
+		}
+		layers = append(layers, inputLayer{source: source, config: m.DeepClone()})
 		userConfigBase.MergeFrom(m)
 	}
 
 	// Apply any --set flags
 	if err := userConfigBase.SetSpecPaths(flags...); err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+	for _, flag := range flags {
+		path, _, _ := strings.Cut(flag, "=")
+		layers = append(layers, inputLayer{source: "--set " + flag, setPath: "spec." + path})
 	}
 
 	installPackagePath := userConfigBase.GetPathString("spec.installPackagePath")
@@ -301,16 +519,17 @@ func MergeInputs(filenames []string, flags []string, client kube.Client) (values
 	// Now we have the base
 	base, err := readProfile(installPackagePath, profile)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
+	layers = append([]inputLayer{{source: fmt.Sprintf("profile %q", ptr.NonEmptyOrDefault(profile, "default")), config: base.DeepClone()}}, layers...)
 
 	// Overlay detected settings
 	if err := base.SetSpecPaths(clusterSpecificSettings(client)...); err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	// Insert compiled in hub/tag
 	if err := base.SetSpecPaths(hubTagOverlay()...); err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	// Merge the user values on top
@@ -319,7 +538,7 @@ func MergeInputs(filenames []string, flags []string, client kube.Client) (values
 	// Canonical-ize some of the values, translating things like `spec.hub` to `spec.values.global.hub` for helm compatibility
 	base, err = translateIstioOperatorToHelm(base)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	// User values may override things from translateIstioOperatorToHelm.
@@ -329,7 +548,7 @@ func MergeInputs(filenames []string, flags []string, client kube.Client) (values
 	if userValues != nil {
 		base.MergeFrom(values.Map{"spec": values.Map{"values": userValues}})
 	}
-	return base, nil
+	return base, layers, nil
 }
 
 func checkNoMultipleIOPs(s string) error {
@@ -25,6 +25,7 @@ import (
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/selection"
 
+	"istio.io/api/annotation"
 	"istio.io/api/label"
 	"istio.io/istio/operator/pkg/component"
 	"istio.io/istio/operator/pkg/manifest"
@@ -34,6 +35,14 @@ import (
 	"istio.io/istio/pkg/kube"
 	"istio.io/istio/pkg/kube/controllers"
 	"istio.io/istio/pkg/ptr"
+	"istio.io/istio/pkg/slices"
+)
+
+const (
+	// caRootCertConfigMap is the ConfigMap istiod creates in every namespace with the root certificate of the mesh.
+	caRootCertConfigMap = "istio-ca-root-cert"
+	// caRootCertConfigMapSelector selects the root certificate ConfigMaps created by istiod.
+	caRootCertConfigMapSelector = "istio.io/config=true"
 )
 
 var (
@@ -73,6 +82,7 @@ func NamespacedResources() []schema.GroupVersionKind {
 		{Group: "rbac.authorization.k8s.io", Version: "v1", Kind: "RoleBinding"},
 		{Group: "rbac.authorization.k8s.io", Version: "v1", Kind: "Role"},
 		{Group: "policy", Version: "v1", Kind: "PodDisruptionBudget"},
+		{Group: "networking.k8s.io", Version: "v1", Kind: "NetworkPolicy"},
 		{Group: "autoscaling", Version: "v2", Kind: "HorizontalPodAutoscaler"},
 		gvk.EnvoyFilter.Kubernetes(),
 	}
@@ -93,10 +103,62 @@ func DeleteObjectsList(c kube.CLIClient, dryRun bool, log clog.Logger, objectsLi
 	return errs.ToError()
 }
 
+// RuntimeResources lists the resource types istiod creates at runtime rather than the installer: root certificate
+// ConfigMaps, auto-registered WorkloadEntries, and the Deployments and Services of automated gateway deployments.
+// They have no component label, and are shared by all revisions, so they are only removed by a purge.
+func RuntimeResources() []schema.GroupVersionKind {
+	return []schema.GroupVersionKind{
+		gvk.ConfigMap.Kubernetes(),
+		gvk.WorkloadEntry.Kubernetes(),
+		gvk.Deployment.Kubernetes(),
+		gvk.Service.Kubernetes(),
+	}
+}
+
+// GetRuntimeResources gets the list of resources created at runtime by istiod in the cluster, as listed by
+// RuntimeResources.
+func GetRuntimeResources(clt kube.CLIClient) ([]*unstructured.UnstructuredList, error) {
+	var usList []*unstructured.UnstructuredList
+	for _, g := range RuntimeResources() {
+		opts := metav1.ListOptions{}
+		// keep selects the resources created by istiod among those listed.
+		keep := func(o unstructured.Unstructured) bool { return true }
+		switch g.Kind {
+		case gvk.ConfigMap.Kind:
+			opts.LabelSelector = caRootCertConfigMapSelector
+			keep = func(o unstructured.Unstructured) bool { return o.GetName() == caRootCertConfigMap }
+		case gvk.WorkloadEntry.Kind:
+			keep = func(o unstructured.Unstructured) bool {
+				return o.GetAnnotations()[annotation.IoIstioAutoRegistrationGroup.Name] != ""
+			}
+		default:
+			opts.LabelSelector = label.GatewayManaged.Name
+		}
+		c, err := clt.DynamicClientFor(g, nil, "")
+		if err != nil {
+			return nil, err
+		}
+		result, err := c.List(context.Background(), opts)
+		if controllers.IgnoreNotFound(err) != nil {
+			return nil, err
+		}
+		if result == nil {
+			continue
+		}
+		result.Items = slices.FilterInPlace(result.Items, keep)
+		if len(result.Items) == 0 {
+			continue
+		}
+		usList = append(usList, result)
+	}
+	return usList, nil
+}
+
 // GetPrunedResources get the list of resources to be removed
 // 1. if includeClusterResources is false, we list the namespaced resources by matching revision and component labels.
 // 2. if includeClusterResources is true, we list the namespaced and cluster resources by component labels only.
 // If componentName is not empty, only resources associated with specific components would be returned
+// Resources created at runtime by istiod are only returned, first, if includeClusterResources is true.
 // UnstructuredList of objects and corresponding list of name kind hash of k8sObjects would be returned
 func GetPrunedResources(clt kube.CLIClient, iopName, iopNamespace, revision string, includeClusterResources bool) (
 	[]*unstructured.UnstructuredList, error,
@@ -157,6 +219,14 @@ func GetPrunedResources(clt kube.CLIClient, iopName, iopNamespace, revision stri
 		}
 		usList = append(usList, result)
 	}
+	if includeClusterResources {
+		// Runtime resources are removed first, before the CRDs of the WorkloadEntries.
+		runtimeList, err := GetRuntimeResources(clt)
+		if err != nil {
+			return nil, err
+		}
+		usList = append(runtimeList, usList...)
+	}
 
 	return usList, nil
 }
@@ -59,6 +59,7 @@ func ApplyClusterMerge(pctx networking.EnvoyFilter_PatchContext, efw *model.Envo
 			if !tsMerged {
 				merge.Merge(c, cp.Value)
 			}
+			c.Metadata = util.AddEnvoyFilterToMetadata(c.Metadata, cp.Namespace, cp.Name)
 		}
 		IncrementEnvoyFilterMetric(cp.Key(), Cluster, applied)
 	}
@@ -151,7 +152,9 @@ func InsertedClusters(pctx networking.EnvoyFilter_PatchContext, efw *model.Envoy
 				continue
 			}
 			if commonConditionMatch(pctx, cp) {
-				result = append(result, proto.Clone(cp.Value).(*cluster.Cluster))
+				c := proto.Clone(cp.Value).(*cluster.Cluster)
+				c.Metadata = util.AddEnvoyFilterToMetadata(c.Metadata, cp.Namespace, cp.Name)
+				result = append(result, c)
 			}
 		}
 	}
@@ -15,6 +15,7 @@
 package envoyfilter
 
 import (
+	"fmt"
 	"testing"
 
 	udpa "github.com/cncf/xds/go/udpa/type/v1"
@@ -26,6 +27,7 @@ import (
 
 	networking "istio.io/api/networking/v1alpha3"
 	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/networking/util"
 	"istio.io/istio/pilot/pkg/serviceregistry/memory"
 	"istio.io/istio/pilot/pkg/util/protoconv"
 	"istio.io/istio/pkg/config/host"
@@ -398,6 +400,7 @@ func TestClusterPatching(t *testing.T) {
 	sidecarOutboundOut := []*cluster.Cluster{
 		{
 			Name:            "outbound|443||cluster1",
+			Metadata:        envoyFilterMetadata(5, 6, 7),
 			DnsLookupFamily: cluster.Cluster_V6_ONLY,
 			LbPolicy:        cluster.Cluster_RING_HASH,
 			TransportSocketMatches: []*cluster.Cluster_TransportSocketMatch{
@@ -424,7 +427,8 @@ func TestClusterPatching(t *testing.T) {
 			},
 		},
 		{
-			Name: "outbound|443||cluster2",
+			Name:     "outbound|443||cluster2",
+			Metadata: envoyFilterMetadata(5, 6, 7),
 			Http2ProtocolOptions: &core.Http2ProtocolOptions{
 				AllowConnect:  true,
 				AllowMetadata: true,
@@ -446,6 +450,7 @@ func TestClusterPatching(t *testing.T) {
 		},
 		{
 			Name:            "outbound|443||cluster3",
+			Metadata:        envoyFilterMetadata(5, 6, 7),
 			DnsLookupFamily: cluster.Cluster_V6_ONLY,
 			LbPolicy:        cluster.Cluster_RING_HASH,
 			TransportSocket: &core.TransportSocket{
@@ -469,6 +474,7 @@ func TestClusterPatching(t *testing.T) {
 		},
 		{
 			Name:            "outbound|7777||custom-tls-addition",
+			Metadata:        envoyFilterMetadata(5, 6, 8),
 			DnsLookupFamily: cluster.Cluster_V6_ONLY,
 			LbPolicy:        cluster.Cluster_RING_HASH,
 			TransportSocket: &core.TransportSocket{
@@ -483,6 +489,7 @@ func TestClusterPatching(t *testing.T) {
 		},
 		{
 			Name:            "outbound|7777||custom-tls-replacement",
+			Metadata:        envoyFilterMetadata(5, 6, 8),
 			DnsLookupFamily: cluster.Cluster_V6_ONLY,
 			LbPolicy:        cluster.Cluster_RING_HASH,
 			TransportSocket: &core.TransportSocket{
@@ -497,6 +504,7 @@ func TestClusterPatching(t *testing.T) {
 		},
 		{
 			Name:            "outbound|7777||custom-tls-replacement-tsm",
+			Metadata:        envoyFilterMetadata(5, 6, 8),
 			DnsLookupFamily: cluster.Cluster_V6_ONLY,
 			LbPolicy:        cluster.Cluster_RING_HASH,
 			TransportSocketMatches: []*cluster.Cluster_TransportSocketMatch{
@@ -522,8 +530,8 @@ func TestClusterPatching(t *testing.T) {
 				},
 			},
 		},
-		{Name: "new-cluster1"},
-		{Name: "new-cluster2"},
+		{Name: "new-cluster1", Metadata: envoyFilterMetadata(0)},
+		{Name: "new-cluster2", Metadata: envoyFilterMetadata(1)},
 	}
 
 	sidecarInboundIn := []*cluster.Cluster{
@@ -531,7 +539,10 @@ func TestClusterPatching(t *testing.T) {
 		{Name: "inbound|9999||mgmtCluster"},
 	}
 	sidecarInboundOut := []*cluster.Cluster{
-		{Name: "cluster1", DnsLookupFamily: cluster.Cluster_V6_ONLY, LbPolicy: cluster.Cluster_RING_HASH},
+		{
+			Name: "cluster1", DnsLookupFamily: cluster.Cluster_V6_ONLY, LbPolicy: cluster.Cluster_RING_HASH,
+			Metadata: envoyFilterMetadata(5, 6),
+		},
 	}
 
 	sidecarInboundServiceIn := []*cluster.Cluster{
@@ -541,6 +552,7 @@ func TestClusterPatching(t *testing.T) {
 		{
 			Name: "inbound|7443||", ClusterDiscoveryType: &cluster.Cluster_Type{Type: cluster.Cluster_EDS},
 			DnsLookupFamily: cluster.Cluster_V6_ONLY, LbPolicy: cluster.Cluster_RING_HASH,
+			Metadata: envoyFilterMetadata(4, 5, 6),
 		},
 	}
 
@@ -556,13 +568,17 @@ func TestClusterPatching(t *testing.T) {
 		{Name: "outbound|443||gateway.com"},
 	}
 	gatewayOutput := []*cluster.Cluster{
-		{Name: "cluster1", DnsLookupFamily: cluster.Cluster_V6_ONLY, LbPolicy: cluster.Cluster_RING_HASH},
+		{
+			Name: "cluster1", DnsLookupFamily: cluster.Cluster_V6_ONLY, LbPolicy: cluster.Cluster_RING_HASH,
+			Metadata: envoyFilterMetadata(5, 6),
+		},
 		{
 			Name: "cluster2",
 			Http2ProtocolOptions: &core.Http2ProtocolOptions{
 				AllowConnect:  true,
 				AllowMetadata: true,
 			}, LbPolicy: cluster.Cluster_RING_HASH, DnsLookupFamily: cluster.Cluster_V6_ONLY,
+			Metadata: envoyFilterMetadata(5, 6),
 		},
 	}
 
@@ -626,3 +642,12 @@ func TestClusterPatching(t *testing.T) {
 		})
 	}
 }
+
+// envoyFilterMetadata builds the cluster metadata recorded for the given test EnvoyFilters, in patch order.
+func envoyFilterMetadata(indexes ...int) *core.Metadata {
+	var md *core.Metadata
+	for _, i := range indexes {
+		md = util.AddEnvoyFilterToMetadata(md, "not-default", fmt.Sprintf("test-envoyfilter-%d", i))
+	}
+	return md
+}
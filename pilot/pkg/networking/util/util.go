@@ -46,6 +46,7 @@ import (
 	"istio.io/istio/pilot/pkg/util/protoconv"
 	"istio.io/istio/pkg/config"
 	"istio.io/istio/pkg/config/constants"
+	"istio.io/istio/pkg/config/schema/gvk"
 	kubelabels "istio.io/istio/pkg/kube/labels"
 	"istio.io/istio/pkg/log"
 	pm "istio.io/istio/pkg/model"
@@ -76,6 +77,10 @@ const (
 	// regarding the virtual service or destination rule used for each
 	IstioMetadataKey = "istio"
 
+	// EnvoyFiltersMetadataKey is the key under the "istio" metadata listing the EnvoyFilters
+	// that patched a cluster.
+	EnvoyFiltersMetadataKey = "envoy_filters"
+
 	// EnvoyTransportSocketMetadataKey is the key under which metadata is added to an endpoint
 	// which determines the endpoint level transport socket configuration.
 	EnvoyTransportSocketMetadataKey = "envoy.transport_socket_match"
@@ -388,6 +393,40 @@ func AddSubsetToMetadata(md *core.Metadata, subset string) {
 	}
 }
 
+// AddEnvoyFilterToMetadata records the path of an EnvoyFilter that patched the resource under the
+// "envoy_filters" key of the "istio" metadata, so tooling can attribute settings to the EnvoyFilter.
+// If metadata is not initialized, builds a new metadata.
+func AddEnvoyFilterToMetadata(metadata *core.Metadata, namespace, name string) *core.Metadata {
+	if metadata == nil {
+		metadata = &core.Metadata{
+			FilterMetadata: map[string]*structpb.Struct{},
+		}
+	}
+	if metadata.FilterMetadata == nil {
+		metadata.FilterMetadata = map[string]*structpb.Struct{}
+	}
+	if _, ok := metadata.FilterMetadata[IstioMetadataKey]; !ok {
+		metadata.FilterMetadata[IstioMetadataKey] = &structpb.Struct{
+			Fields: map[string]*structpb.Value{},
+		}
+	}
+	s := "/apis/" + gvk.EnvoyFilter.Group + "/" + gvk.EnvoyFilter.Version + "/namespaces/" + namespace + "/" +
+		strcase.CamelCaseToKebabCase(gvk.EnvoyFilter.Kind) + "/" + name
+	fields := metadata.FilterMetadata[IstioMetadataKey].Fields
+	existing := fields[EnvoyFiltersMetadataKey].GetListValue()
+	if existing == nil {
+		existing = &structpb.ListValue{}
+	}
+	for _, v := range existing.Values {
+		if v.GetStringValue() == s {
+			return metadata
+		}
+	}
+	existing.Values = append(existing.Values, structpb.NewStringValue(s))
+	fields[EnvoyFiltersMetadataKey] = structpb.NewListValue(existing)
+	return metadata
+}
+
 // AddALPNOverrideToMetadata sets filter metadata `istio.alpn_override: "false"` in the given core.Metadata struct,
 // when TLS mode is SIMPLE or MUTUAL. If metadata is not initialized, builds a new metadata.
 func AddALPNOverrideToMetadata(metadata *core.Metadata, tlsMode networking.ClientTLSSettings_TLSmode) *core.Metadata {
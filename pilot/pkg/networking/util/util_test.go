@@ -568,6 +568,70 @@ func TestAddConfigInfoMetadata(t *testing.T) {
 	}
 }
 
+func TestAddEnvoyFilterToMetadata(t *testing.T) {
+	ef := func(name string) *structpb.Value {
+		return structpb.NewStringValue("/apis/networking.istio.io/v1alpha3/namespaces/default/envoy-filter/" + name)
+	}
+	cases := []struct {
+		name  string
+		in    *core.Metadata
+		added []string
+		want  *core.Metadata
+	}{
+		{
+			"nil metadata",
+			nil,
+			[]string{"ef-a"},
+			&core.Metadata{
+				FilterMetadata: map[string]*structpb.Struct{
+					IstioMetadataKey: {
+						Fields: map[string]*structpb.Value{
+							EnvoyFiltersMetadataKey: structpb.NewListValue(&structpb.ListValue{Values: []*structpb.Value{ef("ef-a")}}),
+						},
+					},
+				},
+			},
+		},
+		{
+			"existing config is kept, duplicates are dropped",
+			&core.Metadata{
+				FilterMetadata: map[string]*structpb.Struct{
+					IstioMetadataKey: {
+						Fields: map[string]*structpb.Value{
+							"config": structpb.NewStringValue("/apis/networking.istio.io/v1/namespaces/default/destination-rule/svcA"),
+						},
+					},
+				},
+			},
+			[]string{"ef-a", "ef-b", "ef-a"},
+			&core.Metadata{
+				FilterMetadata: map[string]*structpb.Struct{
+					IstioMetadataKey: {
+						Fields: map[string]*structpb.Value{
+							"config": structpb.NewStringValue("/apis/networking.istio.io/v1/namespaces/default/destination-rule/svcA"),
+							EnvoyFiltersMetadataKey: structpb.NewListValue(&structpb.ListValue{Values: []*structpb.Value{
+								ef("ef-a"), ef("ef-b"),
+							}}),
+						},
+					},
+				},
+			},
+		},
+	}
+
+	for _, v := range cases {
+		t.Run(v.name, func(t *testing.T) {
+			got := v.in
+			for _, name := range v.added {
+				got = AddEnvoyFilterToMetadata(got, "default", name)
+			}
+			if diff := cmp.Diff(got, v.want, protocmp.Transform()); diff != "" {
+				t.Errorf("AddEnvoyFilterToMetadata produced incorrect result:\n%s", diff)
+			}
+		})
+	}
+}
+
 func TestAddSubsetToMetadata(t *testing.T) {
 	cases := []struct {
 		name   string
@@ -16,6 +16,7 @@ package analysis
 
 import (
 	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/analysis/diag"
 	"istio.io/istio/pkg/config/analysis/scope"
 	"istio.io/istio/pkg/config/schema/collection"
 	"istio.io/istio/pkg/util/sets"
@@ -66,8 +67,9 @@ func (c *InternalCombinedAnalyzer) RelevantSubset(kinds sets.Set[config.GroupVer
 // Metadata implements Analyzer
 func (c *InternalCombinedAnalyzer) Metadata() Metadata {
 	return Metadata{
-		Name:   c.name,
-		Inputs: combineInputs(c.analyzers),
+		Name:     c.name,
+		Inputs:   combineInputs(c.analyzers),
+		Messages: combineMessages(c.analyzers),
 	}
 }
 
@@ -132,3 +134,11 @@ func combineInputs(analyzers []Analyzer) []config.GroupVersionKind {
 	}
 	return result.UnsortedList()
 }
+
+func combineMessages(analyzers []Analyzer) []*diag.MessageType {
+	result := sets.New[*diag.MessageType]()
+	for _, a := range analyzers {
+		result.InsertAll(a.Metadata().Messages...)
+	}
+	return result.UnsortedList()
+}
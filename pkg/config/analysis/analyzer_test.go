@@ -27,16 +27,18 @@ import (
 )
 
 type analyzer struct {
-	name   string
-	inputs []config.GroupVersionKind
-	ran    bool
+	name     string
+	inputs   []config.GroupVersionKind
+	messages []*diag.MessageType
+	ran      bool
 }
 
 // Metadata implements Analyzer
 func (a *analyzer) Metadata() Metadata {
 	return Metadata{
-		Name:   a.name,
-		Inputs: a.inputs,
+		Name:     a.name,
+		Inputs:   a.inputs,
+		Messages: a.messages,
 	}
 }
 
@@ -83,6 +85,30 @@ func TestCombinedAnalyzer(t *testing.T) {
 	g.Expect(a4.ran).To(BeFalse())
 }
 
+func TestCatalog(t *testing.T) {
+	g := NewWithT(t)
+
+	col1 := newSchema("col1")
+	warning := diag.NewMessageType(diag.Warning, "IST0002", "Warning %s")
+	err := diag.NewMessageType(diag.Error, "IST0001", "Error %s")
+
+	a1 := &analyzer{name: "b", inputs: []config.GroupVersionKind{col1.GroupVersionKind()}, messages: []*diag.MessageType{warning, err}}
+	a2 := &analyzer{name: "a"}
+
+	g.Expect(Combine("combined", a1, a2).Metadata().Messages).To(ConsistOf(warning, err))
+	g.Expect(Catalog(a1, a2, a1)).To(Equal([]AnalyzerInfo{
+		{Name: "a", Inputs: []string{}, Messages: []MessageInfo{}},
+		{
+			Name:   "b",
+			Inputs: []string{col1.GroupVersionKind().String()},
+			Messages: []MessageInfo{
+				{Code: "IST0001", Level: "Error", Template: "Error %s"},
+				{Code: "IST0002", Level: "Warning", Template: "Warning %s"},
+			},
+		},
+	}))
+}
+
 func newSchema(name string) resource2.Schema {
 	return resource2.Builder{
 		Kind:         name,
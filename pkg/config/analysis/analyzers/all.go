@@ -33,6 +33,7 @@ import (
 	"istio.io/istio/pkg/config/analysis/analyzers/sidecar"
 	"istio.io/istio/pkg/config/analysis/analyzers/telemetry"
 	"istio.io/istio/pkg/config/analysis/analyzers/virtualservice"
+	"istio.io/istio/pkg/config/analysis/analyzers/wasmplugin"
 	"istio.io/istio/pkg/config/analysis/analyzers/webhook"
 )
 
@@ -42,8 +43,10 @@ func All() []analysis.Analyzer {
 		// Please keep this list sorted alphabetically by pkg.name for convenience
 		&annotations.K8sAnalyzer{},
 		&authz.AuthorizationPoliciesAnalyzer{},
+		&authz.CoverageAnalyzer{},
 		&deployment.ServiceAssociationAnalyzer{},
 		&deployment.ApplicationUIDAnalyzer{},
+		&deployment.ProxyStartupOrderAnalyzer{},
 		&deprecation.FieldAnalyzer{},
 		&externalcontrolplane.ExternalControlPlaneAnalyzer{},
 		&gateway.IngressGatewayPortAnalyzer{},
@@ -54,15 +57,20 @@ func All() []analysis.Analyzer {
 		&injection.ImageAnalyzer{},
 		&injection.ImageAutoAnalyzer{},
 		&k8sgateway.SelectorAnalyzer{},
+		&k8sgateway.RouteAnalyzer{},
+		&k8sgateway.ListenerConflictAnalyzer{},
 		&multicluster.MeshNetworksAnalyzer{},
 		&service.PortNameAnalyzer{},
 		&sidecar.SelectorAnalyzer{},
+		&sidecar.ScopeAnalyzer{},
 		&virtualservice.ConflictingMeshGatewayHostsAnalyzer{},
 		&virtualservice.DestinationHostAnalyzer{},
 		&virtualservice.DestinationRuleAnalyzer{},
 		&virtualservice.GatewayAnalyzer{},
 		&virtualservice.JWTClaimRouteAnalyzer{},
+		&virtualservice.WeightedRouteAnalyzer{},
 		&destinationrule.CaCertificateAnalyzer{},
+		&destinationrule.MergeAnalyzer{},
 		&serviceentry.ProtocolAddressesAnalyzer{},
 		&webhook.Analyzer{},
 		&envoyfilter.EnvoyPatchAnalyzer{},
@@ -70,6 +78,8 @@ func All() []analysis.Analyzer {
 		&telemetry.SelectorAnalyzer{},
 		&telemetry.DefaultSelectorAnalyzer{},
 		&telemetry.LightstepAnalyzer{},
+		&telemetry.ConflictAnalyzer{},
+		&wasmplugin.Analyzer{},
 		&multicluster.ServiceAnalyzer{},
 	}
 
@@ -43,6 +43,7 @@ import (
 	"istio.io/istio/pkg/config/analysis/analyzers/sidecar"
 	"istio.io/istio/pkg/config/analysis/analyzers/telemetry"
 	"istio.io/istio/pkg/config/analysis/analyzers/virtualservice"
+	"istio.io/istio/pkg/config/analysis/analyzers/wasmplugin"
 	"istio.io/istio/pkg/config/analysis/analyzers/webhook"
 	"istio.io/istio/pkg/config/analysis/diag"
 	"istio.io/istio/pkg/config/analysis/local"
@@ -278,6 +279,14 @@ var testGrid = []testCase{
 			{msg.NamespaceMultipleInjectionLabels, "Namespace multi-ns-3"},
 		},
 	},
+	{
+		name:       "istioInjectionRevisionNotFound",
+		inputFiles: []string{"testdata/injection-revision-not-found.yaml"},
+		analyzer:   &injection.Analyzer{},
+		expected: []message{
+			{msg.NamespaceRevisionNotFound, "Namespace removed-revision"},
+		},
+	},
 	{
 		name: "istioInjectionEnableNamespacesByDefault",
 		inputFiles: []string{
@@ -386,6 +395,15 @@ var testGrid = []testCase{
 			{msg.IneffectivePolicy, "Sidecar ns-ambient/pod-scoped"},
 		},
 	},
+	{
+		name:       "sidecarScope",
+		inputFiles: []string{"testdata/sidecar-scope.yaml"},
+		analyzer:   &sidecar.ScopeAnalyzer{MinVisibleServices: 4},
+		expected: []message{
+			{msg.UnscopedSidecarConfig, "Namespace frontend"},
+			{msg.UnscopedSidecarConfig, "Namespace batch"},
+		},
+	},
 	{
 		name:       "sidecarSelector",
 		inputFiles: []string{"testdata/sidecar-selector.yaml"},
@@ -450,6 +468,15 @@ var testGrid = []testCase{
 			{msg.ReferencedResourceNotFound, "VirtualService default/reviews-mirror-bogussubset"},
 		},
 	},
+	{
+		name:       "virtualServiceWeightedRoutes",
+		inputFiles: []string{"testdata/virtualservice_weightedroutes.yaml"},
+		analyzer:   &virtualservice.WeightedRouteAnalyzer{},
+		expected: []message{
+			{msg.VirtualServiceRouteWeightSum, "VirtualService default/reviews-weight-sum"},
+			{msg.UnreachableSubset, "VirtualService default/reviews-unreachable"},
+		},
+	},
 	{
 		name:       "virtualServiceGateways",
 		inputFiles: []string{"testdata/virtualservice_gateways.yaml"},
@@ -529,6 +556,18 @@ var testGrid = []testCase{
 			{msg.NoMatchingWorkloadsFound, "AuthorizationPolicy test-ambient/no-workload"},
 		},
 	},
+	{
+		name: "authorizationpolicy coverage",
+		inputFiles: []string{
+			"testdata/authorizationpolicy-coverage.yaml",
+		},
+		analyzer: &authz.CoverageAnalyzer{},
+		expected: []message{
+			{msg.WorkloadNotCoveredByAuthorizationPolicy, "Service shop/cart"},
+			{msg.AuthorizationPolicyShadowedByDeny, "AuthorizationPolicy shop/payments"},
+			{msg.AuthorizationPolicyShadowedByDeny, "AuthorizationPolicy blog/blog-admin"},
+		},
+	},
 	{
 		name: "destinationrule with no cacert, simple at destinationlevel",
 		inputFiles: []string{
@@ -805,6 +844,31 @@ var testGrid = []testCase{
 			{msg.InvalidApplicationUID, "Deployment deploy-con-sec-uid"},
 		},
 	},
+	{
+		name: "Proxy startup ordering missing for init-heavy workloads",
+		inputFiles: []string{
+			"testdata/proxy-startup-order.yaml",
+		},
+		analyzer: &deployment.ProxyStartupOrderAnalyzer{},
+		expected: []message{
+			{msg.ProxyStartupOrderingMissing, "Pod default/init-wait-for-db"},
+			{msg.ProxyStartupOrderingMissing, "Pod default/flyway-no-hold"},
+			{msg.ProxyStartupOrderingMissing, "Deployment default/liquibase-no-hold"},
+		},
+	},
+	{
+		name: "Proxy startup ordering missing for user-provided startup images",
+		inputFiles: []string{
+			"testdata/proxy-startup-order.yaml",
+		},
+		analyzer: &deployment.ProxyStartupOrderAnalyzer{StartupImages: []string{"db-seeder"}},
+		expected: []message{
+			{msg.ProxyStartupOrderingMissing, "Pod default/init-wait-for-db"},
+			{msg.ProxyStartupOrderingMissing, "Pod default/flyway-no-hold"},
+			{msg.ProxyStartupOrderingMissing, "Pod default/custom-startup-image"},
+			{msg.ProxyStartupOrderingMissing, "Deployment default/liquibase-no-hold"},
+		},
+	},
 	{
 		name: "Detect `image: auto` in non-injected pods",
 		inputFiles: []string{
@@ -944,6 +1008,45 @@ var testGrid = []testCase{
 			{msg.Deprecated, "Telemetry istio-system/mesh-default"},
 		},
 	},
+	{
+		name:           "Telemetry conflicts",
+		inputFiles:     []string{"testdata/telemetry-conflict.yaml"},
+		analyzer:       &telemetry.ConflictAnalyzer{},
+		meshConfigFile: "testdata/telemetry-conflict-meshconfig.yaml",
+		expected: []message{
+			{msg.TelemetrySettingsOverridden, "Telemetry bookinfo/sampling"},
+			{msg.ConflictingTelemetrySettings, "Telemetry bookinfo/gateway-tracing"},
+			{msg.ConflictingTelemetrySettings, "Telemetry bookinfo/gateway-debug"},
+			{msg.TelemetryProviderNotFound, "Telemetry ratings/datadog-logging"},
+		},
+	},
+	{
+		name:       "wasmPlugin",
+		inputFiles: []string{"testdata/wasmplugin.yaml"},
+		analyzer:   &wasmplugin.Analyzer{},
+		expected: []message{
+			{msg.WasmPluginImageUnpullable, "WasmPlugin bookinfo/private"},
+			{msg.WasmPluginImageUnpullable, "WasmPlugin bookinfo/pinned"},
+			{msg.WasmPluginImageUnpullable, "WasmPlugin bookinfo/ftp"},
+			{msg.WasmPluginOrderingConflict, "WasmPlugin bookinfo/rate-limit"},
+			{msg.WasmPluginOrderingConflict, "WasmPlugin bookinfo/auth-headers"},
+			{msg.WasmPluginOrderingConflict, "WasmPlugin bookinfo/rate-limit"},
+			{msg.WasmPluginOrderingConflict, "WasmPlugin bookinfo/rate-limit"},
+			{msg.WasmPluginOrderingConflict, "WasmPlugin bookinfo/egress-headers"},
+			{msg.WasmPluginOrderingConflict, "WasmPlugin bookinfo/reviews-headers"},
+			{msg.WasmPluginUnsupportedProxy, "WasmPlugin bookinfo/tcp-stats"},
+			{msg.WasmPluginUnsupportedProxy, "WasmPlugin ambient/ratings-headers"},
+		},
+	},
+	{
+		name:       "destinationRuleMerge",
+		inputFiles: []string{"testdata/destinationrule-merge.yaml"},
+		analyzer:   &destinationrule.MergeAnalyzer{},
+		expected: []message{
+			{msg.DestinationRuleTrafficPolicyDiscarded, "DestinationRule bookinfo/reviews-outlier"},
+			{msg.DestinationRuleTrafficPolicyDiscarded, "DestinationRule istio-system/default"},
+		},
+	},
 	{
 		name:       "KubernetesGatewaySelector",
 		inputFiles: []string{"testdata/k8sgateway-selector.yaml"},
@@ -955,6 +1058,25 @@ var testGrid = []testCase{
 			{msg.IneffectiveSelector, "Telemetry default/telemetry-ineffective"},
 		},
 	},
+	{
+		name:       "KubernetesGatewayRoutes",
+		inputFiles: []string{"testdata/k8sgateway-routes.yaml"},
+		analyzer:   &k8sgateway.RouteAnalyzer{},
+		expected: []message{
+			{msg.ReferencedResourceNotFound, "HTTPRoute default/missing-gateway"},
+			{msg.BackendReferenceNotPermitted, "HTTPRoute default/not-permitted"},
+			{msg.ReferencedResourceNotFound, "HTTPRoute default/missing-backend"},
+			{msg.BackendPortNotFound, "HTTPRoute default/wrong-port"},
+		},
+	},
+	{
+		name:       "KubernetesGatewayListenerConflict",
+		inputFiles: []string{"testdata/k8sgateway-listener-conflict.yaml"},
+		analyzer:   &k8sgateway.ListenerConflictAnalyzer{},
+		expected: []message{
+			{msg.GatewayListenerConflict, "Gateway default/gateway"},
+		},
+	},
 	{
 		name:       "ServiceEntry Addresses Required Lowercase Protocol",
 		inputFiles: []string{"testdata/serviceentry-address-required-lowercase.yaml"},
@@ -984,6 +1106,7 @@ var ignoreAnalyzers = []string{
 // TestAnalyzers allows for table-based testing of Analyzers.
 func TestAnalyzers(t *testing.T) {
 	requestedInputsByAnalyzer := make(map[string]map[config.GroupVersionKind]struct{})
+	reportedMessagesByAnalyzer := make(map[string]sets.Set[*diag.MessageType])
 
 	// For each test case, verify we get the expected messages as output
 	for _, tc := range testGrid {
@@ -1012,6 +1135,12 @@ func TestAnalyzers(t *testing.T) {
 			}
 
 			g.Expect(extractFields(result.Messages)).To(ConsistOf(tc.expected), "%v", prettyPrintMessages(result.Messages))
+			if _, ok := reportedMessagesByAnalyzer[analyzerName]; !ok {
+				reportedMessagesByAnalyzer[analyzerName] = sets.New[*diag.MessageType]()
+			}
+			for _, m := range result.Messages {
+				reportedMessagesByAnalyzer[analyzerName].Insert(m.Type)
+			}
 		})
 	}
 
@@ -1054,6 +1183,19 @@ func TestAnalyzers(t *testing.T) {
 					"Either the metadata is wrong or the test cases for the analyzer are insufficient.", analyzerName))
 		}
 	})
+
+	// Verify that the messages reported during testing are declared in the metadata of the analyzers, as they are
+	// listed in the catalog of analyzers
+	t.Run("CheckMetadataMessages", func(t *testing.T) {
+		g := NewWithT(t)
+		for _, tc := range testGrid {
+			analyzerName := tc.analyzer.Metadata().Name
+			for m := range reportedMessagesByAnalyzer[analyzerName] {
+				g.Expect(tc.analyzer.Metadata().Messages).To(ContainElement(m), fmt.Sprintf(
+					"Message %s reported by analyzer %q is not declared in its metadata.", m.Code(), analyzerName))
+			}
+		}
+	})
 }
 
 // Verify that all of the analyzers tested here are also registered in All()
@@ -23,6 +23,7 @@ import (
 	"istio.io/istio/pkg/config/analysis"
 	"istio.io/istio/pkg/config/analysis/analyzers/maturity"
 	"istio.io/istio/pkg/config/analysis/analyzers/util"
+	"istio.io/istio/pkg/config/analysis/diag"
 	"istio.io/istio/pkg/config/analysis/msg"
 	"istio.io/istio/pkg/config/resource"
 	"istio.io/istio/pkg/config/schema/gvk"
@@ -46,6 +47,12 @@ func (*K8sAnalyzer) Metadata() analysis.Metadata {
 			gvk.Pod,
 			gvk.Deployment,
 		},
+		Messages: []*diag.MessageType{
+			msg.DeprecatedAnnotation,
+			msg.InvalidAnnotation,
+			msg.MisplacedAnnotation,
+			msg.UnknownAnnotation,
+		},
 	}
 }
 
@@ -25,6 +25,7 @@ import (
 	"istio.io/istio/pkg/config"
 	"istio.io/istio/pkg/config/analysis"
 	"istio.io/istio/pkg/config/analysis/analyzers/util"
+	"istio.io/istio/pkg/config/analysis/diag"
 	"istio.io/istio/pkg/config/analysis/msg"
 	"istio.io/istio/pkg/config/resource"
 	"istio.io/istio/pkg/config/schema/gvk"
@@ -48,6 +49,10 @@ func (a *AuthorizationPoliciesAnalyzer) Metadata() analysis.Metadata {
 			gvk.Namespace,
 			gvk.Pod,
 		},
+		Messages: []*diag.MessageType{
+			msg.NoMatchingWorkloadsFound,
+			msg.ReferencedResourceNotFound,
+		},
 	}
 }
 
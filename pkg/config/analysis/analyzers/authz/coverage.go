@@ -0,0 +1,192 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authz
+
+import (
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	klabels "k8s.io/apimachinery/pkg/labels"
+
+	"istio.io/api/mesh/v1alpha1"
+	"istio.io/api/security/v1beta1"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/analysis"
+	"istio.io/istio/pkg/config/analysis/analyzers/util"
+	"istio.io/istio/pkg/config/analysis/diag"
+	"istio.io/istio/pkg/config/analysis/msg"
+	"istio.io/istio/pkg/config/resource"
+	"istio.io/istio/pkg/config/schema/gvk"
+	"istio.io/istio/pkg/util/sets"
+)
+
+// CoverageAnalyzer finds the gaps in the coverage of authorization policies: services of a namespace left open while
+// other workloads of the namespace are locked down, and ALLOW policies shadowed by a DENY policy denying all requests.
+type CoverageAnalyzer struct{}
+
+var _ analysis.Analyzer = &CoverageAnalyzer{}
+
+func (a *CoverageAnalyzer) Metadata() analysis.Metadata {
+	return analysis.Metadata{
+		Name:        "auth.CoverageAnalyzer",
+		Description: "Checks for services without authorization policies and ALLOW policies shadowed by DENY policies",
+		Inputs: []config.GroupVersionKind{
+			gvk.MeshConfig,
+			gvk.AuthorizationPolicy,
+			gvk.Namespace,
+			gvk.Pod,
+			gvk.Service,
+		},
+		Messages: []*diag.MessageType{
+			msg.AuthorizationPolicyShadowedByDeny,
+			msg.WorkloadNotCoveredByAuthorizationPolicy,
+		},
+	}
+}
+
+// policy is an authorization policy applying to workloads selected by labels.
+type policy struct {
+	r      *resource.Instance
+	ap     *v1beta1.AuthorizationPolicy
+	name   string
+	ns     string
+	global bool
+}
+
+// applies returns whether the policy applies to the workload with the labels, in the namespace.
+func (p policy) applies(ns string, labels klabels.Set) bool {
+	if !p.global && p.ns != ns {
+		return false
+	}
+	return p.ap.GetSelector() == nil || klabels.SelectorFromSet(p.ap.GetSelector().MatchLabels).Matches(labels)
+}
+
+// covers returns whether the policy applies to all the workloads the other policy applies to.
+func (p policy) covers(other policy) bool {
+	if !p.global && (other.global || p.ns != other.ns) {
+		return false
+	}
+	if p.ap.GetSelector() == nil {
+		return true
+	}
+	// The workloads of the other policy all carry its labels, so they are selected if these include ours.
+	return other.ap.GetSelector() != nil && klabels.SelectorFromSet(p.ap.GetSelector().MatchLabels).
+		Matches(klabels.Set(other.ap.GetSelector().MatchLabels))
+}
+
+func (a *CoverageAnalyzer) Analyze(c analysis.Context) {
+	rootNamespace := ""
+	c.ForEach(gvk.MeshConfig, func(r *resource.Instance) bool {
+		rootNamespace = r.Message.(*v1alpha1.MeshConfig).GetRootNamespace()
+		return r.Metadata.FullName.Name != util.MeshConfigName
+	})
+	var policies []policy
+	// Policies attached to waypoints and gateways with targetRefs do not select workloads, so the coverage of their
+	// namespaces is not known.
+	targetRefNamespaces := sets.New[string]()
+	c.ForEach(gvk.AuthorizationPolicy, func(r *resource.Instance) bool {
+		ap := r.Message.(*v1beta1.AuthorizationPolicy)
+		ns := r.Metadata.FullName.Namespace.String()
+		if ap.GetTargetRef() != nil || len(ap.GetTargetRefs()) > 0 {
+			targetRefNamespaces.Insert(ns)
+			return true
+		}
+		policies = append(policies, policy{r: r, ap: ap, name: r.Metadata.FullName.String(), ns: ns, global: ns == rootNamespace})
+		return true
+	})
+
+	a.analyzeUncoveredServices(c, policies, targetRefNamespaces)
+	a.analyzeShadowedPolicies(c, policies)
+}
+
+func (a *CoverageAnalyzer) analyzeUncoveredServices(c analysis.Context, policies []policy, targetRefNamespaces sets.String) {
+	podLabelsMap := initPodLabelsMap(c)
+	c.ForEach(gvk.Service, func(r *resource.Instance) bool {
+		ns := r.Metadata.FullName.Namespace.String()
+		selector := r.Message.(*corev1.ServiceSpec).Selector
+		if len(selector) == 0 || targetRefNamespaces.Contains(ns) {
+			return true
+		}
+		serviceSelector := klabels.SelectorFromSet(selector)
+		var backends, others []klabels.Set
+		for _, labels := range podLabelsMap[ns] {
+			if serviceSelector.Matches(labels) {
+				backends = append(backends, labels)
+			} else {
+				others = append(others, labels)
+			}
+		}
+		if len(backends) == 0 {
+			return true
+		}
+		for _, labels := range backends {
+			for _, p := range policies {
+				if p.ap.GetAction() != v1beta1.AuthorizationPolicy_AUDIT && p.applies(ns, labels) {
+					return true
+				}
+			}
+		}
+		// Other workloads are locked down by ALLOW policies, which deny the requests they do not allow.
+		lockedDown := sets.New[string]()
+		for _, labels := range others {
+			for _, p := range policies {
+				if p.ap.GetAction() == v1beta1.AuthorizationPolicy_ALLOW && p.applies(ns, labels) {
+					lockedDown.Insert(p.name)
+				}
+			}
+		}
+		if len(lockedDown) > 0 {
+			names := sets.SortedList(lockedDown)
+			c.Report(gvk.Service, msg.NewWorkloadNotCoveredByAuthorizationPolicy(r, "AuthorizationPolicies "+strings.Join(names, ", ")))
+		}
+		return true
+	})
+}
+
+func (a *CoverageAnalyzer) analyzeShadowedPolicies(c analysis.Context, policies []policy) {
+	var denyAll []policy
+	for _, p := range policies {
+		if p.ap.GetAction() == v1beta1.AuthorizationPolicy_DENY && deniesAll(p.ap) {
+			denyAll = append(denyAll, p)
+		}
+	}
+	for _, p := range policies {
+		// ALLOW policies without rules allow no request in the first place.
+		if p.ap.GetAction() != v1beta1.AuthorizationPolicy_ALLOW || len(p.ap.GetRules()) == 0 {
+			continue
+		}
+		for _, deny := range denyAll {
+			if deny.covers(p) {
+				m := msg.NewAuthorizationPolicyShadowedByDeny(p.r, deny.name)
+				if line, ok := util.ErrorLine(p.r, util.MetadataName); ok {
+					m.Line = line
+				}
+				c.Report(gvk.AuthorizationPolicy, m)
+				break
+			}
+		}
+	}
+}
+
+// deniesAll returns whether the DENY policy has a rule matching every request, without source, operation or
+// condition.
+func deniesAll(ap *v1beta1.AuthorizationPolicy) bool {
+	for _, rule := range ap.GetRules() {
+		if len(rule.GetFrom()) == 0 && len(rule.GetTo()) == 0 && len(rule.GetWhen()) == 0 {
+			return true
+		}
+	}
+	return false
+}
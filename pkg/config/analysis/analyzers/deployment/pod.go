@@ -21,6 +21,7 @@ import (
 	"istio.io/istio/pkg/config"
 	"istio.io/istio/pkg/config/analysis"
 	"istio.io/istio/pkg/config/analysis/analyzers/util"
+	"istio.io/istio/pkg/config/analysis/diag"
 	"istio.io/istio/pkg/config/analysis/msg"
 	"istio.io/istio/pkg/config/resource"
 	"istio.io/istio/pkg/config/schema/gvk"
@@ -42,6 +43,7 @@ func (appUID *ApplicationUIDAnalyzer) Metadata() analysis.Metadata {
 			gvk.Pod,
 			gvk.Deployment,
 		},
+		Messages: []*diag.MessageType{msg.InvalidApplicationUID},
 	}
 }
 
@@ -26,6 +26,7 @@ import (
 	"istio.io/istio/pkg/config"
 	"istio.io/istio/pkg/config/analysis"
 	"istio.io/istio/pkg/config/analysis/analyzers/util"
+	"istio.io/istio/pkg/config/analysis/diag"
 	"istio.io/istio/pkg/config/analysis/msg"
 	"istio.io/istio/pkg/config/constants"
 	"istio.io/istio/pkg/config/resource"
@@ -58,6 +59,10 @@ func (s *ServiceAssociationAnalyzer) Metadata() analysis.Metadata {
 			gvk.Deployment,
 			gvk.Namespace,
 		},
+		Messages: []*diag.MessageType{
+			msg.DeploymentAssociatedToMultipleServices,
+			msg.DeploymentConflictingPorts,
+		},
 	}
 }
 
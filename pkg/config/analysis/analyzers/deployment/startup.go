@@ -0,0 +1,249 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deployment
+
+import (
+	"fmt"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	klabels "k8s.io/apimachinery/pkg/labels"
+
+	"istio.io/api/annotation"
+	"istio.io/api/label"
+	meshconfig "istio.io/api/mesh/v1alpha1"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/analysis"
+	"istio.io/istio/pkg/config/analysis/analyzers/util"
+	"istio.io/istio/pkg/config/analysis/diag"
+	"istio.io/istio/pkg/config/analysis/msg"
+	"istio.io/istio/pkg/config/constants"
+	"istio.io/istio/pkg/config/resource"
+	"istio.io/istio/pkg/config/schema/gvk"
+	"istio.io/istio/pkg/util/protomarshal"
+)
+
+// ProxyStartupOrderAnalyzer checks for workloads that are likely to make network calls at startup
+// without configuring the application to wait for the sidecar proxy.
+type ProxyStartupOrderAnalyzer struct {
+	// StartupImages is an optional list of additional image name fragments which are known to make
+	// network calls at startup, set by the --startup-images flag of istioctl analyze. It extends DefaultStartupImages.
+	StartupImages []string
+}
+
+var _ analysis.Analyzer = &ProxyStartupOrderAnalyzer{}
+
+// DefaultStartupImages is the list of image name fragments, typically database migration tools,
+// which are known to make network calls as soon as they start.
+var DefaultStartupImages = []string{
+	"flyway",
+	"liquibase",
+	"alembic",
+	"sqitch",
+}
+
+// istioInitContainers are the init containers added by the injector; they do not make network calls.
+var istioInitContainers = map[string]struct{}{
+	"istio-init":        {},
+	"istio-validation":  {},
+	util.IstioProxyName: {},
+}
+
+func (a *ProxyStartupOrderAnalyzer) Metadata() analysis.Metadata {
+	return analysis.Metadata{
+		Name:        "deployment.ProxyStartupOrderAnalyzer",
+		Description: "Checks for workloads that may make network calls before the sidecar proxy is ready",
+		Inputs: []config.GroupVersionKind{
+			gvk.Pod,
+			gvk.Deployment,
+			gvk.Namespace,
+			gvk.MeshConfig,
+		},
+		Messages: []*diag.MessageType{msg.ProxyStartupOrderingMissing},
+	}
+}
+
+func (a *ProxyStartupOrderAnalyzer) Analyze(c analysis.Context) {
+	meshHold := false
+	c.ForEach(gvk.MeshConfig, func(r *resource.Instance) bool {
+		mc := r.Message.(*meshconfig.MeshConfig)
+		meshHold = mc.GetDefaultConfig().GetHoldApplicationUntilProxyStarts().GetValue()
+		return r.Metadata.FullName.Name != util.MeshConfigName
+	})
+
+	// The pods of a Deployment are reported with their Deployment rather than one by one.
+	deployments := map[string][]klabels.Selector{}
+	c.ForEach(gvk.Deployment, func(r *resource.Instance) bool {
+		d := r.Message.(*appsv1.DeploymentSpec)
+		if selector, err := metav1.LabelSelectorAsSelector(d.Selector); err == nil && !selector.Empty() {
+			ns := r.Metadata.FullName.Namespace.String()
+			deployments[ns] = append(deployments[ns], selector)
+		}
+		return true
+	})
+
+	c.ForEach(gvk.Pod, func(r *resource.Instance) bool {
+		if util.IsIstioControlPlane(r) || inAmbientMode(r, r.Metadata.Labels, c) || !util.PodInMesh(r, c) {
+			return true
+		}
+		if ownedByDeployment(r, deployments[r.Metadata.FullName.Namespace.String()]) {
+			return true
+		}
+		pod := r.Message.(*v1.PodSpec)
+		if reason := a.startupRace(r.Metadata.Annotations, pod, meshHold); reason != "" {
+			c.Report(gvk.Pod, msg.NewProxyStartupOrderingMissing(r, r.Metadata.FullName.String(), reason))
+		}
+		return true
+	})
+
+	c.ForEach(gvk.Deployment, func(r *resource.Instance) bool {
+		d := r.Message.(*appsv1.DeploymentSpec)
+		if util.IsIstioControlPlane(r) || inAmbientMode(r, d.Template.Labels, c) || !util.DeploymentInMesh(r, c) {
+			return true
+		}
+		if reason := a.startupRace(d.Template.Annotations, &d.Template.Spec, meshHold); reason != "" {
+			c.Report(gvk.Deployment, msg.NewProxyStartupOrderingMissing(r, r.Metadata.FullName.String(), reason))
+		}
+		return true
+	})
+}
+
+// ownedByDeployment returns whether the pod was created by one of the Deployments, through a ReplicaSet which labels
+// its pods with the hash of their template.
+func ownedByDeployment(r *resource.Instance, deployments []klabels.Selector) bool {
+	if _, ok := r.Metadata.Labels[appsv1.DefaultDeploymentUniqueLabelKey]; !ok {
+		return false
+	}
+	for _, selector := range deployments {
+		if selector.Matches(klabels.Set(r.Metadata.Labels)) {
+			return true
+		}
+	}
+	return false
+}
+
+// startupRace returns a description of the startup race the pod is exposed to, or an empty string if
+// the pod is either not expected to make network calls at startup or has proxy startup ordering configured.
+func (a *ProxyStartupOrderAnalyzer) startupRace(annos map[string]string, pod *v1.PodSpec, meshHold bool) string {
+	if usesNativeSidecar(annos, pod) {
+		// The proxy runs as a native sidecar and is started (and ready) before all other containers.
+		return ""
+	}
+
+	for _, c := range pod.InitContainers {
+		if _, ok := istioInitContainers[c.Name]; ok || runsAsProxyUser(pod, c) {
+			// Traffic of the proxy user is not redirected to the proxy, so it does not depend on it being started.
+			continue
+		}
+		// Init containers run before the sidecar is started, so holdApplicationUntilProxyStarts has no effect on them.
+		if image := a.matchStartupImage(c.Image); image != "" {
+			return fmt.Sprintf("init container %q uses image %q which is known to connect to remote services as soon as it starts, "+
+				"and runs before the sidecar is started; enable native sidecars with the %s annotation, or exclude its traffic from redirection",
+				c.Name, c.Image, annotation.SidecarNativeSidecar.Name)
+		}
+		return fmt.Sprintf("init container %q runs before the sidecar is started and its traffic is redirected to a proxy "+
+			"that is not yet running; enable native sidecars with the %s annotation, or exclude its traffic from redirection",
+			c.Name, annotation.SidecarNativeSidecar.Name)
+	}
+
+	if meshHold || holdFromAnnotation(annos) || holdInjected(pod) {
+		return ""
+	}
+	for _, c := range pod.Containers {
+		if c.Name == util.IstioProxyName {
+			continue
+		}
+		if image := a.matchStartupImage(c.Image); image != "" {
+			return fmt.Sprintf("container %q uses image %q which is known to connect to remote services as soon as it starts; "+
+				"set holdApplicationUntilProxyStarts: true in the %s annotation so the application waits for the proxy",
+				c.Name, c.Image, annotation.ProxyConfig.Name)
+		}
+	}
+	return ""
+}
+
+func (a *ProxyStartupOrderAnalyzer) matchStartupImage(image string) string {
+	for _, candidates := range [][]string{DefaultStartupImages, a.StartupImages} {
+		for _, candidate := range candidates {
+			if candidate != "" && strings.Contains(image, candidate) {
+				return candidate
+			}
+		}
+	}
+	return ""
+}
+
+// inAmbientMode returns whether the workload is captured by ztunnel rather than by a sidecar, which has no startup
+// ordering to configure. Workloads explicitly injected with a sidecar are not captured by ztunnel.
+func inAmbientMode(r *resource.Instance, podLabels map[string]string, c analysis.Context) bool {
+	if util.PodInAmbientMode(r) {
+		return true
+	}
+	if podLabels[label.SidecarInject.Name] == "true" {
+		return false
+	}
+	switch podLabels[label.IoIstioDataplaneMode.Name] {
+	case constants.DataplaneModeAmbient:
+		return true
+	case constants.DataplaneModeNone:
+		return false
+	}
+	ns := c.Find(gvk.Namespace, resource.NewFullName("", resource.LocalName(r.Metadata.FullName.Namespace)))
+	return util.NamespaceInAmbientMode(ns)
+}
+
+// runsAsProxyUser returns whether the container runs as the user of the proxy, whose traffic is not redirected.
+func runsAsProxyUser(pod *v1.PodSpec, c v1.Container) bool {
+	if c.SecurityContext != nil && c.SecurityContext.RunAsUser != nil {
+		return *c.SecurityContext.RunAsUser == UserID
+	}
+	return pod.SecurityContext != nil && pod.SecurityContext.RunAsUser != nil && *pod.SecurityContext.RunAsUser == UserID
+}
+
+func usesNativeSidecar(annos map[string]string, pod *v1.PodSpec) bool {
+	if annos[annotation.SidecarNativeSidecar.Name] == "true" {
+		return true
+	}
+	for _, c := range pod.InitContainers {
+		if c.Name == util.IstioProxyName && c.RestartPolicy != nil && *c.RestartPolicy == v1.ContainerRestartPolicyAlways {
+			return true
+		}
+	}
+	return false
+}
+
+// holdInjected returns true if the injector already ordered the proxy ahead of the application, which
+// it does by placing the proxy first with a postStart hook that blocks until the proxy is ready.
+func holdInjected(pod *v1.PodSpec) bool {
+	if len(pod.Containers) == 0 {
+		return false
+	}
+	first := pod.Containers[0]
+	return first.Name == util.IstioProxyName && first.Lifecycle != nil && first.Lifecycle.PostStart != nil
+}
+
+func holdFromAnnotation(annos map[string]string) bool {
+	v, ok := annos[annotation.ProxyConfig.Name]
+	if !ok {
+		return false
+	}
+	pc := &meshconfig.ProxyConfig{}
+	if err := protomarshal.ApplyYAML(v, pc); err != nil {
+		return false
+	}
+	return pc.GetHoldApplicationUntilProxyStarts().GetValue()
+}
@@ -21,6 +21,7 @@ import (
 	"istio.io/istio/pkg/config"
 	"istio.io/istio/pkg/config/analysis"
 	"istio.io/istio/pkg/config/analysis/analyzers/util"
+	"istio.io/istio/pkg/config/analysis/diag"
 	"istio.io/istio/pkg/config/analysis/msg"
 	"istio.io/istio/pkg/config/resource"
 	"istio.io/istio/pkg/config/schema/gvk"
@@ -38,6 +39,10 @@ func (c *CaCertificateAnalyzer) Metadata() analysis.Metadata {
 		Inputs: []config.GroupVersionKind{
 			gvk.DestinationRule,
 		},
+		Messages: []*diag.MessageType{
+			msg.NoServerCertificateVerificationDestinationLevel,
+			msg.NoServerCertificateVerificationPortLevel,
+		},
 	}
 }
 
@@ -0,0 +1,191 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package destinationrule
+
+import (
+	"sort"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	meshconfig "istio.io/api/mesh/v1alpha1"
+	"istio.io/api/networking/v1alpha3"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/analysis"
+	"istio.io/istio/pkg/config/analysis/analyzers/util"
+	"istio.io/istio/pkg/config/analysis/diag"
+	"istio.io/istio/pkg/config/analysis/msg"
+	"istio.io/istio/pkg/config/constants"
+	"istio.io/istio/pkg/config/host"
+	"istio.io/istio/pkg/config/labels"
+	"istio.io/istio/pkg/config/resource"
+	"istio.io/istio/pkg/config/schema/gvk"
+	"istio.io/istio/pkg/util/protomarshal"
+	"istio.io/istio/pkg/util/sets"
+)
+
+// MergeAnalyzer checks that the top-level trafficPolicy of DestinationRules is not discarded, because:
+// * an older DestinationRule for the same host, in the same namespace and with the same workload selector and exportTo,
+// is merged with it: only the trafficPolicy of the oldest one is kept
+// * a DestinationRule for a more specific host, or for the same host outside of the root namespace, takes precedence
+// over it without setting all the fields of its trafficPolicy, which are not inherited
+type MergeAnalyzer struct{}
+
+var _ analysis.Analyzer = &MergeAnalyzer{}
+
+func (m *MergeAnalyzer) Metadata() analysis.Metadata {
+	return analysis.Metadata{
+		Name:        "destinationrule.MergeAnalyzer",
+		Description: "Checks that the trafficPolicy of DestinationRules is not discarded by other DestinationRules for the same host",
+		Inputs: []config.GroupVersionKind{
+			gvk.DestinationRule,
+			gvk.MeshConfig,
+		},
+		Messages: []*diag.MessageType{msg.DestinationRuleTrafficPolicyDiscarded},
+	}
+}
+
+// destinationRule is a DestinationRule, its FQDN host, and the key of the DestinationRules it is merged with.
+type destinationRule struct {
+	r     *resource.Instance
+	rule  *v1alpha3.DestinationRule
+	host  host.Name
+	group string
+}
+
+func (m *MergeAnalyzer) Analyze(ctx analysis.Context) {
+	rootNamespace := resource.Namespace(constants.IstioSystemNamespace)
+	ctx.ForEach(gvk.MeshConfig, func(r *resource.Instance) bool {
+		if ns := r.Message.(*meshconfig.MeshConfig).GetRootNamespace(); ns != "" {
+			rootNamespace = resource.Namespace(ns)
+		}
+		return r.Metadata.FullName.Name != util.MeshConfigName
+	})
+
+	var rules []destinationRule
+	ctx.ForEach(gvk.DestinationRule, func(r *resource.Instance) bool {
+		rule := r.Message.(*v1alpha3.DestinationRule)
+		h := host.Name(util.ConvertHostToFQDN(r.Metadata.FullName.Namespace, rule.GetHost()))
+		exportTo := append([]string{}, rule.GetExportTo()...)
+		sort.Strings(exportTo)
+		rules = append(rules, destinationRule{
+			r:    r,
+			rule: rule,
+			host: h,
+			group: strings.Join([]string{
+				r.Metadata.FullName.Namespace.String(), string(h),
+				labels.Instance(rule.GetWorkloadSelector().GetMatchLabels()).String(), strings.Join(exportTo, ","),
+			}, "/"),
+		})
+		return true
+	})
+	// Sort as istiod does before merging: DestinationRules with a workload selector first, then by creation time.
+	sort.SliceStable(rules, func(i, j int) bool {
+		a, b := rules[i], rules[j]
+		if (a.rule.GetWorkloadSelector() == nil) != (b.rule.GetWorkloadSelector() == nil) {
+			return a.rule.GetWorkloadSelector() != nil
+		}
+		if !a.r.Metadata.CreateTime.Equal(b.r.Metadata.CreateTime) {
+			return a.r.Metadata.CreateTime.Before(b.r.Metadata.CreateTime)
+		}
+		if a.r.Metadata.FullName.Name != b.r.Metadata.FullName.Name {
+			return a.r.Metadata.FullName.Name < b.r.Metadata.FullName.Name
+		}
+		return a.r.Metadata.FullName.Namespace < b.r.Metadata.FullName.Namespace
+	})
+
+	// The DestinationRule whose trafficPolicy is kept when merging each group, the first one setting it.
+	effective := map[string]destinationRule{}
+	for _, dr := range rules {
+		e, ok := effective[dr.group]
+		if !ok || (e.rule.GetTrafficPolicy() == nil && dr.rule.GetTrafficPolicy() != nil) {
+			effective[dr.group] = dr
+			continue
+		}
+		if dr.rule.GetTrafficPolicy() == nil {
+			continue
+		}
+		if discarded := discardedFields(dr.rule.GetTrafficPolicy(), e.rule.GetTrafficPolicy(), false); len(discarded) > 0 {
+			m.report(ctx, dr, e, discarded)
+		}
+	}
+
+	reported := sets.New[string]()
+	for _, shadowed := range rules {
+		if shadowed.rule.GetTrafficPolicy() == nil || shadowed.rule.GetWorkloadSelector() != nil {
+			continue
+		}
+		for _, dr := range rules {
+			if dr.rule.GetWorkloadSelector() != nil || !takesPrecedence(dr, shadowed, rootNamespace) {
+				continue
+			}
+			e := effective[dr.group]
+			if reported.InsertContains(shadowed.r.Metadata.FullName.String() + "/" + dr.group) {
+				continue
+			}
+			if discarded := discardedFields(shadowed.rule.GetTrafficPolicy(), e.rule.GetTrafficPolicy(), true); len(discarded) > 0 {
+				m.report(ctx, shadowed, e, discarded)
+			}
+		}
+	}
+}
+
+func (m *MergeAnalyzer) report(ctx analysis.Context, dr, effective destinationRule, discarded []string) {
+	policy := "empty"
+	if tp := effective.rule.GetTrafficPolicy(); tp != nil {
+		if js, err := protomarshal.ToJSON(tp); err == nil {
+			policy = js
+		}
+	}
+	ctx.Report(gvk.DestinationRule, msg.NewDestinationRuleTrafficPolicyDiscarded(dr.r, effective.r.Metadata.FullName.String(),
+		string(effective.host), strings.Join(discarded, ", "), policy))
+}
+
+// takesPrecedence returns whether the DestinationRule is used instead of the other one, without workload selectors,
+// for the hosts of the DestinationRule: as it is for a more specific host, or for the same host outside of the root
+// namespace.
+func takesPrecedence(dr, other destinationRule, rootNamespace resource.Namespace) bool {
+	ns, otherNs := dr.r.Metadata.FullName.Namespace, other.r.Metadata.FullName.Namespace
+	if dr.host == other.host {
+		return otherNs == rootNamespace && ns != rootNamespace
+	}
+	return other.host.IsWildCarded() && dr.host.SubsetOf(other.host) && (ns == otherNs || otherNs == rootNamespace)
+}
+
+// discardedFields returns the JSON names of the fields of the trafficPolicy which are not in the effective one, or,
+// unless onlyMissing, which are set to other values.
+func discardedFields(tp, effective *v1alpha3.TrafficPolicy, onlyMissing bool) []string {
+	if effective == nil {
+		effective = &v1alpha3.TrafficPolicy{}
+	}
+	var discarded []string
+	e := effective.ProtoReflect()
+	tp.ProtoReflect().Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		if !e.Has(fd) || (!onlyMissing && !proto.Equal(field(tp, fd), field(effective, fd))) {
+			discarded = append(discarded, fd.JSONName())
+		}
+		return true
+	})
+	sort.Strings(discarded)
+	return discarded
+}
+
+// field returns a TrafficPolicy with only the given field of the trafficPolicy.
+func field(tp *v1alpha3.TrafficPolicy, fd protoreflect.FieldDescriptor) *v1alpha3.TrafficPolicy {
+	out := &v1alpha3.TrafficPolicy{}
+	out.ProtoReflect().Set(fd, tp.ProtoReflect().Get(fd))
+	return out
+}
@@ -21,6 +21,7 @@ import (
 	"istio.io/istio/pkg/config"
 	"istio.io/istio/pkg/config/analysis"
 	"istio.io/istio/pkg/config/analysis/analyzers/util"
+	"istio.io/istio/pkg/config/analysis/diag"
 	"istio.io/istio/pkg/config/analysis/msg"
 	"istio.io/istio/pkg/config/resource"
 	"istio.io/istio/pkg/config/schema/gvk"
@@ -40,6 +41,13 @@ func (*EnvoyPatchAnalyzer) Metadata() analysis.Metadata {
 		Inputs: []config.GroupVersionKind{
 			gvk.EnvoyFilter,
 		},
+		Messages: []*diag.MessageType{
+			msg.EnvoyFilterUsesAddOperationIncorrectly,
+			msg.EnvoyFilterUsesRelativeOperation,
+			msg.EnvoyFilterUsesRelativeOperationWithProxyVersion,
+			msg.EnvoyFilterUsesRemoveOperationIncorrectly,
+			msg.EnvoyFilterUsesReplaceOperationIncorrectly,
+		},
 	}
 }
 
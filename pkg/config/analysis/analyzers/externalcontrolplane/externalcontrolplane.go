@@ -24,6 +24,7 @@ import (
 
 	"istio.io/istio/pkg/config"
 	"istio.io/istio/pkg/config/analysis"
+	"istio.io/istio/pkg/config/analysis/diag"
 	"istio.io/istio/pkg/config/analysis/msg"
 	"istio.io/istio/pkg/config/resource"
 	"istio.io/istio/pkg/config/schema/gvk"
@@ -43,6 +44,10 @@ func (s *ExternalControlPlaneAnalyzer) Metadata() analysis.Metadata {
 			gvk.ValidatingWebhookConfiguration,
 			gvk.MutatingWebhookConfiguration,
 		},
+		Messages: []*diag.MessageType{
+			msg.ExternalControlPlaneAddressIsNotAHostname,
+			msg.InvalidExternalControlPlaneConfig,
+		},
 	}
 }
 
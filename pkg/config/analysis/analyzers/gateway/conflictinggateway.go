@@ -27,6 +27,7 @@ import (
 	"istio.io/istio/pkg/config"
 	"istio.io/istio/pkg/config/analysis"
 	"istio.io/istio/pkg/config/analysis/analyzers/util"
+	"istio.io/istio/pkg/config/analysis/diag"
 	"istio.io/istio/pkg/config/analysis/msg"
 	"istio.io/istio/pkg/config/resource"
 	"istio.io/istio/pkg/config/schema/gvk"
@@ -47,6 +48,10 @@ func (*ConflictingGatewayAnalyzer) Metadata() analysis.Metadata {
 			gvk.Gateway,
 			gvk.Pod,
 		},
+		Messages: []*diag.MessageType{
+			msg.ConflictingGateways,
+			msg.ReferencedResourceNotFound,
+		},
 	}
 }
 
@@ -24,6 +24,7 @@ import (
 	"istio.io/istio/pkg/config"
 	"istio.io/istio/pkg/config/analysis"
 	"istio.io/istio/pkg/config/analysis/analyzers/util"
+	"istio.io/istio/pkg/config/analysis/diag"
 	"istio.io/istio/pkg/config/analysis/msg"
 	"istio.io/istio/pkg/config/resource"
 	"istio.io/istio/pkg/config/schema/gvk"
@@ -45,6 +46,10 @@ func (*IngressGatewayPortAnalyzer) Metadata() analysis.Metadata {
 			gvk.Pod,
 			gvk.Service,
 		},
+		Messages: []*diag.MessageType{
+			msg.GatewayPortNotDefinedOnService,
+			msg.ReferencedResourceNotFound,
+		},
 	}
 }
 
@@ -26,6 +26,7 @@ import (
 	"istio.io/istio/pkg/config"
 	"istio.io/istio/pkg/config/analysis"
 	"istio.io/istio/pkg/config/analysis/analyzers/util"
+	"istio.io/istio/pkg/config/analysis/diag"
 	"istio.io/istio/pkg/config/analysis/msg"
 	"istio.io/istio/pkg/config/resource"
 	"istio.io/istio/pkg/config/schema/gvk"
@@ -46,6 +47,10 @@ func (a *SecretAnalyzer) Metadata() analysis.Metadata {
 			gvk.Pod,
 			gvk.Secret,
 		},
+		Messages: []*diag.MessageType{
+			msg.InvalidGatewayCredential,
+			msg.ReferencedResourceNotFound,
+		},
 	}
 }
 
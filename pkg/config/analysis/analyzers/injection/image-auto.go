@@ -25,6 +25,7 @@ import (
 
 	"istio.io/istio/pkg/config"
 	"istio.io/istio/pkg/config/analysis"
+	"istio.io/istio/pkg/config/analysis/diag"
 	"istio.io/istio/pkg/config/analysis/msg"
 	"istio.io/istio/pkg/config/resource"
 	"istio.io/istio/pkg/config/schema/gvk"
@@ -51,6 +52,10 @@ func (a *ImageAutoAnalyzer) Metadata() analysis.Metadata {
 			gvk.Deployment,
 			gvk.MutatingWebhookConfiguration,
 		},
+		Messages: []*diag.MessageType{
+			msg.ImageAutoWithoutInjectionError,
+			msg.ImageAutoWithoutInjectionWarning,
+		},
 	}
 }
 
@@ -25,6 +25,7 @@ import (
 	"istio.io/istio/pkg/config"
 	"istio.io/istio/pkg/config/analysis"
 	"istio.io/istio/pkg/config/analysis/analyzers/util"
+	"istio.io/istio/pkg/config/analysis/diag"
 	"istio.io/istio/pkg/config/analysis/msg"
 	"istio.io/istio/pkg/config/resource"
 	"istio.io/istio/pkg/config/schema/gvk"
@@ -63,6 +64,7 @@ func (a *ImageAnalyzer) Metadata() analysis.Metadata {
 			gvk.MeshConfig,
 			gvk.ProxyConfig,
 		},
+		Messages: []*diag.MessageType{msg.PodsIstioProxyImageMismatchInNamespace},
 	}
 }
 
@@ -26,11 +26,13 @@ import (
 	"istio.io/istio/pkg/config"
 	"istio.io/istio/pkg/config/analysis"
 	"istio.io/istio/pkg/config/analysis/analyzers/util"
+	"istio.io/istio/pkg/config/analysis/diag"
 	"istio.io/istio/pkg/config/analysis/msg"
 	"istio.io/istio/pkg/config/constants"
 	"istio.io/istio/pkg/config/resource"
 	"istio.io/istio/pkg/config/schema/gvk"
 	"istio.io/istio/pkg/slices"
+	"istio.io/istio/pkg/util/sets"
 )
 
 // Analyzer checks conditions related to Istio sidecar injection.
@@ -54,6 +56,14 @@ func (a *Analyzer) Metadata() analysis.Metadata {
 			gvk.Namespace,
 			gvk.Pod,
 			gvk.ConfigMap,
+			gvk.MutatingWebhookConfiguration,
+		},
+		Messages: []*diag.MessageType{
+			msg.NamespaceInjectionEnabledByDefault,
+			msg.NamespaceMultipleInjectionLabels,
+			msg.NamespaceNotInjected,
+			msg.NamespaceRevisionNotFound,
+			msg.PodMissingProxy,
 		},
 	}
 }
@@ -63,6 +73,18 @@ func (a *Analyzer) Analyze(c analysis.Context) {
 	enableNamespacesByDefault := false
 	injectedNamespaces := make(map[string]bool)
 
+	// The revisions and tags namespaces can be labeled with are those of the injection webhooks.
+	revisions := sets.New[string]()
+	c.ForEach(gvk.MutatingWebhookConfiguration, func(r *resource.Instance) bool {
+		if rev, ok := r.Metadata.Labels[label.IoIstioRev.Name]; ok {
+			revisions.Insert(rev)
+			if tag, ok := r.Metadata.Labels[label.IoIstioTag.Name]; ok {
+				revisions.Insert(tag)
+			}
+		}
+		return true
+	})
+
 	c.ForEach(gvk.Namespace, func(r *resource.Instance) bool {
 		if r.Metadata.FullName.String() == constants.IstioSystemNamespace {
 			return true
@@ -86,6 +108,10 @@ func (a *Analyzer) Analyze(c analysis.Context) {
 			m := msg.NewNamespaceMultipleInjectionLabels(r, istioLabels)
 			c.Report(gvk.Namespace, m)
 		}
+		// Without any injection webhook, as when analyzing files, revisions cannot be checked.
+		if okNewInjectionLabel && revisions.Len() > 0 && !revisions.Contains(nsRevision) {
+			c.Report(gvk.Namespace, msg.NewNamespaceRevisionNotFound(r, nsRevision))
+		}
 
 		if r.Metadata.Labels[label.IoIstioDataplaneMode.Name] == constants.DataplaneModeAmbient {
 			return true
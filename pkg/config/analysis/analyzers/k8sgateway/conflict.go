@@ -0,0 +1,112 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8sgateway
+
+import (
+	"strings"
+
+	klabels "k8s.io/apimachinery/pkg/labels"
+	k8sbeta "sigs.k8s.io/gateway-api/apis/v1beta1"
+
+	"istio.io/api/label"
+	"istio.io/api/networking/v1alpha3"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/analysis"
+	"istio.io/istio/pkg/config/analysis/diag"
+	"istio.io/istio/pkg/config/analysis/msg"
+	"istio.io/istio/pkg/config/host"
+	"istio.io/istio/pkg/config/resource"
+	"istio.io/istio/pkg/config/schema/gvk"
+	"istio.io/istio/pkg/util/sets"
+)
+
+var _ analysis.Analyzer = &ListenerConflictAnalyzer{}
+
+// ListenerConflictAnalyzer checks that the listeners of Kubernetes Gateways do not conflict with the servers of Istio
+// Gateways selecting the same gateway pods.
+type ListenerConflictAnalyzer struct{}
+
+func (a *ListenerConflictAnalyzer) Metadata() analysis.Metadata {
+	return analysis.Metadata{
+		Name:        "k8sgateway.ListenerConflictAnalyzer",
+		Description: "Check that the listeners of Kubernetes Gateways do not conflict with Istio Gateways",
+		Inputs: []config.GroupVersionKind{
+			gvk.KubernetesGateway,
+			gvk.Gateway,
+			gvk.Pod,
+		},
+		Messages: []*diag.MessageType{msg.GatewayListenerConflict},
+	}
+}
+
+// Analyze implements analysis.Analyzer
+func (a *ListenerConflictAnalyzer) Analyze(c analysis.Context) {
+	c.ForEach(gvk.Gateway, func(r *resource.Instance) bool {
+		gw := r.Message.(*v1alpha3.Gateway)
+		if len(gw.Selector) == 0 {
+			return true
+		}
+		for _, k8sGateway := range selectedKubernetesGateways(c, klabels.SelectorFromSet(gw.Selector)) {
+			reportConflicts(c, k8sGateway, gw, r.Metadata.FullName.String())
+		}
+		return true
+	})
+}
+
+// selectedKubernetesGateways returns the Kubernetes Gateways of the gateway pods the selector selects.
+func selectedKubernetesGateways(c analysis.Context, selector klabels.Selector) []*resource.Instance {
+	seen := sets.New[resource.FullName]()
+	var gateways []*resource.Instance
+	c.ForEach(gvk.Pod, func(r *resource.Instance) bool {
+		name, ok := r.Metadata.Labels[label.IoK8sNetworkingGatewayGatewayName.Name]
+		if !ok || !selector.Matches(klabels.Set(r.Metadata.Labels)) {
+			return true
+		}
+		fullName := resource.NewFullName(r.Metadata.FullName.Namespace, resource.LocalName(name))
+		if seen.InsertContains(fullName) {
+			return true
+		}
+		if gw := c.Find(gvk.KubernetesGateway, fullName); gw != nil {
+			gateways = append(gateways, gw)
+		}
+		return true
+	})
+	return gateways
+}
+
+func reportConflicts(c analysis.Context, k8sGateway *resource.Instance, gw *v1alpha3.Gateway, gwName string) {
+	spec := k8sGateway.Message.(*k8sbeta.GatewaySpec)
+	for _, l := range spec.Listeners {
+		listenerHost := host.Name("*")
+		if l.Hostname != nil {
+			listenerHost = host.Name(*l.Hostname)
+		}
+		for _, server := range gw.Servers {
+			if server.GetPort().GetNumber() != uint32(l.Port) {
+				continue
+			}
+			for _, h := range server.Hosts {
+				// Hosts may be scoped to the namespace of the VirtualServices, as namespace/host.
+				if _, hostname, ok := strings.Cut(h, "/"); ok {
+					h = hostname
+				}
+				if listenerHost.Matches(host.Name(h)) {
+					c.Report(gvk.KubernetesGateway, msg.NewGatewayListenerConflict(k8sGateway, string(l.Name), int(l.Port), gwName))
+					return
+				}
+			}
+		}
+	}
+}
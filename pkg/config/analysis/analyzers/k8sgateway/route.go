@@ -0,0 +1,139 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8sgateway
+
+import (
+	v1 "k8s.io/api/core/v1"
+	k8s "sigs.k8s.io/gateway-api/apis/v1"
+	k8sbeta "sigs.k8s.io/gateway-api/apis/v1beta1"
+
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/analysis"
+	"istio.io/istio/pkg/config/analysis/diag"
+	"istio.io/istio/pkg/config/analysis/msg"
+	"istio.io/istio/pkg/config/resource"
+	"istio.io/istio/pkg/config/schema/gvk"
+	"istio.io/istio/pkg/ptr"
+)
+
+var _ analysis.Analyzer = &RouteAnalyzer{}
+
+// RouteAnalyzer checks the references of HTTPRoutes to their parent Gateways and their backend Services.
+type RouteAnalyzer struct{}
+
+func (a *RouteAnalyzer) Metadata() analysis.Metadata {
+	return analysis.Metadata{
+		Name:        "k8sgateway.RouteAnalyzer",
+		Description: "Check that HTTPRoutes reference existing Gateways, and permitted backend Services with the port used",
+		Inputs: []config.GroupVersionKind{
+			gvk.HTTPRoute,
+			gvk.KubernetesGateway,
+			gvk.ReferenceGrant,
+			gvk.Service,
+		},
+		Messages: []*diag.MessageType{
+			msg.BackendPortNotFound,
+			msg.BackendReferenceNotPermitted,
+			msg.ReferencedResourceNotFound,
+		},
+	}
+}
+
+// Analyze implements analysis.Analyzer
+func (a *RouteAnalyzer) Analyze(c analysis.Context) {
+	c.ForEach(gvk.HTTPRoute, func(r *resource.Instance) bool {
+		a.analyzeRoute(r, c)
+		return true
+	})
+}
+
+func (a *RouteAnalyzer) analyzeRoute(r *resource.Instance, c analysis.Context) {
+	route := r.Message.(*k8sbeta.HTTPRouteSpec)
+	ns := r.Metadata.FullName.Namespace.String()
+
+	for _, parent := range route.ParentRefs {
+		group, kind := ptr.OrDefault(parent.Group, k8s.GroupName), ptr.OrDefault(parent.Kind, k8s.Kind(gvk.KubernetesGateway.Kind))
+		if string(group) != k8s.GroupName || string(kind) != gvk.KubernetesGateway.Kind {
+			// Services are the parents of the routes of the mesh.
+			continue
+		}
+		parentNs := string(ptr.OrDefault(parent.Namespace, k8s.Namespace(ns)))
+		if !c.Exists(gvk.KubernetesGateway, resource.NewFullName(resource.Namespace(parentNs), resource.LocalName(parent.Name))) {
+			c.Report(gvk.HTTPRoute, msg.NewReferencedResourceNotFound(r, "parent Gateway", parentNs+"/"+string(parent.Name)))
+		}
+	}
+
+	for _, rule := range route.Rules {
+		for _, backend := range rule.BackendRefs {
+			ref := backend.BackendObjectReference
+			if string(ptr.OrEmpty(ref.Group)) != "" || string(ptr.OrDefault(ref.Kind, k8s.Kind(gvk.Service.Kind))) != gvk.Service.Kind {
+				continue
+			}
+			backendNs := string(ptr.OrDefault(ref.Namespace, k8s.Namespace(ns)))
+			name := backendNs + "/" + string(ref.Name)
+			if backendNs != ns && !referencePermitted(c, ns, backendNs, string(ref.Name)) {
+				c.Report(gvk.HTTPRoute, msg.NewBackendReferenceNotPermitted(r, "Service "+name, backendNs))
+				continue
+			}
+			svc := c.Find(gvk.Service, resource.NewFullName(resource.Namespace(backendNs), resource.LocalName(ref.Name)))
+			if svc == nil {
+				c.Report(gvk.HTTPRoute, msg.NewReferencedResourceNotFound(r, "backend Service", name))
+				continue
+			}
+			if ref.Port != nil && !hasPort(svc.Message.(*v1.ServiceSpec), int32(*ref.Port)) {
+				c.Report(gvk.HTTPRoute, msg.NewBackendPortNotFound(r, name, int(*ref.Port)))
+			}
+		}
+	}
+}
+
+// referencePermitted returns whether a ReferenceGrant of the backend namespace permits the HTTPRoutes of the route
+// namespace to reference the Service.
+func referencePermitted(c analysis.Context, routeNs, backendNs, service string) bool {
+	permitted := false
+	c.ForEach(gvk.ReferenceGrant, func(r *resource.Instance) bool {
+		if r.Metadata.FullName.Namespace.String() != backendNs {
+			return true
+		}
+		grant := r.Message.(*k8sbeta.ReferenceGrantSpec)
+		from := false
+		for _, f := range grant.From {
+			if string(f.Group) == k8s.GroupName && string(f.Kind) == gvk.HTTPRoute.Kind && string(f.Namespace) == routeNs {
+				from = true
+				break
+			}
+		}
+		if !from {
+			return true
+		}
+		for _, t := range grant.To {
+			if string(t.Group) == "" && string(t.Kind) == gvk.Service.Kind && (t.Name == nil || string(*t.Name) == service) {
+				permitted = true
+				return false
+			}
+		}
+		return true
+	})
+	return permitted
+}
+
+func hasPort(svc *v1.ServiceSpec, port int32) bool {
+	for _, p := range svc.Ports {
+		if p.Port == port {
+			return true
+		}
+	}
+	return false
+}
@@ -19,6 +19,7 @@ import (
 	typev1beta1 "istio.io/api/type/v1beta1"
 	"istio.io/istio/pkg/config"
 	"istio.io/istio/pkg/config/analysis"
+	"istio.io/istio/pkg/config/analysis/diag"
 	"istio.io/istio/pkg/config/analysis/msg"
 	"istio.io/istio/pkg/config/resource"
 	"istio.io/istio/pkg/config/schema/gvk"
@@ -52,6 +53,7 @@ func (w *SelectorAnalyzer) Metadata() analysis.Metadata {
 			gvk.WasmPlugin,
 			gvk.Pod,
 		},
+		Messages: []*diag.MessageType{msg.IneffectiveSelector},
 	}
 }
 
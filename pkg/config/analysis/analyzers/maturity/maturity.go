@@ -23,6 +23,7 @@ import (
 	"istio.io/istio/pkg/config"
 	"istio.io/istio/pkg/config/analysis"
 	"istio.io/istio/pkg/config/analysis/analyzers/util"
+	"istio.io/istio/pkg/config/analysis/diag"
 	"istio.io/istio/pkg/config/analysis/msg"
 	"istio.io/istio/pkg/config/resource"
 	"istio.io/istio/pkg/config/schema/gvk"
@@ -48,6 +49,7 @@ func (*AlphaAnalyzer) Metadata() analysis.Metadata {
 			gvk.Pod,
 			gvk.Deployment,
 		},
+		Messages: []*diag.MessageType{msg.AlphaAnnotation},
 	}
 }
 
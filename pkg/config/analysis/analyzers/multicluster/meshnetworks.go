@@ -24,6 +24,7 @@ import (
 	"istio.io/istio/pkg/config"
 	"istio.io/istio/pkg/config/analysis"
 	"istio.io/istio/pkg/config/analysis/analyzers/util"
+	"istio.io/istio/pkg/config/analysis/diag"
 	"istio.io/istio/pkg/config/analysis/msg"
 	"istio.io/istio/pkg/config/resource"
 	"istio.io/istio/pkg/config/schema/gvk"
@@ -51,6 +52,7 @@ func (s *MeshNetworksAnalyzer) Metadata() analysis.Metadata {
 			gvk.MeshNetworks,
 			gvk.Secret,
 		},
+		Messages: []*diag.MessageType{msg.UnknownMeshNetworksServiceRegistry},
 	}
 }
 
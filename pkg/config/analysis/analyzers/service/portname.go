@@ -23,6 +23,7 @@ import (
 	"istio.io/istio/pkg/config"
 	"istio.io/istio/pkg/config/analysis"
 	"istio.io/istio/pkg/config/analysis/analyzers/util"
+	"istio.io/istio/pkg/config/analysis/diag"
 	"istio.io/istio/pkg/config/analysis/msg"
 	"istio.io/istio/pkg/config/constants"
 	configKube "istio.io/istio/pkg/config/kube"
@@ -43,6 +44,10 @@ func (s *PortNameAnalyzer) Metadata() analysis.Metadata {
 		Inputs: []config.GroupVersionKind{
 			gvk.Service,
 		},
+		Messages: []*diag.MessageType{
+			msg.ExternalNameServiceTypeInvalidPortName,
+			msg.PortNameIsNotUnderNamingConvention,
+		},
 	}
 }
 
@@ -23,6 +23,7 @@ import (
 	"istio.io/istio/pkg/config"
 	"istio.io/istio/pkg/config/analysis"
 	"istio.io/istio/pkg/config/analysis/analyzers/util"
+	"istio.io/istio/pkg/config/analysis/diag"
 	"istio.io/istio/pkg/config/analysis/msg"
 	"istio.io/istio/pkg/config/resource"
 	"istio.io/istio/pkg/config/schema/gvk"
@@ -40,6 +41,7 @@ func (serviceEntry *ProtocolAddressesAnalyzer) Metadata() analysis.Metadata {
 			gvk.ServiceEntry,
 			gvk.MeshConfig,
 		},
+		Messages: []*diag.MessageType{msg.ServiceEntryAddressesRequired},
 	}
 }
 
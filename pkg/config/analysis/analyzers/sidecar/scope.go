@@ -0,0 +1,221 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sidecar
+
+import (
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"istio.io/api/annotation"
+	meshconfig "istio.io/api/mesh/v1alpha1"
+	"istio.io/api/networking/v1alpha3"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/analysis"
+	"istio.io/istio/pkg/config/analysis/analyzers/util"
+	"istio.io/istio/pkg/config/analysis/diag"
+	"istio.io/istio/pkg/config/analysis/msg"
+	"istio.io/istio/pkg/config/constants"
+	"istio.io/istio/pkg/config/resource"
+	"istio.io/istio/pkg/config/schema/gvk"
+)
+
+const (
+	// defaultMinVisibleServices is the number of visible services from which unscoped namespaces are reported.
+	defaultMinVisibleServices = 500
+
+	// bytesPerServicePort is a rough estimate of the config generated for each port of a visible service: its cluster,
+	// its routes and its endpoints, for services of a few endpoints.
+	bytesPerServicePort = 2 * 1024
+)
+
+// ScopeAnalyzer checks that the proxies of the namespaces seeing many services have a Sidecar resource restricting
+// their egress hosts, as they otherwise receive the config of every service of the mesh.
+type ScopeAnalyzer struct {
+	// MinVisibleServices is the number of visible services from which namespaces are reported, 500 if zero.
+	MinVisibleServices int
+}
+
+var _ analysis.Analyzer = &ScopeAnalyzer{}
+
+// Metadata implements Analyzer
+func (a *ScopeAnalyzer) Metadata() analysis.Metadata {
+	return analysis.Metadata{
+		Name:        "sidecar.ScopeAnalyzer",
+		Description: "Checks that the proxies of namespaces seeing many services have a Sidecar resource restricting their egress hosts",
+		Inputs: []config.GroupVersionKind{
+			gvk.Sidecar,
+			gvk.Pod,
+			gvk.Namespace,
+			gvk.Service,
+			gvk.ServiceEntry,
+			gvk.MeshConfig,
+		},
+		Messages: []*diag.MessageType{msg.UnscopedSidecarConfig},
+	}
+}
+
+// visibleService is a service, and the namespaces it is exported to.
+type visibleService struct {
+	namespace resource.Namespace
+	exportTo  []string
+	ports     int
+}
+
+func (s visibleService) visibleFrom(ns resource.Namespace) bool {
+	if len(s.exportTo) == 0 {
+		return true
+	}
+	for _, e := range s.exportTo {
+		switch e {
+		case util.ExportToAllNamespaces:
+			return true
+		case util.ExportToNamespaceLocal:
+			if ns == s.namespace {
+				return true
+			}
+		default:
+			if e == ns.String() {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Analyze implements Analyzer
+func (a *ScopeAnalyzer) Analyze(c analysis.Context) {
+	minVisibleServices := a.MinVisibleServices
+	if minVisibleServices == 0 {
+		minVisibleServices = defaultMinVisibleServices
+	}
+	var meshConfig *meshconfig.MeshConfig
+	c.ForEach(gvk.MeshConfig, func(r *resource.Instance) bool {
+		meshConfig = r.Message.(*meshconfig.MeshConfig)
+		return r.Metadata.FullName.Name != util.MeshConfigName
+	})
+	rootNamespace := resource.Namespace(meshConfig.GetRootNamespace())
+	if rootNamespace == "" {
+		rootNamespace = constants.IstioSystemNamespace
+	}
+
+	// Sidecars restricting the egress hosts, by namespace.
+	scoping := map[resource.Namespace][]*v1alpha3.Sidecar{}
+	c.ForEach(gvk.Sidecar, func(r *resource.Instance) bool {
+		s := r.Message.(*v1alpha3.Sidecar)
+		if len(s.GetEgress()) > 0 {
+			scoping[r.Metadata.FullName.Namespace] = append(scoping[r.Metadata.FullName.Namespace], s)
+		}
+		return true
+	})
+	for _, s := range scoping[rootNamespace] {
+		if len(s.GetWorkloadSelector().GetLabels()) == 0 {
+			// The mesh default Sidecar scopes every namespace without a Sidecar of its own.
+			return
+		}
+	}
+
+	// Namespaces with proxies whose egress hosts are not restricted by a Sidecar.
+	unscoped := map[resource.Namespace]bool{}
+	c.ForEach(gvk.Pod, func(r *resource.Instance) bool {
+		ns := r.Metadata.FullName.Namespace
+		if unscoped[ns] || ns == rootNamespace || util.PodInAmbientMode(r) || !util.PodInMesh(r, c) {
+			return true
+		}
+		if !isScoped(r.Metadata.Labels, scoping[ns]) {
+			unscoped[ns] = true
+		}
+		return true
+	})
+	if len(unscoped) == 0 {
+		return
+	}
+
+	defaultExportTo := meshConfig.GetDefaultServiceExportTo()
+	var services []visibleService
+	c.ForEach(gvk.Service, func(r *resource.Instance) bool {
+		exportTo := defaultExportTo
+		if anno := r.Metadata.Annotations[annotation.NetworkingExportTo.Name]; anno != "" {
+			exportTo = strings.Split(anno, ",")
+			for i := range exportTo {
+				exportTo[i] = strings.TrimSpace(exportTo[i])
+			}
+		}
+		services = append(services, visibleService{
+			namespace: r.Metadata.FullName.Namespace,
+			exportTo:  exportTo,
+			ports:     len(r.Message.(*corev1.ServiceSpec).Ports),
+		})
+		return true
+	})
+	c.ForEach(gvk.ServiceEntry, func(r *resource.Instance) bool {
+		se := r.Message.(*v1alpha3.ServiceEntry)
+		for range se.GetHosts() {
+			services = append(services, visibleService{
+				namespace: r.Metadata.FullName.Namespace,
+				exportTo:  se.GetExportTo(),
+				ports:     len(se.GetPorts()),
+			})
+		}
+		return true
+	})
+
+	c.ForEach(gvk.Namespace, func(r *resource.Instance) bool {
+		ns := resource.Namespace(r.Metadata.FullName.Name)
+		if !unscoped[ns] {
+			return true
+		}
+		visible, ports, removable, removablePorts := 0, 0, 0, 0
+		for _, s := range services {
+			if !s.visibleFrom(ns) {
+				continue
+			}
+			visible++
+			ports += s.ports
+			if s.namespace != ns && s.namespace != rootNamespace {
+				removable++
+				removablePorts += s.ports
+			}
+		}
+		if visible < minVisibleServices {
+			return true
+		}
+		m := msg.NewUnscopedSidecarConfig(r, visible, formatSize(ports*bytesPerServicePort), rootNamespace.String(),
+			removable, formatSize(removablePorts*bytesPerServicePort))
+		c.Report(gvk.Namespace, m)
+		return true
+	})
+}
+
+// isScoped returns whether one of the Sidecars, restricting egress hosts, applies to the workload of the labels.
+func isScoped(workloadLabels map[string]string, sidecars []*v1alpha3.Sidecar) bool {
+	for _, s := range sidecars {
+		selector := s.GetWorkloadSelector().GetLabels()
+		if len(selector) == 0 || labels.SelectorFromSet(selector).Matches(labels.Set(workloadLabels)) {
+			return true
+		}
+	}
+	return false
+}
+
+// formatSize formats the size in bytes as KiB or MiB.
+func formatSize(bytes int) string {
+	if bytes < 1024*1024 {
+		return fmt.Sprintf("%d KiB", bytes/1024)
+	}
+	return fmt.Sprintf("%.1f MiB", float64(bytes)/(1024*1024))
+}
@@ -23,6 +23,7 @@ import (
 	"istio.io/istio/pkg/config"
 	"istio.io/istio/pkg/config/analysis"
 	"istio.io/istio/pkg/config/analysis/analyzers/util"
+	"istio.io/istio/pkg/config/analysis/diag"
 	"istio.io/istio/pkg/config/analysis/msg"
 	"istio.io/istio/pkg/config/resource"
 	"istio.io/istio/pkg/config/schema/gvk"
@@ -46,6 +47,12 @@ func (a *SelectorAnalyzer) Metadata() analysis.Metadata {
 			gvk.Pod,
 			gvk.Namespace,
 		},
+		Messages: []*diag.MessageType{
+			msg.ConflictingSidecarWorkloadSelectors,
+			msg.IneffectivePolicy,
+			msg.MultipleSidecarsWithoutWorkloadSelectors,
+			msg.ReferencedResourceNotFound,
+		},
 	}
 }
 
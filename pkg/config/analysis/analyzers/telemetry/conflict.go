@@ -0,0 +1,227 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetry
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"istio.io/api/telemetry/v1alpha1"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/analysis"
+	"istio.io/istio/pkg/config/analysis/diag"
+	"istio.io/istio/pkg/config/analysis/msg"
+	"istio.io/istio/pkg/config/resource"
+	"istio.io/istio/pkg/config/schema/gvk"
+	"istio.io/istio/pkg/util/sets"
+)
+
+// ConflictAnalyzer checks that:
+// * the Telemetries targeting the same Gateway or Service do not set conflicting providers or sampling
+// * the providers referenced by Telemetries are defined by the extensionProviders of the mesh config
+// * the tracing settings of namespace Telemetries are not disabled by the mesh-wide Telemetry
+type ConflictAnalyzer struct{}
+
+var _ analysis.Analyzer = &ConflictAnalyzer{}
+
+// Metadata implements Analyzer
+func (a *ConflictAnalyzer) Metadata() analysis.Metadata {
+	return analysis.Metadata{
+		Name: "telemetry.ConflictAnalyzer",
+		Description: "Validates that telemetries targeting the same resource do not conflict, that their providers are defined, " +
+			"and that their settings are not disabled by the mesh-wide telemetry",
+		Inputs: []config.GroupVersionKind{
+			gvk.Telemetry,
+			gvk.MeshConfig,
+		},
+		Messages: []*diag.MessageType{
+			msg.ConflictingTelemetrySettings,
+			msg.TelemetryProviderNotFound,
+			msg.TelemetrySettingsOverridden,
+		},
+	}
+}
+
+// Analyze implements Analyzer
+func (a *ConflictAnalyzer) Analyze(c analysis.Context) {
+	meshConfig := fetchMeshConfig(c)
+	providers := sets.New[string]()
+	for _, p := range meshConfig.GetExtensionProviders() {
+		providers.Insert(strings.ToLower(p.Name))
+	}
+	rootNamespace := resource.Namespace(meshConfig.GetRootNamespace())
+
+	targets := map[string][]*resource.Instance{}
+	var meshWide *resource.Instance
+	var namespaceWide []*resource.Instance
+	c.ForEach(gvk.Telemetry, func(r *resource.Instance) bool {
+		t := r.Message.(*v1alpha1.Telemetry)
+		a.analyzeProviders(r, t, providers, c)
+
+		refs := t.GetTargetRefs()
+		if t.GetTargetRef() != nil {
+			refs = append(refs, t.GetTargetRef())
+		}
+		for _, ref := range refs {
+			target := fmt.Sprintf("%s %s/%s", ref.GetKind(), r.Metadata.FullName.Namespace, ref.GetName())
+			targets[target] = append(targets[target], r)
+		}
+		if len(refs) == 0 && len(t.GetSelector().GetMatchLabels()) == 0 {
+			if r.Metadata.FullName.Namespace == rootNamespace {
+				meshWide = r
+			} else {
+				namespaceWide = append(namespaceWide, r)
+			}
+		}
+		return true
+	})
+
+	for target, rs := range targets {
+		if len(rs) < 2 {
+			continue
+		}
+		if conflicts, conflicting := conflictingSettings(rs); len(conflicts) > 0 {
+			names := getNames(conflicting)
+			for _, r := range conflicting {
+				c.Report(gvk.Telemetry, msg.NewConflictingTelemetrySettings(r, names, target, strings.Join(conflicts, " and ")))
+			}
+		}
+	}
+
+	if meshWide == nil || !disablesSpanReporting(meshWide.Message.(*v1alpha1.Telemetry)) {
+		return
+	}
+	for _, r := range namespaceWide {
+		if overridesTracing(r.Message.(*v1alpha1.Telemetry)) {
+			c.Report(gvk.Telemetry, msg.NewTelemetrySettingsOverridden(r, meshWide.Metadata.FullName.String()))
+		}
+	}
+}
+
+func (a *ConflictAnalyzer) analyzeProviders(r *resource.Instance, t *v1alpha1.Telemetry, providers sets.String, c analysis.Context) {
+	check := func(section string, refs []*v1alpha1.ProviderRef) {
+		for _, p := range refs {
+			if !providers.Contains(strings.ToLower(p.GetName())) {
+				c.Report(gvk.Telemetry, msg.NewTelemetryProviderNotFound(r, section, p.GetName()))
+			}
+		}
+	}
+	for _, tr := range t.GetTracing() {
+		check("tracing", tr.GetProviders())
+	}
+	for _, l := range t.GetAccessLogging() {
+		check("access logging", l.GetProviders())
+	}
+	for _, m := range t.GetMetrics() {
+		check("metrics", m.GetProviders())
+	}
+}
+
+// conflictingSettings returns the settings the Telemetries set to different values, and the Telemetries setting them.
+func conflictingSettings(rs []*resource.Instance) ([]string, []*resource.Instance) {
+	settings := []struct {
+		name  string
+		value func(t *v1alpha1.Telemetry) string
+	}{
+		{"tracing providers", func(t *v1alpha1.Telemetry) string {
+			var refs []*v1alpha1.ProviderRef
+			for _, tr := range t.GetTracing() {
+				refs = append(refs, tr.GetProviders()...)
+			}
+			return providerNames(refs)
+		}},
+		{"tracing sampling", func(t *v1alpha1.Telemetry) string {
+			for _, tr := range t.GetTracing() {
+				if tr.GetRandomSamplingPercentage() != nil {
+					return fmt.Sprint(tr.GetRandomSamplingPercentage().GetValue())
+				}
+			}
+			return ""
+		}},
+		{"access logging providers", func(t *v1alpha1.Telemetry) string {
+			var refs []*v1alpha1.ProviderRef
+			for _, l := range t.GetAccessLogging() {
+				refs = append(refs, l.GetProviders()...)
+			}
+			return providerNames(refs)
+		}},
+		{"metrics providers", func(t *v1alpha1.Telemetry) string {
+			var refs []*v1alpha1.ProviderRef
+			for _, m := range t.GetMetrics() {
+				refs = append(refs, m.GetProviders()...)
+			}
+			return providerNames(refs)
+		}},
+	}
+	var conflicts []string
+	involved := sets.New[*resource.Instance]()
+	for _, s := range settings {
+		values := sets.New[string]()
+		var setting []*resource.Instance
+		for _, r := range rs {
+			// Telemetries not setting the value do not conflict with the others.
+			if v := s.value(r.Message.(*v1alpha1.Telemetry)); v != "" {
+				values.Insert(v)
+				setting = append(setting, r)
+			}
+		}
+		if values.Len() > 1 {
+			conflicts = append(conflicts, s.name)
+			involved.InsertAll(setting...)
+		}
+	}
+	var conflicting []*resource.Instance
+	for _, r := range rs {
+		if involved.Contains(r) {
+			conflicting = append(conflicting, r)
+		}
+	}
+	return conflicts, conflicting
+}
+
+func providerNames(refs []*v1alpha1.ProviderRef) string {
+	names := make([]string, 0, len(refs))
+	for _, p := range refs {
+		names = append(names, p.GetName())
+	}
+	sort.Strings(names)
+	return strings.Join(names, ",")
+}
+
+// disablesSpanReporting returns whether the Telemetry disables span reporting for both the clients and servers.
+func disablesSpanReporting(t *v1alpha1.Telemetry) bool {
+	for _, tr := range t.GetTracing() {
+		if tr.GetDisableSpanReporting().GetValue() && tr.GetMatch().GetMode() == v1alpha1.WorkloadMode_CLIENT_AND_SERVER {
+			return true
+		}
+	}
+	return false
+}
+
+// overridesTracing returns whether the Telemetry sets tracing settings without enabling span reporting, which then
+// remains disabled if the mesh-wide Telemetry disables it.
+func overridesTracing(t *v1alpha1.Telemetry) bool {
+	configured := false
+	for _, tr := range t.GetTracing() {
+		if tr.GetDisableSpanReporting() != nil {
+			return false
+		}
+		if tr.GetRandomSamplingPercentage() != nil || len(tr.GetCustomTags()) > 0 || len(tr.GetProviders()) > 0 {
+			configured = true
+		}
+	}
+	return configured
+}
@@ -17,6 +17,7 @@ import (
 	"istio.io/api/telemetry/v1alpha1"
 	"istio.io/istio/pkg/config"
 	"istio.io/istio/pkg/config/analysis"
+	"istio.io/istio/pkg/config/analysis/diag"
 	"istio.io/istio/pkg/config/analysis/msg"
 	"istio.io/istio/pkg/config/resource"
 	"istio.io/istio/pkg/config/schema/gvk"
@@ -38,6 +39,7 @@ func (a *DefaultSelectorAnalyzer) Metadata() analysis.Metadata {
 		Inputs: []config.GroupVersionKind{
 			gvk.Telemetry,
 		},
+		Messages: []*diag.MessageType{msg.MultipleTelemetriesWithoutWorkloadSelectors},
 	}
 }
 
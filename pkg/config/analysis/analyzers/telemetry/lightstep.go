@@ -21,6 +21,7 @@ import (
 	telemetryapi "istio.io/api/telemetry/v1alpha1"
 	"istio.io/istio/pkg/config"
 	"istio.io/istio/pkg/config/analysis"
+	"istio.io/istio/pkg/config/analysis/diag"
 	"istio.io/istio/pkg/config/analysis/msg"
 	"istio.io/istio/pkg/config/resource"
 	"istio.io/istio/pkg/config/schema/gvk"
@@ -40,6 +41,7 @@ func (a *LightstepAnalyzer) Metadata() analysis.Metadata {
 			gvk.Telemetry,
 			gvk.MeshConfig,
 		},
+		Messages: []*diag.MessageType{msg.Deprecated},
 	}
 }
 
@@ -18,6 +18,7 @@ import (
 	telemetryapi "istio.io/api/telemetry/v1alpha1"
 	"istio.io/istio/pkg/config"
 	"istio.io/istio/pkg/config/analysis"
+	"istio.io/istio/pkg/config/analysis/diag"
 	"istio.io/istio/pkg/config/analysis/msg"
 	"istio.io/istio/pkg/config/resource"
 	"istio.io/istio/pkg/config/schema/gvk"
@@ -36,6 +37,7 @@ func (a *ProdiverAnalyzer) Metadata() analysis.Metadata {
 			gvk.Telemetry,
 			gvk.MeshConfig,
 		},
+		Messages: []*diag.MessageType{msg.InvalidTelemetryProvider},
 	}
 }
 
@@ -22,6 +22,7 @@ import (
 	"istio.io/istio/pkg/config"
 	"istio.io/istio/pkg/config/analysis"
 	"istio.io/istio/pkg/config/analysis/analyzers/util"
+	"istio.io/istio/pkg/config/analysis/diag"
 	"istio.io/istio/pkg/config/analysis/msg"
 	"istio.io/istio/pkg/config/resource"
 	"istio.io/istio/pkg/config/schema/gvk"
@@ -44,6 +45,10 @@ func (a *SelectorAnalyzer) Metadata() analysis.Metadata {
 			gvk.Telemetry,
 			gvk.Pod,
 		},
+		Messages: []*diag.MessageType{
+			msg.ConflictingTelemetryWorkloadSelectors,
+			msg.ReferencedResourceNotFound,
+		},
 	}
 }
 
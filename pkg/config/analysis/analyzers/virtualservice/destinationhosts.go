@@ -21,6 +21,7 @@ import (
 	"istio.io/istio/pkg/config"
 	"istio.io/istio/pkg/config/analysis"
 	"istio.io/istio/pkg/config/analysis/analyzers/util"
+	"istio.io/istio/pkg/config/analysis/diag"
 	"istio.io/istio/pkg/config/analysis/msg"
 	"istio.io/istio/pkg/config/resource"
 	"istio.io/istio/pkg/config/schema/gvk"
@@ -46,6 +47,11 @@ func (a *DestinationHostAnalyzer) Metadata() analysis.Metadata {
 			gvk.VirtualService,
 			gvk.Service,
 		},
+		Messages: []*diag.MessageType{
+			msg.IngressRouteRulesNotAffected,
+			msg.ReferencedResourceNotFound,
+			msg.VirtualServiceDestinationPortSelectorRequired,
+		},
 	}
 }
 
@@ -21,6 +21,7 @@ import (
 	"istio.io/istio/pkg/config"
 	"istio.io/istio/pkg/config/analysis"
 	"istio.io/istio/pkg/config/analysis/analyzers/util"
+	"istio.io/istio/pkg/config/analysis/diag"
 	"istio.io/istio/pkg/config/analysis/msg"
 	"istio.io/istio/pkg/config/resource"
 	"istio.io/istio/pkg/config/schema/gvk"
@@ -40,6 +41,7 @@ func (d *DestinationRuleAnalyzer) Metadata() analysis.Metadata {
 			gvk.VirtualService,
 			gvk.DestinationRule,
 		},
+		Messages: []*diag.MessageType{msg.ReferencedResourceNotFound},
 	}
 }
 
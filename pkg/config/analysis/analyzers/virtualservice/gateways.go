@@ -22,6 +22,7 @@ import (
 	"istio.io/istio/pkg/config"
 	"istio.io/istio/pkg/config/analysis"
 	"istio.io/istio/pkg/config/analysis/analyzers/util"
+	"istio.io/istio/pkg/config/analysis/diag"
 	"istio.io/istio/pkg/config/analysis/msg"
 	kubeconfig "istio.io/istio/pkg/config/gateway/kube"
 	"istio.io/istio/pkg/config/host"
@@ -43,6 +44,11 @@ func (s *GatewayAnalyzer) Metadata() analysis.Metadata {
 			gvk.Gateway,
 			gvk.VirtualService,
 		},
+		Messages: []*diag.MessageType{
+			msg.ReferencedInternalGateway,
+			msg.ReferencedResourceNotFound,
+			msg.VirtualServiceHostNotFoundInGateway,
+		},
 	}
 }
 
@@ -22,6 +22,7 @@ import (
 	"istio.io/istio/pkg/config"
 	"istio.io/istio/pkg/config/analysis"
 	"istio.io/istio/pkg/config/analysis/analyzers/util"
+	"istio.io/istio/pkg/config/analysis/diag"
 	"istio.io/istio/pkg/config/analysis/msg"
 	"istio.io/istio/pkg/config/constants"
 	"istio.io/istio/pkg/config/resource"
@@ -44,6 +45,7 @@ func (s *JWTClaimRouteAnalyzer) Metadata() analysis.Metadata {
 			gvk.Gateway,
 			gvk.Pod,
 		},
+		Messages: []*diag.MessageType{msg.JwtClaimBasedRoutingWithoutRequestAuthN},
 	}
 }
 
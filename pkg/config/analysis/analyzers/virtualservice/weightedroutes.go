@@ -0,0 +1,177 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package virtualservice
+
+import (
+	"fmt"
+
+	v1 "k8s.io/api/core/v1"
+	klabels "k8s.io/apimachinery/pkg/labels"
+
+	"istio.io/api/networking/v1alpha3"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/analysis"
+	"istio.io/istio/pkg/config/analysis/analyzers/util"
+	"istio.io/istio/pkg/config/analysis/diag"
+	"istio.io/istio/pkg/config/analysis/msg"
+	"istio.io/istio/pkg/config/resource"
+	"istio.io/istio/pkg/config/schema/gvk"
+)
+
+// WeightedRouteAnalyzer checks that the weights of route destinations add up to 100 and that the
+// subsets traffic is routed to match pods of the destination service.
+type WeightedRouteAnalyzer struct{}
+
+var _ analysis.Analyzer = &WeightedRouteAnalyzer{}
+
+// Metadata implements Analyzer
+func (a *WeightedRouteAnalyzer) Metadata() analysis.Metadata {
+	return analysis.Metadata{
+		Name:        "virtualservice.WeightedRouteAnalyzer",
+		Description: "Checks the weights of route destinations and that the subsets they route to match running pods",
+		Inputs: []config.GroupVersionKind{
+			gvk.VirtualService,
+			gvk.DestinationRule,
+			gvk.Service,
+			gvk.Pod,
+		},
+		Messages: []*diag.MessageType{
+			msg.UnreachableSubset,
+			msg.VirtualServiceRouteWeightSum,
+		},
+	}
+}
+
+type weightedDestination struct {
+	destination *v1alpha3.Destination
+	weight      int32
+}
+
+// Analyze implements Analyzer
+func (a *WeightedRouteAnalyzer) Analyze(ctx analysis.Context) {
+	subsetLabels := initSubsetLabels(ctx)
+
+	ctx.ForEach(gvk.VirtualService, func(r *resource.Instance) bool {
+		vs := r.Message.(*v1alpha3.VirtualService)
+		for i, route := range vs.GetHttp() {
+			dests := make([]weightedDestination, 0, len(route.GetRoute()))
+			for _, d := range route.GetRoute() {
+				dests = append(dests, weightedDestination{destination: d.GetDestination(), weight: d.GetWeight()})
+			}
+			a.analyzeRoute(ctx, r, "http", i, dests, subsetLabels)
+		}
+		for i, route := range vs.GetTcp() {
+			dests := make([]weightedDestination, 0, len(route.GetRoute()))
+			for _, d := range route.GetRoute() {
+				dests = append(dests, weightedDestination{destination: d.GetDestination(), weight: d.GetWeight()})
+			}
+			a.analyzeRoute(ctx, r, "tcp", i, dests, subsetLabels)
+		}
+		for i, route := range vs.GetTls() {
+			dests := make([]weightedDestination, 0, len(route.GetRoute()))
+			for _, d := range route.GetRoute() {
+				dests = append(dests, weightedDestination{destination: d.GetDestination(), weight: d.GetWeight()})
+			}
+			a.analyzeRoute(ctx, r, "tls", i, dests, subsetLabels)
+		}
+		return true
+	})
+}
+
+func (a *WeightedRouteAnalyzer) analyzeRoute(ctx analysis.Context, r *resource.Instance, routeRule string, routeIndex int,
+	dests []weightedDestination, subsetLabels map[hostAndSubset]map[string]string,
+) {
+	if len(dests) > 1 {
+		var sum int32
+		for _, d := range dests {
+			sum += d.weight
+		}
+		// A zero sum is rejected by validation.
+		if sum != 0 && sum != 100 {
+			m := msg.NewVirtualServiceRouteWeightSum(r, routeRule, routeIndex, sum)
+			if line, ok := util.ErrorLine(r, fmt.Sprintf(util.DestinationHost, routeRule, routeIndex, 0)); ok {
+				m.Line = line
+			}
+			ctx.Report(gvk.VirtualService, m)
+		}
+	}
+
+	for i, d := range dests {
+		// A destination without weight receives no traffic when the route has several destinations.
+		if len(dests) > 1 && d.weight == 0 {
+			continue
+		}
+		subset := d.destination.GetSubset()
+		if subset == "" {
+			continue
+		}
+		host := util.GetResourceNameFromHost(r.Metadata.FullName.Namespace, d.destination.GetHost())
+		labels, ok := subsetLabels[hostAndSubset{host: host, subset: subset}]
+		if !ok {
+			// Missing subsets are reported by the DestinationRuleAnalyzer.
+			continue
+		}
+		if reachable(ctx, host, labels) {
+			continue
+		}
+		m := msg.NewUnreachableSubset(r, subset, d.destination.GetHost(), host.String())
+		if line, ok := util.ErrorLine(r, fmt.Sprintf(util.DestinationHost, routeRule, routeIndex, i)); ok {
+			m.Line = line
+		}
+		ctx.Report(gvk.VirtualService, m)
+	}
+}
+
+// reachable returns true unless the service has pods and none of them match the subset labels. Services
+// that are not found, or without any pod, are not reported as the pods may simply not be part of the analysis.
+func reachable(ctx analysis.Context, service resource.FullName, subsetLabels map[string]string) bool {
+	svc := ctx.Find(gvk.Service, service)
+	if svc == nil {
+		return true
+	}
+	selector := svc.Message.(*v1.ServiceSpec).Selector
+	if len(selector) == 0 {
+		return true
+	}
+	serviceSelector := klabels.SelectorFromSet(selector)
+	subsetSelector := klabels.SelectorFromSet(subsetLabels)
+	selected, matched := false, false
+	ctx.ForEach(gvk.Pod, func(p *resource.Instance) bool {
+		if p.Metadata.FullName.Namespace != service.Namespace {
+			return true
+		}
+		podLabels := klabels.Set(p.Metadata.Labels)
+		if !serviceSelector.Matches(podLabels) {
+			return true
+		}
+		selected = true
+		matched = subsetSelector.Matches(podLabels)
+		return !matched
+	})
+	return !selected || matched
+}
+
+func initSubsetLabels(ctx analysis.Context) map[hostAndSubset]map[string]string {
+	res := make(map[hostAndSubset]map[string]string)
+	ctx.ForEach(gvk.DestinationRule, func(r *resource.Instance) bool {
+		dr := r.Message.(*v1alpha3.DestinationRule)
+		host := util.GetResourceNameFromHost(r.Metadata.FullName.Namespace, dr.GetHost())
+		for _, ss := range dr.GetSubsets() {
+			res[hostAndSubset{host: host, subset: ss.GetName()}] = ss.GetLabels()
+		}
+		return true
+	})
+	return res
+}
@@ -0,0 +1,310 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wasmplugin
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	extensions "istio.io/api/extensions/v1alpha1"
+	"istio.io/api/label"
+	meshconfig "istio.io/api/mesh/v1alpha1"
+	typev1beta1 "istio.io/api/type/v1beta1"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/analysis"
+	"istio.io/istio/pkg/config/analysis/analyzers/util"
+	"istio.io/istio/pkg/config/analysis/diag"
+	"istio.io/istio/pkg/config/analysis/msg"
+	"istio.io/istio/pkg/config/constants"
+	"istio.io/istio/pkg/config/resource"
+	"istio.io/istio/pkg/config/schema/gvk"
+	"istio.io/istio/pkg/util/sets"
+)
+
+// networkPluginVersion is the first Istio version whose proxies run NETWORK plugins.
+var networkPluginVersion = &model.IstioVersion{Major: 1, Minor: 20, Patch: -1}
+
+// Analyzer checks that:
+// * the WasmPlugins applying to the same pod in the same phase have different priorities, as their order is otherwise
+// not deterministic
+// * the Wasm modules of WasmPlugins can be fetched: their URL is valid and their pull secret exists
+// * the proxies of the pods WasmPlugins apply to can run them
+type Analyzer struct{}
+
+var _ analysis.Analyzer = &Analyzer{}
+
+// Metadata implements Analyzer
+func (a *Analyzer) Metadata() analysis.Metadata {
+	return analysis.Metadata{
+		Name: "wasmplugin.Analyzer",
+		Description: "Checks that WasmPlugins applying to the same workloads are ordered, that their modules can be fetched, " +
+			"and that the proxies of their workloads can run them",
+		Inputs: []config.GroupVersionKind{
+			gvk.WasmPlugin,
+			gvk.Pod,
+			gvk.Secret,
+			gvk.MeshConfig,
+		},
+		Messages: []*diag.MessageType{
+			msg.WasmPluginImageUnpullable,
+			msg.WasmPluginOrderingConflict,
+			msg.WasmPluginUnsupportedProxy,
+		},
+	}
+}
+
+// Analyze implements Analyzer
+func (a *Analyzer) Analyze(c analysis.Context) {
+	rootNamespace := constants.IstioSystemNamespace
+	c.ForEach(gvk.MeshConfig, func(r *resource.Instance) bool {
+		if ns := r.Message.(*meshconfig.MeshConfig).GetRootNamespace(); ns != "" {
+			rootNamespace = ns
+		}
+		return r.Metadata.FullName.Name != util.MeshConfigName
+	})
+
+	var plugins []*resource.Instance
+	c.ForEach(gvk.WasmPlugin, func(r *resource.Instance) bool {
+		plugins = append(plugins, r)
+		analyzeImage(r, c)
+		return true
+	})
+	if len(plugins) == 0 {
+		return
+	}
+
+	reported := sets.New[string]()
+	unsupported := sets.New[string]()
+	c.ForEach(gvk.Pod, func(r *resource.Instance) bool {
+		var applying []*resource.Instance
+		for _, p := range plugins {
+			if appliesTo(p, r, rootNamespace) {
+				applying = append(applying, p)
+			}
+		}
+		if len(applying) == 0 {
+			return true
+		}
+		pod := r.Metadata.FullName.String()
+
+		proxy := proxyContainer(r.Message.(*v1.PodSpec))
+		if proxy == nil {
+			if util.PodInAmbientMode(r) {
+				for _, p := range applying {
+					if !unsupported.InsertContains(p.Metadata.FullName.String()) {
+						c.Report(gvk.WasmPlugin, msg.NewWasmPluginUnsupportedProxy(p, pod,
+							"its traffic is handled by ztunnel, which does not run Wasm plugins, and the WasmPlugin should target its waypoint instead"))
+					}
+				}
+			}
+			return true
+		}
+		version := model.ParseIstioVersion(imageTag(proxy.Image))
+		if version.Compare(networkPluginVersion) < 0 {
+			for _, p := range applying {
+				if p.Message.(*extensions.WasmPlugin).GetType() == extensions.PluginType_NETWORK &&
+					!unsupported.InsertContains(p.Metadata.FullName.String()) {
+					c.Report(gvk.WasmPlugin, msg.NewWasmPluginUnsupportedProxy(p, pod,
+						fmt.Sprintf("NETWORK plugins require proxies of Istio %d.%d or later, and its proxy is %s",
+							networkPluginVersion.Major, networkPluginVersion.Minor, version)))
+				}
+			}
+		}
+
+		for i, p := range applying {
+			for _, o := range applying[i+1:] {
+				if !sameOrder(p, o) {
+					continue
+				}
+				names := getNames([]*resource.Instance{p, o})
+				if reported.InsertContains(strings.Join(names, ",")) {
+					continue
+				}
+				spec := p.Message.(*extensions.WasmPlugin)
+				for _, r := range []*resource.Instance{p, o} {
+					c.Report(gvk.WasmPlugin, msg.NewWasmPluginOrderingConflict(r, names, pod, phase(spec), priority(spec)))
+				}
+			}
+		}
+		return true
+	})
+}
+
+// analyzeImage reports the WasmPlugins whose module cannot be fetched.
+func analyzeImage(r *resource.Instance, c analysis.Context) {
+	spec := r.Message.(*extensions.WasmPlugin)
+	report := func(reason string) {
+		c.Report(gvk.WasmPlugin, msg.NewWasmPluginImageUnpullable(r, spec.GetUrl(), reason))
+	}
+	u, err := url.Parse(spec.GetUrl())
+	if err != nil {
+		report(fmt.Sprintf("the URL is invalid: %v", err))
+		return
+	}
+	switch u.Scheme {
+	case "", "oci":
+		ref, err := name.ParseReference(strings.TrimPrefix(spec.GetUrl(), "oci://"))
+		if err != nil {
+			report(fmt.Sprintf("the image reference is invalid: %v", err))
+			return
+		}
+		if d, ok := ref.(name.Digest); ok && spec.GetSha256() != "" &&
+			strings.TrimPrefix(d.DigestStr(), "sha256:") != spec.GetSha256() {
+			report(fmt.Sprintf("the digest of the image does not match the sha256 %s of the WasmPlugin", spec.GetSha256()))
+			return
+		}
+		// The pull secret is only used to fetch images, and must be in the namespace of the WasmPlugin.
+		if secret := spec.GetImagePullSecret(); secret != "" &&
+			!c.Exists(gvk.Secret, resource.NewFullName(r.Metadata.FullName.Namespace, resource.LocalName(secret))) {
+			report(fmt.Sprintf("the image pull secret %s does not exist in the namespace %s", secret, r.Metadata.FullName.Namespace))
+		}
+	case "http", "https", "file":
+		// Fetched over HTTP or read by the proxy, which cannot be checked from the config.
+	default:
+		report(fmt.Sprintf("the scheme %q is not supported, use oci, http, https or file", u.Scheme))
+	}
+}
+
+// appliesTo returns whether the WasmPlugin applies to the pod.
+func appliesTo(p, pod *resource.Instance, rootNamespace string) bool {
+	spec := p.Message.(*extensions.WasmPlugin)
+	refs := spec.GetTargetRefs()
+	if spec.GetTargetRef() != nil {
+		refs = append(refs, spec.GetTargetRef())
+	}
+	if len(refs) > 0 {
+		if p.Metadata.FullName.Namespace != pod.Metadata.FullName.Namespace {
+			return false
+		}
+		gateway := pod.Metadata.Labels[label.IoK8sNetworkingGatewayGatewayName.Name]
+		for _, ref := range refs {
+			if ref.GetKind() == gvk.KubernetesGateway.Kind && ref.GetName() == gateway {
+				return true
+			}
+		}
+		return false
+	}
+	if p.Metadata.FullName.Namespace != pod.Metadata.FullName.Namespace && p.Metadata.FullName.Namespace.String() != rootNamespace {
+		return false
+	}
+	selector := spec.GetSelector().GetMatchLabels()
+	return len(selector) == 0 || labels.SelectorFromSet(selector).Matches(labels.Set(pod.Metadata.Labels))
+}
+
+// sameOrder returns whether the WasmPlugins are in the same filter chain, at the same position, for some traffic.
+func sameOrder(a, b *resource.Instance) bool {
+	sa, sb := a.Message.(*extensions.WasmPlugin), b.Message.(*extensions.WasmPlugin)
+	return pluginType(sa) == pluginType(sb) && sa.GetPhase() == sb.GetPhase() &&
+		priority(sa) == priority(sb) && trafficOverlaps(sa.GetMatch(), sb.GetMatch())
+}
+
+// trafficOverlaps returns whether some traffic is selected by both sets of traffic selectors, any traffic being
+// selected by an empty set.
+func trafficOverlaps(a, b []*extensions.WasmPlugin_TrafficSelector) bool {
+	if len(a) == 0 || len(b) == 0 {
+		return true
+	}
+	for _, sa := range a {
+		for _, sb := range b {
+			if modesOverlap(sa.GetMode(), sb.GetMode()) && portsOverlap(sa.GetPorts(), sb.GetPorts()) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func modesOverlap(a, b typev1beta1.WorkloadMode) bool {
+	both := func(m typev1beta1.WorkloadMode) bool {
+		return m == typev1beta1.WorkloadMode_UNDEFINED || m == typev1beta1.WorkloadMode_CLIENT_AND_SERVER
+	}
+	return a == b || both(a) || both(b)
+}
+
+func portsOverlap(a, b []*typev1beta1.PortSelector) bool {
+	if len(a) == 0 || len(b) == 0 {
+		return true
+	}
+	ports := sets.New[uint32]()
+	for _, p := range a {
+		ports.Insert(p.GetNumber())
+	}
+	for _, p := range b {
+		if ports.Contains(p.GetNumber()) {
+			return true
+		}
+	}
+	return false
+}
+
+func pluginType(p *extensions.WasmPlugin) extensions.PluginType {
+	if p.GetType() == extensions.PluginType_UNSPECIFIED_PLUGIN_TYPE {
+		return extensions.PluginType_HTTP
+	}
+	return p.GetType()
+}
+
+func phase(p *extensions.WasmPlugin) string {
+	if p.GetPhase() == extensions.PluginPhase_UNSPECIFIED_PHASE {
+		return "default"
+	}
+	return p.GetPhase().String()
+}
+
+func priority(p *extensions.WasmPlugin) string {
+	if p.GetPriority() == nil {
+		return "unset"
+	}
+	return fmt.Sprint(p.GetPriority().GetValue())
+}
+
+func proxyContainer(pod *v1.PodSpec) *v1.Container {
+	for i := range pod.Containers {
+		if pod.Containers[i].Name == util.IstioProxyName {
+			return &pod.Containers[i]
+		}
+	}
+	for i := range pod.InitContainers {
+		if pod.InitContainers[i].Name == util.IstioProxyName {
+			return &pod.InitContainers[i]
+		}
+	}
+	return nil
+}
+
+// imageTag returns the tag of the image, or an empty string if it has none.
+func imageTag(image string) string {
+	image, _, _ = strings.Cut(image, "@")
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		return image[i+1:]
+	}
+	return ""
+}
+
+func getNames(entries []*resource.Instance) []string {
+	names := make([]string, 0, len(entries))
+	for _, r := range entries {
+		names = append(names, r.Metadata.FullName.String())
+	}
+	sort.Strings(names)
+	return names
+}
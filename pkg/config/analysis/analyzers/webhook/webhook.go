@@ -25,6 +25,7 @@ import (
 	"istio.io/api/label"
 	"istio.io/istio/pkg/config"
 	"istio.io/istio/pkg/config/analysis"
+	"istio.io/istio/pkg/config/analysis/diag"
 	"istio.io/istio/pkg/config/analysis/msg"
 	"istio.io/istio/pkg/config/resource"
 	"istio.io/istio/pkg/config/schema/gvk"
@@ -45,6 +46,7 @@ func (a *Analyzer) Metadata() analysis.Metadata {
 		Inputs: []config.GroupVersionKind{
 			gvk.MutatingWebhookConfiguration,
 		},
+		Messages: []*diag.MessageType{msg.InvalidWebhook},
 	}
 	if !a.SkipServiceCheck {
 		meta.Inputs = append(meta.Inputs, gvk.Service)
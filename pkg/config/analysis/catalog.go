@@ -0,0 +1,68 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package analysis
+
+import (
+	"sort"
+
+	"istio.io/istio/pkg/util/sets"
+)
+
+// AnalyzerInfo describes an analyzer and the messages it reports, for documentation generators and policy tooling.
+type AnalyzerInfo struct {
+	Name        string        `json:"name"`
+	Description string        `json:"description"`
+	Inputs      []string      `json:"inputs"`
+	Messages    []MessageInfo `json:"messages"`
+}
+
+// MessageInfo describes a type of message reported by analyzers.
+type MessageInfo struct {
+	Code     string `json:"code"`
+	Level    string `json:"level"`
+	Template string `json:"template"`
+}
+
+// Catalog returns the description of the analyzers, sorted by name, and of the messages they report, sorted by code.
+func Catalog(analyzers ...Analyzer) []AnalyzerInfo {
+	seen := sets.New[string]()
+	catalog := make([]AnalyzerInfo, 0, len(analyzers))
+	for _, a := range analyzers {
+		m := a.Metadata()
+		if seen.InsertContains(m.Name) {
+			continue
+		}
+		info := AnalyzerInfo{
+			Name:        m.Name,
+			Description: m.Description,
+			Inputs:      make([]string, 0, len(m.Inputs)),
+			Messages:    make([]MessageInfo, 0, len(m.Messages)),
+		}
+		for _, in := range m.Inputs {
+			info.Inputs = append(info.Inputs, in.String())
+		}
+		for _, t := range m.Messages {
+			info.Messages = append(info.Messages, MessageInfo{Code: t.Code(), Level: t.Level().String(), Template: t.Template()})
+		}
+		sort.Slice(info.Messages, func(i, j int) bool {
+			return info.Messages[i].Code < info.Messages[j].Code
+		})
+		catalog = append(catalog, info)
+	}
+	sort.Slice(catalog, func(i, j int) bool {
+		return catalog[i].Name < catalog[j].Name
+	})
+	return catalog
+}
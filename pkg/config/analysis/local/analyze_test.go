@@ -31,9 +31,11 @@ import (
 	"istio.io/istio/pkg/config/analysis/msg"
 	"istio.io/istio/pkg/config/mesh"
 	"istio.io/istio/pkg/config/resource"
+	"istio.io/istio/pkg/config/schema/gvk"
 	"istio.io/istio/pkg/kube"
 	"istio.io/istio/pkg/kube/controllers"
 	"istio.io/istio/pkg/test/util/assert"
+	"istio.io/istio/pkg/util/sets"
 )
 
 type testAnalyzer struct {
@@ -138,6 +140,93 @@ func TestFilterOutputByNamespace(t *testing.T) {
 	g.Expect(result.Messages).To(ConsistOf(msg1))
 }
 
+func TestSeverities(t *testing.T) {
+	g := NewWithT(t)
+
+	cancel := make(chan struct{})
+
+	r := createTestResource(t, "ns", "resource", "v1")
+	internal := msg.NewInternalError(r, "msg")
+	deprecated := msg.NewDeprecated(r, "field")
+	unknownAnnotation := msg.NewUnknownAnnotation(r, "foo")
+	a := &testAnalyzer{
+		fn: func(ctx analysis.Context) {
+			ctx.Report(K8SCollection1.GroupVersionKind(), internal)
+			ctx.Report(K8SCollection1.GroupVersionKind(), deprecated)
+			ctx.Report(K8SCollection1.GroupVersionKind(), unknownAnnotation)
+		},
+	}
+
+	sa := NewSourceAnalyzer(analysis.Combine("a", a), "", "", nil)
+	sa.SetSeverities([]AnalysisSeverity{
+		{Code: deprecated.Type.Code(), Level: diag.Error},
+		{Code: unknownAnnotation.Type.Code(), Ignore: true},
+	})
+	err := sa.AddReaderKubeSource(nil)
+	g.Expect(err).To(BeNil())
+
+	result, err := sa.Analyze(cancel)
+	g.Expect(err).To(BeNil())
+	g.Expect(result.Messages).To(HaveLen(2))
+	g.Expect(result.Messages[0].Type.Code()).To(Equal(internal.Type.Code()))
+	g.Expect(result.Messages[1].Type.Code()).To(Equal(deprecated.Type.Code()))
+	g.Expect(result.Messages[1].Type.Level()).To(Equal(diag.Error))
+	g.Expect(result.Messages[1].String()).To(Equal(strings.Replace(deprecated.String(), "Warning", "Error", 1)))
+}
+
+func TestReAnalyzeIncremental(t *testing.T) {
+	g := NewWithT(t)
+
+	cancel := make(chan struct{})
+
+	r := createTestResource(t, "ns", "resource", "v1")
+	runs := map[string]int{}
+	newAnalyzer := func(name string, input config.GroupVersionKind, m diag.Message) analysis.Analyzer {
+		return &namedTestAnalyzer{name: name, testAnalyzer: testAnalyzer{
+			fn: func(ctx analysis.Context) {
+				runs[name]++
+				ctx.Report(input, m)
+			},
+			inputs: []config.GroupVersionKind{input},
+		}}
+	}
+	internal := msg.NewInternalError(r, "msg")
+	deprecated := msg.NewDeprecated(r, "field")
+	a1 := newAnalyzer("a1", gvk.DestinationRule, internal)
+	a2 := newAnalyzer("a2", gvk.VirtualService, deprecated)
+
+	sa := NewSourceAnalyzer(analysis.Combine("all", a1, a2), "", "", nil)
+	err := sa.AddReaderKubeSource(nil)
+	g.Expect(err).To(BeNil())
+	g.Expect(sa.Init(cancel)).To(Succeed())
+
+	// Without a previous execution, all the analyzers are executed.
+	result, err := sa.ReAnalyzeIncremental(sets.New(gvk.VirtualService), cancel)
+	g.Expect(err).To(BeNil())
+	g.Expect(result.Messages).To(ConsistOf(internal, deprecated))
+	g.Expect(runs).To(Equal(map[string]int{"a1": 1, "a2": 1}))
+
+	result, err = sa.ReAnalyzeIncremental(sets.New(gvk.VirtualService), cancel)
+	g.Expect(err).To(BeNil())
+	g.Expect(result.ExecutedAnalyzers).To(ConsistOf("a2"))
+	g.Expect(result.Messages).To(ConsistOf(internal, deprecated))
+	g.Expect(runs).To(Equal(map[string]int{"a1": 1, "a2": 2}))
+}
+
+// namedTestAnalyzer is a testAnalyzer with its own name.
+type namedTestAnalyzer struct {
+	testAnalyzer
+	name string
+}
+
+// Metadata implements Analyzer
+func (a *namedTestAnalyzer) Metadata() analysis.Metadata {
+	return analysis.Metadata{
+		Name:   a.name,
+		Inputs: a.inputs,
+	}
+}
+
 func TestAddInMemorySource(t *testing.T) {
 	g := NewWithT(t)
 
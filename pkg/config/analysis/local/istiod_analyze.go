@@ -73,6 +73,12 @@ type IstiodAnalyzer struct {
 	// List of code and resource suppressions to exclude messages on
 	suppressions []AnalysisSuppression
 
+	// List of codes to change the level of the messages of
+	severities []AnalysisSeverity
+
+	// Messages of each analyzer at its last execution, reused by ReAnalyzeIncremental
+	analyzerMessages map[string]diag.Messages
+
 	// Mesh config for this analyzer. This can come from multiple sources, and the last added version will take precedence.
 	meshCfg *v1alpha1.MeshConfig
 
@@ -133,6 +139,25 @@ func (sa *IstiodAnalyzer) ReAnalyzeSubset(kinds sets.Set[config.GroupVersionKind
 	return sa.internalAnalyze(subset, cancel)
 }
 
+// ReAnalyzeIncremental executes again only the analyzers with an input of the
+// kinds changed, and reuses the messages of the other analyzers from their last
+// execution. All the analyzers are executed if none was before.
+func (sa *IstiodAnalyzer) ReAnalyzeIncremental(kinds sets.Set[config.GroupVersionKind], cancel <-chan struct{}) (AnalysisResult, error) {
+	if sa.analyzerMessages == nil {
+		return sa.ReAnalyze(cancel)
+	}
+	result, err := sa.ReAnalyzeSubset(kinds, cancel)
+	if err != nil {
+		return result, err
+	}
+	var msgs diag.Messages
+	for _, m := range sa.analyzerMessages {
+		msgs.Add(m...)
+	}
+	result.Messages = msgs.SortedDedupedCopy()
+	return result, nil
+}
+
 // ReAnalyze loads the sources and executes the analysis, assuming init is already called
 func (sa *IstiodAnalyzer) ReAnalyze(cancel <-chan struct{}) (AnalysisResult, error) {
 	return sa.internalAnalyze(sa.analyzer, cancel)
@@ -169,10 +194,16 @@ func (sa *IstiodAnalyzer) internalAnalyze(a analysis.CombinedAnalyzer, cancel <-
 	for _, analyzerName := range result.ExecutedAnalyzers {
 
 		// TODO: analysis is run for all namespaces, even if they are requested to be filtered.
-		msgs := filterMessages(ctx.(*istiodContext).GetMessages(analyzerName), namespaces, sa.suppressions)
+		msgs := filterMessages(ctx.(*istiodContext).GetMessages(analyzerName), namespaces, sa.suppressions, sa.severities)
 		result.MappedMessages[analyzerName] = msgs.SortedDedupedCopy()
 	}
-	msgs := filterMessages(ctx.(*istiodContext).GetMessages(), namespaces, sa.suppressions)
+	if sa.analyzerMessages == nil {
+		sa.analyzerMessages = make(map[string]diag.Messages, len(result.MappedMessages))
+	}
+	for analyzerName, msgs := range result.MappedMessages {
+		sa.analyzerMessages[analyzerName] = msgs
+	}
+	msgs := filterMessages(ctx.(*istiodContext).GetMessages(), namespaces, sa.suppressions, sa.severities)
 	result.Messages = msgs.SortedDedupedCopy()
 
 	return result, nil
@@ -265,6 +296,13 @@ func (sa *IstiodAnalyzer) SetSuppressions(suppressions []AnalysisSuppression) {
 	sa.suppressions = suppressions
 }
 
+// SetSeverities will set the list of severity overrides for the analyzer. The
+// messages of the codes overridden are reported at the level of the override,
+// or not included in the final message output if ignored.
+func (sa *IstiodAnalyzer) SetSeverities(severities []AnalysisSeverity) {
+	sa.severities = severities
+}
+
 // AddTestReaderKubeSource adds a yaml source to the analyzer, which will analyze
 // runtime resources like pods and namespaces for use in tests.
 func (sa *IstiodAnalyzer) AddTestReaderKubeSource(readers []ReaderSource) error {
@@ -276,6 +314,13 @@ func (sa *IstiodAnalyzer) AddReaderKubeSource(readers []ReaderSource) error {
 	return sa.addReaderKubeSourceInternal(readers, false)
 }
 
+// AddClusterDumpKubeSource adds a source based on the resources dumped from a cluster, such as those archived by a bug
+// report. Unlike config files, a dump also holds the runtime resources of the cluster, like pods and namespaces, which
+// are analyzed too. It must be added before any other yaml source.
+func (sa *IstiodAnalyzer) AddClusterDumpKubeSource(readers []ReaderSource) error {
+	return sa.addReaderKubeSourceInternal(readers, true)
+}
+
 func (sa *IstiodAnalyzer) addReaderKubeSourceInternal(readers []ReaderSource, includeRuntimeResources bool) error {
 	var src *file.KubeSource
 	if sa.fileSource != nil {
@@ -520,8 +565,13 @@ func (sa *IstiodAnalyzer) addRunningKubeIstioConfigMapSource(client kubelib.Clie
 	if err != nil {
 		return fmt.Errorf("could not read configmap %q from namespace %q: %v", meshConfigMapName, sa.istioNamespace, err)
 	}
+	return sa.AddKubeMeshConfigMap(meshConfigMap.Data)
+}
 
-	configYaml, ok := meshConfigMap.Data[meshConfigMapKey]
+// AddKubeMeshConfigMap gets mesh config and mesh networks from the data of the istio ConfigMap, such as the one of a
+// cluster dump.
+func (sa *IstiodAnalyzer) AddKubeMeshConfigMap(data map[string]string) error {
+	configYaml, ok := data[meshConfigMapKey]
 	if !ok {
 		return fmt.Errorf("missing config map key %q", meshConfigMapKey)
 	}
@@ -533,7 +583,7 @@ func (sa *IstiodAnalyzer) addRunningKubeIstioConfigMapSource(client kubelib.Clie
 
 	sa.meshCfg = cfg
 
-	meshNetworksYaml, ok := meshConfigMap.Data[meshNetworksMapKey]
+	meshNetworksYaml, ok := data[meshNetworksMapKey]
 	if !ok {
 		return fmt.Errorf("missing config map key %q", meshNetworksMapKey)
 	}
@@ -557,7 +607,9 @@ func (sa *IstiodAnalyzer) AddSourceForCluster(src model.ConfigStoreController, c
 type CollectionReporterFn func(config.GroupVersionKind)
 
 // copied from processing/snapshotter/analyzingdistributor.go
-func filterMessages(messages diag.Messages, namespaces sets.Set[resource.Namespace], suppressions []AnalysisSuppression) diag.Messages {
+func filterMessages(messages diag.Messages, namespaces sets.Set[resource.Namespace], suppressions []AnalysisSuppression,
+	severities []AnalysisSeverity,
+) diag.Messages {
 	nsNames := sets.New[string]()
 	for k := range namespaces {
 		nsNames.Insert(k.String())
@@ -599,6 +651,18 @@ FilterMessages:
 			continue FilterMessages
 		}
 
+		// Override the level of the messages of the codes in our severities, or filter them out if ignored.
+		for _, s := range severities {
+			if s.Code != m.Type.Code() {
+				continue
+			}
+			if s.Ignore {
+				scope.Analysis.Debugf("Ignoring code %s due to severities list", m.Type.Code())
+				continue FilterMessages
+			}
+			m.Type = diag.NewMessageType(s.Level, m.Type.Code(), m.Type.Template())
+		}
+
 		msgs = append(msgs, m)
 	}
 	return msgs
@@ -618,6 +682,21 @@ type AnalysisSuppression struct {
 	ResourceName string
 }
 
+// AnalysisSeverity overrides the level of the messages of an analysis code, so
+// that a message can be promoted to an error failing the analysis, or demoted
+// or ignored when not relevant.
+type AnalysisSeverity struct {
+	// Code is the analysis code to override the level of (e.g. "IST0118").
+	Code string
+
+	// Level is the level the messages of the code are reported at.
+	Level diag.Level
+
+	// Ignore excludes the messages of the code from the analysis, regardless
+	// of Level.
+	Ignore bool
+}
+
 // ReaderSource is a tuple of a io.Reader and filepath.
 type ReaderSource struct {
 	// Name is the name of the source (commonly the path to a file, but can be "-" for sources read from stdin or "" if completely synthetic).
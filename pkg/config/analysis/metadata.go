@@ -14,7 +14,10 @@
 
 package analysis
 
-import "istio.io/istio/pkg/config"
+import (
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/analysis/diag"
+)
 
 // Metadata represents metadata for an analyzer
 type Metadata struct {
@@ -23,4 +26,7 @@ type Metadata struct {
 	// field is displayed to users when --list-analyzers is called.
 	Description string
 	Inputs      []config.GroupVersionKind
+	// Messages are the types of the messages the analyzer reports. They are
+	// listed in the catalog of analyzers.
+	Messages []*diag.MessageType
 }
@@ -251,6 +251,198 @@ var (
 	// MultiClusterInconsistentService defines a diag.MessageType for message "MultiClusterInconsistentService".
 	// Description: The services live in different clusters under multi-cluster deployment model are inconsistent
 	MultiClusterInconsistentService = diag.NewMessageType(diag.Warning, "IST0170", "The service %v in namespace %q is inconsistent across clusters %q, which can lead to undefined behaviors. The inconsistent behaviors are: %v.")
+
+	// ProxyStartupOrderingMissing defines a diag.MessageType for message "ProxyStartupOrderingMissing".
+	// Description: A workload may make network calls at startup before the sidecar proxy is ready
+	ProxyStartupOrderingMissing = diag.NewMessageType(diag.Info, "IST0171", "The workload %s may make network calls before the sidecar proxy is ready: %s.")
+
+	// OutdatedIstioCRD defines a diag.MessageType for message "OutdatedIstioCRD".
+	// Description: An installed Istio CRD does not match the version of the control plane
+	OutdatedIstioCRD = diag.NewMessageType(diag.Warning, "IST0172", "The CustomResourceDefinition %s does not match the version of the control plane: %s. Upgrade the Istio CRDs to the version of the control plane, otherwise fields added in this version may be rejected or pruned.")
+
+	// VirtualServiceRouteWeightSum defines a diag.MessageType for message "VirtualServiceRouteWeightSum".
+	// Description: The weights of the destinations of a VirtualService route do not add up to 100
+	VirtualServiceRouteWeightSum = diag.NewMessageType(diag.Info, "IST0173", "The weights of the destinations of %s route %d add up to %d rather than 100; traffic is split in proportion to the weights.")
+
+	// UnreachableSubset defines a diag.MessageType for message "UnreachableSubset".
+	// Description: A VirtualService routes traffic to a subset that does not match any pod
+	UnreachableSubset = diag.NewMessageType(diag.Warning, "IST0174", "The subset %q of host %s does not match any pod selected by service %s; traffic routed to it will fail with 503 errors.")
+
+	// CACertificateLifetime defines a diag.MessageType for message "CACertificateLifetime".
+	// Description: Remaining lifetime of a certificate of the Istio CA
+	CACertificateLifetime = diag.NewMessageType(diag.Info, "IST0175", "The %s certificate %q in secret %s is valid until %s (%s remaining).")
+
+	// CACertificateExpiring defines a diag.MessageType for message "CACertificateExpiring".
+	// Description: A certificate of the Istio CA expires soon
+	CACertificateExpiring = diag.NewMessageType(diag.Warning, "IST0176", "The %s certificate %q in secret %s expires at %s, in %s; rotate it before it expires.")
+
+	// CACertificateExpired defines a diag.MessageType for message "CACertificateExpired".
+	// Description: A certificate of the Istio CA has expired
+	CACertificateExpired = diag.NewMessageType(diag.Error, "IST0177", "The %s certificate %q in secret %s expired at %s; workload certificates it signs are not trusted.")
+
+	// CACertificateTrustDomainMismatch defines a diag.MessageType for message "CACertificateTrustDomainMismatch".
+	// Description: A certificate of the Istio CA is issued for a trust domain other than the mesh trust domain
+	CACertificateTrustDomainMismatch = diag.NewMessageType(diag.Warning, "IST0178", "The %s certificate %q in secret %s is issued for trust domain %s, but the mesh trust domain is %s.")
+
+	// RootCertBundleMismatch defines a diag.MessageType for message "RootCertBundleMismatch".
+	// Description: The root certificate distributed to a namespace does not match the Istio CA
+	RootCertBundleMismatch = diag.NewMessageType(diag.Warning, "IST0179", "The istio-ca-root-cert ConfigMap in namespace %s does not contain the root certificate of the CA in secret %s; workloads in the namespace will not trust the certificates it signs.")
+
+	// ConfigSizeBudgetExceeded defines a diag.MessageType for message "ConfigSizeBudgetExceeded".
+	// Description: The configuration of a proxy or namespace exceeds the size budget of the mesh
+	ConfigSizeBudgetExceeded = diag.NewMessageType(diag.Warning, "IST0180", "%s %s has %d %s, exceeding the budget of %d.")
+
+	// RevisionTagServiceNotFound defines a diag.MessageType for message "RevisionTagServiceNotFound".
+	// Description: A revision tag webhook points at an injection service which does not exist
+	RevisionTagServiceNotFound = diag.NewMessageType(diag.Error, "IST0181", "The webhook %s of revision tag %q points at service %s, which does not exist; pods using the tag will fail to be created or will not be injected.")
+
+	// RevisionTagServiceMismatch defines a diag.MessageType for message "RevisionTagServiceMismatch".
+	// Description: A revision tag webhook points at the injection service of another revision
+	RevisionTagServiceMismatch = diag.NewMessageType(diag.Warning, "IST0182", "Revision tag %q is labeled for revision %s, but its webhook %s points at service %s of revision %s.")
+
+	// RevisionTagRevisionNotInstalled defines a diag.MessageType for message "RevisionTagRevisionNotInstalled".
+	// Description: A revision tag points at a revision which is not installed
+	RevisionTagRevisionNotInstalled = diag.NewMessageType(diag.Warning, "IST0183", "Revision tag %q points at revision %s, which is not installed; pods in %s will not be injected.")
+
+	// NamespaceRevisionNotFound defines a diag.MessageType for message "NamespaceRevisionNotFound".
+	// Description: A namespace is labeled with a revision which is neither installed nor a revision tag
+	NamespaceRevisionNotFound = diag.NewMessageType(diag.Warning, "IST0184", "The namespace is labeled istio.io/rev=%s, which matches neither an installed revision nor a revision tag, so its pods will not be injected.")
+
+	// EnvoyFilterIncompatible defines a diag.MessageType for message "EnvoyFilterIncompatible".
+	// Description: An EnvoyFilter patch is not compatible with the proxies of this Istio release
+	EnvoyFilterIncompatible = diag.NewMessageType(diag.Warning, "IST0185", "Patch %d of the EnvoyFilter is not compatible with the proxies of Istio %s: %s.")
+
+	// PodSecurityLevelIncompatible defines a diag.MessageType for message "PodSecurityLevelIncompatible".
+	// Description: A namespace enforces a Pod Security level which Istio components installed in it do not satisfy
+	PodSecurityLevelIncompatible = diag.NewMessageType(diag.Error, "IST0186", "The namespace enforces the %q Pod Security level, but %s requires the %q level, so its pods will be rejected on admission; relax the pod-security.kubernetes.io/enforce label of the namespace or install it into another namespace.")
+
+	// IstiodReplicasNotReady defines a diag.MessageType for message "IstiodReplicasNotReady".
+	// Description: Replicas of istiod are not ready to serve proxies
+	IstiodReplicasNotReady = diag.NewMessageType(diag.Warning, "IST0187", "%d of %d replicas of istiod are not ready: %s.")
+
+	// LeaderElectionHolderUnhealthy defines a diag.MessageType for message "LeaderElectionHolderUnhealthy".
+	// Description: A leader election lock of istiod is held by a pod which does not renew it
+	LeaderElectionHolderUnhealthy = diag.NewMessageType(diag.Warning, "IST0188", "The leader election lock is held by %s, which %s; the controllers it elects do not run until another istiod replica acquires it.")
+
+	// PodDisruptionBudgetBlocksDrain defines a diag.MessageType for message "PodDisruptionBudgetBlocksDrain".
+	// Description: A PodDisruptionBudget allows no voluntary disruption of istiod, blocking node drains
+	PodDisruptionBudgetBlocksDrain = diag.NewMessageType(diag.Warning, "IST0189", "The PodDisruptionBudget requires %s available replicas of istiod %s, which runs %d, so no replica can be evicted and node drains will block; run more replicas than the budget requires.")
+
+	// WorkloadNotCoveredByAuthorizationPolicy defines a diag.MessageType for message "WorkloadNotCoveredByAuthorizationPolicy".
+	// Description: A service receives traffic without AuthorizationPolicy while other workloads of its namespace are locked down
+	WorkloadNotCoveredByAuthorizationPolicy = diag.NewMessageType(diag.Warning, "IST0190", "No AuthorizationPolicy applies to the workloads of the service, so requests to it are allowed from any source, while %s lock down other workloads of the namespace.")
+
+	// AuthorizationPolicyShadowedByDeny defines a diag.MessageType for message "AuthorizationPolicyShadowedByDeny".
+	// Description: An ALLOW AuthorizationPolicy never applies, as a broader DENY policy denies all the requests it allows
+	AuthorizationPolicyShadowedByDeny = diag.NewMessageType(diag.Warning, "IST0191", "The ALLOW policy never allows a request, as the DENY AuthorizationPolicy %s denies all the requests to its workloads.")
+
+	// BackendReferenceNotPermitted defines a diag.MessageType for message "BackendReferenceNotPermitted".
+	// Description: A route references a backend in another namespace, which no ReferenceGrant permits
+	BackendReferenceNotPermitted = diag.NewMessageType(diag.Error, "IST0192", "The backend %s is in namespace %s, and no ReferenceGrant of that namespace permits references to it from the route, so the route does not send traffic to it.")
+
+	// BackendPortNotFound defines a diag.MessageType for message "BackendPortNotFound".
+	// Description: A route references a port which its backend Service does not define
+	BackendPortNotFound = diag.NewMessageType(diag.Error, "IST0193", "The backend Service %s has no port %d.")
+
+	// GatewayListenerConflict defines a diag.MessageType for message "GatewayListenerConflict".
+	// Description: A listener of a Kubernetes Gateway conflicts with a server of an Istio Gateway selecting the same gateway pods
+	GatewayListenerConflict = diag.NewMessageType(diag.Warning, "IST0194", "The listener %s conflicts with a server on port %d of the Istio Gateway %s, which selects the same gateway pods and serves overlapping hosts.")
+
+	// ConflictingTelemetrySettings defines a diag.MessageType for message "ConflictingTelemetrySettings".
+	// Description: Telemetry resources targeting the same workloads set conflicting providers or sampling
+	ConflictingTelemetrySettings = diag.NewMessageType(diag.Warning, "IST0195", "The Telemetries %v target the same %s and set conflicting %s, so which of them applies is undefined.")
+
+	// TelemetryProviderNotFound defines a diag.MessageType for message "TelemetryProviderNotFound".
+	// Description: A Telemetry resource references a provider which the extensionProviders of the mesh config do not define
+	TelemetryProviderNotFound = diag.NewMessageType(diag.Error, "IST0196", "The %s provider %q is not defined in the extensionProviders of the mesh config, and is ignored.")
+
+	// TelemetrySettingsOverridden defines a diag.MessageType for message "TelemetrySettingsOverridden".
+	// Description: The settings of a namespace Telemetry resource have no effect, as the mesh-wide Telemetry disables them
+	TelemetrySettingsOverridden = diag.NewMessageType(diag.Warning, "IST0197", "The tracing settings of the Telemetry have no effect, as the mesh-wide Telemetry %s disables span reporting and the Telemetry does not enable it.")
+
+	// UnscopedSidecarConfig defines a diag.MessageType for message "UnscopedSidecarConfig".
+	// Description: The proxies of a namespace see many services, and no Sidecar resource restricts their egress hosts
+	UnscopedSidecarConfig = diag.NewMessageType(diag.Warning, "IST0198", "The proxies of the namespace see %d services, about %s of config per proxy, and no Sidecar resource restricts their egress hosts. A Sidecar restricting the egress hosts to the services of the namespace and of %s would remove %d of them, about %s per proxy.")
+
+	// WasmPluginOrderingConflict defines a diag.MessageType for message "WasmPluginOrderingConflict".
+	// Description: WasmPlugins applying to the same workload have the same phase and priority, so their order is not deterministic
+	WasmPluginOrderingConflict = diag.NewMessageType(diag.Warning, "IST0199", "The WasmPlugins %v apply to the pod %s in the %s phase with the same priority %s, so their order is not deterministic. Set different priorities.")
+
+	// WasmPluginImageUnpullable defines a diag.MessageType for message "WasmPluginImageUnpullable".
+	// Description: The Wasm module of a WasmPlugin cannot be fetched
+	WasmPluginImageUnpullable = diag.NewMessageType(diag.Error, "IST0200", "The Wasm module %q cannot be fetched: %s.")
+
+	// WasmPluginUnsupportedProxy defines a diag.MessageType for message "WasmPluginUnsupportedProxy".
+	// Description: A WasmPlugin applies to workloads whose proxies cannot run it
+	WasmPluginUnsupportedProxy = diag.NewMessageType(diag.Warning, "IST0201", "The WasmPlugin applies to the pod %s, whose proxy cannot run it: %s.")
+
+	// DestinationRuleTrafficPolicyDiscarded defines a diag.MessageType for message "DestinationRuleTrafficPolicyDiscarded".
+	// Description: Settings of the top-level trafficPolicy of a DestinationRule are discarded, as another DestinationRule for the same host takes precedence
+	DestinationRuleTrafficPolicyDiscarded = diag.NewMessageType(diag.Warning, "IST0202", "The DestinationRule %s takes precedence for the host %s, so the fields %s of the trafficPolicy of this DestinationRule are discarded. The effective trafficPolicy is %s.")
+
+	// CNINodeNotCovered defines a diag.MessageType for message "CNINodeNotCovered".
+	// Description: A node is not covered by exactly one Istio CNI DaemonSet of its node pool
+	CNINodeNotCovered = diag.NewMessageType(diag.Error, "IST0203", "The node %s %s, so the traffic of its pods may not be redirected to their proxies; check the nodePools of the istio-cni chart.")
+
+	// MeshConfigInvalid defines a diag.MessageType for message "MeshConfigInvalid".
+	// Description: The mesh config of the control plane has unknown fields or invalid values
+	MeshConfigInvalid = diag.NewMessageType(diag.Error, "IST0204", "The mesh config in the ConfigMap %s is invalid: %s; %s.")
+
+	// MeshConfigDeprecatedField defines a diag.MessageType for message "MeshConfigDeprecatedField".
+	// Description: The mesh config of the control plane sets deprecated fields
+	MeshConfigDeprecatedField = diag.NewMessageType(diag.Warning, "IST0205", "The mesh config in the ConfigMap %s sets the deprecated field %s, which may be ignored or removed in a later release.")
+
+	// IstiodSingleReplicaDrainBlocked defines a diag.MessageType for message "IstiodSingleReplicaDrainBlocked".
+	// Description: istiod runs a single replica, which the default PodDisruptionBudget of the chart keeps available
+	IstiodSingleReplicaDrainBlocked = diag.NewMessageType(diag.Info, "IST0206", "istiod %s runs a single replica, which the default PodDisruptionBudget of the chart keeps available, so draining its node blocks until it is scaled up; set autoscaleMin or replicaCount to 2 or more in production.")
+
+	// IstiodReplicasNotSpread defines a diag.MessageType for message "IstiodReplicasNotSpread".
+	// Description: The replicas of istiod all run in the same node or zone
+	IstiodReplicasNotSpread = diag.NewMessageType(diag.Warning, "IST0207", "The %d ready replicas of istiod %s all run in the same %s, %s, out of the %d they may be scheduled in, %s; losing it makes the control plane unavailable.")
+
+	// AutoscalerIneffective defines a diag.MessageType for message "AutoscalerIneffective".
+	// Description: A HorizontalPodAutoscaler of an Istio component cannot scale its Deployment as configured
+	AutoscalerIneffective = diag.NewMessageType(diag.Warning, "IST0208", "The HorizontalPodAutoscaler of the Deployment %s cannot scale it as configured: %s.")
+
+	// PodDisruptionBudgetIneffective defines a diag.MessageType for message "PodDisruptionBudgetIneffective".
+	// Description: A PodDisruptionBudget of an Istio component does not match the pods it protects
+	PodDisruptionBudgetIneffective = diag.NewMessageType(diag.Warning, "IST0209", "The PodDisruptionBudget %s %s.")
+
+	// ServiceNoReadyEndpoints defines a diag.MessageType for message "ServiceNoReadyEndpoints".
+	// Description: A Service of the control plane or of a gateway has no ready endpoints
+	ServiceNoReadyEndpoints = diag.NewMessageType(diag.Error, "IST0210", "The Service %s has no ready endpoints, as %s; its clients cannot reach it.")
+
+	// GatewayAddressPending defines a diag.MessageType for message "GatewayAddressPending".
+	// Description: A LoadBalancer Service of a gateway has no external address assigned
+	GatewayAddressPending = diag.NewMessageType(diag.Warning, "IST0211", "The LoadBalancer Service %s of a gateway has had no external address assigned for %s; it cannot be reached from outside the cluster until the load balancer is provisioned.")
+
+	// AmbientComponentUnhealthy defines a diag.MessageType for message "AmbientComponentUnhealthy".
+	// Description: A component of the ambient mesh is not rolled out, not ready or misconfigured
+	AmbientComponentUnhealthy = diag.NewMessageType(diag.Error, "IST0212", "The %s %s of the ambient mesh is unhealthy: %s.")
+
+	// ProxyVersionSkew defines a diag.MessageType for message "ProxyVersionSkew".
+	// Description: The proxy of a pod is too many minor versions behind the control plane of its revision
+	ProxyVersionSkew = diag.NewMessageType(diag.Warning, "IST0213", "The proxy of the pod %s runs Istio %s, %d minor versions behind istiod %s of revision %s; restart the pod to upgrade its proxy.")
+
+	// ExtensionProviderUnresolved defines a diag.MessageType for message "ExtensionProviderUnresolved".
+	// Description: An extension provider of the mesh config uses a Service which does not exist or lacks its port
+	ExtensionProviderUnresolved = diag.NewMessageType(diag.Warning, "IST0214", "The extension provider %s in the ConfigMap %s uses the service %s, which %s; the proxies cannot reach it.")
+
+	// ConflictingResourceManagers defines a diag.MessageType for message "ConflictingResourceManagers".
+	// Description: A resource of an Istio component is managed by several tools
+	ConflictingResourceManagers = diag.NewMessageType(diag.Warning, "IST0215", "The %s %s is managed by several tools: %s; each of them may revert the changes of the others, so only one should manage it.")
+
+	// HelmReleaseDetected defines a diag.MessageType for message "HelmReleaseDetected".
+	// Description: Istio is installed by a Helm release
+	HelmReleaseDetected = diag.NewMessageType(diag.Info, "IST0216", "Istio is installed by the Helm release %s, which manages the components %s.")
+
+	// HelmReleaseUnhealthy defines a diag.MessageType for message "HelmReleaseUnhealthy".
+	// Description: A Helm release installing Istio cannot be upgraded, or the components it manages are not ready
+	HelmReleaseUnhealthy = diag.NewMessageType(diag.Warning, "IST0217", "The Helm release %s of Istio is unhealthy: %s.")
+
+	// IstiodSpreadUnverified defines a diag.MessageType for message "IstiodSpreadUnverified".
+	// Description: The spread of the istiod replicas cannot be verified, as the nodes cannot be read
+	IstiodSpreadUnverified = diag.NewMessageType(diag.Info, "IST0218", "Cannot verify the spread of the istiod replicas across nodes and zones, as the nodes cannot be read: %s.")
 )
 
 // All returns a list of all known message types.
@@ -317,6 +509,54 @@ func All() []*diag.MessageType {
 		UnknownUpgradeCompatibility,
 		UpdateIncompatibility,
 		MultiClusterInconsistentService,
+		ProxyStartupOrderingMissing,
+		OutdatedIstioCRD,
+		VirtualServiceRouteWeightSum,
+		UnreachableSubset,
+		CACertificateLifetime,
+		CACertificateExpiring,
+		CACertificateExpired,
+		CACertificateTrustDomainMismatch,
+		RootCertBundleMismatch,
+		ConfigSizeBudgetExceeded,
+		RevisionTagServiceNotFound,
+		RevisionTagServiceMismatch,
+		RevisionTagRevisionNotInstalled,
+		NamespaceRevisionNotFound,
+		EnvoyFilterIncompatible,
+		PodSecurityLevelIncompatible,
+		IstiodReplicasNotReady,
+		LeaderElectionHolderUnhealthy,
+		PodDisruptionBudgetBlocksDrain,
+		WorkloadNotCoveredByAuthorizationPolicy,
+		AuthorizationPolicyShadowedByDeny,
+		BackendReferenceNotPermitted,
+		BackendPortNotFound,
+		GatewayListenerConflict,
+		ConflictingTelemetrySettings,
+		TelemetryProviderNotFound,
+		TelemetrySettingsOverridden,
+		UnscopedSidecarConfig,
+		WasmPluginOrderingConflict,
+		WasmPluginImageUnpullable,
+		WasmPluginUnsupportedProxy,
+		DestinationRuleTrafficPolicyDiscarded,
+		CNINodeNotCovered,
+		MeshConfigInvalid,
+		MeshConfigDeprecatedField,
+		IstiodSingleReplicaDrainBlocked,
+		IstiodReplicasNotSpread,
+		AutoscalerIneffective,
+		PodDisruptionBudgetIneffective,
+		ServiceNoReadyEndpoints,
+		GatewayAddressPending,
+		AmbientComponentUnhealthy,
+		ProxyVersionSkew,
+		ExtensionProviderUnresolved,
+		ConflictingResourceManagers,
+		HelmReleaseDetected,
+		HelmReleaseUnhealthy,
+		IstiodSpreadUnverified,
 	}
 }
 
@@ -919,3 +1159,523 @@ func NewMultiClusterInconsistentService(r *resource.Instance, serviceName string
 		error,
 	)
 }
+
+// NewProxyStartupOrderingMissing returns a new diag.Message based on ProxyStartupOrderingMissing.
+func NewProxyStartupOrderingMissing(r *resource.Instance, workload string, reason string) diag.Message {
+	return diag.NewMessage(
+		ProxyStartupOrderingMissing,
+		r,
+		workload,
+		reason,
+	)
+}
+
+// NewOutdatedIstioCRD returns a new diag.Message based on OutdatedIstioCRD.
+func NewOutdatedIstioCRD(r *resource.Instance, crd string, problems string) diag.Message {
+	return diag.NewMessage(
+		OutdatedIstioCRD,
+		r,
+		crd,
+		problems,
+	)
+}
+
+// NewVirtualServiceRouteWeightSum returns a new diag.Message based on VirtualServiceRouteWeightSum.
+func NewVirtualServiceRouteWeightSum(r *resource.Instance, routeRule string, routeIndex int, weightSum int32) diag.Message {
+	return diag.NewMessage(
+		VirtualServiceRouteWeightSum,
+		r,
+		routeRule,
+		routeIndex,
+		weightSum,
+	)
+}
+
+// NewUnreachableSubset returns a new diag.Message based on UnreachableSubset.
+func NewUnreachableSubset(r *resource.Instance, subset string, host string, service string) diag.Message {
+	return diag.NewMessage(
+		UnreachableSubset,
+		r,
+		subset,
+		host,
+		service,
+	)
+}
+
+// NewCACertificateLifetime returns a new diag.Message based on CACertificateLifetime.
+func NewCACertificateLifetime(r *resource.Instance, role string, subject string, secret string, notAfter string, remaining string) diag.Message {
+	return diag.NewMessage(
+		CACertificateLifetime,
+		r,
+		role,
+		subject,
+		secret,
+		notAfter,
+		remaining,
+	)
+}
+
+// NewCACertificateExpiring returns a new diag.Message based on CACertificateExpiring.
+func NewCACertificateExpiring(r *resource.Instance, role string, subject string, secret string, notAfter string, remaining string) diag.Message {
+	return diag.NewMessage(
+		CACertificateExpiring,
+		r,
+		role,
+		subject,
+		secret,
+		notAfter,
+		remaining,
+	)
+}
+
+// NewCACertificateExpired returns a new diag.Message based on CACertificateExpired.
+func NewCACertificateExpired(r *resource.Instance, role string, subject string, secret string, notAfter string) diag.Message {
+	return diag.NewMessage(
+		CACertificateExpired,
+		r,
+		role,
+		subject,
+		secret,
+		notAfter,
+	)
+}
+
+// NewCACertificateTrustDomainMismatch returns a new diag.Message based on CACertificateTrustDomainMismatch.
+func NewCACertificateTrustDomainMismatch(r *resource.Instance, role string, subject string, secret string, certTrustDomain string, meshTrustDomain string) diag.Message {
+	return diag.NewMessage(
+		CACertificateTrustDomainMismatch,
+		r,
+		role,
+		subject,
+		secret,
+		certTrustDomain,
+		meshTrustDomain,
+	)
+}
+
+// NewRootCertBundleMismatch returns a new diag.Message based on RootCertBundleMismatch.
+func NewRootCertBundleMismatch(r *resource.Instance, namespace string, secret string) diag.Message {
+	return diag.NewMessage(
+		RootCertBundleMismatch,
+		r,
+		namespace,
+		secret,
+	)
+}
+
+// NewConfigSizeBudgetExceeded returns a new diag.Message based on ConfigSizeBudgetExceeded.
+func NewConfigSizeBudgetExceeded(r *resource.Instance, kind string, name string, count int, item string, budget int) diag.Message {
+	return diag.NewMessage(
+		ConfigSizeBudgetExceeded,
+		r,
+		kind,
+		name,
+		count,
+		item,
+		budget,
+	)
+}
+
+// NewRevisionTagServiceNotFound returns a new diag.Message based on RevisionTagServiceNotFound.
+func NewRevisionTagServiceNotFound(r *resource.Instance, webhook string, tag string, service string) diag.Message {
+	return diag.NewMessage(
+		RevisionTagServiceNotFound,
+		r,
+		webhook,
+		tag,
+		service,
+	)
+}
+
+// NewRevisionTagServiceMismatch returns a new diag.Message based on RevisionTagServiceMismatch.
+func NewRevisionTagServiceMismatch(r *resource.Instance, tag string, revision string, webhook string, service string, serviceRevision string) diag.Message {
+	return diag.NewMessage(
+		RevisionTagServiceMismatch,
+		r,
+		tag,
+		revision,
+		webhook,
+		service,
+		serviceRevision,
+	)
+}
+
+// NewRevisionTagRevisionNotInstalled returns a new diag.Message based on RevisionTagRevisionNotInstalled.
+func NewRevisionTagRevisionNotInstalled(r *resource.Instance, tag string, revision string, affected string) diag.Message {
+	return diag.NewMessage(
+		RevisionTagRevisionNotInstalled,
+		r,
+		tag,
+		revision,
+		affected,
+	)
+}
+
+// NewNamespaceRevisionNotFound returns a new diag.Message based on NamespaceRevisionNotFound.
+func NewNamespaceRevisionNotFound(r *resource.Instance, revision string) diag.Message {
+	return diag.NewMessage(
+		NamespaceRevisionNotFound,
+		r,
+		revision,
+	)
+}
+
+// NewEnvoyFilterIncompatible returns a new diag.Message based on EnvoyFilterIncompatible.
+func NewEnvoyFilterIncompatible(r *resource.Instance, patch int, version string, reason string) diag.Message {
+	return diag.NewMessage(
+		EnvoyFilterIncompatible,
+		r,
+		patch,
+		version,
+		reason,
+	)
+}
+
+// NewPodSecurityLevelIncompatible returns a new diag.Message based on PodSecurityLevelIncompatible.
+func NewPodSecurityLevelIncompatible(r *resource.Instance, enforced string, component string, required string) diag.Message {
+	return diag.NewMessage(
+		PodSecurityLevelIncompatible,
+		r,
+		enforced,
+		component,
+		required,
+	)
+}
+
+// NewIstiodReplicasNotReady returns a new diag.Message based on IstiodReplicasNotReady.
+func NewIstiodReplicasNotReady(r *resource.Instance, notReady int, replicas int, pods string) diag.Message {
+	return diag.NewMessage(
+		IstiodReplicasNotReady,
+		r,
+		notReady,
+		replicas,
+		pods,
+	)
+}
+
+// NewLeaderElectionHolderUnhealthy returns a new diag.Message based on LeaderElectionHolderUnhealthy.
+func NewLeaderElectionHolderUnhealthy(r *resource.Instance, holder string, reason string) diag.Message {
+	return diag.NewMessage(
+		LeaderElectionHolderUnhealthy,
+		r,
+		holder,
+		reason,
+	)
+}
+
+// NewPodDisruptionBudgetBlocksDrain returns a new diag.Message based on PodDisruptionBudgetBlocksDrain.
+func NewPodDisruptionBudgetBlocksDrain(r *resource.Instance, minAvailable string, deployment string, replicas int) diag.Message {
+	return diag.NewMessage(
+		PodDisruptionBudgetBlocksDrain,
+		r,
+		minAvailable,
+		deployment,
+		replicas,
+	)
+}
+
+// NewWorkloadNotCoveredByAuthorizationPolicy returns a new diag.Message based on WorkloadNotCoveredByAuthorizationPolicy.
+func NewWorkloadNotCoveredByAuthorizationPolicy(r *resource.Instance, policies string) diag.Message {
+	return diag.NewMessage(
+		WorkloadNotCoveredByAuthorizationPolicy,
+		r,
+		policies,
+	)
+}
+
+// NewAuthorizationPolicyShadowedByDeny returns a new diag.Message based on AuthorizationPolicyShadowedByDeny.
+func NewAuthorizationPolicyShadowedByDeny(r *resource.Instance, denyPolicy string) diag.Message {
+	return diag.NewMessage(
+		AuthorizationPolicyShadowedByDeny,
+		r,
+		denyPolicy,
+	)
+}
+
+// NewBackendReferenceNotPermitted returns a new diag.Message based on BackendReferenceNotPermitted.
+func NewBackendReferenceNotPermitted(r *resource.Instance, backend string, namespace string) diag.Message {
+	return diag.NewMessage(
+		BackendReferenceNotPermitted,
+		r,
+		backend,
+		namespace,
+	)
+}
+
+// NewBackendPortNotFound returns a new diag.Message based on BackendPortNotFound.
+func NewBackendPortNotFound(r *resource.Instance, service string, port int) diag.Message {
+	return diag.NewMessage(
+		BackendPortNotFound,
+		r,
+		service,
+		port,
+	)
+}
+
+// NewGatewayListenerConflict returns a new diag.Message based on GatewayListenerConflict.
+func NewGatewayListenerConflict(r *resource.Instance, listener string, port int, gateway string) diag.Message {
+	return diag.NewMessage(
+		GatewayListenerConflict,
+		r,
+		listener,
+		port,
+		gateway,
+	)
+}
+
+// NewConflictingTelemetrySettings returns a new diag.Message based on ConflictingTelemetrySettings.
+func NewConflictingTelemetrySettings(r *resource.Instance, conflictingTelemetries []string, target string, settings string) diag.Message {
+	return diag.NewMessage(
+		ConflictingTelemetrySettings,
+		r,
+		conflictingTelemetries,
+		target,
+		settings,
+	)
+}
+
+// NewTelemetryProviderNotFound returns a new diag.Message based on TelemetryProviderNotFound.
+func NewTelemetryProviderNotFound(r *resource.Instance, section string, provider string) diag.Message {
+	return diag.NewMessage(
+		TelemetryProviderNotFound,
+		r,
+		section,
+		provider,
+	)
+}
+
+// NewTelemetrySettingsOverridden returns a new diag.Message based on TelemetrySettingsOverridden.
+func NewTelemetrySettingsOverridden(r *resource.Instance, meshTelemetry string) diag.Message {
+	return diag.NewMessage(
+		TelemetrySettingsOverridden,
+		r,
+		meshTelemetry,
+	)
+}
+
+// NewUnscopedSidecarConfig returns a new diag.Message based on UnscopedSidecarConfig.
+func NewUnscopedSidecarConfig(r *resource.Instance, services int, configSize string, rootNamespace string, removableServices int, reduction string) diag.Message {
+	return diag.NewMessage(
+		UnscopedSidecarConfig,
+		r,
+		services,
+		configSize,
+		rootNamespace,
+		removableServices,
+		reduction,
+	)
+}
+
+// NewWasmPluginOrderingConflict returns a new diag.Message based on WasmPluginOrderingConflict.
+func NewWasmPluginOrderingConflict(r *resource.Instance, plugins []string, pod string, phase string, priority string) diag.Message {
+	return diag.NewMessage(
+		WasmPluginOrderingConflict,
+		r,
+		plugins,
+		pod,
+		phase,
+		priority,
+	)
+}
+
+// NewWasmPluginImageUnpullable returns a new diag.Message based on WasmPluginImageUnpullable.
+func NewWasmPluginImageUnpullable(r *resource.Instance, url string, reason string) diag.Message {
+	return diag.NewMessage(
+		WasmPluginImageUnpullable,
+		r,
+		url,
+		reason,
+	)
+}
+
+// NewWasmPluginUnsupportedProxy returns a new diag.Message based on WasmPluginUnsupportedProxy.
+func NewWasmPluginUnsupportedProxy(r *resource.Instance, pod string, reason string) diag.Message {
+	return diag.NewMessage(
+		WasmPluginUnsupportedProxy,
+		r,
+		pod,
+		reason,
+	)
+}
+
+// NewDestinationRuleTrafficPolicyDiscarded returns a new diag.Message based on DestinationRuleTrafficPolicyDiscarded.
+func NewDestinationRuleTrafficPolicyDiscarded(r *resource.Instance, effectiveDestinationRule string, host string, discardedFields string, effectiveTrafficPolicy string) diag.Message {
+	return diag.NewMessage(
+		DestinationRuleTrafficPolicyDiscarded,
+		r,
+		effectiveDestinationRule,
+		host,
+		discardedFields,
+		effectiveTrafficPolicy,
+	)
+}
+
+// NewCNINodeNotCovered returns a new diag.Message based on CNINodeNotCovered.
+func NewCNINodeNotCovered(r *resource.Instance, node string, reason string) diag.Message {
+	return diag.NewMessage(
+		CNINodeNotCovered,
+		r,
+		node,
+		reason,
+	)
+}
+
+// NewMeshConfigInvalid returns a new diag.Message based on MeshConfigInvalid.
+func NewMeshConfigInvalid(r *resource.Instance, configMap string, problem string, consequence string) diag.Message {
+	return diag.NewMessage(
+		MeshConfigInvalid,
+		r,
+		configMap,
+		problem,
+		consequence,
+	)
+}
+
+// NewMeshConfigDeprecatedField returns a new diag.Message based on MeshConfigDeprecatedField.
+func NewMeshConfigDeprecatedField(r *resource.Instance, configMap string, field string) diag.Message {
+	return diag.NewMessage(
+		MeshConfigDeprecatedField,
+		r,
+		configMap,
+		field,
+	)
+}
+
+// NewIstiodSingleReplicaDrainBlocked returns a new diag.Message based on IstiodSingleReplicaDrainBlocked.
+func NewIstiodSingleReplicaDrainBlocked(r *resource.Instance, deployment string) diag.Message {
+	return diag.NewMessage(
+		IstiodSingleReplicaDrainBlocked,
+		r,
+		deployment,
+	)
+}
+
+// NewIstiodReplicasNotSpread returns a new diag.Message based on IstiodReplicasNotSpread.
+func NewIstiodReplicasNotSpread(r *resource.Instance, replicas int, deployment string, topology string, domain string, domains int, reason string) diag.Message {
+	return diag.NewMessage(
+		IstiodReplicasNotSpread,
+		r,
+		replicas,
+		deployment,
+		topology,
+		domain,
+		domains,
+		reason,
+	)
+}
+
+// NewAutoscalerIneffective returns a new diag.Message based on AutoscalerIneffective.
+func NewAutoscalerIneffective(r *resource.Instance, deployment string, problem string) diag.Message {
+	return diag.NewMessage(
+		AutoscalerIneffective,
+		r,
+		deployment,
+		problem,
+	)
+}
+
+// NewPodDisruptionBudgetIneffective returns a new diag.Message based on PodDisruptionBudgetIneffective.
+func NewPodDisruptionBudgetIneffective(r *resource.Instance, podDisruptionBudget string, problem string) diag.Message {
+	return diag.NewMessage(
+		PodDisruptionBudgetIneffective,
+		r,
+		podDisruptionBudget,
+		problem,
+	)
+}
+
+// NewServiceNoReadyEndpoints returns a new diag.Message based on ServiceNoReadyEndpoints.
+func NewServiceNoReadyEndpoints(r *resource.Instance, service string, reason string) diag.Message {
+	return diag.NewMessage(
+		ServiceNoReadyEndpoints,
+		r,
+		service,
+		reason,
+	)
+}
+
+// NewGatewayAddressPending returns a new diag.Message based on GatewayAddressPending.
+func NewGatewayAddressPending(r *resource.Instance, service string, age string) diag.Message {
+	return diag.NewMessage(
+		GatewayAddressPending,
+		r,
+		service,
+		age,
+	)
+}
+
+// NewAmbientComponentUnhealthy returns a new diag.Message based on AmbientComponentUnhealthy.
+func NewAmbientComponentUnhealthy(r *resource.Instance, kind string, name string, problem string) diag.Message {
+	return diag.NewMessage(
+		AmbientComponentUnhealthy,
+		r,
+		kind,
+		name,
+		problem,
+	)
+}
+
+// NewProxyVersionSkew returns a new diag.Message based on ProxyVersionSkew.
+func NewProxyVersionSkew(r *resource.Instance, pod string, proxyVersion string, skew int, istiodVersion string, revision string) diag.Message {
+	return diag.NewMessage(
+		ProxyVersionSkew,
+		r,
+		pod,
+		proxyVersion,
+		skew,
+		istiodVersion,
+		revision,
+	)
+}
+
+// NewExtensionProviderUnresolved returns a new diag.Message based on ExtensionProviderUnresolved.
+func NewExtensionProviderUnresolved(r *resource.Instance, provider string, configMap string, service string, problem string) diag.Message {
+	return diag.NewMessage(
+		ExtensionProviderUnresolved,
+		r,
+		provider,
+		configMap,
+		service,
+		problem,
+	)
+}
+
+// NewConflictingResourceManagers returns a new diag.Message based on ConflictingResourceManagers.
+func NewConflictingResourceManagers(r *resource.Instance, kind string, name string, managers string) diag.Message {
+	return diag.NewMessage(
+		ConflictingResourceManagers,
+		r,
+		kind,
+		name,
+		managers,
+	)
+}
+
+// NewHelmReleaseDetected returns a new diag.Message based on HelmReleaseDetected.
+func NewHelmReleaseDetected(r *resource.Instance, release string, components string) diag.Message {
+	return diag.NewMessage(
+		HelmReleaseDetected,
+		r,
+		release,
+		components,
+	)
+}
+
+// NewHelmReleaseUnhealthy returns a new diag.Message based on HelmReleaseUnhealthy.
+func NewHelmReleaseUnhealthy(r *resource.Instance, release string, problem string) diag.Message {
+	return diag.NewMessage(
+		HelmReleaseUnhealthy,
+		r,
+		release,
+		problem,
+	)
+}
+
+// NewIstiodSpreadUnverified returns a new diag.Message based on IstiodSpreadUnverified.
+func NewIstiodSpreadUnverified(r *resource.Instance, error string) diag.Message {
+	return diag.NewMessage(
+		IstiodSpreadUnverified,
+		r,
+		error,
+	)
+}
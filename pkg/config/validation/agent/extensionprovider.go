@@ -235,7 +235,8 @@ func ValidateExtensionProviderEnvoyTCPAls(provider *meshconfig.MeshConfig_Extens
 	return
 }
 
-func validateExtensionProvider(config *meshconfig.MeshConfig) (errs error) {
+// ValidateExtensionProviders checks that the extension providers of the mesh config have unique names and valid settings.
+func ValidateExtensionProviders(config *meshconfig.MeshConfig) (errs error) {
 	definedProviders := sets.String{}
 	for _, c := range config.ExtensionProviders {
 		var currentErrs error
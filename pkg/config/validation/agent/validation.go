@@ -808,7 +808,7 @@ func ValidateMeshConfig(mesh *meshconfig.MeshConfig) (Warning, error) {
 	v = AppendValidation(v, validateServiceSettings(mesh))
 	v = AppendValidation(v, validateTrustDomainConfig(mesh))
 
-	if err := validateExtensionProvider(mesh); err != nil {
+	if err := ValidateExtensionProviders(mesh); err != nil {
 		scope.Warnf("found invalid extension provider (can be ignored if the given extension provider is not used): %v", err)
 	}
 